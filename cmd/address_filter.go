@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+var (
+	// Address filter flags. --device and --exclude-device are the short
+	// forms of --allow-device and --deny-device; both lists are merged.
+	allowDevices   []string
+	denyDevices    []string
+	includeDevices []string
+	excludeDevices []string
+	logDenied      bool
+
+	// deviceFilter is built from the flags before any command runs
+	deviceFilter = &addressFilter{}
+)
+
+// parseAddress parses a device address given in hex, with or without a 0x
+// prefix, or by its alias
+func parseAddress(s string) (uint64, error) {
+	if address, err := parseHexAddress(s); err == nil {
+		return address, nil
+	}
+	if address, ok := lookupAlias(s); ok {
+		return address, nil
+	}
+	return 0, fmt.Errorf("invalid device address %q: expected hex or a device alias", s)
+}
+
+// parseHexAddress parses a device address given in hex, with or without a
+// 0x prefix
+func parseHexAddress(s string) (uint64, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	address, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid device address %q: expected hex", s)
+	}
+	return address, nil
+}
+
+// addressFilter guards shared buses by restricting which device addresses
+// are processed and commanded. An empty allow list allows every address
+// that is not denied. The stateless (router) address is always allowed.
+type addressFilter struct {
+	allow  map[uint64]bool
+	deny   map[uint64]bool
+	log    bool
+	denied atomic.Uint64
+}
+
+// newAddressFilter builds a filter from hex address lists
+func newAddressFilter(allow, deny []string, logDenied bool) (*addressFilter, error) {
+	allowSet, err := parseAddressSet(allow)
+	if err != nil {
+		return nil, err
+	}
+	denySet, err := parseAddressSet(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &addressFilter{allow: allowSet, deny: denySet, log: logDenied}, nil
+}
+
+// parseAddressSet parses hex addresses into a set (nil for an empty list)
+func parseAddressSet(names []string) (map[uint64]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	set := make(map[uint64]bool, len(names))
+	for _, name := range names {
+		address, err := parseAddress(name)
+		if err != nil {
+			return nil, err
+		}
+		set[address] = true
+	}
+	return set, nil
+}
+
+// allows reports whether packets from or commands to address are permitted
+func (f *addressFilter) allows(address uint64) bool {
+	if address == fusain.AddressStateless {
+		return true
+	}
+	if f.deny[address] {
+		return false
+	}
+	if f.allow != nil {
+		return f.allow[address]
+	}
+	return true
+}
+
+// admit reports whether a received packet should be processed.
+// Packets from disallowed addresses are counted and, with --log-denied,
+// logged to stderr.
+func (f *addressFilter) admit(p *fusain.Packet) bool {
+	if f.allows(p.Address()) {
+		return true
+	}
+	f.denied.Add(1)
+	if f.log {
+		log.Printf("Denied %s from %s", fusain.FormatMessageType(p.Type()), formatAddress(p.Address()))
+	}
+	return false
+}
+
+// active reports whether any address is filtered
+func (f *addressFilter) active() bool {
+	return f.allow != nil || f.deny != nil
+}
+
+// summary describes the filter for TUI headers, empty when inactive
+func (f *addressFilter) summary() string {
+	if !f.active() {
+		return ""
+	}
+	var parts []string
+	if f.allow != nil {
+		parts = append(parts, "Devices: "+formatAddressSet(f.allow))
+	}
+	if f.deny != nil {
+		parts = append(parts, "Excluded: "+formatAddressSet(f.deny))
+	}
+	return fmt.Sprintf("%s (%d filtered)", strings.Join(parts, ", "), f.deniedCount())
+}
+
+// formatAddressSet lists addresses in ascending order
+func formatAddressSet(set map[uint64]bool) string {
+	addresses := make([]uint64, 0, len(set))
+	for a := range set {
+		addresses = append(addresses, a)
+	}
+	slices.Sort(addresses)
+	names := make([]string, len(addresses))
+	for i, a := range addresses {
+		names[i] = deviceLabel(a)
+	}
+	return strings.Join(names, " ")
+}
+
+// deniedCount returns the number of packets dropped by admit
+func (f *addressFilter) deniedCount() uint64 {
+	return f.denied.Load()
+}
+
+// checkCommand refuses commands to disallowed addresses. Broadcast commands
+// are refused when an allow list is set, since they would reach devices
+// outside it; an emergency stop is always permitted.
+func (f *addressFilter) checkCommand(p *fusain.Packet) error {
+	if isEmergencyStop(p) {
+		return nil
+	}
+	address := p.Address()
+	if address == fusain.AddressBroadcast {
+		if f.allow != nil {
+			return fmt.Errorf("broadcast not permitted with --allow-device")
+		}
+		return nil
+	}
+	if !f.allows(address) {
+		return fmt.Errorf("device %s is not permitted by the address filter", formatAddress(address))
+	}
+	return nil
+}
+
+// isEmergencyStop reports whether p is a STATE_COMMAND with mode EMERGENCY
+func isEmergencyStop(p *fusain.Packet) bool {
+	if p.Type() != fusain.MsgStateCommand {
+		return false
+	}
+	mode, _ := fusain.GetMapUint(p.PayloadMap(), 0)
+	return fusain.Mode(mode) == fusain.ModeEmergency
+}
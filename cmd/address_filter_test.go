@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// useAliases replaces the device aliases until the test ends
+func useAliases(t *testing.T, names map[uint64]string) {
+	t.Helper()
+	aliasesMu.Lock()
+	saved := aliases
+	aliases = names
+	aliasesMu.Unlock()
+	t.Cleanup(func() {
+		aliasesMu.Lock()
+		aliases = saved
+		aliasesMu.Unlock()
+	})
+}
+
+func TestAddressFilterAdmit(t *testing.T) {
+	useAliases(t, map[uint64]string{0x2A: "kitchen"})
+
+	tests := []struct {
+		name        string
+		allow, deny []string
+		admitted    []uint64
+		denied      []uint64
+	}{
+		{"no lists", nil, nil, []uint64{1, 2, 0x2A}, nil},
+		{"allow list", []string{"1", "kitchen"}, nil, []uint64{1, 0x2A}, []uint64{2}},
+		{"deny list", nil, []string{"0x2", "KITCHEN"}, []uint64{1}, []uint64{2, 0x2A}},
+		{"deny wins", []string{"1", "2"}, []string{"2"}, []uint64{1}, []uint64{2, 3}},
+		{"stateless", []string{"1"}, []string{"FFFFFFFFFFFFFFFF"}, []uint64{fusain.AddressStateless}, nil},
+	}
+	for _, tt := range tests {
+		f, err := newAddressFilter(tt.allow, tt.deny, false)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, address := range tt.admitted {
+			if !f.admit(stateData(address, fusain.SysStateIdle)) {
+				t.Errorf("%s: %s denied", tt.name, formatAddress(address))
+			}
+		}
+		for _, address := range tt.denied {
+			if f.admit(stateData(address, fusain.SysStateIdle)) {
+				t.Errorf("%s: %s admitted", tt.name, formatAddress(address))
+			}
+		}
+		if got := f.deniedCount(); got != uint64(len(tt.denied)) {
+			t.Errorf("%s: denied count %d, want %d", tt.name, got, len(tt.denied))
+		}
+	}
+
+	if _, err := newAddressFilter([]string{"pantry"}, nil, false); err == nil {
+		t.Error("unknown alias accepted")
+	}
+}
+
+func TestAddressFilterCheckCommand(t *testing.T) {
+	useAliases(t, map[uint64]string{0x2A: "kitchen"})
+	fan := func(address uint64) *fusain.Packet {
+		return fusain.NewStateCommand(address, uint8(fusain.ModeFan), nil)
+	}
+	estop := func(address uint64) *fusain.Packet {
+		return fusain.NewStateCommand(address, uint8(fusain.ModeEmergency), nil)
+	}
+
+	tests := []struct {
+		name        string
+		allow, deny []string
+		packet      *fusain.Packet
+		refused     bool
+	}{
+		{"no lists", nil, nil, fan(2), false},
+		{"no lists broadcast", nil, nil, fan(fusain.AddressBroadcast), false},
+		{"allowed", []string{"kitchen"}, nil, fan(0x2A), false},
+		{"not allowed", []string{"kitchen"}, nil, fan(2), true},
+		{"allow list broadcast", []string{"kitchen"}, nil, fan(fusain.AddressBroadcast), true},
+		{"denied", nil, []string{"kitchen"}, fan(0x2A), true},
+		{"deny list broadcast", nil, []string{"kitchen"}, fan(fusain.AddressBroadcast), false},
+		{"stateless", []string{"1"}, nil, fusain.NewPingRequest(fusain.AddressStateless), false},
+		{"emergency stop denied", nil, []string{"kitchen"}, estop(0x2A), false},
+		{"emergency stop broadcast", []string{"1"}, nil, estop(fusain.AddressBroadcast), false},
+	}
+	for _, tt := range tests {
+		f, err := newAddressFilter(tt.allow, tt.deny, false)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := f.checkCommand(tt.packet); (err != nil) != tt.refused {
+			t.Errorf("%s: got %v, want refused=%v", tt.name, err, tt.refused)
+		}
+	}
+}
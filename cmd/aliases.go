@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxAliasLength keeps aliases short enough for the TUI device lists
+const maxAliasLength = 24
+
+var (
+	aliasSpecs []string
+
+	// Device aliases, loaded from aliases.json before any command runs
+	aliasesMu   sync.RWMutex
+	aliases     = map[uint64]string{}
+	aliasesFile string
+)
+
+// defaultAliasesPath returns aliases.json next to the config file
+func defaultAliasesPath() string {
+	path := configPath
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), "aliases.json")
+}
+
+// loadAliases reads an aliases file: a JSON object of hex addresses to
+// names, e.g. {"0123456789ABCDEF": "garage heater"}. A missing file has no
+// aliases.
+func loadAliases(path string) (map[uint64]string, error) {
+	loaded := map[uint64]string{}
+	if path == "" {
+		return loaded, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return loaded, nil
+		}
+		return nil, fmt.Errorf("cannot read aliases: %v", err)
+	}
+
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("invalid aliases %s: %v", path, err)
+	}
+	for addr, name := range names {
+		address, err := parseHexAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aliases %s: %v", path, err)
+		}
+		if err := validateAlias(loaded, address, name); err != nil {
+			return nil, fmt.Errorf("invalid aliases %s: %v", path, err)
+		}
+		loaded[address] = name
+	}
+	return loaded, nil
+}
+
+// saveAliases writes an aliases file, replacing it atomically
+func saveAliases(path string, names map[uint64]string) error {
+	if path == "" {
+		return fmt.Errorf("no config directory to save aliases in")
+	}
+	out := make(map[string]string, len(names))
+	for address, name := range names {
+		out[fmt.Sprintf("%016X", address)] = name
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cannot save aliases: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cannot save aliases: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot save aliases: %v", err)
+	}
+	return nil
+}
+
+// validateAlias checks a new name for address against the other aliases.
+// Names that parse as hex would make --device ambiguous, so they are
+// refused.
+func validateAlias(names map[uint64]string, address uint64, name string) error {
+	if name != strings.TrimSpace(name) || name == "" {
+		return fmt.Errorf("alias %q for %016X must be non-empty without surrounding spaces", name, address)
+	}
+	if len(name) > maxAliasLength {
+		return fmt.Errorf("alias %q for %016X is longer than %d characters", name, address, maxAliasLength)
+	}
+	if _, err := parseHexAddress(name); err == nil {
+		return fmt.Errorf("alias %q for %016X looks like an address", name, address)
+	}
+	for other, existing := range names {
+		if other != address && strings.EqualFold(existing, name) {
+			return fmt.Errorf("alias %q is already used by %016X", name, other)
+		}
+	}
+	return nil
+}
+
+// setupAliases loads the aliases file and applies --alias
+func setupAliases() error {
+	aliasesFile = defaultAliasesPath()
+	loaded, err := loadAliases(aliasesFile)
+	if err != nil {
+		return err
+	}
+	aliasesMu.Lock()
+	aliases = loaded
+	aliasesMu.Unlock()
+
+	for _, spec := range aliasSpecs {
+		addr, name, ok := strings.Cut(spec, "=")
+		if !ok {
+			return fmt.Errorf("invalid --alias %q: expected ADDRESS=NAME", spec)
+		}
+		address, err := parseHexAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid --alias %q: %v", spec, err)
+		}
+		if err := setAlias(address, name); err != nil {
+			return fmt.Errorf("invalid --alias %q: %v", spec, err)
+		}
+	}
+	return nil
+}
+
+// setAlias names a device, or removes its name if name is empty, and saves
+// the aliases file
+func setAlias(address uint64, name string) error {
+	aliasesMu.Lock()
+	defer aliasesMu.Unlock()
+
+	updated := make(map[uint64]string, len(aliases)+1)
+	for a, n := range aliases {
+		updated[a] = n
+	}
+	if name == "" {
+		delete(updated, address)
+	} else {
+		if err := validateAlias(updated, address, name); err != nil {
+			return err
+		}
+		updated[address] = name
+	}
+
+	if err := saveAliases(aliasesFile, updated); err != nil {
+		return err
+	}
+	aliases = updated
+	return nil
+}
+
+// deviceAlias returns a device's name, or "" if it has none
+func deviceAlias(address uint64) string {
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+	return aliases[address]
+}
+
+// lookupAlias finds the device with a name (case-insensitive)
+func lookupAlias(name string) (uint64, bool) {
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+	for address, alias := range aliases {
+		if strings.EqualFold(alias, name) {
+			return address, true
+		}
+	}
+	return 0, false
+}
+
+// deviceLabel names a device where space is short: its alias, or its
+// address if it has none
+func deviceLabel(address uint64) string {
+	if name := deviceAlias(address); name != "" {
+		return name
+	}
+	return fmt.Sprintf("%016X", address)
+}
+
+// formatAddress formats a device address for people: the hex address,
+// followed by the device's alias if it has one
+func formatAddress(address uint64) string {
+	if name := deviceAlias(address); name != "" {
+		return fmt.Sprintf("%016X (%s)", address, name)
+	}
+	return fmt.Sprintf("%016X", address)
+}
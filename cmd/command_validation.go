@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// validateCommand checks an outgoing command before it is transmitted.
+//
+// The target address is checked against --allow-device/--deny-device and
+// the command against configured interlocks.
+// Component indices are checked against the target device's announced
+// capabilities (when dev is non-nil), and the payload is run through the
+// fusain validator so out-of-range values (RPM, glow duration, pump rate)
+// are rejected client-side with a descriptive error instead of by the device.
+func validateCommand(dev *device, p *fusain.Packet) error {
+	msgName := fusain.FormatMessageType(p.Type())
+
+	if err := checkCommandPolicy(p); err != nil {
+		return err
+	}
+
+	if dev != nil {
+		if err := checkCommandTargets(dev, p); err != nil {
+			return fmt.Errorf("%s rejected: %v", msgName, err)
+		}
+	}
+
+	if errs := fusain.ValidatePacketWithOptions(p, fusain.ValidateOptions{Limits: appConfig().Limits}); len(errs) > 0 {
+		return fmt.Errorf("%s rejected: %s", msgName, errs[0].Message)
+	}
+
+	return nil
+}
+
+// isCommand reports whether p is a controller-to-appliance message: a
+// configuration (0x10-0x1F) or control (0x20-0x2F) command
+func isCommand(p *fusain.Packet) bool {
+	return p.Type() >= fusain.MsgMotorConfig && p.Type() <= fusain.MsgPingRequest
+}
+
+// checkCommandPolicy checks an outgoing command against the
+// --allow-device/--deny-device filter and the configured interlocks. Unlike
+// the value checks in validateCommand, these can never be skipped.
+func checkCommandPolicy(p *fusain.Packet) error {
+	msgName := fusain.FormatMessageType(p.Type())
+
+	if err := deviceFilter.checkCommand(p); err != nil {
+		return fmt.Errorf("%s rejected: %v", msgName, err)
+	}
+	if err := checkInterlocks(appConfig().Interlocks, p); err != nil {
+		return fmt.Errorf("%s rejected: %v", msgName, err)
+	}
+	return nil
+}
+
+// checkCommandTargets verifies that every component index referenced by a
+// command exists on the target device
+func checkCommandTargets(dev *device, p *fusain.Packet) error {
+	payloadMap := p.PayloadMap()
+
+	switch p.Type() {
+	case fusain.MsgStateCommand:
+		// CBOR keys: 0=mode, 1=argument
+		mode, _ := fusain.GetMapUint(payloadMap, 0)
+		switch fusain.Mode(mode) {
+		case fusain.ModeFan:
+			return dev.checkIndex("motor", 0, dev.MotorCount())
+		case fusain.ModeHeat:
+			if err := dev.checkIndex("motor", 0, dev.MotorCount()); err != nil {
+				return err
+			}
+			return dev.checkIndex("pump", 0, dev.PumpCount())
+		}
+
+	case fusain.MsgMotorCommand, fusain.MsgMotorConfig:
+		// CBOR keys: 0=motor
+		motor, _ := fusain.GetMapInt(payloadMap, 0)
+		return dev.checkIndex("motor", motor, dev.MotorCount())
+
+	case fusain.MsgPumpCommand, fusain.MsgPumpConfig:
+		// CBOR keys: 0=pump
+		pump, _ := fusain.GetMapInt(payloadMap, 0)
+		return dev.checkIndex("pump", pump, dev.PumpCount())
+
+	case fusain.MsgGlowCommand, fusain.MsgGlowConfig:
+		// CBOR keys: 0=glow
+		glow, _ := fusain.GetMapInt(payloadMap, 0)
+		return dev.checkIndex("glow plug", glow, dev.GlowCount())
+
+	case fusain.MsgTempCommand:
+		// CBOR keys: 0=thermometer, 1=type, 2=motor-index (optional)
+		therm, _ := fusain.GetMapInt(payloadMap, 0)
+		if err := dev.checkIndex("thermometer", therm, dev.ThermometerCount()); err != nil {
+			return err
+		}
+		if motor, ok := fusain.GetMapInt(payloadMap, 2); ok {
+			return dev.checkIndex("motor", motor, dev.MotorCount())
+		}
+
+	case fusain.MsgTempConfig:
+		// CBOR keys: 0=thermometer
+		therm, _ := fusain.GetMapInt(payloadMap, 0)
+		return dev.checkIndex("thermometer", therm, dev.ThermometerCount())
+	}
+
+	return nil
+}
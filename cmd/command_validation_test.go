@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func TestCheckCommandTargets(t *testing.T) {
+	announced := &device{address: 1, hasCaps: true, caps: deviceCapabilities{
+		motorCount: 1, thermometerCount: 2, pumpCount: 1, glowCount: 1,
+	}}
+	noPump := &device{address: 2, hasCaps: true, caps: deviceCapabilities{motorCount: 1}}
+	unknown := &device{address: 3}
+	watch := func(thermometer, motor uint64) *fusain.Packet {
+		return fusain.NewPacketWithPayload(1, fusain.MsgTempCommand, map[int]interface{}{
+			0: thermometer, 1: uint64(fusain.TempCmdWatchMotor), 2: motor,
+		})
+	}
+
+	tests := []struct {
+		name    string
+		dev     *device
+		packet  *fusain.Packet
+		refused bool
+	}{
+		{"fan", announced, fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil), false},
+		{"heat", announced, fusain.NewStateCommand(1, uint8(fusain.ModeHeat), nil), false},
+		{"heat without a pump", noPump, fusain.NewStateCommand(2, uint8(fusain.ModeHeat), nil), true},
+		{"idle without a pump", noPump, fusain.NewStateCommand(2, uint8(fusain.ModeIdle), nil), false},
+		{"motor 0", announced, fusain.NewMotorCommand(1, 0, 2000), false},
+		{"motor 1", announced, fusain.NewMotorCommand(1, 1, 2000), true},
+		{"pump 1", announced, fusain.NewPumpCommand(1, 1, 500), true},
+		{"glow 1", announced, fusain.NewGlowCommand(1, 1, 1000), true},
+		{"thermometer 1", announced, watch(1, 0), false},
+		{"thermometer 2", announced, watch(2, 0), true},
+		{"watched motor 1", announced, watch(0, 1), true},
+		{"no announcement", unknown, fusain.NewMotorCommand(3, 7, 2000), false},
+		{"ping", noPump, fusain.NewPingRequest(2), false},
+	}
+	for _, tt := range tests {
+		if err := checkCommandTargets(tt.dev, tt.packet); (err != nil) != tt.refused {
+			t.Errorf("%s: got %v, want refused=%v", tt.name, err, tt.refused)
+		}
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	useConfig(t, func(c *Config) {
+		c.Interlocks = []InterlockRule{{Commands: []string{"glow"}}}
+	})
+	saved := deviceFilter
+	t.Cleanup(func() { deviceFilter = saved })
+	deviceFilter = &addressFilter{deny: map[uint64]bool{2: true}}
+	dev := &device{address: 1, hasCaps: true, caps: deviceCapabilities{motorCount: 1, pumpCount: 1, glowCount: 1}}
+	reverse := int64(-1)
+
+	tests := []struct {
+		name    string
+		dev     *device
+		packet  *fusain.Packet
+		refused bool
+	}{
+		{"valid", dev, fusain.NewMotorCommand(1, 0, 2000), false},
+		{"without a device", nil, fusain.NewMotorCommand(1, 5, 2000), false},
+		{"missing motor", dev, fusain.NewMotorCommand(1, 5, 2000), true},
+		{"rpm too high", nil, fusain.NewMotorCommand(1, 0, 7000), true},
+		{"negative fan rpm", nil, fusain.NewStateCommand(1, uint8(fusain.ModeFan), &reverse), true},
+		{"interlocked", dev, fusain.NewGlowCommand(1, 0, 1000), true},
+		{"filtered device", nil, fusain.NewMotorCommand(2, 0, 2000), true},
+		{"emergency stop", nil, fusain.NewStateCommand(2, uint8(fusain.ModeEmergency), nil), false},
+	}
+	for _, tt := range tests {
+		if err := validateCommand(tt.dev, tt.packet); (err != nil) != tt.refused {
+			t.Errorf("%s: got %v, want refused=%v", tt.name, err, tt.refused)
+		}
+	}
+
+	// Configured limits replace the defaults
+	limits := fusain.DefaultValidationLimits()
+	limits.MaxRPM = 8000
+	useConfig(t, func(c *Config) { c.Limits = &limits })
+	if err := validateCommand(nil, fusain.NewMotorCommand(1, 0, 7000)); err != nil {
+		t.Errorf("7000 RPM with max_rpm 8000: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+var (
+	configPath string
+	limitsPath string
+
+	// loadedConfig is loaded from the config file before any command runs
+	// and replaced on SIGHUP in long-running modes
+	loadedConfig atomic.Pointer[Config]
+)
+
+func init() {
+	loadedConfig.Store(&Config{})
+}
+
+// appConfig returns the current configuration. A reload replaces it, so
+// look it up where it is used rather than keeping it.
+func appConfig() *Config {
+	return loadedConfig.Load()
+}
+
+// Config is the heliostat configuration file (JSON).
+//
+// Example:
+//
+//	{
+//	  "interlocks": [
+//	    {"device": "0123456789ABCDEF", "commands": ["heat", "glow"]},
+//	    {"commands": ["glow"]}
+//	  ],
+//	  "validation_limits": {"max_rpm": 8000, "max_temp": 850},
+//	  "tui": {
+//	    "stats": ["totals", "rates", "devices"],
+//	    "theme": "light",
+//	    "colors": {"accent": "#005F87"},
+//	    "keys": {"quit": ["Q"], "estop": ["f12"]}
+//	  },
+//	  "polling": [
+//	    {"device": "0123456789ABCDEF", "telemetry": ["state", "temp:0"], "interval": "2s"}
+//	  ],
+//	  "error_hints": {"OVERHEAT": "Check the intake screen"},
+//	  "serve": {
+//	    "users": [{"username": "dashboard", "password_sha256": "<hex>", "role": "viewer"}]
+//	  }
+//	}
+type Config struct {
+	Interlocks []InterlockRule `json:"interlocks"`
+
+	// Limits overrides the validator thresholds for this appliance model;
+	// omitted fields keep the fusain defaults (see --limits)
+	Limits *fusain.ValidationLimits `json:"validation_limits,omitempty"`
+
+	TUI TUIConfig `json:"tui"`
+
+	// Polling lists the telemetry 'heliostat poll' requests per device
+	Polling []PollRule `json:"polling,omitempty"`
+
+	// ErrorHints replaces the troubleshooting hints shown for device error
+	// codes, keyed by code name (OVERHEAT, FLAME_OUT, ...); "" hides one
+	ErrorHints map[string]string `json:"error_hints,omitempty"`
+
+	// Serve holds the credentials and roles of 'heliostat serve' clients
+	Serve ServeConfig `json:"serve"`
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/heliostat/config.json (or the
+// platform equivalent), or "" if no config directory is known
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "heliostat", "config.json")
+}
+
+// loadConfig reads the config file. A missing file at the default location
+// yields an empty config; a missing file given with --config is an error.
+func loadConfig(path string, explicit bool) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("cannot read config: %v", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// loadAppConfig reads the config file (--config, or the default location)
+// and applies --limits
+func loadAppConfig() (*Config, error) {
+	path, explicit := configPath, configPath != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	cfg, err := loadConfig(path, explicit)
+	if err != nil {
+		return nil, err
+	}
+
+	if limitsPath != "" {
+		limits, err := fusain.LoadValidationLimits(limitsPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Limits = &limits
+	}
+	return cfg, nil
+}
+
+// validate checks values that JSON decoding cannot
+func (c *Config) validate() error {
+	for i, rule := range c.Interlocks {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("interlocks[%d]: %v", i, err)
+		}
+	}
+	if err := c.TUI.validate(); err != nil {
+		return fmt.Errorf("tui: %v", err)
+	}
+	for i, rule := range c.Polling {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("polling[%d]: %v", i, err)
+		}
+	}
+	if err := validateErrorHints(c.ErrorHints); err != nil {
+		return fmt.Errorf("error_hints: %v", err)
+	}
+	if err := c.Serve.validate(); err != nil {
+		return fmt.Errorf("serve: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import "testing"
+
+// useConfig publishes a copy of the current config changed by edit, as a
+// reload would, and restores the original when the test ends
+func useConfig(t *testing.T, edit func(c *Config)) {
+	t.Helper()
+	saved := appConfig()
+	c := *saved
+	edit(&c)
+	loadedConfig.Store(&c)
+	t.Cleanup(func() { loadedConfig.Store(saved) })
+}
@@ -5,24 +5,30 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
 	"github.com/gorilla/websocket"
 	"go.bug.st/serial"
 	"golang.org/x/term"
 )
 
-// Connection provides a common interface for reading/writing bytes from serial or WebSocket
+// Connection provides a common interface for reading/writing bytes from serial, WebSocket or TCP
 type Connection interface {
 	io.Reader
 	io.Writer
@@ -37,8 +43,15 @@ type SerialConnection struct {
 	port serial.Port
 }
 
+// Read reads from the port. A closed or unplugged port is reported as
+// ErrConnectionClosed.
 func (s *SerialConnection) Read(p []byte) (int, error) {
-	return s.port.Read(p)
+	n, err := s.port.Read(p)
+	var portErr *serial.PortError
+	if errors.As(err, &portErr) && portErr.Code() == serial.PortClosed {
+		return n, ErrConnectionClosed
+	}
+	return n, err
 }
 
 func (s *SerialConnection) Write(p []byte) (int, error) {
@@ -49,15 +62,42 @@ func (s *SerialConnection) Close() error {
 	return s.port.Close()
 }
 
-// ErrConnectionClosed is returned when reading from a closed WebSocket connection
-var ErrConnectionClosed = fmt.Errorf("websocket connection closed")
-
-// WebSocketConnection wraps a WebSocket connection for byte-level reading
+// ErrConnectionClosed is returned when reading from a closed WebSocket or
+// TCP connection or a closed or unplugged serial port
+var ErrConnectionClosed = fmt.Errorf("connection closed")
+
+// ErrReconnected is returned once by a ReconnectingConnection's Read after
+// it reconnected, so readers can drop a partial frame from the old
+// connection
+var ErrReconnected = fmt.Errorf("reconnected")
+
+// WebSocketFrameProtocol is the WebSocket subprotocol in which every binary
+// message carries exactly one Fusain frame. Without it, messages are an
+// arbitrary slicing of the byte stream.
+const WebSocketFrameProtocol = "fusain.frame.v1"
+
+// WebSocketConnection wraps a WebSocket connection for byte-level reading.
+//
+// Writes may be coalesced: with a flush interval set, bytes written within
+// the interval are sent as one binary message, reducing per-message overhead
+// for command bursts. Writes larger than the maximum message size are split.
+//
+// When the server selects WebSocketFrameProtocol, coalescing and splitting
+// are disabled and each written frame is sent as its own message.
 type WebSocketConnection struct {
-	conn      *websocket.Conn
-	buf       []byte
-	bufOffset int
-	closed    bool // Track if connection has failed/closed
+	conn       *websocket.Conn
+	buf        []byte
+	bufOffset  int
+	closed     bool // Track if connection has failed/closed
+	framed     bool // One Fusain frame per message (WebSocketFrameProtocol)
+	compressed bool // permessage-deflate negotiated
+
+	writeMu       sync.Mutex
+	pending       []byte        // Coalesced bytes not yet sent
+	flushTimer    *time.Timer   // Pending flush (nil when nothing is queued)
+	flushInterval time.Duration // Coalescing window (0 = send each write immediately)
+	maxMessage    int           // Maximum binary message size (0 = unlimited)
+	writeErr      error         // Error from a timed flush, returned by the next Write
 }
 
 func (w *WebSocketConnection) Read(p []byte) (int, error) {
@@ -97,18 +137,155 @@ func (w *WebSocketConnection) Read(p []byte) (int, error) {
 	}
 }
 
+// Framed reports whether the server accepted WebSocketFrameProtocol
+func (w *WebSocketConnection) Framed() bool {
+	return w.framed
+}
+
+// Compressed reports whether the server accepted permessage-deflate
+func (w *WebSocketConnection) Compressed() bool {
+	return w.compressed
+}
+
+// SetWriteShaping configures write coalescing and message splitting
+func (w *WebSocketConnection) SetWriteShaping(flushInterval time.Duration, maxMessage int) {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	w.flushInterval = flushInterval
+	w.maxMessage = maxMessage
+}
+
+// Write sends p, or queues it for the next flush when coalescing. A failed
+// timed flush is reported by the following Write.
 func (w *WebSocketConnection) Write(p []byte) (int, error) {
-	err := w.conn.WriteMessage(websocket.BinaryMessage, p)
-	if err != nil {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	if err := w.writeErr; err != nil {
+		w.writeErr = nil
 		return 0, err
 	}
+
+	if w.framed {
+		if err := w.sendFrames(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if w.flushInterval <= 0 {
+		if err := w.send(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	w.pending = append(w.pending, p...)
+
+	// Send complete messages now; the remainder waits for the flush timer
+	if w.maxMessage > 0 && len(w.pending) >= w.maxMessage {
+		full := len(w.pending) - len(w.pending)%w.maxMessage
+		err := w.send(w.pending[:full])
+		w.pending = append(w.pending[:0], w.pending[full:]...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(w.pending) > 0 && w.flushTimer == nil {
+		w.flushTimer = time.AfterFunc(w.flushInterval, w.timedFlush)
+	}
 	return len(p), nil
 }
 
+// Flush sends coalesced writes immediately
+func (w *WebSocketConnection) Flush() error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	return w.flushLocked()
+}
+
+func (w *WebSocketConnection) timedFlush() {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if err := w.flushLocked(); err != nil && w.writeErr == nil {
+		w.writeErr = err
+	}
+}
+
+func (w *WebSocketConnection) flushLocked() error {
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+		w.flushTimer = nil
+	}
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.send(w.pending)
+	w.pending = w.pending[:0]
+	return err
+}
+
+// send writes data as binary messages of at most maxMessage bytes
+func (w *WebSocketConnection) send(data []byte) error {
+	for len(data) > 0 {
+		n := len(data)
+		if w.maxMessage > 0 && n > w.maxMessage {
+			n = w.maxMessage
+		}
+		if err := w.conn.WriteMessage(websocket.BinaryMessage, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// sendFrames sends each complete frame in pending+data as its own message.
+// A trailing partial frame stays pending until its END byte is written.
+func (w *WebSocketConnection) sendFrames(data []byte) error {
+	w.pending = append(w.pending, data...)
+	for {
+		end := bytes.IndexByte(w.pending, fusain.EndByte)
+		if end < 0 {
+			return nil
+		}
+		err := w.conn.WriteMessage(websocket.BinaryMessage, w.pending[:end+1])
+		w.pending = append(w.pending[:0], w.pending[end+1:]...)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SetWriteDeadline bounds the writes that follow, so a stalled peer can't
+// block the writer forever
+func (w *WebSocketConnection) SetWriteDeadline(t time.Time) error {
+	return w.conn.SetWriteDeadline(t)
+}
+
+// Close flushes coalesced writes and closes the connection
 func (w *WebSocketConnection) Close() error {
+	w.Flush()
 	return w.conn.Close()
 }
 
+// writePacket encodes and writes p. Emergency-stop packets are flushed at
+// once so write coalescing never delays them.
+func writePacket(conn Connection, p *fusain.Packet) error {
+	data, err := fusain.EncodePacket(p.Address(), p.Type(), p.PayloadMap())
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	if flusher, ok := conn.(interface{ Flush() error }); ok && p.IsEmergency() {
+		return flusher.Flush()
+	}
+	return nil
+}
+
 // OpenSerialConnection opens a serial port connection
 func OpenSerialConnection(portName string, baudRate int) (ByteReader, error) {
 	mode := &serial.Mode{
@@ -126,8 +303,68 @@ func OpenSerialConnection(portName string, baudRate int) (ByteReader, error) {
 	return &SerialConnection{port: port}, nil
 }
 
-// OpenWebSocketConnection opens a WebSocket connection with HTTP Basic auth
-func OpenWebSocketConnection(wsURL, username, password string, skipSSLVerify bool) (ByteReader, error) {
+// tcpDialTimeout bounds how long OpenTCPConnection waits for the bridge
+const tcpDialTimeout = 10 * time.Second
+
+// TCPConnection wraps a TCP connection to a serial bridge such as ser2net
+// or socat, which carries the serial byte stream unchanged
+type TCPConnection struct {
+	conn   net.Conn
+	closed bool // A read failed; the connection is gone
+}
+
+// Read reads from the socket. The bridge closing the connection is reported
+// as ErrConnectionClosed; any other failed read reports its cause first and
+// ErrConnectionClosed after.
+func (t *TCPConnection) Read(p []byte) (int, error) {
+	if t.closed {
+		return 0, ErrConnectionClosed
+	}
+	n, err := t.conn.Read(p)
+	if err == nil {
+		return n, nil
+	}
+	t.closed = true
+	if err == io.EOF || errors.Is(err, net.ErrClosed) {
+		return n, ErrConnectionClosed
+	}
+	return n, err
+}
+
+func (t *TCPConnection) Write(p []byte) (int, error) {
+	return t.conn.Write(p)
+}
+
+func (t *TCPConnection) Close() error {
+	return t.conn.Close()
+}
+
+// OpenTCPConnection connects to a serial bridge at address (host:port)
+func OpenTCPConnection(address string) (ByteReader, error) {
+	conn, err := net.DialTimeout("tcp", address, tcpDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	return &TCPConnection{conn: conn}, nil
+}
+
+// WebSocketOptions configures OpenWebSocketConnection
+type WebSocketOptions struct {
+	Username      string // HTTP Basic auth (sent only with Password)
+	Password      string
+	SkipSSLVerify bool // Skip TLS certificate verification (wss:// only)
+
+	// OfferFrames offers WebSocketFrameProtocol to the server; servers that
+	// don't select it get the plain byte-stream mode
+	OfferFrames bool
+
+	// Compress negotiates permessage-deflate (RFC 7692). Servers that
+	// don't support it leave messages uncompressed.
+	Compress bool
+}
+
+// OpenWebSocketConnection opens a WebSocket connection
+func OpenWebSocketConnection(wsURL string, opts WebSocketOptions) (ByteReader, error) {
 	// Parse and validate URL
 	u, err := url.Parse(wsURL)
 	if err != nil {
@@ -144,20 +381,24 @@ func OpenWebSocketConnection(wsURL, username, password string, skipSSLVerify boo
 
 	// Create dialer with timeout
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
+		HandshakeTimeout:  10 * time.Second,
+		EnableCompression: opts.Compress,
+	}
+	if opts.OfferFrames {
+		dialer.Subprotocols = []string{WebSocketFrameProtocol}
 	}
 
 	// Configure TLS for wss://
 	if u.Scheme == "wss" {
 		dialer.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: skipSSLVerify,
+			InsecureSkipVerify: opts.SkipSSLVerify,
 		}
 	}
 
 	// Build HTTP headers with Basic auth
 	headers := http.Header{}
-	if username != "" && password != "" {
-		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	if opts.Username != "" && opts.Password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(opts.Username + ":" + opts.Password))
 		headers.Set("Authorization", "Basic "+credentials)
 	}
 
@@ -173,7 +414,11 @@ func OpenWebSocketConnection(wsURL, username, password string, skipSSLVerify boo
 		return nil, fmt.Errorf("WebSocket connection failed: %v", err)
 	}
 
-	return &WebSocketConnection{conn: conn}, nil
+	return &WebSocketConnection{
+		conn:       conn,
+		framed:     conn.Subprotocol() == WebSocketFrameProtocol,
+		compressed: strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate"),
+	}, nil
 }
 
 // GetPassword retrieves password from environment or prompts user
@@ -203,27 +448,74 @@ func GetPassword() (string, error) {
 	return string(passwordBytes), nil
 }
 
-// OpenConnection opens either a serial or WebSocket connection based on flags
+// wsPassword is the HTTP Basic auth password, once asked for
+var wsPassword string
+
+// OpenConnection opens a serial, WebSocket or TCP connection based on flags.
+// Errors are returned as *ExitError with code ExitConnection.
 func OpenConnection() (ByteReader, string, error) {
+	conn, connInfo, err := openConnection()
+	if err != nil {
+		return nil, "", &ExitError{Code: ExitConnection, Err: err}
+	}
+	if connImpairment != nil {
+		conn = impairConnection(conn, connImpairment)
+		connInfo += fmt.Sprintf(" [impaired: %s]", connImpairment)
+	}
+	trackConnection(conn)
+	service.setConnection(true, connInfo)
+	return conn, connInfo, nil
+}
+
+// openConnection opens the connection selected by the global flags
+func openConnection() (ByteReader, string, error) {
 	if wsURL != "" {
-		// WebSocket mode
-		password := ""
-		if wsUsername != "" {
+		// WebSocket mode; the password is asked for once, not on every
+		// reconnect
+		if wsUsername != "" && wsPassword == "" {
 			var err error
-			password, err = GetPassword()
+			wsPassword, err = GetPassword()
 			if err != nil {
 				return nil, "", err
 			}
 		}
 
-		conn, err := OpenWebSocketConnection(wsURL, wsUsername, password, wsNoSSLVerify)
+		conn, err := OpenWebSocketConnection(wsURL, WebSocketOptions{
+			Username:      wsUsername,
+			Password:      wsPassword,
+			SkipSSLVerify: wsNoSSLVerify,
+			OfferFrames:   !wsStream,
+			Compress:      wsCompress,
+		})
 		if err != nil {
 			return nil, "", err
 		}
+		ws := conn.(*WebSocketConnection)
+		ws.SetWriteShaping(wsCoalesce, wsMaxMessage)
 
+		var modes []string
+		if ws.Framed() {
+			modes = append(modes, "framed")
+		}
+		if ws.Compressed() {
+			modes = append(modes, "compressed")
+		}
+		if len(modes) > 0 {
+			return conn, fmt.Sprintf("WebSocket: %s (%s)", wsURL, strings.Join(modes, ", ")), nil
+		}
 		return conn, fmt.Sprintf("WebSocket: %s", wsURL), nil
 	}
 
+	if tcpAddr != "" {
+		// TCP mode: a serial bridge such as ser2net or socat
+		conn, err := OpenTCPConnection(tcpAddr)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return conn, fmt.Sprintf("TCP: %s", tcpAddr), nil
+	}
+
 	if portName != "" {
 		// Serial mode
 		conn, err := OpenSerialConnection(portName, baudRate)
@@ -234,5 +526,178 @@ func OpenConnection() (ByteReader, string, error) {
 		return conn, fmt.Sprintf("Serial: %s @ %d baud", portName, baudRate), nil
 	}
 
-	return nil, "", fmt.Errorf("either --port or --url must be specified")
+	return nil, "", fmt.Errorf("one of --port, --url or --tcp must be specified")
+}
+
+// ReconnectingConnection is a Connection that reopens the connection
+// selected by the flags whenever it is lost, retrying with exponential
+// backoff (1s, doubling up to 30s). Reads block while it reconnects, so a
+// reader loop carries on across a serial unplug, a dropped WebSocket or a
+// restarted TCP bridge.
+//
+// ConnectionLost (with Reconnecting set) and ConnectionRestored are
+// published on the bus. The first Read after reconnecting returns
+// ErrReconnected; Read returns ErrConnectionClosed only after Close.
+type ReconnectingConnection struct {
+	bus *events.Bus
+
+	// onRestored is called with each new connection (may be nil)
+	onRestored func(conn Connection)
+
+	mu   sync.RWMutex
+	conn Connection
+	info string
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenReconnectingConnection opens the connection selected by the flags
+// like OpenConnection. Unless --no-reconnect is set, it is wrapped in a
+// ReconnectingConnection publishing on eventBus, which calls onRestored
+// (if not nil) with each new connection, e.g. to repeat a request.
+func OpenReconnectingConnection(onRestored func(conn Connection)) (ByteReader, string, error) {
+	conn, connInfo, err := OpenConnection()
+	if err != nil || noReconnect {
+		return conn, connInfo, err
+	}
+	r := &ReconnectingConnection{
+		bus:        eventBus,
+		onRestored: onRestored,
+		conn:       conn,
+		info:       connInfo,
+		done:       make(chan struct{}),
+	}
+	trackConnection(r)
+	return r, connInfo, nil
+}
+
+// Conn returns the current underlying connection
+func (r *ReconnectingConnection) Conn() Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.conn
+}
+
+// Info returns the description of the current connection
+func (r *ReconnectingConnection) Info() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.info
+}
+
+func (r *ReconnectingConnection) Read(p []byte) (int, error) {
+	conn := r.Conn()
+	n, err := conn.Read(p)
+	if err == nil || !connectionGone(conn, err) {
+		return n, err
+	}
+	if !r.reconnect(conn, err) {
+		return 0, ErrConnectionClosed
+	}
+	return 0, ErrReconnected
+}
+
+// Write writes to the current connection. Writes while reconnecting fail.
+func (r *ReconnectingConnection) Write(p []byte) (int, error) {
+	return r.Conn().Write(p)
+}
+
+// Close closes the connection and stops any reconnect in progress
+func (r *ReconnectingConnection) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.Conn().Close()
+	})
+	return err
+}
+
+// reconnect replaces the lost connection, returning false if Close was
+// called first
+func (r *ReconnectingConnection) reconnect(lost Connection, cause error) bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+	}
+	lost.Close()
+	r.bus.Publish(events.ConnectionLost{At: time.Now(), Err: cause, Reconnecting: true})
+
+	backoff := 1 * time.Second
+	maxBackoff := 30 * time.Second
+
+	for {
+		select {
+		case <-r.done:
+			return false
+		case <-time.After(backoff):
+		}
+
+		conn, connInfo, err := OpenConnection()
+		if err == nil {
+			r.mu.Lock()
+			r.conn = conn
+			r.info = connInfo
+			r.mu.Unlock()
+
+			// OpenConnection tracked the new connection; shutdown must
+			// close this one, or the reader would reconnect again
+			trackConnection(r)
+
+			// Closed while opening
+			select {
+			case <-r.done:
+				conn.Close()
+				return false
+			default:
+			}
+
+			r.bus.Publish(events.ConnectionRestored{At: time.Now(), Info: connInfo})
+			if r.onRestored != nil {
+				r.onRestored(conn)
+			}
+			return true
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connectionGone reports whether a read error means conn is permanently
+// gone rather than a transient failure. WebSocket and TCP connections are
+// closed by any failed read, which reports the cause first and
+// ErrConnectionClosed after.
+func connectionGone(conn Connection, err error) bool {
+	if err == ErrConnectionClosed {
+		return true
+	}
+	switch c := conn.(type) {
+	case *WebSocketConnection:
+		return c.closed
+	case *TCPConnection:
+		return c.closed
+	}
+	return false
+}
+
+// connectionLostMessage describes a ConnectionLost event for event logs and
+// text output
+func connectionLostMessage(e events.ConnectionLost) string {
+	if !e.Reconnecting {
+		return "Connection closed"
+	}
+	if e.Err == nil || e.Err == ErrConnectionClosed {
+		return "Connection lost - reconnecting..."
+	}
+	return fmt.Sprintf("Connection lost (%v) - reconnecting...", e.Err)
+}
+
+// connectionRestoredMessage describes a ConnectionRestored event for event
+// logs and text output
+func connectionRestoredMessage(e events.ConnectionRestored) string {
+	return "Reconnected via " + e.Info
 }
@@ -5,8 +5,7 @@ package cmd
 
 import (
 	"fmt"
-	"sync"
-	"time"
+	"os"
 
 	"github.com/Thermoquad/heliostat/pkg/fusain"
 	tea "github.com/charmbracelet/bubbletea"
@@ -24,286 +23,116 @@ connected via WebSocket (through Slate) or UART (direct connection).
 Features:
   - Device discovery (DEVICE_ANNOUNCE)
   - Real-time telemetry display
-  - State control (idle, fan mode)
+  - State control (idle, fan mode, heat with confirmation and abort)
+  - Pump, glow plug and target temperature controls
+  - Device config editor with diff-before-send
+  - Device naming ('n'; see --alias)
   - Statistics tracking
   - Event logging
   - Automatic reconnection on connection loss
+  - Automatic device refresh when the router restarts or a new device
+    announces itself
 
 The TUI discovers devices first before enabling control. Tab switches between
 device list and control panel. Arrow keys navigate the device list.
 
-Supports both serial and WebSocket connections.`,
-	RunE: runControl,
-}
-
-func init() {
-	rootCmd.AddCommand(controlCmd)
-}
-
-// connectionManager handles connection lifecycle and reconnection
-type connectionManager struct {
-	conn     Connection
-	connInfo string
-	mu       sync.RWMutex
-	p        *tea.Program
-	done     chan struct{}
-	stopRead chan struct{}
-}
+Charts below the telemetry plot the selected device's motor RPM and
+temperature against their targets over the last --chart-window (default 1m,
+or "chart_window" in the config file's "tui" section), to make oscillation
+visible. 'c' hides or shows them.
 
-func (cm *connectionManager) getConn() Connection {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-	return cm.conn
-}
-
-func (cm *connectionManager) setConn(conn Connection, connInfo string) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-	cm.conn = conn
-	cm.connInfo = connInfo
-}
-
-func runControl(cmd *cobra.Command, args []string) error {
-	// Open initial connection (serial or WebSocket)
-	conn, connInfo, err := OpenConnection()
-	if err != nil {
-		return err
-	}
+Pump rate (PREHEAT 2 and HEATING, '+'/'-' step 10 ms), glow plug on-time
+(IDLE and preheat; 0 extinguishes) and target temperature (in the display
+units) inputs send PUMP_COMMAND, GLOW_COMMAND and TEMP_COMMAND
+SET_TARGET_TEMP in the states that take them.
 
-	// Create connection manager
-	cm := &connectionManager{
-		conn:     conn,
-		connInfo: connInfo,
-		done:     make(chan struct{}),
-		stopRead: make(chan struct{}),
-	}
+'o' opens the device config editor for the selected heater (motor, pump,
+thermometer, glow plug and command timeout settings); 's' reviews the
+changes before sending them.
 
-	// Create TUI model with connection manager
-	m := initialControlModel(cm, connInfo)
+'n' names the selected heater. The name replaces its address in the device
+list and follows it in the log, and is saved to aliases.json for every
+heliostat command (see --alias).
 
-	// Create TUI program with alt screen and mouse support
-	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
-	cm.p = p
+HEAT is started from IDLE with a pump rate (ms between pulses) and must be
+confirmed with 'y'. The control panel then follows the heater through
+PREHEAT, PREHEAT 2 and HEATING with the time spent in each; 'x' (or the
+ABORT button) returns it to IDLE, which shuts the burner down via COOLING.
 
-	// Start reader goroutines (similar to error_detection.go pattern)
-	go cm.readerLoop()
+'E' or F12 opens the emergency-stop prompt from anywhere: 'y' (or the hotkey
+again) sends EMERGENCY to the selected heater, 'a' to every heater. A
+stopped heater's controls are locked behind an E_STOP panel until it leaves
+E_STOP; its Reset to IDLE button sends IDLE once it has stopped.
 
-	// Send initial discovery request
-	sendInitialDiscoveryRequest(cm.getConn())
+'w' writes the commands sent so far, with the time between them, as a
+script for 'heliostat run' (to --export-script, or heliostat-DATE-TIME.run
+in the current directory), so a procedure performed by hand can be repeated
+exactly. With --export-script, the script is also written on exit.
 
-	// Run TUI
-	if _, err := p.Run(); err != nil {
-		close(cm.done) // Signal goroutines to stop
-		cm.getConn().Close()
-		return fmt.Errorf("TUI error: %v", err)
-	}
+The keys above are the defaults: "keys" in the config file's "tui" section
+rebinds them, and --theme or "theme" picks the colors (see the README).
 
-	close(cm.done) // Signal goroutines to stop
-	cm.getConn().Close()
-	return nil
+Supports both serial and WebSocket connections.`,
+	RunE: runControl,
 }
 
-// readerLoop handles reading from connection with automatic reconnection
-func (cm *connectionManager) readerLoop() {
-	for {
-		select {
-		case <-cm.done:
-			return
-		default:
-		}
-
-		// Start reading from current connection
-		connLost := cm.readFromConnection()
-
-		if connLost {
-			// Notify TUI about connection loss
-			cm.p.Send(connectionLostMsg{})
-
-			// Attempt to reconnect
-			if !cm.reconnect() {
-				return // Shutdown requested during reconnect
-			}
-		}
-	}
+func init() {
+	rootCmd.AddCommand(controlCmd)
+	controlCmd.Flags().DurationVar(&controlChartWindow, "chart-window", 0, "Time span of the telemetry charts (default 1m)")
+	controlCmd.Flags().StringVar(&controlExportScript, "export-script", "", "On exit, write the commands sent as a script for 'heliostat run' (also the file 'w' writes)")
 }
 
-// readFromConnection reads packets from the connection until it fails
-// Returns true if connection was lost, false if shutdown requested
-func (cm *connectionManager) readFromConnection() bool {
-	decoder := fusain.NewDecoder()
-	synchronized := false
-	invalidBytesBeforeSync := 0
+func runControl(cmd *cobra.Command, args []string) error {
+	// Open the connection (serial or WebSocket); discovery starts over
+	// after each reconnect
+	conn, connInfo, err := OpenReconnectingConnection(sendInitialDiscoveryRequest)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
 
-	// Buffered channel for batching updates
-	batchChan := make(chan controlDataMsg, 100)
-	syncChan := make(chan controlSyncMsg, 1)
-	readerDone := make(chan struct{})
+	// Create TUI model
+	m := initialControlModel(conn, connInfo)
 
-	// Reader goroutine - decodes packets and sends to batch channel
-	go func() {
-		defer close(readerDone)
-		buf := make([]byte, 128)
-		for {
-			select {
-			case <-cm.done:
-				return
-			case <-cm.stopRead:
-				return
-			default:
-			}
+	// Denied packets are only counted; logging to stderr would corrupt the TUI
+	deviceFilter.log = false
 
-			conn := cm.getConn()
-			if conn == nil {
-				return
-			}
-
-			n, err := conn.Read(buf)
+	// Export the session on exit, however it ends; registered first, so it
+	// runs after the TUI has restored the terminal
+	if controlExportScript != "" {
+		audit := m.audit
+		onShutdown(func() {
+			n, err := audit.export(controlExportScript)
 			if err != nil {
-				// Check if we're shutting down
-				select {
-				case <-cm.done:
-					return
-				default:
-					// For WebSocket connections, a read error usually means
-					// the connection is permanently closed
-					if err == ErrConnectionClosed {
-						return
-					}
-					// Brief pause before retry on transient errors (e.g., serial)
-					time.Sleep(10 * time.Millisecond)
-					continue
-				}
-			}
-
-			for i := 0; i < n; i++ {
-				packet, decodeErr := decoder.DecodeByte(buf[i])
-
-				if decodeErr != nil {
-					if synchronized {
-						select {
-						case batchChan <- controlDataMsg{
-							packet:           nil,
-							decodeErr:        decodeErr,
-							validationErrors: nil,
-						}:
-						default:
-						}
-					} else {
-						invalidBytesBeforeSync++
-					}
-				} else if packet != nil {
-					if !synchronized {
-						synchronized = true
-						select {
-						case syncChan <- controlSyncMsg{invalidBytes: invalidBytesBeforeSync}:
-						default:
-						}
-					}
-
-					validationErrors := fusain.ValidatePacket(packet)
-					select {
-					case batchChan <- controlDataMsg{
-						packet:           packet,
-						decodeErr:        nil,
-						validationErrors: validationErrors,
-					}:
-					default:
-					}
-				}
-			}
-		}
-	}()
-
-	// Batch sender goroutine - sends batched updates to TUI at fixed rate
-	go func() {
-		ticker := time.NewTicker(50 * time.Millisecond)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-cm.done:
+				fmt.Fprintf(os.Stderr, "Cannot export script: %v\n", err)
 				return
-			case <-readerDone:
-				return
-			case <-ticker.C:
-				var batch controlBatchMsg
-
-				// Check for sync message
-				select {
-				case sync := <-syncChan:
-					batch.syncMsg = &sync
-				default:
-				}
-
-				// Drain all available messages from batch channel
-			drainLoop:
-				for {
-					select {
-					case msg := <-batchChan:
-						batch.messages = append(batch.messages, msg)
-					default:
-						break drainLoop
-					}
-				}
-
-				// Send batch if we have anything
-				if batch.syncMsg != nil || len(batch.messages) > 0 {
-					cm.p.Send(batch)
-				}
 			}
-		}
-	}()
-
-	// Wait for reader to finish (connection lost or shutdown)
-	<-readerDone
-
-	// Check if we're shutting down
-	select {
-	case <-cm.done:
-		return false
-	default:
-		return true // Connection lost
-	}
-}
-
-// reconnect attempts to reconnect with exponential backoff
-// Returns false if shutdown was requested during reconnection
-func (cm *connectionManager) reconnect() bool {
-	// Close old connection
-	if conn := cm.getConn(); conn != nil {
-		conn.Close()
+			fmt.Fprintf(os.Stderr, "Exported %d commands to %s\n", n, controlExportScript)
+		})
 	}
 
-	backoff := 1 * time.Second
-	maxBackoff := 30 * time.Second
-
-	for {
-		select {
-		case <-cm.done:
-			return false
-		case <-time.After(backoff):
-		}
-
-		// Attempt to reconnect
-		conn, connInfo, err := OpenConnection()
-		if err == nil {
-			cm.setConn(conn, connInfo)
+	// Create TUI program with alt screen and mouse support
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithoutSignalHandler())
+	onShutdownProgram(p)
 
-			// Notify TUI about reconnection
-			cm.p.Send(reconnectedMsg{connInfo: connInfo})
+	// Events are dropped if the TUI can't keep up
+	sub := eventBus.Subscribe(1000)
+	defer sub.Close()
 
-			// Send discovery request
-			sendInitialDiscoveryRequest(conn)
+	// Start reader and TUI forwarder goroutines
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, true).run(conn, done)
+	go forwardEvents(sub, p, done)
 
-			return true
-		}
+	// Send initial discovery request
+	sendInitialDiscoveryRequest(conn)
 
-		// Exponential backoff
-		backoff *= 2
-		if backoff > maxBackoff {
-			backoff = maxBackoff
-		}
+	// Run TUI
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("TUI error: %v", err)
 	}
+	return nil
 }
 
 // sendInitialDiscoveryRequest sends a discovery request to find devices
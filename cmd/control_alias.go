@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// aliasEditor names the selected device
+type aliasEditor struct {
+	address uint64
+	input   textinput.Model
+}
+
+// openAliasEditor starts naming the selected device, with its current
+// alias to edit
+func (m *controlModel) openAliasEditor() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil {
+		return m, nil
+	}
+
+	input := textinput.New()
+	input.Placeholder = "garage heater"
+	input.CharLimit = maxAliasLength
+	input.Width = maxAliasLength
+	input.SetValue(deviceAlias(selected.address))
+	input.Focus()
+	m.aliasEditor = &aliasEditor{address: selected.address, input: input}
+	return m, textinput.Blink
+}
+
+// handleAliasKey saves the name on enter (an empty name removes it) and
+// closes the editor on esc
+func (m *controlModel) handleAliasKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := m.aliasEditor
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.aliasEditor = nil
+		return m, nil
+	case "enter":
+		name := strings.TrimSpace(e.input.Value())
+		if err := setAlias(e.address, name); err != nil {
+			m.addLogEntry(err.Error(), true)
+			return m, nil
+		}
+		m.aliasEditor = nil
+		m.updateDeviceList()
+		if name == "" {
+			m.addLogEntry(fmt.Sprintf("Removed the alias of %016X", e.address), false)
+		} else {
+			m.addLogEntry(fmt.Sprintf("Named %016X %q", e.address, name), false)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	e.input, cmd = e.input.Update(msg)
+	return m, cmd
+}
+
+// renderAliasEditor renders the alias prompt
+func (m controlModel) renderAliasEditor(statsLabelStyle, headerStyle lipgloss.Style) string {
+	e := m.aliasEditor
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("%s Heater %016X\n\n", statsLabelStyle.Render("Name:"), e.address))
+	s.WriteString(e.input.View())
+	s.WriteString("\n\n")
+	s.WriteString(headerStyle.Render("enter=save (empty removes)  esc=cancel"))
+	return s.String()
+}
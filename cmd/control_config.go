@@ -0,0 +1,461 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// configSection is a config message the editor offers, one per component
+type configSection struct {
+	msgType uint8
+	title   string
+	count   func(device) int // Component count; nil for device-wide messages
+}
+
+// configSections are the config messages in editor order
+var configSections = []configSection{
+	{fusain.MsgMotorConfig, "Motor", device.MotorCount},
+	{fusain.MsgPumpConfig, "Pump", device.PumpCount},
+	{fusain.MsgTempConfig, "Thermometer", device.ThermometerCount},
+	{fusain.MsgGlowConfig, "Glow plug", device.GlowCount},
+	{fusain.MsgTimeoutConfig, "Command timeout", nil},
+}
+
+// configKey identifies a config value: message type, component index (-1
+// for device-wide messages) and payload key
+type configKey struct {
+	msgType uint8
+	index   int64
+	key     int
+}
+
+// configField is one editable value of a config message
+type configField struct {
+	configKey
+	title  string // Section title, e.g. "Motor 0"
+	schema fusain.FieldSchema
+}
+
+// configEditor is the device config screen
+type configEditor struct {
+	address uint64
+	fields  []configField
+	cursor  int
+	edits   map[configKey]interface{} // Values changed in the editor
+	input   textinput.Model
+	editing bool // The input is open on the cursor's field
+	review  bool // The diff is shown, awaiting confirmation
+}
+
+// configChange is an edited value with the value it replaces
+type configChange struct {
+	field  configField
+	old    interface{} // nil when unknown
+	newVal interface{}
+}
+
+// configFieldsFor lists the editable fields of a device: every optional
+// field of each config message (required ones of device-wide messages),
+// for each announced component. Devices that haven't announced their
+// capabilities get one of each.
+func configFieldsFor(dev *device) []configField {
+	var fields []configField
+	for _, section := range configSections {
+		schema, ok := fusain.LookupSchema(section.msgType)
+		if !ok {
+			continue
+		}
+		if section.count == nil {
+			for _, f := range schema.Fields {
+				fields = append(fields, configField{configKey{section.msgType, -1, f.Key}, section.title, f})
+			}
+			continue
+		}
+		count := 1
+		if dev.hasCaps {
+			count = section.count(*dev)
+		}
+		for i := 0; i < count; i++ {
+			title := fmt.Sprintf("%s %d", section.title, i)
+			for _, f := range schema.Fields {
+				if f.Required {
+					continue // The component index
+				}
+				fields = append(fields, configField{configKey{section.msgType, int64(i), f.Key}, title, f})
+			}
+		}
+	}
+	return fields
+}
+
+// parseConfigValue parses an entered value as the field's CBOR kind
+func parseConfigValue(f fusain.FieldSchema, s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch f.Kind {
+	case fusain.KindUint:
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a non-negative integer", f.Name, s)
+		}
+		if f.Max > 0 && v > f.Max {
+			return nil, fmt.Errorf("%s: %d is above %d", f.Name, v, f.Max)
+		}
+		return v, nil
+	case fusain.KindInt:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not an integer", f.Name, s)
+		}
+		return v, nil
+	case fusain.KindFloat:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", f.Name, s)
+		}
+		return v, nil
+	case fusain.KindBool:
+		switch strings.ToLower(s) {
+		case "true", "on", "yes", "1":
+			return true, nil
+		case "false", "off", "no", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%s: %q is not on or off", f.Name, s)
+	}
+	return nil, fmt.Errorf("%s: %s values can't be edited", f.Name, f.Kind)
+}
+
+// formatConfigValue formats a config value, "-" when unknown
+func formatConfigValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "-"
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		if x {
+			return "on"
+		}
+		return "off"
+	}
+	return fmt.Sprint(v)
+}
+
+// knownConfig returns the config values known for a device, creating the
+// map on first use. Devices can't be asked for their config, so these are
+// the values sent this session and those telemetry reports.
+func (m *controlModel) knownConfig(address uint64) map[configKey]interface{} {
+	known := m.deviceConfigs[address]
+	if known == nil {
+		known = make(map[configKey]interface{})
+		m.deviceConfigs[address] = known
+	}
+	return known
+}
+
+// value returns a field's edited value, else its known value, else nil
+func (e *configEditor) value(known map[configKey]interface{}, key configKey) interface{} {
+	if v, ok := e.edits[key]; ok {
+		return v
+	}
+	return known[key]
+}
+
+// changes returns the edited values that differ from the known ones, in
+// field order
+func (e *configEditor) changes(known map[configKey]interface{}) []configChange {
+	var changes []configChange
+	for _, f := range e.fields {
+		v, ok := e.edits[f.configKey]
+		if !ok || v == known[f.configKey] {
+			continue
+		}
+		changes = append(changes, configChange{field: f, old: known[f.configKey], newVal: v})
+	}
+	return changes
+}
+
+// configMessage is a config message to send and the changes it carries
+type configMessage struct {
+	packet  *fusain.Packet
+	changes []configChange
+}
+
+// messages builds one config message per edited component, holding only
+// the changed values. Device-wide messages, whose fields are all required
+// (TIMEOUT_CONFIG), are sent whole.
+func (e *configEditor) messages(known map[configKey]interface{}, changes []configChange) ([]configMessage, error) {
+	type component struct {
+		msgType uint8
+		index   int64
+	}
+	var order []component
+	grouped := make(map[component][]configChange)
+	for _, c := range changes {
+		comp := component{c.field.msgType, c.field.index}
+		if _, ok := grouped[comp]; !ok {
+			order = append(order, comp)
+		}
+		grouped[comp] = append(grouped[comp], c)
+	}
+
+	msgs := make([]configMessage, 0, len(order))
+	for _, comp := range order {
+		payload := make(map[int]interface{})
+		if comp.index >= 0 {
+			payload[0] = uint64(comp.index)
+		}
+		for _, c := range grouped[comp] {
+			payload[c.field.key] = c.newVal
+		}
+		if comp.index < 0 {
+			schema, _ := fusain.LookupSchema(comp.msgType)
+			for _, f := range schema.Fields {
+				if _, ok := payload[f.Key]; ok {
+					continue
+				}
+				v := e.value(known, configKey{comp.msgType, comp.index, f.Key})
+				if v == nil {
+					return nil, fmt.Errorf("%s needs %s too", fusain.FormatMessageType(comp.msgType), f.Name)
+				}
+				payload[f.Key] = v
+			}
+		}
+		msgs = append(msgs, configMessage{fusain.NewPacketWithPayload(e.address, comp.msgType, payload), grouped[comp]})
+	}
+	return msgs, nil
+}
+
+// String describes a config message for the event log
+func (c configMessage) String() string {
+	values := make([]string, len(c.changes))
+	for i, change := range c.changes {
+		values[i] = change.field.schema.Name + "=" + formatConfigValue(change.newVal)
+	}
+	return fmt.Sprintf("%s (%s: %s)", fusain.FormatMessageType(c.packet.Type()), c.changes[0].field.title, strings.Join(values, ", "))
+}
+
+// noteReportedConfig records a config value a device reported in its
+// telemetry
+func (m *controlModel) noteReportedConfig(address uint64, key configKey, value interface{}) {
+	m.knownConfig(address)[key] = value
+}
+
+// openConfigEditor opens the config screen for the selected device
+func (m *controlModel) openConfigEditor() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil || !m.discoveryDone {
+		return m, nil
+	}
+	if m.estopLocked(selected) {
+		m.addLogEntry("Config editor unavailable: device is emergency-stopped", true)
+		return m, nil
+	}
+
+	input := textinput.New()
+	input.CharLimit = 12
+	input.Width = 12
+	m.configEditor = &configEditor{
+		address: selected.address,
+		fields:  configFieldsFor(selected),
+		edits:   make(map[configKey]interface{}),
+		input:   input,
+	}
+	m.rpmInput.Blur()
+	m.pumpInput.Blur()
+	m.glowInput.Blur()
+	m.tempInput.Blur()
+	return m, nil
+}
+
+// handleConfigKey handles keys on the config screen
+func (m *controlModel) handleConfigKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := m.configEditor
+	known := m.knownConfig(e.address)
+	key := msg.String()
+	if key == "ctrl+c" {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	// Diff review: y sends, anything else returns to the editor
+	if e.review {
+		e.review = false
+		if key == "y" || key == "Y" {
+			return m.sendConfig()
+		}
+		return m, nil
+	}
+
+	// Value input
+	if e.editing {
+		switch key {
+		case "enter":
+			field := e.fields[e.cursor]
+			v, err := parseConfigValue(field.schema, e.input.Value())
+			if err != nil {
+				m.addLogEntry(err.Error(), true)
+				return m, nil
+			}
+			e.edits[field.configKey] = v
+			e.editing = false
+			e.input.Blur()
+		case "esc":
+			e.editing = false
+			e.input.Blur()
+		default:
+			var cmd tea.Cmd
+			e.input, cmd = e.input.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "esc", "o":
+		if len(e.changes(known)) > 0 && key != "esc" {
+			m.addLogEntry("Unsent config changes; press Esc to discard them", true)
+			return m, nil
+		}
+		m.configEditor = nil
+	case "up", "k", "shift+tab":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case "down", "j", "tab":
+		if e.cursor < len(e.fields)-1 {
+			e.cursor++
+		}
+	case "enter":
+		if len(e.fields) == 0 {
+			return m, nil
+		}
+		field := e.fields[e.cursor]
+		e.input.SetValue("")
+		if v := e.value(known, field.configKey); v != nil {
+			e.input.SetValue(formatConfigValue(v))
+		}
+		e.input.CursorEnd()
+		e.input.Focus()
+		e.editing = true
+	case "backspace", "delete", "u":
+		delete(e.edits, e.fields[e.cursor].configKey)
+	case "s":
+		if len(e.changes(known)) == 0 {
+			m.addLogEntry("No config changes to send", false)
+			return m, nil
+		}
+		e.review = true
+	}
+	return m, nil
+}
+
+// sendConfig validates and sends the edited config messages, then records
+// the sent values as known
+func (m *controlModel) sendConfig() (tea.Model, tea.Cmd) {
+	if m.connectionLost {
+		m.addLogEntry("Cannot send command: connection lost", true)
+		return m, nil
+	}
+	e := m.configEditor
+	dev := m.lookupDevice(e.address)
+	if dev == nil {
+		return m, nil
+	}
+	if m.estopLocked(dev) {
+		m.addLogEntry("Config not sent: device is emergency-stopped", true)
+		return m, nil
+	}
+
+	known := m.knownConfig(e.address)
+	changes := e.changes(known)
+	msgs, err := e.messages(known, changes)
+	if err != nil {
+		m.addLogEntry(fmt.Sprintf("Config not sent: %v", err), true)
+		return m, nil
+	}
+	// Validate all before sending any, so a bad value doesn't leave the
+	// device half-configured
+	for _, msg := range msgs {
+		if err := validateCommand(dev, msg.packet); err != nil {
+			m.addLogEntry(fmt.Sprintf("Config not sent: %v", err), true)
+			return m, nil
+		}
+	}
+
+	for _, msg := range msgs {
+		if err := m.sendCommand(dev, msg.packet); err != nil {
+			m.addLogEntry(err.Error(), true)
+			return m, nil
+		}
+		for _, c := range msg.changes {
+			known[c.field.configKey] = c.newVal
+			delete(e.edits, c.field.configKey)
+		}
+		m.addLogEntry(fmt.Sprintf("Sent %s to %s", msg, formatAddress(e.address)), false)
+	}
+	return m, nil
+}
+
+// renderConfigEditor renders the config screen in the control panel
+func (m controlModel) renderConfigEditor(statsLabelStyle, statsValueStyle, warningStyle, headerStyle lipgloss.Style) string {
+	e := m.configEditor
+	known := m.deviceConfigs[e.address]
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("%s Heater %s\n", statsLabelStyle.Render("Config:"), formatAddress(e.address)))
+
+	if e.review {
+		s.WriteString(warningStyle.Bold(true).Render("Send these changes?"))
+		s.WriteString("\n\n")
+		for _, c := range e.changes(known) {
+			s.WriteString(fmt.Sprintf("  %-16s %-14s %s -> %s\n", c.field.title, c.field.schema.Name,
+				formatConfigValue(c.old), statsValueStyle.Render(formatConfigValue(c.newVal))))
+		}
+		s.WriteString("\n")
+		s.WriteString(warningStyle.Render("[y] Send   [n] Back to editor"))
+		return s.String()
+	}
+
+	s.WriteString(headerStyle.Render("Values sent this session or reported by the device; - = unknown"))
+	s.WriteString("\n")
+	title := ""
+	for i, f := range e.fields {
+		if f.title != title {
+			title = f.title
+			s.WriteString("\n" + statsLabelStyle.Render(title) + "\n")
+		}
+		marker := "  "
+		if i == e.cursor {
+			marker = "> "
+		}
+		var value string
+		switch {
+		case i == e.cursor && e.editing:
+			value = e.input.View()
+		default:
+			edited, ok := e.edits[f.configKey]
+			if ok {
+				value = statsValueStyle.Render(formatConfigValue(edited)) + warningStyle.Render(" *")
+			} else {
+				value = formatConfigValue(known[f.configKey])
+			}
+		}
+		line := fmt.Sprintf("%s%-14s %s", marker, f.schema.Name, value)
+		if i == e.cursor && !e.editing {
+			line = lipgloss.NewStyle().Bold(true).Render(line)
+		}
+		s.WriteString(line + "\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(headerStyle.Render("Enter edit  u undo  s review & send  Esc close"))
+	return s.String()
+}
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	pumpRateStep      = 10    // +/- step of the pump rate input (ms)
+	defaultGlowTime   = 10000 // Glow plug duration offered (ms)
+	defaultTargetTemp = 180.0 // Target temperature offered (°C)
+)
+
+// pumpControlState reports whether a state takes PUMP_COMMAND: fuel only
+// flows from PREHEAT 2 on
+func pumpControlState(state uint64) bool {
+	return state == uint64(fusain.SysStatePreheatStage2) || state == uint64(fusain.SysStateHeating)
+}
+
+// glowControlState reports whether a state takes GLOW_COMMAND: IDLE, to
+// test the glow plug, and the preheat stages, to help ignition
+func glowControlState(state uint64) bool {
+	switch fusain.SysState(state) {
+	case fusain.SysStateIdle, fusain.SysStatePreheat, fusain.SysStatePreheatStage2:
+		return true
+	}
+	return false
+}
+
+// tempTargetState reports whether a state takes a new target temperature:
+// any state the heater is being run in
+func tempTargetState(state uint64) bool {
+	return state == uint64(fusain.SysStateIdle) || state == uint64(fusain.SysStateBlowing) || isHeatState(state)
+}
+
+// hasComponent reports whether a device has a component of the given
+// count; devices that haven't announced their capabilities are assumed to
+// have one
+func (d device) hasComponent(count int) bool {
+	return !d.hasCaps || count > 0
+}
+
+// newNumberInput returns a text input for a numeric control
+func newNumberInput(placeholder string, charLimit int) textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = placeholder
+	ti.CharLimit = charLimit
+	ti.Width = 10
+	return ti
+}
+
+// inputValue returns an input's value, or its placeholder when empty
+func inputValue(ti textinput.Model) string {
+	if ti.Value() == "" {
+		return ti.Placeholder
+	}
+	return ti.Value()
+}
+
+// adjustPumpRate steps the pump rate input, like a slider
+func (m *controlModel) adjustPumpRate(delta int64) {
+	rate, err := strconv.ParseInt(inputValue(m.pumpInput), 10, 64)
+	if err != nil {
+		return
+	}
+	rate += delta
+	if rate < pumpRateStep {
+		rate = pumpRateStep
+	}
+	m.pumpInput.SetValue(strconv.FormatInt(rate, 10))
+	m.pumpInput.CursorEnd()
+}
+
+// sendPumpCommand sets the pump rate of a heater that is burning fuel
+func (m *controlModel) sendPumpCommand() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil {
+		return m, nil
+	}
+
+	rateStr := inputValue(m.pumpInput)
+	rate, err := strconv.ParseInt(rateStr, 10, 32)
+	if err != nil || rate <= 0 {
+		m.addLogEntry(fmt.Sprintf("Invalid pump rate: %s", rateStr), true)
+		return m, nil
+	}
+
+	packet := fusain.NewPumpCommand(selected.address, 0, int32(rate))
+	if err := m.sendCommand(selected, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
+		return m, nil
+	}
+	if run := m.heatRuns[selected.address]; run != nil {
+		run.pumpRate = rate
+	}
+
+	m.addLogEntry(fmt.Sprintf("Sent PUMP command (rate=%d ms) to %s", rate, formatAddress(selected.address)), false)
+	return m, nil
+}
+
+// sendGlowCommand lights the glow plug for the entered duration; 0
+// extinguishes it
+func (m *controlModel) sendGlowCommand() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil {
+		return m, nil
+	}
+
+	durationStr := inputValue(m.glowInput)
+	duration, err := strconv.ParseInt(durationStr, 10, 32)
+	if err != nil || duration < 0 {
+		m.addLogEntry(fmt.Sprintf("Invalid glow duration: %s", durationStr), true)
+		return m, nil
+	}
+
+	packet := fusain.NewGlowCommand(selected.address, 0, int32(duration))
+	if err := m.sendCommand(selected, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
+		return m, nil
+	}
+
+	if duration == 0 {
+		m.addLogEntry(fmt.Sprintf("Sent GLOW command (extinguish) to %s", formatAddress(selected.address)), false)
+	} else {
+		m.addLogEntry(fmt.Sprintf("Sent GLOW command (%d ms) to %s", duration, formatAddress(selected.address)), false)
+	}
+	return m, nil
+}
+
+// sendTargetTempCommand sets thermometer 0's target temperature, entered in
+// the display units
+func (m *controlModel) sendTargetTempCommand() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil {
+		return m, nil
+	}
+
+	tempStr := inputValue(m.tempInput)
+	value, err := strconv.ParseFloat(tempStr, 64)
+	if err != nil {
+		m.addLogEntry(fmt.Sprintf("Invalid target temperature: %s", tempStr), true)
+		return m, nil
+	}
+
+	target := fusain.ToCelsius(value, displayUnits)
+	packet := fusain.NewTargetTempCommand(selected.address, 0, target)
+	if err := m.sendCommand(selected, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
+		return m, nil
+	}
+
+	m.addLogEntry(fmt.Sprintf("Sent SET_TARGET_TEMP (%s) to %s", formatTemperature(target), formatAddress(selected.address)), false)
+	return m, nil
+}
+
+// renderInputField renders a text input, as plain text when not focused
+func renderInputField(ti textinput.Model, focused bool) string {
+	if focused {
+		return ti.View()
+	}
+	return fmt.Sprintf("[%s]", inputValue(ti))
+}
+
+// renderDirectControls renders the pump, glow plug and target temperature
+// inputs the selected device's state offers. The IDLE panel's pump rate
+// input starts HEAT, so it's only rendered here while fuel flows.
+func (m controlModel) renderDirectControls(dev *device, statsLabelStyle, headerStyle lipgloss.Style) string {
+	var lines []string
+	if pumpControlState(dev.state) && m.focusAvailable(focusPumpInput, dev) {
+		lines = append(lines, statsLabelStyle.Render("Pump rate (ms): ")+
+			renderInputField(m.pumpInput, m.focusedField == focusPumpInput)+
+			headerStyle.Render(fmt.Sprintf("  +/- %d", pumpRateStep)))
+	}
+	if m.focusAvailable(focusGlowInput, dev) {
+		lines = append(lines, statsLabelStyle.Render("Glow plug (ms): ")+
+			renderInputField(m.glowInput, m.focusedField == focusGlowInput)+
+			headerStyle.Render("  0 extinguishes"))
+	}
+	if m.focusAvailable(focusTempInput, dev) {
+		label := fmt.Sprintf("Target temp (%s): ", temperatureUnit())
+		line := statsLabelStyle.Render(label) + renderInputField(m.tempInput, m.focusedField == focusTempInput)
+		if charts := m.charts[dev.address]; charts != nil {
+			if sample, ok := charts.temp.last(); ok && sample.hasTarget {
+				line += headerStyle.Render("  now " + formatTemperature(sample.target))
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n" + strings.Join(lines, "\n")
+}
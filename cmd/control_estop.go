@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// requestEstop opens the emergency-stop confirmation prompt
+func (m *controlModel) requestEstop() (tea.Model, tea.Cmd) {
+	if len(m.estopTargets(true)) == 0 {
+		m.addLogEntry("No devices to emergency-stop", true)
+		return m, nil
+	}
+	m.heatConfirm = nil
+	m.estopConfirm = true
+	return m, nil
+}
+
+// handleEstopConfirmKey answers the emergency-stop prompt: the hotkey again
+// or 'y' stops the selected device, 'a' every device
+func (m *controlModel) handleEstopConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	switch {
+	case key == "y" || key == "Y" || keyAction(key) == actionEstop:
+		if m.getSelectedDevice() != nil {
+			m.estopConfirm = false
+			return m.sendEstop(false)
+		}
+	case key == "a" || key == "A":
+		m.estopConfirm = false
+		return m.sendEstop(true)
+	case key == "n" || key == "N" || key == "esc":
+		m.estopConfirm = false
+		m.addLogEntry("Emergency stop cancelled", false)
+	case key == "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// estopTargets returns the selected device, or every known device
+func (m *controlModel) estopTargets(all bool) []*device {
+	var targets []*device
+	if !all {
+		if selected := m.getSelectedDevice(); selected != nil {
+			targets = append(targets, selected)
+		}
+		return targets
+	}
+	if m.discoveryDone {
+		for i := range m.devices {
+			targets = append(targets, &m.devices[i])
+		}
+		return targets
+	}
+	for _, dev := range m.discoveryDevices {
+		targets = append(targets, dev)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].address < targets[j].address })
+	return targets
+}
+
+// sendEstop sends STATE_COMMAND EMERGENCY and locks each device's controls
+// until it recovers from E_STOP
+func (m *controlModel) sendEstop(all bool) (tea.Model, tea.Cmd) {
+	for _, dev := range m.estopTargets(all) {
+		packet := fusain.NewStateCommand(dev.address, uint8(fusain.ModeEmergency), nil)
+		if err := m.sendCommand(dev, packet); err != nil {
+			m.addEmergencyLogEntry(fmt.Sprintf("EMERGENCY STOP to %s failed: %v", formatAddress(dev.address), err))
+			continue
+		}
+		m.estopPending[dev.address] = time.Now()
+	}
+	m.checkFocus()
+	return m, nil
+}
+
+// estopLocked reports whether a device's controls are locked: it is in
+// E_STOP, or an emergency stop was sent and it hasn't entered E_STOP yet
+func (m *controlModel) estopLocked(dev *device) bool {
+	if dev.state == uint64(fusain.SysStateEstop) {
+		return true
+	}
+	_, pending := m.estopPending[dev.address]
+	return pending
+}
+
+// lockedDevices returns the addresses of every locked device
+func (m *controlModel) lockedDevices() []uint64 {
+	var locked []uint64
+	for i := range m.devices {
+		if m.estopLocked(&m.devices[i]) {
+			locked = append(locked, m.devices[i].address)
+		}
+	}
+	return locked
+}
+
+// trackEstop follows a device's state changes into and out of E_STOP
+func (m *controlModel) trackEstop(address uint64, oldState, stateName string) {
+	if stateName == "E_STOP" {
+		delete(m.estopPending, address)
+		return
+	}
+	if oldState == "E_STOP" {
+		m.addLogEntry(fmt.Sprintf("Device %s recovered from E_STOP (%s); controls unlocked", formatAddress(address), stateName), false)
+	}
+}
+
+// estopRejected releases the lock of a device that rejected its emergency
+// stop, so the UI doesn't wait for an E_STOP that won't come
+func (m *controlModel) estopRejected(address uint64) {
+	if _, pending := m.estopPending[address]; pending {
+		delete(m.estopPending, address)
+		m.addEmergencyLogEntry(fmt.Sprintf("EMERGENCY STOP rejected by %s", formatAddress(address)))
+	}
+}
+
+// renderEstopConfirm renders the emergency-stop prompt
+func (m controlModel) renderEstopConfirm(boxStyle lipgloss.Style) string {
+	var s strings.Builder
+	s.WriteString(emergencyStyle.Render(" EMERGENCY STOP "))
+	s.WriteString("\n\n")
+	if selected := m.getSelectedDevice(); selected != nil {
+		s.WriteString(fmt.Sprintf("[y] Stop Heater %s   ", formatAddress(selected.address)))
+	}
+	s.WriteString(fmt.Sprintf("[a] Stop all %d heaters   [n] Cancel", len(m.estopTargets(true))))
+	return boxStyle.BorderForeground(theme.bad).Width(m.width - 4).Render(s.String())
+}
+
+// renderEstopPanel replaces the control panel of a locked device
+func (m controlModel) renderEstopPanel(dev *device, headerStyle, buttonStyle, focusedButtonStyle lipgloss.Style) string {
+	var s strings.Builder
+	s.WriteString(emergencyStyle.Render(" EMERGENCY STOP "))
+	s.WriteString("\n\n")
+	if dev.state != uint64(fusain.SysStateEstop) {
+		s.WriteString(fmt.Sprintf("Sent %s ago; waiting for E_STOP (now %s)\n",
+			formatElapsed(time.Since(m.estopPending[dev.address])), dev.stateName))
+		s.WriteString(headerStyle.Render("Controls are locked until the device recovers"))
+		return s.String()
+	}
+	s.WriteString(fmt.Sprintf("Heater %s is in E_STOP\n", formatAddress(dev.address)))
+	s.WriteString(headerStyle.Render("Controls are locked until the device recovers"))
+	s.WriteString("\n\n")
+
+	btnText := "[ Reset to IDLE ]"
+	if m.focusedField == focusButton {
+		s.WriteString(focusedButtonStyle.Render(btnText))
+	} else {
+		s.WriteString(buttonStyle.Render(btnText))
+	}
+	return s.String()
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// controlExportScript holds the --export-script flag value
+var controlExportScript string
+
+// commandAudit is the log of commands a control session sent. The TUI
+// records to it and the shutdown hook exports it, so it is locked.
+type commandAudit struct {
+	mu       sync.Mutex
+	started  time.Time
+	connInfo string
+	commands []sentCommand
+}
+
+func newCommandAudit(connInfo string) *commandAudit {
+	return &commandAudit{started: time.Now(), connInfo: connInfo}
+}
+
+// record adds a sent command
+func (a *commandAudit) record(at time.Time, packet *fusain.Packet) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.commands = append(a.commands, sentCommand{at: at, packet: packet})
+}
+
+// export writes the sent commands to path as a script for the run
+// command, returning how many it wrote
+func (a *commandAudit) export(path string) (int, error) {
+	a.mu.Lock()
+	commands := append([]sentCommand(nil), a.commands...)
+	a.mu.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	header := []string{
+		fmt.Sprintf("Control session started %s, exported %s", a.started.Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05")),
+		"Connection: " + a.connInfo,
+		"Replay with: heliostat run [--port PORT | --url URL | --tcp HOST:PORT] " + path,
+	}
+	if err := writeRunScript(f, header, commands); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(commands), f.Close()
+}
+
+// exportPath is where the export action writes: --export-script, or a
+// file named after the session's start in the current directory
+func (a *commandAudit) exportPath() string {
+	if controlExportScript != "" {
+		return controlExportScript
+	}
+	return a.started.Format("heliostat-20060102-150405.run")
+}
+
+// exportScript handles the export action
+func (m *controlModel) exportScript() {
+	path := m.audit.exportPath()
+	n, err := m.audit.export(path)
+	if err != nil {
+		m.addLogEntry(fmt.Sprintf("Cannot export script: %v", err), true)
+		return
+	}
+	m.addLogEntry(fmt.Sprintf("Exported %d commands to %s", n, path), false)
+}
@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultPumpRate is the pump rate offered for HEAT (ms between pulses)
+const defaultPumpRate = 200
+
+// heatStages are the states a heater passes through on its way to HEATING
+var heatStages = []fusain.SysState{
+	fusain.SysStatePreheat,
+	fusain.SysStatePreheatStage2,
+	fusain.SysStateHeating,
+}
+
+// heatRequest is a HEAT command waiting for confirmation
+type heatRequest struct {
+	address  uint64
+	pumpRate int64
+}
+
+// heatRun follows a device from a HEAT command through PREHEAT to HEATING
+type heatRun struct {
+	pumpRate   int64
+	started    time.Time
+	stage      fusain.SysState // Last heat stage reached (0 = none yet)
+	stageStart time.Time
+}
+
+// isHeatState reports whether a device state is part of a heat cycle
+func isHeatState(state uint64) bool {
+	for _, stage := range heatStages {
+		if state == uint64(stage) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestHeat validates the pump rate and asks for confirmation before
+// starting HEAT on the selected device
+func (m *controlModel) requestHeat() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil {
+		return m, nil
+	}
+
+	rateStr := m.pumpInput.Value()
+	if rateStr == "" {
+		rateStr = m.pumpInput.Placeholder
+	}
+	rate, err := strconv.ParseInt(rateStr, 10, 64)
+	if err != nil || rate <= 0 {
+		m.addLogEntry(fmt.Sprintf("Invalid pump rate: %s", rateStr), true)
+		return m, nil
+	}
+
+	// Catch policy and limit violations before asking
+	if err := validateCommand(selected, fusain.NewStateCommand(selected.address, uint8(fusain.ModeHeat), &rate)); err != nil {
+		m.addLogEntry(err.Error(), true)
+		return m, nil
+	}
+
+	m.heatConfirm = &heatRequest{address: selected.address, pumpRate: rate}
+	return m, nil
+}
+
+// handleHeatConfirmKey answers the HEAT confirmation dialog
+func (m *controlModel) handleHeatConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		req := m.heatConfirm
+		m.heatConfirm = nil
+		return m.sendHeatCommand(req)
+	case "n", "N", "esc":
+		m.heatConfirm = nil
+		m.addLogEntry("HEAT cancelled", false)
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *controlModel) sendHeatCommand(req *heatRequest) (tea.Model, tea.Cmd) {
+	dev := m.lookupDevice(req.address)
+	if dev == nil {
+		return m, nil
+	}
+	if dev.state != uint64(fusain.SysStateIdle) {
+		m.addLogEntry(fmt.Sprintf("HEAT not sent: %s is no longer IDLE", formatAddress(req.address)), true)
+		return m, nil
+	}
+
+	rate := req.pumpRate
+	packet := fusain.NewStateCommand(req.address, uint8(fusain.ModeHeat), &rate)
+	if err := m.sendCommand(dev, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
+		return m, nil
+	}
+
+	now := time.Now()
+	m.heatRuns[req.address] = &heatRun{pumpRate: rate, started: now, stageStart: now}
+	m.focusedField = focusButton // The abort button
+	m.pumpInput.Blur()
+	m.addLogEntry(fmt.Sprintf("Sent HEAT command (pump rate=%d ms) to %s", rate, formatAddress(req.address)), false)
+	return m, nil
+}
+
+// abortHeat returns a heating device to IDLE, which shuts the burner down
+// through COOLING
+func (m *controlModel) abortHeat() (tea.Model, tea.Cmd) {
+	selected := m.getSelectedDevice()
+	if selected == nil || !isHeatState(selected.state) || m.estopLocked(selected) {
+		return m, nil
+	}
+
+	packet := fusain.NewStateCommand(selected.address, uint8(fusain.ModeIdle), nil)
+	if err := m.sendCommand(selected, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
+		return m, nil
+	}
+	m.addLogEntry(fmt.Sprintf("ABORT: sent IDLE to %s", formatAddress(selected.address)), true)
+	return m, nil
+}
+
+// trackHeatRun follows a device's state changes through a heat cycle,
+// logging each stage reached and how the run ended
+func (m *controlModel) trackHeatRun(address uint64, state uint64, stateName string) {
+	run := m.heatRuns[address]
+	if run == nil {
+		if !isHeatState(state) {
+			return
+		}
+		// Heat started elsewhere; follow it from here
+		run = &heatRun{started: time.Now()}
+		m.heatRuns[address] = run
+	}
+
+	now := time.Now()
+	switch {
+	case isHeatState(state):
+		if fusain.SysState(state) == run.stage {
+			return
+		}
+		run.stage = fusain.SysState(state)
+		run.stageStart = now
+		if run.stage == fusain.SysStateHeating {
+			m.addLogEntry(fmt.Sprintf("Device %s reached HEATING after %s", formatAddress(address), formatElapsed(now.Sub(run.started))), false)
+		}
+	case state == uint64(fusain.SysStateCooling):
+		// Still winding down; the run ends in IDLE
+	case state == uint64(fusain.SysStateIdle) && run.stage == 0:
+		// HEAT not picked up yet
+	default:
+		m.addLogEntry(fmt.Sprintf("Device %s heat run ended in %s after %s", formatAddress(address), stateName, formatElapsed(now.Sub(run.started))),
+			state == uint64(fusain.SysStateError) || state == uint64(fusain.SysStateEstop))
+		delete(m.heatRuns, address)
+	}
+}
+
+// formatElapsed formats a duration as m:ss
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// renderHeatConfirm renders the HEAT confirmation dialog
+func (m controlModel) renderHeatConfirm(statsLabelStyle, warningStyle lipgloss.Style) string {
+	req := m.heatConfirm
+	var s strings.Builder
+	s.WriteString(warningStyle.Bold(true).Render("Start HEAT?"))
+	s.WriteString("\n\n")
+	s.WriteString(fmt.Sprintf("%s Heater %s\n", statsLabelStyle.Render("Device:"), formatAddress(req.address)))
+	s.WriteString(fmt.Sprintf("%s %d ms\n\n", statsLabelStyle.Render("Pump rate:"), req.pumpRate))
+	s.WriteString("The heater will preheat, ignite and burn fuel until stopped.\n\n")
+	s.WriteString(warningStyle.Render("[y] Start heat   [n] Cancel"))
+	return s.String()
+}
+
+// renderHeatProgress renders the stages of a heat cycle with the current
+// one highlighted, timings and the abort button
+func (m controlModel) renderHeatProgress(dev *device, statsLabelStyle, statsValueStyle, headerStyle lipgloss.Style) string {
+	var s strings.Builder
+	run := m.heatRuns[dev.address]
+
+	stageNames := map[fusain.SysState]string{
+		fusain.SysStatePreheat:       "PREHEAT",
+		fusain.SysStatePreheatStage2: "PREHEAT 2",
+		fusain.SysStateHeating:       "HEATING",
+	}
+	passedStyle := statsValueStyle
+	currentStyle := lipgloss.NewStyle().Bold(true).Reverse(true)
+	reached := true
+	var stages []string
+	for _, stage := range heatStages {
+		name := stageNames[stage]
+		switch {
+		case dev.state == uint64(stage):
+			stages = append(stages, currentStyle.Render(" "+name+" "))
+			reached = false
+		case reached:
+			stages = append(stages, passedStyle.Render(name))
+		default:
+			stages = append(stages, headerStyle.Render(name))
+		}
+	}
+	s.WriteString(statsLabelStyle.Render("Heat: "))
+	s.WriteString(strings.Join(stages, headerStyle.Render(" > ")))
+	s.WriteString("\n")
+
+	if run != nil {
+		now := time.Now()
+		s.WriteString(fmt.Sprintf("%s %s  %s %s",
+			statsLabelStyle.Render("In stage:"), statsValueStyle.Render(formatElapsed(now.Sub(run.stageStart))),
+			statsLabelStyle.Render("Total:"), statsValueStyle.Render(formatElapsed(now.Sub(run.started)))))
+		if run.pumpRate > 0 {
+			s.WriteString(fmt.Sprintf("  %s %s", statsLabelStyle.Render("Pump:"), statsValueStyle.Render(fmt.Sprintf("%d ms", run.pumpRate))))
+		}
+		s.WriteString("\n")
+	}
+	if telem := m.lastTelemetry[dev.address]; telem != nil && len(telem.temperatures) > 0 {
+		s.WriteString(fmt.Sprintf("%s %s\n", statsLabelStyle.Render("Temp:"), statsValueStyle.Render(formatTemperature(telem.temperatures[0]))))
+	}
+	s.WriteString("\n")
+
+	abortStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.alertFg).
+		Background(theme.bad).
+		Padding(0, 2)
+	if m.focusedField == focusButton {
+		abortStyle = abortStyle.Reverse(true)
+	}
+	s.WriteString(abortStyle.Render("[ ABORT HEAT (x) ]"))
+	return s.String()
+}
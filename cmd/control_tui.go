@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Thermoquad/heliostat/pkg/events"
 	"github.com/Thermoquad/heliostat/pkg/fusain"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -23,8 +24,6 @@ import (
 const (
 	discoveryTimeoutSeconds = 3 // Discovery ends N seconds after last device seen
 	pingIntervalSeconds     = 5 // Send ping requests every N seconds
-	maxRPM                  = 6000
-	minRPM                  = 0
 )
 
 // Focus states
@@ -32,29 +31,92 @@ const (
 	focusDeviceList = iota
 	focusRPMInput
 	focusButton
+	focusPumpInput
+	focusHeatButton
+	focusGlowInput
+	focusTempInput
 )
 
 //////////////////////////////////////////////////////////////
 // Types
 //////////////////////////////////////////////////////////////
 
+// deviceCapabilities caches the component counts from DEVICE_ANNOUNCE
+type deviceCapabilities struct {
+	motorCount       uint64
+	thermometerCount uint64
+	pumpCount        uint64
+	glowCount        uint64
+}
+
 // device represents a discovered Helios heater
 type device struct {
 	address   uint64
 	state     uint64
 	stateName string
 	lastSeen  time.Time
+
+	// Capabilities from DEVICE_ANNOUNCE (valid only when hasCaps is set)
+	caps    deviceCapabilities
+	hasCaps bool
 }
 
 // Implement list.Item interface
-func (d device) Title() string       { return fmt.Sprintf("Heater %016X", d.address) }
-func (d device) Description() string { return d.stateName }
-func (d device) FilterValue() string { return fmt.Sprintf("%X", d.address) }
+func (d device) FilterValue() string { return fmt.Sprintf("%X %s", d.address, deviceAlias(d.address)) }
+
+// Title is the device's alias, or its address if it has none
+func (d device) Title() string {
+	if name := deviceAlias(d.address); name != "" {
+		return name
+	}
+	return fmt.Sprintf("Heater %016X", d.address)
+}
+
+// Description is the device's state, after its address if the title is
+// an alias
+func (d device) Description() string {
+	state := d.stateName
+	if !d.hasCaps {
+		state += " (capabilities unknown)"
+	}
+	if deviceAlias(d.address) != "" {
+		return fmt.Sprintf("%016X %s", d.address, state)
+	}
+	return state
+}
+
+// HasCapabilities reports whether the device has announced its capabilities
+func (d device) HasCapabilities() bool { return d.hasCaps }
+
+// MotorCount returns the number of motors announced by the device
+func (d device) MotorCount() int { return int(d.caps.motorCount) }
+
+// ThermometerCount returns the number of thermometers announced by the device
+func (d device) ThermometerCount() int { return int(d.caps.thermometerCount) }
+
+// PumpCount returns the number of pumps announced by the device
+func (d device) PumpCount() int { return int(d.caps.pumpCount) }
+
+// GlowCount returns the number of glow plugs announced by the device
+func (d device) GlowCount() int { return int(d.caps.glowCount) }
+
+// checkIndex returns an error if the device has announced its capabilities
+// and idx is not a valid index for a component with the given count.
+// Devices without announced capabilities accept any non-negative index.
+func (d device) checkIndex(component string, idx int64, count int) error {
+	if idx < 0 {
+		return fmt.Errorf("invalid %s index %d", component, idx)
+	}
+	if d.hasCaps && idx >= int64(count) {
+		return fmt.Errorf("%s %d does not exist on %s (device has %d)", component, idx, formatAddress(d.address), count)
+	}
+	return nil
+}
 
 // controlModel is the Bubble Tea model for the control TUI
 type controlModel struct {
-	// Connection manager (for sending commands and reconnection)
-	connMgr  *connectionManager
+	// Connection for sending commands; it reconnects itself
+	conn     Connection
 	connInfo string
 
 	// Device tracking
@@ -72,9 +134,33 @@ type controlModel struct {
 	maxLogEntries int
 	lastTelemetry map[uint64]*telemetryData // Telemetry per device address
 
+	// Telemetry charts per device address
+	charts      map[uint64]*deviceCharts
+	chartWindow time.Duration
+	hideCharts  bool
+
 	// Control
 	rpmInput     textinput.Model
+	pumpInput    textinput.Model
+	glowInput    textinput.Model
+	tempInput    textinput.Model
 	focusedField int
+	transactor   *transactor
+	audit        *commandAudit // Commands sent, for --export-script and 'w'
+
+	// Heat workflow
+	heatConfirm *heatRequest        // HEAT awaiting confirmation
+	heatRuns    map[uint64]*heatRun // Heat cycles per device address
+
+	// Device config editor
+	configEditor  *configEditor                        // Open config screen
+	deviceConfigs map[uint64]map[configKey]interface{} // Known config values per device
+
+	aliasEditor *aliasEditor // Open device naming prompt
+
+	// Emergency stop
+	estopConfirm bool                 // Prompt open
+	estopPending map[uint64]time.Time // Sent, device not in E_STOP yet
 
 	// UI state
 	width          int
@@ -87,6 +173,9 @@ type controlModel struct {
 	lastPingTime    time.Time
 	routerUptime    uint64 // Router uptime from stateless address ping responses
 	hasRouterUptime bool
+	pingSent        map[uint64]time.Time // Outstanding pings, for link RTT
+
+	link *fusain.LinkMonitor
 }
 
 //////////////////////////////////////////////////////////////
@@ -95,40 +184,29 @@ type controlModel struct {
 
 type controlTickMsg time.Time
 
-type controlDataMsg struct {
-	packet           *fusain.Packet
-	decodeErr        error
-	validationErrors []fusain.ValidationError
-}
-
-type controlSyncMsg struct {
-	invalidBytes int
-}
-
-type controlBatchMsg struct {
-	messages []controlDataMsg
-	syncMsg  *controlSyncMsg
-}
-
 type discoveryCompleteMsg struct{}
 
-type connectionLostMsg struct{}
-
-type reconnectedMsg struct {
-	connInfo string
-}
-
 //////////////////////////////////////////////////////////////
 // Model Initialization
 //////////////////////////////////////////////////////////////
 
-func initialControlModel(connMgr *connectionManager, connInfo string) controlModel {
+func initialControlModel(conn Connection, connInfo string) controlModel {
 	// Initialize text input for RPM
 	ti := textinput.New()
 	ti.Placeholder = "1500"
 	ti.CharLimit = 5
 	ti.Width = 10
 
+	// Text input for the HEAT pump rate
+	pi := textinput.New()
+	pi.Placeholder = strconv.Itoa(defaultPumpRate)
+	pi.CharLimit = 5
+	pi.Width = 10
+
+	// Text inputs for the direct glow plug and target temperature controls
+	gi := newNumberInput(strconv.Itoa(defaultGlowTime), 6)
+	tti := newNumberInput(strconv.FormatFloat(fusain.ConvertTemperature(defaultTargetTemp, displayUnits), 'f', -1, 64), 6)
+
 	// Initialize device list with empty items
 	delegate := list.NewDefaultDelegate()
 	delegate.ShowDescription = true
@@ -140,7 +218,7 @@ func initialControlModel(connMgr *connectionManager, connInfo string) controlMod
 	deviceList.SetFilteringEnabled(false)
 
 	return controlModel{
-		connMgr:          connMgr,
+		conn:             conn,
 		connInfo:         connInfo,
 		devices:          make([]device, 0),
 		deviceList:       deviceList,
@@ -150,8 +228,20 @@ func initialControlModel(connMgr *connectionManager, connInfo string) controlMod
 		errorLog:         make([]errorLogEntry, 0),
 		maxLogEntries:    100,
 		lastTelemetry:    make(map[uint64]*telemetryData),
+		charts:           make(map[uint64]*deviceCharts),
+		chartWindow:      appConfig().TUI.chartWindow(),
 		rpmInput:         ti,
+		pumpInput:        pi,
+		glowInput:        gi,
+		tempInput:        tti,
+		heatRuns:         make(map[uint64]*heatRun),
+		deviceConfigs:    make(map[uint64]map[configKey]interface{}),
+		estopPending:     make(map[uint64]time.Time),
 		focusedField:     focusDeviceList,
+		transactor:       newTransactor(),
+		audit:            newCommandAudit(connInfo),
+		pingSent:         make(map[uint64]time.Time),
+		link:             fusain.NewLinkMonitor(),
 		width:            80,
 		height:           24,
 		synchronized:     false,
@@ -189,6 +279,7 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case controlTickMsg:
 		m.stats.CalculateRates()
+		m.settleCommands()
 		// Check discovery timeout
 		if !m.discoveryDone && !m.lastDeviceSeen.IsZero() {
 			if time.Since(m.lastDeviceSeen) > time.Duration(discoveryTimeoutSeconds)*time.Second {
@@ -207,46 +298,21 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, controlTickCmd()
 
-	case controlSyncMsg:
-		m.synchronized = true
-		if msg.invalidBytes > 0 {
-			m.addLogEntry(fmt.Sprintf("Synchronized after skipping %d invalid bytes", msg.invalidBytes), false)
-		} else {
-			m.addLogEntry("Synchronized", false)
-		}
-
-	case controlBatchMsg:
-		if msg.syncMsg != nil {
-			m.synchronized = true
-			if msg.syncMsg.invalidBytes > 0 {
-				m.addLogEntry(fmt.Sprintf("Synchronized after skipping %d invalid bytes", msg.syncMsg.invalidBytes), false)
-			} else {
-				m.addLogEntry("Synchronized", false)
-			}
-		}
-		for _, data := range msg.messages {
-			m.processControlData(data)
+	case eventBatchMsg:
+		for _, e := range msg.events {
+			m.processEvent(e)
 		}
+		m.checkFocus()
 
 	case discoveryCompleteMsg:
 		m.finishDiscovery()
 
-	case connectionLostMsg:
-		m.connectionLost = true
-		m.addLogEntry("Connection lost - reconnecting...", true)
-
-	case reconnectedMsg:
-		m.connectionLost = false
-		m.connInfo = msg.connInfo
-		// Reset discovery state for new discovery cycle
-		m.resetDiscovery()
-		m.addLogEntry("Reconnected - starting discovery", false)
 	}
 
 	// Update child components
 	var cmd tea.Cmd
-	if m.focusedField == focusRPMInput {
-		m.rpmInput, cmd = m.rpmInput.Update(msg)
+	if input := m.focusedInput(); input != nil {
+		*input, cmd = input.Update(msg)
 		cmds = append(cmds, cmd)
 	}
 
@@ -259,8 +325,63 @@ func (m controlModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *controlModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The emergency-stop prompt takes every key, and the hotkey works
+	// everywhere else
+	if m.estopConfirm {
+		return m.handleEstopConfirmKey(msg)
+	}
+	if keyAction(msg.String()) == actionEstop {
+		return m.requestEstop()
+	}
+
+	// The HEAT confirmation dialog takes every key
+	if m.heatConfirm != nil {
+		return m.handleHeatConfirmKey(msg)
+	}
+
+	// The config screen and naming prompt take every key
+	if m.configEditor != nil {
+		return m.handleConfigKey(msg)
+	}
+	if m.aliasEditor != nil {
+		return m.handleAliasKey(msg)
+	}
+
+	switch keyAction(msg.String()) {
+	case actionQuit:
+		m.quitting = true
+		return m, tea.Quit
+
+	case actionCharts:
+		if !m.inputFocused() {
+			m.hideCharts = !m.hideCharts
+			return m, nil
+		}
+
+	case actionAbort:
+		if !m.inputFocused() {
+			return m.abortHeat()
+		}
+
+	case actionConfig:
+		if !m.inputFocused() {
+			return m.openConfigEditor()
+		}
+
+	case actionName:
+		if !m.inputFocused() {
+			return m.openAliasEditor()
+		}
+
+	case actionExport:
+		if !m.inputFocused() {
+			m.exportScript()
+			return m, nil
+		}
+	}
+
 	switch msg.String() {
-	case "q", "ctrl+c":
+	case "ctrl+c":
 		m.quitting = true
 		return m, tea.Quit
 
@@ -275,6 +396,16 @@ func (m *controlModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m.handleEnter()
 		}
 
+	case "+", "-":
+		if m.focusedField == focusPumpInput {
+			if msg.String() == "+" {
+				m.adjustPumpRate(pumpRateStep)
+			} else {
+				m.adjustPumpRate(-pumpRateStep)
+			}
+			return m, nil
+		}
+
 	case "up", "k":
 		if m.focusedField == focusDeviceList {
 			m.deviceList, _ = m.deviceList.Update(msg)
@@ -287,15 +418,35 @@ func (m *controlModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 
 	// Pass through to focused component
-	if m.focusedField == focusRPMInput {
+	if input := m.focusedInput(); input != nil {
 		var cmd tea.Cmd
-		m.rpmInput, cmd = m.rpmInput.Update(msg)
+		*input, cmd = input.Update(msg)
 		return m, cmd
 	}
 
 	return m, nil
 }
 
+// focusedInput returns the text input that has the focus, or nil
+func (m *controlModel) focusedInput() *textinput.Model {
+	switch m.focusedField {
+	case focusRPMInput:
+		return &m.rpmInput
+	case focusPumpInput:
+		return &m.pumpInput
+	case focusGlowInput:
+		return &m.glowInput
+	case focusTempInput:
+		return &m.tempInput
+	}
+	return nil
+}
+
+// inputFocused reports whether a text input has the focus
+func (m *controlModel) inputFocused() bool {
+	return m.focusedInput() != nil
+}
+
 func (m *controlModel) handleMouseMsg(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	if msg.Action != tea.MouseActionRelease || msg.Button != tea.MouseButtonLeft {
 		return m, nil
@@ -317,30 +468,68 @@ func (m *controlModel) cycleFocus(delta int) *controlModel {
 		return m
 	}
 
-	// Determine max focus based on state
-	maxFocus := focusButton
 	selected := m.getSelectedDevice()
 	if selected == nil {
 		m.focusedField = focusDeviceList
 		return m
 	}
 
-	// Cycle through focus states
-	m.focusedField = (m.focusedField + delta + maxFocus + 1) % (maxFocus + 1)
-
-	// Skip RPM input if not in IDLE state
-	if m.focusedField == focusRPMInput && selected.state != uint64(fusain.SysStateIdle) {
-		m.focusedField = (m.focusedField + delta + maxFocus + 1) % (maxFocus + 1)
+	// Cycle through focus states, skipping those the state doesn't offer
+	const fields = focusTempInput + 1
+	m.focusedField = (m.focusedField + delta + fields) % fields
+	for !m.focusAvailable(m.focusedField, selected) {
+		m.focusedField = (m.focusedField + delta + fields) % fields
 	}
+	m.updateInputFocus()
 
-	// Update focus state
-	if m.focusedField == focusRPMInput {
-		m.rpmInput.Focus()
-	} else {
-		m.rpmInput.Blur()
+	return m
+}
+
+// focusAvailable reports whether a focus state is offered for a device:
+// the fan and HEAT controls only in IDLE, the pump, glow plug and target
+// temperature controls in the states that take them (see control_direct.go),
+// and none after an emergency stop
+func (m *controlModel) focusAvailable(field int, dev *device) bool {
+	if field == focusDeviceList || field == focusButton {
+		return true
+	}
+	if m.estopLocked(dev) {
+		return false
+	}
+	idle := dev.state == uint64(fusain.SysStateIdle)
+	switch field {
+	case focusRPMInput, focusHeatButton:
+		return idle
+	case focusPumpInput:
+		return idle || pumpControlState(dev.state) && dev.hasComponent(dev.PumpCount())
+	case focusGlowInput:
+		return glowControlState(dev.state) && dev.hasComponent(dev.GlowCount())
+	case focusTempInput:
+		return tempTargetState(dev.state) && dev.hasComponent(dev.ThermometerCount())
+	}
+	return true
+}
+
+// checkFocus moves the focus to the button when the selected device's
+// state change took away the focused field
+func (m *controlModel) checkFocus() {
+	selected := m.getSelectedDevice()
+	if selected == nil || m.focusAvailable(m.focusedField, selected) {
+		return
 	}
+	m.focusedField = focusButton
+	m.updateInputFocus()
+}
 
-	return m
+// updateInputFocus focuses the text input matching the focus state
+func (m *controlModel) updateInputFocus() {
+	m.rpmInput.Blur()
+	m.pumpInput.Blur()
+	m.glowInput.Blur()
+	m.tempInput.Blur()
+	if input := m.focusedInput(); input != nil {
+		input.Focus()
+	}
 }
 
 func (m *controlModel) handleEnter() (tea.Model, tea.Cmd) {
@@ -355,11 +544,40 @@ func (m *controlModel) handleEnter() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	// In IDLE state: Start Fan button or RPM input triggers fan command
+	// Emergency-stopped: only Reset to IDLE, once the device is in E_STOP
+	if m.estopLocked(selected) {
+		if selected.state == uint64(fusain.SysStateEstop) && m.focusedField == focusButton {
+			return m.sendIdleCommand()
+		}
+		return m, nil
+	}
+
+	// Direct controls, in the states that offer them
+	switch m.focusedField {
+	case focusGlowInput:
+		return m.sendGlowCommand()
+	case focusTempInput:
+		return m.sendTargetTempCommand()
+	case focusPumpInput:
+		if pumpControlState(selected.state) {
+			return m.sendPumpCommand()
+		}
+	}
+
+	// In IDLE state: Start Fan button or RPM input triggers fan command,
+	// Start Heat button or pump rate input asks to confirm HEAT
 	if selected.state == uint64(fusain.SysStateIdle) {
 		if m.focusedField == focusButton || m.focusedField == focusRPMInput {
 			return m.sendFanCommand()
 		}
+		if m.focusedField == focusHeatButton || m.focusedField == focusPumpInput {
+			return m.requestHeat()
+		}
+	}
+
+	// In heat states: Abort button
+	if isHeatState(selected.state) && m.focusedField == focusButton {
+		return m.abortHeat()
 	}
 
 	// In FAN/BLOWING state: Return to Idle button
@@ -382,47 +600,48 @@ func (m controlModel) View() string {
 	// Styles
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("12")).
-		Background(lipgloss.Color("235")).
+		Foreground(theme.accent).
+		Background(theme.titleBg).
 		Padding(0, 1)
 
 	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241"))
+		Foreground(theme.muted)
 
 	statsLabelStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("12")).
+		Foreground(theme.accent).
 		Bold(true)
 
 	statsValueStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("10"))
+		Foreground(theme.good)
 
 	errorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("9")).
+		Foreground(theme.bad).
 		Bold(true)
 
 	warningStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("11"))
+		Foreground(theme.warning)
 
 	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		Border(glyphs.border).
+		BorderForeground(theme.border).
 		Padding(0, 1)
 
 	focusedBoxStyle := boxStyle.
-		BorderForeground(lipgloss.Color("12"))
+		BorderForeground(theme.accent)
 
 	buttonStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("0")).
-		Background(lipgloss.Color("12")).
+		Foreground(theme.buttonFg).
+		Background(theme.accent).
 		Padding(0, 2)
 
-	focusedButtonStyle := buttonStyle.
-		Background(lipgloss.Color("10"))
+	focusedButtonStyle := theme.highlight(buttonStyle.
+		Background(theme.good))
 
 	// Header
-	helpText := "q=quit"
+	helpText := keyHelp(actionQuit) + "=quit"
 	if m.discoveryDone {
-		helpText = "q=quit Tab=switch"
+		helpText = fmt.Sprintf("%s=quit Tab=switch %s=charts %s=name %s=script %s=e-stop",
+			keyHelp(actionQuit), keyHelp(actionCharts), keyHelp(actionName), keyHelp(actionExport), keyHelp(actionEstop))
 	}
 	s.WriteString(titleStyle.Render("HELIOSTAT CONTROL"))
 	s.WriteString(" ")
@@ -430,7 +649,15 @@ func (m controlModel) View() string {
 	if m.connectionLost {
 		connStatus = warningStyle.Render("RECONNECTING...")
 	}
-	s.WriteString(headerStyle.Render(fmt.Sprintf("| %s | %s", connStatus, helpText)))
+	s.WriteString(headerStyle.Render(fmt.Sprintf("| %s | %s | ", connStatus, helpText)))
+	s.WriteString(renderLinkQuality(m.link.Quality(time.Now())))
+	if filter := deviceFilter.summary(); filter != "" {
+		s.WriteString(headerStyle.Render(" | " + filter))
+	}
+	if locked := m.lockedDevices(); len(locked) > 0 {
+		s.WriteString(" ")
+		s.WriteString(emergencyStyle.Render(fmt.Sprintf(" E-STOP: %d locked ", len(locked))))
+	}
 	s.WriteString("\n")
 
 	// Router uptime (below header)
@@ -441,6 +668,11 @@ func (m controlModel) View() string {
 	}
 	s.WriteString("\n\n")
 
+	if m.estopConfirm {
+		s.WriteString(m.renderEstopConfirm(boxStyle))
+		s.WriteString("\n\n")
+	}
+
 	if !m.discoveryDone {
 		// Discovery mode view
 		s.WriteString(m.renderDiscoveryView(statsLabelStyle, statsValueStyle, warningStyle, boxStyle))
@@ -484,7 +716,7 @@ func (m controlModel) renderControlView(statsLabelStyle, statsValueStyle, errorS
 	devicePanel := listStyle.Render(m.deviceList.View())
 
 	// Control panel
-	controlContent := m.renderControlPanel(statsLabelStyle, statsValueStyle, headerStyle, buttonStyle, focusedButtonStyle)
+	controlContent := m.renderControlPanel(statsLabelStyle, statsValueStyle, warningStyle, headerStyle, buttonStyle, focusedButtonStyle)
 	controlStyle := boxStyle.Width(rightWidth)
 	controlPanel := controlStyle.Render(controlContent)
 
@@ -501,6 +733,11 @@ func (m controlModel) renderControlView(statsLabelStyle, statsValueStyle, errorS
 	if selected != nil {
 		s.WriteString(m.renderTelemetry(selected.address, statsLabelStyle, statsValueStyle, boxStyle))
 		s.WriteString("\n\n")
+		if charts := m.charts[selected.address]; charts != nil && !m.hideCharts {
+			st := chartStyles{label: statsLabelStyle, value: statsValueStyle, target: warningStyle, axis: headerStyle}
+			s.WriteString(boxStyle.Width(m.width - 4).Render(renderDeviceCharts(charts, time.Now(), m.chartWindow, m.width-8, st)))
+			s.WriteString("\n\n")
+		}
 	}
 
 	// Event log
@@ -509,9 +746,19 @@ func (m controlModel) renderControlView(statsLabelStyle, statsValueStyle, errorS
 	return s.String()
 }
 
-func (m controlModel) renderControlPanel(statsLabelStyle, statsValueStyle, headerStyle, buttonStyle, focusedButtonStyle lipgloss.Style) string {
+func (m controlModel) renderControlPanel(statsLabelStyle, statsValueStyle, warningStyle, headerStyle, buttonStyle, focusedButtonStyle lipgloss.Style) string {
 	var s strings.Builder
 
+	if m.heatConfirm != nil {
+		return m.renderHeatConfirm(statsLabelStyle, warningStyle)
+	}
+	if m.configEditor != nil {
+		return m.renderConfigEditor(statsLabelStyle, statsValueStyle, warningStyle, headerStyle)
+	}
+	if m.aliasEditor != nil {
+		return m.renderAliasEditor(statsLabelStyle, headerStyle)
+	}
+
 	selected := m.getSelectedDevice()
 	if selected == nil {
 		s.WriteString(headerStyle.Render("No device selected"))
@@ -519,11 +766,13 @@ func (m controlModel) renderControlPanel(statsLabelStyle, statsValueStyle, heade
 	}
 
 	// Selected device info
-	s.WriteString(fmt.Sprintf("%s Heater %016X\n", statsLabelStyle.Render("Selected:"), selected.address))
+	s.WriteString(fmt.Sprintf("%s Heater %s\n", statsLabelStyle.Render("Selected:"), formatAddress(selected.address)))
 	s.WriteString(fmt.Sprintf("%s %s\n\n", statsLabelStyle.Render("State:"), statsValueStyle.Render(selected.stateName)))
 
 	// Control based on state
-	if selected.state == uint64(fusain.SysStateIdle) {
+	if m.estopLocked(selected) {
+		s.WriteString(m.renderEstopPanel(selected, headerStyle, buttonStyle, focusedButtonStyle))
+	} else if selected.state == uint64(fusain.SysStateIdle) {
 		// IDLE state: show fan RPM input and Start Fan button
 		s.WriteString(statsLabelStyle.Render("Fan RPM: "))
 		if m.focusedField == focusRPMInput {
@@ -545,6 +794,35 @@ func (m controlModel) renderControlPanel(statsLabelStyle, statsValueStyle, heade
 		} else {
 			s.WriteString(buttonStyle.Render(btnText))
 		}
+		s.WriteString("\n\n")
+
+		// Pump rate input and Start Heat button
+		s.WriteString(statsLabelStyle.Render("Pump rate (ms): "))
+		if m.focusedField == focusPumpInput {
+			s.WriteString(m.pumpInput.View())
+		} else {
+			val := m.pumpInput.Value()
+			if val == "" {
+				val = m.pumpInput.Placeholder
+			}
+			s.WriteString(fmt.Sprintf("[%s]", val))
+		}
+		s.WriteString("\n\n")
+
+		btnText = "[ Start Heat... ]"
+		if m.focusedField == focusHeatButton {
+			s.WriteString(focusedButtonStyle.Render(btnText))
+		} else {
+			s.WriteString(buttonStyle.Render(btnText))
+		}
+		s.WriteString(m.renderDirectControls(selected, statsLabelStyle, headerStyle))
+	} else if isHeatState(selected.state) {
+		// PREHEAT/HEATING: show progress, the Abort button and the pump,
+		// glow plug and target temperature controls
+		s.WriteString(m.renderHeatProgress(selected, statsLabelStyle, statsValueStyle, headerStyle))
+		s.WriteString(m.renderDirectControls(selected, statsLabelStyle, headerStyle))
+	} else if selected.state == uint64(fusain.SysStateCooling) {
+		s.WriteString(headerStyle.Render("Cooling down; the fan runs until the heater is cool"))
 	} else if selected.state == uint64(fusain.SysStateBlowing) {
 		// FAN/BLOWING state: show current RPM and Return to Idle button
 		telem := m.lastTelemetry[selected.address]
@@ -561,6 +839,7 @@ func (m controlModel) renderControlPanel(statsLabelStyle, statsValueStyle, heade
 		} else {
 			s.WriteString(buttonStyle.Render(btnText))
 		}
+		s.WriteString(m.renderDirectControls(selected, statsLabelStyle, headerStyle))
 	} else {
 		// Other states: just show state name
 		s.WriteString(headerStyle.Render(fmt.Sprintf("State: %s (no controls available)", selected.stateName)))
@@ -619,7 +898,7 @@ func (m controlModel) renderTelemetry(address uint64, statsLabelStyle, statsValu
 	if len(telem.temperatures) > 0 {
 		content.WriteString(fmt.Sprintf("%s %s  ",
 			statsLabelStyle.Render("Temp:"),
-			statsValueStyle.Render(fmt.Sprintf("%.1fC", telem.temperatures[0]))))
+			statsValueStyle.Render(formatTemperature(telem.temperatures[0]))))
 	}
 
 	// Device uptime (from device-addressed ping response)
@@ -629,6 +908,16 @@ func (m controlModel) renderTelemetry(address uint64, statsLabelStyle, statsValu
 			statsValueStyle.Render(formatUptime(telem.uptime))))
 	}
 
+	// Firmware and other identity fields, when the device reports them
+	if len(telem.info) > 0 {
+		content.WriteString("\n" + statsLabelStyle.Render("DEVICE") + " | ")
+		for _, f := range telem.info {
+			content.WriteString(fmt.Sprintf("%s %s  ",
+				statsLabelStyle.Render(f.Name+":"),
+				statsValueStyle.Render(f.FormatValue())))
+		}
+	}
+
 	return boxStyle.Width(m.width - 4).Render(content.String())
 }
 
@@ -637,8 +926,8 @@ func (m controlModel) renderEventLog(statsLabelStyle, warningStyle, boxStyle lip
 	s.WriteString(statsLabelStyle.Render("EVENTS"))
 	s.WriteString("\n")
 
-	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
-	errorStyleLocal := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	headerStyle := lipgloss.NewStyle().Foreground(theme.muted)
+	errorStyleLocal := lipgloss.NewStyle().Foreground(theme.bad).Bold(true)
 
 	// Calculate available height for log
 	logHeight := 8
@@ -659,14 +948,21 @@ func (m controlModel) renderEventLog(statsLabelStyle, warningStyle, boxStyle lip
 			timestamp := entry.timestamp.Format("15:04:05.000")
 			icon := "i"
 			style := warningStyle
+			message := entry.message
 			if entry.isError {
 				icon = "x"
 				style = errorStyleLocal
 			}
-			s.WriteString(fmt.Sprintf("%s %s %s\n",
+			if entry.emergency {
+				icon = "!"
+				style = emergencyStyle
+				message = emergencyStyle.Render(message)
+			}
+			s.WriteString(fmt.Sprintf("%s %s %s%s\n",
 				headerStyle.Render(timestamp),
 				style.Render(icon),
-				entry.message))
+				message,
+				headerStyle.Render(entry.repeatSuffix())))
 		}
 	}
 
@@ -677,57 +973,120 @@ func (m controlModel) renderEventLog(statsLabelStyle, warningStyle, boxStyle lip
 // Data Processing
 //////////////////////////////////////////////////////////////
 
-func (m *controlModel) processControlData(msg controlDataMsg) {
-	if msg.decodeErr != nil {
+func (m *controlModel) processEvent(e events.Event) {
+	if msg := emergencyLogMessage(e); msg != "" {
+		m.addEmergencyLogEntry(msg)
+	}
+
+	switch e := e.(type) {
+	case events.Synchronized:
+		m.synchronized = true
+		if e.Skipped > 0 {
+			m.addLogEntry(fmt.Sprintf("Synchronized after skipping %d invalid bytes", e.Skipped), false)
+		} else {
+			m.addLogEntry("Synchronized", false)
+		}
+
+	case events.DecodeError:
 		if m.synchronized {
-			m.stats.Update(nil, msg.decodeErr, nil)
-			m.addLogEntry(fmt.Sprintf("DECODE ERROR: %v", msg.decodeErr), true)
+			m.stats.Update(nil, e.Err, nil)
+			m.link.RecordDecodeError(e.At, e.Err)
+			m.addLogEntry(fmt.Sprintf("DECODE ERROR: %v", e.Err), true)
 		}
-		return
-	}
 
-	if msg.packet == nil {
-		return
+	case events.PacketReceived:
+		m.link.RecordPacket(e.At)
+		m.processPacket(e.Packet, e.Anomalies)
+
+	case events.DeviceStateChanged:
+		if msg := errorHintLogMessage(e); msg != "" {
+			m.addLogEntry(msg, true)
+		}
+
+	case events.AlertRaised, events.AlertCleared:
+		m.appendLogEntry(alertLogEntry(e))
+
+	case events.CommandAcked:
+		if !e.Accepted() {
+			m.addLogEntry(fmt.Sprintf("%s rejected: %s",
+				fusain.FormatMessageType(e.MsgType), describeErrorReply(e.Rejection)), true)
+			if e.MsgType == fusain.MsgStateCommand {
+				m.estopRejected(e.Address)
+			}
+		}
+
+	case events.ConnectionLost:
+		m.connectionLost = true
+		m.addLogEntry(connectionLostMessage(e), true)
+
+	case events.ConnectionRestored:
+		m.connectionLost = false
+		m.connInfo = e.Info
+		m.link.Reset()
+		clear(m.pingSent)
+		// Reset discovery state for new discovery cycle
+		m.resetDiscovery()
+		m.addLogEntry("Reconnected - starting discovery", false)
 	}
+}
 
-	m.stats.Update(msg.packet, nil, msg.validationErrors)
+func (m *controlModel) processPacket(packet *fusain.Packet, anomalies []fusain.ValidationError) {
+	m.stats.Update(packet, nil, anomalies)
 
 	// Process packet based on type
-	msgType := msg.packet.Type()
-	address := msg.packet.Address()
+	msgType := packet.Type()
+	address := packet.Address()
+
+	if isDeviceTelemetry(packet) && m.lookupDevice(address) == nil {
+		m.backfillDevice(address)
+	}
 
 	switch msgType {
 	case fusain.MsgDeviceAnnounce:
-		m.handleDeviceAnnounce(msg.packet)
+		m.handleDeviceAnnounce(packet)
 
 	case fusain.MsgStateData:
-		m.handleStateData(msg.packet, address)
+		m.handleStateData(packet, address)
 		// Also parse telemetry
-		m.parseTelemetryForDevice(msg.packet, address)
+		m.parseTelemetryForDevice(packet, address)
 
 	case fusain.MsgMotorData, fusain.MsgTempData:
-		m.parseTelemetryForDevice(msg.packet, address)
+		m.parseTelemetryForDevice(packet, address)
 
 	case fusain.MsgPingResponse:
+		if sent, ok := m.pingSent[address]; ok {
+			m.link.RecordRTT(packet.Timestamp(), packet.Timestamp().Sub(sent))
+			delete(m.pingSent, address)
+		}
+
 		// If from stateless address (router), update router uptime only
 		// If from specific device, update that device's uptime
 		if address == fusain.AddressStateless {
 			// Router uptime - store separately, don't update device uptimes
-			payloadMap := msg.packet.PayloadMap()
+			payloadMap := packet.PayloadMap()
 			uptime, ok := fusain.GetMapUint(payloadMap, 0)
 			if ok {
+				// Uptime going backwards means the router restarted and
+				// lost its subscriptions
+				restarted := m.hasRouterUptime && uptime < m.routerUptime
 				m.routerUptime = uptime
 				m.hasRouterUptime = true
+				if restarted && m.discoveryDone {
+					m.refreshTopology("Router restarted (uptime reset)")
+				}
 			}
 		} else {
 			// Device-specific uptime
-			m.parseTelemetryForDevice(msg.packet, address)
+			m.parseTelemetryForDevice(packet, address)
 		}
 
+	case fusain.MsgErrorInvalidCmd, fusain.MsgErrorStateReject:
+		m.handleErrorReply(packet)
+
 	default:
 		// Other packet types - just log if there are validation errors
-		if len(msg.validationErrors) > 0 {
-			for _, err := range msg.validationErrors {
+		if len(anomalies) > 0 {
+			for _, err := range anomalies {
 				m.addLogEntry(fmt.Sprintf("%s: %s", fusain.FormatMessageType(msgType), err.Message), true)
 			}
 		}
@@ -746,19 +1105,154 @@ func (m *controlModel) handleDeviceAnnounce(packet *fusain.Packet) {
 		return
 	}
 
-	// Add/update device in discovery map
-	if _, exists := m.discoveryDevices[address]; !exists {
-		m.discoveryDevices[address] = &device{
-			address:   address,
-			state:     uint64(fusain.SysStateIdle),
-			stateName: "IDLE",
-			lastSeen:  time.Now(),
+	caps, clamped := parseDeviceCapabilities(packet)
+	if len(clamped) > 0 {
+		m.addLogEntry(fmt.Sprintf("Device %s announced too many components, clamped: %s",
+			formatAddress(address), strings.Join(clamped, ", ")), true)
+	}
+
+	// Refresh capabilities of an already-known device
+	if dev := m.lookupDevice(address); dev != nil {
+		dev.caps = caps
+		dev.hasCaps = true
+		if !m.discoveryDone {
+			m.lastDeviceSeen = time.Now()
+		} else {
+			m.updateDeviceList()
 		}
-		m.addLogEntry(fmt.Sprintf("Device discovered: %016X", address), false)
+		return
 	}
+
+	dev := device{
+		address:   address,
+		state:     uint64(fusain.SysStateIdle),
+		stateName: "IDLE",
+		lastSeen:  time.Now(),
+		caps:      caps,
+		hasCaps:   true,
+	}
+
+	// A new device announcing outside a discovery window means the topology
+	// changed (device or router restarted): add it and re-subscribe
+	if m.discoveryDone {
+		m.devices = append(m.devices, dev)
+		m.updateDeviceList()
+		m.refreshTopology(fmt.Sprintf("New device announced: %s (motors=%d, thermometers=%d, pumps=%d, glow=%d)",
+			formatAddress(address), caps.motorCount, caps.thermometerCount, caps.pumpCount, caps.glowCount))
+		return
+	}
+
+	// Add device to discovery map
+	m.discoveryDevices[address] = &dev
+	m.addLogEntry(fmt.Sprintf("Device discovered: %s (motors=%d, thermometers=%d, pumps=%d, glow=%d)",
+		formatAddress(address), caps.motorCount, caps.thermometerCount, caps.pumpCount, caps.glowCount), false)
 	m.lastDeviceSeen = time.Now()
 }
 
+// refreshTopology re-subscribes to every known device and asks all devices
+// to announce again, without leaving the control view. Devices that were
+// not known yet are added as their announcements arrive.
+func (m *controlModel) refreshTopology(reason string) {
+	m.addLogEntry(reason+" - refreshing device list", false)
+	for _, dev := range m.devices {
+		m.sendTelemetrySubscription(dev.address)
+	}
+	m.sendDiscoveryRequest(fusain.AddressBroadcast)
+}
+
+// isDeviceTelemetry reports whether p is telemetry from a device address
+func isDeviceTelemetry(p *fusain.Packet) bool {
+	switch p.Address() {
+	case fusain.AddressBroadcast, fusain.AddressStateless:
+		return false
+	}
+	switch p.Type() {
+	case fusain.MsgStateData, fusain.MsgMotorData, fusain.MsgPumpData,
+		fusain.MsgGlowData, fusain.MsgTempData, fusain.MsgPingResponse:
+		return true
+	}
+	return false
+}
+
+// backfillDevice adds a device first seen in telemetry (e.g. discovery was
+// skipped or missed it) with unknown capabilities, and asks it to announce
+// itself. The DEVICE_ANNOUNCE reply fills in the capabilities.
+func (m *controlModel) backfillDevice(address uint64) {
+	dev := device{
+		address:   address,
+		stateName: "UNKNOWN",
+		lastSeen:  time.Now(),
+	}
+
+	if m.discoveryDone {
+		m.devices = append(m.devices, dev)
+		m.updateDeviceList()
+		m.sendTelemetrySubscription(address)
+	} else {
+		m.discoveryDevices[address] = &dev
+	}
+
+	m.addLogEntry(fmt.Sprintf("Device %s seen in telemetry (capabilities unknown) - requesting announce", formatAddress(address)), false)
+	m.sendDiscoveryRequest(address)
+}
+
+// maxAnnouncedComponents bounds the component counts taken from
+// DEVICE_ANNOUNCE whatever the validation limits say, and the component
+// indexes telemetry is stored under: component indexes are uint8 on the
+// wire
+const maxAnnouncedComponents = 0xFF
+
+// parseDeviceCapabilities extracts component counts from a DEVICE_ANNOUNCE packet
+// CBOR keys: 0=motor-count, 1=thermometer-count, 2=pump-count, 3=glow-count
+//
+// Counts above the validation limits' MaxComponents are clamped to it, so
+// a malformed announce cannot size the per-component telemetry; clamped
+// lists each such count as announced, e.g. "motor_count=4096 (max 10)".
+func parseDeviceCapabilities(packet *fusain.Packet) (caps deviceCapabilities, clamped []string) {
+	payloadMap := packet.PayloadMap()
+
+	limits := fusain.DefaultValidationLimits()
+	if appConfig().Limits != nil {
+		limits = *appConfig().Limits
+	}
+	limit := min(limits.MaxComponents, maxAnnouncedComponents)
+
+	count := func(key int, name string) uint64 {
+		n, _ := fusain.GetMapUint(payloadMap, key)
+		if n > limit {
+			clamped = append(clamped, fmt.Sprintf("%s=%d (max %d)", name, n, limit))
+			return limit
+		}
+		return n
+	}
+
+	caps = deviceCapabilities{
+		motorCount:       count(0, "motor_count"),
+		thermometerCount: count(1, "temp_count"),
+		pumpCount:        count(2, "pump_count"),
+		glowCount:        count(3, "glow_count"),
+	}
+	return caps, clamped
+}
+
+// handleErrorReply reports a device error reply together with the command it rejected
+func (m *controlModel) handleErrorReply(packet *fusain.Packet) {
+	m.settleCommands()
+
+	if cmd, ok := m.transactor.correlate(packet); ok {
+		// Logged when the event comes back from the bus
+		eventBus.Publish(events.CommandAcked{
+			At:        packet.Timestamp(),
+			Address:   packet.Address(),
+			MsgType:   cmd.msgType,
+			Rejection: packet,
+		})
+		return
+	}
+
+	m.addLogEntry(fmt.Sprintf("Device %s rejected command: %s", formatAddress(packet.Address()), describeErrorReply(packet)), true)
+}
+
 func (m *controlModel) handleStateData(packet *fusain.Packet, address uint64) {
 	payloadMap := packet.PayloadMap()
 	stateNames := []string{"INITIALIZING", "IDLE", "BLOWING", "PREHEAT", "PREHEAT_STAGE_2", "HEATING", "COOLING", "ERROR", "E_STOP"}
@@ -784,7 +1278,9 @@ func (m *controlModel) handleStateData(packet *fusain.Packet, address uint64) {
 
 				// Log state change
 				if oldState != stateName {
-					m.addLogEntry(fmt.Sprintf("Device %016X: %s -> %s", address, oldState, stateName), false)
+					m.addLogEntry(fmt.Sprintf("Device %s: %s -> %s", formatAddress(address), oldState, stateName), false)
+					m.trackHeatRun(address, state, stateName)
+					m.trackEstop(address, oldState, stateName)
 				}
 
 				// Update list
@@ -807,11 +1303,8 @@ func (m *controlModel) parseTelemetryForDevice(packet *fusain.Packet, address ui
 	payloadMap := packet.PayloadMap()
 	stateNames := []string{"INITIALIZING", "IDLE", "BLOWING", "PREHEAT", "PREHEAT_STAGE_2", "HEATING", "COOLING", "ERROR", "E_STOP"}
 
-	// Ensure telemetry entry exists
-	if m.lastTelemetry[address] == nil {
-		m.lastTelemetry[address] = &telemetryData{timestamp: time.Now()}
-	}
-	telem := m.lastTelemetry[address]
+	telem := m.telemetryFor(address)
+	dev := m.lookupDevice(address)
 
 	switch packet.Type() {
 	case fusain.MsgStateData:
@@ -832,10 +1325,16 @@ func (m *controlModel) parseTelemetryForDevice(packet *fusain.Packet, address ui
 			telem.uptime = uptime
 			telem.hasUptime = true
 		}
+		if info := fusain.DeviceInfo(packet); len(info) > 0 {
+			telem.info = info
+		}
 
 	case fusain.MsgMotorData:
 		motorIdx, ok := fusain.GetMapInt(payloadMap, 0)
-		if !ok || motorIdx < 0 {
+		if !ok || motorIdx < 0 || motorIdx > maxAnnouncedComponents {
+			return
+		}
+		if dev != nil && dev.checkIndex("motor", motorIdx, dev.MotorCount()) != nil {
 			return
 		}
 		rpm, _ := fusain.GetMapInt(payloadMap, 2)
@@ -849,10 +1348,22 @@ func (m *controlModel) parseTelemetryForDevice(packet *fusain.Packet, address ui
 		}
 		telem.motorRPM[motorIdx] = rpm
 		telem.motorTarget[motorIdx] = target
+		if maxRPM, ok := fusain.GetMapInt(payloadMap, 4); ok {
+			m.noteReportedConfig(address, configKey{fusain.MsgMotorConfig, motorIdx, 5}, maxRPM)
+		}
+		if minRPM, ok := fusain.GetMapInt(payloadMap, 5); ok {
+			m.noteReportedConfig(address, configKey{fusain.MsgMotorConfig, motorIdx, 6}, minRPM)
+		}
+		if motorIdx == 0 {
+			m.chartsFor(address).rpm.add(chartSample{at: time.Now(), value: float64(rpm), target: float64(target), hasTarget: true}, m.chartWindow)
+		}
 
 	case fusain.MsgTempData:
 		tempIdx, ok := fusain.GetMapInt(payloadMap, 0)
-		if !ok || tempIdx < 0 {
+		if !ok || tempIdx < 0 || tempIdx > maxAnnouncedComponents {
+			return
+		}
+		if dev != nil && dev.checkIndex("thermometer", tempIdx, dev.ThermometerCount()) != nil {
 			return
 		}
 		reading, _ := fusain.GetMapFloat(payloadMap, 2)
@@ -861,9 +1372,24 @@ func (m *controlModel) parseTelemetryForDevice(packet *fusain.Packet, address ui
 			telem.temperatures = append(telem.temperatures, 0)
 		}
 		telem.temperatures[tempIdx] = reading
+		if tempIdx == 0 {
+			target, hasTarget := fusain.GetMapFloat(payloadMap, 5)
+			m.chartsFor(address).temp.add(chartSample{at: time.Now(), value: reading, target: target, hasTarget: hasTarget}, m.chartWindow)
+		}
 	}
 }
 
+// chartsFor returns the telemetry charts of a device, creating them on
+// first use
+func (m *controlModel) chartsFor(address uint64) *deviceCharts {
+	charts := m.charts[address]
+	if charts == nil {
+		charts = &deviceCharts{}
+		m.charts[address] = charts
+	}
+	return charts
+}
+
 //////////////////////////////////////////////////////////////
 // Commands
 //////////////////////////////////////////////////////////////
@@ -886,26 +1412,14 @@ func (m *controlModel) sendFanCommand() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	if rpm < minRPM || rpm > maxRPM {
-		m.addLogEntry(fmt.Sprintf("RPM must be between %d and %d", minRPM, maxRPM), true)
-		return m, nil
-	}
-
 	// Send fan command
 	packet := fusain.NewStateCommand(selected.address, uint8(fusain.ModeFan), &rpm)
-	wireBytes := fusain.MustEncodePacket(packet)
-	conn := m.connMgr.getConn()
-	if conn == nil {
-		m.addLogEntry("Cannot send command: connection lost", true)
-		return m, nil
-	}
-	_, err = conn.Write(wireBytes)
-	if err != nil {
-		m.addLogEntry(fmt.Sprintf("Failed to send command: %v", err), true)
+	if err := m.sendCommand(selected, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
 		return m, nil
 	}
 
-	m.addLogEntry(fmt.Sprintf("Sent FAN command (RPM=%d) to %016X", rpm, selected.address), false)
+	m.addLogEntry(fmt.Sprintf("Sent FAN command (RPM=%d) to %s", rpm, formatAddress(selected.address)), false)
 	return m, nil
 }
 
@@ -917,31 +1431,70 @@ func (m *controlModel) sendIdleCommand() (tea.Model, tea.Cmd) {
 
 	// Send idle command
 	packet := fusain.NewStateCommand(selected.address, uint8(fusain.ModeIdle), nil)
-	wireBytes := fusain.MustEncodePacket(packet)
-	conn := m.connMgr.getConn()
-	if conn == nil {
-		m.addLogEntry("Cannot send command: connection lost", true)
-		return m, nil
-	}
-	_, err := conn.Write(wireBytes)
-	if err != nil {
-		m.addLogEntry(fmt.Sprintf("Failed to send command: %v", err), true)
+	if err := m.sendCommand(selected, packet); err != nil {
+		m.addLogEntry(err.Error(), true)
 		return m, nil
 	}
 
-	m.addLogEntry(fmt.Sprintf("Sent IDLE command to %016X", selected.address), false)
+	m.addLogEntry(fmt.Sprintf("Sent IDLE command to %s", formatAddress(selected.address)), false)
 	return m, nil
 }
 
+// sendCommand validates a command against the target device and transmits it
+func (m *controlModel) sendCommand(dev *device, packet *fusain.Packet) error {
+	if err := validateCommand(dev, packet); err != nil {
+		return err
+	}
+
+	if err := writePacket(m.conn, packet); err != nil {
+		return fmt.Errorf("Failed to send command: %v", err)
+	}
+
+	now := time.Now()
+	m.transactor.track(packet)
+	m.audit.record(now, packet)
+	eventBus.Publish(events.CommandSent{At: now, Packet: packet})
+	return nil
+}
+
+// settleCommands publishes CommandAcked for commands whose reply window
+// passed without an error reply
+func (m *controlModel) settleCommands() {
+	for address, cmds := range m.transactor.settle() {
+		for _, cmd := range cmds {
+			eventBus.Publish(events.CommandAcked{
+				At:      cmd.sentAt.Add(m.transactor.timeout),
+				Address: address,
+				MsgType: cmd.msgType,
+			})
+		}
+	}
+}
+
 //////////////////////////////////////////////////////////////
 // Helpers
 //////////////////////////////////////////////////////////////
 
 func (m *controlModel) addLogEntry(message string, isError bool) {
-	entry := errorLogEntry{
+	m.appendLogEntry(errorLogEntry{
 		timestamp: time.Now(),
 		message:   message,
 		isError:   isError,
+	})
+}
+
+func (m *controlModel) addEmergencyLogEntry(message string) {
+	m.appendLogEntry(errorLogEntry{
+		timestamp: time.Now(),
+		message:   message,
+		isError:   true,
+		emergency: true,
+	})
+}
+
+func (m *controlModel) appendLogEntry(entry errorLogEntry) {
+	if collapseRepeat(m.errorLog, entry) {
+		return
 	}
 	m.errorLog = append(m.errorLog, entry)
 
@@ -963,6 +1516,36 @@ func (m *controlModel) getSelectedDevice() *device {
 	return &m.devices[idx]
 }
 
+// lookupDevice returns the device with the given address from the device
+// list (after discovery) or the discovery map (during discovery)
+func (m *controlModel) lookupDevice(address uint64) *device {
+	for i := range m.devices {
+		if m.devices[i].address == address {
+			return &m.devices[i]
+		}
+	}
+	return m.discoveryDevices[address]
+}
+
+// telemetryFor returns the telemetry entry for a device, creating it on first
+// use with per-component slices sized from the device's announced capabilities
+func (m *controlModel) telemetryFor(address uint64) *telemetryData {
+	if telem := m.lastTelemetry[address]; telem != nil {
+		return telem
+	}
+
+	telem := &telemetryData{timestamp: time.Now()}
+	if dev := m.lookupDevice(address); dev != nil && dev.HasCapabilities() {
+		telem.motorCount = uint8(dev.MotorCount())
+		telem.tempCount = uint8(dev.ThermometerCount())
+		telem.motorRPM = make([]int64, dev.MotorCount())
+		telem.motorTarget = make([]int64, dev.MotorCount())
+		telem.temperatures = make([]float64, dev.ThermometerCount())
+	}
+	m.lastTelemetry[address] = telem
+	return telem
+}
+
 func (m *controlModel) finishDiscovery() {
 	if m.discoveryDone {
 		return
@@ -998,6 +1581,7 @@ func (m *controlModel) resetDiscovery() {
 	m.devices = make([]device, 0)
 	m.lastDeviceSeen = time.Time{}
 	m.lastTelemetry = make(map[uint64]*telemetryData)
+	m.transactor.reset()
 	m.synchronized = false
 	m.updateDeviceList()
 }
@@ -1006,31 +1590,32 @@ func (m *controlModel) sendTelemetrySubscription(address uint64) {
 	// Send DATA_SUBSCRIPTION to router (stateless address) to subscribe to this appliance
 	packet := fusain.NewDataSubscription(fusain.AddressStateless, address)
 	wireBytes := fusain.MustEncodePacket(packet)
-	conn := m.connMgr.getConn()
-	if conn == nil {
-		m.addLogEntry(fmt.Sprintf("Failed to subscribe to %016X: connection lost", address), true)
-		return
-	}
-	_, err := conn.Write(wireBytes)
+	_, err := m.conn.Write(wireBytes)
 	if err != nil {
-		m.addLogEntry(fmt.Sprintf("Failed to subscribe to %016X: %v", address, err), true)
+		m.addLogEntry(fmt.Sprintf("Failed to subscribe to %s: %v", formatAddress(address), err), true)
 		return
 	}
-	m.addLogEntry(fmt.Sprintf("Subscribed to telemetry: %016X", address), false)
+	m.addLogEntry(fmt.Sprintf("Subscribed to telemetry: %s", formatAddress(address)), false)
 }
 
 func (m *controlModel) sendPingRequest(address uint64) {
 	// Send PING_REQUEST to device to get uptime
 	packet := fusain.NewPingRequest(address)
 	wireBytes := fusain.MustEncodePacket(packet)
-	conn := m.connMgr.getConn()
-	if conn == nil {
-		return // Silently fail - connection lost is handled elsewhere
-	}
-	_, err := conn.Write(wireBytes)
+	_, err := m.conn.Write(wireBytes)
 	if err != nil {
 		return // Silently fail - next tick will retry
 	}
+	m.pingSent[address] = time.Now()
+}
+
+func (m *controlModel) sendDiscoveryRequest(address uint64) {
+	// Send DISCOVERY_REQUEST so the device (or all devices, for the
+	// broadcast address) re-announces
+	packet := fusain.NewDiscoveryRequest(address)
+	if _, err := m.conn.Write(fusain.MustEncodePacket(packet)); err != nil {
+		m.addLogEntry(fmt.Sprintf("Failed to request announce from %s: %v", formatAddress(address), err), true)
+	}
 }
 
 func (m *controlModel) updateDeviceList() {
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func deviceAnnounce(address uint64, motors, thermometers uint64) *fusain.Packet {
+	return fusain.NewPacketWithPayload(address, fusain.MsgDeviceAnnounce, map[int]interface{}{
+		0: motors, 1: thermometers, 2: uint64(1), 3: uint64(1),
+	})
+}
+
+func TestParseDeviceCapabilities(t *testing.T) {
+	caps, clamped := parseDeviceCapabilities(deviceAnnounce(1, 2, 1))
+	if caps.motorCount != 2 || caps.thermometerCount != 1 || len(clamped) != 0 {
+		t.Errorf("got %+v, clamped %v", caps, clamped)
+	}
+}
+
+func TestParseDeviceCapabilities_Oversized(t *testing.T) {
+	limit := fusain.DefaultValidationLimits().MaxComponents
+
+	caps, clamped := parseDeviceCapabilities(deviceAnnounce(1, 1<<40, 300))
+	if caps.motorCount != limit || caps.thermometerCount != limit {
+		t.Errorf("counts = %d motors, %d thermometers, want both clamped to %d", caps.motorCount, caps.thermometerCount, limit)
+	}
+	if len(clamped) != 2 || !strings.HasPrefix(clamped[0], "motor_count=1099511627776") {
+		t.Errorf("clamped = %v", clamped)
+	}
+
+	// The telemetry is sized from the clamped counts
+	m := &controlModel{
+		devices:       []device{{address: 1, caps: caps, hasCaps: true}},
+		lastTelemetry: make(map[uint64]*telemetryData),
+	}
+	telem := m.telemetryFor(1)
+	if int(telem.motorCount) != len(telem.motorRPM) || len(telem.motorRPM) != int(limit) ||
+		int(telem.tempCount) != len(telem.temperatures) || len(telem.temperatures) != int(limit) {
+		t.Errorf("telemetry sized %d/%d motors, %d/%d thermometers, want %d",
+			telem.motorCount, len(telem.motorRPM), telem.tempCount, len(telem.temperatures), limit)
+	}
+}
+
+func TestParseDeviceCapabilities_LimitAboveWireMax(t *testing.T) {
+	limits := fusain.DefaultValidationLimits()
+	limits.MaxComponents = 100000
+	useConfig(t, func(c *Config) { c.Limits = &limits })
+
+	caps, clamped := parseDeviceCapabilities(deviceAnnounce(1, 4096, 1))
+	if caps.motorCount != maxAnnouncedComponents || len(clamped) != 1 {
+		t.Errorf("motorCount = %d, clamped %v; want %d", caps.motorCount, clamped, maxAnnouncedComponents)
+	}
+}
@@ -0,0 +1,484 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+// daemonModes lists the long-running commands that can run under daemon start
+var daemonModes = map[string]bool{
+	"watchdog": true,
+	"serve":    true,
+	"mqtt":     true,
+	"influx":   true,
+}
+
+var (
+	daemonName          string
+	daemonRunDir        string
+	daemonControlSocket string
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run long-running modes as a background service",
+	Long: `Start, stop and query heliostat running in the background.
+
+A daemon runs one long-running command (watchdog, serve, mqtt or influx)
+detached from the terminal. Its PID file, control socket and log file live in
+--run-dir (default: $XDG_RUNTIME_DIR/heliostat, or heliostat under the user
+cache directory), named after --name (default: the command name).
+
+Examples:
+  heliostat daemon start -- watchdog --url ws://slate.local/ws --device 0123456789ABCDEF --estop
+  heliostat daemon status
+  heliostat daemon reload
+  heliostat daemon stop
+
+  # Several instances side by side
+  heliostat daemon start --name heater1 -- watchdog --device 0123456789ABCDEF --estop
+  heliostat daemon status --name heater1
+
+  # Bridges
+  heliostat daemon start -- mqtt -p /dev/ttyUSB0 --broker tcp://localhost:1883
+  heliostat daemon stop --name mqtt`,
+}
+
+var daemonStartCmd = &cobra.Command{
+	Use:   "start -- COMMAND [FLAGS...]",
+	Short: "Start a command in the background",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runDaemonStart,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop a background command (SIGTERM)",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStop,
+}
+
+var daemonReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the configuration of a background command (SIGHUP)",
+	Long: `Make a running daemon re-read the config file, --limits, aliases and
+--rules and reopen its --jsonl file, without dropping its connection. A
+config that fails to load is reported in the daemon's log and the previous
+one stays in effect.`,
+	Args: cobra.NoArgs,
+	RunE: runDaemonReload,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Report uptime, connection state and statistics of a background command",
+	Long: `Query the control socket of a running daemon.
+
+Exit codes:
+  0 - Daemon is running
+  1 - Daemon is not running`,
+	Args: cobra.NoArgs,
+	RunE: runDaemonStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStartCmd, daemonStopCmd, daemonReloadCmd, daemonStatusCmd)
+	daemonCmd.PersistentFlags().StringVar(&daemonName, "name", "", "Instance name (default: command name for start, watchdog otherwise)")
+	daemonCmd.PersistentFlags().StringVar(&daemonRunDir, "run-dir", "", "Directory for PID file, control socket and log")
+
+	// Set by daemon start on the detached child; not meant for direct use
+	rootCmd.PersistentFlags().StringVar(&daemonControlSocket, "control-socket", "", "Serve status on this Unix socket")
+	rootCmd.PersistentFlags().MarkHidden("control-socket")
+}
+
+// daemonPaths holds the files belonging to one daemon instance
+type daemonPaths struct {
+	pid    string
+	socket string
+	log    string
+}
+
+func resolveDaemonPaths(name string) (daemonPaths, error) {
+	dir := daemonRunDir
+	if dir == "" {
+		// Never fall back to the shared temp dir: another user could
+		// pre-create it and plant or read the PID file, socket and log
+		base := os.Getenv("XDG_RUNTIME_DIR")
+		if base == "" {
+			var err error
+			if base, err = os.UserCacheDir(); err != nil {
+				return daemonPaths{}, fmt.Errorf("no run directory (%v); set --run-dir", err)
+			}
+		}
+		dir = filepath.Join(base, "heliostat")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return daemonPaths{}, fmt.Errorf("cannot create run directory: %v", err)
+	}
+	return daemonPaths{
+		pid:    filepath.Join(dir, name+".pid"),
+		socket: filepath.Join(dir, name+".sock"),
+		log:    filepath.Join(dir, name+".log"),
+	}, nil
+}
+
+func daemonInstanceName(fallback string) string {
+	if daemonName != "" {
+		return daemonName
+	}
+	return fallback
+}
+
+// readPIDFile returns the PID of a running daemon, or 0 if none is running
+func readPIDFile(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
+
+func runDaemonStart(cmd *cobra.Command, args []string) error {
+	mode := args[0]
+	if !daemonModes[mode] {
+		supported := make([]string, 0, len(daemonModes))
+		for m := range daemonModes {
+			supported = append(supported, m)
+		}
+		sort.Strings(supported)
+		return fmt.Errorf("%q cannot run as a daemon (supported: %s)", mode, strings.Join(supported, ", "))
+	}
+
+	name := daemonInstanceName(mode)
+	paths, err := resolveDaemonPaths(name)
+	if err != nil {
+		return err
+	}
+	if pid := readPIDFile(paths.pid); pid != 0 {
+		return fmt.Errorf("daemon %q already running (pid %d)", name, pid)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(paths.log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open log file: %v", err)
+	}
+	defer logFile.Close()
+
+	childArgs := append([]string{}, args...)
+	childArgs = append(childArgs, "--control-socket", paths.socket)
+
+	child := exec.Command(exe, childArgs...)
+	child.Stdout = logFile
+	child.Stderr = logFile
+	child.SysProcAttr = detachedProcAttr()
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("cannot start daemon: %v", err)
+	}
+
+	pid := child.Process.Pid
+	if err := os.WriteFile(paths.pid, []byte(strconv.Itoa(pid)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("cannot write PID file: %v", err)
+	}
+	child.Process.Release()
+
+	fmt.Printf("Started %s (pid %d)\n", name, pid)
+	fmt.Printf("  Log:    %s\n", paths.log)
+	fmt.Printf("  Socket: %s\n", paths.socket)
+	return nil
+}
+
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	name := daemonInstanceName("watchdog")
+	paths, err := resolveDaemonPaths(name)
+	if err != nil {
+		return err
+	}
+
+	pid := readPIDFile(paths.pid)
+	if pid == 0 {
+		os.Remove(paths.pid)
+		return exitErrorf(ExitFailure, "daemon %q is not running", name)
+	}
+	if err := terminateProcess(pid); err != nil {
+		return fmt.Errorf("cannot stop pid %d: %v", pid, err)
+	}
+
+	deadline := time.Now().Add(shutdownTimeout + 2*time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return exitErrorf(ExitTimeout, "daemon %q (pid %d) did not exit", name, pid)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	os.Remove(paths.pid)
+	fmt.Printf("Stopped %s (pid %d)\n", name, pid)
+	return nil
+}
+
+func runDaemonReload(cmd *cobra.Command, args []string) error {
+	name := daemonInstanceName("watchdog")
+	paths, err := resolveDaemonPaths(name)
+	if err != nil {
+		return err
+	}
+
+	pid := readPIDFile(paths.pid)
+	if pid == 0 {
+		return exitErrorf(ExitFailure, "daemon %q is not running", name)
+	}
+	if err := reloadProcess(pid); err != nil {
+		return fmt.Errorf("cannot reload pid %d: %v", pid, err)
+	}
+	fmt.Printf("Reloading %s (pid %d); see %s\n", name, pid, paths.log)
+	return nil
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	name := daemonInstanceName("watchdog")
+	paths, err := resolveDaemonPaths(name)
+	if err != nil {
+		return err
+	}
+
+	pid := readPIDFile(paths.pid)
+	if pid == 0 {
+		fmt.Printf("%s: not running\n", name)
+		return exitSilently(ExitFailure)
+	}
+
+	var status serviceStatus
+	if err := queryControlSocket(paths.socket, controlRequest{Op: "status"}, &status); err != nil {
+		fmt.Printf("%s: running (pid %d), control socket unavailable: %v\n", name, pid, err)
+		return nil
+	}
+
+	state := "disconnected"
+	if status.Connected {
+		state = "connected"
+	}
+	fmt.Printf("%s: running (pid %d)\n", name, pid)
+	fmt.Printf("  Mode:       %s\n", status.Mode)
+	fmt.Printf("  Uptime:     %s\n", formatUptime(uint64(time.Since(status.Started).Milliseconds())))
+	fmt.Printf("  Connection: %s (%s)\n", state, status.Connection)
+
+	keys := make([]string, 0, len(status.Stats))
+	for k := range status.Stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("  %-11s %d\n", k+":", status.Stats[k])
+	}
+	return nil
+}
+
+// serviceStatus is the JSON document served on the control socket
+type serviceStatus struct {
+	Mode       string            `json:"mode"`
+	Started    time.Time         `json:"started"`
+	Connected  bool              `json:"connected"`
+	Connection string            `json:"connection"`
+	Stats      map[string]uint64 `json:"stats"`
+}
+
+// serviceState collects status for the control socket. Long-running modes
+// update it as they go; it is cheap enough to update unconditionally.
+type serviceState struct {
+	mu     sync.Mutex
+	status serviceStatus
+
+	// devices holds per-device statistics, keyed by address
+	devices map[uint64]*fusain.Statistics
+
+	// info holds each device's identity fields from its last PING_RESPONSE
+	// that had any (firmware version, ...)
+	info map[uint64][]fusain.PayloadField
+}
+
+var service = &serviceState{
+	status:  serviceStatus{Started: time.Now(), Stats: map[string]uint64{}},
+	devices: map[uint64]*fusain.Statistics{},
+	info:    map[uint64][]fusain.PayloadField{},
+}
+
+// setConnection records the current connection state
+func (s *serviceState) setConnection(connected bool, connInfo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Connected = connected
+	if connInfo != "" {
+		s.status.Connection = connInfo
+	}
+}
+
+// consume updates connection state and counters from the event bus
+func (s *serviceState) consume(sub *events.Subscription) {
+	for e := range sub.Events() {
+		switch e := e.(type) {
+		case events.PacketReceived:
+			s.count("packets", 1)
+			s.updateDevice(e.Packet, e.Anomalies)
+		case events.DecodeError:
+			s.count("decode_errors", 1)
+		case events.ValidationAnomaly:
+			s.count("anomalies", 1)
+		case events.DeviceStale:
+			s.count("stale_devices", 1)
+		case events.AlertRaised:
+			s.count("alerts", 1)
+		case events.CommandSent:
+			s.count("commands", 1)
+		case events.ConnectionLost:
+			s.setConnection(false, "")
+		case events.ConnectionRestored:
+			s.setConnection(true, e.Info)
+		}
+	}
+}
+
+// count adds delta to the named statistic
+func (s *serviceState) count(name string, delta uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Stats[name] += delta
+}
+
+func (s *serviceState) snapshot() serviceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := s.status
+	snap.Stats = make(map[string]uint64, len(s.status.Stats))
+	for k, v := range s.status.Stats {
+		snap.Stats[k] = v
+	}
+	return snap
+}
+
+// serveControlSocket answers each connection on path with the current status
+func serveControlSocket(path, mode string) error {
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("cannot open control socket: %v", err)
+	}
+	onShutdown(func() {
+		listener.Close()
+		os.Remove(path)
+	})
+
+	service.mu.Lock()
+	service.status.Mode = mode
+	service.mu.Unlock()
+
+	go service.consume(eventBus.Subscribe(1000))
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go service.handle(conn)
+		}
+	}()
+	return nil
+}
+
+// controlRequest is one request line on the control socket. A connection
+// carries one request and one JSON reply.
+type controlRequest struct {
+	Op     string `json:"op"`               // status, device_stats or reset_device_stats
+	Device string `json:"device,omitempty"` // Hex address; empty for every device
+}
+
+// handle answers one control socket request
+func (s *serviceState) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	var req controlRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil && err != io.EOF {
+		json.NewEncoder(conn).Encode(controlError{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	enc := json.NewEncoder(conn)
+	switch req.Op {
+	case "", "status":
+		enc.Encode(s.snapshot())
+	case "device_stats", "reset_device_stats":
+		var address uint64
+		all := req.Device == ""
+		if !all {
+			var err error
+			if address, err = parseAddress(req.Device); err != nil {
+				enc.Encode(controlError{Error: err.Error()})
+				return
+			}
+		}
+		reply, err := s.deviceStats(address, all, req.Op == "reset_device_stats")
+		if err != nil {
+			enc.Encode(controlError{Error: err.Error()})
+			return
+		}
+		enc.Encode(reply)
+	default:
+		enc.Encode(controlError{Error: fmt.Sprintf("unknown op %q", req.Op)})
+	}
+}
+
+// controlError is the reply to a request that failed
+type controlError struct {
+	Error string `json:"error"`
+}
+
+// queryControlSocket sends req to the control socket at path and decodes the
+// reply into reply
+func queryControlSocket(path string, req controlRequest, reply interface{}) error {
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(conn).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid reply from control socket: %v", err)
+	}
+	var failed controlError
+	if json.Unmarshal(raw, &failed) == nil && failed.Error != "" {
+		return fmt.Errorf("%s", failed.Error)
+	}
+	return json.Unmarshal(raw, reply)
+}
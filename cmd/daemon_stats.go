@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonStatsDevice string
+	daemonStatsReset  bool
+)
+
+var daemonStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report or reset per-device statistics of a background command",
+	Long: `Fetch per-device statistics from the control socket of a running daemon.
+
+Each device's packets are counted and validated separately, so fleet
+monitoring can track every unit's error budget on its own. Decode errors
+(CRC failures, lost framing) can't be attributed to a device and are only
+counted in 'daemon status'.
+
+--device limits the report to one address. --reset clears the reported
+statistics after fetching them, so periodic polling with --reset yields
+per-interval counts.
+
+Examples:
+  heliostat daemon stats
+  heliostat daemon stats --device 0123456789ABCDEF --output json
+  heliostat daemon stats --name heater1 --device 0123456789ABCDEF --reset`,
+	Args: cobra.NoArgs,
+	RunE: runDaemonStats,
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonStatsCmd)
+	daemonStatsCmd.Flags().StringVar(&daemonStatsDevice, "device", "", "Only this device address (hex or alias)")
+	daemonStatsCmd.Flags().BoolVar(&daemonStatsReset, "reset", false, "Reset the reported statistics after fetching them")
+	daemonStatsCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format: text, or json for the raw reply")
+}
+
+// deviceStatsReply is the control socket reply to device_stats and
+// reset_device_stats: statistics keyed by 16-digit hex address, and the
+// identity fields (firmware version, ...) of devices that reported any,
+// by schema name or key number
+type deviceStatsReply struct {
+	Devices map[string]json.RawMessage        `json:"devices"`
+	Info    map[string]map[string]interface{} `json:"info,omitempty"`
+}
+
+// updateDevice counts a packet in its device's statistics. Packets from
+// sources that don't validate are checked here, so every device has an
+// anomaly count.
+func (s *serviceState) updateDevice(p *fusain.Packet, anomalies []fusain.ValidationError) {
+	if anomalies == nil {
+		anomalies = fusain.ValidatePacketWithOptions(p, validateOptions())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.devices[p.Address()]
+	if !ok {
+		stats = newStatistics()
+		s.devices[p.Address()] = stats
+	}
+	stats.Update(p, nil, anomalies)
+	if info := fusain.DeviceInfo(p); len(info) > 0 {
+		s.info[p.Address()] = info
+	}
+}
+
+// deviceStats returns the statistics of one device (or every device with
+// all set) and optionally resets them
+func (s *serviceState) deviceStats(address uint64, all, reset bool) (deviceStatsReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reply := deviceStatsReply{Devices: make(map[string]json.RawMessage)}
+	add := func(address uint64, stats *fusain.Statistics) error {
+		stats.CalculateRates()
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return err
+		}
+		reply.Devices[fmt.Sprintf("%016X", address)] = data
+		if info := s.info[address]; len(info) > 0 {
+			if reply.Info == nil {
+				reply.Info = make(map[string]map[string]interface{})
+			}
+			fields := make(map[string]interface{}, len(info))
+			for _, f := range info {
+				fields[f.Name] = jsonFieldValue(f.Value)
+			}
+			reply.Info[fmt.Sprintf("%016X", address)] = fields
+		}
+		if reset {
+			s.devices[address] = newStatistics()
+		}
+		return nil
+	}
+
+	if !all {
+		stats, ok := s.devices[address]
+		if !ok {
+			return reply, fmt.Errorf("no packets from device %s", formatAddress(address))
+		}
+		return reply, add(address, stats)
+	}
+	for address, stats := range s.devices {
+		if err := add(address, stats); err != nil {
+			return reply, err
+		}
+	}
+	return reply, nil
+}
+
+func runDaemonStats(cmd *cobra.Command, args []string) error {
+	jsonMode, err := jsonOutput()
+	if err != nil {
+		return err
+	}
+	if daemonStatsDevice != "" {
+		if _, err := parseAddress(daemonStatsDevice); err != nil {
+			return err
+		}
+	}
+
+	name := daemonInstanceName("watchdog")
+	paths, err := resolveDaemonPaths(name)
+	if err != nil {
+		return err
+	}
+	if readPIDFile(paths.pid) == 0 {
+		return exitErrorf(ExitFailure, "daemon %q is not running", name)
+	}
+
+	req := controlRequest{Op: "device_stats", Device: daemonStatsDevice}
+	if daemonStatsReset {
+		req.Op = "reset_device_stats"
+	}
+	var reply deviceStatsReply
+	if err := queryControlSocket(paths.socket, req, &reply); err != nil {
+		return exitErrorf(ExitFailure, "%s: %v", name, err)
+	}
+
+	if jsonMode {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reply)
+	}
+
+	if len(reply.Devices) == 0 {
+		fmt.Printf("%s: no devices seen\n", name)
+		return nil
+	}
+	addresses := make([]string, 0, len(reply.Devices))
+	for address := range reply.Devices {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	fmt.Printf("%-16s  %10s  %10s  %10s  %10s\n", "DEVICE", "PACKETS", "VALID", "ANOMALOUS", "MALFORMED")
+	for _, address := range addresses {
+		var stats struct {
+			TotalPackets     uint64 `json:"total_packets"`
+			ValidPackets     uint64 `json:"valid_packets"`
+			AnomalousValues  uint64 `json:"anomalous_values"`
+			MalformedPackets uint64 `json:"malformed_packets"`
+		}
+		if err := json.Unmarshal(reply.Devices[address], &stats); err != nil {
+			return fmt.Errorf("invalid statistics for %s: %v", address, err)
+		}
+		fmt.Printf("%-16s  %10d  %10d  %10d  %10d\n", address, stats.TotalPackets, stats.ValidPackets,
+			stats.AnomalousValues, stats.MalformedPackets)
+	}
+	if len(reply.Info) > 0 {
+		fmt.Printf("\n%-16s  %s\n", "DEVICE", "IDENTITY")
+	}
+	for _, address := range addresses {
+		info := reply.Info[address]
+		if len(info) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(info))
+		for name := range info {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = fmt.Sprintf("%s=%v", name, info[name])
+		}
+		fmt.Printf("%-16s  %s\n", address, strings.Join(fields, " "))
+	}
+	if daemonStatsReset {
+		fmt.Println("Statistics reset")
+	}
+	return nil
+}
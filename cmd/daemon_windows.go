@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// detachedProcAttr starts the daemon without a console window
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{HideWindow: true}
+}
+
+// processAlive reports whether a process with the given PID exists.
+// FindProcess opens a handle on Windows and fails for exited processes.
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	proc.Release()
+	return true
+}
+
+// terminateProcess stops the daemon. Windows has no SIGTERM, so the
+// process is killed without running its shutdown sequence.
+func terminateProcess(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Kill()
+}
+
+// reloadProcess is unsupported: Windows has no SIGHUP
+func reloadProcess(pid int) error {
+	return fmt.Errorf("reload is not supported on Windows; restart the daemon instead")
+}
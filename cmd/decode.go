@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decodeFile     string
+	decodeEncoding string
+)
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode [DATA...]",
+	Short: "Decode packets from hex, base64 or binary input",
+	Long: `Run bytes through the Fusain decoder and print each packet with its
+validation results, without a connection.
+
+Input is taken from the arguments, from --file, or from stdin when neither
+is given. --encoding selects how it is read:
+  auto    Arguments and text input are hex if they look like hex, base64
+          otherwise; other files and stdin are binary (default)
+  hex     Hex bytes; spaces, commas, colons and 0x prefixes are ignored, so
+          logic-analyzer exports can be pasted as is
+  base64  Standard or URL-safe base64
+  binary  Raw bytes
+
+Several packets and partial frames may be given; bytes before the first
+START byte are skipped. Packets are validated as in error_detection
+(limits from the config, --skip-schema). The exit code is 1 if any frame
+failed to decode, the input ends inside a frame, or any packet has
+anomalies.
+
+Examples:
+  heliostat decode 7E 04 01 00 00 00 00 00 00 00 82 18 2F F6 6D 47 7F
+  heliostat decode "0x7E,0x04,0x01,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x82,0x18,0x2F,0xF6,0x6D,0x47,0x7F"
+  heliostat decode --encoding base64 fgQBAAAAAAAAAIIYL/ZtR38=
+  heliostat decode --file capture.bin`,
+	RunE: runDecode,
+}
+
+func init() {
+	rootCmd.AddCommand(decodeCmd)
+	decodeCmd.Flags().StringVarP(&decodeFile, "file", "f", "", `Read input from a file ("-" for stdin)`)
+	decodeCmd.Flags().StringVar(&decodeEncoding, "encoding", "auto", "Input encoding: auto, hex, base64 or binary")
+	decodeCmd.Flags().BoolVar(&skipSchema, "skip-schema", false, "Skip protocol schema checks (required keys, CBOR types, enum ranges)")
+}
+
+func runDecode(cmd *cobra.Command, args []string) error {
+	switch decodeEncoding {
+	case "auto", "hex", "base64", "binary":
+	default:
+		return fmt.Errorf("invalid --encoding %q (valid: auto, hex, base64, binary)", decodeEncoding)
+	}
+	if len(args) > 0 && decodeFile != "" {
+		return fmt.Errorf("give input as arguments or with --file, not both")
+	}
+
+	var data []byte
+	var err error
+	if len(args) > 0 {
+		data, err = decodeText(strings.Join(args, " "), decodeEncoding)
+	} else {
+		data, err = readDecodeInput(decodeFile)
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("no input")
+	}
+
+	session := fusain.NewSessionValidator()
+	session.StaleTimeout = 0
+
+	var packets, anomalous, decodeErrors int
+	decoder := fusain.NewDecoder()
+	for _, b := range data {
+		packet, err := decoder.DecodeByte(b)
+		if err != nil {
+			decodeErrors++
+			fmt.Printf("[ERROR] %v\n\n", err)
+			continue
+		}
+		if packet == nil {
+			continue
+		}
+
+		packets++
+		fmt.Print(fusain.FormatPacketWithOptions(packet, formatOptions()))
+		anomalies := fusain.ValidatePacketWithOptions(packet, validateOptions())
+		anomalies = append(anomalies, session.Validate(packet)...)
+		if len(anomalies) > 0 {
+			anomalous++
+		}
+		for _, anomaly := range anomalies {
+			if anomaly.Check != "" {
+				fmt.Printf("  Anomaly: %s (check %s)\n", anomaly.Message, anomaly.Check)
+			} else {
+				fmt.Printf("  Anomaly: %s\n", anomaly.Message)
+			}
+		}
+		fmt.Println()
+	}
+
+	rest := decoder.GetRawBytes()
+	if len(rest) > 0 {
+		fmt.Printf("[INCOMPLETE] %d bytes of an unfinished frame: % X\n\n", len(rest), rest)
+	}
+	fmt.Printf("%d packets, %d with anomalies, %d decode errors\n", packets, anomalous, decodeErrors)
+
+	if anomalous > 0 || decodeErrors > 0 || len(rest) > 0 {
+		return exitSilently(ExitFailure)
+	}
+	return nil
+}
+
+// readDecodeInput reads a file, or stdin for "" and "-", and decodes it
+// with --encoding
+func readDecodeInput(path string) ([]byte, error) {
+	var raw []byte
+	var err error
+	if path == "" || path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read input: %v", err)
+	}
+
+	switch decodeEncoding {
+	case "binary":
+		return raw, nil
+	case "auto":
+		if !isText(raw) {
+			return raw, nil
+		}
+	}
+	return decodeText(string(raw), decodeEncoding)
+}
+
+// isText reports whether data holds only printable ASCII and whitespace,
+// i.e. could be a hex or base64 dump rather than a binary capture
+func isText(data []byte) bool {
+	for _, b := range data {
+		if (b < 0x20 || b > 0x7E) && b != '\n' && b != '\r' && b != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeText decodes a hex or base64 dump. Auto picks hex when every
+// character is a hex digit or separator.
+func decodeText(text, encoding string) ([]byte, error) {
+	digits := hexDigits(text)
+	if encoding == "hex" || encoding == "auto" && digits != "" {
+		if digits == "" {
+			return nil, fmt.Errorf("invalid hex input")
+		}
+		data, err := hex.DecodeString(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex input: %v", err)
+		}
+		return data, nil
+	}
+
+	compact := strings.Join(strings.Fields(text), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(compact); err == nil {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid input: not hex or base64")
+}
+
+// hexDigits returns the hex digits of a hex dump with its separators and
+// 0x prefixes removed, or "" if it contains anything else. Runs of bytes
+// without separators ("7E0E01") and single-digit bytes ("0x7,0xE") are
+// both accepted.
+func hexDigits(text string) string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ',' || r == ':' || r == ';'
+	})
+
+	var digits strings.Builder
+	for _, field := range fields {
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+		if field == "" {
+			return ""
+		}
+		for _, r := range field {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return ""
+			}
+		}
+		if len(field) == 1 {
+			field = "0" + field
+		}
+		digits.WriteString(field)
+	}
+	return digits.String()
+}
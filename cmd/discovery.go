@@ -4,8 +4,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/Thermoquad/heliostat/pkg/fusain"
@@ -34,6 +36,11 @@ Per the Fusain spec:
   - Appliances ignore non-broadcast DISCOVERY_REQUEST
   - Routers respond to stateless address with known devices
 
+Each device found is sent a PING_REQUEST; its PING_RESPONSE gives the uptime
+and any identity fields the firmware reports (version, build, ...), named
+from the schema registry. --output json prints one object listing the
+devices with these fields instead of the progress text.
+
 Examples:
   # Direct serial discovery to Helios
   heliostat discovery --port /dev/ttyUSB0
@@ -41,10 +48,14 @@ Examples:
   # WebSocket router discovery (Slate)
   heliostat discovery --url ws://slate.local/fusain --router
 
+  # Device list for scripts
+  heliostat discovery --port /dev/ttyUSB0 --output json
+
 Exit codes:
   0 - Discovery successful (at least one device found)
-  1 - Discovery failed (no devices or timeout)
-  2 - Connection error`,
+  1 - Discovery completed but no devices were found
+  2 - Connection error
+  3 - Timeout (no devices responded, or no end-of-discovery marker in router mode)`,
 	RunE: runDiscovery,
 }
 
@@ -52,16 +63,20 @@ func init() {
 	rootCmd.AddCommand(discoveryCmd)
 	discoveryCmd.Flags().IntVar(&discoveryTimeout, "timeout", 5, "Timeout in seconds for discovery")
 	discoveryCmd.Flags().BoolVar(&discoveryRouter, "router", false, "Use router mode (stateless address)")
+	discoveryCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format: text, or json for one object listing the devices found")
 }
 
 func runDiscovery(cmd *cobra.Command, args []string) error {
-	// Open connection (serial or WebSocket)
-	conn, connInfo, err := OpenConnection()
+	jsonMode, err := jsonOutput()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
-		os.Exit(2)
+		return err
+	}
+	// Progress goes to stdout in text mode only
+	printf := func(format string, a ...interface{}) {
+		if !jsonMode {
+			fmt.Printf(format, a...)
+		}
 	}
-	defer conn.Close()
 
 	mode := "appliance"
 	var address uint64 = fusain.AddressBroadcast
@@ -70,27 +85,39 @@ func runDiscovery(cmd *cobra.Command, args []string) error {
 		address = fusain.AddressStateless
 	}
 
-	fmt.Printf("Heliostat - Device Discovery\n")
-	fmt.Printf("Connection: %s\n", connInfo)
-	fmt.Printf("Mode: %s\n", mode)
-	fmt.Printf("Timeout: %d seconds\n\n", discoveryTimeout)
-
-	decoder := fusain.NewDecoder()
-
 	// Create DISCOVERY_REQUEST packet
 	discoveryPacket := fusain.NewDiscoveryRequest(address)
 	wireBytes := fusain.MustEncodePacket(discoveryPacket)
 
+	// Open connection (serial or WebSocket); the request is repeated if
+	// the connection is lost and restored before the timeout
+	conn, connInfo, err := OpenReconnectingConnection(func(conn Connection) {
+		printf("Reconnected - sending DISCOVERY_REQUEST again\n")
+		conn.Write(wireBytes)
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	printf("Heliostat - Device Discovery\n")
+	printf("Connection: %s\n", connInfo)
+	printf("Mode: %s\n", mode)
+	printf("Timeout: %d seconds\n\n", discoveryTimeout)
+
+	decoder := fusain.NewDecoder()
+
 	// Send discovery request
-	fmt.Printf("Sending DISCOVERY_REQUEST (address=0x%016X)...\n", address)
+	printf("Sending DISCOVERY_REQUEST (address=0x%016X)...\n", address)
 	_, err = conn.Write(wireBytes)
 	if err != nil {
-		fmt.Printf("SEND FAILED: %v\n", err)
-		os.Exit(2)
+		return exitErrorf(ExitConnection, "SEND FAILED: %v", err)
 	}
 
-	// Collect DEVICE_ANNOUNCE responses
-	devices := make([]discoveryDeviceInfo, 0)
+	// Collect DEVICE_ANNOUNCE responses, and the PING_RESPONSE each device
+	// sends to the PING_REQUEST that follows its announce
+	var mu sync.Mutex
+	devices := make([]*discoveryDeviceInfo, 0)
 	done := make(chan bool, 1)
 	errChan := make(chan error, 1)
 
@@ -98,91 +125,218 @@ func runDiscovery(cmd *cobra.Command, args []string) error {
 		buf := make([]byte, 128)
 		for {
 			n, err := conn.Read(buf)
+			if err == ErrReconnected {
+				decoder = fusain.NewDecoder()
+				continue
+			}
 			if err != nil {
 				errChan <- err
 				return
 			}
 
-			for j := 0; j < n; j++ {
-				packet, decodeErr := decoder.DecodeByte(buf[j])
-				if decodeErr != nil {
-					continue
-				}
-				if packet != nil {
-					msgType := packet.Type()
-					if msgType == fusain.MsgDeviceAnnounce {
-						device := parseDiscoveryAnnounce(packet)
-
-						// End-of-discovery marker (router mode only)
-						if device.isEndMarker() {
-							if discoveryRouter {
-								fmt.Printf("\nEnd of discovery marker received\n")
-								done <- true
-								return
-							}
-							// Ignore zero-capability devices in appliance mode
-							continue
+			packets, _ := decoder.Decode(buf[:n])
+			for _, packet := range packets {
+				switch packet.Type() {
+				case fusain.MsgDeviceAnnounce:
+					device := parseDiscoveryAnnounce(packet)
+
+					// End-of-discovery marker (router mode only)
+					if device.isEndMarker() {
+						if discoveryRouter {
+							printf("\nEnd of discovery marker received\n")
+							done <- true
+							return
 						}
+						// Ignore zero-capability devices in appliance mode
+						continue
+					}
 
-						devices = append(devices, device)
-						fmt.Printf("\nDevice found:\n")
-						fmt.Printf("  Address: 0x%016X\n", device.address)
-						fmt.Printf("  Motors: %d\n", device.motorCount)
-						fmt.Printf("  Thermometers: %d\n", device.thermometerCount)
-						fmt.Printf("  Pumps: %d\n", device.pumpCount)
-						fmt.Printf("  Glow plugs: %d\n", device.glowCount)
-
-						// In appliance mode, we might get multiple devices
-						// but typically just one (Helios) on a point-to-point link
-						// Continue listening for more responses
+					mu.Lock()
+					devices = append(devices, &device)
+					mu.Unlock()
+					printf("\nDevice found:\n")
+					printf("  Address: 0x%s\n", formatAddress(device.address))
+					printf("  Motors: %d\n", device.motorCount)
+					printf("  Thermometers: %d\n", device.thermometerCount)
+					printf("  Pumps: %d\n", device.pumpCount)
+					printf("  Glow plugs: %d\n", device.glowCount)
+
+					// Ask for its uptime and identity (firmware version, ...)
+					conn.Write(fusain.MustEncodePacket(fusain.NewPingRequest(device.address)))
+
+					// In appliance mode, we might get multiple devices
+					// but typically just one (Helios) on a point-to-point link
+					// Continue listening for more responses
+
+				case fusain.MsgPingResponse:
+					mu.Lock()
+					device := findDiscoveredDevice(devices, packet.Address())
+					if device != nil && !device.pinged {
+						device.pinged = true
+						device.uptime, _ = fusain.GetMapUint(packet.PayloadMap(), 0)
+						device.info = fusain.DeviceInfo(packet)
+						printf("\nDevice 0x%s:\n", formatAddress(device.address))
+						printf("  Uptime: %s\n", formatUptime(device.uptime))
+						for _, f := range device.info {
+							printf("  %s: %s\n", f.Name, f.FormatValue())
+						}
 					}
+					mu.Unlock()
 				}
 			}
 		}
 	}()
 
 	// Wait for discovery to complete or timeout
+	timedOut := false
 	select {
 	case <-done:
-		// Discovery complete (router sent end marker)
+		// Discovery complete (router sent end marker); give the last
+		// devices time to answer their ping
+		waitForDiscoveryPings(&mu, devices)
 	case err := <-errChan:
-		fmt.Printf("READ FAILED: %v\n", err)
-		os.Exit(2)
+		return exitErrorf(ExitConnection, "READ FAILED: %v", err)
 	case <-time.After(time.Duration(discoveryTimeout) * time.Second):
+		mu.Lock()
+		found := len(devices)
+		mu.Unlock()
+
+		// In appliance mode, a timeout after devices responded is the normal end
+		timedOut = discoveryRouter || found == 0
 		if discoveryRouter {
-			fmt.Printf("\nTIMEOUT: No end-of-discovery marker received in %ds\n", discoveryTimeout)
+			printf("\nTIMEOUT: No end-of-discovery marker received in %ds\n", discoveryTimeout)
 		} else {
 			// In appliance mode, timeout is expected after all devices respond
-			if len(devices) > 0 {
-				fmt.Printf("\nDiscovery timeout reached\n")
+			if found > 0 {
+				printf("\nDiscovery timeout reached\n")
 			} else {
-				fmt.Printf("\nTIMEOUT: No devices responded in %ds\n", discoveryTimeout)
+				printf("\nTIMEOUT: No devices responded in %ds\n", discoveryTimeout)
 			}
 		}
 	}
 
-	// Summary
-	fmt.Printf("\n--- Discovery summary ---\n")
-	fmt.Printf("Devices found: %d\n", len(devices))
+	mu.Lock()
+	found := append([]*discoveryDeviceInfo(nil), devices...)
+	mu.Unlock()
 
-	if len(devices) == 0 {
-		if discoveryRouter {
-			fmt.Printf("No devices discovered. Router may not have any connected devices.\n")
-		} else {
-			fmt.Printf("No devices discovered. Check connection and device power.\n")
+	if jsonMode {
+		writeDiscoveryJSON(connInfo, mode, timedOut, found)
+	} else {
+		// Summary
+		fmt.Printf("\n--- Discovery summary ---\n")
+		fmt.Printf("Devices found: %d\n", len(found))
+
+		if len(found) == 0 {
+			if discoveryRouter {
+				fmt.Printf("No devices discovered. Router may not have any connected devices.\n")
+			} else {
+				fmt.Printf("No devices discovered. Check connection and device power.\n")
+			}
 		}
-		os.Exit(1)
 	}
 
+	if timedOut {
+		return exitSilently(ExitTimeout)
+	}
+	if len(found) == 0 {
+		return exitSilently(ExitFailure)
+	}
 	return nil
 }
 
+// discoveryPingWait bounds how long discovery waits, after the router's
+// end marker, for the devices' ping responses
+const discoveryPingWait = time.Second
+
+// waitForDiscoveryPings waits until every device answered its ping, or
+// for discoveryPingWait
+func waitForDiscoveryPings(mu *sync.Mutex, devices []*discoveryDeviceInfo) {
+	deadline := time.Now().Add(discoveryPingWait)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		pending := false
+		for _, d := range devices {
+			pending = pending || !d.pinged
+		}
+		mu.Unlock()
+		if !pending {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// findDiscoveredDevice returns the discovered device with address, or nil
+func findDiscoveredDevice(devices []*discoveryDeviceInfo, address uint64) *discoveryDeviceInfo {
+	for _, d := range devices {
+		if d.address == address {
+			return d
+		}
+	}
+	return nil
+}
+
+// discoveryJSON is the --output json document
+type discoveryJSON struct {
+	Connection string                `json:"connection"`
+	Mode       string                `json:"mode"`
+	TimedOut   bool                  `json:"timed_out"`
+	Devices    []discoveryDeviceJSON `json:"devices"`
+}
+
+type discoveryDeviceJSON struct {
+	Address      string  `json:"address"`
+	Motors       uint64  `json:"motors"`
+	Thermometers uint64  `json:"thermometers"`
+	Pumps        uint64  `json:"pumps"`
+	GlowPlugs    uint64  `json:"glow_plugs"`
+	UptimeMS     *uint64 `json:"uptime_ms,omitempty"` // Absent if the device didn't answer its ping
+
+	// Identity fields from PING_RESPONSE (firmware version, ...), by
+	// schema name or key number
+	Info map[string]interface{} `json:"info,omitempty"`
+}
+
+// writeDiscoveryJSON writes the devices found as one JSON object
+func writeDiscoveryJSON(connInfo, mode string, timedOut bool, devices []*discoveryDeviceInfo) {
+	doc := discoveryJSON{Connection: connInfo, Mode: mode, TimedOut: timedOut, Devices: []discoveryDeviceJSON{}}
+	for _, d := range devices {
+		dev := discoveryDeviceJSON{
+			Address:      fmt.Sprintf("%016X", d.address),
+			Motors:       d.motorCount,
+			Thermometers: d.thermometerCount,
+			Pumps:        d.pumpCount,
+			GlowPlugs:    d.glowCount,
+		}
+		if d.pinged {
+			uptime := d.uptime
+			dev.UptimeMS = &uptime
+		}
+		if len(d.info) > 0 {
+			dev.Info = make(map[string]interface{}, len(d.info))
+			for _, f := range d.info {
+				dev.Info[f.Name] = jsonFieldValue(f.Value)
+			}
+		}
+		doc.Devices = append(doc.Devices, dev)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(doc)
+}
+
 type discoveryDeviceInfo struct {
 	address          uint64
 	motorCount       uint64
 	thermometerCount uint64
 	pumpCount        uint64
 	glowCount        uint64
+
+	// From the device's PING_RESPONSE, once pinged is set
+	pinged bool
+	uptime uint64
+	info   []fusain.PayloadField
 }
 
 func (d discoveryDeviceInfo) isEndMarker() bool {
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// displayRateLimits holds the --rate-limit flag values
+var displayRateLimits []string
+
+const rateLimitUsage = "Show at most one packet of a type per device per interval in text output (TYPE=INTERVAL, or INTERVAL for all telemetry data; repeatable)"
+
+// displayKey identifies one rate-limited stream
+type displayKey struct {
+	address uint64
+	msgType uint8
+}
+
+// displayLimiter thins high-frequency telemetry in text output so command
+// and response traffic stays readable. Each (device, message type) stream
+// with an interval shows at most one packet per interval; the next packet
+// shown reports how many were suppressed in between. Only display is
+// affected: statistics and validation still see every packet.
+type displayLimiter struct {
+	intervals  map[uint8]time.Duration
+	last       map[displayKey]time.Time
+	suppressed map[displayKey]int
+}
+
+// newDisplayLimiter parses --rate-limit values. It returns nil when no
+// limits are set; a nil limiter shows everything.
+func newDisplayLimiter(specs []string) (*displayLimiter, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	intervals := make(map[uint8]time.Duration)
+	for _, spec := range specs {
+		name, value, found := strings.Cut(spec, "=")
+		if !found {
+			name, value = "", spec
+		}
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid --rate-limit %q: interval must be a positive duration", spec)
+		}
+
+		if name == "" {
+			for t := uint8(fusain.MsgStateData); t <= fusain.MsgTempData; t++ {
+				if _, set := intervals[t]; !set {
+					intervals[t] = interval
+				}
+			}
+			continue
+		}
+		msgType, err := parseMessageTypeFlag(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --rate-limit %q: %v", spec, err)
+		}
+		intervals[msgType] = interval
+	}
+
+	return &displayLimiter{
+		intervals:  intervals,
+		last:       make(map[displayKey]time.Time),
+		suppressed: make(map[displayKey]int),
+	}, nil
+}
+
+// admit reports whether p should be shown and, if so, how many packets of
+// its stream were suppressed since the last one shown. Emergency-stop
+// traffic is always shown.
+func (l *displayLimiter) admit(p *fusain.Packet) (bool, int) {
+	if l == nil || p.IsEmergency() {
+		return true, 0
+	}
+	interval, ok := l.intervals[p.Type()]
+	if !ok {
+		return true, 0
+	}
+
+	key := displayKey{address: p.Address(), msgType: p.Type()}
+	at := p.Timestamp()
+	if last, seen := l.last[key]; seen && at.Sub(last) < interval {
+		l.suppressed[key]++
+		return false, 0
+	}
+	l.last[key] = at
+	suppressed := l.suppressed[key]
+	delete(l.suppressed, key)
+	return true, suppressed
+}
+
+// format formats p for display, with the suppressed count appended to its
+// header line
+func (l *displayLimiter) format(p *fusain.Packet, suppressed int) string {
+	text := fusain.FormatPacketWithOptions(p, formatOptions())
+	if suppressed == 0 {
+		return text
+	}
+	return withHeaderSuffix(text, fmt.Sprintf(" (+%d suppressed)", suppressed))
+}
+
+// withHeaderSuffix appends suffix to the first line of a formatted packet
+func withHeaderSuffix(text, suffix string) string {
+	if header, rest, found := strings.Cut(text, "\n"); found {
+		return header + suffix + "\n" + rest
+	}
+	return text + suffix
+}
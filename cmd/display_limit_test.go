@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func stateData(address uint64, state fusain.SysState) *fusain.Packet {
+	return fusain.NewPacketWithPayload(address, fusain.MsgStateData, map[int]interface{}{
+		0: false, 1: uint64(0), 2: uint64(state), 3: uint64(1000),
+	})
+}
+
+func TestDisplayLimiterSuppressesWithinInterval(t *testing.T) {
+	l, err := newDisplayLimiter([]string{"1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := l.admit(stateData(1, fusain.SysStateIdle)); !ok {
+		t.Fatal("first packet suppressed")
+	}
+	if ok, _ := l.admit(stateData(1, fusain.SysStateIdle)); ok {
+		t.Fatal("second packet within the interval shown")
+	}
+	if ok, _ := l.admit(stateData(2, fusain.SysStateIdle)); !ok {
+		t.Fatal("packet from another device suppressed")
+	}
+}
+
+func TestDisplayLimiterPassesEmergency(t *testing.T) {
+	l, err := newDisplayLimiter([]string{"1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.admit(stateData(1, fusain.SysStateHeating))
+	if ok, _ := l.admit(stateData(1, fusain.SysStateEstop)); !ok {
+		t.Fatal("E_STOP within the interval suppressed")
+	}
+
+	estop := fusain.NewStateCommand(1, uint8(fusain.ModeEmergency), nil)
+	l2, err := newDisplayLimiter([]string{"STATE_COMMAND=1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2.admit(fusain.NewStateCommand(1, uint8(fusain.ModeIdle), nil))
+	if ok, _ := l2.admit(estop); !ok {
+		t.Fatal("emergency STATE_COMMAND within the interval suppressed")
+	}
+}
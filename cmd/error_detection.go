@@ -5,17 +5,25 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Thermoquad/heliostat/pkg/events"
 	"github.com/Thermoquad/heliostat/pkg/fusain"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 )
 
 var (
-	showAll       bool
-	statsInterval int
-	useTUI        bool
+	showAll           bool
+	statsInterval     int
+	useTUI            bool
+	simpleMode        bool
+	simpleRefresh     time.Duration
+	skipSchema        bool
+	checkTypes        bool // Deprecated: schema checks are on by default
+	telemetryInterval time.Duration
+	staleTimeout      time.Duration
 )
 
 var errorDetectionCmd = &cobra.Command{
@@ -26,14 +34,48 @@ var errorDetectionCmd = &cobra.Command{
 This command validates each packet and detects:
   - Malformed packets (invalid counts, length mismatches)
   - CRC errors and decode failures
-  - Anomalous telemetry values (RPM > 6000, invalid temperatures)
+  - Anomalous telemetry values (RPM above the limit, invalid temperatures)
   - Statistics and trends (packet rate, error rate, success rate)
 
 By default, only errors are displayed. Use --show-all to display valid packets too.
+In text mode, --rate-limit thins the valid packets shown (e.g. MOTOR_DATA=1s
+shows at most one MOTOR_DATA per device per second); errors and ping
+responses are always shown.
+Runs of identical errors (e.g. a storm of CRC mismatches) are collapsed:
+the first is shown, followed by how many times it repeated and when the run
+ended (in the TUI log, "(x12, last 15:04:05.000)" on the entry). Use
+--no-dedup to show each one.
+Payloads are also checked against the protocol schema: missing required keys,
+keys whose CBOR type does not match (e.g. a float reading encoded as an
+integer) and out-of-range enum or index values. Use --skip-schema to turn
+these checks off for firmware that predates the schema.
+
+Each device is also tracked across packets: state transitions its state
+machine does not allow (e.g. IDLE -> HEATING without PREHEAT), device
+timestamps going backwards, repeated telemetry and devices that stop
+sending (see --stale-timeout) are reported.
 
 Packets are validated in real-time, with errors highlighted immediately and
 periodic statistics summaries displayed at configurable intervals.
 
+With --simple, a one-line summary is redrawn in place using only carriage
+returns (no alternate screen or cursor movement) and errors are printed on
+their own lines, for serial consoles and CI logs where the TUI garbles
+output.
+
+With --output json (overrides --tui and --simple), one JSON object is
+written per line for jq, vector or fluentd: "packet" records (errors and
+ping responses only unless --show-all; with an "anomalies" list when
+validation failed),
+"decode_error", "sync" and "stale" records, and a "stats" snapshot every
+--stats-interval and when the connection closes.
+
+With --auto-throttle, a TUI that keeps dropping events for 3 seconds raises
+every device's telemetry interval with TELEMETRY_CONFIG, doubling it while
+the overflow lasts (up to --throttle-max). After 30 seconds without drops
+the interval is halved again, step by step, until the original is restored;
+devices are also restored on exit. Each change is shown in the event log.
+
 Supports both serial and WebSocket connections.`,
 	RunE: runErrorDetection,
 }
@@ -41,18 +83,59 @@ Supports both serial and WebSocket connections.`,
 func init() {
 	rootCmd.AddCommand(errorDetectionCmd)
 	errorDetectionCmd.Flags().BoolVar(&showAll, "show-all", false, "Show all packets (not just errors)")
+	errorDetectionCmd.Flags().StringSliceVar(&displayRateLimits, "rate-limit", nil, rateLimitUsage)
+	errorDetectionCmd.Flags().BoolVar(&noDedup, "no-dedup", false, "Show every repeated identical error instead of collapsing runs into one entry with a count (text, --simple and TUI)")
+	errorDetectionCmd.Flags().StringVar(&outputFormat, "output", "text", outputUsage)
 	errorDetectionCmd.Flags().IntVar(&statsInterval, "stats-interval", 10, "Statistics update interval (seconds)")
 	errorDetectionCmd.Flags().BoolVar(&useTUI, "tui", true, "Use terminal UI (false for text mode)")
+	errorDetectionCmd.Flags().BoolVar(&simpleMode, "simple", false, "Plain one-line summary refreshed with carriage returns (overrides --tui)")
+	errorDetectionCmd.Flags().DurationVar(&simpleRefresh, "refresh", time.Second, "Summary refresh interval for --simple")
+	errorDetectionCmd.Flags().BoolVar(&skipSchema, "skip-schema", false, "Skip protocol schema checks (required keys, CBOR types, enum ranges)")
+	errorDetectionCmd.Flags().BoolVar(&checkTypes, "check-types", false, "Verify payload CBOR types against the protocol schema")
+	errorDetectionCmd.Flags().MarkDeprecated("check-types", "schema checks are now on by default; use --skip-schema to turn them off")
+	errorDetectionCmd.Flags().DurationVar(&staleTimeout, "stale-timeout", fusain.DefaultStaleTimeout, "Report devices silent for longer than this (0 disables)")
+	errorDetectionCmd.Flags().DurationVar(&telemetryInterval, "telemetry-interval", 0, "Expected telemetry interval for slow-telemetry detection (default: learn from TELEMETRY_CONFIG)")
+	errorDetectionCmd.Flags().BoolVar(&autoThrottle, "auto-throttle", false, "Raise device telemetry intervals while the TUI drops events, and restore them later")
+	errorDetectionCmd.Flags().DurationVar(&throttleMaxInterval, "throttle-max", 5*time.Second, "Longest telemetry interval --auto-throttle sets")
+}
+
+// newStatistics creates a statistics tracker configured by flags
+func newStatistics() *fusain.Statistics {
+	stats := fusain.NewStatistics()
+	stats.TelemetryInterval = telemetryInterval
+	return stats
+}
+
+// validateOptions returns the validation options selected by flags
+func validateOptions() fusain.ValidateOptions {
+	return fusain.ValidateOptions{SkipSchema: skipSchema, Limits: appConfig().Limits}
 }
 
 func runErrorDetection(cmd *cobra.Command, args []string) error {
+	if simpleMode && simpleRefresh <= 0 {
+		return fmt.Errorf("--refresh must be positive")
+	}
+	if autoThrottle && throttleMaxInterval <= 0 {
+		return fmt.Errorf("--throttle-max must be positive")
+	}
+	jsonMode, err := jsonOutput()
+	if err != nil {
+		return err
+	}
+
 	// Open connection (serial or WebSocket)
-	conn, connInfo, err := OpenConnection()
+	conn, connInfo, err := OpenReconnectingConnection(nil)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
+	if jsonMode {
+		return runJSONMode(conn)
+	}
+	if simpleMode {
+		return runSimpleMode(conn, connInfo)
+	}
 	if useTUI {
 		return runTUIMode(conn, connInfo)
 	}
@@ -66,6 +149,17 @@ func printDecodeError(err error) {
 	fmt.Printf("  >>> DECODE FAILED <<<\n\n")
 }
 
+// anomaliesKey identifies a packet's validation errors for collapsing
+// repeats: the same message type, device and messages
+func anomaliesKey(packet *fusain.Packet, anomalies []fusain.ValidationError) string {
+	var key strings.Builder
+	fmt.Fprintf(&key, "%02X %016X", packet.Type(), packet.Address())
+	for _, a := range anomalies {
+		key.WriteString("\n" + a.Message)
+	}
+	return key.String()
+}
+
 // printPingResponse prints a ping response with uptime
 func printPingResponse(packet *fusain.Packet) {
 	timestamp := packet.Timestamp().Format("15:04:05.000")
@@ -94,28 +188,35 @@ func printValidationErrors(packet *fusain.Packet, errors []fusain.ValidationErro
 		switch err.Type {
 		case fusain.AnomalyInvalidCount:
 			fmt.Printf("  Issue %d: \033[1;31m%s\033[0m\n", i+1, err.Message)
+			maxCount, _ := err.Details["max"].(uint64)
 			if motorCount, ok := err.Details["motor_count"].(uint64); ok {
-				fmt.Printf("    motor_count=%d (max 10)\n", motorCount)
+				fmt.Printf("    motor_count=%d (max %d)\n", motorCount, maxCount)
 			}
 			if tempCount, ok := err.Details["temp_count"].(uint64); ok {
-				fmt.Printf("    temp_count=%d (max 10)\n", tempCount)
+				fmt.Printf("    temp_count=%d (max %d)\n", tempCount, maxCount)
 			}
 
-		case fusain.AnomalyLengthMismatch:
+		case fusain.AnomalyLengthMismatch, fusain.AnomalyMissingField:
 			fmt.Printf("  Issue %d: \033[1;31m%s\033[0m\n", i+1, err.Message)
 
 		case fusain.AnomalyHighRPM:
 			fmt.Printf("  Issue %d: \033[1;33m%s\033[0m\n", i+1, err.Message)
 			if rpm, ok := err.Details["rpm"].(int64); ok {
 				if targetRPM, ok := err.Details["target_rpm"].(int64); ok {
-					fmt.Printf("    RPM=%d, target=%d (max 6000)\n", rpm, targetRPM)
+					maxRPM, _ := err.Details["max"].(int64)
+					fmt.Printf("    RPM=%d, target=%d (max %d)\n", rpm, targetRPM, maxRPM)
 				}
 			}
 
 		case fusain.AnomalyInvalidTemp:
 			fmt.Printf("  Issue %d: \033[1;33m%s\033[0m\n", i+1, err.Message)
 			if temp, ok := err.Details["value"].(float64); ok {
-				fmt.Printf("    Temperature=%.1f°C (valid: -50 to 1000°C)\n", temp)
+				minTemp, _ := err.Details["min"].(float64)
+				maxTemp, _ := err.Details["max"].(float64)
+				fmt.Printf("    Temperature=%s (valid: %s to %s)\n",
+					fusain.FormatTemperature(temp, displayUnits),
+					fusain.FormatTemperature(minTemp, displayUnits),
+					fusain.FormatTemperature(maxTemp, displayUnits))
 			}
 
 		case fusain.AnomalyInvalidPWM:
@@ -129,9 +230,12 @@ func printValidationErrors(packet *fusain.Packet, errors []fusain.ValidationErro
 		case fusain.AnomalyDecodeError:
 			fmt.Printf("  Issue %d: \033[1;31m%s\033[0m\n", i+1, err.Message)
 
-		case fusain.AnomalyInvalidValue:
+		case fusain.AnomalyInvalidValue, fusain.AnomalyInvalidTransition, fusain.AnomalyTimestamp, fusain.AnomalyDuplicate:
 			fmt.Printf("  Issue %d: \033[1;33m%s\033[0m\n", i+1, err.Message)
 
+		case fusain.AnomalyCustom:
+			fmt.Printf("  Issue %d: \033[1;33m%s\033[0m (check %s)\n", i+1, err.Message, err.Check)
+
 		default:
 			fmt.Printf("  Issue %d: %s\n", i+1, err.Message)
 		}
@@ -158,148 +262,52 @@ func printValidationErrors(packet *fusain.Packet, errors []fusain.ValidationErro
 
 // runTUIMode runs error detection in TUI mode
 func runTUIMode(conn ByteReader, connInfo string) error {
-	decoder := fusain.NewDecoder()
-	synchronized := false
-	invalidBytesBeforeSync := 0
+	// Denied packets are only counted; logging to stderr would corrupt the TUI
+	deviceFilter.log = false
 
 	// Create TUI program with alt screen for flicker-free rendering
 	m := initialModel(connInfo, statsInterval, showAll)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithoutSignalHandler())
+	onShutdownProgram(p)
 
 	// Done channel for shutdown signaling
 	done := make(chan struct{})
 
-	// Buffered channel for batching updates (prevents TUI glitches)
-	batchChan := make(chan serialDataMsg, 100)
-	syncChan := make(chan syncMsg, 1)
-
-	// Reader goroutine - decodes packets and sends to batch channel
-	go func() {
-		buf := make([]byte, 128)
-		for {
-			// Check if we should stop
-			select {
-			case <-done:
-				return
-			default:
-			}
+	// Events are dropped if the TUI can't keep up
+	sub := eventBus.Subscribe(1000)
+	defer sub.Close()
 
-			n, err := conn.Read(buf)
-			if err != nil {
-				// Check if we're shutting down
-				select {
-				case <-done:
-					return
-				default:
-					// For WebSocket connections, a read error usually means
-					// the connection is permanently closed - exit gracefully
-					if err == ErrConnectionClosed {
-						return
-					}
-					// Brief pause before retry on transient errors (e.g., serial)
-					time.Sleep(10 * time.Millisecond)
-					continue
-				}
-			}
-
-			// Process bytes
-			for i := 0; i < n; i++ {
-				packet, decodeErr := decoder.DecodeByte(buf[i])
-
-				// Handle decode errors
-				if decodeErr != nil {
-					if synchronized {
-						// We're synced, this is a real error
-						select {
-						case batchChan <- serialDataMsg{
-							packet:           nil,
-							decodeErr:        decodeErr,
-							validationErrors: nil,
-						}:
-						default:
-							// Channel full, drop oldest by reading and discarding
-						}
-					} else {
-						// Not synced yet, just count invalid bytes
-						invalidBytesBeforeSync++
-					}
-				} else if packet != nil {
-					// Successfully decoded a packet
-					if !synchronized {
-						// First packet! We're now synchronized
-						synchronized = true
-						select {
-						case syncChan <- syncMsg{invalidBytes: invalidBytesBeforeSync}:
-						default:
-						}
-					}
-
-					// Validate packet
-					validationErrors := fusain.ValidatePacket(packet)
-					select {
-					case batchChan <- serialDataMsg{
-						packet:           packet,
-						decodeErr:        nil,
-						validationErrors: validationErrors,
-					}:
-					default:
-						// Channel full, drop message (TUI can't keep up)
-					}
-				}
-			}
-		}
-	}()
-
-	// Batch sender goroutine - sends batched updates to TUI at fixed rate
-	go func() {
-		ticker := time.NewTicker(50 * time.Millisecond) // 20 updates/sec max
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-done:
-				return
-			case <-ticker.C:
-				var batch batchDataMsg
-
-				// Check for sync message
-				select {
-				case sync := <-syncChan:
-					batch.syncMsg = &sync
-				default:
-				}
+	go newPacketSource(eventBus, true).run(conn, done)
+	go forwardEvents(sub, p, done)
 
-				// Drain all available messages from batch channel
-			drainLoop:
-				for {
-					select {
-					case msg := <-batchChan:
-						batch.messages = append(batch.messages, msg)
-					default:
-						break drainLoop
-					}
-				}
-
-				// Send batch if we have anything
-				if batch.syncMsg != nil || len(batch.messages) > 0 {
-					p.Send(batch)
-				}
-			}
-		}
-	}()
+	// The throttle restores devices on exit, before the connection closes
+	throttled := make(chan struct{})
+	if autoThrottle {
+		go func() {
+			defer close(throttled)
+			newTelemetryThrottle(eventBus, conn, sub).run(done)
+		}()
+	} else {
+		close(throttled)
+	}
 
 	// Run TUI
-	if _, err := p.Run(); err != nil {
-		close(done) // Signal goroutines to stop
+	_, err := p.Run()
+	close(done)
+	<-throttled
+	if err != nil {
 		return fmt.Errorf("TUI error: %v", err)
 	}
-
-	close(done) // Signal goroutines to stop
 	return nil
 }
 
 // runTextMode runs error detection in text mode (original behavior)
 func runTextMode(conn ByteReader, connInfo string) error {
+	limiter, err := newDisplayLimiter(displayRateLimits)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Heliostat - Error Detection Mode\n")
 	fmt.Printf("Connection: %s\n", connInfo)
 	fmt.Printf("Statistics interval: %d seconds\n", statsInterval)
@@ -310,84 +318,173 @@ func runTextMode(conn ByteReader, connInfo string) error {
 	}
 	fmt.Printf("Press Ctrl+C to exit\n\n")
 
-	decoder := fusain.NewDecoder()
-	stats := fusain.NewStatistics()
-	buf := make([]byte, 128)
+	stats := newStatistics()
 
-	// Sync tracking - ignore decode errors until first valid packet
+	// Decode errors are ignored until the first valid packet
 	synchronized := false
-	invalidBytesBeforeSync := 0
+
+	// Runs of identical errors are printed once, with a summary
+	var repeats logRepeats
+	printRepeats := func(summary string) {
+		if summary != "" {
+			fmt.Printf("%s\n\n", summary)
+		}
+	}
 
 	// Statistics ticker
 	statsTicker := time.NewTicker(time.Duration(statsInterval) * time.Second)
 	defer statsTicker.Stop()
 
-	// Channel for non-blocking reads
-	dataBuf := make(chan []byte, 10)
-	go func() {
-		for {
-			n, err := conn.Read(buf)
-			if err != nil {
-				// Connection closed or error - exit goroutine silently
-				return
-			}
-			data := make([]byte, n)
-			copy(data, buf[:n])
-			dataBuf <- data
-		}
-	}()
+	sub := eventBus.SubscribeLossless(256)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, true).run(conn, done)
 
 	for {
 		select {
-		case data := <-dataBuf:
-			// Process bytes
-			for _, b := range data {
-				packet, decodeErr := decoder.DecodeByte(b)
-
-				// Handle decode errors
-				if decodeErr != nil {
-					if synchronized {
-						// We're synced, this is a real error
-						stats.Update(nil, decodeErr, nil)
-						printDecodeError(decodeErr)
-					} else {
-						// Not synced yet, just count invalid bytes
-						invalidBytesBeforeSync++
-					}
-				} else if packet != nil {
-					// Successfully decoded a packet
-					if !synchronized {
-						// First packet! We're now synchronized
-						synchronized = true
-						if invalidBytesBeforeSync > 0 {
-							fmt.Printf("[SYNC] Synchronized after skipping %d invalid bytes\n\n", invalidBytesBeforeSync)
-						} else {
-							fmt.Printf("[SYNC] Synchronized\n\n")
-						}
+		case e := <-sub.Events():
+			switch e := e.(type) {
+			case events.DecodeError:
+				if synchronized {
+					// We're synced, this is a real error
+					stats.Update(nil, e.Err, nil)
+					repeat, summary := repeats.add("decode "+e.Err.Error(), e.At)
+					printRepeats(summary)
+					if !repeat {
+						printDecodeError(e.Err)
 					}
+				}
 
-					// Validate packet
-					validationErrors := fusain.ValidatePacket(packet)
-					stats.Update(packet, nil, validationErrors)
-
-					// Print packet or error based on mode
-					if len(validationErrors) > 0 {
-						printValidationErrors(packet, validationErrors)
-					} else if packet.Type() == fusain.MsgPingResponse {
-						// Always print ping responses (for debugging)
-						printPingResponse(packet)
-					} else if showAll {
-						// Print valid packet (only if --show-all flag is set)
-						fmt.Print(fusain.FormatPacket(packet))
+			case events.Synchronized:
+				synchronized = true
+				printRepeats(repeats.flush())
+				if e.Skipped > 0 {
+					fmt.Printf("[SYNC] Synchronized after skipping %d invalid bytes\n\n", e.Skipped)
+				} else {
+					fmt.Printf("[SYNC] Synchronized\n\n")
+				}
+
+			case events.PacketReceived:
+				stats.Update(e.Packet, nil, e.Anomalies)
+
+				// Print packet or error based on mode
+				if len(e.Anomalies) > 0 {
+					repeat, summary := repeats.add(anomaliesKey(e.Packet, e.Anomalies), e.At)
+					printRepeats(summary)
+					if !repeat {
+						printValidationErrors(e.Packet, e.Anomalies)
+					}
+				} else if e.Packet.Type() == fusain.MsgPingResponse {
+					// Always print ping responses (for debugging)
+					printRepeats(repeats.flush())
+					printPingResponse(e.Packet)
+				} else if showAll {
+					// Print valid packet (only if --show-all flag is set)
+					if show, suppressed := limiter.admit(e.Packet); show {
+						printRepeats(repeats.flush())
+						fmt.Print(limiter.format(e.Packet, suppressed))
 					}
 				}
+
+			case events.DeviceStale:
+				stats.RecordStale([]fusain.ValidationError{e.Anomaly})
+				printRepeats(repeats.flush())
+				fmt.Printf("[%s] \033[1;33mSTALE DEVICE:\033[0m %s\n\n", e.At.Format("15:04:05.000"), e.Anomaly.Message)
+
+			case events.AlertRaised:
+				printRepeats(repeats.flush())
+				fmt.Printf("[%s] \033[1;31m%s\033[0m\n\n", e.At.Format("15:04:05.000"), alertLogMessage(e))
+
+			case events.AlertCleared:
+				printRepeats(repeats.flush())
+				fmt.Printf("[%s] %s\n\n", e.At.Format("15:04:05.000"), alertLogMessage(e))
+
+			case events.ConnectionLost:
+				printRepeats(repeats.flush())
+				fmt.Printf("%s\n", connectionLostMessage(e))
+				if !e.Reconnecting {
+					return nil
+				}
+
+			case events.ConnectionRestored:
+				fmt.Printf("%s\n\n", connectionRestoredMessage(e))
 			}
 
 		case <-statsTicker.C:
 			// Print statistics
+			printRepeats(repeats.flush())
 			fmt.Println()
 			fmt.Print(stats.String())
 			fmt.Println()
 		}
 	}
 }
+
+// runJSONMode runs error detection with JSON Lines output
+func runJSONMode(conn ByteReader) error {
+	out := newJSONLines()
+	stats := newStatistics()
+
+	// Decode errors are ignored until the first valid packet
+	synchronized := false
+
+	statsTicker := time.NewTicker(time.Duration(statsInterval) * time.Second)
+	defer statsTicker.Stop()
+
+	sub := eventBus.SubscribeLossless(256)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, true).run(conn, done)
+
+	for {
+		select {
+		case e := <-sub.Events():
+			switch e := e.(type) {
+			case events.DecodeError:
+				if synchronized {
+					stats.Update(nil, e.Err, nil)
+					out.decodeError(e.At, e.Err)
+				}
+
+			case events.Synchronized:
+				synchronized = true
+				out.write(jsonRecord{Kind: "sync", Time: e.At, Skipped: e.Skipped})
+
+			case events.PacketReceived:
+				stats.Update(e.Packet, nil, e.Anomalies)
+				if len(e.Anomalies) > 0 || showAll || e.Packet.Type() == fusain.MsgPingResponse {
+					out.packet(e.At, e.Packet, e.Anomalies)
+				}
+
+			case events.DeviceStale:
+				stats.RecordStale([]fusain.ValidationError{e.Anomaly})
+				out.write(jsonRecord{
+					Kind:      "stale",
+					Time:      e.At,
+					Address:   fmt.Sprintf("%016X", e.Address),
+					Anomalies: []fusain.ValidationError{e.Anomaly},
+				})
+
+			case events.AlertRaised, events.AlertCleared:
+				out.write(jsonRecord{Kind: "alert", Time: e.Time(), Alert: alertRecord(e)})
+
+			case events.ConnectionLost:
+				out.write(jsonRecord{Kind: "connection_lost", Time: e.At})
+				if !e.Reconnecting {
+					out.stats(stats)
+					return nil
+				}
+
+			case events.ConnectionRestored:
+				out.write(jsonRecord{Kind: "connection_restored", Time: e.At, Connection: e.Info})
+			}
+
+		case <-statsTicker.C:
+			out.stats(stats)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// defaultErrorHints are the troubleshooting hints shown when a device
+// reports an error code; the config file's "error_hints" replaces them
+var defaultErrorHints = map[fusain.ErrorCode]string{
+	fusain.ErrorOverheat:     "Check for blocked intake or exhaust ducting and a stalled combustion fan; let the heater cool before restarting",
+	fusain.ErrorSensorFault:  "Check the thermometer wiring and connectors for open or shorted leads",
+	fusain.ErrorIgnitionFail: "Check the fuel level and pump priming, then the glow plug resistance and supply voltage under load",
+	fusain.ErrorFlameOut:     "Check for air in the fuel line, a clogged fuel filter or screen, and a restricted exhaust",
+	fusain.ErrorMotorStall:   "Check the combustion fan for obstructions or seized bearings and the motor wiring",
+	fusain.ErrorPumpFault:    "Check the fuel pump wiring and connector, and the pump coil resistance",
+}
+
+// errorCodeByName resolves an error code name (NONE, OVERHEAT, ...)
+func errorCodeByName(name string) (fusain.ErrorCode, bool) {
+	for code := fusain.ErrorNone; code <= fusain.ErrorCommandedStop; code++ {
+		if strings.EqualFold(name, fusain.FormatErrorCode(int32(code))) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// validateErrorHints checks the error code names of the config's hints
+func validateErrorHints(hints map[string]string) error {
+	for name := range hints {
+		if _, ok := errorCodeByName(name); !ok {
+			return fmt.Errorf("unknown error code %q (valid: OVERHEAT, SENSOR_FAULT, IGNITION_FAIL, FLAME_OUT, MOTOR_STALL, PUMP_FAULT, COMMANDED_ESTOP)", name)
+		}
+	}
+	return nil
+}
+
+// errorHint returns the troubleshooting hint for an error code, or "" if
+// there is none. Config hints take precedence; an empty one hides the
+// default.
+func errorHint(code fusain.ErrorCode) string {
+	for name, hint := range appConfig().ErrorHints {
+		if c, ok := errorCodeByName(name); ok && c == code {
+			return hint
+		}
+	}
+	return defaultErrorHints[code]
+}
+
+// errorHintLogMessage describes a device entering an error state, with the
+// hint for its error code, for event logs. Returns "" for other state
+// changes.
+func errorHintLogMessage(e events.DeviceStateChanged) string {
+	failed := e.Error || e.State == fusain.SysStateError
+	if !failed || e.Code == fusain.ErrorNone {
+		return ""
+	}
+	msg := fmt.Sprintf("Device %s error %s", formatAddress(e.Address), fusain.FormatErrorCode(int32(e.Code)))
+	if hint := errorHint(e.Code); hint != "" {
+		msg += ": " + hint
+	}
+	return msg
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// eventBus carries events from the connection to every frontend (TUIs,
+// loggers, the watchdog and the daemon control socket)
+var eventBus = events.NewBus()
+
+// packetSource decodes a connection's byte stream and publishes
+// PacketReceived, DecodeError, Synchronized, ValidationAnomaly,
+// AlertRaised, AlertCleared, DeviceStateChanged, DeviceStale, ReadError and
+// ConnectionLost events
+type packetSource struct {
+	bus      *events.Bus
+	validate bool
+
+	// session tracks devices across packets; mu guards it, since stale
+	// devices are checked on a timer while reads block
+	mu      sync.Mutex
+	session *fusain.SessionValidator
+
+	synchronized bool
+	skipped      int
+	states       map[uint64]fusain.StateData
+}
+
+// newPacketSource creates a source for one connection. With validate set,
+// packets are checked with fusain.ValidatePacketWithOptions and a
+// fusain.SessionValidator.
+func newPacketSource(bus *events.Bus, validate bool) *packetSource {
+	s := &packetSource{
+		bus:      bus,
+		validate: validate,
+		states:   make(map[uint64]fusain.StateData),
+	}
+	if validate {
+		s.session = fusain.NewSessionValidator()
+		s.session.StaleTimeout = staleTimeout
+	}
+	return s
+}
+
+// run reads conn until done is closed or the connection is closed.
+// Transient read errors are published and retried.
+func (s *packetSource) run(conn ByteReader, done <-chan struct{}) {
+	if s.session != nil && s.session.StaleTimeout > 0 {
+		go s.watchStale(done)
+	}
+
+	decoder := fusain.NewDecoder()
+	buf := make([]byte, 256)
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			// A new connection starts mid-stream: drop the old one's
+			// partial frame and synchronize again
+			if err == ErrReconnected {
+				decoder = fusain.NewDecoder()
+				s.synchronized = false
+				s.skipped = 0
+				continue
+			}
+			// ErrConnectionClosed means the connection is permanently gone
+			if err == ErrConnectionClosed {
+				s.bus.Publish(events.ConnectionLost{At: time.Now(), Err: err})
+				return
+			}
+			s.bus.Publish(events.ReadError{At: time.Now(), Err: err})
+			// Brief pause before retry on transient errors (e.g., serial)
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		// Errors are published ahead of the packets from the same read,
+		// so every error in the read that synchronizes counts as skipped
+		packets, decodeErrs := decoder.Decode(buf[:n])
+		for _, decodeErr := range decodeErrs {
+			if !s.synchronized {
+				s.skipped++
+			}
+			at := time.Now()
+			reportSinkError(outputSinks.WriteDecodeError(at, decodeErr))
+			s.bus.Publish(events.DecodeError{At: at, Err: decodeErr})
+		}
+		for _, packet := range packets {
+			if deviceFilter.admit(packet) {
+				s.publishPacket(packet)
+			}
+		}
+	}
+}
+
+func (s *packetSource) publishPacket(packet *fusain.Packet) {
+	now := packet.Timestamp()
+	deviceClock.Observe(packet)
+
+	if !s.synchronized {
+		s.synchronized = true
+		s.bus.Publish(events.Synchronized{At: now, Skipped: s.skipped})
+	}
+
+	var anomalies []fusain.ValidationError
+	if s.validate {
+		anomalies = fusain.ValidatePacketWithOptions(packet, validateOptions())
+		s.mu.Lock()
+		anomalies = append(anomalies, s.session.Validate(packet)...)
+		s.mu.Unlock()
+	}
+
+	reportSinkError(outputSinks.WritePacket(now, packet, anomalies))
+	s.bus.Publish(events.PacketReceived{At: now, Packet: packet, Anomalies: anomalies})
+	if len(anomalies) > 0 {
+		s.bus.Publish(events.ValidationAnomaly{At: now, Packet: packet, Anomalies: anomalies})
+	}
+	publishAlerts(s.bus, packet)
+
+	if packet.Type() != fusain.MsgStateData {
+		return
+	}
+	state, err := fusain.DecodeStateData(packet)
+	if err != nil {
+		return
+	}
+	previous, known := s.states[packet.Address()]
+	s.states[packet.Address()] = state
+	if known && previous.State == state.State && previous.Error == state.Error {
+		return
+	}
+	s.bus.Publish(events.DeviceStateChanged{
+		At:       now,
+		Address:  packet.Address(),
+		Known:    known,
+		Previous: previous.State,
+		State:    state.State,
+		Error:    state.Error,
+		Code:     state.Code,
+	})
+}
+
+// watchStale publishes DeviceStale for devices that go silent, until done
+// is closed
+func (s *packetSource) watchStale(done <-chan struct{}) {
+	ticker := time.NewTicker(s.session.StaleTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			stale := s.session.Stale(now)
+			s.mu.Unlock()
+			for _, anomaly := range stale {
+				address, _ := anomaly.Details["address"].(uint64)
+				lastSeen, _ := anomaly.Details["last_seen"].(time.Time)
+				s.bus.Publish(events.DeviceStale{At: now, Address: address, LastSeen: lastSeen, Anomaly: anomaly})
+			}
+		}
+	}
+}
+
+// eventBatchMsg carries events to a TUI in publish order
+type eventBatchMsg struct {
+	events []events.Event
+}
+
+// forwardEvents sends the subscription's events to a TUI program in
+// batches at a fixed rate (prevents TUI glitches) until done is closed.
+// An urgent event (emergency stop) is sent at once with the batch so far.
+func forwardEvents(sub *events.Subscription, p *tea.Program, done <-chan struct{}) {
+	ticker := time.NewTicker(50 * time.Millisecond) // 20 updates/sec max
+	defer ticker.Stop()
+
+	var batch eventBatchMsg
+	flush := func() {
+		if len(batch.events) > 0 {
+			p.Send(batch)
+			batch = eventBatchMsg{}
+		}
+	}
+
+	in := sub.Events()
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-in:
+			if !ok {
+				in = nil // Subscription closed; flush what's left on the next tick
+				continue
+			}
+			batch.events = append(batch.events, e)
+			if events.IsUrgent(e) {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
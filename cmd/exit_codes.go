@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Process exit codes shared by all subcommands
+const (
+	ExitOK         = 0 // Success
+	ExitFailure    = 1 // Protocol or validation failure
+	ExitConnection = 2 // Connection could not be opened or was lost
+	ExitTimeout    = 3 // Expected response not received in time
+	ExitUserAbort  = 4 // Interrupted by the user (Ctrl+C / SIGINT)
+	ExitTerminated = 5 // Stopped by SIGTERM or SIGHUP (e.g. systemctl stop)
+	ExitAlert      = 6 // Stopped by an alert rule with exit: true
+)
+
+// exitCodeDescriptions documents each exit code for the exit_codes command
+var exitCodeDescriptions = []struct {
+	code        int
+	description string
+}{
+	{ExitOK, "Success"},
+	{ExitFailure, "Protocol or validation failure (bad packet, no devices, rejected command)"},
+	{ExitConnection, "Connection error (could not open port/URL, read or write failed)"},
+	{ExitTimeout, "Timeout (no response or packet within the configured time)"},
+	{ExitUserAbort, "Aborted by user (Ctrl+C / SIGINT)"},
+	{ExitTerminated, "Terminated by SIGTERM or SIGHUP after a clean shutdown"},
+	{ExitAlert, "Stopped by a --rules alert rule with exit: true, after a clean shutdown"},
+}
+
+// ExitError is an error that carries the process exit code to use
+type ExitError struct {
+	Code int
+	Err  error // May be nil when the command already reported the failure
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// exitErrorf creates an ExitError with a formatted message
+func exitErrorf(code int, format string, args ...interface{}) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// exitSilently creates an ExitError for failures the command has already
+// printed a report for
+func exitSilently(code int) error {
+	return &ExitError{Code: code}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitFailure
+}
+
+var exitCodesCmd = &cobra.Command{
+	Use:     "exit_codes",
+	Aliases: []string{"exit-codes"},
+	Short:   "Describe the exit codes used by all commands",
+	Long: `Print the exit code scheme shared by all heliostat commands.
+
+Scripts can branch on these codes, for example:
+
+  heliostat discovery --port /dev/ttyUSB0
+  case $? in
+    0) echo "found devices" ;;
+    3) echo "timed out" ;;
+  esac`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, e := range exitCodeDescriptions {
+			fmt.Printf("  %d  %s\n", e.code, e.description)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exitCodesCmd)
+}
@@ -0,0 +1,368 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat  string
+	exportSplitBy string
+	exportOutput  string
+	exportTypes   []string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export CAPTURE...",
+	Short: "Export capture files as CSV or JSON Lines",
+	Long: `Decode capture files written by 'heliostat record' and export the packets as
+CSV or JSON Lines, with payload fields named as in the protocol schema
+(e.g. rpm, target, reading).
+
+--split-by type writes one file per message type, each with its own
+columns, so MOTOR_DATA and TEMP_DATA load straight into separate data
+frames. The files are written in parallel. Without it, every packet goes
+to one file; CSV rows then hold the named fields as a JSON object.
+
+Files are named from -o (default: the first capture's name without its
+extension): session.csv, or session-motor_data.csv, session-temp_data.csv,
+... with --split-by type. -o - writes a single export to stdout.
+
+Frames that fail to decode are skipped and counted. --type and the global
+--allow-device/--deny-device flags select packets.
+
+Examples:
+  heliostat export session.cap --split-by type
+  heliostat export bench-0001.cap bench-0002.cap --format jsonl -o bench
+  heliostat export session.cap --type MOTOR_DATA -o - | head`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "csv", "Output format: csv or jsonl")
+	exportCmd.Flags().StringVar(&exportSplitBy, "split-by", "none", "Split the export into files: none or type")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file name prefix, or - for stdout (default: first capture name)")
+	exportCmd.Flags().StringSliceVar(&exportTypes, "type", nil, "Export only these message types (names or numbers)")
+}
+
+// exportColumns returns the field columns for a message type: the schema
+// fields, or a single raw payload column for types without a schema
+func exportColumns(msgType uint8) []string {
+	schema, ok := fusain.LookupSchema(msgType)
+	if !ok {
+		return []string{"payload"}
+	}
+	columns := make([]string, len(schema.Fields))
+	for i, f := range schema.Fields {
+		columns[i] = f.Name
+	}
+	return columns
+}
+
+// csvValue formats a CBOR value for a CSV cell
+func csvValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case bool:
+		return strconv.FormatBool(x)
+	case []byte:
+		return hex.EncodeToString(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonFieldValue makes a CBOR value JSON-encodable (byte strings as hex)
+func jsonFieldValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return hex.EncodeToString(b)
+	}
+	return v
+}
+
+// exportFile writes the packets of one output file. Each file is written by
+// its own goroutine, fed through packets.
+type exportFile struct {
+	name    string
+	msgType uint8 // Message type of a --split-by type file
+	split   bool
+
+	packets chan *fusain.Packet
+	count   uint64
+	err     error
+}
+
+// run writes every packet received on f.packets to w
+func (f *exportFile) run(w io.Writer) {
+	out := bufio.NewWriter(w)
+	write := f.writer(out)
+	for p := range f.packets {
+		if f.err != nil {
+			continue // Drain, so the dispatcher never blocks
+		}
+		if f.err = write(p); f.err == nil {
+			f.count++
+		}
+	}
+	if f.err == nil {
+		f.err = out.Flush()
+	}
+}
+
+// writer returns the record encoder for the file's format and layout
+func (f *exportFile) writer(out *bufio.Writer) func(p *fusain.Packet) error {
+	common := func(p *fusain.Packet) (string, string) {
+		return p.Timestamp().Format(time.RFC3339Nano), fmt.Sprintf("%016X", p.Address())
+	}
+
+	if exportFormat == "jsonl" {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		return func(p *fusain.Packet) error {
+			at, address := common(p)
+			fields := map[string]interface{}{}
+			for _, field := range fusain.PayloadFields(p) {
+				fields[field.Name] = jsonFieldValue(field.Value)
+			}
+			return enc.Encode(struct {
+				Time    string                 `json:"time"`
+				Address string                 `json:"address"`
+				Type    string                 `json:"type"`
+				Fields  map[string]interface{} `json:"fields"`
+			}{at, address, fusain.FormatMessageType(p.Type()), fields})
+		}
+	}
+
+	w := csv.NewWriter(out)
+	header := false
+	if !f.split {
+		return func(p *fusain.Packet) error {
+			if !header {
+				header = true
+				w.Write([]string{"time", "address", "type", "fields"})
+			}
+			at, address := common(p)
+			fields := map[string]interface{}{}
+			for _, field := range fusain.PayloadFields(p) {
+				fields[field.Name] = jsonFieldValue(field.Value)
+			}
+			encoded, err := json.Marshal(fields)
+			if err != nil {
+				return err
+			}
+			w.Write([]string{at, address, fusain.FormatMessageType(p.Type()), string(encoded)})
+			w.Flush()
+			return w.Error()
+		}
+	}
+
+	columns := exportColumns(f.msgType)
+	index := make(map[string]int, len(columns))
+	for i, c := range columns {
+		index[c] = i
+	}
+	return func(p *fusain.Packet) error {
+		if !header {
+			header = true
+			w.Write(append([]string{"time", "address"}, columns...))
+		}
+		at, address := common(p)
+		row := make([]string, 2+len(columns))
+		row[0], row[1] = at, address
+		if len(columns) == 1 && columns[0] == "payload" {
+			row[2] = hex.EncodeToString(p.Payload())
+		} else {
+			for _, field := range fusain.PayloadFields(p) {
+				if i, ok := index[field.Name]; ok {
+					row[2+i] = csvValue(field.Value)
+				}
+			}
+		}
+		w.Write(row)
+		w.Flush()
+		return w.Error()
+	}
+}
+
+// exportTypeName names a message type in a --split-by type file name
+func exportTypeName(msgType uint8) string {
+	if name := fusain.FormatMessageType(msgType); name != "UNKNOWN" {
+		return strings.ToLower(name)
+	}
+	return fmt.Sprintf("type_%02x", msgType)
+}
+
+// exportPrefix returns the output name prefix from -o or the first capture
+func exportPrefix(captures []string) string {
+	if exportOutput != "" {
+		return exportOutput
+	}
+	return strings.TrimSuffix(captures[0], filepath.Ext(captures[0]))
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	var ext string
+	switch exportFormat {
+	case "csv":
+		ext = ".csv"
+	case "jsonl":
+		ext = ".jsonl"
+	default:
+		return fmt.Errorf("invalid --format %q (valid: csv, jsonl)", exportFormat)
+	}
+	split := false
+	switch exportSplitBy {
+	case "", "none":
+	case "type":
+		split = true
+	default:
+		return fmt.Errorf("invalid --split-by %q (valid: none, type)", exportSplitBy)
+	}
+	if split && exportOutput == "-" {
+		return fmt.Errorf("--split-by type writes several files; -o - is not supported")
+	}
+	include, err := parseMessageTypeSet(exportTypes)
+	if err != nil {
+		return fmt.Errorf("invalid --type: %v", err)
+	}
+
+	prefix := exportPrefix(args)
+	files := make(map[uint8]*exportFile)
+	var order []*exportFile
+	var wg sync.WaitGroup
+	var closers []io.Closer
+
+	// open starts the writer goroutine for a new output file
+	open := func(msgType uint8) (*exportFile, error) {
+		f := &exportFile{msgType: msgType, split: split, packets: make(chan *fusain.Packet, 256)}
+		var w io.Writer
+		switch {
+		case exportOutput == "-":
+			f.name = "stdout"
+			w = os.Stdout
+		default:
+			f.name = prefix + ext
+			if split {
+				f.name = prefix + "-" + exportTypeName(msgType) + ext
+			}
+			file, err := os.Create(f.name)
+			if err != nil {
+				return nil, err
+			}
+			closers = append(closers, file)
+			w = file
+		}
+		order = append(order, f)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.run(w)
+		}()
+		return f, nil
+	}
+
+	// finish waits for the writers and closes the files
+	finish := func() error {
+		for _, f := range order {
+			close(f.packets)
+		}
+		wg.Wait()
+		var errs []error
+		for _, f := range order {
+			if f.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.name, f.err))
+			}
+		}
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	capture := newCaptureReader(args, 0, false)
+	var skipped uint64
+	for {
+		frame, err := capture.nextFrame()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			finish()
+			return err
+		}
+		p, err := frame.Packet()
+		if err != nil {
+			skipped++
+			continue
+		}
+		if include != nil && !include[p.Type()] || !deviceFilter.admit(p) {
+			continue
+		}
+
+		key := uint8(0)
+		if split {
+			key = p.Type()
+		}
+		f, ok := files[key]
+		if !ok {
+			if f, err = open(key); err != nil {
+				finish()
+				return err
+			}
+			files[key] = f
+		}
+		f.packets <- p
+	}
+	if err := finish(); err != nil {
+		return exitErrorf(ExitFailure, "export failed: %v", err)
+	}
+
+	var total uint64
+	for _, f := range order {
+		total += f.count
+	}
+	if exportOutput == "-" {
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Skipped %d undecodable frames\n", skipped)
+		}
+		return nil
+	}
+	fmt.Printf("Exported %d packets to %d files", total, len(order))
+	if skipped > 0 {
+		fmt.Printf(" (skipped %d undecodable frames)", skipped)
+	}
+	fmt.Println()
+	for _, f := range order {
+		fmt.Printf("  %-40s %d packets\n", f.name, f.count)
+	}
+	return nil
+}
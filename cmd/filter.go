@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	filterTypes        []string
+	filterExcludeTypes []string
+	filterFormat       string
+	filterDropInvalid  bool
+	filterOnlyInvalid  bool
+	filterVerbose      bool
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "Filter framed packets from stdin to stdout",
+	Long: `Read raw framed Fusain bytes on stdin, keep the packets that pass the
+filters, and write them to stdout, so heliostat can sit in a Unix pipeline
+between socat, tee and other tools.
+
+Packets are selected by:
+  --type / --exclude-type         message types (names or numbers, repeatable)
+  --allow-device / --deny-device  device addresses (global flags)
+  --drop-invalid / --only-invalid validation result, as in error_detection
+                                  (limits from the config, --skip-schema)
+
+Output is re-encoded wire frames (--format frames, the default) or one JSON
+packet per line (--format json), in the JSON packet format used by 'send'.
+Frames that fail CRC or decoding are dropped; -v reports them and a summary
+on stderr.
+
+Examples:
+  socat -u /dev/ttyUSB0,raw,b115200 - | heliostat filter --type STATE_DATA --format json
+  heliostat filter --deny-device 0123456789ABCDEF < in.bin > out.bin`,
+	Args: cobra.NoArgs,
+	RunE: runFilter,
+}
+
+func init() {
+	rootCmd.AddCommand(filterCmd)
+	filterCmd.Flags().StringSliceVar(&filterTypes, "type", nil, "Keep only these message types (names or numbers)")
+	filterCmd.Flags().StringSliceVar(&filterExcludeTypes, "exclude-type", nil, "Drop these message types (names or numbers)")
+	filterCmd.Flags().StringVar(&filterFormat, "format", "frames", "Output format: frames or json")
+	filterCmd.Flags().BoolVar(&filterDropInvalid, "drop-invalid", false, "Drop packets with validation anomalies")
+	filterCmd.Flags().BoolVar(&filterOnlyInvalid, "only-invalid", false, "Keep only packets with validation anomalies")
+	filterCmd.Flags().BoolVar(&skipSchema, "skip-schema", false, "Skip protocol schema checks (required keys, CBOR types, enum ranges)")
+	filterCmd.Flags().BoolVarP(&filterVerbose, "verbose", "v", false, "Report decode errors and a summary on stderr")
+	filterCmd.MarkFlagsMutuallyExclusive("drop-invalid", "only-invalid")
+}
+
+// parseMessageTypeSet parses message type flags into a set (nil for an
+// empty list)
+func parseMessageTypeSet(names []string) (map[uint8]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	set := make(map[uint8]bool, len(names))
+	for _, name := range names {
+		t, err := parseMessageTypeFlag(name)
+		if err != nil {
+			return nil, err
+		}
+		set[t] = true
+	}
+	return set, nil
+}
+
+func runFilter(cmd *cobra.Command, args []string) error {
+	include, err := parseMessageTypeSet(filterTypes)
+	if err != nil {
+		return fmt.Errorf("invalid --type: %v", err)
+	}
+	exclude, err := parseMessageTypeSet(filterExcludeTypes)
+	if err != nil {
+		return fmt.Errorf("invalid --exclude-type: %v", err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	var write func(p *fusain.Packet) error
+	switch filterFormat {
+	case "frames":
+		encoder := fusain.NewEncoder(out)
+		write = encoder.WritePacket
+	case "json":
+		encoder := json.NewEncoder(out)
+		write = func(p *fusain.Packet) error { return encoder.Encode(p) }
+	default:
+		return fmt.Errorf("invalid --format %q (valid: frames, json)", filterFormat)
+	}
+
+	validate := filterDropInvalid || filterOnlyInvalid
+	session := fusain.NewSessionValidator()
+	session.StaleTimeout = 0
+
+	decoder := fusain.NewDecoder()
+	buf := make([]byte, 4096)
+	var in, kept, decodeErrors uint64
+
+	for {
+		n, readErr := os.Stdin.Read(buf)
+		packets, errs := decoder.Decode(buf[:n])
+		decodeErrors += uint64(len(errs))
+		if filterVerbose {
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "filter: %v\n", err)
+			}
+		}
+
+		for _, p := range packets {
+			in++
+			if include != nil && !include[p.Type()] || exclude[p.Type()] || !deviceFilter.admit(p) {
+				continue
+			}
+			if validate {
+				anomalies := fusain.ValidatePacketWithOptions(p, validateOptions())
+				anomalies = append(anomalies, session.Validate(p)...)
+				if (len(anomalies) > 0) != filterOnlyInvalid {
+					continue
+				}
+			}
+			if err := write(p); err != nil {
+				return exitErrorf(ExitFailure, "write failed: %v", err)
+			}
+			kept++
+		}
+
+		// Flush per read so packets keep flowing through slow pipelines
+		if err := out.Flush(); err != nil {
+			return exitErrorf(ExitFailure, "write failed: %v", err)
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return exitErrorf(ExitFailure, "read failed: %v", readErr)
+		}
+	}
+
+	if filterVerbose {
+		fmt.Fprintf(os.Stderr, "filter: %d packets in, %d out, %d decode errors\n", in, kept, decodeErrors)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+// fuzzCorpusHeader starts every Go native fuzzing corpus file
+const fuzzCorpusHeader = "go test fuzz v1"
+
+var (
+	triageOutput     string
+	triageNoMinimize bool
+)
+
+var fuzzTriageCmd = &cobra.Command{
+	Use:     "fuzz_triage <input>",
+	Aliases: []string{"fuzz-triage"},
+	Short:   "Minimize a decoder fuzz crash and trace it byte by byte",
+	Long: `Replay a fuzzer crash input through the Fusain decoder, minimize it and
+print a timeline of decoder states.
+
+The input is a Go native fuzzing corpus file (as saved under
+pkg/fusain/testdata/fuzz/FuzzDecode) or a raw binary capture. It is checked
+with fusain.VerifyDecode, the invariant behind the FuzzDecode target. If it
+fails, bytes are removed for as long as it still fails the same way, and the
+minimized reproducer is written in corpus format (default <input>.min), ready
+to be copied into testdata/fuzz/FuzzDecode as a regression case.
+
+The timeline shows, for each byte of the reproducer, the decoder state
+before and after it and what happened: framing bytes, escapes, resyncs,
+completed packets and decode errors.
+
+No connection is needed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFuzzTriage,
+}
+
+func init() {
+	rootCmd.AddCommand(fuzzTriageCmd)
+	fuzzTriageCmd.Flags().StringVarP(&triageOutput, "output", "o", "", "Reproducer file (default <input>.min)")
+	fuzzTriageCmd.Flags().BoolVar(&triageNoMinimize, "no-minimize", false, "Trace the input as is, without minimizing")
+}
+
+func runFuzzTriage(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	data, err := readFuzzInput(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Heliostat - Fuzz Triage\n")
+	fmt.Printf("Input: %s (%d bytes)\n", input, len(data))
+
+	failure := fusain.VerifyDecode(data)
+	if failure == nil {
+		fmt.Printf("Result: input does not reproduce a failure\n\n")
+		printDecoderTimeline(data)
+		return nil
+	}
+	fmt.Printf("Failure: %v\n", failure)
+
+	if !triageNoMinimize {
+		signature := failureSignature(failure)
+		data = minimizeInput(data, func(candidate []byte) bool {
+			err := fusain.VerifyDecode(candidate)
+			return err != nil && failureSignature(err) == signature
+		})
+
+		output := triageOutput
+		if output == "" {
+			output = input + ".min"
+		}
+		if err := os.WriteFile(output, formatFuzzInput(data), 0o644); err != nil {
+			return fmt.Errorf("cannot write reproducer: %v", err)
+		}
+		fmt.Printf("Minimized: %d bytes -> %s\n", len(data), output)
+		fmt.Printf("Failure: %v\n", fusain.VerifyDecode(data))
+	}
+
+	fmt.Println()
+	printDecoderTimeline(data)
+	return nil
+}
+
+// readFuzzInput reads a Go fuzzing corpus file with a single []byte (or
+// string) value, or any other file as raw bytes
+func readFuzzInput(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read input: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte(fuzzCorpusHeader+"\n")) {
+		return raw, nil
+	}
+
+	var values [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		literal, ok := strings.CutPrefix(line, "[]byte(")
+		if !ok {
+			literal, ok = strings.CutPrefix(line, "string(")
+		}
+		literal, closed := strings.CutSuffix(literal, ")")
+		if !ok || !closed {
+			return nil, fmt.Errorf("%s: unsupported corpus value %q (want []byte or string)", path, line)
+		}
+		value, err := strconv.Unquote(literal)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid corpus value %q: %v", path, line, err)
+		}
+		values = append(values, []byte(value))
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("%s: expected one corpus value, found %d", path, len(values))
+	}
+	return values[0], nil
+}
+
+// formatFuzzInput encodes data as a Go fuzzing corpus file
+func formatFuzzInput(data []byte) []byte {
+	return []byte(fmt.Sprintf("%s\n[]byte(%q)\n", fuzzCorpusHeader, data))
+}
+
+// digitRun matches the numbers in a failure message
+var digitRun = regexp.MustCompile(`[0-9]+`)
+
+// failureSignature identifies a kind of failure regardless of the indexes
+// and lengths in its message, so minimization does not drift to another bug
+func failureSignature(err error) string {
+	return digitRun.ReplaceAllString(err.Error(), "N")
+}
+
+// minimizeInput removes chunks of data, halving the chunk size down to
+// single bytes, for as long as fails still reports the failure
+func minimizeInput(data []byte, fails func([]byte) bool) []byte {
+	for chunk := len(data) / 2; chunk >= 1; {
+		removed := false
+		for start := 0; start+chunk <= len(data); {
+			candidate := append(append([]byte{}, data[:start]...), data[start+chunk:]...)
+			if fails(candidate) {
+				data = candidate
+				removed = true
+				continue // Retry the chunk that moved into this position
+			}
+			start += chunk
+		}
+		if !removed {
+			chunk /= 2
+		}
+	}
+	return data
+}
+
+// printDecoderTimeline feeds data through a decoder one byte at a time and
+// prints each state transition
+func printDecoderTimeline(data []byte) {
+	fmt.Printf("%-6s  %-4s  %-18s  %s\n", "Offset", "Byte", "State", "Event")
+
+	decoder := fusain.NewDecoder()
+	escaped := false
+	for i, b := range data {
+		before := decoder.StateName()
+		packet, panicked, err := traceDecodeByte(decoder, b)
+		after := decoder.StateName()
+
+		var event string
+		switch {
+		case panicked != nil:
+			event = fmt.Sprintf("PANIC: %v", panicked)
+		case err != nil:
+			event = fmt.Sprintf("error: %v", err)
+		case packet != nil:
+			event = fmt.Sprintf("packet %s addr=%016X len=%d", fusain.FormatMessageType(packet.Type()), packet.Address(), packet.Length())
+			if parseErr := packet.ParseError(); parseErr != nil {
+				event += fmt.Sprintf(" (CBOR error: %v)", parseErr)
+			}
+		case b == fusain.EscByte && !escaped:
+			event = "ESC (next byte XOR 0x20)"
+		case b == fusain.StartByte && !escaped:
+			event = "START"
+			if before != "IDLE" {
+				event = "START (resync, partial frame dropped)"
+			}
+		case escaped:
+			event = fmt.Sprintf("escaped 0x%02X", b^fusain.EscXor)
+		case before == "IDLE" && after == "IDLE":
+			event = "ignored (waiting for START)"
+		case before == "LENGTH":
+			event = fmt.Sprintf("payload length %d", b)
+		case before == "ADDRESS" && after != "ADDRESS":
+			event = "address complete"
+		case before == "PAYLOAD" && after == "CRC1":
+			event = "payload complete"
+		case before == "CRC2":
+			event = "CRC complete, waiting for END"
+		}
+		escaped = b == fusain.EscByte && !escaped
+
+		fmt.Printf("%6d  %02X    %-18s  %s\n", i, b, before+" -> "+after, event)
+
+		if panicked != nil {
+			return
+		}
+	}
+
+	if state := decoder.StateName(); state != "IDLE" {
+		fmt.Printf("End of input in state %s (incomplete frame)\n", state)
+	}
+}
+
+// traceDecodeByte runs DecodeByte, returning a panic instead of crashing
+func traceDecodeByte(decoder *fusain.Decoder, b byte) (packet *fusain.Packet, panicked interface{}, err error) {
+	defer func() {
+		panicked = recover()
+	}()
+	packet, err = decoder.DecodeByte(b)
+	return packet, nil, err
+}
@@ -0,0 +1,329 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// impairSpecs holds the --impair flag values
+	impairSpecs []string
+
+	// connImpairment is parsed from impairSpecs; nil leaves connections alone
+	connImpairment *impairment
+)
+
+const impairUsage = "Impair the connection for testing: latency=50ms,jitter=20ms,ber=1e-5,drop=1e-4,dir=rx|tx|both,seed=N"
+
+// impairment describes the artificial faults added to a connection
+type impairment struct {
+	latency time.Duration // Added to every read and write
+	jitter  time.Duration // Random ± variation of latency
+	ber     float64       // Probability each bit is flipped
+	drop    float64       // Probability each byte is lost
+	rx, tx  bool          // Directions impaired
+	seed    uint64        // 0 = random
+}
+
+// parseImpairment parses --impair key=value options
+func parseImpairment(specs []string) (*impairment, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	imp := &impairment{rx: true, tx: true}
+	for _, spec := range specs {
+		key, value, found := strings.Cut(strings.TrimSpace(spec), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid --impair %q: expected key=value", spec)
+		}
+		var err error
+		switch key {
+		case "latency", "jitter":
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			if err == nil && d < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+			if key == "latency" {
+				imp.latency = d
+			} else {
+				imp.jitter = d
+			}
+		case "ber", "drop":
+			var p float64
+			p, err = strconv.ParseFloat(value, 64)
+			if err == nil && (p < 0 || p > 1) {
+				err = fmt.Errorf("must be a probability from 0 to 1")
+			}
+			if key == "ber" {
+				imp.ber = p
+			} else {
+				imp.drop = p
+			}
+		case "dir":
+			switch value {
+			case "rx":
+				imp.rx, imp.tx = true, false
+			case "tx":
+				imp.rx, imp.tx = false, true
+			case "both":
+				imp.rx, imp.tx = true, true
+			default:
+				err = fmt.Errorf("must be rx, tx or both")
+			}
+		case "seed":
+			imp.seed, err = strconv.ParseUint(value, 0, 64)
+		default:
+			return nil, fmt.Errorf("invalid --impair %q: unknown option %q (valid: latency, jitter, ber, drop, dir, seed)", spec, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid --impair %q: %v", spec, err)
+		}
+	}
+	return imp, nil
+}
+
+// String describes the impairment for the connection info line
+func (imp *impairment) String() string {
+	var parts []string
+	if imp.latency > 0 {
+		parts = append(parts, "latency="+imp.latency.String())
+	}
+	if imp.jitter > 0 {
+		parts = append(parts, "jitter="+imp.jitter.String())
+	}
+	if imp.ber > 0 {
+		parts = append(parts, "ber="+strconv.FormatFloat(imp.ber, 'g', -1, 64))
+	}
+	if imp.drop > 0 {
+		parts = append(parts, "drop="+strconv.FormatFloat(imp.drop, 'g', -1, 64))
+	}
+	if !imp.tx {
+		parts = append(parts, "dir=rx")
+	} else if !imp.rx {
+		parts = append(parts, "dir=tx")
+	}
+	return strings.Join(parts, ",")
+}
+
+// impairedChunk is a read result waiting for its delivery time
+type impairedChunk struct {
+	data []byte
+	err  error
+	at   time.Time
+}
+
+// impairedConnection wraps a Connection with latency, jitter, bit errors
+// and dropped bytes. Received bytes are read ahead by a goroutine and
+// released after their delay; delays never reorder the stream, so jitter
+// stretches gaps instead of swapping bytes. Writes are delayed before they
+// reach the connection, so a writer waits out the latency like on a slow
+// link; writes are serialized, so jitter can't reorder them.
+type impairedConnection struct {
+	conn Connection
+	imp  impairment
+
+	mu      sync.Mutex // Guards rng and the skip counters
+	rng     *rand.Rand
+	writeMu sync.Mutex // Keeps delayed writes in order
+
+	// Bytes until the next bit error or drop, per direction
+	rxBitSkip, rxDropSkip uint64
+	txBitSkip, txDropSkip uint64
+
+	chunks  chan impairedChunk
+	untrack func()
+	pending []byte
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// impairConnection wraps conn with imp
+func impairConnection(conn Connection, imp *impairment) *impairedConnection {
+	seed := imp.seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	c := &impairedConnection{
+		conn:   conn,
+		imp:    *imp,
+		rng:    rand.New(rand.NewPCG(seed, seed^0x9E3779B97F4A7C15)),
+		closed: make(chan struct{}),
+	}
+	c.rxBitSkip, c.rxDropSkip = c.skip(imp.ber), c.skip(imp.drop)
+	c.txBitSkip, c.txDropSkip = c.skip(imp.ber), c.skip(imp.drop)
+	if imp.rx {
+		c.chunks = make(chan impairedChunk, 256)
+		c.untrack = trackQueue("impair", func() int { return len(c.chunks) }, cap(c.chunks))
+		go c.readLoop()
+	}
+	return c
+}
+
+// skip draws how many trials pass before the next event of probability p
+// (geometric distribution), so rare errors cost nothing per byte
+func (c *impairedConnection) skip(p float64) uint64 {
+	switch {
+	case p <= 0:
+		return math.MaxUint64
+	case p >= 1:
+		return 0
+	}
+	n := math.Floor(math.Log(1-c.rng.Float64()) / math.Log1p(-p))
+	if n >= math.MaxUint64/2 {
+		return math.MaxUint64
+	}
+	return uint64(n)
+}
+
+// delay draws one latency with jitter. Callers hold c.mu.
+func (c *impairedConnection) delay() time.Duration {
+	d := c.imp.latency
+	if c.imp.jitter > 0 {
+		d += time.Duration(c.rng.Int64N(int64(2*c.imp.jitter)+1)) - c.imp.jitter
+	}
+	return max(d, 0)
+}
+
+// corrupt returns data with dropped bytes removed and bit errors applied.
+// Callers hold c.mu.
+func (c *impairedConnection) corrupt(data []byte, bitSkip, dropSkip *uint64) []byte {
+	out := make([]byte, 0, len(data))
+	for _, b := range data {
+		if *dropSkip == 0 {
+			*dropSkip = c.skip(c.imp.drop)
+			continue
+		}
+		if *dropSkip != math.MaxUint64 {
+			*dropSkip--
+		}
+		// bitSkip counts bits: flip every bit error that lands in this byte
+		for bit := uint64(0); bit < 8; {
+			if *bitSkip == math.MaxUint64 {
+				break
+			}
+			if *bitSkip >= 8-bit {
+				*bitSkip -= 8 - bit
+				break
+			}
+			bit += *bitSkip
+			b ^= 1 << bit
+			bit++
+			*bitSkip = c.skip(c.imp.ber)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// readLoop reads the connection and queues each impaired read with its
+// delivery time, until the connection is closed
+func (c *impairedConnection) readLoop() {
+	defer close(c.chunks)
+	buf := make([]byte, 4096)
+	var last time.Time
+	for {
+		n, err := c.conn.Read(buf)
+
+		c.mu.Lock()
+		at := time.Now().Add(c.delay())
+		var data []byte
+		if n > 0 {
+			data = c.corrupt(buf[:n], &c.rxBitSkip, &c.rxDropSkip)
+		}
+		c.mu.Unlock()
+		if at.Before(last) {
+			at = last
+		}
+		last = at
+
+		select {
+		case c.chunks <- impairedChunk{data: data, err: err, at: at}:
+		case <-c.closed:
+			return
+		}
+		if err == ErrConnectionClosed || err == io.EOF {
+			return
+		}
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func (c *impairedConnection) Read(p []byte) (int, error) {
+	if !c.imp.rx {
+		return c.conn.Read(p)
+	}
+	for len(c.pending) == 0 {
+		chunk, ok := <-c.chunks
+		if !ok {
+			return 0, ErrConnectionClosed
+		}
+		if wait := time.Until(chunk.at); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-c.closed:
+				return 0, ErrConnectionClosed
+			}
+		}
+		if chunk.err != nil {
+			return 0, chunk.err
+		}
+		c.pending = chunk.data
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write delays and corrupts p before writing it. Dropped bytes count as
+// written.
+func (c *impairedConnection) Write(p []byte) (int, error) {
+	if !c.imp.tx {
+		return c.conn.Write(p)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.Lock()
+	data := c.corrupt(p, &c.txBitSkip, &c.txDropSkip)
+	delay := c.delay()
+	c.mu.Unlock()
+
+	time.Sleep(delay)
+	if len(data) > 0 {
+		if _, err := c.conn.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush flushes a connection that coalesces writes
+func (c *impairedConnection) Flush() error {
+	if flusher, ok := c.conn.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (c *impairedConnection) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		if c.untrack != nil {
+			c.untrack()
+		}
+	})
+	return c.conn.Close()
+}
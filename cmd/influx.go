@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	influxURL    string
+	influxOrg    string
+	influxBucket string
+)
+
+var influxCmd = &cobra.Command{
+	Use:   "influx",
+	Short: "Write telemetry as InfluxDB line protocol",
+	Long: `Write decoded telemetry as InfluxDB line protocol, to stdout or to an
+InfluxDB v2 server.
+
+Each telemetry packet becomes one point. Measurements are state, motor,
+pump, glow and temperature; every point is tagged with the device address,
+and component telemetry with the motor, pump, glow or thermometer index:
+
+  motor,address=0123456789ABCDEF,motor=0 device_time_ms=5120i,rpm=2480i,target_rpm=2500i 1735830000123456789
+
+Temperatures are in Celsius regardless of --units. Without --influx-url the
+lines are printed to stdout (e.g. for Telegraf's execd input or
+"influx write"); with it they are posted to the v2 write API in batches.
+The API token is read from the INFLUX_TOKEN environment variable.
+
+Examples:
+  heliostat influx -p /dev/ttyUSB0 > telemetry.lp
+  INFLUX_TOKEN=... heliostat influx -p /dev/ttyUSB0 --influx-url http://localhost:8086 --org home --bucket heaters`,
+	Args: cobra.NoArgs,
+	RunE: runInflux,
+}
+
+func init() {
+	rootCmd.AddCommand(influxCmd)
+	influxCmd.Flags().StringVar(&influxURL, "influx-url", "", "InfluxDB server URL (default: write to stdout)")
+	influxCmd.Flags().StringVar(&influxOrg, "org", "", "InfluxDB organization")
+	influxCmd.Flags().StringVar(&influxBucket, "bucket", "", "InfluxDB bucket")
+}
+
+func runInflux(cmd *cobra.Command, args []string) error {
+	sink := sinks.NewInfluxWriter(os.Stdout)
+	if influxURL != "" {
+		var err error
+		sink, err = sinks.NewInfluxHTTP(sinks.InfluxConfig{
+			URL:    influxURL,
+			Org:    influxOrg,
+			Bucket: influxBucket,
+			Token:  os.Getenv("INFLUX_TOKEN"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The sink is fed from packetSource and closed with the other outputs
+	outputSinks.AddTelemetrySink(sink)
+
+	if influxURL != "" {
+		fmt.Printf("Writing telemetry from %s to %s (bucket %s)\n", connInfo, influxURL, influxBucket)
+	}
+
+	sub := eventBus.SubscribeLossless(256)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, false).run(conn, done)
+
+	for e := range sub.Events() {
+		if e, ok := e.(events.ConnectionLost); ok {
+			return exitErrorf(ExitConnection, "connection lost: %v", e.Err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// unlockInterlocks is set by --unlock to bypass configured interlocks
+var unlockInterlocks bool
+
+// Interlockable command names
+const (
+	interlockHeat  = "heat"  // STATE_COMMAND mode HEAT
+	interlockFan   = "fan"   // STATE_COMMAND mode FAN
+	interlockGlow  = "glow"  // GLOW_COMMAND
+	interlockPump  = "pump"  // PUMP_COMMAND
+	interlockMotor = "motor" // MOTOR_COMMAND
+)
+
+// defaultInterlockCommands are locked when a rule lists no commands
+var defaultInterlockCommands = []string{interlockHeat, interlockGlow}
+
+// InterlockRule forbids commands to a device (or to all devices when Device
+// is empty) unless --unlock is given. Useful for bench units with fuel lines
+// or glow plugs disconnected.
+type InterlockRule struct {
+	Device   string   `json:"device,omitempty"`   // Hex address; empty = all devices
+	Commands []string `json:"commands,omitempty"` // heat, fan, glow, pump, motor; empty = heat, glow
+}
+
+func (r InterlockRule) validate() error {
+	if r.Device != "" {
+		if _, err := parseAddress(r.Device); err != nil {
+			return err
+		}
+	}
+	for _, name := range r.Commands {
+		switch strings.ToLower(name) {
+		case interlockHeat, interlockFan, interlockGlow, interlockPump, interlockMotor:
+		default:
+			return fmt.Errorf("unknown command %q (valid: heat, fan, glow, pump, motor)", name)
+		}
+	}
+	return nil
+}
+
+// appliesTo reports whether the rule covers commands sent to address.
+// Global rules also cover broadcasts.
+func (r InterlockRule) appliesTo(address uint64) bool {
+	if r.Device == "" {
+		return true
+	}
+	device, _ := parseAddress(r.Device)
+	return device == address || address == fusain.AddressBroadcast
+}
+
+// locks reports whether the rule forbids the named command
+func (r InterlockRule) locks(command string) bool {
+	commands := r.Commands
+	if len(commands) == 0 {
+		commands = defaultInterlockCommands
+	}
+	for _, name := range commands {
+		if strings.EqualFold(name, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// interlockCommand returns the interlock name of a command packet, or ""
+// for packets interlocks never apply to (including emergency stop)
+func interlockCommand(p *fusain.Packet) string {
+	switch p.Type() {
+	case fusain.MsgStateCommand:
+		mode, _ := fusain.GetMapUint(p.PayloadMap(), 0)
+		switch fusain.Mode(mode) {
+		case fusain.ModeHeat:
+			return interlockHeat
+		case fusain.ModeFan:
+			return interlockFan
+		}
+	case fusain.MsgGlowCommand:
+		return interlockGlow
+	case fusain.MsgPumpCommand:
+		return interlockPump
+	case fusain.MsgMotorCommand:
+		return interlockMotor
+	}
+	return ""
+}
+
+// checkInterlocks refuses commands locked by the config unless --unlock
+// was given. A broadcast is refused if any device rule would lock it.
+func checkInterlocks(rules []InterlockRule, p *fusain.Packet) error {
+	if unlockInterlocks {
+		return nil
+	}
+	command := interlockCommand(p)
+	if command == "" {
+		return nil
+	}
+
+	for _, rule := range rules {
+		if !rule.appliesTo(p.Address()) || !rule.locks(command) {
+			continue
+		}
+		target := "all devices"
+		if device, err := parseAddress(rule.Device); err == nil && rule.Device != "" {
+			target = fmt.Sprintf("device %s", formatAddress(device))
+		}
+		return fmt.Errorf("%s is interlocked for %s (use --unlock to override)", strings.ToUpper(command), target)
+	}
+	return nil
+}
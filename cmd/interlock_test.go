@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func TestCheckInterlocks(t *testing.T) {
+	heat := func(address uint64) *fusain.Packet {
+		return fusain.NewStateCommand(address, uint8(fusain.ModeHeat), nil)
+	}
+	fan := func(address uint64) *fusain.Packet {
+		return fusain.NewStateCommand(address, uint8(fusain.ModeFan), nil)
+	}
+	estop := fusain.NewStateCommand(fusain.AddressBroadcast, uint8(fusain.ModeEmergency), nil)
+	global := []InterlockRule{{}} // Default commands: heat, glow
+	device := []InterlockRule{{Device: "1", Commands: []string{"FAN", "pump"}}}
+
+	tests := []struct {
+		name   string
+		rules  []InterlockRule
+		packet *fusain.Packet
+		locked bool
+	}{
+		{"no rules", nil, heat(1), false},
+		{"default heat", global, heat(1), true},
+		{"default glow", global, fusain.NewGlowCommand(1, 0, 1000), true},
+		{"default fan", global, fan(1), false},
+		{"default pump", global, fusain.NewPumpCommand(1, 0, 500), false},
+		{"default idle", global, fusain.NewStateCommand(1, uint8(fusain.ModeIdle), nil), false},
+		{"global broadcast", global, heat(fusain.AddressBroadcast), true},
+		{"device fan", device, fan(1), true},
+		{"device pump", device, fusain.NewPumpCommand(1, 0, 500), true},
+		{"device heat", device, heat(1), false},
+		{"other device", device, fan(2), false},
+		{"device broadcast", device, fan(fusain.AddressBroadcast), true},
+		{"motor", []InterlockRule{{Commands: []string{"motor"}}}, fusain.NewMotorCommand(1, 0, 2000), true},
+		{"emergency stop", []InterlockRule{{Commands: []string{"heat", "fan", "glow", "pump", "motor"}}}, estop, false},
+		{"not a command", global, stateData(1, fusain.SysStateHeating), false},
+	}
+	for _, tt := range tests {
+		if err := checkInterlocks(tt.rules, tt.packet); (err != nil) != tt.locked {
+			t.Errorf("%s: got %v, want locked=%v", tt.name, err, tt.locked)
+		}
+	}
+}
+
+func TestCheckInterlocksUnlock(t *testing.T) {
+	saved := unlockInterlocks
+	t.Cleanup(func() { unlockInterlocks = saved })
+	unlockInterlocks = true
+
+	rules := []InterlockRule{{}, {Device: "1", Commands: []string{"fan"}}}
+	for _, p := range []*fusain.Packet{
+		fusain.NewStateCommand(1, uint8(fusain.ModeHeat), nil),
+		fusain.NewStateCommand(fusain.AddressBroadcast, uint8(fusain.ModeFan), nil),
+		fusain.NewGlowCommand(1, 0, 1000),
+	} {
+		if err := checkInterlocks(rules, p); err != nil {
+			t.Errorf("%s with --unlock: %v", fusain.FormatMessageType(p.Type()), err)
+		}
+	}
+}
+
+func TestInterlockRuleValidate(t *testing.T) {
+	valid := []InterlockRule{{}, {Device: "0x1A", Commands: []string{"Heat", "motor"}}}
+	for _, r := range valid {
+		if err := r.validate(); err != nil {
+			t.Errorf("%+v: %v", r, err)
+		}
+	}
+	invalid := []InterlockRule{{Device: "kitchen"}, {Commands: []string{"ignite"}}}
+	for _, r := range invalid {
+		if r.validate() == nil {
+			t.Errorf("%+v: expected an error", r)
+		}
+	}
+}
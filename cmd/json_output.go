@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+)
+
+// outputFormat holds the --output flag value
+var outputFormat string
+
+const outputUsage = "Output format: text, or json for JSON Lines (one object per packet, error and statistics snapshot)"
+
+// jsonOutput reports whether --output selects JSON Lines
+func jsonOutput() (bool, error) {
+	switch outputFormat {
+	case "", "text":
+		return false, nil
+	case "json", "jsonl":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid --output %q: must be text or json", outputFormat)
+}
+
+// jsonRecord is one line of --output json. Kind is one of "packet",
+// "decode_error", "sync", "stale", "alert", "stats", "connection_lost" or
+// "connection_restored"; the other fields are set as they apply.
+type jsonRecord struct {
+	Kind       string                   `json:"kind"`
+	Time       time.Time                `json:"time"`
+	Address    string                   `json:"address,omitempty"`
+	Packet     *fusain.Packet           `json:"packet,omitempty"`
+	Anomalies  []fusain.ValidationError `json:"anomalies,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	Skipped    int                      `json:"skipped,omitempty"`
+	Stats      *fusain.Statistics       `json:"stats,omitempty"`
+	Alert      *sinks.Alert             `json:"alert,omitempty"`
+	Connection string                   `json:"connection,omitempty"`
+}
+
+// jsonLines writes records to stdout, one JSON object per line
+type jsonLines struct {
+	enc *json.Encoder
+}
+
+func newJSONLines() *jsonLines {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	return &jsonLines{enc: enc}
+}
+
+// write encodes r. Records that cannot be encoded are reported on stderr
+// so stdout stays parseable.
+func (j *jsonLines) write(r jsonRecord) {
+	if err := j.enc.Encode(r); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot encode %s record: %v\n", r.Kind, err)
+	}
+}
+
+func (j *jsonLines) packet(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) {
+	j.write(jsonRecord{Kind: "packet", Time: at, Packet: p, Anomalies: anomalies})
+}
+
+func (j *jsonLines) decodeError(at time.Time, err error) {
+	j.write(jsonRecord{Kind: "decode_error", Time: at, Error: err.Error()})
+}
+
+func (j *jsonLines) stats(stats *fusain.Statistics) {
+	j.write(jsonRecord{Kind: "stats", Time: time.Now(), Stats: stats})
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TUI actions that can be bound to other keys in the config file
+const (
+	actionQuit        = "quit"         // Both TUIs
+	actionEstop       = "estop"        // Control: emergency stop (also confirms it)
+	actionCharts      = "charts"       // Control: show or hide the charts
+	actionAbort       = "abort"        // Control: abort heating
+	actionConfig      = "config"       // Control: device config editor
+	actionName        = "name"         // Control: name the selected device
+	actionExport      = "export"       // Control: export sent commands as a script
+	actionDeviceClock = "device_clock" // Error detection: device timestamps
+	actionInspect     = "inspect"      // Error detection: packet inspector
+)
+
+// defaultKeys are the keys of each action unless the config file rebinds it
+var defaultKeys = map[string][]string{
+	actionQuit:        {"q"},
+	actionEstop:       {"E", "f12"},
+	actionCharts:      {"c"},
+	actionAbort:       {"x"},
+	actionConfig:      {"o"},
+	actionName:        {"n"},
+	actionExport:      {"w"},
+	actionDeviceClock: {"t"},
+	actionInspect:     {"i"},
+}
+
+// reservedKeys keep their meaning in every TUI and cannot be bound:
+// navigation, text entry and the scrollback and inspector keys
+var reservedKeys = map[string]bool{
+	"ctrl+c": true, "esc": true, "enter": true, "tab": true, "shift+tab": true,
+	"up": true, "down": true, "k": true, "j": true, "pgup": true, "pgdown": true,
+	"home": true, "end": true, "g": true, "G": true, " ": true, "/": true,
+	"+": true, "-": true, "backspace": true, "delete": true,
+}
+
+// keyActions maps each bound key to its action, set by setupKeys
+var keyActions = bindKeys(nil)
+
+// bindKeys returns the key-to-action map for the default bindings with
+// overrides replacing an action's keys
+func bindKeys(overrides map[string][]string) map[string]string {
+	bound := make(map[string]string)
+	for action, keys := range defaultKeys {
+		if custom, ok := overrides[action]; ok {
+			keys = custom
+		}
+		for _, key := range keys {
+			bound[key] = action
+		}
+	}
+	return bound
+}
+
+// validateKeys checks the config file's key bindings: known actions, at
+// least one key each, no reserved keys and no key bound twice
+func validateKeys(overrides map[string][]string) error {
+	for action, keys := range overrides {
+		if _, ok := defaultKeys[action]; !ok {
+			return fmt.Errorf("unknown action %q (valid: %s)", action, strings.Join(sortedKeys(defaultKeys), ", "))
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("%s has no keys", action)
+		}
+		for _, key := range keys {
+			if key == "" || reservedKeys[key] {
+				return fmt.Errorf("%s: key %q is reserved", action, key)
+			}
+		}
+	}
+
+	seen := make(map[string]string)
+	for _, action := range sortedKeys(defaultKeys) {
+		keys, ok := overrides[action]
+		if !ok {
+			keys = defaultKeys[action]
+		}
+		for _, key := range keys {
+			if other, ok := seen[key]; ok {
+				return fmt.Errorf("key %q is bound to both %s and %s", key, other, action)
+			}
+			seen[key] = action
+		}
+	}
+	return nil
+}
+
+// setupKeys applies the config file's key bindings
+func setupKeys() {
+	keyActions = bindKeys(appConfig().TUI.Keys)
+}
+
+// keyAction returns the action bound to a key, or "" if there is none
+func keyAction(key string) string {
+	return keyActions[key]
+}
+
+// keyHelp returns an action's keys for help text, e.g. "E/F12"
+func keyHelp(action string) string {
+	var keys []string
+	for _, key := range sortedKeys(keyActions) {
+		if keyActions[key] == action {
+			keys = append(keys, formatKey(key))
+		}
+	}
+	return strings.Join(keys, "/")
+}
+
+// formatKey spells a key the way help text does (F12 rather than f12)
+func formatKey(key string) string {
+	if len(key) > 1 && key[0] == 'f' && strings.Trim(key[1:], "0123456789") == "" {
+		return strings.ToUpper(key)
+	}
+	return key
+}
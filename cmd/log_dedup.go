@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// noDedup holds the --no-dedup flag value
+var noDedup bool
+
+// numberPattern matches the numbers repeatKey masks, and device addresses,
+// which it keeps
+var numberPattern = regexp.MustCompile(`\b(0x[0-9A-Fa-f]+|[0-9A-F]{16}|[0-9]+(\.[0-9]+)?)\b`)
+
+// repeatKey returns the text entries are compared by when collapsing
+// repeats: the message with its numbers masked, so a storm of "CRC
+// mismatch: expected 0x95D2, got 0xFBB2" lines with different CRCs is one
+// run. Device addresses are kept, so each device's errors stay apart.
+func repeatKey(message string) string {
+	return numberPattern.ReplaceAllStringFunc(message, func(n string) string {
+		if len(n) == 16 {
+			return n
+		}
+		return "#"
+	})
+}
+
+// logRepeats collapses runs of repeated entries in text output (the same
+// apart from numbers, see repeatKey): the first entry of a run is printed,
+// the rest are only counted, and a summary with the count and the run's
+// first and last timestamps is printed when the run ends. The zero value
+// is ready to use.
+type logRepeats struct {
+	key   string
+	count int // Entries after the first
+	first time.Time
+	last  time.Time
+}
+
+// add records an entry identified by key (its text without the timestamp).
+// It reports whether the entry repeats the previous one and should not be
+// printed, and returns the summary of the run it ended, if any, to print
+// first.
+func (r *logRepeats) add(key string, at time.Time) (repeat bool, summary string) {
+	key = repeatKey(key)
+	if !noDedup && key == r.key && r.key != "" {
+		r.count++
+		r.last = at
+		return true, ""
+	}
+	summary = r.flush()
+	r.key, r.first, r.last = key, at, at
+	return false, summary
+}
+
+// flush ends the current run, returning its summary, or "" if its entry
+// was not repeated. Call it before printing anything else, so only
+// consecutive entries are collapsed.
+func (r *logRepeats) flush() string {
+	var summary string
+	if r.count > 0 {
+		summary = fmt.Sprintf("  (%d more like this, %s to %s)",
+			r.count, r.first.Format("15:04:05.000"), r.last.Format("15:04:05.000"))
+	}
+	*r = logRepeats{}
+	return summary
+}
+
+// collapseRepeat folds entry into the last entry of a TUI event log when
+// both have the same severity and message apart from numbers (see
+// repeatKey), reporting whether it did. The first message is kept.
+// Emergency entries are never folded, so each one stays visible.
+func collapseRepeat(log []errorLogEntry, entry errorLogEntry) bool {
+	if noDedup || len(log) == 0 || entry.emergency {
+		return false
+	}
+	last := &log[len(log)-1]
+	if last.emergency || last.isError != entry.isError || repeatKey(last.message) != repeatKey(entry.message) {
+		return false
+	}
+	last.repeats++
+	last.lastSeen = entry.timestamp
+	return true
+}
+
+// repeatSuffix describes how often an event log entry repeated, e.g.
+// " (x12, last 15:04:05.000)", or returns "" for a single entry
+func (e errorLogEntry) repeatSuffix() string {
+	if e.repeats == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (x%d, last %s)", e.repeats+1, e.lastSeen.Format("15:04:05.000"))
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+var metricsAddr string
+
+// Pipeline metrics: channel depths registered by the stages running in this
+// process, and the record flush loop's tick latency
+var (
+	metricsMu     sync.Mutex
+	metricsQueues = map[string]queueGauge{}
+	batchTicks    tickStats
+)
+
+// queueGauge reports a channel's depth
+type queueGauge struct {
+	depth    func() int
+	capacity int
+}
+
+// tickStats accumulates the latency of a periodic loop: how long each tick
+// took, and how late it started
+type tickStats struct {
+	count   uint64
+	total   time.Duration
+	slowest time.Duration
+	lag     time.Duration
+}
+
+func init() {
+	expvar.Publish("heliostat", expvar.Func(func() any { return metricsSnapshot() }))
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics", "", "Serve queue depth, goroutine and tick latency metrics over HTTP on this address (e.g. localhost:9464): /metrics (Prometheus) and /debug/vars (expvar)")
+}
+
+// trackQueue reports a channel's depth as queue name until the returned
+// function is called
+func trackQueue(name string, depth func() int, capacity int) (untrack func()) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metricsQueues[name] = queueGauge{depth: depth, capacity: capacity}
+	return func() {
+		metricsMu.Lock()
+		defer metricsMu.Unlock()
+		delete(metricsQueues, name)
+	}
+}
+
+// recordBatchTick records one tick of the record flush loop, scheduled at
+// scheduled and started at start
+func recordBatchTick(scheduled, start time.Time) {
+	took := time.Since(start)
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	batchTicks.count++
+	batchTicks.total += took
+	batchTicks.slowest = max(batchTicks.slowest, took)
+	batchTicks.lag = start.Sub(scheduled)
+}
+
+// metricsValues is one snapshot of every metric
+type metricsValues struct {
+	Goroutines int                    `json:"goroutines"`
+	Queues     map[string]queueValues `json:"queues"`
+	Events     eventValues            `json:"events"`
+	BatchTicks tickValues             `json:"batch_ticks"`
+}
+
+type queueValues struct {
+	Depth    int `json:"depth"`
+	Capacity int `json:"capacity"`
+}
+
+type eventValues struct {
+	Subscribers    int     `json:"subscribers"`
+	MaxFill        float64 `json:"max_fill"`
+	Dropped        uint64  `json:"dropped"`
+	BlockedSeconds float64 `json:"blocked_seconds"`
+}
+
+type tickValues struct {
+	Count          uint64  `json:"count"`
+	TotalSeconds   float64 `json:"total_seconds"`
+	SlowestSeconds float64 `json:"slowest_seconds"`
+	LagSeconds     float64 `json:"lag_seconds"`
+}
+
+// metricsSnapshot reads every metric. The event bus's subscriber queues are
+// reported together as the "events" queue.
+func metricsSnapshot() metricsValues {
+	bus := eventBus.Stats()
+	v := metricsValues{
+		Goroutines: runtime.NumGoroutine(),
+		Queues: map[string]queueValues{
+			"events": {Depth: bus.Depth, Capacity: bus.Capacity},
+		},
+		Events: eventValues{
+			Subscribers:    bus.Subscribers,
+			MaxFill:        bus.MaxFill,
+			Dropped:        bus.Dropped,
+			BlockedSeconds: bus.Blocked.Seconds(),
+		},
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	for name, q := range metricsQueues {
+		v.Queues[name] = queueValues{Depth: q.depth(), Capacity: q.capacity}
+	}
+	v.BatchTicks = tickValues{
+		Count:          batchTicks.count,
+		TotalSeconds:   batchTicks.total.Seconds(),
+		SlowestSeconds: batchTicks.slowest.Seconds(),
+		LagSeconds:     batchTicks.lag.Seconds(),
+	}
+	return v
+}
+
+// writePrometheusMetrics writes a snapshot in the Prometheus text format
+func writePrometheusMetrics(w io.Writer, v metricsValues) {
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("heliostat_goroutines", "gauge", "Number of goroutines.")
+	fmt.Fprintf(w, "heliostat_goroutines %d\n", v.Goroutines)
+
+	names := make([]string, 0, len(v.Queues))
+	for name := range v.Queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metric("heliostat_queue_depth", "gauge", "Items buffered in a pipeline queue.")
+	for _, name := range names {
+		fmt.Fprintf(w, "heliostat_queue_depth{queue=%q} %d\n", name, v.Queues[name].Depth)
+	}
+	metric("heliostat_queue_capacity", "gauge", "Buffer size of a pipeline queue.")
+	for _, name := range names {
+		fmt.Fprintf(w, "heliostat_queue_capacity{queue=%q} %d\n", name, v.Queues[name].Capacity)
+	}
+
+	metric("heliostat_event_subscribers", "gauge", "Event bus subscribers.")
+	fmt.Fprintf(w, "heliostat_event_subscribers %d\n", v.Events.Subscribers)
+	metric("heliostat_event_queue_max_fill_ratio", "gauge", "Fill of the fullest event subscriber's buffer (0-1).")
+	fmt.Fprintf(w, "heliostat_event_queue_max_fill_ratio %g\n", v.Events.MaxFill)
+	metric("heliostat_events_dropped_total", "counter", "Events dropped by lossy subscribers with full buffers.")
+	fmt.Fprintf(w, "heliostat_events_dropped_total %d\n", v.Events.Dropped)
+	metric("heliostat_event_publish_blocked_seconds_total", "counter", "Time publishing waited on lossless subscribers with full buffers.")
+	fmt.Fprintf(w, "heliostat_event_publish_blocked_seconds_total %g\n", v.Events.BlockedSeconds)
+
+	metric("heliostat_batch_tick_seconds", "summary", "Time taken by each tick of the record flush loop.")
+	fmt.Fprintf(w, "heliostat_batch_tick_seconds_sum %g\n", v.BatchTicks.TotalSeconds)
+	fmt.Fprintf(w, "heliostat_batch_tick_seconds_count %d\n", v.BatchTicks.Count)
+	metric("heliostat_batch_tick_slowest_seconds", "gauge", "Slowest tick of the record flush loop.")
+	fmt.Fprintf(w, "heliostat_batch_tick_slowest_seconds %g\n", v.BatchTicks.SlowestSeconds)
+	metric("heliostat_batch_tick_lag_seconds", "gauge", "How late the last tick of the record flush loop started.")
+	fmt.Fprintf(w, "heliostat_batch_tick_lag_seconds %g\n", v.BatchTicks.LagSeconds)
+}
+
+// serveMetrics serves /metrics and /debug/vars on addr until shutdown
+func serveMetrics(addr string) error {
+	listener, err := listenServer(addr)
+	if err != nil {
+		return fmt.Errorf("cannot serve metrics: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheusMetrics(w, metricsSnapshot())
+	})
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	onShutdown(func() { server.Close() })
+	go server.Serve(listener)
+	return nil
+}
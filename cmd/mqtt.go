@@ -0,0 +1,566 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mqttBroker          string
+	mqttClientID        string
+	mqttUsername        string
+	mqttTopicPrefix     string
+	mqttDiscoveryPrefix string
+	mqttNoDiscovery     bool
+	mqttQuiet           bool
+)
+
+const (
+	// mqttRepublishInterval is how often an unchanged value is published
+	// again, so late subscribers see it without retained telemetry
+	mqttRepublishInterval = 30 * time.Second
+
+	// mqttDiscoveryHoldoff limits how often devices are asked to announce
+	// themselves when telemetry arrives from an unknown address
+	mqttDiscoveryHoldoff = time.Second
+
+	// mqttTimeout bounds connecting to and disconnecting from the broker
+	mqttTimeout = 5 * time.Second
+)
+
+var mqttCmd = &cobra.Command{
+	Use:   "mqtt",
+	Short: "Bridge telemetry and commands to an MQTT broker",
+	Long: `Publish decoded telemetry to MQTT and turn messages on command topics into
+Fusain commands, with Home Assistant MQTT discovery so heaters show up
+automatically.
+
+Telemetry is published per device and metric under --topic-prefix, when a
+value changes and at least every 30s:
+  <prefix>/<ADDR>/state                  System state (IDLE, HEATING, ...)
+  <prefix>/<ADDR>/error                  Error code (NONE, OVERHEAT, ...)
+  <prefix>/<ADDR>/motor/<n>/rpm          Motor RPM
+  <prefix>/<ADDR>/motor/<n>/target_rpm   Motor target RPM
+  <prefix>/<ADDR>/pump/<n>/rate          Pump rate (ms)
+  <prefix>/<ADDR>/glow/<n>/lit           Glow plug (ON/OFF)
+  <prefix>/<ADDR>/temperature/<n>        Temperature (°C)
+  <prefix>/<ADDR>/temperature/<n>/target Target temperature (°C)
+  <prefix>/<ADDR>/command_error          Command rejections (not retained)
+  <prefix>/status                        Bridge availability (online/offline)
+
+Commands are accepted on:
+  <prefix>/<ADDR>/set/mode               idle, fan, heat or emergency,
+                                         optionally followed by the argument
+                                         (e.g. "fan 2500")
+  <prefix>/<ADDR>/set/motor/<n>/rpm      Motor target RPM
+
+Commands go through the same checks as the control TUI: the
+--allow-device/--deny-device filter, configured interlocks, the device's
+announced components and the validation limits. Rejections are logged and
+published to the device's command_error topic.
+
+Home Assistant discovery configs are published (retained) under
+--discovery-prefix for each announced device: state, error, RPM, pump rate,
+glow and temperature sensors, a mode select and a target RPM number per
+motor. --no-discovery turns this off.
+
+The broker password is read from the MQTT_PASSWORD environment variable.
+
+Examples:
+  heliostat mqtt -p /dev/ttyUSB0 --broker tcp://localhost:1883
+  heliostat mqtt -u ws://slate.local/ws --broker tcp://hass.local:1883 --mqtt-username heliostat
+  mosquitto_pub -t heliostat/0123456789ABCDEF/set/mode -m "heat 2500"`,
+	Args: cobra.NoArgs,
+	RunE: runMQTT,
+}
+
+func init() {
+	rootCmd.AddCommand(mqttCmd)
+	mqttCmd.Flags().StringVar(&mqttBroker, "broker", "tcp://localhost:1883", "MQTT broker URL (tcp://, ssl:// or ws://)")
+	mqttCmd.Flags().StringVar(&mqttClientID, "client-id", "heliostat", "MQTT client ID")
+	mqttCmd.Flags().StringVar(&mqttUsername, "mqtt-username", "", "MQTT username (password from MQTT_PASSWORD)")
+	mqttCmd.Flags().StringVar(&mqttTopicPrefix, "topic-prefix", "heliostat", "Topic prefix for telemetry and commands")
+	mqttCmd.Flags().StringVar(&mqttDiscoveryPrefix, "discovery-prefix", "homeassistant", "Home Assistant discovery prefix")
+	mqttCmd.Flags().BoolVar(&mqttNoDiscovery, "no-discovery", false, "Don't publish Home Assistant discovery configs")
+	mqttCmd.Flags().BoolVarP(&mqttQuiet, "quiet", "q", false, "Only print connection status")
+}
+
+func runMQTT(cmd *cobra.Command, args []string) error {
+	mqttTopicPrefix = strings.Trim(mqttTopicPrefix, "/")
+	if mqttTopicPrefix == "" {
+		return fmt.Errorf("--topic-prefix cannot be empty")
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	b := &mqttBridge{
+		fusain:  fusain.NewClient(conn),
+		devices: make(map[uint64]*mqttDevice),
+		values:  make(map[string]mqttValue),
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(mqttBroker).
+		SetClientID(mqttClientID).
+		SetUsername(mqttUsername).
+		SetPassword(os.Getenv("MQTT_PASSWORD")).
+		SetWill(b.topic("status"), "offline", 1, true).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			fmt.Fprintf(os.Stderr, "MQTT connection lost: %v (reconnecting)\n", err)
+		})
+	b.broker = mqtt.NewClient(opts)
+
+	token := b.broker.Connect()
+	if !token.WaitTimeout(mqttTimeout) {
+		return exitErrorf(ExitConnection, "cannot connect to %s: timed out", mqttBroker)
+	}
+	if err := token.Error(); err != nil {
+		return exitErrorf(ExitConnection, "cannot connect to %s: %v", mqttBroker, err)
+	}
+	onShutdown(b.close)
+	defer b.close()
+
+	fmt.Printf("Bridging %s to %s (topics under %s/)\n", connInfo, mqttBroker, mqttTopicPrefix)
+
+	// Devices are added as their DEVICE_ANNOUNCE replies arrive
+	b.requestDiscovery()
+
+	for {
+		select {
+		case p := <-b.fusain.Packets():
+			b.fromDevice(p)
+		case <-b.fusain.Done():
+			return exitErrorf(ExitConnection, "connection lost: %v", b.fusain.Err())
+		}
+	}
+}
+
+// mqttLog prints a timestamped log line unless --quiet is set
+func mqttLog(format string, args ...interface{}) {
+	if mqttQuiet {
+		return
+	}
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+// mqttDevice is a heater known to the bridge
+type mqttDevice struct {
+	address  uint64
+	announce fusain.DeviceAnnounce
+}
+
+// capabilities returns the device in the form validateCommand checks
+// component indices against
+func (d *mqttDevice) capabilities() *device {
+	return &device{
+		address: d.address,
+		caps: deviceCapabilities{
+			motorCount:       uint64(d.announce.MotorCount),
+			thermometerCount: uint64(d.announce.ThermometerCount),
+			pumpCount:        uint64(d.announce.PumpCount),
+			glowCount:        uint64(d.announce.GlowCount),
+		},
+		hasCaps: true,
+	}
+}
+
+// mqttValue is the last payload published on a telemetry topic
+type mqttValue struct {
+	payload string
+	at      time.Time
+}
+
+// mqttBridge connects a Fusain client to an MQTT broker
+type mqttBridge struct {
+	fusain *fusain.Client
+	broker mqtt.Client
+
+	mu            sync.Mutex
+	devices       map[uint64]*mqttDevice
+	values        map[string]mqttValue
+	lastDiscovery time.Time
+	closed        bool
+}
+
+// topic joins parts under the topic prefix
+func (b *mqttBridge) topic(parts ...string) string {
+	return mqttTopicPrefix + "/" + strings.Join(parts, "/")
+}
+
+// deviceTopic joins parts under a device's topic
+func (b *mqttBridge) deviceTopic(address uint64, parts ...string) string {
+	return b.topic(append([]string{fmt.Sprintf("%016X", address)}, parts...)...)
+}
+
+// onConnect runs on every (re)connection to the broker: it marks the bridge
+// online, subscribes to command topics and republishes discovery configs in
+// case the broker lost its retained messages
+func (b *mqttBridge) onConnect(client mqtt.Client) {
+	mqttLog("Connected to %s", mqttBroker)
+	client.Publish(b.topic("status"), 1, true, "online")
+	client.Subscribe(b.topic("+", "set", "#"), 1, b.onCommand)
+
+	b.mu.Lock()
+	devices := make([]*mqttDevice, 0, len(b.devices))
+	for _, d := range b.devices {
+		devices = append(devices, d)
+	}
+	b.values = make(map[string]mqttValue)
+	b.mu.Unlock()
+
+	for _, d := range devices {
+		b.publishDiscovery(d)
+	}
+}
+
+// close marks the bridge offline and disconnects from the broker
+func (b *mqttBridge) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	b.broker.Publish(b.topic("status"), 1, true, "offline").WaitTimeout(mqttTimeout)
+	b.broker.Disconnect(250)
+}
+
+// addDevice records an announced device, subscribes to its telemetry and
+// publishes its discovery configs
+func (b *mqttBridge) addDevice(address uint64, announce fusain.DeviceAnnounce) {
+	b.mu.Lock()
+	d, known := b.devices[address]
+	if known && d.announce == announce {
+		b.mu.Unlock()
+		return
+	}
+	d = &mqttDevice{address: address, announce: announce}
+	b.devices[address] = d
+	b.mu.Unlock()
+
+	mqttLog("Device %s: %d motors, %d thermometers, %d pumps, %d glow plugs",
+		formatAddress(address), announce.MotorCount, announce.ThermometerCount, announce.PumpCount, announce.GlowCount)
+	if err := b.fusain.Subscribe(address); err != nil {
+		mqttLog("Subscribing to %s failed: %v", formatAddress(address), err)
+	}
+	b.publishDiscovery(d)
+}
+
+// requestDiscovery asks every device to announce itself: through the
+// router's stateless address over WebSocket, or by broadcast on a serial
+// link
+func (b *mqttBridge) requestDiscovery() {
+	var address uint64 = fusain.AddressBroadcast
+	if wsURL != "" {
+		address = fusain.AddressStateless
+	}
+	b.mu.Lock()
+	b.lastDiscovery = time.Now()
+	b.mu.Unlock()
+	if err := b.fusain.Send(fusain.NewDiscoveryRequest(address)); err != nil {
+		mqttLog("Discovery request failed: %v", err)
+	}
+}
+
+// fromDevice publishes a packet received from the Fusain connection
+func (b *mqttBridge) fromDevice(p *fusain.Packet) {
+	if p.IsBroadcast() || p.IsStateless() {
+		return
+	}
+	address := p.Address()
+
+	if p.Type() == fusain.MsgDeviceAnnounce {
+		if announce, err := fusain.DecodeDeviceAnnounce(p); err == nil {
+			b.addDevice(address, announce)
+		}
+		return
+	}
+
+	b.mu.Lock()
+	_, known := b.devices[address]
+	rediscover := !known && time.Since(b.lastDiscovery) > mqttDiscoveryHoldoff
+	b.mu.Unlock()
+	if rediscover {
+		// Learn the components of a device that appeared after startup
+		b.requestDiscovery()
+	}
+
+	switch p.Type() {
+	case fusain.MsgStateData:
+		if d, err := fusain.DecodeStateData(p); err == nil {
+			b.publishValue(b.deviceTopic(address, "state"), fusain.FormatState(uint32(d.State)))
+			b.publishValue(b.deviceTopic(address, "error"), fusain.FormatErrorCode(int32(d.Code)))
+		}
+	case fusain.MsgMotorData:
+		if d, err := fusain.DecodeMotorData(p); err == nil {
+			n := strconv.Itoa(int(d.Motor))
+			b.publishValue(b.deviceTopic(address, "motor", n, "rpm"), strconv.Itoa(int(d.RPM)))
+			b.publishValue(b.deviceTopic(address, "motor", n, "target_rpm"), strconv.Itoa(int(d.Target)))
+		}
+	case fusain.MsgPumpData:
+		if d, err := fusain.DecodePumpData(p); err == nil && d.Rate != nil {
+			b.publishValue(b.deviceTopic(address, "pump", strconv.Itoa(int(d.Pump)), "rate"), strconv.Itoa(int(*d.Rate)))
+		}
+	case fusain.MsgGlowData:
+		if d, err := fusain.DecodeGlowData(p); err == nil {
+			lit := "OFF"
+			if d.Lit {
+				lit = "ON"
+			}
+			b.publishValue(b.deviceTopic(address, "glow", strconv.Itoa(int(d.Glow)), "lit"), lit)
+		}
+	case fusain.MsgTempData:
+		if d, err := fusain.DecodeTempData(p); err == nil {
+			n := strconv.Itoa(int(d.Thermometer))
+			b.publishValue(b.deviceTopic(address, "temperature", n), strconv.FormatFloat(d.Reading, 'f', 1, 64))
+			if d.TargetTemperature != nil {
+				b.publishValue(b.deviceTopic(address, "temperature", n, "target"), strconv.FormatFloat(*d.TargetTemperature, 'f', 1, 64))
+			}
+		}
+	case fusain.MsgErrorInvalidCmd, fusain.MsgErrorStateReject:
+		reason := strings.TrimSpace(fusain.FormatPayloadMap(p.Type(), p.PayloadMap()))
+		b.commandError(address, fmt.Sprintf("%s: %s", fusain.FormatMessageType(p.Type()), reason))
+	}
+}
+
+// publishValue publishes a telemetry value when it changed, or when it was
+// last published more than mqttRepublishInterval ago
+func (b *mqttBridge) publishValue(topic, payload string) {
+	now := time.Now()
+	b.mu.Lock()
+	last, ok := b.values[topic]
+	if ok && last.payload == payload && now.Sub(last.at) < mqttRepublishInterval {
+		b.mu.Unlock()
+		return
+	}
+	b.values[topic] = mqttValue{payload: payload, at: now}
+	b.mu.Unlock()
+
+	b.broker.Publish(topic, 0, false, payload)
+}
+
+// commandError logs a rejected command and publishes it to the device's
+// command_error topic
+func (b *mqttBridge) commandError(address uint64, message string) {
+	mqttLog("Command to %s rejected: %s", formatAddress(address), message)
+	b.broker.Publish(b.deviceTopic(address, "command_error"), 1, false, message)
+}
+
+// onCommand handles a message on <prefix>/<ADDR>/set/...
+func (b *mqttBridge) onCommand(_ mqtt.Client, msg mqtt.Message) {
+	parts := strings.Split(strings.TrimPrefix(msg.Topic(), mqttTopicPrefix+"/"), "/")
+	if len(parts) < 3 {
+		return
+	}
+	address, err := strconv.ParseUint(parts[0], 16, 64)
+	if err != nil {
+		mqttLog("Ignoring command on %s: invalid address", msg.Topic())
+		return
+	}
+
+	p, err := parseMQTTCommand(address, parts[2:], strings.TrimSpace(string(msg.Payload())))
+	if err != nil {
+		b.commandError(address, err.Error())
+		return
+	}
+
+	b.mu.Lock()
+	d := b.devices[address]
+	b.mu.Unlock()
+	var dev *device
+	if d != nil {
+		dev = d.capabilities()
+	}
+	if err := validateCommand(dev, p); err != nil {
+		b.commandError(address, err.Error())
+		return
+	}
+
+	if err := b.fusain.Send(p); err != nil {
+		b.commandError(address, fmt.Sprintf("send failed: %v", err))
+		return
+	}
+	mqttLog("Sent %s to %s", fusain.FormatMessageType(p.Type()), formatAddress(address))
+}
+
+// mqttModes maps mode names accepted on set/mode to STATE_COMMAND modes
+var mqttModes = map[string]fusain.Mode{
+	"idle":      fusain.ModeIdle,
+	"fan":       fusain.ModeFan,
+	"heat":      fusain.ModeHeat,
+	"emergency": fusain.ModeEmergency,
+}
+
+// parseMQTTCommand builds the command for a set/... topic (path is the
+// part after "set") and its payload
+func parseMQTTCommand(address uint64, path []string, payload string) (*fusain.Packet, error) {
+	switch {
+	case len(path) == 1 && path[0] == "mode":
+		fields := strings.Fields(strings.ToLower(payload))
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid mode %q: expected a mode and an optional argument", payload)
+		}
+		mode, ok := mqttModes[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("invalid mode %q: must be idle, fan, heat or emergency", fields[0])
+		}
+		var argument *int64
+		if len(fields) == 2 {
+			arg, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mode argument %q", fields[1])
+			}
+			argument = &arg
+		}
+		return fusain.StateCommand{Mode: mode, Argument: argument}.Encode(address), nil
+
+	case len(path) == 3 && path[0] == "motor" && path[2] == "rpm":
+		motor, err := strconv.ParseUint(path[1], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid motor index %q", path[1])
+		}
+		// Home Assistant number entities may send "2500.0"
+		rpm, err := strconv.ParseFloat(payload, 64)
+		if err != nil || math.IsNaN(rpm) || math.IsInf(rpm, 0) {
+			return nil, fmt.Errorf("invalid RPM %q", payload)
+		}
+		if rpm != math.Trunc(rpm) {
+			return nil, fmt.Errorf("invalid RPM %q: must be a whole number", payload)
+		}
+		if rpm < math.MinInt32 || rpm > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid RPM %q: out of range", payload)
+		}
+		return fusain.MotorCommand{Motor: uint8(motor), RPM: int32(rpm)}.Encode(address), nil
+	}
+	return nil, fmt.Errorf("unknown command topic set/%s", strings.Join(path, "/"))
+}
+
+// publishDiscovery publishes the Home Assistant discovery configs for d
+func (b *mqttBridge) publishDiscovery(d *mqttDevice) {
+	if mqttNoDiscovery {
+		return
+	}
+	for _, entity := range b.homeAssistantEntities(d) {
+		data, err := json.Marshal(entity.config)
+		if err != nil {
+			continue
+		}
+		b.broker.Publish(entity.topic, 1, true, data)
+	}
+}
+
+// haEntity is one Home Assistant discovery config and the topic it is
+// published on
+type haEntity struct {
+	topic  string
+	config map[string]interface{}
+}
+
+// homeAssistantEntities returns the discovery configs for a device's
+// sensors and controls
+func (b *mqttBridge) homeAssistantEntities(d *mqttDevice) []haEntity {
+	addr := fmt.Sprintf("%016X", d.address)
+	nodeID := "heliostat_" + strings.ToLower(addr)
+	haDevice := map[string]interface{}{
+		"identifiers":  []string{nodeID},
+		"name":         "Heater " + addr,
+		"manufacturer": "Thermoquad",
+		"model":        "Helios",
+	}
+
+	limits := fusain.DefaultValidationLimits()
+	if appConfig().Limits != nil {
+		limits = *appConfig().Limits
+	}
+
+	var entities []haEntity
+	add := func(component, objectID, name string, config map[string]interface{}) {
+		config["name"] = name
+		config["unique_id"] = nodeID + "_" + objectID
+		config["device"] = haDevice
+		config["availability_topic"] = b.topic("status")
+		entities = append(entities, haEntity{
+			topic:  strings.Join([]string{mqttDiscoveryPrefix, component, nodeID, objectID, "config"}, "/"),
+			config: config,
+		})
+	}
+
+	add("sensor", "state", "State", map[string]interface{}{
+		"state_topic": b.deviceTopic(d.address, "state"),
+		"icon":        "mdi:radiator",
+	})
+	add("sensor", "error", "Error", map[string]interface{}{
+		"state_topic": b.deviceTopic(d.address, "error"),
+		"icon":        "mdi:alert-circle-outline",
+	})
+	add("select", "mode", "Mode", map[string]interface{}{
+		"command_topic": b.deviceTopic(d.address, "set", "mode"),
+		"options":       []string{"idle", "fan", "heat", "emergency"},
+		"optimistic":    true,
+	})
+
+	for i := 0; i < int(d.announce.MotorCount); i++ {
+		n := strconv.Itoa(i)
+		add("sensor", "motor_"+n+"_rpm", "Motor "+n+" RPM", map[string]interface{}{
+			"state_topic":         b.deviceTopic(d.address, "motor", n, "rpm"),
+			"unit_of_measurement": "RPM",
+			"state_class":         "measurement",
+			"icon":                "mdi:fan",
+		})
+		add("number", "motor_"+n+"_target_rpm", "Motor "+n+" target RPM", map[string]interface{}{
+			"state_topic":         b.deviceTopic(d.address, "motor", n, "target_rpm"),
+			"command_topic":       b.deviceTopic(d.address, "set", "motor", n, "rpm"),
+			"unit_of_measurement": "RPM",
+			"min":                 0,
+			"max":                 limits.MaxRPM,
+			"step":                100,
+			"mode":                "box",
+		})
+	}
+	for i := 0; i < int(d.announce.ThermometerCount); i++ {
+		n := strconv.Itoa(i)
+		add("sensor", "temperature_"+n, "Temperature "+n, map[string]interface{}{
+			"state_topic":         b.deviceTopic(d.address, "temperature", n),
+			"unit_of_measurement": "°C",
+			"device_class":        "temperature",
+			"state_class":         "measurement",
+		})
+	}
+	for i := 0; i < int(d.announce.PumpCount); i++ {
+		n := strconv.Itoa(i)
+		add("sensor", "pump_"+n+"_rate", "Pump "+n+" rate", map[string]interface{}{
+			"state_topic":         b.deviceTopic(d.address, "pump", n, "rate"),
+			"unit_of_measurement": "ms",
+			"icon":                "mdi:water-pump",
+		})
+	}
+	for i := 0; i < int(d.announce.GlowCount); i++ {
+		n := strconv.Itoa(i)
+		add("binary_sensor", "glow_"+n, "Glow plug "+n, map[string]interface{}{
+			"state_topic":  b.deviceTopic(d.address, "glow", n, "lit"),
+			"device_class": "heat",
+		})
+	}
+	return entities
+}
@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func TestParseMQTTCommandRPM(t *testing.T) {
+	path := []string{"motor", "0", "rpm"}
+	valid := map[string]int32{"2500": 2500, "2500.0": 2500, "0": 0, "-1": -1}
+	for payload, want := range valid {
+		p, err := parseMQTTCommand(1, path, payload)
+		if err != nil {
+			t.Errorf("%q: %v", payload, err)
+			continue
+		}
+		cmd, err := fusain.DecodeMotorCommand(p)
+		if err != nil || cmd.RPM != want {
+			t.Errorf("%q: got %+v (%v), want %d RPM", payload, cmd, err, want)
+		}
+	}
+
+	invalid := []string{"fast", "NaN", "Inf", "-Inf", "2500.5", "1e10", "-3000000000", ""}
+	for _, payload := range invalid {
+		if _, err := parseMQTTCommand(1, path, payload); err == nil {
+			t.Errorf("%q: expected an error", payload)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/rules"
+	"golang.org/x/term"
+)
+
+var (
+	notifyKinds     []string
+	notifyExec      string
+	notifyErrorRate float64
+)
+
+// notifyHooks tracks running --notify-exec hooks, so shutdown can wait for
+// them
+var notifyHooks sync.WaitGroup
+
+const (
+	// notifyRateWindow is the span the error rate is measured over
+	notifyRateWindow = 10 * time.Second
+
+	// notifyRateMinPackets keeps a few early errors from counting as a
+	// high rate
+	notifyRateMinPackets = 20
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringSliceVar(&notifyKinds, "notify", nil, "Alert when a device enters ERROR or E_STOP, the connection is lost, the error rate is high or a --rules alert is raised: bell, osc (OSC 9), osc777 or desktop")
+	rootCmd.PersistentFlags().StringVar(&notifyExec, "notify-exec", "", "Run this command on each alert, with HELIOSTAT_* variables describing it")
+	rootCmd.PersistentFlags().Float64Var(&notifyErrorRate, "notify-error-rate", 0, "Also alert when more than this percentage of packets fail over 10s (0 = off)")
+}
+
+// notification is one alert
+type notification struct {
+	at      time.Time
+	address uint64 // 0 for the error rate and connection loss
+	reason  string // error, estop, connection, error_rate or rule
+	detail  string
+	state   fusain.SysState
+	code    fusain.ErrorCode
+	packet  *fusain.Packet      // STATE_DATA that raised it, if any
+	rate    float64             // Percentage of packets failing, for error_rate
+	rule    *events.AlertRaised // The alert, for rule
+}
+
+// message is the one-line text of an alert
+func (n notification) message() string {
+	return "heliostat: " + n.text()
+}
+
+// text is the message without the program name, for desktop notifications
+// that show it in the title
+func (n notification) text() string {
+	if !n.device() {
+		return n.detail
+	}
+	return fmt.Sprintf("heater %s %s", deviceLabel(n.address), n.detail)
+}
+
+// device reports whether the alert is about a device
+func (n notification) device() bool {
+	return n.reason != "error_rate" && n.reason != "connection"
+}
+
+// critical reports whether the alert warrants a desktop notification:
+// ERROR, E_STOP, connection loss and critical --rules alerts
+func (n notification) critical() bool {
+	switch n.reason {
+	case "error", "estop", "connection":
+		return true
+	case "rule":
+		return n.rule.Severity == rules.SeverityCritical
+	}
+	return false
+}
+
+// rateBucket counts one second of packets for the error rate
+type rateBucket struct {
+	second int64
+	total  int
+	failed int
+}
+
+// notifier raises alerts from bus events
+type notifier struct {
+	bell, osc, osc777 bool
+	desktop           bool
+	term              io.Writer // nil if stderr is not a terminal
+	exec              string
+	threshold         float64
+
+	failed    map[uint64]bool // Devices in ERROR (or with the error flag)
+	stopped   map[uint64]bool // Devices in E_STOP
+	lastState map[uint64]*fusain.Packet
+	buckets   []rateBucket
+	highRate  bool
+}
+
+// setupNotifications checks the --notify flags and, if any alert is
+// configured, starts raising alerts from the event bus
+func setupNotifications() error {
+	n := &notifier{
+		exec:      notifyExec,
+		threshold: notifyErrorRate,
+		failed:    make(map[uint64]bool),
+		stopped:   make(map[uint64]bool),
+		lastState: make(map[uint64]*fusain.Packet),
+	}
+	for _, kind := range notifyKinds {
+		switch kind {
+		case "bell":
+			n.bell = true
+		case "osc":
+			n.osc = true
+		case "osc777":
+			n.osc777 = true
+		case "desktop":
+			if _, err := exec.LookPath(desktopNotifyTool); err != nil {
+				return fmt.Errorf("--notify desktop needs %s: %v", desktopNotifyTool, err)
+			}
+			n.desktop = true
+		default:
+			return fmt.Errorf("invalid --notify %q (valid: bell, osc, osc777, desktop)", kind)
+		}
+	}
+	if notifyErrorRate < 0 || notifyErrorRate > 100 {
+		return fmt.Errorf("--notify-error-rate must be a percentage (0-100)")
+	}
+	if len(notifyKinds) == 0 && notifyExec == "" {
+		if notifyErrorRate > 0 {
+			return fmt.Errorf("--notify-error-rate needs --notify or --notify-exec")
+		}
+		return nil
+	}
+
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		n.term = os.Stderr
+	}
+	go n.consume(eventBus.SubscribeLossless(256))
+	onShutdown(func() {
+		// Let running hooks finish, without holding up exit for long
+		done := make(chan struct{})
+		go func() {
+			notifyHooks.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(shutdownTimeout):
+		}
+	})
+	return nil
+}
+
+// consume raises alerts until the subscription closes
+func (n *notifier) consume(sub *events.Subscription) {
+	for e := range sub.Events() {
+		switch e := e.(type) {
+		case events.PacketReceived:
+			if e.Packet.Type() == fusain.MsgStateData {
+				n.lastState[e.Packet.Address()] = e.Packet
+			}
+			n.countPacket(e.At, len(e.Anomalies) > 0)
+		case events.DecodeError:
+			n.countPacket(e.At, true)
+		case events.DeviceStateChanged:
+			n.stateChanged(e)
+		case events.AlertRaised:
+			n.alertRaised(e)
+		case events.ConnectionLost:
+			n.connectionLost(e)
+		}
+	}
+}
+
+// stateChanged alerts when a device enters ERROR or E_STOP
+func (n *notifier) stateChanged(e events.DeviceStateChanged) {
+	alert := notification{
+		at:      e.At,
+		address: e.Address,
+		state:   e.State,
+		code:    e.Code,
+		packet:  n.lastState[e.Address],
+	}
+
+	// Devices also set the error flag in E_STOP, which has its own alert
+	failed := e.State == fusain.SysStateError || (e.Error && e.State != fusain.SysStateEstop)
+	if failed && !n.failed[e.Address] {
+		alert.reason = "error"
+		alert.detail = fmt.Sprintf("entered %s (%s)", fusain.FormatState(uint32(e.State)), fusain.FormatErrorCode(int32(e.Code)))
+		if e.State != fusain.SysStateError {
+			alert.detail = fmt.Sprintf("reports an error (%s, state %s)", fusain.FormatErrorCode(int32(e.Code)), fusain.FormatState(uint32(e.State)))
+		}
+		n.notify(alert)
+	}
+	n.failed[e.Address] = failed
+
+	stopped := e.State == fusain.SysStateEstop
+	if stopped && !n.stopped[e.Address] {
+		alert.reason = "estop"
+		alert.detail = "entered E_STOP"
+		n.notify(alert)
+	}
+	n.stopped[e.Address] = stopped
+}
+
+// alertRaised alerts when a --rules alert is raised
+func (n *notifier) alertRaised(e events.AlertRaised) {
+	alert := notification{
+		at:      e.At,
+		address: e.Address,
+		reason:  "rule",
+		detail:  fmt.Sprintf("alert %s [%s]: %s (%s)", e.Rule, e.Severity, e.Condition, formatAlertValue(e.Value)),
+		packet:  n.lastState[e.Address],
+		rule:    &e,
+	}
+	if alert.packet != nil {
+		if state, err := fusain.DecodeStateData(alert.packet); err == nil {
+			alert.state, alert.code = state.State, state.Code
+		}
+	}
+	n.notify(alert)
+}
+
+// connectionLost alerts when the connection drops, but not when shutdown
+// closed it
+func (n *notifier) connectionLost(e events.ConnectionLost) {
+	if shuttingDown.Load() {
+		return
+	}
+	detail := "connection lost"
+	if e.Err != nil {
+		detail += fmt.Sprintf(" (%v)", e.Err)
+	}
+	if e.Reconnecting {
+		detail += " - reconnecting"
+	}
+	n.notify(notification{at: e.At, reason: "connection", detail: detail})
+}
+
+// countPacket adds a packet (or decode error) to the error rate and alerts
+// when the rate crosses the threshold. The alert re-arms once the rate
+// falls back below it.
+func (n *notifier) countPacket(at time.Time, failed bool) {
+	if n.threshold <= 0 {
+		return
+	}
+
+	second := at.Unix()
+	if len(n.buckets) == 0 || n.buckets[len(n.buckets)-1].second != second {
+		n.buckets = append(n.buckets, rateBucket{second: second})
+	}
+	oldest := second - int64(notifyRateWindow/time.Second) + 1
+	for len(n.buckets) > 0 && n.buckets[0].second < oldest {
+		n.buckets = n.buckets[1:]
+	}
+	last := &n.buckets[len(n.buckets)-1]
+	last.total++
+	if failed {
+		last.failed++
+	}
+
+	var total, failedCount int
+	for _, b := range n.buckets {
+		total += b.total
+		failedCount += b.failed
+	}
+	if total < notifyRateMinPackets {
+		return
+	}
+	rate := 100 * float64(failedCount) / float64(total)
+	switch {
+	case rate > n.threshold && !n.highRate:
+		n.highRate = true
+		n.notify(notification{
+			at:     at,
+			reason: "error_rate",
+			detail: fmt.Sprintf("%.1f%% of packets failed in the last %v (%d of %d)", rate, notifyRateWindow, failedCount, total),
+			rate:   rate,
+		})
+	case rate <= n.threshold && n.highRate:
+		n.highRate = false
+	}
+}
+
+// notify raises an alert in the terminal and on the desktop, and runs
+// --notify-exec
+func (n *notifier) notify(alert notification) {
+	if n.term != nil {
+		var seq strings.Builder
+		if n.bell {
+			seq.WriteString("\a")
+		}
+		// Terminals end OSC sequences at BEL; strip control characters
+		// from the text so it cannot end one early
+		text := strings.Map(func(r rune) rune {
+			if r < 0x20 || r == 0x7F {
+				return -1
+			}
+			return r
+		}, alert.message())
+		if n.osc {
+			fmt.Fprintf(&seq, "\x1b]9;%s\a", text)
+		}
+		if n.osc777 {
+			fmt.Fprintf(&seq, "\x1b]777;notify;heliostat;%s\a", strings.ReplaceAll(text, ";", ","))
+		}
+		io.WriteString(n.term, seq.String())
+	}
+
+	// Desktop notifications are for someone at the machine, so only when
+	// running interactively
+	if n.desktop && n.term != nil && alert.critical() {
+		notifyHooks.Add(1)
+		go func() {
+			defer notifyHooks.Done()
+			if err := desktopNotification("heliostat", alert.text()).Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "Desktop notification failed: %v\n", err)
+			}
+		}()
+	}
+
+	if n.exec != "" {
+		notifyHooks.Add(1)
+		go func() {
+			defer notifyHooks.Done()
+			runNotifyHook(n.exec, alert)
+		}()
+	}
+}
+
+// runNotifyHook runs the --notify-exec command with the alert in its
+// environment. Its output goes to stderr.
+func runNotifyHook(path string, alert notification) {
+	env := []string{
+		"HELIOSTAT_REASON=" + alert.reason,
+		"HELIOSTAT_DETAIL=" + alert.detail,
+		"HELIOSTAT_MESSAGE=" + alert.message(),
+		"HELIOSTAT_TIME=" + alert.at.Format(time.RFC3339Nano),
+	}
+	if alert.reason == "error_rate" {
+		env = append(env, fmt.Sprintf("HELIOSTAT_ERROR_RATE=%.1f", alert.rate))
+	} else if alert.device() {
+		env = append(env,
+			fmt.Sprintf("HELIOSTAT_DEVICE=%016X", alert.address),
+			"HELIOSTAT_DEVICE_NAME="+deviceAlias(alert.address),
+			"HELIOSTAT_STATE="+fusain.FormatState(uint32(alert.state)),
+			"HELIOSTAT_ERROR_CODE="+fusain.FormatErrorCode(int32(alert.code)),
+		)
+		if alert.packet != nil {
+			if data, err := json.Marshal(alert.packet); err == nil {
+				env = append(env, "HELIOSTAT_PACKET="+string(data))
+			}
+		}
+	}
+	if alert.rule != nil {
+		env = append(env,
+			"HELIOSTAT_RULE="+alert.rule.Rule,
+			"HELIOSTAT_SEVERITY="+alert.rule.Severity,
+			"HELIOSTAT_CONDITION="+alert.rule.Condition,
+			"HELIOSTAT_VALUE="+strconv.FormatFloat(alert.rule.Value, 'f', -1, 64),
+		)
+	}
+
+	c := exec.Command(path)
+	c.Env = append(os.Environ(), env...)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Notification hook failed: %v\n", err)
+	}
+}
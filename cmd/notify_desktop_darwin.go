@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build darwin
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// desktopNotifyTool shows desktop notifications through Notification Center
+const desktopNotifyTool = "osascript"
+
+// desktopNotification returns the command that shows a desktop
+// notification. The text is passed in the environment so it needs no
+// AppleScript quoting.
+func desktopNotification(title, body string) *exec.Cmd {
+	c := exec.Command(desktopNotifyTool, "-e",
+		`display notification (system attribute "HELIOSTAT_BODY") with title (system attribute "HELIOSTAT_TITLE") sound name "Basso"`)
+	c.Env = append(os.Environ(), "HELIOSTAT_TITLE="+title, "HELIOSTAT_BODY="+body)
+	return c
+}
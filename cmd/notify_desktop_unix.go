@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build !windows && !darwin
+
+package cmd
+
+import "os/exec"
+
+// desktopNotifyTool shows desktop notifications (libnotify)
+const desktopNotifyTool = "notify-send"
+
+// desktopNotification returns the command that shows a critical desktop
+// notification
+func desktopNotification(title, body string) *exec.Cmd {
+	return exec.Command(desktopNotifyTool, "--app-name=heliostat", "--urgency=critical", "--", title, body)
+}
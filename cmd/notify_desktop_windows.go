@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// desktopNotifyTool shows desktop notifications as toasts
+const desktopNotifyTool = "powershell"
+
+// desktopToastScript shows a toast with the text from the environment, so
+// it needs no PowerShell quoting. Toasts need a registered app ID; this
+// borrows PowerShell's.
+const desktopToastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:HELIOSTAT_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:HELIOSTAT_BODY)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show($toast)
+`
+
+// desktopNotification returns the command that shows a desktop
+// notification
+func desktopNotification(title, body string) *exec.Cmd {
+	c := exec.Command(desktopNotifyTool, "-NoProfile", "-NonInteractive", "-Command", desktopToastScript)
+	c.Env = append(os.Environ(), "HELIOSTAT_TITLE="+title, "HELIOSTAT_BODY="+body)
+	return c
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+)
+
+// jsonlPath holds the --jsonl flag value
+var jsonlPath string
+
+// outputSinks receives every packet and decode error read by packetSource.
+// Commands register their outputs here instead of dispatching packets
+// themselves, so one decode loop drives the TUI and every sink at once.
+var outputSinks = sinks.NewFanout()
+
+// jsonlSink is the --jsonl sink, reopened on reload (nil without --jsonl)
+var jsonlSink *sinks.JSONLSink
+
+// setupOutputSinks opens the sinks selected by global flags and closes
+// every registered sink at shutdown
+func setupOutputSinks() error {
+	if jsonlPath != "" {
+		sink, err := sinks.NewJSONLFile(jsonlPath)
+		if err != nil {
+			return fmt.Errorf("cannot open --jsonl file: %w", err)
+		}
+		outputSinks.AddPacketSink(sink)
+		jsonlSink = sink
+	}
+	onShutdown(func() {
+		if err := outputSinks.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing outputs: %v\n", err)
+		}
+	})
+	return nil
+}
+
+var (
+	sinkErrMu   sync.Mutex
+	lastSinkErr string
+)
+
+// reportSinkError prints a sink write error on stderr, once until a
+// different error occurs, so a failing sink doesn't flood the terminal
+func reportSinkError(err error) {
+	if err == nil {
+		return
+	}
+	sinkErrMu.Lock()
+	defer sinkErrMu.Unlock()
+	if msg := err.Error(); msg != lastSinkErr {
+		lastSinkErr = msg
+		fmt.Fprintf(os.Stderr, "Output error: %v\n", err)
+	}
+}
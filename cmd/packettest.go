@@ -5,7 +5,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/Thermoquad/heliostat/pkg/fusain"
@@ -27,8 +26,8 @@ valid packet (passing CRC check).
 
 Exit codes:
   0 - Packet received before timeout
-  1 - Timeout reached without receiving a valid packet
   2 - Connection error
+  3 - Timeout reached without receiving a valid packet
 
 Useful for testing connectivity to Helios or Slate WebSocket bridge.`,
 	RunE: runPacketTest,
@@ -43,8 +42,7 @@ func runPacketTest(cmd *cobra.Command, args []string) error {
 	// Open connection (serial or WebSocket)
 	conn, connInfo, err := OpenConnection()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
-		os.Exit(2)
+		return err
 	}
 	defer conn.Close()
 
@@ -70,21 +68,16 @@ func runPacketTest(cmd *cobra.Command, args []string) error {
 				return
 			}
 
-			for i := 0; i < n; i++ {
-				packet, decodeErr := decoder.DecodeByte(buf[i])
-				if decodeErr != nil {
-					// Ignore decode errors, just count invalid bytes
-					invalidBytes++
-					continue
-				}
-				if packet != nil {
-					// Got a valid packet!
-					if invalidBytes > 0 {
-						fmt.Printf("(skipped %d invalid bytes before sync)\n", invalidBytes)
-					}
-					packetChan <- packet
-					return
+			// Ignore decode errors, just count invalid bytes
+			packets, decodeErrs := decoder.Decode(buf[:n])
+			invalidBytes += len(decodeErrs)
+			if len(packets) > 0 {
+				// Got a valid packet!
+				if invalidBytes > 0 {
+					fmt.Printf("(skipped %d invalid bytes before sync)\n", invalidBytes)
 				}
+				packetChan <- packets[0]
+				return
 			}
 		}
 	}()
@@ -94,19 +87,15 @@ func runPacketTest(cmd *cobra.Command, args []string) error {
 	case packet := <-packetChan:
 		fmt.Printf("SUCCESS: Received valid packet\n")
 		fmt.Printf("  Type: %s (0x%02X)\n", fusain.FormatMessageType(packet.Type()), packet.Type())
-		fmt.Printf("  Address: 0x%016X\n", packet.Address())
+		fmt.Printf("  Address: 0x%s\n", formatAddress(packet.Address()))
 		fmt.Printf("  Length: %d bytes\n", packet.Length())
 		fmt.Printf("  CRC: 0x%04X\n", packet.CRC())
-		os.Exit(0)
+		return nil
 
 	case err := <-errChan:
-		fmt.Fprintf(os.Stderr, "Read error: %v\n", err)
-		os.Exit(2)
+		return exitErrorf(ExitConnection, "Read error: %v", err)
 
 	case <-time.After(time.Duration(packetTestTimeout) * time.Second):
-		fmt.Fprintf(os.Stderr, "TIMEOUT: No valid packet received within %d seconds\n", packetTestTimeout)
-		os.Exit(1)
+		return exitErrorf(ExitTimeout, "TIMEOUT: No valid packet received within %d seconds", packetTestTimeout)
 	}
-
-	return nil
 }
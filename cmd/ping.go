@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingAddress  string
+	pingCount    int
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+)
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Send PING_REQUEST to a device and measure round-trip time",
+	Long: `Send PING_REQUEST packets to a device and report the round-trip time and
+the uptime from each PING_RESPONSE, like ICMP ping.
+
+--addr selects the device (hex or alias). "broadcast" pings every device on the bus
+and reports each responder; "stateless" pings the router. A summary with
+packet loss and min/avg/max/mdev round-trip times is printed at the end, or
+when interrupted with Ctrl+C.
+
+Exit codes:
+  0 - Every ping was answered
+  1 - Some pings were lost, or sending failed
+  2 - Connection error
+  3 - No ping was answered`,
+	Args: cobra.NoArgs,
+	RunE: runPing,
+}
+
+func init() {
+	rootCmd.AddCommand(pingCmd)
+	pingCmd.Flags().StringVar(&pingAddress, "addr", "", "Device address (hex, alias, broadcast or stateless)")
+	pingCmd.Flags().IntVarP(&pingCount, "count", "c", 0, "Stop after this many pings (0 = until interrupted)")
+	pingCmd.Flags().DurationVarP(&pingInterval, "interval", "i", time.Second, "Time between pings")
+	pingCmd.Flags().DurationVar(&pingTimeout, "timeout", 2*time.Second, "Time to wait for each response")
+	pingCmd.MarkFlagRequired("addr")
+}
+
+// pingStats accumulates round-trip times for the summary
+type pingStats struct {
+	mu       sync.Mutex
+	address  uint64
+	sent     int
+	received int // Pings with at least one response
+	rtts     []time.Duration
+	printed  bool
+}
+
+func (s *pingStats) record(rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rtts = append(s.rtts, rtt)
+}
+
+// printSummary prints the ping statistics once
+func (s *pingStats) printSummary() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.printed {
+		return
+	}
+	s.printed = true
+
+	loss := 0.0
+	if s.sent > 0 {
+		loss = float64(s.sent-s.received) / float64(s.sent) * 100
+	}
+	fmt.Printf("\n--- %s ping statistics ---\n", formatAddress(s.address))
+	fmt.Printf("%d pings sent, %d answered, %.0f%% packet loss\n", s.sent, s.received, loss)
+	if len(s.rtts) == 0 {
+		return
+	}
+
+	minRTT, maxRTT := s.rtts[0], s.rtts[0]
+	var sum, sumSq float64
+	for _, rtt := range s.rtts {
+		minRTT = min(minRTT, rtt)
+		maxRTT = max(maxRTT, rtt)
+		ms := float64(rtt) / float64(time.Millisecond)
+		sum += ms
+		sumSq += ms * ms
+	}
+	avg := sum / float64(len(s.rtts))
+	mdev := math.Sqrt(math.Max(sumSq/float64(len(s.rtts))-avg*avg, 0))
+	fmt.Printf("rtt min/avg/max/mdev = %.3f/%.3f/%.3f/%.3f ms\n",
+		float64(minRTT)/float64(time.Millisecond), avg, float64(maxRTT)/float64(time.Millisecond), mdev)
+}
+
+func runPing(cmd *cobra.Command, args []string) error {
+	address, err := parseSendAddress(pingAddress)
+	if err != nil {
+		return err
+	}
+	if err := checkCommandPolicy(fusain.NewPingRequest(address)); err != nil {
+		return err
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Printf("PING %s via %s\n", formatAddress(address), connInfo)
+
+	stats := &pingStats{address: address}
+	onShutdown(stats.printSummary)
+
+	client := fusain.NewClient(conn)
+	for seq := 1; pingCount == 0 || seq <= pingCount; seq++ {
+		started := time.Now()
+		var answered bool
+		if address == fusain.AddressBroadcast {
+			answered, err = pingBroadcast(client, seq, stats)
+		} else {
+			answered, err = pingDevice(client, address, seq, stats)
+		}
+		stats.mu.Lock()
+		stats.sent++
+		if answered {
+			stats.received++
+		}
+		stats.mu.Unlock()
+
+		if errors.Is(err, fusain.ErrClientClosed) {
+			stats.printSummary()
+			return exitErrorf(ExitConnection, "connection lost: %v", client.Err())
+		}
+		if err != nil {
+			fmt.Printf("seq=%d send failed: %v\n", seq, err)
+		}
+
+		if pingCount == 0 || seq < pingCount {
+			time.Sleep(time.Until(started.Add(pingInterval)))
+		}
+	}
+
+	stats.printSummary()
+	switch {
+	case stats.received == 0:
+		return exitSilently(ExitTimeout)
+	case stats.received < stats.sent:
+		return exitSilently(ExitFailure)
+	}
+	return nil
+}
+
+// pingDevice sends one ping to a device and prints the response
+func pingDevice(client *fusain.Client, address uint64, seq int, stats *pingStats) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	resp, rtt, err := client.Ping(ctx, address)
+	switch {
+	case err == nil:
+		stats.record(rtt)
+		fmt.Printf("response from %s: seq=%d uptime=%s rtt=%.3f ms\n",
+			formatAddress(address), seq, formatUptime(resp.Uptime), float64(rtt)/float64(time.Millisecond))
+		return true, nil
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Printf("seq=%d timeout (no response in %v)\n", seq, pingTimeout)
+		return false, nil
+	}
+	return false, err
+}
+
+// pingBroadcast sends one ping to every device and prints each response
+// that arrives within the timeout
+func pingBroadcast(client *fusain.Client, seq int, stats *pingStats) (bool, error) {
+	start := time.Now()
+	if err := client.Send(fusain.NewPingRequest(fusain.AddressBroadcast)); err != nil {
+		return false, err
+	}
+
+	timeout := time.NewTimer(pingTimeout)
+	defer timeout.Stop()
+
+	responders := 0
+	for {
+		select {
+		case p := <-client.Packets():
+			if p.Type() != fusain.MsgPingResponse {
+				continue
+			}
+			rtt := time.Since(start)
+			resp, err := fusain.DecodePingResponse(p)
+			if err != nil {
+				continue
+			}
+			responders++
+			stats.record(rtt)
+			fmt.Printf("response from %s: seq=%d uptime=%s rtt=%.3f ms\n",
+				formatAddress(p.Address()), seq, formatUptime(resp.Uptime), float64(rtt)/float64(time.Millisecond))
+
+		case <-client.Done():
+			return responders > 0, fusain.ErrClientClosed
+
+		case <-timeout.C:
+			if responders == 0 {
+				fmt.Printf("seq=%d timeout (no response in %v)\n", seq, pingTimeout)
+			}
+			return responders > 0, nil
+		}
+	}
+}
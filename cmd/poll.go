@@ -0,0 +1,350 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+// Poll interval defaults
+const (
+	defaultPollInterval    = time.Second
+	defaultPollMaxInterval = 30 * time.Second
+)
+
+var (
+	pollAddress     string
+	pollTelemetry   []string
+	pollInterval    time.Duration
+	pollMaxInterval time.Duration
+	pollTimeout     time.Duration
+	pollMode        bool
+)
+
+var pollCmd = &cobra.Command{
+	Use:   "poll",
+	Short: "Poll telemetry with SEND_TELEMETRY instead of streaming it",
+	Long: `Request telemetry values with SEND_TELEMETRY at intervals instead of
+subscribing to streamed telemetry, for bandwidth-constrained links.
+
+Each value is polled at --interval while it changes. A value that comes back
+unchanged (timestamps aside) doubles its interval, up to --max-interval, and
+drops back to --interval as soon as it changes. Only changes are printed.
+
+Values are given as state, motor:N, temp:N, pump:N or glow:N (index 0 when
+omitted). Without --addr, the devices and values come from the "polling"
+section of the config file, so each device can have its own values and
+intervals:
+
+  "polling": [
+    {"device": "0123456789ABCDEF", "telemetry": ["state", "temp:0"],
+     "interval": "2s", "max_interval": "1m"}
+  ]
+
+--polling-mode first sends TELEMETRY_CONFIG with interval 0 to each device,
+which stops its streamed telemetry.
+
+Examples:
+  heliostat poll --addr 0123456789ABCDEF --telemetry state,motor:0,temp:0
+  heliostat poll --addr 0123456789ABCDEF --telemetry temp --interval 500ms --max-interval 10s
+  heliostat poll --polling-mode`,
+	Args: cobra.NoArgs,
+	RunE: runPoll,
+}
+
+func init() {
+	rootCmd.AddCommand(pollCmd)
+	pollCmd.Flags().StringVar(&pollAddress, "addr", "", "Device address (hex or alias); default: the config file's polling rules")
+	pollCmd.Flags().StringSliceVar(&pollTelemetry, "telemetry", []string{"state"}, "Values to poll: state, motor:N, temp:N, pump:N, glow:N")
+	pollCmd.Flags().DurationVar(&pollInterval, "interval", defaultPollInterval, "Poll interval while values change")
+	pollCmd.Flags().DurationVar(&pollMaxInterval, "max-interval", defaultPollMaxInterval, "Longest interval for unchanged values")
+	pollCmd.Flags().DurationVar(&pollTimeout, "timeout", 2*time.Second, "Time to wait for each reply")
+	pollCmd.Flags().BoolVar(&pollMode, "polling-mode", false, "Switch devices to polling mode (TELEMETRY_CONFIG interval 0) first")
+}
+
+// PollRule polls telemetry values of one device with SEND_TELEMETRY
+type PollRule struct {
+	Device      string   `json:"device"`                 // Hex address
+	Telemetry   []string `json:"telemetry"`              // state, motor:N, temp:N, pump:N, glow:N
+	Interval    string   `json:"interval,omitempty"`     // Poll interval while values change (default 1s)
+	MaxInterval string   `json:"max_interval,omitempty"` // Longest interval for unchanged values (default 30s)
+}
+
+func (r PollRule) validate() error {
+	_, err := r.targets()
+	return err
+}
+
+// targets returns the values polled by the rule
+func (r PollRule) targets() ([]*pollTarget, error) {
+	address, err := parseAddress(r.Device)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := parsePollDuration(r.Interval, defaultPollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %v", err)
+	}
+	maxInterval, err := parsePollDuration(r.MaxInterval, defaultPollMaxInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max_interval: %v", err)
+	}
+	return newPollTargets(address, r.Telemetry, interval, maxInterval)
+}
+
+// parsePollDuration parses a config interval, "" meaning def
+func parsePollDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	return d, err
+}
+
+// pollTelemetryTypes names the telemetry types in --telemetry values
+var pollTelemetryTypes = map[string]fusain.TelemetryType{
+	"state": fusain.TelemetryTypeState,
+	"motor": fusain.TelemetryTypeMotor,
+	"temp":  fusain.TelemetryTypeTemp,
+	"pump":  fusain.TelemetryTypePump,
+	"glow":  fusain.TelemetryTypeGlow,
+}
+
+// parseTelemetrySpec parses a polled value: state, motor:N, temp:N, ...
+func parseTelemetrySpec(s string) (fusain.TelemetryType, uint8, error) {
+	name, indexStr, hasIndex := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	telemetryType, ok := pollTelemetryTypes[name]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown telemetry %q (valid: state, motor:N, temp:N, pump:N, glow:N)", s)
+	}
+	if !hasIndex {
+		return telemetryType, 0, nil
+	}
+	if telemetryType == fusain.TelemetryTypeState {
+		return 0, 0, fmt.Errorf("invalid telemetry %q: state has no index", s)
+	}
+	index, err := strconv.ParseUint(indexStr, 10, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid telemetry index in %q", s)
+	}
+	return telemetryType, uint8(index), nil
+}
+
+// pollTarget is one polled value with its adaptive interval
+type pollTarget struct {
+	address       uint64
+	telemetryType fusain.TelemetryType
+	index         uint8
+	label         string
+
+	interval    time.Duration // Current interval
+	minInterval time.Duration
+	maxInterval time.Duration
+	next        time.Time
+
+	last    string // Values of the last reply, timestamps aside
+	hasLast bool
+}
+
+func newPollTargets(address uint64, specs []string, interval, maxInterval time.Duration) ([]*pollTarget, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no telemetry to poll")
+	}
+	if maxInterval < interval {
+		return nil, fmt.Errorf("max interval %v is shorter than interval %v", maxInterval, interval)
+	}
+	var targets []*pollTarget
+	for _, spec := range specs {
+		telemetryType, index, err := parseTelemetrySpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		label := strings.ToLower(strings.TrimSpace(spec))
+		if telemetryType != fusain.TelemetryTypeState && !strings.Contains(label, ":") {
+			label += ":0"
+		}
+		targets = append(targets, &pollTarget{
+			address:       address,
+			telemetryType: telemetryType,
+			index:         index,
+			label:         label,
+			interval:      interval,
+			minInterval:   interval,
+			maxInterval:   maxInterval,
+		})
+	}
+	return targets, nil
+}
+
+// update records a reply and adapts the interval: doubled while the values
+// are unchanged, reset when they change. Reports whether they changed.
+func (t *pollTarget) update(p *fusain.Packet) (string, bool) {
+	var parts []string
+	for _, f := range fusain.PayloadFields(p) {
+		if f.Name == "timestamp" {
+			continue
+		}
+		parts = append(parts, f.Name+"="+csvValue(f.Value))
+	}
+	values := strings.Join(parts, " ")
+
+	changed := !t.hasLast || values != t.last
+	t.last, t.hasLast = values, true
+	if changed {
+		t.interval = t.minInterval
+	} else {
+		t.interval = min(t.interval*2, t.maxInterval)
+	}
+	return values, changed
+}
+
+// pollStats counts requests for the summary
+type pollStats struct {
+	mu       sync.Mutex
+	sent     int
+	answered int
+	changes  int
+	printed  bool
+}
+
+// printSummary prints the poll statistics once
+func (s *pollStats) printSummary() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.printed {
+		return
+	}
+	s.printed = true
+	fmt.Printf("\n%d requests sent, %d answered, %d changes\n", s.sent, s.answered, s.changes)
+}
+
+// pollTargets returns the values to poll, from the flags or the config
+func pollTargets() ([]*pollTarget, error) {
+	if pollAddress != "" {
+		address, err := parseAddress(pollAddress)
+		if err != nil {
+			return nil, err
+		}
+		if pollInterval <= 0 {
+			return nil, fmt.Errorf("--interval must be positive")
+		}
+		return newPollTargets(address, pollTelemetry, pollInterval, max(pollMaxInterval, pollInterval))
+	}
+
+	if len(appConfig().Polling) == 0 {
+		return nil, fmt.Errorf("nothing to poll: give --addr or add \"polling\" rules to the config file")
+	}
+	var targets []*pollTarget
+	for _, rule := range appConfig().Polling {
+		ruleTargets, err := rule.targets()
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, ruleTargets...)
+	}
+	return targets, nil
+}
+
+func runPoll(cmd *cobra.Command, args []string) error {
+	targets, err := pollTargets()
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if err := checkCommandPolicy(fusain.NewSendTelemetry(t.address, t.telemetryType, t.index)); err != nil {
+			return err
+		}
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Printf("Polling %d values via %s\n", len(targets), connInfo)
+
+	client := fusain.NewClient(conn)
+	if pollMode {
+		configured := make(map[uint64]bool)
+		for _, t := range targets {
+			if configured[t.address] {
+				continue
+			}
+			configured[t.address] = true
+			if err := client.Send(fusain.NewTelemetryConfig(t.address, true, 0)); err != nil {
+				return exitErrorf(ExitConnection, "cannot configure %s: %v", formatAddress(t.address), err)
+			}
+		}
+	}
+
+	stats := &pollStats{}
+	onShutdown(stats.printSummary)
+
+	now := time.Now()
+	for _, t := range targets {
+		t.next = now
+	}
+	for {
+		// Poll the most overdue value
+		t := targets[0]
+		for _, other := range targets[1:] {
+			if other.next.Before(t.next) {
+				t = other
+			}
+		}
+		if wait := time.Until(t.next); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-client.Done():
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+		reply, err := client.RequestTelemetry(ctx, t.address, t.telemetryType, t.index)
+		cancel()
+		stats.mu.Lock()
+		stats.sent++
+		stats.mu.Unlock()
+
+		var cmdErr *fusain.CommandError
+		switch {
+		case errors.As(err, &cmdErr):
+			// Polling again soon won't help
+			t.interval = t.maxInterval
+			fmt.Printf("[%s] %s %s: rejected: %v\n", time.Now().Format("15:04:05.000"), formatAddress(t.address), t.label, err)
+		case errors.Is(err, fusain.ErrClientClosed):
+			stats.printSummary()
+			return exitErrorf(ExitConnection, "connection lost: %v", client.Err())
+		case errors.Is(err, context.DeadlineExceeded):
+			fmt.Printf("[%s] %s %s: timeout (no reply in %v)\n", time.Now().Format("15:04:05.000"), formatAddress(t.address), t.label, pollTimeout)
+		case err != nil:
+			fmt.Printf("[%s] %s %s: send failed: %v\n", time.Now().Format("15:04:05.000"), formatAddress(t.address), t.label, err)
+		default:
+			values, changed := t.update(reply)
+			stats.mu.Lock()
+			stats.answered++
+			if changed {
+				stats.changes++
+			}
+			stats.mu.Unlock()
+			if changed {
+				fmt.Printf("[%s] %s %s: %s\n", time.Now().Format("15:04:05.000"), formatAddress(t.address), t.label, values)
+			}
+		}
+		t.next = time.Now().Add(t.interval)
+	}
+}
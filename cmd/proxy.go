@@ -0,0 +1,376 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	proxyToPort string
+	proxyToBaud int
+	proxyToURL  string
+	proxyNames  []string
+	proxyDrop   []string
+	proxySet    []string
+	proxyQuiet  bool
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Bridge two connections and log traffic in both directions",
+	Long: `Sit between a controller and an appliance: forward every frame between
+two connections while decoding and logging it with a direction tag.
+
+Side A is the usual connection (--port or --url); side B is --to-port or
+--to-url, so serial-serial and serial-WebSocket bridges both work. Log lines
+are tagged A>B or B>A, or with the names given by --names.
+
+Frames are forwarded as received, including frames that fail CRC or
+decoding (logged as errors). Bytes outside frames are not forwarded.
+Packets may be changed on the way through; DIR is the name of the sending
+side and may be left out to match both directions:
+  --drop [DIR:]TYPE            drop packets of a message type
+  --set [DIR:]TYPE.KEY=VALUE   set a payload key (JSON value, converted to
+                               the schema type) and re-encode the frame
+
+Commands (as rewritten) are checked against the address filter and the
+configured interlocks before they are forwarded, like commands heliostat
+sends itself; rejected commands are dropped and logged as [REJECTED].
+
+Packets are validated as in error_detection and anomalies are shown under
+the packet. --rate-limit thins logged telemetry; it never affects what is
+forwarded.
+
+Examples:
+  heliostat proxy --port /dev/ttyUSB0 --to-port /dev/ttyUSB1 --names slate,helios
+  heliostat proxy --port /dev/ttyUSB0 --to-url ws://slate.local/ws --rate-limit 1s
+  heliostat proxy --port /dev/ttyUSB0 --to-port /dev/ttyUSB1 --drop B:PING_RESPONSE
+  heliostat proxy --port /dev/ttyUSB0 --to-port /dev/ttyUSB1 --set A:STATE_COMMAND.1=1500`,
+	Args: cobra.NoArgs,
+	RunE: runProxy,
+}
+
+func init() {
+	rootCmd.AddCommand(proxyCmd)
+	proxyCmd.Flags().StringVar(&proxyToPort, "to-port", "", "Serial port of side B")
+	proxyCmd.Flags().IntVar(&proxyToBaud, "to-baud", 115200, "Baud rate of side B (serial only)")
+	proxyCmd.Flags().StringVar(&proxyToURL, "to-url", "", "WebSocket URL of side B")
+	proxyCmd.Flags().StringSliceVar(&proxyNames, "names", []string{"A", "B"}, "Names of side A and side B for log tags and rules")
+	proxyCmd.Flags().StringArrayVar(&proxyDrop, "drop", nil, "Drop packets: [DIR:]TYPE (repeatable)")
+	proxyCmd.Flags().StringArrayVar(&proxySet, "set", nil, "Rewrite a payload key: [DIR:]TYPE.KEY=VALUE (repeatable)")
+	proxyCmd.Flags().StringSliceVar(&displayRateLimits, "rate-limit", nil, rateLimitUsage)
+	proxyCmd.Flags().BoolVarP(&proxyQuiet, "quiet", "q", false, "Only log changed packets and errors")
+	proxyCmd.Flags().BoolVar(&skipSchema, "skip-schema", false, "Skip protocol schema checks (required keys, CBOR types, enum ranges)")
+	proxyCmd.MarkFlagsMutuallyExclusive("to-port", "to-url")
+}
+
+// proxyRule drops or rewrites packets of one type. from is the index of the
+// sending side, or -1 for both.
+type proxyRule struct {
+	from    int
+	msgType uint8
+	drop    bool
+	key     int
+	value   interface{}
+}
+
+// matches reports whether the rule applies to p sent by side from
+func (r proxyRule) matches(p *fusain.Packet, from int) bool {
+	return (r.from < 0 || r.from == from) && p.Type() == r.msgType
+}
+
+// parseProxyRule parses "[DIR:]TYPE" for --drop or "[DIR:]TYPE.KEY=VALUE"
+// for --set
+func parseProxyRule(spec string, names []string, drop bool) (proxyRule, error) {
+	rule := proxyRule{from: -1, drop: drop}
+
+	rest := spec
+	if dir, after, found := strings.Cut(spec, ":"); found {
+		for i, name := range names {
+			if strings.EqualFold(dir, name) {
+				rule.from = i
+			}
+		}
+		if rule.from < 0 {
+			return rule, fmt.Errorf("%q: unknown side %q (valid: %s)", spec, dir, strings.Join(names, ", "))
+		}
+		rest = after
+	}
+
+	if drop {
+		msgType, err := parseMessageTypeFlag(rest)
+		if err != nil {
+			return rule, fmt.Errorf("%q: %v", spec, err)
+		}
+		rule.msgType = msgType
+		return rule, nil
+	}
+
+	target, value, found := strings.Cut(rest, "=")
+	typeName, keyText, found2 := strings.Cut(target, ".")
+	if !found || !found2 {
+		return rule, fmt.Errorf("%q: expected [DIR:]TYPE.KEY=VALUE", spec)
+	}
+	msgType, err := parseMessageTypeFlag(typeName)
+	if err != nil {
+		return rule, fmt.Errorf("%q: %v", spec, err)
+	}
+	key, err := strconv.Atoi(keyText)
+	if err != nil || key < 0 {
+		return rule, fmt.Errorf("%q: invalid payload key %q", spec, keyText)
+	}
+	if !json.Valid([]byte(value)) {
+		return rule, fmt.Errorf("%q: value %q is not JSON", spec, value)
+	}
+
+	// Convert the value through the JSON packet format for its schema type
+	packet, err := unmarshalSendPacket(map[string]interface{}{
+		"address": "0",
+		"type_id": msgType,
+		"payload": map[string]json.RawMessage{keyText: json.RawMessage(value)},
+	})
+	if err != nil {
+		return rule, fmt.Errorf("%q: %v", spec, err)
+	}
+	rule.msgType = msgType
+	rule.key = key
+	rule.value = packet.PayloadMap()[key]
+	return rule, nil
+}
+
+// proxyStats counts one direction's traffic
+type proxyStats struct {
+	forwarded, dropped, modified, rejected, corrupt uint64
+}
+
+// proxy forwards frames between two connections
+type proxy struct {
+	conns [2]Connection
+	names [2]string
+	rules []proxyRule
+
+	mu       sync.Mutex // Serializes log output, limiter and stats
+	limiter  *displayLimiter
+	sessions [2]*fusain.SessionValidator
+	stats    [2]proxyStats
+
+	summaryOnce sync.Once
+}
+
+func runProxy(cmd *cobra.Command, args []string) error {
+	if proxyToPort == "" && proxyToURL == "" {
+		return fmt.Errorf("--to-port or --to-url is required for side B")
+	}
+	if len(proxyNames) != 2 || proxyNames[0] == "" || proxyNames[1] == "" || strings.EqualFold(proxyNames[0], proxyNames[1]) {
+		return fmt.Errorf("--names needs two different names")
+	}
+
+	p := &proxy{names: [2]string{proxyNames[0], proxyNames[1]}}
+	for _, spec := range proxyDrop {
+		rule, err := parseProxyRule(spec, proxyNames, true)
+		if err != nil {
+			return fmt.Errorf("invalid --drop %v", err)
+		}
+		p.rules = append(p.rules, rule)
+	}
+	for _, spec := range proxySet {
+		rule, err := parseProxyRule(spec, proxyNames, false)
+		if err != nil {
+			return fmt.Errorf("invalid --set %v", err)
+		}
+		p.rules = append(p.rules, rule)
+	}
+	limiter, err := newDisplayLimiter(displayRateLimits)
+	if err != nil {
+		return err
+	}
+	p.limiter = limiter
+	for i := range p.sessions {
+		p.sessions[i] = fusain.NewSessionValidator()
+		p.sessions[i].StaleTimeout = 0
+	}
+
+	connA, infoA, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer connA.Close()
+
+	var connB Connection
+	var infoB string
+	if proxyToURL != "" {
+		connB, err = OpenWebSocketConnection(proxyToURL, WebSocketOptions{SkipSSLVerify: wsNoSSLVerify, OfferFrames: !wsStream})
+		infoB = fmt.Sprintf("WebSocket: %s", proxyToURL)
+	} else {
+		connB, err = OpenSerialConnection(proxyToPort, proxyToBaud)
+		infoB = fmt.Sprintf("Serial: %s @ %d baud", proxyToPort, proxyToBaud)
+	}
+	if err != nil {
+		return exitErrorf(ExitConnection, "side %s: %v", p.names[1], err)
+	}
+	defer connB.Close()
+	p.conns = [2]Connection{connA, connB}
+
+	fmt.Printf("Heliostat - Proxy\n")
+	fmt.Printf("%s: %s\n", p.names[0], infoA)
+	fmt.Printf("%s: %s\n", p.names[1], infoB)
+	fmt.Printf("Press Ctrl+C to exit\n\n")
+
+	onShutdown(p.printSummary)
+
+	closed := make(chan int, 2)
+	go p.forward(0, closed)
+	go p.forward(1, closed)
+
+	side := <-closed
+	fmt.Printf("Connection closed (%s)\n", p.names[side])
+	p.printSummary()
+	return nil
+}
+
+// forward copies frames from side from to the other side until the
+// source connection closes
+func (p *proxy) forward(from int, closed chan<- int) {
+	src, dst := p.conns[from], p.conns[1-from]
+
+	var splitter frameSplitter
+	buf := make([]byte, 256)
+	for {
+		n, err := src.Read(buf)
+		if err != nil {
+			if err == ErrConnectionClosed {
+				closed <- from
+				return
+			}
+			// Transient error (e.g. serial); retry like the packet source
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		for _, frame := range splitter.split(buf[:n]) {
+			out := p.process(from, frame)
+			if out == nil {
+				continue
+			}
+			if _, err := dst.Write(out); err != nil {
+				p.mu.Lock()
+				fmt.Printf("[ERROR] %s: write failed: %v\n", p.names[1-from], err)
+				p.mu.Unlock()
+			}
+		}
+	}
+}
+
+// process applies the rules to a frame from side from, logs it and
+// returns the frame to forward (nil to drop it)
+func (p *proxy) process(from int, frame []byte) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := &p.stats[from]
+	tag := fmt.Sprintf("%s>%s", p.names[from], p.names[1-from])
+
+	packet, err := fusain.DecodePacket(frame)
+	if err != nil {
+		stats.corrupt++
+		stats.forwarded++
+		fmt.Printf("%s [ERROR] %v: % X\n", tag, err, frame)
+		return frame
+	}
+	deviceClock.Observe(packet)
+
+	out := frame
+	var change string
+	for _, rule := range p.rules {
+		if !rule.matches(packet, from) {
+			continue
+		}
+		if rule.drop {
+			out, change = nil, " [DROPPED]"
+			break
+		}
+		payload := maps.Clone(packet.PayloadMap())
+		if payload == nil {
+			payload = make(map[int]interface{})
+		}
+		payload[rule.key] = rule.value
+		encoded, err := fusain.EncodePacket(packet.Address(), packet.Type(), payload)
+		if err != nil {
+			fmt.Printf("%s [ERROR] cannot rewrite %s: %v\n", tag, fusain.FormatMessageType(packet.Type()), err)
+			continue
+		}
+		if rewritten, err := fusain.DecodePacket(encoded); err == nil {
+			out, change, packet = encoded, " [MODIFIED]", rewritten
+		}
+	}
+
+	// Commands pass the address filter and interlocks like any command
+	// heliostat sends, so a --set rule can't turn one into HEAT
+	var rejected error
+	if out != nil && isCommand(packet) {
+		if rejected = checkCommandPolicy(packet); rejected != nil {
+			out, change = nil, " [REJECTED]"
+		}
+	}
+
+	switch {
+	case rejected != nil:
+		stats.rejected++
+	case out == nil:
+		stats.dropped++
+	case change != "":
+		stats.modified++
+		stats.forwarded++
+	default:
+		stats.forwarded++
+	}
+
+	anomalies := fusain.ValidatePacketWithOptions(packet, validateOptions())
+	anomalies = append(anomalies, p.sessions[from].Validate(packet)...)
+
+	if change == "" && len(anomalies) == 0 {
+		if proxyQuiet {
+			return out
+		}
+		if show, suppressed := p.limiter.admit(packet); show {
+			fmt.Print(tag + " " + p.limiter.format(packet, suppressed))
+		}
+		return out
+	}
+
+	fmt.Print(tag + " " + withHeaderSuffix(fusain.FormatPacketWithOptions(packet, formatOptions()), change))
+	if rejected != nil {
+		fmt.Printf("  Rejected: %v\n", rejected)
+	}
+	for _, anomaly := range anomalies {
+		fmt.Printf("  Anomaly: %s\n", anomaly.Message)
+	}
+	return out
+}
+
+// printSummary prints the per-direction counts once, at exit or on signal
+func (p *proxy) printSummary() {
+	p.summaryOnce.Do(p.writeSummary)
+}
+
+func (p *proxy) writeSummary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Printf("\n--- proxy summary ---\n")
+	for from := range p.stats {
+		s := p.stats[from]
+		fmt.Printf("%s>%s: %d forwarded, %d modified, %d dropped, %d rejected, %d corrupt\n",
+			p.names[from], p.names[1-from], s.forwarded, s.modified, s.dropped, s.rejected, s.corrupt)
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func newTestProxy(t *testing.T, sets ...string) *proxy {
+	t.Helper()
+	p := &proxy{names: [2]string{"A", "B"}}
+	for _, spec := range sets {
+		rule, err := parseProxyRule(spec, p.names[:], false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.rules = append(p.rules, rule)
+	}
+	for i := range p.sessions {
+		p.sessions[i] = fusain.NewSessionValidator()
+		p.sessions[i].StaleTimeout = 0
+	}
+	return p
+}
+
+func TestProxyRejectsRewrittenCommand(t *testing.T) {
+	useConfig(t, func(c *Config) { c.Interlocks = []InterlockRule{{}} })
+	p := newTestProxy(t, "A:STATE_COMMAND.0=2")
+
+	// IDLE rewritten to HEAT, which is interlocked
+	if out := p.process(0, wireFrame(t, fusain.NewStateCommand(1, uint8(fusain.ModeIdle), nil))); out != nil {
+		t.Error("rewritten HEAT command forwarded")
+	}
+	// The rule only applies to side A
+	if out := p.process(1, wireFrame(t, fusain.NewStateCommand(1, uint8(fusain.ModeIdle), nil))); out == nil {
+		t.Error("IDLE command dropped")
+	}
+	if s := p.stats[0]; s.rejected != 1 || s.forwarded != 0 || s.modified != 0 {
+		t.Errorf("A>B stats %+v, want 1 rejected", s)
+	}
+	if s := p.stats[1]; s.rejected != 0 || s.forwarded != 1 {
+		t.Errorf("B>A stats %+v, want 1 forwarded", s)
+	}
+}
+
+func TestProxyRejectsFilteredCommand(t *testing.T) {
+	saved := deviceFilter
+	t.Cleanup(func() { deviceFilter = saved })
+	deviceFilter = &addressFilter{allow: map[uint64]bool{1: true}}
+	p := newTestProxy(t)
+
+	if out := p.process(0, wireFrame(t, fusain.NewStateCommand(2, uint8(fusain.ModeFan), nil))); out != nil {
+		t.Error("command to a filtered device forwarded")
+	}
+	if out := p.process(0, wireFrame(t, fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil))); out == nil {
+		t.Error("command to an allowed device dropped")
+	}
+	// Telemetry is not a command
+	if out := p.process(0, wireFrame(t, stateData(2, fusain.SysStateIdle))); out == nil {
+		t.Error("telemetry dropped")
+	}
+	if s := p.stats[0]; s.rejected != 1 || s.forwarded != 2 {
+		t.Errorf("stats %+v, want 1 rejected, 2 forwarded", s)
+	}
+}
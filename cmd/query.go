@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryKind    string
+	queryFrom    string
+	queryTo      string
+	queryTypes   []string
+	queryDevices []string
+	querySession int64
+	queryLimit   int
+	queryFollow  bool
+)
+
+// followPollInterval is how often --follow checks for new records
+const followPollInterval = 500 * time.Millisecond
+
+var queryCmd = &cobra.Command{
+	Use:   "query DATABASE",
+	Short: "Query a session database written by record --db",
+	Long: `Print records from a session database written by 'heliostat record --db',
+filtered by time range, message type and device. DATABASE is an SQLite file
+path or a store URL, as given to --db.
+
+--kind selects what to print: packets (default), anomalies (validation
+errors), decode_errors, stats (statistics snapshots) or sessions. Type and
+device filters apply to packets and anomalies only.
+
+--from and --to accept RFC 3339 times, local times as "2006-01-02 15:04:05",
+or a duration meaning that long ago (e.g. 1h). --from is inclusive, --to
+exclusive.
+
+--output json writes one JSON object per record.
+
+--follow keeps printing records as they are written, like tail -f, so a
+recording in progress can be inspected from a second heliostat instance
+without interrupting it. Ctrl+C stops.
+
+Examples:
+  heliostat query soak.db --kind sessions
+  heliostat query soak.db --type MOTOR_DATA --device 0x1 --from 1h
+  heliostat query soak.db --kind anomalies --from "2025-01-02 15:00:00" --to "2025-01-02 16:00:00"
+  heliostat query soak.db --kind stats --session 2 --output json
+  heliostat query soak.db --kind anomalies --from 10m --follow`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQuery,
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+	queryCmd.Flags().StringVar(&queryKind, "kind", "packets", "Records to print: packets, anomalies, decode_errors, stats or sessions")
+	queryCmd.Flags().StringVar(&queryFrom, "from", "", "Earliest record time (RFC 3339, local \"2006-01-02 15:04:05\", or a duration ago)")
+	queryCmd.Flags().StringVar(&queryTo, "to", "", "Latest record time, exclusive (same formats as --from)")
+	queryCmd.Flags().StringSliceVar(&queryTypes, "type", nil, "Only these message types (name or number, repeatable)")
+	queryCmd.Flags().StringSliceVar(&queryDevices, "device", nil, "Only these device addresses (hex or alias, repeatable)")
+	queryCmd.Flags().Int64Var(&querySession, "session", 0, "Only this recording session (see --kind sessions)")
+	queryCmd.Flags().IntVar(&queryLimit, "limit", 0, "Print at most this many records (0 = all)")
+	queryCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format: text, or json for one object per record")
+	queryCmd.Flags().BoolVarP(&queryFollow, "follow", "f", false, "Keep printing new records as they are recorded")
+	queryCmd.MarkFlagsMutuallyExclusive("follow", "limit")
+	queryCmd.MarkFlagsMutuallyExclusive("follow", "to")
+}
+
+// openRecordedStore opens a session database written by record --db. A
+// missing SQLite file is an error, where opening it would create an empty
+// database.
+func openRecordedStore(location string) (store.Store, error) {
+	if backend, path := store.ParseLocation(location); backend == "sqlite" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+	return store.Open(location)
+}
+
+// parseQueryTime parses a --from/--to value relative to now
+func parseQueryTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339, \"2006-01-02 15:04:05\" or a duration", s)
+}
+
+// buildQuery builds the store query from the flags
+func buildQuery() (store.Query, error) {
+	now := time.Now()
+	q := store.Query{Session: querySession, Limit: queryLimit}
+	var err error
+	if q.From, err = parseQueryTime(queryFrom, now); err != nil {
+		return q, fmt.Errorf("invalid --from: %v", err)
+	}
+	if q.To, err = parseQueryTime(queryTo, now); err != nil {
+		return q, fmt.Errorf("invalid --to: %v", err)
+	}
+	for _, name := range queryTypes {
+		t, err := parseMessageTypeFlag(name)
+		if err != nil {
+			return q, fmt.Errorf("invalid --type: %v", err)
+		}
+		q.Types = append(q.Types, t)
+	}
+	for _, s := range queryDevices {
+		address, err := parseAddress(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid --device: %v", err)
+		}
+		q.Addresses = append(q.Addresses, address)
+	}
+	return q, nil
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
+	jsonMode, err := jsonOutput()
+	if err != nil {
+		return err
+	}
+	q, err := buildQuery()
+	if err != nil {
+		return err
+	}
+	db, err := openRecordedStore(args[0])
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if !queryFollow {
+		_, err := printQueryRecords(db, q, jsonMode)
+		return err
+	}
+
+	// Print what's there, then poll for records with larger IDs
+	for {
+		last, err := printQueryRecords(db, q, jsonMode)
+		if err != nil {
+			return err
+		}
+		q.AfterID = max(q.AfterID, last)
+		time.Sleep(followPollInterval)
+	}
+}
+
+// printQueryRecords prints the records of --kind matching q and returns the
+// largest record ID printed
+func printQueryRecords(db store.Store, q store.Query, jsonMode bool) (int64, error) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	var last int64
+
+	switch queryKind {
+	case "packets":
+		records, err := db.Packets(q)
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range records {
+			last = max(last, r.ID)
+			if jsonMode {
+				enc.Encode(struct {
+					Session int64          `json:"session"`
+					Time    time.Time      `json:"time"`
+					Packet  *fusain.Packet `json:"packet"`
+				}{r.Session, r.Packet.Timestamp(), r.Packet})
+				continue
+			}
+			fmt.Print(fusain.FormatPacketWithOptions(r.Packet, formatOptions()))
+		}
+	case "anomalies":
+		records, err := db.Anomalies(q)
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range records {
+			last = max(last, r.ID)
+			if jsonMode {
+				enc.Encode(struct {
+					Session  int64                  `json:"session"`
+					PacketID int64                  `json:"packet_id"`
+					Time     time.Time              `json:"time"`
+					Address  string                 `json:"address"`
+					Type     string                 `json:"type"`
+					Anomaly  string                 `json:"anomaly"`
+					Check    string                 `json:"check,omitempty"`
+					Message  string                 `json:"message"`
+					Details  map[string]interface{} `json:"details,omitempty"`
+				}{r.Session, r.PacketID, r.Time, fmt.Sprintf("%016X", r.Address), fusain.FormatMessageType(r.MsgType), r.Anomaly, r.Check, r.Message, r.Details})
+				continue
+			}
+			fmt.Printf("[%s] %s %-16s %s: %s\n", r.Time.Format(time.DateTime+".000"), formatAddress(r.Address), fusain.FormatMessageType(r.MsgType), r.Anomaly, r.Message)
+		}
+	case "decode_errors":
+		records, err := db.DecodeErrors(q)
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range records {
+			last = max(last, r.ID)
+			if jsonMode {
+				enc.Encode(struct {
+					Session int64     `json:"session"`
+					Time    time.Time `json:"time"`
+					Error   string    `json:"error"`
+					Frame   string    `json:"frame,omitempty"`
+				}{r.Session, r.Time, r.Message, hex.EncodeToString(r.Frame)})
+				continue
+			}
+			fmt.Printf("[%s] %s", r.Time.Format(time.DateTime+".000"), r.Message)
+			if len(r.Frame) > 0 {
+				fmt.Printf(" (frame % X)", r.Frame)
+			}
+			fmt.Println()
+		}
+	case "stats":
+		records, err := db.Stats(q)
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range records {
+			last = max(last, r.ID)
+			if jsonMode {
+				enc.Encode(struct {
+					Session int64           `json:"session"`
+					Time    time.Time       `json:"time"`
+					Stats   json.RawMessage `json:"stats"`
+				}{r.Session, r.Time, r.Stats})
+				continue
+			}
+			fmt.Printf("[%s] session %d: %s\n", r.Time.Format(time.DateTime), r.Session, summarizeStatsJSON(r.Stats))
+		}
+	case "sessions":
+		sessions, err := db.Sessions()
+		if err != nil {
+			return 0, err
+		}
+		for _, s := range sessions {
+			if s.ID <= q.AfterID {
+				continue
+			}
+			last = max(last, s.ID)
+			if jsonMode {
+				enc.Encode(struct {
+					ID         int64     `json:"id"`
+					StartedAt  time.Time `json:"started_at"`
+					Connection string    `json:"connection"`
+					Packets    int64     `json:"packets"`
+				}{s.ID, s.StartedAt, s.Connection, s.Packets})
+				continue
+			}
+			fmt.Printf("%4d  %s  %8d packets  %s\n", s.ID, s.StartedAt.Format(time.DateTime), s.Packets, s.Connection)
+		}
+	default:
+		return 0, fmt.Errorf("invalid --kind %q (valid: packets, anomalies, decode_errors, stats, sessions)", queryKind)
+	}
+	return last, nil
+}
+
+// summarizeStatsJSON renders the headline counters of a stored statistics
+// snapshot
+func summarizeStatsJSON(raw json.RawMessage) string {
+	var stats map[string]interface{}
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return string(raw)
+	}
+	var parts []string
+	for _, key := range []string{"total_packets", "valid_packets", "crc_errors", "decode_errors", "malformed_packets", "anomalous_values"} {
+		if v, ok := stats[key].(float64); ok {
+			parts = append(parts, fmt.Sprintf("%s=%.0f", key, v))
+		}
+	}
+	if len(parts) == 0 {
+		return string(raw)
+	}
+	return strings.Join(parts, " ")
+}
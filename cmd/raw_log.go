@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/events"
 	"github.com/spf13/cobra"
 )
 
@@ -19,51 +19,88 @@ var rawLogCmd = &cobra.Command{
 This command provides the same output as the original heliostat tool, showing
 each packet with timestamp, message type, and decoded payload data.
 
-Supports both serial and WebSocket connections.`,
+Supports both serial and WebSocket connections.
+
+--rate-limit thins high-frequency telemetry so command and response traffic
+stays readable: MOTOR_DATA=1s shows at most one MOTOR_DATA per device per
+second, and the next one shown is marked with the number suppressed. A bare
+interval applies to every telemetry data type.
+
+--output json writes one JSON object per line instead: a "packet" record
+for each packet and a "decode_error" record for each decode failure.
+
+Examples:
+  heliostat raw_log --port /dev/ttyUSB0 --output json | jq 'select(.packet.type == "STATE_DATA")'
+  heliostat raw_log --port /dev/ttyUSB0 --rate-limit MOTOR_DATA=1s --rate-limit PUMP_DATA=1s
+  heliostat raw_log --port /dev/ttyUSB0 --rate-limit 500ms`,
 	RunE: runRawLog,
 }
 
 func init() {
 	rootCmd.AddCommand(rawLogCmd)
+	rawLogCmd.Flags().StringSliceVar(&displayRateLimits, "rate-limit", nil, rateLimitUsage)
+	rawLogCmd.Flags().StringVar(&outputFormat, "output", "text", outputUsage)
 }
 
 func runRawLog(cmd *cobra.Command, args []string) error {
+	limiter, err := newDisplayLimiter(displayRateLimits)
+	if err != nil {
+		return err
+	}
+	jsonMode, err := jsonOutput()
+	if err != nil {
+		return err
+	}
+
 	// Open connection (serial or WebSocket)
-	conn, connInfo, err := OpenConnection()
+	conn, connInfo, err := OpenReconnectingConnection(nil)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	fmt.Printf("Heliostat - Raw Packet Log\n")
-	fmt.Printf("Connection: %s\n", connInfo)
-	fmt.Printf("Press Ctrl+C to exit\n\n")
+	var out *jsonLines
+	if jsonMode {
+		out = newJSONLines()
+	} else {
+		fmt.Printf("Heliostat - Raw Packet Log\n")
+		fmt.Printf("Connection: %s\n", connInfo)
+		fmt.Printf("Press Ctrl+C to exit\n\n")
+	}
 
-	decoder := fusain.NewDecoder()
-	buf := make([]byte, 128)
+	sub := eventBus.SubscribeLossless(256)
+	defer sub.Close()
 
-	for {
-		n, err := conn.Read(buf)
-		if err != nil {
-			// For WebSocket connections, a read error usually means
-			// the connection is permanently closed - exit gracefully
-			if err == ErrConnectionClosed {
-				log.Printf("Connection closed")
-				return nil
-			}
-			log.Printf("Read error: %v", err)
-			continue
-		}
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, false).run(conn, done)
 
-		for i := 0; i < n; i++ {
-			packet, err := decoder.DecodeByte(buf[i])
-			if err != nil {
-				fmt.Printf("[ERROR] %v\n", err)
+	for e := range sub.Events() {
+		switch e := e.(type) {
+		case events.PacketReceived:
+			if out != nil {
+				out.packet(e.At, e.Packet, nil)
 				continue
 			}
-			if packet != nil {
-				fmt.Print(fusain.FormatPacket(packet))
+			if show, suppressed := limiter.admit(e.Packet); show {
+				fmt.Print(limiter.format(e.Packet, suppressed))
+			}
+		case events.DecodeError:
+			if out != nil {
+				out.decodeError(e.At, e.Err)
+				continue
+			}
+			fmt.Printf("[ERROR] %v\n", e.Err)
+		case events.ReadError:
+			log.Printf("Read error: %v", e.Err)
+		case events.ConnectionLost:
+			log.Print(connectionLostMessage(e))
+			if !e.Reconnecting {
+				return nil
 			}
+		case events.ConnectionRestored:
+			log.Print(connectionRestoredMessage(e))
 		}
 	}
+	return nil
 }
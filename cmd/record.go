@@ -0,0 +1,617 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recordOutput         string
+	recordRotateSize     int
+	recordRotateDuration time.Duration
+	recordFlushInterval  time.Duration
+	recordQuiet          bool
+	recordDB             string
+	recordTriggers       []string
+	recordPreTrigger     time.Duration
+	recordPostTrigger    time.Duration
+	recordFrom           string
+	recordTiming         string
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Capture every received frame to a file",
+	Long: `Write every frame received on the connection to a capture file, with its
+receive time, for offline analysis and bug reports.
+
+Frames are stored exactly as received, START and END bytes included, so
+frames that fail CRC or decoding are kept too. Bytes outside frames are not
+recorded. The capture file is a sequence of Fusain batch records (see
+fusain.BatchWriter); 'heliostat replay' plays it back.
+
+With --rotate-size or --rotate-duration, a new file is started when the
+current one reaches the size or age, and files are numbered:
+session.cap becomes session-0001.cap, session-0002.cap, ...
+
+With --db, frames are also decoded into a session database: each recording
+is a session holding the packets, their validation errors, decode errors and
+a statistics snapshot every minute. 'heliostat query' filters it by time,
+message type and device. --db can be used without --output. --db takes an
+SQLite file path, or a NAME://... URL for another registered store backend.
+
+--device/--exclude-device (or --allow-device/--deny-device) keep only the
+frames of the selected devices; frames that fail to decode are kept, since
+their address is unknown.
+
+Records are written every --flush interval, so at most that much capture is
+lost if heliostat is killed. Ctrl+C flushes and closes the file.
+
+With --trigger, only the frames around events are captured: the last
+--pre-trigger interval is kept in memory, and when a trigger fires a new
+numbered file (session-0001.cap, ...) gets those frames and everything
+until --post-trigger has passed without another trigger. Triggers
+(repeatable; any of them starts a capture):
+  estop                     an emergency stop (E_STOP state or command)
+  type:NAME                 a packet of this message type
+  decode-errors:N/DURATION  N frames failing CRC or decoding within DURATION
+  CONDITION                 a telemetry condition as in --rules, e.g.
+                            "temp0 > 220" or "state.error_code != 0"
+--db still stores every frame.
+
+--from reads frames from an existing capture instead of a connection, e.g.
+to cut the windows around events out of a long soak recording.
+
+--timing also writes every read of the connection, with its time and bytes,
+to a timing file, so the byte pacing can be reconstructed: 'heliostat report
+timing' shows the gaps between reads and whether frames that failed CRC or
+decoding arrived with a stall in the middle.
+
+Examples:
+  heliostat record -p /dev/ttyUSB0 -o session.cap
+  heliostat record --url ws://slate.local/ws -o bench.cap --rotate-size 64 --rotate-duration 1h
+  heliostat record -p /dev/ttyUSB0 --db soak.db
+  heliostat record -p /dev/ttyUSB0 -o fault.cap --trigger estop --trigger decode-errors:5/1s
+  heliostat record --from soak.cap -o overheat.cap --trigger "temp0 > 220" --pre-trigger 30s
+  heliostat record -p /dev/ttyUSB0 -o session.cap --timing session.timing`,
+	Args: cobra.NoArgs,
+	RunE: runRecord,
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "", "Capture file path")
+	recordCmd.Flags().IntVar(&recordRotateSize, "rotate-size", 0, "Start a new file after this many MiB (0 = never)")
+	recordCmd.Flags().DurationVar(&recordRotateDuration, "rotate-duration", 0, "Start a new file after this long (0 = never)")
+	recordCmd.Flags().DurationVar(&recordFlushInterval, "flush", time.Second, "Write buffered frames at least this often")
+	recordCmd.Flags().BoolVarP(&recordQuiet, "quiet", "q", false, "Don't print progress")
+	recordCmd.Flags().StringVar(&recordDB, "db", "", "Also store decoded packets, errors and statistics in this database (SQLite path or store URL)")
+	recordCmd.Flags().StringArrayVar(&recordTriggers, "trigger", nil, "Only capture around events matching this trigger (repeatable; see above)")
+	recordCmd.Flags().DurationVar(&recordPreTrigger, "pre-trigger", 10*time.Second, "Frames before a trigger to include")
+	recordCmd.Flags().DurationVar(&recordPostTrigger, "post-trigger", time.Minute, "Keep capturing this long after the last trigger")
+	recordCmd.Flags().StringVar(&recordFrom, "from", "", "Read frames from this capture file instead of a connection")
+	recordCmd.Flags().StringVar(&recordTiming, "timing", "", "Also write the time and bytes of every read to this file (see 'report timing')")
+}
+
+// captureSink receives the recorded frames: captureWriter keeps them all,
+// triggeredCapture those around trigger events
+type captureSink interface {
+	WriteFrame(at time.Time, frame []byte) error
+	Flush() error
+	Close() error
+	Progress() string // Status line
+	Describe() string // Where frames go, for the start message
+}
+
+// admitFrame applies the address filter to a recorded frame. Frames that
+// don't decode are kept, since their address is unknown.
+func admitFrame(at time.Time, frame []byte) bool {
+	if !deviceFilter.active() {
+		return true
+	}
+	p, err := fusain.BatchFrame{Timestamp: at, Frame: frame}.Packet()
+	return err != nil || deviceFilter.admit(p)
+}
+
+// frameSplitter cuts a byte stream into wire frames, from a START byte to
+// the next END byte. A START byte inside a frame starts a new frame, as in
+// the decoder. Frames are returned as copies.
+type frameSplitter struct {
+	frame   []byte
+	inFrame bool
+}
+
+// split returns the frames completed by data
+func (f *frameSplitter) split(data []byte) [][]byte {
+	var frames [][]byte
+	for _, b := range data {
+		switch {
+		case b == fusain.StartByte:
+			f.frame = append(f.frame[:0], b)
+			f.inFrame = true
+		case !f.inFrame:
+			// Noise between frames
+		case b == fusain.EndByte:
+			f.frame = append(f.frame, b)
+			frames = append(frames, bytes.Clone(f.frame))
+			f.inFrame = false
+		case len(f.frame) >= fusain.MaxPacketSize*2:
+			// Longer than any stuffed frame; wait for the next START
+			f.inFrame = false
+		default:
+			f.frame = append(f.frame, b)
+		}
+	}
+	return frames
+}
+
+// captureWriter writes frames to capture files, rotating by size and age.
+// It is safe for concurrent use, so the shutdown hook can close it.
+type captureWriter struct {
+	mu sync.Mutex
+
+	path        string
+	maxSize     int64
+	maxDuration time.Duration
+
+	file    *os.File
+	counter *countingWriter
+	batch   *fusain.BatchWriter
+	opened  time.Time
+	index   int
+	closed  bool
+
+	frames uint64 // Frames written across all files
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	f *os.File
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.f.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newCaptureWriter(path string, maxSize int64, maxDuration time.Duration) (*captureWriter, error) {
+	w := &captureWriter{path: path, maxSize: maxSize, maxDuration: maxDuration}
+	if err := w.open(time.Now()); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// rotating reports whether files are numbered
+func (w *captureWriter) rotating() bool {
+	return w.maxSize > 0 || w.maxDuration > 0
+}
+
+// fileName returns the name of the current file
+func (w *captureWriter) fileName() string {
+	if !w.rotating() {
+		return w.path
+	}
+	return numberedPath(w.path, w.index)
+}
+
+// numberedPath returns the index'th file of a series named after path:
+// session.cap gives session-0001.cap, ...
+func numberedPath(path string, index int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(path, ext), index, ext)
+}
+
+func (w *captureWriter) open(now time.Time) error {
+	w.index++
+	file, err := os.OpenFile(w.fileName(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("cannot create capture file: %v", err)
+	}
+	w.file = file
+	w.counter = &countingWriter{f: file}
+	w.batch = fusain.NewBatchWriter(w.counter, 0)
+	w.opened = now
+	return nil
+}
+
+// closeFile flushes and closes the current file
+func (w *captureWriter) closeFile() error {
+	flushErr := w.batch.Flush()
+	w.frames += w.batch.Frames()
+	if err := w.file.Close(); err != nil && flushErr == nil {
+		flushErr = err
+	}
+	return flushErr
+}
+
+// WriteFrame adds a frame, rotating first if the current file is full
+func (w *captureWriter) WriteFrame(at time.Time, frame []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	if w.rotating() && w.batch.Frames() > 0 {
+		full := w.maxSize > 0 && w.counter.n >= w.maxSize
+		old := w.maxDuration > 0 && at.Sub(w.opened) >= w.maxDuration
+		if full || old {
+			if err := w.closeFile(); err != nil {
+				return err
+			}
+			if err := w.open(at); err != nil {
+				return err
+			}
+		}
+	}
+	return w.batch.WriteFrame(at, frame)
+}
+
+// Flush writes buffered frames to the current file
+func (w *captureWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	return w.batch.Flush()
+}
+
+// Close flushes and closes the current file. Later calls do nothing.
+func (w *captureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.closeFile()
+}
+
+// Status returns the frames written so far and the current file name
+func (w *captureWriter) Status() (frames uint64, file string, size int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.frames + w.batch.Frames(), w.fileName(), w.counter.n
+}
+
+// Progress describes the capture for the status line
+func (w *captureWriter) Progress() string {
+	frames, file, size := w.Status()
+	return fmt.Sprintf("%d frames, %s (%d bytes)", frames, file, size)
+}
+
+// Describe names the current file
+func (w *captureWriter) Describe() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fileName()
+}
+
+func runRecord(cmd *cobra.Command, args []string) error {
+	if recordRotateSize < 0 || recordRotateDuration < 0 {
+		return fmt.Errorf("--rotate-size and --rotate-duration must not be negative")
+	}
+	if recordFlushInterval <= 0 {
+		return fmt.Errorf("--flush must be positive")
+	}
+
+	if recordOutput == "" && recordDB == "" {
+		return fmt.Errorf("--output or --db is required")
+	}
+	if recordTiming != "" && recordFrom != "" {
+		return fmt.Errorf("--timing records connection reads and can't be combined with --from")
+	}
+
+	var triggers []*captureTrigger
+	for _, spec := range recordTriggers {
+		t, err := parseCaptureTrigger(spec)
+		if err != nil {
+			return err
+		}
+		triggers = append(triggers, t)
+	}
+	if triggers != nil {
+		if recordOutput == "" {
+			return fmt.Errorf("--trigger needs --output")
+		}
+		if recordRotateSize > 0 || recordRotateDuration > 0 {
+			return fmt.Errorf("--trigger starts a new file per event and can't be combined with --rotate-size or --rotate-duration")
+		}
+		if recordPreTrigger < 0 || recordPostTrigger < 0 {
+			return fmt.Errorf("--pre-trigger and --post-trigger must not be negative")
+		}
+	}
+
+	var conn Connection
+	var connInfo string
+	if recordFrom != "" {
+		if portName != "" || wsURL != "" || tcpAddr != "" {
+			return fmt.Errorf("--from reads a capture file instead of a connection; drop --port, --url and --tcp")
+		}
+		connInfo = "capture " + recordFrom
+	} else {
+		var err error
+		conn, connInfo, err = OpenReconnectingConnection(nil)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+
+	var capture captureSink
+	if recordOutput != "" {
+		if triggers != nil {
+			tc := newTriggeredCapture(recordOutput, recordPreTrigger, recordPostTrigger, triggers)
+			tc.onCapture = func(file, trigger string) {
+				if recordQuiet {
+					return
+				}
+				if recordFrom == "" {
+					fmt.Println() // End the status line
+				}
+				fmt.Printf("Trigger %s: capturing to %s\n", trigger, file)
+			}
+			capture = tc
+		} else {
+			w, err := newCaptureWriter(recordOutput, int64(recordRotateSize)<<20, recordRotateDuration)
+			if err != nil {
+				return err
+			}
+			capture = w
+		}
+		onShutdown(func() {
+			if err := capture.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error closing capture: %v\n", err)
+			}
+		})
+		defer capture.Close()
+	}
+
+	var db *dbRecorder
+	if recordDB != "" {
+		var err error
+		db, err = newDBRecorder(recordDB, connInfo)
+		if err != nil {
+			return err
+		}
+		onShutdown(func() {
+			if err := db.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			}
+		})
+		defer db.Close()
+	}
+
+	var timing *readTimingWriter
+	if recordTiming != "" {
+		var err error
+		timing, err = newReadTimingWriter(recordTiming)
+		if err != nil {
+			return err
+		}
+		onShutdown(func() {
+			if err := timing.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error closing timing file: %v\n", err)
+			}
+		})
+		defer timing.Close()
+	}
+
+	if !recordQuiet {
+		if capture != nil {
+			fmt.Printf("Recording %s to %s\n", connInfo, capture.Describe())
+		}
+		if timing != nil {
+			fmt.Printf("Recording read timing to %s\n", recordTiming)
+		}
+		if db != nil {
+			fmt.Printf("Recording %s to database %s\n", connInfo, recordDB)
+		}
+		if recordFrom == "" {
+			fmt.Printf("Press Ctrl+C to stop\n")
+		}
+	}
+
+	if recordFrom != "" {
+		return recordFromCapture(recordFrom, capture, db)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(recordFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case at := <-ticker.C:
+				start := time.Now()
+				var status []string
+				if capture != nil {
+					if err := capture.Flush(); err != nil {
+						fmt.Fprintf(os.Stderr, "Error writing capture: %v\n", err)
+					}
+					status = append(status, capture.Progress())
+				}
+				if db != nil {
+					if err := db.Flush(); err != nil {
+						fmt.Fprintf(os.Stderr, "Error writing database: %v\n", err)
+					}
+					status = append(status, fmt.Sprintf("%d packets in %s", db.Packets(), recordDB))
+				}
+				if timing != nil {
+					if err := timing.Flush(); err != nil {
+						fmt.Fprintf(os.Stderr, "Error writing timing file: %v\n", err)
+					}
+					status = append(status, timing.Progress())
+				}
+				if !recordQuiet {
+					fmt.Printf("\r%s   ", strings.Join(status, ", "))
+				}
+				recordBatchTick(at, start)
+			}
+		}
+	}()
+
+	// Report reconnects; reads block while they happen
+	if !recordQuiet {
+		sub := eventBus.Subscribe(16)
+		defer sub.Close()
+		go func() {
+			for e := range sub.Events() {
+				switch e := e.(type) {
+				case events.ConnectionLost:
+					fmt.Printf("\n%s\n", connectionLostMessage(e))
+				case events.ConnectionRestored:
+					fmt.Printf("\n%s\n", connectionRestoredMessage(e))
+				}
+			}
+		}()
+	}
+
+	var splitter frameSplitter
+	buf := make([]byte, 256)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			// Drop the partial frame the lost connection left
+			if err == ErrReconnected {
+				splitter = frameSplitter{}
+				if timing != nil {
+					if err := timing.WriteRead(time.Now(), nil); err != nil {
+						return exitErrorf(ExitFailure, "error writing timing file: %v", err)
+					}
+				}
+				continue
+			}
+			if err == ErrConnectionClosed {
+				if !recordQuiet {
+					fmt.Println("\nConnection closed")
+				}
+				if timing != nil {
+					if err := timing.Close(); err != nil {
+						return exitErrorf(ExitFailure, "error closing timing file: %v", err)
+					}
+				}
+				return closeRecording(capture, db)
+			}
+			// Transient error (e.g. serial); retry like the packet source
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		now := time.Now()
+		if timing != nil && n > 0 {
+			if err := timing.WriteRead(now, buf[:n]); err != nil {
+				return exitErrorf(ExitFailure, "error writing timing file: %v", err)
+			}
+		}
+		for _, frame := range splitter.split(buf[:n]) {
+			if !admitFrame(now, frame) {
+				continue
+			}
+			if err := writeRecordedFrame(capture, db, now, frame); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeRecordedFrame writes a frame to the capture and the database, each
+// if set
+func writeRecordedFrame(capture captureSink, db *dbRecorder, at time.Time, frame []byte) error {
+	if capture != nil {
+		if err := capture.WriteFrame(at, frame); err != nil {
+			return exitErrorf(ExitFailure, "error writing capture: %v", err)
+		}
+	}
+	if db != nil {
+		if err := db.WriteFrame(at, frame); err != nil {
+			return exitErrorf(ExitFailure, "error writing database: %v", err)
+		}
+	}
+	return nil
+}
+
+// closeRecording closes the capture and the database, each if set
+func closeRecording(capture captureSink, db *dbRecorder) error {
+	if capture != nil {
+		if err := capture.Close(); err != nil {
+			return exitErrorf(ExitFailure, "error closing capture: %v", err)
+		}
+	}
+	if db != nil {
+		if err := db.Close(); err != nil {
+			return exitErrorf(ExitFailure, "error closing database: %v", err)
+		}
+	}
+	return nil
+}
+
+// recordFromCapture records the frames of an existing capture file, with
+// their original receive times
+func recordFromCapture(path string, capture captureSink, db *dbRecorder) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open capture: %v", err)
+	}
+	defer file.Close()
+
+	reader := fusain.NewBatchReader(file)
+	var frames uint64
+	for {
+		batch, err := reader.ReadBatch()
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			fmt.Fprintf(os.Stderr, "Warning: %s ends with a truncated record\n", path)
+			break
+		}
+		if err != nil {
+			return exitErrorf(ExitFailure, "cannot read %s: %v", path, err)
+		}
+		for _, f := range batch {
+			if !admitFrame(f.Timestamp, f.Frame) {
+				continue
+			}
+			frames++
+			if err := writeRecordedFrame(capture, db, f.Timestamp, f.Frame); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := closeRecording(capture, db); err != nil {
+		return err
+	}
+	if !recordQuiet {
+		status := []string{fmt.Sprintf("%d frames read", frames)}
+		if capture != nil {
+			status = append(status, capture.Progress())
+		}
+		if db != nil {
+			status = append(status, fmt.Sprintf("%d packets in %s", db.Packets(), recordDB))
+		}
+		fmt.Println(strings.Join(status, ", "))
+	}
+	return nil
+}
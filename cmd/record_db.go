@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/store"
+)
+
+// recordStatsInterval is how often record --db stores a statistics snapshot
+const recordStatsInterval = time.Minute
+
+// dbRecorder decodes recorded frames into a store session: packets with
+// their validation errors, decode errors, and periodic statistics
+// snapshots. It is safe for concurrent use, so the shutdown hook can close
+// it.
+type dbRecorder struct {
+	mu sync.Mutex
+
+	store        store.Store
+	session      *fusain.SessionValidator
+	stats        *fusain.Statistics
+	lastSnapshot time.Time
+	packets      uint64
+	closed       bool
+}
+
+func newDBRecorder(location, connInfo string) (*dbRecorder, error) {
+	db, err := store.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := db.StartSession(now, connInfo); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &dbRecorder{
+		store:        db,
+		session:      fusain.NewSessionValidator(),
+		stats:        newStatistics(),
+		lastSnapshot: now,
+	}, nil
+}
+
+// WriteFrame decodes, validates and stores a frame received at at
+func (r *dbRecorder) WriteFrame(at time.Time, frame []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return os.ErrClosed
+	}
+
+	p, err := fusain.BatchFrame{Timestamp: at, Frame: frame}.Packet()
+	if err != nil {
+		r.stats.Update(nil, err, nil)
+		return r.store.WriteDecodeError(at, err, frame)
+	}
+	anomalies := fusain.ValidatePacketWithOptions(p, validateOptions())
+	anomalies = append(anomalies, r.session.Validate(p)...)
+	r.stats.Update(p, nil, anomalies)
+	r.packets++
+	return r.store.WritePacket(at, p, anomalies)
+}
+
+// Flush commits buffered writes, storing a statistics snapshot first when
+// one is due
+func (r *dbRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	if now := time.Now(); now.Sub(r.lastSnapshot) >= recordStatsInterval {
+		if err := r.snapshot(now); err != nil {
+			return err
+		}
+	}
+	return r.store.Flush()
+}
+
+// snapshot stores the current statistics. Callers hold r.mu.
+func (r *dbRecorder) snapshot(now time.Time) error {
+	r.lastSnapshot = now
+	r.stats.CalculateRates()
+	return r.store.WriteStats(now, r.stats)
+}
+
+// Close stores a final statistics snapshot and closes the database. Later
+// calls do nothing.
+func (r *dbRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.snapshot(time.Now())
+	if closeErr := r.store.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Packets returns the number of packets stored so far
+func (r *dbRecorder) Packets() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.packets
+}
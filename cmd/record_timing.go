@@ -0,0 +1,341 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+// A timing file (record --timing) holds every read of the connection as a
+// batch record entry: the entry's time is the read's return time and its
+// bytes are the bytes read, noise between frames included. An empty entry
+// marks a reconnect.
+
+// readTimingWriter writes reads to a timing file. It is safe for
+// concurrent use, so the flush loop and the shutdown hook can use it.
+type readTimingWriter struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	batch  *fusain.BatchWriter
+	reads  uint64
+	closed bool
+}
+
+func newReadTimingWriter(path string) (*readTimingWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create timing file: %v", err)
+	}
+	return &readTimingWriter{path: path, file: file, batch: fusain.NewBatchWriter(file, 0)}, nil
+}
+
+// WriteRead records the bytes of one read, or a reconnect for no bytes
+func (w *readTimingWriter) WriteRead(at time.Time, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.reads++
+	return w.batch.WriteFrame(at, data)
+}
+
+// Flush writes the buffered reads
+func (w *readTimingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	return w.batch.Flush()
+}
+
+// Close flushes and closes the file. Later calls do nothing.
+func (w *readTimingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.batch.Flush()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Progress describes the timing file for the status line
+func (w *readTimingWriter) Progress() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return fmt.Sprintf("%d reads in %s", w.reads, w.path)
+}
+
+var (
+	timingGap    time.Duration
+	timingFormat string
+)
+
+var reportTimingCmd = &cobra.Command{
+	Use:   "timing FILE",
+	Short: "Report read gaps and byte pacing from a record --timing file",
+	Long: `Reconstruct the byte pacing of a connection from a timing file written by
+'heliostat record --timing', to tell framing errors caused by timing (a
+stalled USB adapter, a busy device) from corrupted data.
+
+The report shows the reads and bytes per second, a histogram of the gaps
+between reads, and every frame that failed CRC or decoding or had a gap of
+more than --gap between two of its reads. A bad frame with a long gap in
+the middle points at timing; a bad frame received in one burst points at
+the data.
+
+Examples:
+  heliostat record -p /dev/ttyUSB0 -o session.cap --timing session.timing
+  heliostat report timing session.timing
+  heliostat report timing session.timing --gap 5ms --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportTiming,
+}
+
+func init() {
+	reportCmd.AddCommand(reportTimingCmd)
+	reportTimingCmd.Flags().DurationVar(&timingGap, "gap", 20*time.Millisecond, "List frames with a gap longer than this between two of their reads")
+	reportTimingCmd.Flags().StringVar(&timingFormat, "format", "text", "Report format: text or json")
+}
+
+// timingGapBuckets are the upper bounds of the gap histogram rows; the last
+// row counts the longer gaps
+var timingGapBuckets = []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond, time.Second}
+
+// timingBucket is one gap histogram row
+type timingBucket struct {
+	Below string `json:"below,omitempty"` // Upper bound; empty for the last row
+	Reads uint64 `json:"reads"`
+}
+
+// timingFrame is a frame listed in the timing report
+type timingFrame struct {
+	Start  time.Time     `json:"start"`
+	Bytes  int           `json:"bytes"`
+	Reads  int           `json:"reads"`
+	MaxGap time.Duration `json:"max_gap_ns"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// timingReport is the result of report timing
+type timingReport struct {
+	File         string         `json:"file"`
+	First        time.Time      `json:"first"`
+	Last         time.Time      `json:"last"`
+	Reads        uint64         `json:"reads"`
+	Bytes        uint64         `json:"bytes"`
+	Reconnects   int            `json:"reconnects"`
+	MaxGap       time.Duration  `json:"max_gap_ns"`
+	Gaps         []timingBucket `json:"gaps"`
+	Frames       int            `json:"frames"`
+	DecodeErrors int            `json:"decode_errors"`
+	SlowErrors   int            `json:"decode_errors_with_gap"` // Decode errors with a gap over the threshold
+	Listed       []timingFrame  `json:"listed_frames"`
+	Threshold    string         `json:"gap_threshold"`
+}
+
+// timingAnalyzer builds a timingReport from reads
+type timingAnalyzer struct {
+	gap    time.Duration
+	report timingReport
+
+	prev    time.Time // Previous read, zero after a reconnect
+	frame   []byte
+	inFrame bool
+	current timingFrame // Frame being received
+	last    time.Time   // Last read of the current frame
+}
+
+func newTimingAnalyzer(gap time.Duration) *timingAnalyzer {
+	a := &timingAnalyzer{gap: gap}
+	a.report.Threshold = gap.String()
+	for _, b := range timingGapBuckets {
+		a.report.Gaps = append(a.report.Gaps, timingBucket{Below: b.String()})
+	}
+	a.report.Gaps = append(a.report.Gaps, timingBucket{})
+	return a
+}
+
+// read adds one read; no bytes marks a reconnect
+func (a *timingAnalyzer) read(at time.Time, data []byte) {
+	r := &a.report
+	if len(data) == 0 {
+		r.Reconnects++
+		a.prev = time.Time{}
+		a.inFrame = false
+		return
+	}
+
+	if r.First.IsZero() {
+		r.First = at
+	}
+	r.Last = at
+	r.Reads++
+	r.Bytes += uint64(len(data))
+	if !a.prev.IsZero() {
+		gap := at.Sub(a.prev)
+		r.MaxGap = max(r.MaxGap, gap)
+		i := 0
+		for i < len(timingGapBuckets) && gap >= timingGapBuckets[i] {
+			i++
+		}
+		r.Gaps[i].Reads++
+	}
+	a.prev = at
+
+	// A frame continuing from an earlier read
+	if a.inFrame {
+		a.current.Reads++
+		a.current.MaxGap = max(a.current.MaxGap, at.Sub(a.last))
+		a.last = at
+	}
+
+	for _, b := range data {
+		switch {
+		case b == fusain.StartByte:
+			a.frame = append(a.frame[:0], b)
+			a.inFrame = true
+			a.current = timingFrame{Start: at, Reads: 1}
+			a.last = at
+		case !a.inFrame:
+			// Noise between frames
+		case b == fusain.EndByte:
+			a.frame = append(a.frame, b)
+			a.inFrame = false
+			a.finish()
+		case len(a.frame) >= fusain.MaxPacketSize*2:
+			a.inFrame = false
+		default:
+			a.frame = append(a.frame, b)
+		}
+	}
+}
+
+// finish checks a completed frame and lists it if it failed or stalled
+func (a *timingAnalyzer) finish() {
+	r := &a.report
+	f := a.current
+	f.Bytes = len(a.frame)
+	r.Frames++
+
+	slow := f.MaxGap > a.gap
+	if _, err := fusain.DecodePacket(a.frame); err != nil {
+		f.Error = err.Error()
+		r.DecodeErrors++
+		if slow {
+			r.SlowErrors++
+		}
+	}
+	if slow || f.Error != "" {
+		r.Listed = append(r.Listed, f)
+	}
+}
+
+// readTimingFile analyzes a timing file
+func readTimingFile(path string, gap time.Duration) (*timingReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open timing file: %v", err)
+	}
+	defer file.Close()
+
+	a := newTimingAnalyzer(gap)
+	reader := fusain.NewBatchReader(file)
+	for {
+		batch, err := reader.ReadBatch()
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			fmt.Fprintf(os.Stderr, "Warning: %s ends with a truncated record\n", path)
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %v", path, err)
+		}
+		for _, read := range batch {
+			a.read(read.Timestamp, read.Frame)
+		}
+	}
+	a.report.File = path
+	return &a.report, nil
+}
+
+// writeText prints the report as tables
+func (r *timingReport) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "Timing report for %s\n", r.File)
+	if r.Reads == 0 {
+		fmt.Fprintf(w, "No reads\n")
+		return nil
+	}
+	span := r.Last.Sub(r.First)
+	fmt.Fprintf(w, "%s to %s (%s)\n", r.First.Format(time.DateTime), r.Last.Format(time.DateTime), span.Round(time.Millisecond))
+	fmt.Fprintf(w, "%d reads, %d bytes, %.1f bytes per read", r.Reads, r.Bytes, float64(r.Bytes)/float64(r.Reads))
+	if span > 0 {
+		fmt.Fprintf(w, ", %.0f bytes/s", float64(r.Bytes)/span.Seconds())
+	}
+	fmt.Fprintf(w, "\n%d reconnects, longest gap %s\n", r.Reconnects, r.MaxGap)
+	fmt.Fprintf(w, "%d frames, %d decode errors (%d with a gap over %s)\n", r.Frames, r.DecodeErrors, r.SlowErrors, r.Threshold)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\nGaps between reads:\n")
+	for i, b := range r.Gaps {
+		label := "< " + b.Below
+		if b.Below == "" {
+			label = ">= " + r.Gaps[i-1].Below
+		}
+		fmt.Fprintf(tw, "  %s\t%d\n", label, b.Reads)
+	}
+
+	if len(r.Listed) > 0 {
+		fmt.Fprintf(tw, "\nFrames with errors or gaps over %s:\n", r.Threshold)
+		fmt.Fprintf(tw, "  TIME\tBYTES\tREADS\tMAX GAP\tERROR\n")
+		for _, f := range r.Listed {
+			reason := f.Error
+			if reason == "" {
+				reason = "-"
+			}
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\t%s\n", f.Start.Format("15:04:05.000000"), f.Bytes, f.Reads, f.MaxGap, reason)
+		}
+	}
+	return tw.Flush()
+}
+
+func runReportTiming(cmd *cobra.Command, args []string) error {
+	switch timingFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid --format %q: must be text or json", timingFormat)
+	}
+	if timingGap <= 0 {
+		return fmt.Errorf("--gap must be positive")
+	}
+
+	report, err := readTimingFile(args[0], timingGap)
+	if err != nil {
+		return err
+	}
+	if timingFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.writeText(os.Stdout)
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func TestReadTiming(t *testing.T) {
+	good := wireFrame(t, stateData(1, fusain.SysStateIdle))
+	bad := append([]byte(nil), good...)
+	bad[len(bad)-2] ^= 0xFF // Corrupt the CRC
+
+	start := time.Unix(1000, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	half := len(good) / 2
+
+	path := filepath.Join(t.TempDir(), "session.timing")
+	w, err := newReadTimingWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads := []struct {
+		ms   int
+		data []byte
+	}{
+		{0, good},         // Whole frame in one read
+		{10, good[:half]}, // Split with a 50ms stall
+		{60, good[half:]},
+		{70, bad}, // Corrupt in one burst
+		{80, append([]byte{0x00}, bad[:half]...)}, // Corrupt after a 120ms stall
+		{200, bad[half:]},
+		{300, nil}, // Reconnect
+		{2000, good},
+	}
+	for _, r := range reads {
+		if err := w.WriteRead(at(r.ms), r.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := readTimingFile(path, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Reads != 7 || report.Reconnects != 1 || report.Frames != 5 {
+		t.Errorf("got %d reads, %d reconnects, %d frames; want 7, 1, 5", report.Reads, report.Reconnects, report.Frames)
+	}
+	if report.DecodeErrors != 2 || report.SlowErrors != 1 {
+		t.Errorf("got %d decode errors, %d with a gap; want 2, 1", report.DecodeErrors, report.SlowErrors)
+	}
+	// The gap across the reconnect isn't counted
+	if report.MaxGap != 120*time.Millisecond {
+		t.Errorf("longest gap %s, want 120ms", report.MaxGap)
+	}
+	if len(report.Listed) != 3 {
+		t.Fatalf("listed %d frames, want 3", len(report.Listed))
+	}
+	if f := report.Listed[0]; f.Error != "" || f.Reads != 2 || f.MaxGap != 50*time.Millisecond {
+		t.Errorf("stalled frame: %+v", f)
+	}
+	if f := report.Listed[1]; f.Error == "" || f.Reads != 1 || f.MaxGap != 0 {
+		t.Errorf("corrupt frame: %+v", f)
+	}
+	if f := report.Listed[2]; f.Error == "" || f.MaxGap != 120*time.Millisecond {
+		t.Errorf("corrupt stalled frame: %+v", f)
+	}
+}
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/rules"
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+)
+
+// captureTrigger is one --trigger condition
+type captureTrigger struct {
+	spec string
+
+	estop   bool
+	msgType int // Message type for type:NAME, -1 otherwise
+
+	// decode-errors:N/WINDOW
+	burst       int
+	burstWindow time.Duration
+	errorTimes  []time.Time // Decode errors within the window
+
+	// Telemetry condition, as in a rules file
+	rule *rules.Engine
+}
+
+// parseCaptureTrigger parses a --trigger value:
+//
+//	estop                      an emergency stop (E_STOP state or command)
+//	type:NAME                  a packet of this message type
+//	decode-errors:N/DURATION   N frames failing CRC or decoding within DURATION
+//	CONDITION                  a telemetry condition, as in --rules
+func parseCaptureTrigger(spec string) (*captureTrigger, error) {
+	t := &captureTrigger{spec: spec, msgType: -1}
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "estop":
+		t.estop = true
+	case "type":
+		msgType, err := parseMessageTypeFlag(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --trigger %q: %v", spec, err)
+		}
+		t.msgType = int(msgType)
+	case "decode-errors":
+		count, window, ok := strings.Cut(arg, "/")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid --trigger %q: expected decode-errors:N/DURATION", spec)
+		}
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid --trigger %q: expected decode-errors:N/DURATION", spec)
+		}
+		t.burst, t.burstWindow = n, d
+	default:
+		rule, err := rules.Compile(spec, spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --trigger %q (valid: estop, type:NAME, decode-errors:N/DURATION or a --rules condition): %v", spec, err)
+		}
+		t.rule = rules.NewEngine([]rules.Rule{rule})
+	}
+	return t, nil
+}
+
+// fires feeds the trigger one recorded frame, decoded into p or failing
+// with decodeErr, and reports whether the trigger condition is met
+func (t *captureTrigger) fires(at time.Time, p *fusain.Packet, decodeErr error) bool {
+	if decodeErr != nil {
+		if t.burst == 0 {
+			return false
+		}
+		t.errorTimes = append(t.errorTimes, at)
+		for len(t.errorTimes) > 0 && at.Sub(t.errorTimes[0]) > t.burstWindow {
+			t.errorTimes = t.errorTimes[1:]
+		}
+		if len(t.errorTimes) >= t.burst {
+			t.errorTimes = nil
+			return true
+		}
+		return false
+	}
+
+	switch {
+	case t.estop:
+		return p.IsEmergency()
+	case t.msgType >= 0:
+		return int(p.Type()) == t.msgType
+	case t.rule != nil:
+		sample, ok := sinks.TelemetryFromPacket(p)
+		if !ok {
+			return false
+		}
+		for _, a := range t.rule.Evaluate(sample) {
+			if a.Active {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// triggeredCapture records only the frames around trigger events. It keeps
+// the last pre-trigger interval of frames in memory; when a trigger fires
+// it starts a new numbered capture file with them and records until the
+// post-trigger interval has passed without another trigger. It is safe for
+// concurrent use, so the shutdown hook can close it.
+type triggeredCapture struct {
+	mu sync.Mutex
+
+	path      string
+	pre, post time.Duration
+	triggers  []*captureTrigger
+	onCapture func(file, trigger string) // Called when a capture starts
+	ring      []fusain.BatchFrame        // Frames of the last pre interval
+	active    *captureWriter             // nil while waiting for a trigger
+	until     time.Time                  // End of the active capture
+	index     int
+	closed    bool
+}
+
+func newTriggeredCapture(path string, pre, post time.Duration, triggers []*captureTrigger) *triggeredCapture {
+	return &triggeredCapture{path: path, pre: pre, post: post, triggers: triggers}
+}
+
+// WriteFrame feeds a frame to the triggers and records it if a capture is
+// running or starts with it
+func (c *triggeredCapture) WriteFrame(at time.Time, frame []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return os.ErrClosed
+	}
+
+	p, decodeErr := fusain.BatchFrame{Timestamp: at, Frame: frame}.Packet()
+	fired := ""
+	for _, t := range c.triggers {
+		// Every trigger sees every frame, to keep its state current
+		if t.fires(at, p, decodeErr) && fired == "" {
+			fired = t.spec
+		}
+	}
+
+	if c.active != nil && fired == "" && at.After(c.until) {
+		if err := c.finish(); err != nil {
+			return err
+		}
+	}
+
+	if c.active != nil {
+		if fired != "" {
+			c.until = at.Add(c.post)
+		}
+		return c.active.WriteFrame(at, frame)
+	}
+
+	c.ring = append(c.ring, fusain.BatchFrame{Timestamp: at, Frame: frame})
+	drop := 0
+	for drop < len(c.ring) && at.Sub(c.ring[drop].Timestamp) > c.pre {
+		drop++
+	}
+	c.ring = c.ring[drop:]
+
+	if fired == "" {
+		return nil
+	}
+	return c.start(at, fired)
+}
+
+// start opens the next capture file and writes the pre-trigger frames
+func (c *triggeredCapture) start(at time.Time, trigger string) error {
+	c.index++
+	file := numberedPath(c.path, c.index)
+	w, err := newCaptureWriter(file, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, f := range c.ring {
+		if err := w.WriteFrame(f.Timestamp, f.Frame); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	c.ring = nil
+	c.active = w
+	c.until = at.Add(c.post)
+	if c.onCapture != nil {
+		c.onCapture(file, trigger)
+	}
+	return nil
+}
+
+// finish closes the running capture
+func (c *triggeredCapture) finish() error {
+	err := c.active.Close()
+	c.active = nil
+	return err
+}
+
+// Flush writes buffered frames of the running capture
+func (c *triggeredCapture) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active == nil {
+		return nil
+	}
+	return c.active.Flush()
+}
+
+// Close ends the running capture, if any. Later calls do nothing.
+func (c *triggeredCapture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.active == nil {
+		return nil
+	}
+	return c.finish()
+}
+
+// Progress describes the captures for the status line
+func (c *triggeredCapture) Progress() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Sprintf("%d captures", c.index)
+	}
+	if c.active == nil {
+		return fmt.Sprintf("%d captures, waiting for trigger", c.index)
+	}
+	frames, file, _ := c.active.Status()
+	return fmt.Sprintf("%d captures, recording %s (%d frames)", c.index, file, frames)
+}
+
+// Describe names the files written
+func (c *triggeredCapture) Describe() string {
+	return fmt.Sprintf("%s on trigger (%s before, %s after)", numberedPath(c.path, 1), c.pre, c.post)
+}
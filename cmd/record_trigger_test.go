@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func wireFrame(t *testing.T, p *fusain.Packet) []byte {
+	t.Helper()
+	frame, err := fusain.EncodePacket(p.Address(), p.Type(), p.PayloadMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+// readCaptureTimes returns the receive times of every frame in a capture
+func readCaptureTimes(t *testing.T, path string) []time.Time {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var times []time.Time
+	r := fusain.NewBatchReader(f)
+	for {
+		batch, err := r.ReadBatch()
+		if err == io.EOF {
+			return times
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, frame := range batch {
+			times = append(times, frame.Timestamp)
+		}
+	}
+}
+
+func TestTriggeredCapture(t *testing.T) {
+	trigger, err := parseCaptureTrigger("estop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fault.cap")
+	c := newTriggeredCapture(path, 2*time.Second, 3*time.Second, []*captureTrigger{trigger})
+
+	idle := wireFrame(t, stateData(1, fusain.SysStateIdle))
+	estop := wireFrame(t, stateData(1, fusain.SysStateEstop))
+	start := time.Unix(1000, 0)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+
+	// One frame a second; E-stops at 5s and 20s
+	for s := 0; s <= 30; s++ {
+		frame := idle
+		if s == 5 || s == 20 {
+			frame = estop
+		}
+		if err := c.WriteFrame(at(s), frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2s before each trigger, 3s after it
+	for i, want := range [][2]int{{3, 8}, {18, 23}} {
+		times := readCaptureTimes(t, numberedPath(path, i+1))
+		if len(times) != want[1]-want[0]+1 || !times[0].Equal(at(want[0])) || !times[len(times)-1].Equal(at(want[1])) {
+			t.Errorf("capture %d: %d frames from %v to %v, want %ds to %ds", i+1, len(times),
+				times[0].Sub(start), times[len(times)-1].Sub(start), want[0], want[1])
+		}
+	}
+	if _, err := os.Stat(numberedPath(path, 3)); err == nil {
+		t.Error("unexpected third capture")
+	}
+}
+
+func TestCaptureTriggerDecodeErrors(t *testing.T) {
+	trigger, err := parseCaptureTrigger("decode-errors:3/1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	crc := func(ms int) bool {
+		return trigger.fires(start.Add(time.Duration(ms)*time.Millisecond), nil, errors.New("CRC mismatch"))
+	}
+
+	// Spread out: never three within a second
+	if crc(0) || crc(600) || crc(1200) || crc(1800) {
+		t.Error("fired for errors spread over more than the window")
+	}
+	if !crc(1900) {
+		t.Error("didn't fire for three errors within the window")
+	}
+}
+
+func TestParseCaptureTrigger(t *testing.T) {
+	valid := []string{"estop", "type:ERROR_INVALID_CMD", "decode-errors:5/1s", "temp0 > 220", "state.error_code != 0 for 2s"}
+	for _, spec := range valid {
+		if _, err := parseCaptureTrigger(spec); err != nil {
+			t.Errorf("%q: %v", spec, err)
+		}
+	}
+	invalid := []string{"type:BOGUS", "decode-errors:5", "decode-errors:0/1s", "decode-errors:5/soon", "temp0 is hot"}
+	for _, spec := range invalid {
+		if _, err := parseCaptureTrigger(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
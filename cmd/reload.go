@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// reloadModes lists the long-running commands in which SIGHUP reloads the
+// configuration instead of stopping the program. Interactive commands keep
+// treating it as a hangup.
+var reloadModes = map[string]bool{
+	"watchdog": true,
+	"serve":    true,
+	"mqtt":     true,
+	"influx":   true,
+	"record":   true,
+}
+
+// reloadOnHangup is set when the running command is in reloadModes
+var reloadOnHangup atomic.Bool
+
+// reloadConfig re-reads the config file (thresholds, interlocks, error
+// hints, serve users), --limits, the aliases file and --rules, and reopens
+// the --jsonl file. Nothing is applied unless every file loads, and the
+// connection is left alone.
+func reloadConfig() error {
+	cfg, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	names, err := loadAliases(aliasesFile)
+	if err != nil {
+		return err
+	}
+	engine, err := loadRules()
+	if err != nil {
+		return err
+	}
+
+	loadedConfig.Store(cfg)
+	aliasesMu.Lock()
+	aliases = names
+	aliasesMu.Unlock()
+	alertRules.Store(engine)
+
+	if jsonlSink != nil {
+		if err := jsonlSink.Reopen(); err != nil {
+			return fmt.Errorf("cannot reopen --jsonl file: %w", err)
+		}
+	}
+	return nil
+}
+
+// reloadOnSignal runs reloadConfig for SIGHUP and reports the outcome
+func reloadOnSignal() {
+	if err := reloadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Reload failed, keeping the previous configuration: %v\n", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Configuration reloaded")
+}
@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replaySpeed  float64
+	replayFollow bool
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay FILE...",
+	Short: "Play back capture files from 'heliostat record'",
+	Long: `Play back capture files written by 'heliostat record', in order.
+
+Without --port, --url or --tcp, the frames are analyzed offline: they go
+through the decoder and validators exactly as in error_detection, with the
+same TUI, text (--tui=false) or --simple output and statistics.
+
+With --port, --url or --tcp, the frames are transmitted on that connection,
+to reproduce a session against a router or device. Commands in the capture
+are held to the --allow-device/--deny-device filter and command interlocks
+like any other command: those that fail are skipped and reported.
+
+Frames are played at their recorded timing, scaled by --speed (2 plays
+twice as fast; 0 plays as fast as possible). Packet rates, gaps and stale
+device detection follow the playback timing, so use --speed 1 when they
+matter.
+
+--follow keeps reading the last file as 'heliostat record' appends to it,
+like tail -f, and moves on to the next numbered file when a rotating
+recording starts one (session-0002.cap after session-0001.cap). This
+inspects an unattended recording without interrupting it. The frames
+already recorded play as fast as possible unless --speed is given. Ctrl+C
+stops.
+
+Examples:
+  heliostat replay session.cap
+  heliostat replay --speed 0 --tui=false --show-all bench-0001.cap bench-0002.cap
+  heliostat replay --speed 1 -p /dev/ttyUSB1 session.cap
+  heliostat replay --follow --tui=false soak-0003.cap`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "Playback speed relative to the recording (0 = as fast as possible)")
+	replayCmd.Flags().BoolVarP(&replayFollow, "follow", "f", false, "Keep reading the last file as it is recorded (and the files it rotates to)")
+	replayCmd.Flags().BoolVar(&useTUI, "tui", true, "Use terminal UI for offline analysis (false for text mode)")
+	replayCmd.Flags().BoolVar(&simpleMode, "simple", false, "Plain one-line summary refreshed with carriage returns (overrides --tui)")
+	replayCmd.Flags().DurationVar(&simpleRefresh, "refresh", time.Second, "Summary refresh interval for --simple")
+	replayCmd.Flags().BoolVar(&showAll, "show-all", false, "Show all packets (not just errors)")
+	replayCmd.Flags().StringSliceVar(&displayRateLimits, "rate-limit", nil, rateLimitUsage)
+	replayCmd.Flags().IntVar(&statsInterval, "stats-interval", 10, "Statistics update interval (seconds)")
+	replayCmd.Flags().BoolVar(&skipSchema, "skip-schema", false, "Skip protocol schema checks (required keys, CBOR types, enum ranges)")
+	replayCmd.Flags().DurationVar(&staleTimeout, "stale-timeout", fusain.DefaultStaleTimeout, "Report devices silent for longer than this (0 disables)")
+}
+
+// captureReader reads frames from capture files, paced by their recorded
+// timing. It implements Connection so a replay can stand in for a live
+// connection: writes are discarded and the end of the last file reads as
+// ErrConnectionClosed.
+//
+// When following, the end of the last file isn't the end: the reader waits
+// for the recorder to append to it, or to rotate to the next file.
+type captureReader struct {
+	paths  []string
+	speed  float64
+	follow bool
+
+	file    *os.File
+	batches *fusain.BatchReader
+	base    int64 // File offset the batch reader started at
+	frames  []fusain.BatchFrame
+	pending []byte // Unread bytes of the current frame
+
+	first   time.Time // Recorded time of the first frame
+	started time.Time // Wall time the first frame was played
+	played  uint64
+
+	closed chan struct{}
+}
+
+func newCaptureReader(paths []string, speed float64, follow bool) *captureReader {
+	return &captureReader{paths: paths, speed: speed, follow: follow, closed: make(chan struct{})}
+}
+
+// nextCapturePath returns the file a rotating recording starts after path
+// (session-0002.cap after session-0001.cap), or "" if path isn't numbered
+func nextCapturePath(path string) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	i := strings.LastIndex(stem, "-")
+	if i < 0 || len(stem)-i-1 != 4 {
+		return ""
+	}
+	index, err := strconv.Atoi(stem[i+1:])
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s-%04d%s", stem[:i], index+1, ext)
+}
+
+// nextFrame returns the next recorded frame, opening files as needed
+func (r *captureReader) nextFrame() (fusain.BatchFrame, error) {
+	for len(r.frames) == 0 {
+		if r.batches == nil {
+			if len(r.paths) == 0 {
+				return fusain.BatchFrame{}, io.EOF
+			}
+			file, err := os.Open(r.paths[0])
+			if err != nil {
+				return fusain.BatchFrame{}, err
+			}
+			r.file = file
+			r.batches = fusain.NewBatchReader(file)
+			r.base = 0
+		}
+
+		frames, err := r.batches.ReadBatch()
+		if r.follow && len(r.paths) == 1 && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			if err := r.waitForMore(); err != nil {
+				return fusain.BatchFrame{}, err
+			}
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			r.file.Close()
+			r.file, r.batches = nil, nil
+			r.paths = r.paths[1:]
+			continue
+		}
+		if err != nil {
+			return fusain.BatchFrame{}, fmt.Errorf("%s: %v", r.paths[0], err)
+		}
+		r.frames = frames
+	}
+
+	frame := r.frames[0]
+	r.frames = r.frames[1:]
+	return frame, nil
+}
+
+// waitForMore handles the end of the followed file: it moves on to the next
+// file once the recorder has rotated, or else waits and rereads the file
+// from the end of its last complete record. It returns io.EOF if the reader
+// was closed while waiting.
+func (r *captureReader) waitForMore() error {
+	if next := nextCapturePath(r.paths[0]); next != "" {
+		if _, err := os.Stat(next); err == nil {
+			// The recorder closes a file before starting the next, so
+			// this one is complete
+			r.file.Close()
+			r.file, r.batches = nil, nil
+			r.paths = []string{next}
+			return nil
+		}
+	}
+
+	if !r.sleep(followPollInterval) {
+		return io.EOF
+	}
+	offset := r.base + r.batches.Offset()
+	if _, err := r.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.base = offset
+	r.batches = fusain.NewBatchReader(r.file)
+	return nil
+}
+
+// sleep waits for d. It returns false if the reader was closed while
+// waiting.
+func (r *captureReader) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.closed:
+		return false
+	}
+}
+
+// wait sleeps until the frame is due. It returns false if the reader was
+// closed while waiting.
+func (r *captureReader) wait(at time.Time) bool {
+	if r.played == 0 {
+		r.first, r.started = at, time.Now()
+	}
+	r.played++
+	if r.speed <= 0 {
+		return true
+	}
+
+	due := r.started.Add(time.Duration(float64(at.Sub(r.first)) / r.speed))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return true
+	}
+	return r.sleep(delay)
+}
+
+// Read returns the bytes of the next frame once it is due
+func (r *captureReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		frame, err := r.nextFrame()
+		if errors.Is(err, io.EOF) {
+			return 0, ErrConnectionClosed
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !r.wait(frame.Timestamp) {
+			return 0, ErrConnectionClosed
+		}
+		r.pending = frame.Frame
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Write discards p; there is no device to send to
+func (r *captureReader) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close stops playback
+func (r *captureReader) Close() error {
+	select {
+	case <-r.closed:
+	default:
+		close(r.closed)
+	}
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	if replaySpeed < 0 {
+		return fmt.Errorf("--speed must not be negative")
+	}
+	if simpleMode && simpleRefresh <= 0 {
+		return fmt.Errorf("--refresh must be positive")
+	}
+	for _, path := range args {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("cannot read capture: %v", err)
+		}
+	}
+
+	// Catch up with a followed recording as fast as possible by default
+	speed := replaySpeed
+	if replayFollow && !cmd.Flags().Changed("speed") {
+		speed = 0
+	}
+	capture := newCaptureReader(args, speed, replayFollow)
+	defer capture.Close()
+
+	if portName != "" || wsURL != "" || tcpAddr != "" {
+		return replayToConnection(capture)
+	}
+
+	connInfo := "Replay: " + strings.Join(args, ", ")
+	switch {
+	case simpleMode:
+		return runSimpleMode(capture, connInfo)
+	case useTUI:
+		return runTUIMode(capture, connInfo)
+	}
+	return runTextMode(capture, connInfo)
+}
+
+// replayToConnection transmits the captured frames on the connection
+// selected by the global flags
+func replayToConnection(capture *captureReader) error {
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Printf("Replaying to %s\n", connInfo)
+	start := time.Now()
+	skipped := 0
+
+	for {
+		frame, err := capture.nextFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !capture.wait(frame.Timestamp) {
+			break
+		}
+		// Frames that don't decode can't be acted on; they are sent as
+		// recorded, to reproduce the corruption
+		if p, err := fusain.DecodePacket(frame.Frame); err == nil && isCommand(p) {
+			if err := checkCommandPolicy(p); err != nil {
+				skipped++
+				fmt.Printf("[+%9.3fs] Skipped: %v\n", time.Since(start).Seconds(), err)
+				continue
+			}
+		}
+		if _, err := conn.Write(frame.Frame); err != nil {
+			return exitErrorf(ExitConnection, "write failed: %v", err)
+		}
+	}
+
+	if flusher, ok := conn.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return exitErrorf(ExitConnection, "write failed: %v", err)
+		}
+	}
+	fmt.Printf("Replayed %d frames in %v\n", capture.played-uint64(skipped), time.Since(start).Round(time.Millisecond))
+	if skipped > 0 {
+		fmt.Printf("%d commands skipped by the address filter or interlocks\n", skipped)
+		return exitSilently(ExitFailure)
+	}
+	return nil
+}
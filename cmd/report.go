@@ -0,0 +1,399 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+// maxReportBuckets limits the time buckets of a report
+const maxReportBuckets = 10000
+
+var (
+	reportFrom    string
+	reportTo      string
+	reportDevices []string
+	reportSession int64
+	reportBucket  time.Duration
+	reportFormat  string
+	reportOutput  string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate reports from recorded sessions",
+	Long: `Summarize session databases written by 'heliostat record --db' and timing
+files written by 'heliostat record --timing'.
+
+Subcommands:
+  anomalies   Validation errors grouped by device, anomaly type and time
+  timing      Gaps between reads and frames that failed or stalled`,
+}
+
+var reportAnomaliesCmd = &cobra.Command{
+	Use:   "anomalies DATABASE",
+	Short: "Report recorded anomalies by device, type and time bucket",
+	Long: `Report the validation errors in a session database, grouped by device,
+by anomaly type and by time bucket, e.g. after a soak test.
+
+DATABASE is an SQLite file path or a store URL, as given to record --db.
+--from and --to take the same formats as 'heliostat query' (RFC 3339, local
+"2006-01-02 15:04:05", or a duration ago). --bucket sets the length of the
+time buckets; buckets without anomalies are listed too, so quiet periods
+show.
+
+Anomaly types are the validator's names (invalid_value, session_anomaly,
+...), followed by the check name for registered checks.
+
+--format text prints tables, html writes a standalone page with bar charts,
+and json the report as one object.
+
+Examples:
+  heliostat report anomalies soak.db --from 24h
+  heliostat report anomalies soak.db --session 3 --bucket 10m --format html -o soak.html
+  heliostat report anomalies soak.db --device 0123456789ABCDEF --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportAnomalies,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportAnomaliesCmd)
+	reportAnomaliesCmd.Flags().StringVar(&reportFrom, "from", "", "Earliest anomaly time (RFC 3339, local \"2006-01-02 15:04:05\", or a duration ago)")
+	reportAnomaliesCmd.Flags().StringVar(&reportTo, "to", "", "Latest anomaly time, exclusive (same formats as --from)")
+	reportAnomaliesCmd.Flags().StringSliceVar(&reportDevices, "device", nil, "Only these device addresses (hex or alias, repeatable)")
+	reportAnomaliesCmd.Flags().Int64Var(&reportSession, "session", 0, "Only this recording session")
+	reportAnomaliesCmd.Flags().DurationVar(&reportBucket, "bucket", time.Hour, "Length of the time buckets")
+	reportAnomaliesCmd.Flags().StringVar(&reportFormat, "format", "text", "Report format: text, html or json")
+	reportAnomaliesCmd.Flags().StringVarP(&reportOutput, "output", "o", "-", "Output file, or - for stdout")
+}
+
+// reportCount is the number of anomalies of one kind
+type reportCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// reportDevice is the anomalies of one device
+type reportDevice struct {
+	Address string        `json:"address"`
+	Total   int           `json:"total"`
+	First   time.Time     `json:"first"`
+	Last    time.Time     `json:"last"`
+	Types   []reportCount `json:"types"`
+}
+
+// reportBucketRow is the anomalies of one time bucket
+type reportBucketRow struct {
+	Start   time.Time     `json:"start"`
+	Total   int           `json:"total"`
+	Devices []reportCount `json:"devices"`
+	Types   []reportCount `json:"types"`
+}
+
+// anomalyReport is the grouped result of report anomalies
+type anomalyReport struct {
+	Database     string            `json:"database"`
+	From         time.Time         `json:"from"`
+	To           time.Time         `json:"to"`
+	Bucket       string            `json:"bucket"`
+	Total        int               `json:"total"`
+	DecodeErrors int               `json:"decode_errors"`
+	Devices      []reportDevice    `json:"devices"`
+	Types        []reportCount     `json:"types"`
+	Buckets      []reportBucketRow `json:"buckets"`
+
+	bucket time.Duration
+}
+
+// BucketStart formats the start of a time bucket, with seconds for buckets
+// shorter than a minute
+func (r *anomalyReport) BucketStart(t time.Time) string {
+	if r.bucket < time.Minute {
+		return t.Format(time.DateTime)
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// anomalyTypeName names an anomaly for grouping: its type, with the check
+// name for registered checks
+func anomalyTypeName(r store.AnomalyRecord) string {
+	if r.Check != "" {
+		return r.Anomaly + "/" + r.Check
+	}
+	return r.Anomaly
+}
+
+// sortedCounts returns counts largest first, then by name
+func sortedCounts(counts map[string]int) []reportCount {
+	list := make([]reportCount, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, reportCount{name, count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// buildAnomalyReport groups anomalies (in time order) by device, type and
+// bucket. from and to bound the buckets when set; otherwise the first and
+// last anomaly do.
+func buildAnomalyReport(records []store.AnomalyRecord, from, to time.Time, bucket time.Duration) (*anomalyReport, error) {
+	report := &anomalyReport{From: from, To: to, Bucket: bucket.String(), Total: len(records), bucket: bucket}
+	if len(records) > 0 {
+		if report.From.IsZero() {
+			report.From = records[0].Time
+		}
+		if report.To.IsZero() {
+			report.To = records[len(records)-1].Time
+		}
+	}
+
+	devices := make(map[uint64]*reportDevice)
+	deviceTypes := make(map[uint64]map[string]int)
+	types := make(map[string]int)
+	for _, r := range records {
+		name := anomalyTypeName(r)
+		types[name]++
+		dev, ok := devices[r.Address]
+		if !ok {
+			dev = &reportDevice{Address: fmt.Sprintf("%016X", r.Address), First: r.Time}
+			devices[r.Address] = dev
+			deviceTypes[r.Address] = make(map[string]int)
+		}
+		dev.Total++
+		dev.Last = r.Time
+		deviceTypes[r.Address][name]++
+	}
+	for address, dev := range devices {
+		dev.Types = sortedCounts(deviceTypes[address])
+		report.Devices = append(report.Devices, *dev)
+	}
+	sort.Slice(report.Devices, func(i, j int) bool {
+		return report.Devices[i].Address < report.Devices[j].Address
+	})
+	report.Types = sortedCounts(types)
+
+	if len(records) == 0 || report.From.IsZero() {
+		return report, nil
+	}
+	start := report.From.Truncate(bucket)
+	count := int(report.To.Sub(start)/bucket) + 1
+	if count > maxReportBuckets {
+		return nil, fmt.Errorf("%d time buckets of %v; use a longer --bucket", count, bucket)
+	}
+	bucketDevices := make([]map[string]int, count)
+	bucketTypes := make([]map[string]int, count)
+	report.Buckets = make([]reportBucketRow, count)
+	for i := range report.Buckets {
+		report.Buckets[i].Start = start.Add(time.Duration(i) * bucket)
+		bucketDevices[i] = make(map[string]int)
+		bucketTypes[i] = make(map[string]int)
+	}
+	for _, r := range records {
+		i := int(r.Time.Sub(start) / bucket)
+		if i < 0 || i >= count {
+			continue
+		}
+		report.Buckets[i].Total++
+		bucketDevices[i][fmt.Sprintf("%016X", r.Address)]++
+		bucketTypes[i][anomalyTypeName(r)]++
+	}
+	for i := range report.Buckets {
+		report.Buckets[i].Devices = sortedCounts(bucketDevices[i])
+		report.Buckets[i].Types = sortedCounts(bucketTypes[i])
+	}
+	return report, nil
+}
+
+// writeText prints the report as tables
+func (r *anomalyReport) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "Anomaly report for %s\n", r.Database)
+	if r.From.IsZero() {
+		fmt.Fprintf(w, "No anomalies\n")
+	} else {
+		fmt.Fprintf(w, "%s to %s\n", r.From.Format(time.DateTime), r.To.Format(time.DateTime))
+		fmt.Fprintf(w, "%d anomalies, %d decode errors\n", r.Total, r.DecodeErrors)
+	}
+	if r.Total == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\nBy device:\n")
+	fmt.Fprintf(tw, "  DEVICE\tTOTAL\tFIRST\tLAST\n")
+	for _, dev := range r.Devices {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\n", dev.Address, dev.Total, dev.First.Format(time.DateTime), dev.Last.Format(time.DateTime))
+		for _, t := range dev.Types {
+			fmt.Fprintf(tw, "    %s\t%d\n", t.Name, t.Count)
+		}
+	}
+
+	fmt.Fprintf(tw, "\nBy type:\n")
+	for _, t := range r.Types {
+		fmt.Fprintf(tw, "  %s\t%d\t%.1f%%\n", t.Name, t.Count, float64(t.Count)*100/float64(r.Total))
+	}
+
+	fmt.Fprintf(tw, "\nBy time (%s buckets):\n", r.Bucket)
+	fmt.Fprintf(tw, "  START\tTOTAL\tTOP TYPE\tTOP DEVICE\n")
+	for _, b := range r.Buckets {
+		topType, topDevice := "-", "-"
+		if len(b.Types) > 0 {
+			topType = fmt.Sprintf("%s (%d)", b.Types[0].Name, b.Types[0].Count)
+			topDevice = fmt.Sprintf("%s (%d)", b.Devices[0].Name, b.Devices[0].Count)
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\n", r.BucketStart(b.Start), b.Total, topType, topDevice)
+	}
+	return tw.Flush()
+}
+
+// reportHTML is the standalone HTML report page
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Format(time.DateTime) },
+	"percent": func(n, total int) string {
+		if total == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%.1f", float64(n)*100/float64(total))
+	},
+	"maxTotal": func(buckets []reportBucketRow) int {
+		m := 0
+		for _, b := range buckets {
+			m = max(m, b.Total)
+		}
+		return m
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Anomaly report - {{.Database}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.25em 0.75em; text-align: left; border-bottom: 1px solid #ddd; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+td.sub { padding-left: 2em; color: #555; }
+.bar { background: #c0392b; height: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Anomaly report</h1>
+<p>{{.Database}}<br>
+{{if .From.IsZero}}No anomalies{{else}}{{datetime .From}} to {{datetime .To}}<br>
+{{.Total}} anomalies, {{.DecodeErrors}} decode errors{{end}}</p>
+{{if .Total}}
+<h2>By device</h2>
+<table>
+<tr><th>Device</th><th>Anomalies</th><th>First</th><th>Last</th></tr>
+{{range .Devices}}<tr><td><b>{{.Address}}</b></td><td class="n">{{.Total}}</td><td>{{datetime .First}}</td><td>{{datetime .Last}}</td></tr>
+{{range .Types}}<tr><td class="sub">{{.Name}}</td><td class="n">{{.Count}}</td><td></td><td></td></tr>
+{{end}}{{end}}</table>
+
+<h2>By type</h2>
+<table>
+<tr><th>Type</th><th>Anomalies</th><th>Share</th><th></th></tr>
+{{$total := .Total}}{{range .Types}}<tr><td>{{.Name}}</td><td class="n">{{.Count}}</td><td class="n">{{percent .Count $total}}%</td><td style="width: 20em"><div class="bar" style="width: {{percent .Count $total}}%"></div></td></tr>
+{{end}}</table>
+
+<h2>By time ({{.Bucket}} buckets)</h2>
+<table>
+<tr><th>Start</th><th>Anomalies</th><th></th><th>Types</th></tr>
+{{$max := maxTotal .Buckets}}{{range .Buckets}}<tr><td>{{$.BucketStart .Start}}</td><td class="n">{{.Total}}</td><td style="width: 20em"><div class="bar" style="width: {{percent .Total $max}}%"></div></td><td>{{range $i, $t := .Types}}{{if $i}}, {{end}}{{$t.Name}} {{$t.Count}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+func runReportAnomalies(cmd *cobra.Command, args []string) error {
+	switch reportFormat {
+	case "text", "html", "json":
+	default:
+		return fmt.Errorf("invalid --format %q: must be text, html or json", reportFormat)
+	}
+	if reportBucket <= 0 {
+		return fmt.Errorf("--bucket must be positive")
+	}
+
+	now := time.Now()
+	q := store.Query{Session: reportSession}
+	var err error
+	if q.From, err = parseQueryTime(reportFrom, now); err != nil {
+		return fmt.Errorf("invalid --from: %v", err)
+	}
+	if q.To, err = parseQueryTime(reportTo, now); err != nil {
+		return fmt.Errorf("invalid --to: %v", err)
+	}
+	for _, s := range reportDevices {
+		address, err := parseAddress(s)
+		if err != nil {
+			return fmt.Errorf("invalid --device: %v", err)
+		}
+		q.Addresses = append(q.Addresses, address)
+	}
+
+	db, err := openRecordedStore(args[0])
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	records, err := db.Anomalies(q)
+	if err != nil {
+		return err
+	}
+	report, err := buildAnomalyReport(records, q.From, q.To, reportBucket)
+	if err != nil {
+		return err
+	}
+	report.Database = args[0]
+	decodeErrors, err := db.DecodeErrors(q)
+	if err != nil {
+		return err
+	}
+	report.DecodeErrors = len(decodeErrors)
+
+	out := io.Writer(os.Stdout)
+	if reportOutput != "-" {
+		f, err := os.Create(reportOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch reportFormat {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	case "html":
+		err = reportHTML.Execute(out, report)
+	default:
+		err = report.writeText(out)
+	}
+	if err != nil {
+		return err
+	}
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
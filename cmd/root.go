@@ -4,6 +4,9 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
 	"github.com/spf13/cobra"
 )
 
@@ -16,8 +19,28 @@ var (
 	wsURL         string
 	wsUsername    string
 	wsNoSSLVerify bool
+	wsCoalesce    time.Duration
+	wsMaxMessage  int
+	wsStream      bool
+	wsCompress    bool
+
+	// TCP connection flags
+	tcpAddr string
+
+	// noReconnect makes a lost connection end monitoring commands
+	noReconnect bool
+
+	// Display flags
+	unitsName    string
+	displayUnits fusain.UnitSystem
+	cborDiag     bool
+	deviceTime   bool
 )
 
+// deviceClock estimates the wall-clock time of device timestamps; every
+// received packet updates it
+var deviceClock = fusain.NewClockEstimator()
+
 var rootCmd = &cobra.Command{
 	Use:   "heliostat",
 	Short: "Helios Serial Protocol Analyzer",
@@ -29,11 +52,94 @@ diagnose communication issues and protocol anomalies.
 Connection modes:
   Serial:    --port /dev/ttyUSB0 [--baud 115200]
   WebSocket: --url ws://host/path [--username user]
+  TCP:       --tcp host:port (a serial bridge such as ser2net or socat)
 
 For WebSocket authentication, the password is read from the FUSAIN_PASSWORD
 environment variable, or prompted interactively if not set. The --password
-flag is intentionally not provided to avoid leaking credentials in shell history.`,
-	Version: "2.1.0",
+flag is intentionally not provided to avoid leaking credentials in shell history.
+
+When a serial port is unplugged or a WebSocket or TCP connection drops, the
+monitoring commands reconnect with exponential backoff (up to 30s between
+attempts) and carry on; --no-reconnect makes them exit instead.
+
+SIGINT, SIGTERM and SIGHUP restore the terminal and close the connection
+before exiting. Use --on-shutdown to broadcast commands (e.g. idle) first.
+In watchdog, serve, mqtt, influx and record, SIGHUP instead reloads the
+config file, --limits, aliases and --rules and reopens --jsonl, keeping the
+connection.
+
+All commands share the same exit codes; run 'heliostat exit_codes' for details.`,
+	Version:       "2.1.0",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadAppConfig()
+		if err != nil {
+			return err
+		}
+		loadedConfig.Store(cfg)
+		reloadOnHangup.Store(reloadModes[cmd.Name()])
+
+		if err := setupAliases(); err != nil {
+			return err
+		}
+
+		units, err := fusain.ParseUnitSystem(unitsName)
+		if err != nil {
+			return err
+		}
+		displayUnits = units
+		setupTerminal()
+		if err := setupTheme(); err != nil {
+			return err
+		}
+		setupKeys()
+
+		packets, err := parseShutdownSequence(onShutdownNames)
+		if err != nil {
+			return err
+		}
+		shutdownPackets = packets
+
+		filter, err := newAddressFilter(append(allowDevices, includeDevices...), append(denyDevices, excludeDevices...), logDenied)
+		if err != nil {
+			return err
+		}
+		deviceFilter = filter
+
+		imp, err := parseImpairment(impairSpecs)
+		if err != nil {
+			return err
+		}
+		connImpairment = imp
+
+		if err := setupOutputSinks(); err != nil {
+			return err
+		}
+
+		if err := setupRules(); err != nil {
+			return err
+		}
+
+		if err := setupNotifications(); err != nil {
+			return err
+		}
+
+		if err := setupServerTLS(); err != nil {
+			return err
+		}
+
+		if metricsAddr != "" {
+			if err := serveMetrics(metricsAddr); err != nil {
+				return err
+			}
+		}
+
+		if daemonControlSocket != "" {
+			return serveControlSocket(daemonControlSocket, cmd.Name())
+		}
+		return nil
+	},
 }
 
 func init() {
@@ -45,9 +151,57 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&wsURL, "url", "u", "", "WebSocket URL (ws:// or wss://)")
 	rootCmd.PersistentFlags().StringVar(&wsUsername, "username", "", "Username for HTTP Basic auth")
 	rootCmd.PersistentFlags().BoolVar(&wsNoSSLVerify, "no-ssl-verify", false, "Skip TLS certificate verification (wss:// only)")
+	rootCmd.PersistentFlags().DurationVar(&wsCoalesce, "ws-coalesce", 0, "Coalesce outgoing packets into one WebSocket message per interval (e.g. 5ms; 0 = off)")
+	rootCmd.PersistentFlags().IntVar(&wsMaxMessage, "ws-max-message", 512, "Split outgoing WebSocket messages larger than this many bytes (0 = no limit)")
+	rootCmd.PersistentFlags().BoolVar(&wsStream, "ws-stream", false, "Don't offer the one-frame-per-message WebSocket subprotocol")
+	rootCmd.PersistentFlags().BoolVar(&wsCompress, "ws-compress", false, "Negotiate permessage-deflate WebSocket compression")
+
+	// TCP connection flags
+	rootCmd.PersistentFlags().StringVar(&tcpAddr, "tcp", "", "TCP address of a serial bridge such as ser2net or socat (host:port)")
+	rootCmd.PersistentFlags().StringSliceVar(&impairSpecs, "impair", nil, impairUsage)
+	rootCmd.PersistentFlags().BoolVar(&noReconnect, "no-reconnect", false, "Exit when the connection is lost instead of reconnecting (control, error_detection, raw_log, record, discovery)")
+
+	// Display flags
+	rootCmd.PersistentFlags().StringVar(&unitsName, "units", "metric", "Display units: metric (°C) or imperial (°F)")
+	rootCmd.PersistentFlags().BoolVar(&cborDiag, "cbor-diag", false, "Show unknown or undecodable payloads in CBOR diagnostic notation")
+	rootCmd.PersistentFlags().BoolVar(&deviceTime, "device-time", false, "Show the estimated wall-clock time of device timestamps (toggle with 't' in the TUI)")
+	rootCmd.PersistentFlags().BoolVar(&forceASCII, "ascii", false, "Draw TUIs with ASCII symbols and no color (default: detected from TERM, NO_COLOR and the locale)")
+	rootCmd.PersistentFlags().StringVar(&themeName, "theme", "", "TUI color theme: dark, light, high-contrast or no-color (default: tui.theme in the config file, or dark)")
+
+	// Output flags
+	rootCmd.PersistentFlags().StringVar(&jsonlPath, "jsonl", "", "Also append every received packet and decode error to this file as JSON Lines")
+
+	// Configuration flags
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default $XDG_CONFIG_HOME/heliostat/config.json)")
+	rootCmd.PersistentFlags().BoolVar(&unlockInterlocks, "unlock", false, "Bypass command interlocks from the config file")
+	rootCmd.PersistentFlags().StringVar(&limitsPath, "limits", "", "Validation limits profile (JSON), overriding validation_limits in the config file")
+	rootCmd.PersistentFlags().StringArrayVar(&aliasSpecs, "alias", nil, "Name a device and save the name to aliases.json (ADDRESS=NAME; ADDRESS= removes it; repeatable)")
+
+	// Address filter flags
+	rootCmd.PersistentFlags().StringSliceVar(&allowDevices, "allow-device", nil, "Only process and command these device addresses (hex or alias)")
+	rootCmd.PersistentFlags().StringSliceVar(&denyDevices, "deny-device", nil, "Ignore and refuse commands to these device addresses (hex or alias)")
+	rootCmd.PersistentFlags().StringSliceVar(&includeDevices, "device", nil, "Same as --allow-device")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeDevices, "exclude-device", nil, "Same as --deny-device")
+	rootCmd.PersistentFlags().BoolVar(&logDenied, "log-denied", false, "Log packets dropped by --allow-device/--deny-device (text modes)")
+
+	// Shutdown flags
+	rootCmd.PersistentFlags().StringSliceVar(&onShutdownNames, "on-shutdown", nil, "Commands to broadcast when stopped by a signal, in order (idle, estop)")
+}
+
+// formatOptions returns the packet formatting options selected by global flags
+func formatOptions() fusain.FormatOptions {
+	opts := fusain.FormatOptions{Units: displayUnits, CBORDiagnostic: cborDiag, DeviceName: deviceAlias}
+	if deviceTime {
+		opts.Clock = deviceClock
+	}
+	return opts
 }
 
-// Execute runs the root command
+// Execute runs the root command.
+// Use ExitCode to map the returned error to a process exit code.
 func Execute() error {
-	return rootCmd.Execute()
+	handleSignals()
+	err := rootCmd.Execute()
+	runShutdownHooks()
+	return err
 }
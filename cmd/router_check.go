@@ -0,0 +1,432 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	routerCheckTimeout    int
+	routerCheckSamples    int
+	routerCheckMaxLatency int
+)
+
+var routerCheckCmd = &cobra.Command{
+	Use:     "router_check",
+	Aliases: []string{"router-check"},
+	Short:   "Verify a router implementation against the Fusain routing spec",
+	Long: `Run a conformance check against a Fusain router (e.g. Slate) and print a report.
+
+The following behaviors are checked, in order:
+  stateless ping     PING_REQUEST to the stateless address is answered by the
+                     router itself with a PING_RESPONSE from the stateless address
+  discovery          DISCOVERY_REQUEST yields DEVICE_ANNOUNCE for each device,
+                     terminated by the end-of-discovery marker
+  subscription       DATA_SUBSCRIPTION starts telemetry forwarding for a device,
+                     DATA_UNSUBSCRIBE stops it
+  broadcast          PING_REQUEST to the broadcast address is forwarded to every
+                     discovered device
+  forwarding latency Round-trip time of device-addressed pings compared with
+                     router-local pings
+
+Checks that need a device are skipped when discovery finds none.
+
+Examples:
+  heliostat router_check --url ws://slate.local/ws
+  heliostat router_check --url ws://slate.local/ws --samples 50 --max-latency 100
+
+Exit codes:
+  0 - All checks passed (warnings allowed)
+  1 - One or more checks failed
+  2 - Connection error`,
+	RunE: runRouterCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(routerCheckCmd)
+	routerCheckCmd.Flags().IntVar(&routerCheckTimeout, "timeout", 5, "Timeout in seconds for each check step")
+	routerCheckCmd.Flags().IntVar(&routerCheckSamples, "samples", 10, "Number of pings used to measure forwarding latency")
+	routerCheckCmd.Flags().IntVar(&routerCheckMaxLatency, "max-latency", 0, "Fail if average forwarding overhead exceeds this many milliseconds (0 = no limit)")
+}
+
+// Check result statuses
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// checkResult is a single line of the router_check report
+type checkResult struct {
+	name   string
+	status string
+	detail string
+}
+
+// routerChecker drives a connection for the conformance checks.
+// A background reader feeds decoded packets into a channel so each
+// check can wait for the replies it cares about.
+type routerChecker struct {
+	conn    Connection
+	enc     *fusain.Encoder
+	timeout time.Duration
+	packets chan *fusain.Packet
+	errs    chan error
+	devices []deviceInfo
+}
+
+func newRouterChecker(conn Connection, timeout time.Duration) *routerChecker {
+	rc := &routerChecker{
+		conn:    conn,
+		enc:     fusain.NewEncoder(conn),
+		timeout: timeout,
+		packets: make(chan *fusain.Packet, 256),
+		errs:    make(chan error, 1),
+	}
+	go rc.readLoop()
+	return rc
+}
+
+func (rc *routerChecker) readLoop() {
+	decoder := fusain.NewDecoder()
+	buf := make([]byte, 256)
+	for {
+		n, err := rc.conn.Read(buf)
+		if err != nil {
+			rc.errs <- err
+			return
+		}
+		packets, _ := decoder.Decode(buf[:n])
+		for _, packet := range packets {
+			rc.packets <- packet
+		}
+	}
+}
+
+func (rc *routerChecker) send(p *fusain.Packet) error {
+	return rc.enc.WritePacket(p)
+}
+
+// await waits up to timeout for a packet accepted by match, discarding
+// everything else. It returns nil on timeout.
+func (rc *routerChecker) await(timeout time.Duration, match func(*fusain.Packet) bool) (*fusain.Packet, error) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case p := <-rc.packets:
+			if match(p) {
+				return p, nil
+			}
+		case err := <-rc.errs:
+			return nil, err
+		case <-deadline:
+			return nil, nil
+		}
+	}
+}
+
+// collect gathers every packet accepted by match for the full duration.
+func (rc *routerChecker) collect(duration time.Duration, match func(*fusain.Packet) bool) ([]*fusain.Packet, error) {
+	var matched []*fusain.Packet
+	deadline := time.After(duration)
+	for {
+		select {
+		case p := <-rc.packets:
+			if match(p) {
+				matched = append(matched, p)
+			}
+		case err := <-rc.errs:
+			return matched, err
+		case <-deadline:
+			return matched, nil
+		}
+	}
+}
+
+// ping sends a PING_REQUEST to address and returns the round-trip time,
+// or zero if no response arrived in time.
+func (rc *routerChecker) ping(address uint64) (time.Duration, error) {
+	start := time.Now()
+	if err := rc.send(fusain.NewPingRequest(address)); err != nil {
+		return 0, err
+	}
+	p, err := rc.await(rc.timeout, func(p *fusain.Packet) bool {
+		return p.Type() == fusain.MsgPingResponse && p.Address() == address
+	})
+	if err != nil || p == nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+func isTelemetryFrom(address uint64) func(*fusain.Packet) bool {
+	return func(p *fusain.Packet) bool {
+		if p.Address() != address {
+			return false
+		}
+		switch p.Type() {
+		case fusain.MsgStateData, fusain.MsgMotorData, fusain.MsgPumpData,
+			fusain.MsgGlowData, fusain.MsgTempData:
+			return true
+		}
+		return false
+	}
+}
+
+func (rc *routerChecker) checkStatelessPing() (checkResult, error) {
+	result := checkResult{name: "stateless ping"}
+	rtt, err := rc.ping(fusain.AddressStateless)
+	if err != nil {
+		return result, err
+	}
+	if rtt == 0 {
+		result.status = checkFail
+		result.detail = fmt.Sprintf("no PING_RESPONSE from stateless address within %v", rc.timeout)
+		return result, nil
+	}
+	result.status = checkPass
+	result.detail = fmt.Sprintf("rtt=%v", rtt.Round(time.Microsecond))
+	return result, nil
+}
+
+func (rc *routerChecker) checkDiscovery() (checkResult, error) {
+	result := checkResult{name: "discovery"}
+	if err := rc.send(fusain.NewDiscoveryRequest(fusain.AddressStateless)); err != nil {
+		return result, err
+	}
+
+	deadline := time.After(rc.timeout)
+	for {
+		select {
+		case p := <-rc.packets:
+			if p.Type() != fusain.MsgDeviceAnnounce {
+				continue
+			}
+			device := parseDeviceAnnounce(p)
+			if device.isEndMarker() {
+				if device.address != fusain.AddressStateless {
+					result.status = checkWarn
+					result.detail = fmt.Sprintf("%d device(s), end marker sent from 0x%016X instead of stateless address",
+						len(rc.devices), device.address)
+					return result, nil
+				}
+				result.status = checkPass
+				result.detail = fmt.Sprintf("%d device(s), end marker received", len(rc.devices))
+				return result, nil
+			}
+			rc.devices = append(rc.devices, device)
+		case err := <-rc.errs:
+			return result, err
+		case <-deadline:
+			result.status = checkFail
+			result.detail = fmt.Sprintf("%d device(s), no end-of-discovery marker within %v", len(rc.devices), rc.timeout)
+			return result, nil
+		}
+	}
+}
+
+func (rc *routerChecker) checkSubscription() (checkResult, error) {
+	result := checkResult{name: "subscription"}
+	if len(rc.devices) == 0 {
+		result.status = checkSkip
+		result.detail = "no devices discovered"
+		return result, nil
+	}
+	address := rc.devices[0].address
+
+	if err := rc.send(fusain.NewDataSubscription(fusain.AddressStateless, address)); err != nil {
+		return result, err
+	}
+	p, err := rc.await(rc.timeout, isTelemetryFrom(address))
+	if err != nil {
+		return result, err
+	}
+	if p == nil {
+		result.status = checkFail
+		result.detail = fmt.Sprintf("no telemetry from 0x%016X within %v of DATA_SUBSCRIPTION", address, rc.timeout)
+		return result, nil
+	}
+
+	if err := rc.send(fusain.NewDataUnsubscribe(fusain.AddressStateless, address)); err != nil {
+		return result, err
+	}
+	// Allow packets already in flight to drain before counting
+	if _, err := rc.collect(500*time.Millisecond, isTelemetryFrom(address)); err != nil {
+		return result, err
+	}
+	leaked, err := rc.collect(rc.timeout, isTelemetryFrom(address))
+	if err != nil {
+		return result, err
+	}
+	if len(leaked) > 0 {
+		result.status = checkFail
+		result.detail = fmt.Sprintf("%d telemetry packet(s) from 0x%016X after DATA_UNSUBSCRIBE", len(leaked), address)
+		return result, nil
+	}
+
+	result.status = checkPass
+	result.detail = fmt.Sprintf("telemetry started and stopped for 0x%016X", address)
+	return result, nil
+}
+
+func (rc *routerChecker) checkBroadcast() (checkResult, error) {
+	result := checkResult{name: "broadcast"}
+	if len(rc.devices) == 0 {
+		result.status = checkSkip
+		result.detail = "no devices discovered"
+		return result, nil
+	}
+
+	if err := rc.send(fusain.NewPingRequest(fusain.AddressBroadcast)); err != nil {
+		return result, err
+	}
+	responses, err := rc.collect(rc.timeout, func(p *fusain.Packet) bool {
+		return p.Type() == fusain.MsgPingResponse
+	})
+	if err != nil {
+		return result, err
+	}
+
+	responded := make(map[uint64]bool)
+	for _, p := range responses {
+		responded[p.Address()] = true
+	}
+	answered := 0
+	for _, d := range rc.devices {
+		if responded[d.address] {
+			answered++
+		}
+	}
+
+	switch {
+	case answered == len(rc.devices):
+		result.status = checkPass
+	case answered > 0:
+		result.status = checkWarn
+	default:
+		result.status = checkFail
+	}
+	result.detail = fmt.Sprintf("%d/%d device(s) answered broadcast PING_REQUEST", answered, len(rc.devices))
+	return result, nil
+}
+
+func (rc *routerChecker) checkLatency() (checkResult, error) {
+	result := checkResult{name: "forwarding latency"}
+	if len(rc.devices) == 0 {
+		result.status = checkSkip
+		result.detail = "no devices discovered"
+		return result, nil
+	}
+	address := rc.devices[0].address
+
+	var local, forwarded []time.Duration
+	for i := 0; i < routerCheckSamples; i++ {
+		rtt, err := rc.ping(fusain.AddressStateless)
+		if err != nil {
+			return result, err
+		}
+		if rtt > 0 {
+			local = append(local, rtt)
+		}
+		rtt, err = rc.ping(address)
+		if err != nil {
+			return result, err
+		}
+		if rtt > 0 {
+			forwarded = append(forwarded, rtt)
+		}
+	}
+
+	if len(forwarded) == 0 {
+		result.status = checkFail
+		result.detail = fmt.Sprintf("no PING_RESPONSE from 0x%016X in %d attempt(s)", address, routerCheckSamples)
+		return result, nil
+	}
+
+	fMin, fAvg, fMax := durationStats(forwarded)
+	_, lAvg, _ := durationStats(local)
+	overhead := fAvg - lAvg
+
+	result.status = checkPass
+	if len(forwarded) < routerCheckSamples {
+		result.status = checkWarn
+	}
+	if routerCheckMaxLatency > 0 && overhead > time.Duration(routerCheckMaxLatency)*time.Millisecond {
+		result.status = checkFail
+	}
+	result.detail = fmt.Sprintf("%d/%d replies, rtt min/avg/max=%v/%v/%v, overhead=%v",
+		len(forwarded), routerCheckSamples,
+		fMin.Round(time.Microsecond), fAvg.Round(time.Microsecond), fMax.Round(time.Microsecond),
+		overhead.Round(time.Microsecond))
+	return result, nil
+}
+
+// durationStats returns min, average and max of samples (zero if empty)
+func durationStats(samples []time.Duration) (time.Duration, time.Duration, time.Duration) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	lo, hi := samples[0], samples[0]
+	var total time.Duration
+	for _, s := range samples {
+		if s < lo {
+			lo = s
+		}
+		if s > hi {
+			hi = s
+		}
+		total += s
+	}
+	return lo, total / time.Duration(len(samples)), hi
+}
+
+func runRouterCheck(cmd *cobra.Command, args []string) error {
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Printf("Heliostat - Router Conformance Check\n")
+	fmt.Printf("Connection: %s\n", connInfo)
+	fmt.Printf("Timeout: %d seconds per step\n\n", routerCheckTimeout)
+
+	rc := newRouterChecker(conn, time.Duration(routerCheckTimeout)*time.Second)
+	checks := []func() (checkResult, error){
+		rc.checkStatelessPing,
+		rc.checkDiscovery,
+		rc.checkSubscription,
+		rc.checkBroadcast,
+		rc.checkLatency,
+	}
+
+	results := make([]checkResult, 0, len(checks))
+	for _, check := range checks {
+		result, err := check()
+		if err != nil {
+			return exitErrorf(ExitConnection, "%s: %v", result.name, err)
+		}
+		fmt.Printf("[%s] %-20s %s\n", result.status, result.name, result.detail)
+		results = append(results, result)
+	}
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.status]++
+	}
+	fmt.Printf("\n--- Router check summary ---\n")
+	fmt.Printf("%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[checkPass], counts[checkWarn], counts[checkFail], counts[checkSkip])
+
+	if counts[checkFail] > 0 {
+		return exitSilently(ExitFailure)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/Thermoquad/heliostat/pkg/rules"
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+)
+
+// rulesPath holds the --rules flag value
+var rulesPath string
+
+// alertRules evaluates the --rules file against every telemetry packet
+// (nil without --rules). A reload replaces it.
+var alertRules atomic.Pointer[rules.Engine]
+
+// alertExitOnce makes the first exit rule end the program
+var alertExitOnce sync.Once
+
+// alertDrainTimeout bounds how long an exit rule waits for the event log,
+// sinks and hooks to take its alert before shutting down
+const alertDrainTimeout = time.Second
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&rulesPath, "rules", "", "Alert rules file (YAML): conditions on telemetry that raise alerts in the event log, outputs and --notify hooks")
+}
+
+// setupRules loads the --rules file
+func setupRules() error {
+	engine, err := loadRules()
+	if err != nil {
+		return err
+	}
+	alertRules.Store(engine)
+	return nil
+}
+
+// loadRules reads the --rules file and resolves each rule's device. It
+// returns nil without --rules.
+func loadRules() (*rules.Engine, error) {
+	if rulesPath == "" {
+		return nil, nil
+	}
+	loaded, err := rules.Load(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load --rules: %w", err)
+	}
+	for i := range loaded {
+		r := &loaded[i]
+		if r.Device == "" {
+			continue
+		}
+		address, err := parseAddress(r.Device)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load --rules: rule %q: %w", r.Name, err)
+		}
+		r.Address = address
+	}
+	return rules.NewEngine(loaded), nil
+}
+
+// publishAlerts evaluates the rules against a telemetry packet, writing
+// each alert raised or cleared to the output sinks and publishing it
+func publishAlerts(bus *events.Bus, packet *fusain.Packet) {
+	engine := alertRules.Load()
+	if engine == nil {
+		return
+	}
+	sample, ok := sinks.TelemetryFromPacket(packet)
+	if !ok {
+		return
+	}
+	for _, a := range engine.Evaluate(sample) {
+		var e events.Event = events.AlertRaised{
+			At:        a.At,
+			Rule:      a.Rule.Name,
+			Severity:  a.Rule.Severity,
+			Condition: a.Rule.Condition(),
+			Address:   a.Address,
+			Value:     a.Value,
+			Since:     a.Since,
+		}
+		if !a.Active {
+			e = events.AlertCleared(e.(events.AlertRaised))
+		}
+		reportSinkError(outputSinks.WriteAlert(*alertRecord(e)))
+		bus.Publish(e)
+
+		if a.Active && a.Rule.Exit {
+			rule := a.Rule.Name
+			alertExitOnce.Do(func() { go exitForAlert(bus, rule) })
+		}
+	}
+}
+
+// alertRecord converts an alert event for output sinks and JSON output
+func alertRecord(e events.Event) *sinks.Alert {
+	switch e := e.(type) {
+	case events.AlertRaised:
+		return &sinks.Alert{
+			Time:      e.At,
+			Rule:      e.Rule,
+			Severity:  e.Severity,
+			Condition: e.Condition,
+			Address:   fmt.Sprintf("%016X", e.Address),
+			Active:    true,
+			Value:     e.Value,
+			Since:     e.Since,
+		}
+	case events.AlertCleared:
+		a := alertRecord(events.AlertRaised(e))
+		a.Active = false
+		return a
+	}
+	return nil
+}
+
+// exitForAlert ends the program with ExitAlert once every subscriber has
+// taken the alert: the shutdown hooks restore the terminal, close the
+// outputs and wait for --notify-exec hooks, as for a signal
+func exitForAlert(bus *events.Bus, rule string) {
+	deadline := time.Now().Add(alertDrainTimeout)
+	for bus.Stats().Depth > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+	}
+	fmt.Fprintf(os.Stderr, "Stopped by alert rule %q\n", rule)
+	os.Exit(ExitAlert)
+}
+
+// alertLogMessage describes an alert event for event logs and text output
+func alertLogMessage(e events.Event) string {
+	switch e := e.(type) {
+	case events.AlertRaised:
+		return fmt.Sprintf("ALERT %s [%s]: %s on %s (%s)", e.Rule, e.Severity, e.Condition, deviceLabel(e.Address), formatAlertValue(e.Value))
+	case events.AlertCleared:
+		return fmt.Sprintf("ALERT CLEARED %s: %s on %s after %v (%s)", e.Rule, e.Condition, deviceLabel(e.Address), e.At.Sub(e.Since).Round(time.Second), formatAlertValue(e.Value))
+	}
+	return ""
+}
+
+// alertLogEntry returns the TUI event log entry for an alert event. Raised
+// alerts are errors, or highlighted like emergency stops when critical;
+// info alerts and cleared alerts are warnings.
+func alertLogEntry(e events.Event) errorLogEntry {
+	entry := errorLogEntry{timestamp: time.Now(), message: alertLogMessage(e)}
+	if raised, ok := e.(events.AlertRaised); ok {
+		entry.isError = raised.Severity != rules.SeverityInfo
+		entry.emergency = raised.Severity == rules.SeverityCritical
+	}
+	return entry
+}
+
+// formatAlertValue formats the value an alert was raised or cleared with
+func formatAlertValue(v float64) string {
+	return "value " + strconv.FormatFloat(v, 'f', -1, 64)
+}
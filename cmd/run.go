@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runSpeed     float64
+	runDryRun    bool
+	runUnchecked bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run SCRIPT",
+	Short: "Send the commands of a script with their timing",
+	Long: `Send the commands of a script in order, waiting between them as the
+script says. Scripts are written by the control TUI ('w', or --export-script)
+from the commands a session sent, so a bench procedure performed by hand can
+be repeated exactly; they can also be written or edited by hand.
+
+Each line is one step; blank lines and lines starting with # are ignored:
+
+  send ADDRESS TYPE [PAYLOAD]   Send a packet, as 'heliostat send' would
+  wait DURATION                 Wait before the next step (e.g. 2.5s, 1m30s)
+
+ADDRESS is hex, a device alias, "broadcast" or "stateless"; TYPE a message
+type name or number; PAYLOAD a JSON object keyed by CBOR map key, e.g.
+{"0":2,"1":2500}.
+
+Waits are measured from the start of the script, so the sends keep their
+timing however long each takes. --speed scales them (2 runs twice as fast;
+0 sends without waiting).
+
+The whole script is checked before connecting: payload values are validated
+like commands from the control TUI (--unchecked skips this), and the
+--allow-device/--deny-device filter and command interlocks apply to every
+command. Rejections reported by the devices are printed as they arrive and
+make the command exit with code 1. --dry-run prints the schedule without
+connecting.
+
+Examples:
+  heliostat run -p /dev/ttyUSB0 preheat-test.run
+  heliostat run --dry-run preheat-test.run
+  heliostat run -u ws://slate.local/ws --speed 0 smoke.run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRun,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().Float64Var(&runSpeed, "speed", 1, "Playback speed relative to the script's waits (0 = don't wait)")
+	runCmd.Flags().BoolVar(&runDryRun, "dry-run", false, "Print the schedule without connecting")
+	runCmd.Flags().BoolVar(&runUnchecked, "unchecked", false, "Skip payload validation (filters and interlocks still apply)")
+}
+
+// runStep is one send of a script, at offset from its start
+type runStep struct {
+	line   int
+	offset time.Duration
+	packet *fusain.Packet
+}
+
+// parseRunScript reads a script into its sends, with the waits before each
+// added up into its offset
+func parseRunScript(r io.Reader) ([]runStep, error) {
+	var steps []runStep
+	var offset time.Duration
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		verb, rest, _ := strings.Cut(text, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch verb {
+		case "wait":
+			d, err := time.ParseDuration(rest)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("line %d: invalid duration %q", line, rest)
+			}
+			offset += d
+
+		case "send":
+			fields := strings.SplitN(rest, " ", 3)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: expected send ADDRESS TYPE [PAYLOAD]", line)
+			}
+			address, err := parseSendAddress(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			msgType, err := parseMessageTypeFlag(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			var payload string
+			if len(fields) == 3 {
+				payload = fields[2]
+			}
+			packet, err := packetFromPayload(address, msgType, payload)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			steps = append(steps, runStep{line: line, offset: offset, packet: packet})
+
+		default:
+			return nil, fmt.Errorf("line %d: unknown step %q (valid: send, wait)", line, verb)
+		}
+	}
+	return steps, scanner.Err()
+}
+
+// sentCommand is a command a session sent, for export as a script
+type sentCommand struct {
+	at     time.Time
+	packet *fusain.Packet
+}
+
+// writeRunScript writes commands as a script for the run command, with the
+// gaps between them as waits. header lines are written first as comments.
+func writeRunScript(w io.Writer, header []string, commands []sentCommand) error {
+	bw := bufio.NewWriter(w)
+	for _, line := range header {
+		fmt.Fprintf(bw, "# %s\n", line)
+	}
+	if len(header) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	for i, c := range commands {
+		if i > 0 {
+			if gap := c.at.Sub(commands[i-1].at).Round(time.Millisecond); gap > 0 {
+				fmt.Fprintf(bw, "wait %v\n", gap)
+			}
+		}
+		if name := deviceAlias(c.packet.Address()); name != "" {
+			fmt.Fprintf(bw, "# %s\n", name)
+		}
+		fmt.Fprintf(bw, "send %016X %s", c.packet.Address(), fusain.FormatMessageType(c.packet.Type()))
+		if payload := scriptPayload(c.packet); payload != "" {
+			fmt.Fprintf(bw, " %s", payload)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+// scriptPayload returns a packet's payload as the JSON object send takes,
+// or "" if it has none
+func scriptPayload(p *fusain.Packet) string {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	var doc struct {
+		Payload json.RawMessage `json:"payload"`
+	}
+	if json.Unmarshal(data, &doc) != nil {
+		return ""
+	}
+	return string(doc.Payload)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	if runSpeed < 0 {
+		return fmt.Errorf("--speed must not be negative")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	steps, err := parseRunScript(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("%s: no commands to send", args[0])
+	}
+
+	for i := range steps {
+		if runSpeed > 0 {
+			steps[i].offset = time.Duration(float64(steps[i].offset) / runSpeed)
+		} else {
+			steps[i].offset = 0
+		}
+		packet := steps[i].packet
+		if err := checkCommandPolicy(packet); err != nil {
+			return fmt.Errorf("%s: line %d: %v", args[0], steps[i].line, err)
+		}
+		if !runUnchecked {
+			if errs := fusain.ValidatePacketWithOptions(packet, fusain.ValidateOptions{Limits: appConfig().Limits}); len(errs) > 0 {
+				return fmt.Errorf("%s: line %d: %s rejected: %s (use --unchecked to send anyway)",
+					args[0], steps[i].line, fusain.FormatMessageType(packet.Type()), errs[0].Message)
+			}
+		}
+	}
+
+	if runDryRun {
+		for _, step := range steps {
+			fmt.Printf("[+%9.3fs] %s to %s\n", step.offset.Seconds(), fusain.FormatMessageType(step.packet.Type()), formatAddress(step.packet.Address()))
+		}
+		return nil
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	fmt.Printf("Running %d commands via %s\n", len(steps), connInfo)
+
+	client := fusain.NewClient(conn)
+	rejections := make(chan *fusain.Packet, 16)
+	go func() {
+		for p := range client.Packets() {
+			if p.Type() == fusain.MsgErrorInvalidCmd || p.Type() == fusain.MsgErrorStateReject {
+				rejections <- p
+			}
+		}
+	}()
+
+	rejected := 0
+	report := func(p *fusain.Packet) {
+		rejected++
+		fmt.Printf("  Rejected by %s: %s\n", formatAddress(p.Address()), describeErrorReply(p))
+	}
+
+	start := time.Now()
+	for _, step := range steps {
+		timer := time.NewTimer(time.Until(start.Add(step.offset)))
+	wait:
+		for {
+			select {
+			case <-timer.C:
+				break wait
+			case p := <-rejections:
+				report(p)
+			case <-client.Done():
+				timer.Stop()
+				return exitErrorf(ExitConnection, "connection lost: %v", client.Err())
+			}
+		}
+
+		if err := client.Send(step.packet); err != nil {
+			return exitErrorf(ExitConnection, "send failed: %v", err)
+		}
+		fmt.Printf("[+%9.3fs] Sent %s to %s\n", time.Since(start).Seconds(), fusain.FormatMessageType(step.packet.Type()), formatAddress(step.packet.Address()))
+	}
+
+	// Devices only reply to reject a command; give the last one time to
+	deadline := time.After(transactorTimeout)
+	for {
+		select {
+		case p := <-rejections:
+			report(p)
+		case <-deadline:
+			if rejected > 0 {
+				fmt.Printf("%d of %d commands rejected\n", rejected, len(steps))
+				return exitSilently(ExitFailure)
+			}
+			fmt.Println("Done")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sanitizeKeepAddresses bool
+	sanitizeKeepSensitive bool
+	sanitizeMapFile       string
+)
+
+var sanitizeCmd = &cobra.Command{
+	Use:   "sanitize INPUT OUTPUT",
+	Short: "Anonymize a capture file for sharing",
+	Long: `Rewrite a capture file from 'heliostat record' so it can be attached to
+public issues.
+
+Device addresses are replaced with 0000000000000001, 0000000000000002, ...
+in order of first appearance, both in frame headers and in payload fields
+that carry addresses (DATA_SUBSCRIPTION, DATA_UNSUBSCRIBE). Broadcast and
+stateless addresses are kept. Payload fields marked sensitive in the
+protocol schema (PID gains in MOTOR_CONFIG and TEMPERATURE_CONFIG) are
+removed. Every frame is re-encoded with a new CRC; receive times are kept.
+
+Frames that fail CRC or decoding cannot be rewritten reliably and are
+dropped. --map writes the original-to-replacement address mapping to a JSON
+file; keep it private.
+
+Examples:
+  heliostat sanitize session.cap session-public.cap
+  heliostat sanitize --map addresses.json field.cap field-public.cap`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSanitize,
+}
+
+func init() {
+	rootCmd.AddCommand(sanitizeCmd)
+	sanitizeCmd.Flags().BoolVar(&sanitizeKeepAddresses, "keep-addresses", false, "Don't anonymize device addresses")
+	sanitizeCmd.Flags().BoolVar(&sanitizeKeepSensitive, "keep-sensitive", false, "Don't remove sensitive payload fields")
+	sanitizeCmd.Flags().StringVar(&sanitizeMapFile, "map", "", "Write the address mapping to this JSON file")
+}
+
+func runSanitize(cmd *cobra.Command, args []string) error {
+	input, output := args[0], args[1]
+	if input == output {
+		return fmt.Errorf("input and output must be different files")
+	}
+
+	in, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("cannot read capture: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("cannot create output: %v", err)
+	}
+	defer out.Close()
+
+	sanitizer := fusain.NewSanitizer()
+	sanitizer.AnonymizeAddresses = !sanitizeKeepAddresses
+	sanitizer.StripSensitive = !sanitizeKeepSensitive
+
+	reader := fusain.NewBatchReader(in)
+	writer := fusain.NewBatchWriter(out, 0)
+	var dropped uint64
+	for {
+		frames, err := reader.ReadBatch()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", input, err)
+		}
+		for _, f := range frames {
+			clean, err := sanitizer.Frame(f.Frame)
+			if err != nil {
+				dropped++
+				continue
+			}
+			if err := writer.WriteFrame(f.Timestamp, clean); err != nil {
+				return fmt.Errorf("%s: %v", output, err)
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("%s: %v", output, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("%s: %v", output, err)
+	}
+
+	fmt.Printf("Wrote %d frames to %s (%d corrupt frames dropped, %d devices renamed)\n",
+		writer.Frames(), output, dropped, len(sanitizer.Addresses()))
+
+	if sanitizeMapFile != "" {
+		mapping := make(map[string]string)
+		for original, replacement := range sanitizer.Addresses() {
+			mapping[fmt.Sprintf("%016X", original)] = fmt.Sprintf("%016X", replacement)
+		}
+		data, err := json.MarshalIndent(mapping, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(sanitizeMapFile, append(data, '\n'), 0o600); err != nil {
+			return fmt.Errorf("cannot write address map: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sendAddress     string
+	sendType        string
+	sendPayload     string
+	sendPayloadFile string
+	sendWait        time.Duration
+	sendExpect      string
+	sendDryRun      bool
+	sendUnchecked   bool
+)
+
+var sendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Build and transmit any Fusain packet",
+	Long: `Build a Fusain packet from flags and transmit it, optionally waiting for
+the response.
+
+The payload is a JSON object keyed by CBOR map key, as in the JSON packet
+format: --payload '{"0":2,"1":2500}'. Values are converted to the CBOR types
+of the protocol schema, so 180 becomes a float where the schema expects one.
+--payload-file reads the same JSON from a file (*.json), or a raw CBOR
+message ([type, payload map], as in the packet's "raw" field) from any other
+file. With a CBOR file, --type is taken from the message.
+
+Addresses are hex, or "broadcast" and "stateless". Message types are names
+(STATE_COMMAND, state-command) or numbers (0x20).
+
+With --wait, the command waits for the reply:
+  - PING_REQUEST: PING_RESPONSE
+  - DISCOVERY_REQUEST: DEVICE_ANNOUNCE
+  - SEND_TELEMETRY: telemetry data
+  - Other commands: devices only reply to reject a command, so the command
+    waits for ERROR_INVALID_CMD or ERROR_STATE_REJECT and reports the
+    command accepted if none arrives in time
+--expect waits for a specific message type instead.
+
+Payload values are validated like commands from the control TUI;
+--unchecked skips this to send deliberately invalid packets to a bench
+device. The --allow-device/--deny-device filter and command interlocks
+always apply. --dry-run prints the packet and its wire bytes without
+connecting.
+
+Examples:
+  heliostat send -p /dev/ttyUSB0 --addr 0123456789ABCDEF --type STATE_COMMAND --payload '{"0":2,"1":2500}' --wait 1s
+  heliostat send -p /dev/ttyUSB0 --addr 0123456789ABCDEF --type PING_REQUEST --wait 2s
+  heliostat send --addr broadcast --type state-command --payload '{"0":255}' --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runSend,
+}
+
+func init() {
+	rootCmd.AddCommand(sendCmd)
+	sendCmd.Flags().StringVar(&sendAddress, "addr", "", "Destination address (hex, alias, broadcast or stateless)")
+	sendCmd.Flags().StringVar(&sendType, "type", "", "Message type (name or number)")
+	sendCmd.Flags().StringVar(&sendPayload, "payload", "", `Payload as a JSON object keyed by CBOR map key (e.g. '{"0":1}')`)
+	sendCmd.Flags().StringVar(&sendPayloadFile, "payload-file", "", "Read the payload from a JSON (*.json) or raw CBOR message file")
+	sendCmd.Flags().DurationVar(&sendWait, "wait", 0, "Wait this long for the response (0 = don't wait)")
+	sendCmd.Flags().StringVar(&sendExpect, "expect", "", "Response message type to wait for (default: depends on --type)")
+	sendCmd.Flags().BoolVar(&sendDryRun, "dry-run", false, "Print the packet and wire bytes without sending")
+	sendCmd.Flags().BoolVar(&sendUnchecked, "unchecked", false, "Skip payload validation (filters and interlocks still apply)")
+	sendCmd.MarkFlagRequired("addr")
+	sendCmd.MarkFlagsMutuallyExclusive("payload", "payload-file")
+}
+
+func runSend(cmd *cobra.Command, args []string) error {
+	packet, err := buildSendPacket()
+	if err != nil {
+		return err
+	}
+
+	var expect uint8
+	if sendExpect != "" {
+		if expect, err = parseMessageTypeFlag(sendExpect); err != nil {
+			return fmt.Errorf("invalid --expect: %v", err)
+		}
+	}
+
+	if err := checkCommandPolicy(packet); err != nil {
+		return err
+	}
+	if !sendUnchecked {
+		if errs := fusain.ValidatePacketWithOptions(packet, fusain.ValidateOptions{Limits: appConfig().Limits}); len(errs) > 0 {
+			return fmt.Errorf("%s rejected: %s (use --unchecked to send anyway)", fusain.FormatMessageType(packet.Type()), errs[0].Message)
+		}
+	}
+
+	var frame bytes.Buffer
+	if err := fusain.NewEncoder(&frame).WritePacket(packet); err != nil {
+		return err
+	}
+
+	fmt.Print(fusain.FormatPacketWithOptions(packet, formatOptions()))
+	fmt.Printf("  Frame: % X\n", frame.Bytes())
+	if sendDryRun {
+		return nil
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := fusain.NewClient(conn)
+	if sendWait <= 0 {
+		if err := client.Send(packet); err != nil {
+			return exitErrorf(ExitConnection, "send failed: %v", err)
+		}
+		fmt.Printf("Sent via %s\n", connInfo)
+		return nil
+	}
+	fmt.Printf("Sending via %s, waiting up to %v\n", connInfo, sendWait)
+
+	match, rejectionsOnly := responseMatcher(packet, expect)
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), sendWait)
+	defer cancel()
+	reply, err := client.Request(ctx, packet, match)
+
+	switch {
+	case err == nil:
+		fmt.Printf("\nResponse after %v:\n", time.Since(start).Round(time.Millisecond))
+		fmt.Print(fusain.FormatPacketWithOptions(reply, formatOptions()))
+		if reply.Type() == fusain.MsgErrorInvalidCmd || reply.Type() == fusain.MsgErrorStateReject {
+			fmt.Printf("Rejected: %s\n", describeErrorReply(reply))
+			return exitSilently(ExitFailure)
+		}
+		return nil
+
+	case errors.Is(err, context.DeadlineExceeded):
+		if rejectionsOnly {
+			fmt.Printf("\nAccepted (no rejection within %v)\n", sendWait)
+			return nil
+		}
+		return exitErrorf(ExitTimeout, "no response within %v", sendWait)
+
+	case errors.Is(err, fusain.ErrClientClosed):
+		return exitErrorf(ExitConnection, "connection lost: %v", client.Err())
+
+	default:
+		return exitErrorf(ExitConnection, "send failed: %v", err)
+	}
+}
+
+// buildSendPacket builds the packet described by the flags. The payload
+// goes through the JSON packet format, so values get their schema types.
+func buildSendPacket() (*fusain.Packet, error) {
+	address, err := parseSendAddress(sendAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{"address": fmt.Sprintf("%016X", address)}
+
+	payload := sendPayload
+	if sendPayloadFile != "" {
+		data, err := os.ReadFile(sendPayloadFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read payload file: %v", err)
+		}
+		if !strings.EqualFold(filepath.Ext(sendPayloadFile), ".json") {
+			msgType, _, err := fusain.ParseCBORMessage(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid CBOR message: %v", sendPayloadFile, err)
+			}
+			if sendType != "" {
+				if want, err := parseMessageTypeFlag(sendType); err != nil || want != msgType {
+					return nil, fmt.Errorf("--type %s does not match the CBOR message type %s", sendType, fusain.FormatMessageType(msgType))
+				}
+			}
+			doc["raw"] = hex.EncodeToString(data)
+			return unmarshalSendPacket(doc)
+		}
+		payload = string(data)
+	}
+
+	if sendType == "" {
+		return nil, fmt.Errorf("--type is required")
+	}
+	msgType, err := parseMessageTypeFlag(sendType)
+	if err != nil {
+		return nil, err
+	}
+	return packetFromPayload(address, msgType, payload)
+}
+
+// packetFromPayload builds a packet from a payload given as a JSON object
+// keyed by CBOR map key ("" for none)
+func packetFromPayload(address uint64, msgType uint8, payload string) (*fusain.Packet, error) {
+	doc := map[string]interface{}{
+		"address": fmt.Sprintf("%016X", address),
+		"type_id": msgType,
+	}
+	if strings.TrimSpace(payload) != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+			return nil, fmt.Errorf("invalid payload: expected a JSON object keyed by CBOR map key: %v", err)
+		}
+		doc["payload"] = fields
+	}
+	return unmarshalSendPacket(doc)
+}
+
+// unmarshalSendPacket builds a packet from its JSON form
+func unmarshalSendPacket(doc map[string]interface{}) (*fusain.Packet, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var packet fusain.Packet
+	if err := json.Unmarshal(data, &packet); err != nil {
+		return nil, err
+	}
+	return &packet, nil
+}
+
+// parseSendAddress parses a hex address or the name of a special address
+func parseSendAddress(s string) (uint64, error) {
+	switch strings.ToLower(s) {
+	case "broadcast":
+		return fusain.AddressBroadcast, nil
+	case "stateless":
+		return fusain.AddressStateless, nil
+	}
+	return parseAddress(s)
+}
+
+// parseMessageTypeFlag parses a message type name (STATE_COMMAND,
+// state-command) or number (32, 0x20)
+func parseMessageTypeFlag(s string) (uint8, error) {
+	if t, ok := fusain.ParseMessageType(s); ok {
+		return t, nil
+	}
+	n, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unknown message type %q", s)
+	}
+	return uint8(n), nil
+}
+
+// responseMatcher returns the reply filter for a sent packet. rejectionsOnly
+// is set when only an error reply is expected, so silence means accepted.
+func responseMatcher(sent *fusain.Packet, expect uint8) (match func(*fusain.Packet) bool, rejectionsOnly bool) {
+	fromTarget := func(p *fusain.Packet) bool {
+		return sent.IsBroadcast() || p.Address() == sent.Address()
+	}
+
+	if expect != 0 {
+		return func(p *fusain.Packet) bool { return p.Type() == expect && fromTarget(p) }, false
+	}
+
+	switch sent.Type() {
+	case fusain.MsgPingRequest:
+		return func(p *fusain.Packet) bool { return p.Type() == fusain.MsgPingResponse && fromTarget(p) }, false
+	case fusain.MsgDiscoveryRequest:
+		return func(p *fusain.Packet) bool { return p.Type() == fusain.MsgDeviceAnnounce }, false
+	case fusain.MsgSendTelemetry:
+		return func(p *fusain.Packet) bool {
+			return p.Type() >= fusain.MsgStateData && p.Type() <= fusain.MsgTempData && fromTarget(p)
+		}, false
+	}
+
+	return func(p *fusain.Packet) bool {
+		return (p.Type() == fusain.MsgErrorInvalidCmd || p.Type() == fusain.MsgErrorStateReject) && fromTarget(p)
+	}, true
+}
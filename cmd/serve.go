@@ -0,0 +1,517 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen       string
+	servePorts        []string
+	serveQuiet        bool
+	serveAllowOrigins []string
+)
+
+const (
+	// serveDiscoveryHoldoff limits how often a serial link is asked to
+	// re-announce its devices when an unknown address appears on it
+	serveDiscoveryHoldoff = time.Second
+
+	// serveClientQueue is how many packets may wait for a slow client
+	// before lossy ones are dropped
+	serveClientQueue = 256
+
+	// serveWriteTimeout bounds one write to a client
+	serveWriteTimeout = 5 * time.Second
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Act as a Fusain WebSocket router for serial devices",
+	Long: `Expose a Slate-compatible WebSocket endpoint backed by one or more serial
+connections, so bench setups can use WebSocket tools without a Slate.
+
+The router behaves like Slate:
+  - PING_REQUEST to the stateless address is answered by the router
+  - DISCOVERY_REQUEST to the stateless address is answered with a
+    DEVICE_ANNOUNCE for each known device, then the end-of-discovery marker
+  - DATA_SUBSCRIPTION / DATA_UNSUBSCRIBE (stateless or broadcast address)
+    start and stop telemetry forwarding for one appliance to that client
+  - Packets to a device address go to the serial link the device was seen
+    on (every link while it is unknown); broadcast packets go to every link
+  - Telemetry data goes to subscribed clients only; responses, errors and
+    announcements go to every client
+
+Devices are learned from DISCOVERY_REQUEST sent on each link at startup and
+from any packet they send afterwards.
+
+Commands from clients go through the --allow-device/--deny-device filter
+and the configured interlocks; rejected commands are logged and not
+forwarded. Each client has a send queue: when a client can't keep up, its
+packets are dropped rather than stalling the serial links, except
+emergency stops, which are always delivered.
+
+The router listens on localhost unless --listen says otherwise; add
+--tls-cert and --tls-key to serve wss://. Browser clients must be served
+from the router's own host or an --allow-origin.
+
+With users in the config file's "serve" section, clients must log in with
+HTTP Basic auth (heliostat's --username; password from FUSAIN_PASSWORD or
+a prompt). Each user has a role: operators may send any command, viewers
+only pings, discovery, SEND_TELEMETRY and subscriptions, so read-only
+dashboards can't change heater state:
+
+  "serve": {"users": [
+    {"username": "dashboard", "password_sha256": "<hex>", "role": "viewer"},
+    {"username": "bench", "password_sha256": "<hex>", "role": "operator"}
+  ]}
+
+password_sha256 is the hex SHA-256 of the password, e.g. from
+"printf %s PASSWORD | sha256sum".
+
+Examples:
+  heliostat serve --port /dev/ttyUSB0
+  heliostat serve --port /dev/ttyUSB0 --serial /dev/ttyUSB1 --listen :9000
+  heliostat serve --port /dev/ttyUSB0 --listen :8080 --allow-origin http://dashboard.local:3000
+  heliostat router_check --url ws://localhost:8080/ws`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveListen, "listen", "localhost:8080", "Address to serve WebSocket clients on")
+	serveCmd.Flags().StringArrayVar(&servePorts, "serial", nil, "Additional serial port to route (repeatable; uses --baud)")
+	serveCmd.Flags().BoolVarP(&serveQuiet, "quiet", "q", false, "Only print where the router is served")
+	serveCmd.Flags().StringArrayVar(&serveAllowOrigins, "allow-origin", nil, "Also accept browser clients from this origin (repeatable; * for any)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if wsURL != "" || tcpAddr != "" {
+		return fmt.Errorf("--url and --tcp connect to a server; serve routes serial ports given with --port and --serial")
+	}
+
+	var ports []string
+	if portName != "" {
+		ports = append(ports, portName)
+	}
+	ports = append(ports, servePorts...)
+	if len(ports) == 0 {
+		return fmt.Errorf("at least one serial port is required (--port or --serial)")
+	}
+
+	r := &router{
+		devices: make(map[uint64]*routerDevice),
+		clients: make(map[*routerClient]bool),
+		start:   time.Now(),
+	}
+	for _, name := range ports {
+		conn, err := OpenSerialConnection(name, baudRate)
+		if err != nil {
+			return exitErrorf(ExitConnection, "%v", err)
+		}
+		defer conn.Close()
+		r.links = append(r.links, &routerLink{name: name, conn: conn})
+	}
+
+	for _, link := range r.links {
+		fmt.Printf("Routing %s at %d baud\n", link.name, baudRate)
+		go r.readLink(link)
+		r.discover(link)
+	}
+
+	return r.listen(serveListen)
+}
+
+// serveLog prints a timestamped log line unless --quiet is set
+func serveLog(format string, args ...interface{}) {
+	if serveQuiet {
+		return
+	}
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+// routerLink is one serial connection to devices
+type routerLink struct {
+	name string
+	conn Connection
+
+	mu            sync.Mutex // Serializes writes from client goroutines
+	lastDiscovery time.Time
+}
+
+// send writes p to the link
+func (l *routerLink) send(p *fusain.Packet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := writePacket(l.conn, p); err != nil {
+		serveLog("%s: write failed: %v", l.name, err)
+	}
+}
+
+// routerDevice is a device seen on a link
+type routerDevice struct {
+	link     *routerLink
+	announce *fusain.DeviceAnnounce // nil until it sends DEVICE_ANNOUNCE
+}
+
+// routerClient is one WebSocket client
+type routerClient struct {
+	name          string
+	role          string // roleViewer or roleOperator
+	conn          Connection
+	queue         chan *fusain.Packet // Drained by writeLoop
+	done          chan struct{}       // Closed when the client disconnects
+	dropped       atomic.Uint64
+	subscriptions map[uint64]bool // Guarded by router.mu
+}
+
+func newRouterClient(name, role string, conn Connection) *routerClient {
+	return &routerClient{
+		name:          name,
+		role:          role,
+		conn:          conn,
+		queue:         make(chan *fusain.Packet, serveClientQueue),
+		done:          make(chan struct{}),
+		subscriptions: make(map[uint64]bool),
+	}
+}
+
+// send queues packets for the client. When the queue is full, packets are
+// dropped, except emergency stops, which wait for room (bounded by the
+// write timeout) so they are never lost.
+func (c *routerClient) send(packets ...*fusain.Packet) {
+	for _, p := range packets {
+		if p.IsEmergency() {
+			select {
+			case c.queue <- p:
+			case <-c.done:
+				return
+			}
+			continue
+		}
+		select {
+		case c.queue <- p:
+		default:
+			if c.dropped.Add(1) == 1 {
+				serveLog("Client %s is not keeping up, dropping packets", c.name)
+			}
+		}
+	}
+}
+
+// writeLoop writes queued packets until the client disconnects. A failed
+// or timed-out write closes the connection, which ends serveClient.
+func (c *routerClient) writeLoop() {
+	for {
+		select {
+		case p := <-c.queue:
+			if d, ok := c.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
+				d.SetWriteDeadline(time.Now().Add(serveWriteTimeout))
+			}
+			if err := writePacket(c.conn, p); err != nil {
+				serveLog("Client %s: write failed: %v", c.name, err)
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// router tracks devices per serial link and the telemetry subscriptions
+// of each WebSocket client
+type router struct {
+	links []*routerLink
+	start time.Time
+
+	mu      sync.Mutex
+	devices map[uint64]*routerDevice
+	clients map[*routerClient]bool
+}
+
+// listen accepts WebSocket clients on any path until the listener fails
+func (r *router) listen(addr string) error {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{WebSocketFrameProtocol},
+		CheckOrigin:  checkServeOrigin(serveAllowOrigins),
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, ok := appConfig().Serve.authenticate(req)
+		if !ok {
+			serveLog("Client %s rejected: invalid credentials", req.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Basic realm="heliostat"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		name := req.RemoteAddr
+		if user.Username != "" {
+			name = user.Username + "@" + req.RemoteAddr
+		}
+
+		ws, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			serveLog("Client %s rejected: %v", req.RemoteAddr, err)
+			return
+		}
+		conn := &WebSocketConnection{conn: ws, framed: ws.Subprotocol() == WebSocketFrameProtocol}
+		defer conn.Close()
+
+		r.serveClient(newRouterClient(name, user.Role, conn))
+	})
+
+	listener, err := listenServer(addr)
+	if err != nil {
+		return exitErrorf(ExitConnection, "cannot listen on %s: %v", addr, err)
+	}
+	fmt.Printf("Serving WebSocket on %s (%s)\n", addr, serverTransport())
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	if err := server.Serve(listener); err != nil {
+		return exitErrorf(ExitConnection, "cannot serve on %s: %v", addr, err)
+	}
+	return nil
+}
+
+// checkServeOrigin accepts clients that send no Origin (anything but a
+// browser), browsers on the router's own host, and the allowed origins, so
+// a web page elsewhere can't drive the heaters through a visitor's browser
+func checkServeOrigin(allowed []string) func(*http.Request) bool {
+	return func(req *http.Request) bool {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, req.Host)
+	}
+}
+
+// discover asks the devices on link to announce themselves
+func (r *router) discover(link *routerLink) {
+	link.mu.Lock()
+	link.lastDiscovery = time.Now()
+	link.mu.Unlock()
+	link.send(fusain.NewDiscoveryRequest(fusain.AddressBroadcast))
+}
+
+// readLink routes packets from a serial link until it is closed
+func (r *router) readLink(link *routerLink) {
+	decoder := fusain.NewDecoder()
+	buf := make([]byte, 256)
+	for {
+		n, err := link.conn.Read(buf)
+		if err != nil {
+			if err == ErrConnectionClosed {
+				serveLog("%s: connection closed", link.name)
+				return
+			}
+			// Transient error (e.g. serial); retry like the packet source
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		packets, _ := decoder.Decode(buf[:n])
+		for _, p := range packets {
+			r.fromLink(link, p)
+		}
+	}
+}
+
+// fromLink learns the sending device and forwards p to the clients that
+// should see it
+func (r *router) fromLink(link *routerLink, p *fusain.Packet) {
+	address := p.Address()
+	if address == fusain.AddressBroadcast || address == fusain.AddressStateless {
+		return
+	}
+
+	r.mu.Lock()
+	device, known := r.devices[address]
+	if !known {
+		device = &routerDevice{link: link}
+		r.devices[address] = device
+		serveLog("Device %s on %s", formatAddress(address), link.name)
+	} else if device.link != link {
+		serveLog("Device %s moved from %s to %s", formatAddress(address), device.link.name, link.name)
+		device.link = link
+	}
+
+	if p.Type() == fusain.MsgDeviceAnnounce {
+		if announce, err := fusain.DecodeDeviceAnnounce(p); err == nil && !announce.IsEndMarker() {
+			device.announce = &announce
+		}
+	}
+	needsAnnounce := device.announce == nil
+
+	var recipients []*routerClient
+	telemetry := p.Type() >= fusain.MsgStateData && p.Type() <= fusain.MsgTempData
+	for client := range r.clients {
+		if !telemetry || client.subscriptions[address] {
+			recipients = append(recipients, client)
+		}
+	}
+	r.mu.Unlock()
+
+	if needsAnnounce {
+		link.mu.Lock()
+		due := time.Since(link.lastDiscovery) >= serveDiscoveryHoldoff
+		link.mu.Unlock()
+		if due {
+			r.discover(link)
+		}
+	}
+
+	for _, client := range recipients {
+		client.send(p)
+	}
+}
+
+// serveClient handles packets from a WebSocket client until it disconnects
+func (r *router) serveClient(client *routerClient) {
+	r.mu.Lock()
+	r.clients[client] = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.clients, client)
+		r.mu.Unlock()
+		close(client.done)
+		if n := client.dropped.Load(); n > 0 {
+			serveLog("Client %s: %d packets dropped", client.name, n)
+		}
+	}()
+	go client.writeLoop()
+
+	serveLog("Client connected: %s (%s)", client.name, client.role)
+	decoder := fusain.NewDecoder()
+	buf := make([]byte, 256)
+	for {
+		n, err := client.conn.Read(buf)
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) || errors.Is(err, ErrConnectionClosed) {
+				serveLog("Client disconnected: %s", client.name)
+			} else {
+				serveLog("Client %s: read failed: %v", client.name, err)
+			}
+			return
+		}
+
+		packets, _ := decoder.Decode(buf[:n])
+		for _, p := range packets {
+			r.fromClient(client, p)
+		}
+	}
+}
+
+// fromClient answers packets addressed to the router and forwards the rest
+// to the serial links
+func (r *router) fromClient(client *routerClient, p *fusain.Packet) {
+	address := p.Address()
+	if err := checkRole(client.role, p); err != nil {
+		serveLog("Client %s: %v", client.name, err)
+		return
+	}
+
+	switch p.Type() {
+	case fusain.MsgDataSubscription, fusain.MsgDataUnsubscribe:
+		if address == fusain.AddressStateless || address == fusain.AddressBroadcast {
+			r.subscribe(client, p)
+			return
+		}
+	}
+
+	if address == fusain.AddressStateless {
+		switch p.Type() {
+		case fusain.MsgPingRequest:
+			uptime := uint64(time.Since(r.start).Milliseconds())
+			client.send(fusain.PingResponse{Uptime: uptime}.Encode(fusain.AddressStateless))
+		case fusain.MsgDiscoveryRequest:
+			client.send(r.announcements()...)
+		default:
+			serveLog("Client %s: ignoring %s to the stateless address", client.name, fusain.FormatMessageType(p.Type()))
+		}
+		return
+	}
+
+	if isCommand(p) {
+		if err := checkCommandPolicy(p); err != nil {
+			serveLog("Client %s: %v", client.name, err)
+			return
+		}
+	}
+
+	r.mu.Lock()
+	device := r.devices[address]
+	r.mu.Unlock()
+
+	if device != nil {
+		device.link.send(p)
+		return
+	}
+	for _, link := range r.links {
+		link.send(p)
+	}
+}
+
+// subscribe applies a DATA_SUBSCRIPTION or DATA_UNSUBSCRIBE from client
+func (r *router) subscribe(client *routerClient, p *fusain.Packet) {
+	appliance, ok := fusain.GetMapUint(p.PayloadMap(), 0)
+	if !ok {
+		serveLog("Client %s: %s without an appliance address", client.name, fusain.FormatMessageType(p.Type()))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p.Type() == fusain.MsgDataSubscription {
+		client.subscriptions[appliance] = true
+		serveLog("Client %s subscribed to %s", client.name, formatAddress(appliance))
+	} else {
+		delete(client.subscriptions, appliance)
+		serveLog("Client %s unsubscribed from %s", client.name, formatAddress(appliance))
+	}
+}
+
+// announcements returns a DEVICE_ANNOUNCE for each device that has
+// announced itself, in address order, followed by the end-of-discovery
+// marker
+func (r *router) announcements() []*fusain.Packet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var addresses []uint64
+	for address, device := range r.devices {
+		if device.announce != nil {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+
+	packets := make([]*fusain.Packet, 0, len(addresses)+1)
+	for _, address := range addresses {
+		packets = append(packets, r.devices[address].announce.Encode(address))
+	}
+	return append(packets, fusain.DeviceAnnounce{}.Encode(fusain.AddressStateless))
+}
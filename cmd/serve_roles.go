@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// Roles a serve user can have
+const (
+	roleViewer   = "viewer"   // Telemetry, discovery and pings only
+	roleOperator = "operator" // Any command
+)
+
+// ServeConfig configures 'heliostat serve'
+type ServeConfig struct {
+	// Users lists the HTTP Basic auth credentials clients must present.
+	// Without any, clients are not authenticated and act as operators.
+	Users []ServeUser `json:"users,omitempty"`
+}
+
+// ServeUser is one set of serve credentials and the role they grant
+type ServeUser struct {
+	Username       string `json:"username"`
+	PasswordSHA256 string `json:"password_sha256"` // Hex SHA-256 of the password
+	Role           string `json:"role"`            // viewer or operator
+}
+
+func (c ServeConfig) validate() error {
+	seen := make(map[string]bool)
+	for i, u := range c.Users {
+		if u.Username == "" {
+			return fmt.Errorf("users[%d]: username is required", i)
+		}
+		if seen[u.Username] {
+			return fmt.Errorf("users[%d]: duplicate username %q", i, u.Username)
+		}
+		seen[u.Username] = true
+		if sum, err := hex.DecodeString(u.PasswordSHA256); err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("users[%d]: password_sha256 must be 64 hex digits", i)
+		}
+		if u.Role != roleViewer && u.Role != roleOperator {
+			return fmt.Errorf("users[%d]: unknown role %q (valid: viewer, operator)", i, u.Role)
+		}
+	}
+	return nil
+}
+
+// authenticate returns the user whose credentials req carries. With no
+// users configured every request is accepted as an anonymous operator.
+func (c ServeConfig) authenticate(req *http.Request) (ServeUser, bool) {
+	if len(c.Users) == 0 {
+		return ServeUser{Role: roleOperator}, true
+	}
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return ServeUser{}, false
+	}
+	sum := sha256.Sum256([]byte(password))
+	for _, u := range c.Users {
+		want, _ := hex.DecodeString(u.PasswordSHA256)
+		if subtle.ConstantTimeCompare([]byte(u.Username), []byte(username)) == 1 &&
+			subtle.ConstantTimeCompare(sum[:], want) == 1 {
+			return u, true
+		}
+	}
+	return ServeUser{}, false
+}
+
+// checkRole reports whether a client with role may send p. Viewers may
+// only ask for data: pings, discovery, telemetry requests and
+// subscriptions; every other packet type is refused. Emergency stops are
+// commands like any other, so a read-only dashboard can't send them either.
+func checkRole(role string, p *fusain.Packet) error {
+	if role == roleOperator {
+		return nil
+	}
+	switch p.Type() {
+	case fusain.MsgPingRequest, fusain.MsgDiscoveryRequest, fusain.MsgSendTelemetry,
+		fusain.MsgDataSubscription, fusain.MsgDataUnsubscribe:
+		return nil
+	}
+	return fmt.Errorf("%s rejected: viewers may only request data", fusain.FormatMessageType(p.Type()))
+}
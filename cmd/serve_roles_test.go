@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func serveUser(username, password, role string) ServeUser {
+	sum := sha256.Sum256([]byte(password))
+	return ServeUser{Username: username, PasswordSHA256: hex.EncodeToString(sum[:]), Role: role}
+}
+
+func TestServeConfigAuthenticate(t *testing.T) {
+	cfg := ServeConfig{Users: []ServeUser{
+		serveUser("dashboard", "view", roleViewer),
+		serveUser("bench", "operate", roleOperator),
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		username, password string
+		role               string // "" = rejected
+	}{
+		{"dashboard", "view", roleViewer},
+		{"bench", "operate", roleOperator},
+		{"bench", "view", ""},
+		{"nobody", "view", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		req.SetBasicAuth(tt.username, tt.password)
+		user, ok := cfg.authenticate(req)
+		if ok != (tt.role != "") || user.Role != tt.role {
+			t.Errorf("%s/%s: got role %q ok=%v, want %q", tt.username, tt.password, user.Role, ok, tt.role)
+		}
+	}
+
+	if _, ok := cfg.authenticate(httptest.NewRequest("GET", "/ws", nil)); ok {
+		t.Error("request without credentials accepted")
+	}
+	if user, ok := (ServeConfig{}).authenticate(httptest.NewRequest("GET", "/ws", nil)); !ok || user.Role != roleOperator {
+		t.Error("without users, clients should be anonymous operators")
+	}
+}
+
+func TestCheckRole(t *testing.T) {
+	tests := []struct {
+		packet *fusain.Packet
+		viewer bool // Allowed for viewers; operators may send anything
+	}{
+		{fusain.NewPingRequest(1), true},
+		{fusain.NewDiscoveryRequest(fusain.AddressStateless), true},
+		{fusain.NewDataSubscription(fusain.AddressStateless, 1), true},
+		{fusain.NewDataUnsubscribe(fusain.AddressStateless, 1), true},
+		{fusain.NewSendTelemetry(1, fusain.TelemetryTypeState, 0), true},
+		{fusain.NewStateCommand(1, uint8(fusain.ModeEmergency), nil), false},
+		{fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil), false},
+		{fusain.NewMotorCommand(1, 0, 2000), false},
+		// Not commands, but not data requests either
+		{stateData(1, fusain.SysStateIdle), false},
+		{fusain.PingResponse{}.Encode(1), false},
+		{fusain.NewPacketWithPayload(1, 0x7F, nil), false},
+	}
+	for _, tt := range tests {
+		name := fusain.FormatMessageType(tt.packet.Type())
+		if err := checkRole(roleOperator, tt.packet); err != nil {
+			t.Errorf("operator %s: %v", name, err)
+		}
+		if err := checkRole(roleViewer, tt.packet); (err == nil) != tt.viewer {
+			t.Errorf("viewer %s: got %v, want allowed=%v", name, err, tt.viewer)
+		}
+	}
+}
+
+func TestRouterFromClientRoles(t *testing.T) {
+	conn := &bufferConn{}
+	r := &router{
+		links:   []*routerLink{{name: "test", conn: conn}},
+		devices: make(map[uint64]*routerDevice),
+		clients: make(map[*routerClient]bool),
+	}
+	viewer := newRouterClient("viewer", roleViewer, &bufferConn{})
+	operator := newRouterClient("operator", roleOperator, &bufferConn{})
+	sent := func() int {
+		packets, _ := fusain.NewDecoder().Decode(conn.Bytes())
+		conn.Reset()
+		return len(packets)
+	}
+
+	tests := []struct {
+		client *routerClient
+		packet *fusain.Packet
+		sent   bool
+	}{
+		{viewer, fusain.NewPingRequest(1), true},
+		{viewer, fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil), false},
+		{viewer, stateData(1, fusain.SysStateIdle), false},
+		{operator, fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil), true},
+		{operator, stateData(1, fusain.SysStateIdle), true},
+	}
+	for _, tt := range tests {
+		r.fromClient(tt.client, tt.packet)
+		if got := sent() == 1; got != tt.sent {
+			t.Errorf("%s %s: forwarded=%v, want %v", tt.client.role, fusain.FormatMessageType(tt.packet.Type()), got, tt.sent)
+		}
+	}
+}
+
+func TestServeConfigValidate(t *testing.T) {
+	bad := []ServeConfig{
+		{Users: []ServeUser{{Username: "", PasswordSHA256: serveUser("x", "y", roleViewer).PasswordSHA256, Role: roleViewer}}},
+		{Users: []ServeUser{{Username: "a", PasswordSHA256: "abc", Role: roleViewer}}},
+		{Users: []ServeUser{serveUser("a", "b", "admin")}},
+		{Users: []ServeUser{serveUser("a", "b", roleViewer), serveUser("a", "c", roleOperator)}},
+	}
+	for i, cfg := range bad {
+		if cfg.validate() == nil {
+			t.Errorf("config %d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckServeOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string // "" = no Origin header
+		allowed []string
+		ok      bool
+	}{
+		{"no origin", "", nil, true},
+		{"same origin", "http://bench.local:8080", nil, true},
+		{"same origin, other case", "http://BENCH.local:8080", nil, true},
+		{"same host, other port", "http://bench.local:9000", nil, false},
+		{"cross origin", "http://evil.example", nil, false},
+		{"allowed cross origin", "https://dash.example", []string{"https://dash.example/"}, true},
+		{"other cross origin", "https://evil.example", []string{"https://dash.example"}, false},
+		{"any origin", "https://evil.example", []string{"*"}, true},
+		{"malformed", "://", nil, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://bench.local:8080/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := checkServeOrigin(tt.allowed)(req); got != tt.ok {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.ok)
+		}
+	}
+}
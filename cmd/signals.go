@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shutdownTimeout bounds how long shutdown hooks may delay exit
+const shutdownTimeout = 3 * time.Second
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func()
+	activeConn    Connection
+
+	// shuttingDown is set once shutdown has started, so the connection it
+	// closes is not reported as lost
+	shuttingDown atomic.Bool
+
+	// Shutdown sequence selected by --on-shutdown
+	onShutdownNames []string
+	shutdownPackets []*fusain.Packet
+)
+
+// onShutdown registers fn to run when the process is stopped by a signal.
+// Hooks run in reverse registration order, so a TUI registered after its
+// connection is torn down before it. Use this to restore the terminal or
+// flush buffered output.
+func onShutdown(fn func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// onShutdownProgram restores the terminal for a bubbletea program on signal.
+// Programs should be created with tea.WithoutSignalHandler so this handler
+// owns SIGINT/SIGTERM.
+func onShutdownProgram(p *tea.Program) {
+	onShutdown(func() {
+		p.Kill()
+		p.Wait()
+	})
+}
+
+// trackConnection records the most recently opened connection so the
+// shutdown sequence can be sent over it
+func trackConnection(conn Connection) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	activeConn = conn
+}
+
+// parseShutdownSequence converts --on-shutdown names into broadcast packets
+func parseShutdownSequence(names []string) ([]*fusain.Packet, error) {
+	packets := make([]*fusain.Packet, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "idle":
+			packets = append(packets, fusain.NewStateCommand(fusain.AddressBroadcast, uint8(fusain.ModeIdle), nil))
+		case "estop", "emergency":
+			packets = append(packets, fusain.NewStateCommand(fusain.AddressBroadcast, uint8(fusain.ModeEmergency), nil))
+		default:
+			return nil, fmt.Errorf("unknown shutdown command %q (valid: idle, estop)", name)
+		}
+	}
+	return packets, nil
+}
+
+// handleSignals installs the process-wide handler for SIGINT, SIGTERM and
+// SIGHUP. On signal it sends the --on-shutdown sequence, runs the shutdown
+// hooks, closes the active connection and exits with ExitUserAbort (SIGINT)
+// or ExitTerminated (SIGTERM/SIGHUP). In long-running modes SIGHUP reloads
+// the configuration instead (see reloadConfig).
+func handleSignals() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-sigChan
+		for sig == syscall.SIGHUP && reloadOnHangup.Load() {
+			reloadOnSignal()
+			sig = <-sigChan
+		}
+
+		code := ExitTerminated
+		if sig == os.Interrupt {
+			code = ExitUserAbort
+		}
+
+		done := make(chan struct{})
+		go func() {
+			shutdown()
+			close(done)
+		}()
+
+		// A second signal or a stuck hook must not keep the process alive
+		select {
+		case <-done:
+		case <-sigChan:
+		case <-time.After(shutdownTimeout):
+		}
+		os.Exit(code)
+	}()
+}
+
+// shutdown performs the orderly teardown for handleSignals
+func shutdown() {
+	shuttingDown.Store(true)
+
+	shutdownMu.Lock()
+	conn := activeConn
+	shutdownMu.Unlock()
+
+	if conn != nil {
+		for _, packet := range shutdownPackets {
+			writePacket(conn, packet)
+		}
+	}
+
+	runShutdownHooks()
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// runShutdownHooks runs and clears the registered hooks. Execute also
+// calls it when a command returns normally.
+func runShutdownHooks() {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
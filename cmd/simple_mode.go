@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// simpleStatus is the one-line summary redrawn by --simple mode
+type simpleStatus struct {
+	stats   *fusain.Statistics
+	states  map[uint64]fusain.SysState
+	lastLen int // Length of the last status line, to blank it out
+	repeats logRepeats
+}
+
+// line renders the status line (plain ASCII, no escape sequences)
+func (s *simpleStatus) line(now time.Time) string {
+	s.stats.CalculateRates()
+	totalErrors := s.stats.CRCErrors + s.stats.DecodeErrors + s.stats.MalformedPackets + s.stats.AnomalousValues
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s pkts=%d valid=%d errors=%d rate=%.1f/s err_rate=%.1f/s",
+		now.Format("15:04:05"), s.stats.TotalPackets, s.stats.ValidPackets, totalErrors,
+		s.stats.PacketRate, s.stats.ErrorRate)
+	for _, address := range s.stats.Devices() {
+		if state, ok := s.states[address]; ok {
+			fmt.Fprintf(&b, " %s=%s", deviceLabel(address), fusain.FormatState(uint32(state)))
+		}
+	}
+	return b.String()
+}
+
+// redraw overwrites the status line with a carriage return
+func (s *simpleStatus) redraw(now time.Time) {
+	line := s.line(now)
+	pad := max(s.lastLen-len(line), 0)
+	fmt.Printf("\r%s%s", line, strings.Repeat(" ", pad))
+	s.lastLen = len(line)
+}
+
+// printError prints an error line stamped with at, collapsing runs of the
+// same message (see logRepeats)
+func (s *simpleStatus) printError(at time.Time, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	repeat, summary := s.repeats.add(message, at)
+	if summary != "" {
+		s.println("%s", summary)
+	}
+	if !repeat {
+		s.println("%s %s", at.Format("15:04:05.000"), message)
+	}
+}
+
+// println prints a message on its own line above the status line
+func (s *simpleStatus) println(format string, args ...interface{}) {
+	fmt.Printf("\r%s\r", strings.Repeat(" ", s.lastLen))
+	fmt.Printf(format+"\n", args...)
+	s.lastLen = 0
+}
+
+// runSimpleMode runs error detection with a one-line summary refreshed with
+// carriage returns, for serial consoles and CI logs that garble TUIs.
+// Errors are printed on their own lines above it.
+func runSimpleMode(conn ByteReader, connInfo string) error {
+	fmt.Printf("Heliostat - Error Detection Mode (%s)\n", connInfo)
+
+	status := &simpleStatus{
+		stats:  newStatistics(),
+		states: make(map[uint64]fusain.SysState),
+	}
+	synchronized := false
+
+	ticker := time.NewTicker(simpleRefresh)
+	defer ticker.Stop()
+
+	sub := eventBus.SubscribeLossless(256)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, true).run(conn, done)
+
+	for {
+		select {
+		case e := <-sub.Events():
+			switch e := e.(type) {
+			case events.DecodeError:
+				if synchronized {
+					status.stats.Update(nil, e.Err, nil)
+					status.printError(e.At, "DECODE ERROR: %v", e.Err)
+				}
+
+			case events.Synchronized:
+				synchronized = true
+
+			case events.PacketReceived:
+				status.stats.Update(e.Packet, nil, e.Anomalies)
+				for _, anomaly := range e.Anomalies {
+					status.printError(e.At, "%s %s: %s",
+						fusain.FormatMessageType(e.Packet.Type()), formatAddress(e.Packet.Address()), anomaly.Message)
+				}
+
+			case events.DeviceStateChanged:
+				status.states[e.Address] = e.State
+
+			case events.DeviceStale:
+				status.stats.RecordStale([]fusain.ValidationError{e.Anomaly})
+				status.printError(e.At, "STALE DEVICE: %s", e.Anomaly.Message)
+
+			case events.AlertRaised, events.AlertCleared:
+				status.printError(e.Time(), "%s", alertLogMessage(e))
+
+			case events.ConnectionLost:
+				if summary := status.repeats.flush(); summary != "" {
+					status.println("%s", summary)
+				}
+				status.println("%s", connectionLostMessage(e))
+				if !e.Reconnecting {
+					return nil
+				}
+
+			case events.ConnectionRestored:
+				status.println("%s", connectionRestoredMessage(e))
+			}
+
+		case now := <-ticker.C:
+			status.redraw(now)
+		}
+	}
+}
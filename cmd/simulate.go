@@ -0,0 +1,481 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+var (
+	simulateAddress   string
+	simulatePTY       bool
+	simulateListen    string
+	simulateTelemetry time.Duration
+	simulateQuiet     bool
+	simulateSeed      uint64
+	simulateScenario  string
+	simulateDevices   int
+	simulateRouter    bool
+	simulateSpeed     float64
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Emulate a Helios appliance",
+	Long: `Run a virtual Helios ICU so controllers and heliostat itself can be
+developed without hardware.
+
+The simulated appliance has one motor, thermometer, pump and glow plug. It
+answers DISCOVERY_REQUEST (broadcast only, after a random 0-50ms delay),
+PING_REQUEST, SEND_TELEMETRY, TELEMETRY_CONFIG and TIMEOUT_CONFIG, and
+follows STATE_COMMAND through the appliance state machine:
+
+  FAN        IDLE/COOLING -> BLOWING (argument: RPM, default 2000)
+  HEAT       IDLE/BLOWING/COOLING -> PREHEAT -> PREHEAT_STAGE_2 -> HEATING
+             (argument: pump rate in ms, default 200)
+  IDLE       heating states -> COOLING -> IDLE; clears ERROR and E_STOP
+  EMERGENCY  any state -> E_STOP
+
+Commands the current state does not accept get ERROR_STATE_REJECT; bad
+values and component indexes get ERROR_INVALID_CMD. Telemetry (STATE, MOTOR,
+TEMP and GLOW data every --telemetry-interval, PUMP_DATA per pump cycle and
+STATE_DATA on every transition) follows simple physics: motor RPM ramps
+toward its target and the temperature rises with the fuel rate and falls
+back to ambient.
+
+The discovery delays and the telemetry noise come from a random seed, which
+is printed at startup (even with --quiet). Pass it back with --seed to
+repeat the same sequence when a test driven by the simulator fails.
+
+Simulated time advances in fixed 20ms steps, independent of how promptly
+the host runs them, so the same seed, scenario and commands give the same
+telemetry, device timestamps included. --speed runs the steps faster (or
+slower) than real time, e.g. --speed 10 plays a ten-minute soak in one
+minute; reply delays scale with it.
+
+--scenario FILE scripts telemetry curves and faults from a YAML scenario
+file. Curves replace the physics of one metric with a profile over time,
+linear between points; a curve with a state starts each time the appliance
+enters it, and the first matching curve for a metric applies:
+
+  curves:
+    - metric: temp         # temp (°C) or rpm
+      state: PREHEAT_STAGE_2
+      points:              # ignition once temp reaches 80°C
+        - {at: 0s, value: 20}
+        - {at: 8s, value: 85}
+    - metric: rpm          # RPM settling with an overshoot
+      state: HEATING
+      points:
+        - {at: 0s, value: 1800}
+        - {at: 1s, value: 3400}
+        - {at: 3s, value: 3000}
+
+Faults exercise error_detection and the control TUI against realistic
+failures, and take precedence over curves:
+
+  faults:
+    - fault: overheat      # temperature ramps while burning -> OVERHEAT
+      state: HEATING       # start once in this state (default: at startup)
+      after: 20s           # ...for this long
+      rate: 8              # °C per second (default 5)
+    - fault: motor_stall   # rotor blocked -> MOTOR_STALL after 2s
+      after: 2m
+      duration: 30s        # how long the fault lasts (default: for good)
+    - fault: flame_out     # fuel stops burning -> FLAME_OUT when HEATING
+      state: HEATING       # drops below 60°C
+      after: 1m
+    - fault: delay         # replies are held back
+      delay: 800ms
+    - fault: garbage       # random bytes between frames
+      rate: 5              # bursts per second (default 5)
+      bytes: 16            # bytes per burst (default 8)
+      device: "2"          # only this appliance (default: all of --devices)
+
+Each fault happens once. The protections trip at 260°C (OVERHEAT), after
+2s below the minimum RPM (MOTOR_STALL) and below 60°C while HEATING after a
+flame-out (FLAME_OUT); IDLE clears the error as usual.
+
+--devices N simulates N appliances at consecutive addresses from --addr, on
+one shared connection. --router puts them behind a simulated Slate-style
+router, for multi-device control without a Slate:
+  - PING_REQUEST to the stateless address is answered by the router
+  - DISCOVERY_REQUEST to the stateless address is answered with a
+    DEVICE_ANNOUNCE per appliance, then the end-of-discovery marker from
+    the stateless address; broadcast discovery still reaches the
+    appliances, which answer themselves
+  - DATA_SUBSCRIPTION / DATA_UNSUBSCRIBE (stateless or broadcast address)
+    start and stop an appliance's telemetry for that client; without a
+    subscription a client gets no telemetry data, only responses, errors
+    and announcements
+Without --router every client gets everything, as on a serial bus.
+
+The appliance is served on one of:
+  --port PORT    a serial port (e.g. one end of a null-modem cable)
+  --pty          a new pseudo-terminal; connect to the printed path (Linux)
+  --listen ADDR  a WebSocket server; telemetry goes to every client
+
+Examples:
+  heliostat simulate --pty
+  heliostat error_detection --port /dev/pts/5
+
+  heliostat simulate --listen :8080 --addr 0123456789ABCDEF
+  heliostat control --url ws://localhost:8080/ws
+
+  heliostat simulate --pty --seed 0x5eed
+  heliostat simulate --pty --scenario overheat.yaml
+  heliostat simulate --pty --scenario soak.yaml --seed 42 --speed 10
+
+  heliostat simulate --listen :8080 --devices 3 --router
+  heliostat control --url ws://localhost:8080/ws`,
+	Args: cobra.NoArgs,
+	RunE: runSimulate,
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+	simulateCmd.Flags().StringVar(&simulateAddress, "addr", "0000000000000001", "Appliance address (hex); with --devices, the first one")
+	simulateCmd.Flags().BoolVar(&simulatePTY, "pty", false, "Serve on a new pseudo-terminal (Linux)")
+	simulateCmd.Flags().StringVar(&simulateListen, "listen", "", "Serve as a WebSocket server on this address (e.g. :8080)")
+	simulateCmd.Flags().DurationVar(&simulateTelemetry, "telemetry-interval", 500*time.Millisecond, "Initial telemetry interval (0 = off until TELEMETRY_CONFIG)")
+	simulateCmd.Flags().BoolVarP(&simulateQuiet, "quiet", "q", false, "Only print the seed and where the appliance is served")
+	simulateCmd.Flags().Uint64Var(&simulateSeed, "seed", 0, "Seed for discovery delays, telemetry noise and garbage bytes (0 = random)")
+	simulateCmd.Flags().StringVar(&simulateScenario, "scenario", "", "Inject the faults of this scenario file (YAML; see above)")
+	simulateCmd.Flags().IntVar(&simulateDevices, "devices", 1, "Number of appliances, at consecutive addresses from --addr")
+	simulateCmd.Flags().Float64Var(&simulateSpeed, "speed", 1, "Simulated time per real time (e.g. 10 = ten times faster)")
+	simulateCmd.Flags().BoolVar(&simulateRouter, "router", false, "Serve the appliances behind a simulated router (stateless address, subscriptions)")
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	address, err := parseAddress(simulateAddress)
+	if err != nil {
+		return err
+	}
+	if simulateSpeed < simMinSpeed || simulateSpeed > simMaxSpeed {
+		return fmt.Errorf("--speed must be between %g and %g", simMinSpeed, simMaxSpeed)
+	}
+	if simulateDevices < 1 || simulateDevices > simMaxDevices {
+		return fmt.Errorf("--devices must be between 1 and %d", simMaxDevices)
+	}
+	last := address + uint64(simulateDevices-1)
+	if address == fusain.AddressBroadcast || last < address || last >= fusain.AddressStateless {
+		return fmt.Errorf("--addr and --devices cannot include the broadcast or stateless address")
+	}
+	if wsURL != "" || tcpAddr != "" {
+		return fmt.Errorf("--url and --tcp connect to a server; use --listen to serve the simulator over WebSocket")
+	}
+
+	transports := 0
+	for _, set := range []bool{portName != "", simulatePTY, simulateListen != ""} {
+		if set {
+			transports++
+		}
+	}
+	if transports != 1 {
+		return fmt.Errorf("exactly one of --port, --pty or --listen is required")
+	}
+
+	var scenario *simScenario
+	if simulateScenario != "" {
+		if scenario, err = loadSimScenario(simulateScenario); err != nil {
+			return err
+		}
+	}
+
+	seed := simulateSeed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	start := time.Now()
+	hub := &simHub{router: simulateRouter, clock: newSimClock(start, simulateSpeed), peers: make(map[*simPeer]bool)}
+	for i := 0; i < simulateDevices; i++ {
+		app := newSimAppliance(address+uint64(i), simulateTelemetry, start, seed)
+		app.curves = newSimCurves(scenario, app.address)
+		app.faults = newSimFaults(scenario, app.address)
+		hub.apps = append(hub.apps, app)
+	}
+	go hub.run()
+
+	if simulateDevices == 1 {
+		simLog("Simulating Helios %016X", address)
+	} else {
+		simLog("Simulating %d Helios appliances, %016X to %016X", simulateDevices, address, last)
+	}
+	if simulateRouter {
+		simLog("Behind a simulated router at the stateless address")
+	}
+	if scenario != nil {
+		simLog("Scenario %s: %d curves, %d faults", simulateScenario, len(scenario.Curves), len(scenario.Faults))
+	}
+	if simulateSpeed != 1 {
+		simLog("Running at %gx speed", simulateSpeed)
+	}
+	// Printed even with --quiet: it's what reproduces a failing run
+	fmt.Printf("Seed %d\n", seed)
+
+	switch {
+	case simulatePTY:
+		conn, path, err := openPTY()
+		if err != nil {
+			return exitErrorf(ExitConnection, "cannot create pty: %v", err)
+		}
+		defer conn.Close()
+		fmt.Printf("Serving on %s\n", path)
+		return hub.serve(&simPeer{conn: conn})
+
+	case simulateListen != "":
+		return serveSimulatorWebSocket(hub, simulateListen)
+
+	default:
+		conn, err := OpenSerialConnection(portName, baudRate)
+		if err != nil {
+			return exitErrorf(ExitConnection, "%v", err)
+		}
+		defer conn.Close()
+		fmt.Printf("Serving on %s at %d baud\n", portName, baudRate)
+		return hub.serve(&simPeer{conn: conn})
+	}
+}
+
+// simLog prints a timestamped log line unless --quiet is set
+func simLog(format string, args ...interface{}) {
+	if simulateQuiet {
+		return
+	}
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+// serveSimulatorWebSocket accepts WebSocket clients on any path until the
+// listener fails
+func serveSimulatorWebSocket(hub *simHub, addr string) error {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{WebSocketFrameProtocol},
+		CheckOrigin:  func(r *http.Request) bool { return true },
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := &WebSocketConnection{conn: ws, framed: ws.Subprotocol() == WebSocketFrameProtocol}
+		defer conn.Close()
+
+		simLog("Client connected: %s", r.RemoteAddr)
+		hub.serve(&simPeer{conn: conn})
+		simLog("Client disconnected: %s", r.RemoteAddr)
+	})
+
+	listener, err := listenServer(addr)
+	if err != nil {
+		return exitErrorf(ExitConnection, "cannot listen on %s: %v", addr, err)
+	}
+	fmt.Printf("Serving WebSocket on %s (%s)\n", addr, serverTransport())
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	if err := server.Serve(listener); err != nil {
+		return exitErrorf(ExitConnection, "cannot serve on %s: %v", addr, err)
+	}
+	return nil
+}
+
+// simPeer is one connection to the simulated appliances
+type simPeer struct {
+	mu   sync.Mutex // Serializes replies and broadcast telemetry
+	conn Connection
+
+	// Appliances whose telemetry the peer gets behind the router; guarded
+	// by simHub.mu
+	subscriptions map[uint64]bool
+}
+
+// send writes raw bytes, then packets, to the peer
+func (p *simPeer) send(noise []byte, packets []*fusain.Packet) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(noise) > 0 {
+		if _, err := p.conn.Write(noise); err != nil {
+			return err
+		}
+	}
+	for _, packet := range packets {
+		if err := writePacket(p.conn, packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simStep is how far simulated time advances per tick
+const simStep = 20 * time.Millisecond
+
+// --speed limits
+const (
+	simMinSpeed = 0.1
+	simMaxSpeed = 100.0
+)
+
+// simClock is the simulated time. It only advances by whole steps, so the
+// appliances see the same times whatever the host's scheduling.
+type simClock struct {
+	mu    sync.Mutex
+	start time.Time
+	now   time.Time
+	speed float64
+}
+
+func newSimClock(start time.Time, speed float64) *simClock {
+	return &simClock{start: start, now: start, speed: speed}
+}
+
+// Now returns the current simulated time
+func (c *simClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// advance moves the clock one step and returns the new time
+func (c *simClock) advance() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(simStep)
+	return c.now
+}
+
+// real converts a simulated duration to real time
+func (c *simClock) real(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / c.speed)
+}
+
+// simHub connects the appliances to every peer, directly or through the
+// simulated router
+type simHub struct {
+	apps   []*simAppliance
+	router bool
+	clock  *simClock
+
+	mu    sync.Mutex
+	peers map[*simPeer]bool
+}
+
+// run advances the appliances a step per tick and delivers their telemetry
+func (h *simHub) run() {
+	ticker := time.NewTicker(h.clock.real(simStep))
+	defer ticker.Stop()
+
+	lastStates := make([]fusain.SysState, len(h.apps))
+	for i, app := range h.apps {
+		lastStates[i], _, _ = app.status()
+	}
+	for range ticker.C {
+		h.step(lastStates)
+	}
+}
+
+// step advances the clock and the appliances one step. lastStates holds
+// each appliance's state for the transition log.
+func (h *simHub) step(lastStates []fusain.SysState) {
+	now := h.clock.advance()
+	for i, app := range h.apps {
+		packets, noise := app.tick(now)
+
+		prefix := ""
+		if len(h.apps) > 1 {
+			prefix = fmt.Sprintf("%016X: ", app.address)
+		}
+		for _, line := range app.takeLogs() {
+			simLog("%s%s", prefix, line)
+		}
+		if state, rpm, temp := app.status(); state != lastStates[i] {
+			simLog("%s%s -> %s (%.0f RPM, %.1f°C)", prefix, fusain.FormatState(uint32(lastStates[i])), fusain.FormatState(uint32(state)), rpm, temp)
+			lastStates[i] = state
+		}
+
+		if len(packets) > 0 || len(noise) > 0 {
+			h.broadcast(noise, packets)
+		}
+	}
+}
+
+// broadcast sends appliance output to every peer; behind the router,
+// telemetry data only goes to subscribed peers
+func (h *simHub) broadcast(noise []byte, packets []*fusain.Packet) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for peer := range h.peers {
+		visible := packets
+		if h.router {
+			visible = nil
+			for _, p := range packets {
+				telemetry := p.Type() >= fusain.MsgStateData && p.Type() <= fusain.MsgTempData
+				if !telemetry || peer.subscriptions[p.Address()] {
+					visible = append(visible, p)
+				}
+			}
+		}
+		if len(visible) > 0 || len(noise) > 0 {
+			// A failed write surfaces as a read error in serve
+			peer.send(noise, visible)
+		}
+	}
+}
+
+// serve answers packets from peer until its connection fails. The serial
+// and pty transports only end on error; a closed WebSocket returns nil.
+func (h *simHub) serve(peer *simPeer) error {
+	h.mu.Lock()
+	h.peers[peer] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.peers, peer)
+		h.mu.Unlock()
+	}()
+
+	decoder := fusain.NewDecoder()
+	buf := make([]byte, 256)
+	for {
+		n, err := peer.conn.Read(buf)
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) || errors.Is(err, ErrConnectionClosed) {
+				return nil
+			}
+			return exitErrorf(ExitConnection, "read failed: %v", err)
+		}
+
+		packets, _ := decoder.Decode(buf[:n])
+		for _, p := range packets {
+			if h.router && h.route(peer, p) {
+				continue
+			}
+			for _, app := range h.apps {
+				replies, delay := app.handle(p, h.clock.Now())
+				h.deliver(peer, replies, delay)
+			}
+		}
+	}
+}
+
+// deliver sends an appliance's replies to the peer that asked, after
+// their delay in simulated time
+func (h *simHub) deliver(peer *simPeer, replies []*fusain.Packet, delay time.Duration) {
+	if len(replies) == 0 {
+		return
+	}
+	if delay > 0 {
+		time.AfterFunc(h.clock.real(delay), func() { peer.send(nil, replies) })
+		return
+	}
+	peer.send(nil, replies)
+}
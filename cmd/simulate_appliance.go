@@ -0,0 +1,547 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// Simulated appliance behaviour. The numbers are rough approximations of a
+// Helios burner, tuned so a heat cycle completes in well under a minute.
+const (
+	simAmbientTemp   = 20.0  // °C
+	simIgnitionTemp  = 80.0  // PREHEAT_STAGE_2 -> HEATING (°C)
+	simCooledTemp    = 50.0  // COOLING -> IDLE (°C)
+	simFlameTempRate = 36000 // Flame temperature rise is this divided by the pump rate in ms (°C)
+	simTempTau       = 10 * time.Second
+	simMotorTau      = 800 * time.Millisecond
+	simStartupTime   = time.Second
+	simPreheatTime   = 6 * time.Second
+	simIgnitionLimit = 30 * time.Second // PREHEAT_STAGE_2 without ignition -> ERROR
+
+	simMaxRPM     = 5000
+	simMinRPM     = 800
+	simPWMMax     = 1000 // µs
+	simPreheatRPM = 1200
+	simStage2RPM  = 1800
+	simHeatRPM    = 3000
+	simCoolRPM    = 2500
+	simFanRPM     = 2000 // FAN without an argument
+	simPumpRate   = 200  // HEAT without an argument (ms)
+	simMinRate    = 50   // Fastest pump rate accepted (ms)
+)
+
+// simAppliance models a Helios ICU with one motor, thermometer, pump and
+// glow plug. handle answers commands and tick advances the physics and
+// returns the telemetry to broadcast. It is safe for concurrent use.
+type simAppliance struct {
+	mu      sync.Mutex
+	address uint64
+	start   time.Time
+	rng     *rand.Rand
+
+	state     fusain.SysState
+	stateAt   time.Time
+	errorCode fusain.ErrorCode
+	lastStep  time.Time
+
+	rpm       float64
+	fanRPM    int32 // Commanded RPM for BLOWING and HEATING
+	temp      float64
+	pumpRate  int32 // ms between pump cycles (0 = off)
+	pumpNext  time.Time
+	glowUntil time.Time
+
+	telemetry     bool
+	interval      time.Duration
+	lastTelemetry time.Time
+
+	timeout     time.Duration // Communication timeout (0 = disabled)
+	lastCommand time.Time
+
+	events []*fusain.Packet // Queued for the next tick
+	noise  []byte           // Garbage fault bytes for the next tick
+	logs   []string         // Log lines for the simulator output
+
+	curves       []*simCurve // --scenario telemetry curves
+	faults       []*simFault // --scenario faults
+	stalledSince time.Time   // Motor below minimum RPM despite a target
+}
+
+// newSimAppliance creates an appliance that starts in INITIALIZING.
+// A zero telemetry interval starts with telemetry disabled. The seed drives
+// the discovery delays and telemetry noise, so a run can be repeated.
+func newSimAppliance(address uint64, interval time.Duration, now time.Time, seed uint64) *simAppliance {
+	return &simAppliance{
+		address:   address,
+		start:     now,
+		rng:       rand.New(rand.NewPCG(seed, address)),
+		state:     fusain.SysStateInitializing,
+		stateAt:   now,
+		lastStep:  now,
+		temp:      simAmbientTemp,
+		telemetry: interval > 0,
+		interval:  interval,
+	}
+}
+
+// uptime returns the device timestamp for now in milliseconds
+func (a *simAppliance) uptime(now time.Time) uint64 {
+	return uint64(now.Sub(a.start).Milliseconds())
+}
+
+// handle answers a received packet. Packets for other devices and
+// telemetry are ignored. The replies are sent after delay (non-zero for
+// DEVICE_ANNOUNCE, which appliances send with a random 0-50ms delay, and
+// during a delay fault).
+func (a *simAppliance) handle(p *fusain.Packet, now time.Time) (replies []*fusain.Packet, delay time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	defer func() {
+		if len(replies) > 0 {
+			delay += a.replyDelay()
+		}
+	}()
+
+	if p.Address() != a.address && p.Address() != fusain.AddressBroadcast {
+		return nil, 0
+	}
+	a.step(now)
+
+	switch p.Type() {
+	case fusain.MsgDiscoveryRequest:
+		// Appliances ignore non-broadcast discovery
+		if p.Address() != fusain.AddressBroadcast {
+			return nil, 0
+		}
+		return []*fusain.Packet{a.announce().Encode(a.address)}, time.Duration(a.rng.Int64N(int64(50 * time.Millisecond)))
+
+	case fusain.MsgPingRequest:
+		a.lastCommand = now
+		return a.reply(fusain.PingResponse{Uptime: a.uptime(now)}.Encode(a.address)), 0
+
+	case fusain.MsgTelemetryConfig:
+		a.lastCommand = now
+		enabled, ok1 := fusain.GetMapBool(p.PayloadMap(), 0)
+		interval, ok2 := fusain.GetMapUint(p.PayloadMap(), 1)
+		if !ok1 || !ok2 {
+			return a.invalid(1), 0
+		}
+		a.telemetry = enabled && interval > 0
+		a.interval = time.Duration(interval) * time.Millisecond
+		return nil, 0
+
+	case fusain.MsgTimeoutConfig:
+		a.lastCommand = now
+		enabled, ok1 := fusain.GetMapBool(p.PayloadMap(), 0)
+		timeout, ok2 := fusain.GetMapUint(p.PayloadMap(), 1)
+		if !ok1 || !ok2 {
+			return a.invalid(1), 0
+		}
+		a.timeout = 0
+		if enabled {
+			a.timeout = time.Duration(timeout) * time.Millisecond
+		}
+		return nil, 0
+
+	case fusain.MsgSendTelemetry:
+		a.lastCommand = now
+		telemetryType, ok := fusain.GetMapUint(p.PayloadMap(), 0)
+		if !ok || telemetryType > uint64(fusain.TelemetryTypeGlow) {
+			return a.invalid(1), 0
+		}
+		if index, ok := fusain.GetMapUint(p.PayloadMap(), 1); ok && index != 0 {
+			return a.invalid(2), 0
+		}
+		return a.reply(a.telemetryPacket(fusain.TelemetryType(telemetryType), now)), 0
+
+	case fusain.MsgStateCommand:
+		a.lastCommand = now
+		cmd, err := fusain.DecodeStateCommand(p)
+		if err != nil {
+			return a.invalid(1), 0
+		}
+		return a.stateCommand(cmd, now), 0
+
+	case fusain.MsgMotorCommand:
+		a.lastCommand = now
+		cmd, err := fusain.DecodeMotorCommand(p)
+		if err != nil || cmd.RPM < 0 || cmd.RPM > simMaxRPM {
+			return a.invalid(1), 0
+		}
+		if cmd.Motor != 0 {
+			return a.invalid(2), 0
+		}
+		if a.state != fusain.SysStateBlowing && a.state != fusain.SysStateHeating {
+			return a.reject(), 0
+		}
+		a.fanRPM = cmd.RPM
+		return nil, 0
+
+	case fusain.MsgPumpCommand:
+		a.lastCommand = now
+		cmd, err := fusain.DecodePumpCommand(p)
+		if err != nil || cmd.RateMs != 0 && cmd.RateMs < simMinRate {
+			return a.invalid(1), 0
+		}
+		if cmd.Pump != 0 {
+			return a.invalid(2), 0
+		}
+		if a.state != fusain.SysStatePreheatStage2 && a.state != fusain.SysStateHeating {
+			return a.reject(), 0
+		}
+		a.setPumpRate(cmd.RateMs, now)
+		return nil, 0
+
+	case fusain.MsgGlowCommand:
+		a.lastCommand = now
+		cmd, err := fusain.DecodeGlowCommand(p)
+		if err != nil || cmd.DurationMs < 0 {
+			return a.invalid(1), 0
+		}
+		if cmd.Glow != 0 {
+			return a.invalid(2), 0
+		}
+		if a.locked() {
+			return a.reject(), 0
+		}
+		a.glowUntil = now.Add(time.Duration(cmd.DurationMs) * time.Millisecond)
+		return nil, 0
+
+	case fusain.MsgTempCommand:
+		a.lastCommand = now
+		if index, ok := fusain.GetMapUint(p.PayloadMap(), 0); !ok || index != 0 {
+			return a.invalid(2), 0
+		}
+		return nil, 0
+	}
+	return nil, 0
+}
+
+// stateCommand applies a STATE_COMMAND
+func (a *simAppliance) stateCommand(cmd fusain.StateCommand, now time.Time) []*fusain.Packet {
+	switch cmd.Mode {
+	case fusain.ModeEmergency:
+		a.errorCode = fusain.ErrorCommandedStop
+		a.setState(fusain.SysStateEstop, now)
+		return nil
+
+	case fusain.ModeIdle:
+		switch a.state {
+		case fusain.SysStateInitializing:
+			return a.reject()
+		case fusain.SysStatePreheat, fusain.SysStatePreheatStage2, fusain.SysStateHeating:
+			a.setState(fusain.SysStateCooling, now)
+		case fusain.SysStateBlowing, fusain.SysStateError, fusain.SysStateEstop:
+			a.errorCode = fusain.ErrorNone
+			a.setState(fusain.SysStateIdle, now)
+		}
+		return nil
+
+	case fusain.ModeFan:
+		rpm := int64(simFanRPM)
+		if cmd.Argument != nil {
+			rpm = *cmd.Argument
+		}
+		if rpm < 0 || rpm > simMaxRPM {
+			return a.invalid(1)
+		}
+		switch a.state {
+		case fusain.SysStateIdle, fusain.SysStateBlowing, fusain.SysStateCooling:
+			a.fanRPM = int32(rpm)
+			a.setState(fusain.SysStateBlowing, now)
+			return nil
+		}
+		return a.reject()
+
+	case fusain.ModeHeat:
+		rate := int64(simPumpRate)
+		if cmd.Argument != nil {
+			rate = *cmd.Argument
+		}
+		if rate < simMinRate || rate > math.MaxInt32 {
+			return a.invalid(1)
+		}
+		switch a.state {
+		case fusain.SysStateIdle, fusain.SysStateBlowing, fusain.SysStateCooling:
+			a.pumpRate = int32(rate)
+			a.fanRPM = simHeatRPM
+			a.setState(fusain.SysStatePreheat, now)
+			return nil
+		case fusain.SysStatePreheat:
+			a.pumpRate = int32(rate)
+			return nil
+		case fusain.SysStatePreheatStage2, fusain.SysStateHeating:
+			a.setPumpRate(int32(rate), now)
+			return nil
+		}
+		return a.reject()
+	}
+	return a.invalid(1)
+}
+
+// locked reports whether the appliance refuses component commands
+func (a *simAppliance) locked() bool {
+	switch a.state {
+	case fusain.SysStateInitializing, fusain.SysStateError, fusain.SysStateEstop:
+		return true
+	}
+	return false
+}
+
+// announce describes the appliance's components
+func (a *simAppliance) announce() fusain.DeviceAnnounce {
+	return fusain.DeviceAnnounce{MotorCount: 1, ThermometerCount: 1, PumpCount: 1, GlowCount: 1}
+}
+
+func (a *simAppliance) reply(p *fusain.Packet) []*fusain.Packet {
+	return []*fusain.Packet{p}
+}
+
+// invalid builds ERROR_INVALID_CMD (1 = invalid parameter, 2 = invalid index)
+func (a *simAppliance) invalid(code int32) []*fusain.Packet {
+	return a.reply(fusain.ErrorInvalidCmd{Code: code}.Encode(a.address))
+}
+
+// reject builds ERROR_STATE_REJECT for the current state
+func (a *simAppliance) reject() []*fusain.Packet {
+	return a.reply(fusain.ErrorStateReject{State: a.state}.Encode(a.address))
+}
+
+// setState moves to state and queues STATE_DATA so observers see every
+// transition, not just the ones that last past a telemetry interval
+func (a *simAppliance) setState(state fusain.SysState, now time.Time) {
+	if state == a.state {
+		return
+	}
+	a.state = state
+	a.stateAt = now
+	if a.pumping() && a.pumpNext.IsZero() {
+		a.pumpNext = now
+	}
+	if a.telemetry {
+		a.events = append(a.events, a.telemetryPacket(fusain.TelemetryTypeState, now))
+	}
+}
+
+// setPumpRate changes the pump rate while pumping
+func (a *simAppliance) setPumpRate(rate int32, now time.Time) {
+	a.pumpRate = rate
+	a.pumpNext = now
+}
+
+// pumping reports whether fuel is being delivered
+func (a *simAppliance) pumping() bool {
+	return a.pumpRate > 0 && (a.state == fusain.SysStatePreheatStage2 || a.state == fusain.SysStateHeating)
+}
+
+// tick advances the simulation to now and returns the packets to
+// broadcast (queued state changes, pump events and periodic telemetry) and
+// the garbage fault bytes to send before them
+func (a *simAppliance) tick(now time.Time) ([]*fusain.Packet, []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.step(now)
+
+	out, noise := a.events, a.noise
+	a.events, a.noise = nil, nil
+	if a.telemetry && now.Sub(a.lastTelemetry) >= a.interval {
+		a.lastTelemetry = now
+		for _, t := range []fusain.TelemetryType{fusain.TelemetryTypeState, fusain.TelemetryTypeMotor, fusain.TelemetryTypeTemp, fusain.TelemetryTypeGlow} {
+			out = append(out, a.telemetryPacket(t, now))
+		}
+	}
+	return out, noise
+}
+
+// step advances the state machine and physics to now
+func (a *simAppliance) step(now time.Time) {
+	dt := now.Sub(a.lastStep)
+	if dt <= 0 {
+		return
+	}
+	a.lastStep = now
+	a.updateFaults(now)
+	inState := now.Sub(a.stateAt)
+
+	// Communication timeout: stop heating when the controller goes quiet
+	if a.timeout > 0 && now.Sub(a.lastCommand) > a.timeout {
+		switch a.state {
+		case fusain.SysStateBlowing:
+			a.setState(fusain.SysStateIdle, now)
+		case fusain.SysStatePreheat, fusain.SysStatePreheatStage2, fusain.SysStateHeating:
+			a.setState(fusain.SysStateCooling, now)
+		}
+	}
+
+	switch a.state {
+	case fusain.SysStateInitializing:
+		if inState >= simStartupTime {
+			a.setState(fusain.SysStateIdle, now)
+		}
+	case fusain.SysStatePreheat:
+		if inState >= simPreheatTime {
+			a.setState(fusain.SysStatePreheatStage2, now)
+		}
+	case fusain.SysStatePreheatStage2:
+		if a.temp >= simIgnitionTemp {
+			a.setState(fusain.SysStateHeating, now)
+		} else if inState >= simIgnitionLimit {
+			a.errorCode = fusain.ErrorIgnitionFail
+			a.setState(fusain.SysStateError, now)
+		}
+	case fusain.SysStateCooling:
+		if a.temp <= simCooledTemp {
+			a.setState(fusain.SysStateIdle, now)
+		}
+	}
+
+	// Motor: first-order lag toward the target with a little jitter; a
+	// stalled rotor spins down whatever the target
+	target := float64(a.targetRPM())
+	stalled := a.fault(faultMotorStall) != nil
+	if stalled {
+		target = 0
+	}
+	a.rpm += (target - a.rpm) * (1 - math.Exp(-dt.Seconds()/simMotorTau.Seconds()))
+	if target > 0 {
+		a.rpm += a.rng.NormFloat64() * 5
+	}
+	if rpm, ok := a.curve(curveRPM, now); ok && !stalled {
+		a.rpm = rpm + a.rng.NormFloat64()*5
+	}
+	a.rpm = max(a.rpm, 0)
+	if stalled && a.targetRPM() > 0 && a.rpm < simMinRPM {
+		if a.stalledSince.IsZero() {
+			a.stalledSince = now
+		}
+	} else {
+		a.stalledSince = time.Time{}
+	}
+
+	// Temperature: toward the flame temperature while burning, ambient
+	// otherwise or after a flame-out; overheat adds its ramp while burning.
+	// A curve replaces this unless one of those faults is active.
+	flame := simAmbientTemp
+	if a.pumping() && a.fault(faultFlameOut) == nil {
+		flame += simFlameTempRate / float64(a.pumpRate)
+	}
+	a.temp += (flame - a.temp) * (1 - math.Exp(-dt.Seconds()/simTempTau.Seconds()))
+	a.temp += a.rng.NormFloat64() * 0.1
+	overheat := a.fault(faultOverheat)
+	if temp, ok := a.curve(curveTemp, now); ok && overheat == nil && a.fault(faultFlameOut) == nil {
+		a.temp = temp + a.rng.NormFloat64()*0.1
+	}
+	if overheat != nil && a.pumping() {
+		a.temp += overheat.Rate * dt.Seconds()
+	}
+	a.protect(now)
+	a.noise = append(a.noise, a.garbage(dt)...)
+
+	// Pump: one cycle per rate interval
+	if a.pumping() {
+		for !a.pumpNext.After(now) {
+			if a.telemetry {
+				a.events = append(a.events, a.pumpPacket(fusain.PumpEventCycleStart, a.pumpNext))
+			}
+			a.pumpNext = a.pumpNext.Add(time.Duration(a.pumpRate) * time.Millisecond)
+		}
+	} else {
+		a.pumpNext = time.Time{}
+	}
+}
+
+// targetRPM returns the fan speed the current state asks for
+func (a *simAppliance) targetRPM() int32 {
+	switch a.state {
+	case fusain.SysStateBlowing, fusain.SysStateHeating:
+		return a.fanRPM
+	case fusain.SysStatePreheat:
+		return simPreheatRPM
+	case fusain.SysStatePreheatStage2:
+		return simStage2RPM
+	case fusain.SysStateCooling:
+		return simCoolRPM
+	}
+	return 0
+}
+
+// glowLit reports whether the glow plug is on
+func (a *simAppliance) glowLit(now time.Time) bool {
+	return a.state == fusain.SysStatePreheat || a.state == fusain.SysStatePreheatStage2 || now.Before(a.glowUntil)
+}
+
+// telemetryPacket builds the current telemetry of one type
+func (a *simAppliance) telemetryPacket(t fusain.TelemetryType, now time.Time) *fusain.Packet {
+	timestamp := a.uptime(now)
+	switch t {
+	case fusain.TelemetryTypeMotor:
+		maxRPM, minRPM := int32(simMaxRPM), int32(simMinRPM)
+		pwm := uint32(math.Min(a.rpm/simMaxRPM, 1) * simPWMMax)
+		pwmMax := uint32(simPWMMax)
+		return fusain.MotorData{
+			Timestamp: timestamp,
+			RPM:       int32(math.Round(a.rpm)),
+			Target:    a.targetRPM(),
+			MaxRPM:    &maxRPM,
+			MinRPM:    &minRPM,
+			PWM:       &pwm,
+			PWMMax:    &pwmMax,
+		}.Encode(a.address)
+	case fusain.TelemetryTypeTemp:
+		return fusain.TempData{Timestamp: timestamp, Reading: math.Round(a.temp*10) / 10}.Encode(a.address)
+	case fusain.TelemetryTypePump:
+		event := fusain.PumpEventReady
+		if a.pumping() {
+			event = fusain.PumpEventCycleStart
+		}
+		return a.pumpPacket(event, now)
+	case fusain.TelemetryTypeGlow:
+		return fusain.GlowData{Timestamp: timestamp, Lit: a.glowLit(now)}.Encode(a.address)
+	}
+	return fusain.StateData{
+		Error:     a.state == fusain.SysStateError || a.state == fusain.SysStateEstop,
+		Code:      a.errorCode,
+		State:     a.state,
+		Timestamp: timestamp,
+	}.Encode(a.address)
+}
+
+// pumpPacket builds PUMP_DATA for an event at the given time
+func (a *simAppliance) pumpPacket(event fusain.PumpEvent, at time.Time) *fusain.Packet {
+	data := fusain.PumpData{Timestamp: a.uptime(at), Event: event}
+	if a.pumpRate > 0 {
+		rate := a.pumpRate
+		data.Rate = &rate
+	}
+	return data.Encode(a.address)
+}
+
+// logf queues a log line for the simulator output
+func (a *simAppliance) logf(format string, args ...interface{}) {
+	a.logs = append(a.logs, fmt.Sprintf(format, args...))
+}
+
+// takeLogs returns and clears the queued log lines
+func (a *simAppliance) takeLogs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	logs := a.logs
+	a.logs = nil
+	return logs
+}
+
+// status returns the current state for log lines
+func (a *simAppliance) status() (fusain.SysState, float64, float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.state, a.rpm, a.temp
+}
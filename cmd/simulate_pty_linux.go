@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// ptyStaleBytes is how much unread data may queue on the terminal side
+// before it is discarded as stale
+const ptyStaleBytes = 1024
+
+// ptyConn is the master side of a pseudo-terminal. The terminal side is
+// kept open so reads block rather than fail while no client is connected;
+// data no client is reading is flushed so writes never block on a full
+// buffer and a new client starts with fresh telemetry.
+type ptyConn struct {
+	master *os.File
+	slave  *os.File
+}
+
+func (c *ptyConn) Read(p []byte) (int, error) {
+	return c.master.Read(p)
+}
+
+func (c *ptyConn) Write(p []byte) (int, error) {
+	fd := int(c.slave.Fd())
+	if queued, err := unix.IoctlGetInt(fd, unix.TIOCINQ); err == nil && queued > ptyStaleBytes {
+		unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIFLUSH)
+	}
+	return c.master.Write(p)
+}
+
+func (c *ptyConn) Close() error {
+	c.slave.Close()
+	return c.master.Close()
+}
+
+// openPTY creates a pseudo-terminal in raw mode and returns its master
+// side and the terminal's path for clients to open
+func openPTY() (Connection, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, "", fmt.Errorf("unlockpt: %v", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, "", fmt.Errorf("ptsname: %v", err)
+	}
+	path := fmt.Sprintf("/dev/pts/%d", n)
+
+	slave, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+	if _, err := term.MakeRaw(int(slave.Fd())); err != nil {
+		slave.Close()
+		master.Close()
+		return nil, "", fmt.Errorf("raw mode: %v", err)
+	}
+	return &ptyConn{master: master, slave: slave}, path, nil
+}
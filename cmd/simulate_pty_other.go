@@ -0,0 +1,13 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build !linux
+
+package cmd
+
+import "fmt"
+
+// openPTY is only implemented on Linux
+func openPTY() (Connection, string, error) {
+	return nil, "", fmt.Errorf("--pty is only supported on Linux")
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// simMaxDevices bounds --devices
+const simMaxDevices = 64
+
+// route answers the packets the simulated router handles itself, as serve
+// does: pings and discovery to the stateless address, and subscriptions.
+// It reports whether p was handled; other packets go to the appliances.
+func (h *simHub) route(peer *simPeer, p *fusain.Packet) bool {
+	address := p.Address()
+
+	switch p.Type() {
+	case fusain.MsgDataSubscription, fusain.MsgDataUnsubscribe:
+		if address == fusain.AddressStateless || address == fusain.AddressBroadcast {
+			h.subscribe(peer, p)
+			return true
+		}
+	}
+
+	if address != fusain.AddressStateless {
+		return false
+	}
+	switch p.Type() {
+	case fusain.MsgPingRequest:
+		uptime := uint64(h.clock.Now().Sub(h.clock.start).Milliseconds())
+		peer.send(nil, []*fusain.Packet{fusain.PingResponse{Uptime: uptime}.Encode(fusain.AddressStateless)})
+	case fusain.MsgDiscoveryRequest:
+		peer.send(nil, h.announcements())
+	default:
+		simLog("Router: ignoring %s to the stateless address", fusain.FormatMessageType(p.Type()))
+	}
+	return true
+}
+
+// subscribe applies a DATA_SUBSCRIPTION or DATA_UNSUBSCRIBE from peer
+func (h *simHub) subscribe(peer *simPeer, p *fusain.Packet) {
+	appliance, ok := fusain.GetMapUint(p.PayloadMap(), 0)
+	if !ok {
+		simLog("Router: %s without an appliance address", fusain.FormatMessageType(p.Type()))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if p.Type() == fusain.MsgDataSubscription {
+		if peer.subscriptions == nil {
+			peer.subscriptions = make(map[uint64]bool)
+		}
+		peer.subscriptions[appliance] = true
+		simLog("Router: subscribed to %016X", appliance)
+	} else {
+		delete(peer.subscriptions, appliance)
+		simLog("Router: unsubscribed from %016X", appliance)
+	}
+}
+
+// announcements returns a DEVICE_ANNOUNCE for each appliance, in address
+// order, followed by the end-of-discovery marker
+func (h *simHub) announcements() []*fusain.Packet {
+	packets := make([]*fusain.Packet, 0, len(h.apps)+1)
+	for _, app := range h.apps {
+		packets = append(packets, app.announce().Encode(app.address))
+	}
+	return append(packets, fusain.DeviceAnnounce{}.Encode(fusain.AddressStateless))
+}
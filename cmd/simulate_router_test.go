@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// bufferConn is a Connection that keeps what is written to it
+type bufferConn struct {
+	bytes.Buffer
+}
+
+func (c *bufferConn) Close() error { return nil }
+
+// received decodes and clears what was written to the peer
+func received(t *testing.T, peer *simPeer) []*fusain.Packet {
+	t.Helper()
+	conn := peer.conn.(*bufferConn)
+	packets, errs := fusain.NewDecoder().Decode(conn.Bytes())
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	conn.Reset()
+	return packets
+}
+
+func newSimRouter(devices int) (*simHub, *simPeer) {
+	start := time.Unix(1000, 0)
+	hub := &simHub{router: true, clock: newSimClock(start, 1), peers: make(map[*simPeer]bool)}
+	for i := 0; i < devices; i++ {
+		hub.apps = append(hub.apps, newSimAppliance(uint64(i+1), 500*time.Millisecond, start, 1))
+	}
+	peer := &simPeer{conn: &bufferConn{}}
+	hub.peers[peer] = true
+	return hub, peer
+}
+
+func TestSimRouterStateless(t *testing.T) {
+	hub, peer := newSimRouter(3)
+
+	if !hub.route(peer, fusain.NewPingRequest(fusain.AddressStateless)) {
+		t.Fatal("stateless ping not handled by the router")
+	}
+	if got := received(t, peer); len(got) != 1 || got[0].Type() != fusain.MsgPingResponse || got[0].Address() != fusain.AddressStateless {
+		t.Errorf("stateless ping: got %v", got)
+	}
+
+	hub.route(peer, fusain.NewDiscoveryRequest(fusain.AddressStateless))
+	got := received(t, peer)
+	if len(got) != 4 {
+		t.Fatalf("discovery: got %d packets, want 3 announcements and the end marker", len(got))
+	}
+	for i, p := range got[:3] {
+		if p.Type() != fusain.MsgDeviceAnnounce || p.Address() != uint64(i+1) {
+			t.Errorf("announcement %d: %s from %016X", i, fusain.FormatMessageType(p.Type()), p.Address())
+		}
+	}
+	end, err := fusain.DecodeDeviceAnnounce(got[3])
+	if err != nil || !end.IsEndMarker() || got[3].Address() != fusain.AddressStateless {
+		t.Errorf("no end-of-discovery marker from the stateless address")
+	}
+
+	// Device-addressed packets go to the appliances
+	if hub.route(peer, fusain.NewPingRequest(2)) {
+		t.Error("device ping handled by the router")
+	}
+}
+
+func TestSimRouterSubscriptions(t *testing.T) {
+	hub, peer := newSimRouter(2)
+	temp := func(address uint64) *fusain.Packet {
+		return fusain.TempData{Reading: 20}.Encode(address)
+	}
+	pong := fusain.PingResponse{}.Encode(1)
+
+	// Without a subscription only non-telemetry gets through
+	hub.broadcast(nil, []*fusain.Packet{temp(1), temp(2), pong})
+	if got := received(t, peer); len(got) != 1 || got[0].Type() != fusain.MsgPingResponse {
+		t.Errorf("unsubscribed: got %d packets, want the ping response", len(got))
+	}
+
+	hub.route(peer, fusain.NewDataSubscription(fusain.AddressStateless, 2))
+	hub.broadcast(nil, []*fusain.Packet{temp(1), temp(2)})
+	if got := received(t, peer); len(got) != 1 || got[0].Address() != 2 {
+		t.Errorf("subscribed to 2: got %d packets", len(got))
+	}
+
+	hub.route(peer, fusain.NewDataUnsubscribe(fusain.AddressStateless, 2))
+	hub.broadcast(nil, []*fusain.Packet{temp(2)})
+	if got := received(t, peer); len(got) != 0 {
+		t.Errorf("unsubscribed from 2: got %d packets", len(got))
+	}
+}
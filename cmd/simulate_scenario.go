@@ -0,0 +1,396 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"gopkg.in/yaml.v3"
+)
+
+// Simulated faults
+const (
+	faultOverheat   = "overheat"    // Temperature ramps while burning until OVERHEAT
+	faultMotorStall = "motor_stall" // The rotor is blocked until MOTOR_STALL
+	faultFlameOut   = "flame_out"   // Fuel stops burning until FLAME_OUT
+	faultDelay      = "delay"       // Replies are held back
+	faultGarbage    = "garbage"     // Random bytes between frames
+)
+
+// Fault defaults and appliance protection limits
+const (
+	simOverheatRate  = 5.0   // °C per second added by overheat
+	simOverheatTemp  = 260.0 // Overheat protection (°C)
+	simFlameOutTemp  = 60.0  // HEATING below this -> FLAME_OUT (°C)
+	simStallTime     = 2 * time.Second
+	simGarbageRate   = 5.0 // Bursts per second
+	simGarbageBytes  = 8
+	simMaxFaultBytes = 1024
+)
+
+// simScenario is a --scenario file (YAML):
+//
+//	curves:
+//	  - metric: temp           # temp (°C) or rpm
+//	    state: HEATING         # while in this state, from entering it (default: from startup)
+//	    points:                # value over time, linear between points
+//	      - {at: 0s, value: 80}
+//	      - {at: 30s, value: 190}
+//	    device: "2"            # only this appliance with --devices (default: all)
+//	faults:
+//	  - fault: overheat        # overheat, motor_stall, flame_out, delay or garbage
+//	    state: HEATING         # start once the appliance has been in this state...
+//	    after: 20s             # ...this long (without state: since startup)
+//	    duration: 1m           # how long it lasts (0 = for good)
+//	    rate: 8                # overheat: °C/s; garbage: bursts per second
+//	    device: "2"            # only this appliance with --devices (default: all)
+//	  - fault: delay
+//	    after: 10s
+//	    delay: 800ms           # delay: how long replies are held back
+//	  - fault: garbage
+//	    bytes: 16              # garbage: bytes per burst
+type simScenario struct {
+	Curves []simCurve     `yaml:"curves"`
+	Faults []simFaultSpec `yaml:"faults"`
+}
+
+// Curve metrics
+const (
+	curveTemp = "temp"
+	curveRPM  = "rpm"
+)
+
+// simCurveMaxTemp bounds temperature curve values (°C)
+const simCurveMaxTemp = 1000.0
+
+// simCurve replaces the physics of one metric with a time-based profile
+type simCurve struct {
+	Metric string     `yaml:"metric"`
+	State  string     `yaml:"state"`
+	Device string     `yaml:"device"`
+	Points []simPoint `yaml:"points"`
+
+	state    fusain.SysState // Parsed State (when hasState)
+	hasState bool
+	address  uint64 // Parsed Device (0 = every appliance)
+}
+
+// simPoint is a curve value at a time since the curve started
+type simPoint struct {
+	At    time.Duration `yaml:"at"`
+	Value float64       `yaml:"value"`
+}
+
+// simFaultSpec is one scenario fault
+type simFaultSpec struct {
+	Fault    string        `yaml:"fault"`
+	State    string        `yaml:"state"`
+	After    time.Duration `yaml:"after"`
+	Duration time.Duration `yaml:"duration"`
+	Rate     float64       `yaml:"rate"`
+	Delay    time.Duration `yaml:"delay"`
+	Bytes    int           `yaml:"bytes"`
+	Device   string        `yaml:"device"`
+
+	state    fusain.SysState // Parsed State (when hasState)
+	hasState bool
+	address  uint64 // Parsed Device (0 = every appliance)
+}
+
+// loadSimScenario reads and checks a scenario file
+func loadSimScenario(path string) (*simScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read scenario: %v", err)
+	}
+	scenario, err := parseSimScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return scenario, nil
+}
+
+// parseSimScenario parses a scenario, filling in fault defaults
+func parseSimScenario(data []byte) (*simScenario, error) {
+	var scenario simScenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&scenario); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for i := range scenario.Curves {
+		c := &scenario.Curves[i]
+		if err := c.check(); err != nil {
+			return nil, fmt.Errorf("curve %d (%s): %v", i+1, c.Metric, err)
+		}
+	}
+	for i := range scenario.Faults {
+		f := &scenario.Faults[i]
+		if err := f.check(); err != nil {
+			return nil, fmt.Errorf("fault %d (%s): %v", i+1, f.Fault, err)
+		}
+	}
+	return &scenario, nil
+}
+
+// check validates a fault and fills in its defaults
+func (f *simFaultSpec) check() error {
+	if f.After < 0 || f.Duration < 0 || f.Delay < 0 || f.Rate < 0 || f.Bytes < 0 {
+		return fmt.Errorf("after, duration, delay, rate and bytes must not be negative")
+	}
+	var err error
+	if f.address, f.state, f.hasState, err = parseScenarioTarget(f.Device, f.State); err != nil {
+		return err
+	}
+
+	switch f.Fault {
+	case faultOverheat:
+		if f.Rate == 0 {
+			f.Rate = simOverheatRate
+		}
+	case faultMotorStall, faultFlameOut:
+	case faultDelay:
+		if f.Delay == 0 {
+			return fmt.Errorf("delay needs a delay")
+		}
+	case faultGarbage:
+		if f.Rate == 0 {
+			f.Rate = simGarbageRate
+		}
+		if f.Bytes == 0 {
+			f.Bytes = simGarbageBytes
+		}
+		if f.Bytes > simMaxFaultBytes {
+			return fmt.Errorf("at most %d bytes per burst", simMaxFaultBytes)
+		}
+	default:
+		return fmt.Errorf("unknown fault (valid: %s, %s, %s, %s, %s)", faultOverheat, faultMotorStall, faultFlameOut, faultDelay, faultGarbage)
+	}
+	return nil
+}
+
+// check validates a curve
+func (c *simCurve) check() error {
+	limit := simCurveMaxTemp
+	switch c.Metric {
+	case curveTemp:
+	case curveRPM:
+		limit = simMaxRPM
+	default:
+		return fmt.Errorf("unknown metric (valid: %s, %s)", curveTemp, curveRPM)
+	}
+	if len(c.Points) == 0 {
+		return fmt.Errorf("no points")
+	}
+	for i, p := range c.Points {
+		if p.At < 0 || i > 0 && p.At < c.Points[i-1].At {
+			return fmt.Errorf("point %d: times must not be negative or go backwards", i+1)
+		}
+		if p.Value < 0 && c.Metric == curveRPM || p.Value > limit {
+			return fmt.Errorf("point %d: value %g out of range (max %g)", i+1, p.Value, limit)
+		}
+	}
+
+	var err error
+	c.address, c.state, c.hasState, err = parseScenarioTarget(c.Device, c.State)
+	return err
+}
+
+// value returns the curve value at t since the curve started: the first
+// point's value before it, linear between points, the last one after them
+func (c *simCurve) value(t time.Duration) float64 {
+	points := c.Points
+	if t <= points[0].At {
+		return points[0].Value
+	}
+	for i := 1; i < len(points); i++ {
+		if t < points[i].At {
+			prev, next := points[i-1], points[i]
+			frac := float64(t-prev.At) / float64(next.At-prev.At)
+			return prev.Value + (next.Value-prev.Value)*frac
+		}
+	}
+	return points[len(points)-1].Value
+}
+
+// parseScenarioTarget parses the device and state of a curve or fault
+func parseScenarioTarget(device, state string) (address uint64, s fusain.SysState, hasState bool, err error) {
+	if device != "" {
+		if address, err = parseAddress(device); err != nil {
+			return 0, 0, false, err
+		}
+	}
+	if state != "" {
+		var ok bool
+		if s, ok = parseSysState(state); !ok {
+			return 0, 0, false, fmt.Errorf("unknown state %q", state)
+		}
+		hasState = true
+	}
+	return address, s, hasState, nil
+}
+
+// parseSysState looks up a state by name (HEATING, e_stop, ...)
+func parseSysState(name string) (fusain.SysState, bool) {
+	for s := fusain.SysStateInitializing; s <= fusain.SysStateEstop; s++ {
+		if fusain.FormatState(uint32(s)) == strings.ToUpper(name) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// simFault is a scenario fault in a running appliance. Each fault happens
+// once: it starts when its condition is first met and ends after its
+// duration.
+type simFault struct {
+	simFaultSpec
+	active bool
+	done   bool
+	end    time.Time // Zero for a fault that lasts for good
+	due    float64   // Garbage bursts owed
+}
+
+// describe names the fault for log lines
+func (f *simFault) describe() string {
+	switch f.Fault {
+	case faultOverheat:
+		return fmt.Sprintf("%s (+%g°C/s)", f.Fault, f.Rate)
+	case faultDelay:
+		return fmt.Sprintf("%s (%s)", f.Fault, f.Delay)
+	case faultGarbage:
+		return fmt.Sprintf("%s (%g x %d bytes/s)", f.Fault, f.Rate, f.Bytes)
+	}
+	return f.Fault
+}
+
+// newSimFaults prepares the faults of a scenario for the appliance at
+// address (nil for none)
+func newSimFaults(scenario *simScenario, address uint64) []*simFault {
+	if scenario == nil {
+		return nil
+	}
+	var faults []*simFault
+	for _, spec := range scenario.Faults {
+		if spec.address == 0 || spec.address == address {
+			faults = append(faults, &simFault{simFaultSpec: spec})
+		}
+	}
+	return faults
+}
+
+// newSimCurves returns the curves of a scenario for the appliance at
+// address (nil for none)
+func newSimCurves(scenario *simScenario, address uint64) []*simCurve {
+	if scenario == nil {
+		return nil
+	}
+	var curves []*simCurve
+	for i, c := range scenario.Curves {
+		if c.address == 0 || c.address == address {
+			curves = append(curves, &scenario.Curves[i])
+		}
+	}
+	return curves
+}
+
+// curve returns the value of the first curve for metric that applies at
+// now, if any. A curve with a state applies while the appliance is in it
+// and restarts each time the state is entered.
+func (a *simAppliance) curve(metric string, now time.Time) (float64, bool) {
+	for _, c := range a.curves {
+		switch {
+		case c.Metric != metric:
+		case !c.hasState:
+			return c.value(now.Sub(a.start)), true
+		case c.state == a.state:
+			return c.value(now.Sub(a.stateAt)), true
+		}
+	}
+	return 0, false
+}
+
+// updateFaults starts and ends scenario faults at now
+func (a *simAppliance) updateFaults(now time.Time) {
+	for _, f := range a.faults {
+		switch {
+		case f.done:
+		case f.active:
+			if !f.end.IsZero() && !now.Before(f.end) {
+				f.active, f.done = false, true
+				a.logf("Fault %s ended", f.Fault)
+			}
+		case f.hasState && (a.state != f.state || now.Sub(a.stateAt) < f.After):
+		case !f.hasState && now.Sub(a.start) < f.After:
+		default:
+			f.active = true
+			if f.Duration > 0 {
+				f.end = now.Add(f.Duration)
+			}
+			a.logf("Fault %s started", f.describe())
+		}
+	}
+}
+
+// fault returns the active fault of a kind, or nil
+func (a *simAppliance) fault(kind string) *simFault {
+	for _, f := range a.faults {
+		if f.active && f.Fault == kind {
+			return f
+		}
+	}
+	return nil
+}
+
+// replyDelay returns how long an active delay fault holds replies back
+func (a *simAppliance) replyDelay() time.Duration {
+	if f := a.fault(faultDelay); f != nil {
+		return f.Delay
+	}
+	return 0
+}
+
+// garbage returns the random bytes active garbage faults send over dt
+func (a *simAppliance) garbage(dt time.Duration) []byte {
+	var out []byte
+	for _, f := range a.faults {
+		if !f.active || f.Fault != faultGarbage {
+			continue
+		}
+		f.due += f.Rate * dt.Seconds()
+		for ; f.due >= 1; f.due-- {
+			for i := 0; i < f.Bytes; i++ {
+				out = append(out, byte(a.rng.UintN(256)))
+			}
+		}
+	}
+	return out
+}
+
+// protect trips the appliance's protections the physics faults lead to
+func (a *simAppliance) protect(now time.Time) {
+	trip := fusain.ErrorNone
+	switch {
+	case a.temp >= simOverheatTemp && !a.locked():
+		trip = fusain.ErrorOverheat
+	case !a.stalledSince.IsZero() && now.Sub(a.stalledSince) >= simStallTime:
+		trip = fusain.ErrorMotorStall
+	case a.state == fusain.SysStateHeating && a.fault(faultFlameOut) != nil && a.temp < simFlameOutTemp:
+		trip = fusain.ErrorFlameOut
+	}
+	if trip != fusain.ErrorNone {
+		a.logf("Protection tripped: %s", fusain.FormatErrorCode(int32(trip)))
+		a.errorCode = trip
+		a.stalledSince = time.Time{}
+		a.setState(fusain.SysStateError, now)
+	}
+}
@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// simRun is a simulated appliance driven on a fake clock
+type simRun struct {
+	t     *testing.T
+	app   *simAppliance
+	start time.Time
+	now   time.Time
+	noise int // Garbage bytes sent
+}
+
+func newSimRun(t *testing.T, scenario string) *simRun {
+	t.Helper()
+	s, err := parseSimScenario([]byte(scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	app := newSimAppliance(1, 500*time.Millisecond, start, 1)
+	app.curves = newSimCurves(s, 1)
+	app.faults = newSimFaults(s, 1)
+	return &simRun{t: t, app: app, start: start, now: start}
+}
+
+// until ticks the appliance every 20ms up to the given time since start
+func (r *simRun) until(d time.Duration) {
+	for end := r.start.Add(d); r.now.Before(end); {
+		r.now = r.now.Add(20 * time.Millisecond)
+		_, noise := r.app.tick(r.now)
+		r.noise += len(noise)
+	}
+}
+
+// command sends a STATE_COMMAND now
+func (r *simRun) command(mode fusain.Mode) {
+	r.t.Helper()
+	if replies, _ := r.app.handle(fusain.NewStateCommand(1, uint8(mode), nil), r.now); len(replies) > 0 {
+		r.t.Fatalf("mode %d rejected: %s", mode, fusain.FormatMessageType(replies[0].Type()))
+	}
+}
+
+// expectError checks the appliance tripped with code
+func (r *simRun) expectError(code fusain.ErrorCode) {
+	r.t.Helper()
+	if r.app.state != fusain.SysStateError || r.app.errorCode != code {
+		r.t.Errorf("state %s, error %s; want ERROR, %s", fusain.FormatState(uint32(r.app.state)),
+			fusain.FormatErrorCode(int32(r.app.errorCode)), fusain.FormatErrorCode(int32(code)))
+	}
+}
+
+func TestSimulateFaultOverheat(t *testing.T) {
+	r := newSimRun(t, "faults:\n  - fault: overheat\n    state: HEATING\n    after: 5s\n    rate: 20\n")
+	r.until(2 * time.Second)
+	r.command(fusain.ModeHeat)
+	r.until(time.Minute)
+	r.expectError(fusain.ErrorOverheat)
+}
+
+func TestSimulateFaultMotorStall(t *testing.T) {
+	r := newSimRun(t, "faults:\n  - fault: motor_stall\n")
+	r.until(2 * time.Second)
+	r.command(fusain.ModeFan)
+	r.until(3 * time.Second)
+	if r.app.state != fusain.SysStateBlowing {
+		t.Fatalf("tripped after 1s in %s", fusain.FormatState(uint32(r.app.state)))
+	}
+	r.until(5 * time.Second)
+	r.expectError(fusain.ErrorMotorStall)
+}
+
+func TestSimulateFaultFlameOut(t *testing.T) {
+	r := newSimRun(t, "faults:\n  - fault: flame_out\n    state: HEATING\n    after: 2s\n")
+	r.until(2 * time.Second)
+	r.command(fusain.ModeHeat)
+	r.until(time.Minute)
+	r.expectError(fusain.ErrorFlameOut)
+}
+
+func TestSimulateFaultDelayAndGarbage(t *testing.T) {
+	r := newSimRun(t, "faults:\n  - fault: delay\n    after: 1s\n    duration: 1s\n    delay: 800ms\n  - fault: garbage\n    rate: 10\n    bytes: 4\n    duration: 2s\n")
+	ping := func() time.Duration {
+		_, delay := r.app.handle(fusain.NewPingRequest(1), r.now)
+		return delay
+	}
+
+	r.until(500 * time.Millisecond)
+	if d := ping(); d != 0 {
+		t.Errorf("delay %s before the fault", d)
+	}
+	r.until(1500 * time.Millisecond)
+	if d := ping(); d != 800*time.Millisecond {
+		t.Errorf("delay %s during the fault, want 800ms", d)
+	}
+	r.until(3 * time.Second)
+	if d := ping(); d != 0 {
+		t.Errorf("delay %s after the fault", d)
+	}
+	// 10 bursts of 4 bytes a second for 2s, give or take a burst
+	if r.noise < 76 || r.noise > 80 {
+		t.Errorf("%d garbage bytes, want 80", r.noise)
+	}
+}
+
+func TestParseSimScenario(t *testing.T) {
+	invalid := []string{
+		"faults:\n  - fault: meltdown\n",
+		"faults:\n  - fault: overheat\n    state: ROASTING\n",
+		"faults:\n  - fault: delay\n",
+		"faults:\n  - fault: garbage\n    bytes: 100000\n",
+		"faults:\n  - fault: overheat\n    after: -1s\n",
+		"faults:\n  - fault: overheat\n    when: soon\n",
+		"curves:\n  - metric: pressure\n    points: [{at: 0s, value: 1}]\n",
+		"curves:\n  - metric: temp\n",
+		"curves:\n  - metric: rpm\n    points: [{at: 0s, value: 9000}]\n",
+		"curves:\n  - metric: temp\n    points: [{at: 2s, value: 1}, {at: 1s, value: 2}]\n",
+	}
+	for _, data := range invalid {
+		if _, err := parseSimScenario([]byte(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
+
+func TestSimCurveValue(t *testing.T) {
+	c := simCurve{Points: []simPoint{{At: time.Second, Value: 100}, {At: 3 * time.Second, Value: 200}, {At: 3 * time.Second, Value: 50}}}
+	tests := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{0, 100},
+		{time.Second, 100},
+		{2 * time.Second, 150},
+		{3 * time.Second, 50}, // A step at 3s
+		{time.Minute, 50},
+	}
+	for _, tt := range tests {
+		if got := c.value(tt.at); got != tt.want {
+			t.Errorf("value(%s) = %g, want %g", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestSimulateCurves(t *testing.T) {
+	r := newSimRun(t, `
+curves:
+  - metric: temp
+    state: PREHEAT_STAGE_2
+    points:
+      - {at: 0s, value: 20}
+      - {at: 8s, value: 85}
+  - metric: rpm
+    state: HEATING
+    points:
+      - {at: 0s, value: 1800}
+      - {at: 1s, value: 3400}
+      - {at: 3s, value: 3000}
+`)
+	r.until(2 * time.Second)
+	r.command(fusain.ModeHeat)
+
+	// PREHEAT for 6s, then ignition at 80°C: 7.4s into the temp curve
+	r.until(15 * time.Second)
+	if r.app.state != fusain.SysStatePreheatStage2 {
+		t.Fatalf("state %s 7s into stage 2, want PREHEAT_STAGE_2", fusain.FormatState(uint32(r.app.state)))
+	}
+	r.until(16 * time.Second)
+	if r.app.state != fusain.SysStateHeating {
+		t.Fatalf("state %s 8s into stage 2, want HEATING", fusain.FormatState(uint32(r.app.state)))
+	}
+	heating := r.app.stateAt.Sub(r.start)
+
+	r.until(heating + time.Second)
+	if r.app.rpm < 3350 || r.app.rpm > 3450 {
+		t.Errorf("%.0f RPM at the overshoot, want about 3400", r.app.rpm)
+	}
+	r.until(heating + 5*time.Second)
+	if r.app.rpm < 2950 || r.app.rpm > 3050 {
+		t.Errorf("%.0f RPM after settling, want about 3000", r.app.rpm)
+	}
+}
+
+func TestSimulateDeterministic(t *testing.T) {
+	s, err := parseSimScenario([]byte("curves:\n  - metric: temp\n    points:\n      - {at: 0s, value: 20}\n      - {at: 10s, value: 120}\nfaults:\n  - fault: garbage\n    after: 2s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two simulators with the same seed, scenario and commands
+	run := func() []byte {
+		start := time.Unix(1000, 0)
+		hub := &simHub{clock: newSimClock(start, 1), peers: make(map[*simPeer]bool)}
+		for _, address := range []uint64{1, 2} {
+			app := newSimAppliance(address, 100*time.Millisecond, start, 42)
+			app.curves = newSimCurves(s, address)
+			app.faults = newSimFaults(s, address)
+			hub.apps = append(hub.apps, app)
+		}
+		conn := &bufferConn{}
+		hub.peers[&simPeer{conn: conn}] = true
+
+		states := make([]fusain.SysState, len(hub.apps))
+		for i := 0; i < 500; i++ {
+			if i == 100 {
+				hub.apps[0].handle(fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil), hub.clock.Now())
+			}
+			hub.step(states)
+		}
+		return conn.Bytes()
+	}
+	first, second := run(), run()
+	if len(first) == 0 || !bytes.Equal(first, second) {
+		t.Errorf("runs differ: %d and %d bytes", len(first), len(second))
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// forceASCII holds the --ascii flag value
+var forceASCII bool
+
+// tuiGlyphs are the symbols and border the TUIs draw with
+type tuiGlyphs struct {
+	waiting     string // Waiting for synchronization
+	ok          string // Synchronized
+	err         string // Error log entry
+	info        string // Warning log entry
+	emergency   string // Emergency-stop log entry
+	degree      string // Temperature unit prefix
+	link        string // Link-quality indicator
+	chartValue  string // Telemetry chart value
+	chartTarget string // Telemetry chart target
+	border      lipgloss.Border
+}
+
+var unicodeGlyphs = tuiGlyphs{
+	waiting:     "⏳",
+	ok:          "✓",
+	err:         "✗",
+	info:        "ℹ",
+	emergency:   "‼",
+	degree:      "°",
+	link:        "●",
+	chartValue:  "•",
+	chartTarget: "─",
+	border:      lipgloss.RoundedBorder(),
+}
+
+var asciiGlyphs = tuiGlyphs{
+	waiting:     "...",
+	ok:          "OK",
+	err:         "x",
+	info:        "i",
+	emergency:   "!!",
+	degree:      "",
+	link:        "*",
+	chartValue:  "*",
+	chartTarget: "-",
+	border:      lipgloss.ASCIIBorder(),
+}
+
+// glyphs is the set in use, chosen by setupTerminal
+var glyphs = unicodeGlyphs
+
+// terminalColor reports whether setupTerminal found a terminal with colors
+var terminalColor = true
+
+// detectTerminal reports whether the terminal described by the environment
+// can show colors and Unicode symbols. NO_COLOR (https://no-color.org) and
+// TERM=dumb turn colors off. Unicode needs a UTF-8 locale (LC_ALL, LC_CTYPE
+// or LANG, first one set); the Linux virtual console and VT terminals lack
+// the symbols even then. Windows has no POSIX locale and is assumed to
+// support Unicode unless a locale says otherwise.
+func detectTerminal(getenv func(string) string) (color, unicode bool) {
+	term := getenv("TERM")
+	color = getenv("NO_COLOR") == "" && term != "dumb"
+
+	locale := ""
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale = getenv(name); locale != "" {
+			break
+		}
+	}
+	if locale == "" {
+		unicode = runtime.GOOS == "windows"
+	} else {
+		locale = strings.ToLower(locale)
+		unicode = strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+	}
+	if term == "dumb" || term == "linux" || strings.HasPrefix(term, "vt") {
+		unicode = false
+	}
+	return color, unicode
+}
+
+// setupTerminal selects the TUI glyphs and color profile for the terminal,
+// or plain ASCII without color when --ascii is set
+func setupTerminal() {
+	color, unicode := detectTerminal(os.Getenv)
+	if forceASCII {
+		color, unicode = false, false
+	}
+
+	glyphs = unicodeGlyphs
+	terminalColor = color
+	if !unicode {
+		glyphs = asciiGlyphs
+	}
+	if !color {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
+
+// formatTemperature formats a Celsius value in the display units with the
+// terminal's degree symbol (e.g. "21.5°C", or "21.5C" without Unicode)
+func formatTemperature(celsius float64) string {
+	return strings.Replace(fusain.FormatTemperature(celsius, displayUnits), "°", glyphs.degree, 1)
+}
+
+// temperatureUnit returns the display temperature unit with the terminal's
+// degree symbol
+func temperatureUnit() string {
+	return strings.Replace(fusain.TemperatureUnit(displayUnits), "°", glyphs.degree, 1)
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// themeName holds the --theme flag value
+var themeName string
+
+// tuiTheme is the palette the TUIs draw with, by role
+type tuiTheme struct {
+	accent   lipgloss.TerminalColor // Titles, labels, focused borders
+	titleBg  lipgloss.TerminalColor // Title bar background
+	muted    lipgloss.TerminalColor // Header and help text
+	border   lipgloss.TerminalColor // Box borders
+	good     lipgloss.TerminalColor // Values, healthy link, focused button
+	warning  lipgloss.TerminalColor // Warnings, chart targets
+	bad      lipgloss.TerminalColor // Errors, poor link, emergency background
+	alertFg  lipgloss.TerminalColor // Text on the bad color
+	buttonFg lipgloss.TerminalColor // Text on buttons
+
+	// noColor drops every color, leaving bold and reverse video
+	noColor bool
+}
+
+// The built-in themes. dark suits dark terminal backgrounds and is the
+// default; light keeps contrast on light backgrounds; high-contrast uses
+// only the 16 basic colors at full brightness; no-color draws without color.
+var themes = map[string]tuiTheme{
+	"dark": {
+		accent:   lipgloss.Color("12"),
+		titleBg:  lipgloss.Color("235"),
+		muted:    lipgloss.Color("241"),
+		border:   lipgloss.Color("240"),
+		good:     lipgloss.Color("10"),
+		warning:  lipgloss.Color("11"),
+		bad:      lipgloss.Color("9"),
+		alertFg:  lipgloss.Color("15"),
+		buttonFg: lipgloss.Color("0"),
+	},
+	"light": {
+		accent:   lipgloss.Color("25"),
+		titleBg:  lipgloss.Color("254"),
+		muted:    lipgloss.Color("242"),
+		border:   lipgloss.Color("248"),
+		good:     lipgloss.Color("28"),
+		warning:  lipgloss.Color("130"),
+		bad:      lipgloss.Color("160"),
+		alertFg:  lipgloss.Color("231"),
+		buttonFg: lipgloss.Color("231"),
+	},
+	"high-contrast": {
+		accent:   lipgloss.Color("14"),
+		titleBg:  lipgloss.Color("0"),
+		muted:    lipgloss.Color("15"),
+		border:   lipgloss.Color("15"),
+		good:     lipgloss.Color("10"),
+		warning:  lipgloss.Color("11"),
+		bad:      lipgloss.Color("9"),
+		alertFg:  lipgloss.Color("15"),
+		buttonFg: lipgloss.Color("0"),
+	},
+	"no-color": {
+		accent:   lipgloss.NoColor{},
+		titleBg:  lipgloss.NoColor{},
+		muted:    lipgloss.NoColor{},
+		border:   lipgloss.NoColor{},
+		good:     lipgloss.NoColor{},
+		warning:  lipgloss.NoColor{},
+		bad:      lipgloss.NoColor{},
+		alertFg:  lipgloss.NoColor{},
+		buttonFg: lipgloss.NoColor{},
+		noColor:  true,
+	},
+}
+
+// theme is the palette in use, chosen by setupTheme
+var theme = themes["dark"]
+
+// themeRoles names the colors the config file can override
+var themeRoles = map[string]func(*tuiTheme) *lipgloss.TerminalColor{
+	"accent":    func(t *tuiTheme) *lipgloss.TerminalColor { return &t.accent },
+	"title_bg":  func(t *tuiTheme) *lipgloss.TerminalColor { return &t.titleBg },
+	"muted":     func(t *tuiTheme) *lipgloss.TerminalColor { return &t.muted },
+	"border":    func(t *tuiTheme) *lipgloss.TerminalColor { return &t.border },
+	"good":      func(t *tuiTheme) *lipgloss.TerminalColor { return &t.good },
+	"warning":   func(t *tuiTheme) *lipgloss.TerminalColor { return &t.warning },
+	"bad":       func(t *tuiTheme) *lipgloss.TerminalColor { return &t.bad },
+	"alert_fg":  func(t *tuiTheme) *lipgloss.TerminalColor { return &t.alertFg },
+	"button_fg": func(t *tuiTheme) *lipgloss.TerminalColor { return &t.buttonFg },
+}
+
+// colorPattern matches an ANSI color index (0-255) or a #RGB/#RRGGBB color
+var colorPattern = regexp.MustCompile(`^([0-9]|[1-9][0-9]|1[0-9][0-9]|2[0-4][0-9]|25[0-5]|#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6})$`)
+
+// sortedKeys returns a map's keys in order, for error messages
+func sortedKeys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateTheme checks a theme name and color overrides
+func validateTheme(name string, colors map[string]string) error {
+	if _, ok := themes[name]; name != "" && !ok {
+		return fmt.Errorf("unknown theme %q (valid: %s)", name, strings.Join(sortedKeys(themes), ", "))
+	}
+	for role, color := range colors {
+		if themeRoles[role] == nil {
+			return fmt.Errorf("unknown color %q (valid: %s)", role, strings.Join(sortedKeys(themeRoles), ", "))
+		}
+		if color != "" && color != "none" && !colorPattern.MatchString(color) {
+			return fmt.Errorf("invalid color %s %q: expected an ANSI index 0-255, #RGB, #RRGGBB or none", role, color)
+		}
+	}
+	return nil
+}
+
+// buildTheme returns the named theme (default dark) with colors overriding
+// its roles; "none" removes a color
+func buildTheme(name string, colors map[string]string) (tuiTheme, error) {
+	if err := validateTheme(name, colors); err != nil {
+		return tuiTheme{}, err
+	}
+	if name == "" {
+		name = "dark"
+	}
+	t := themes[name]
+	for role, color := range colors {
+		switch color {
+		case "":
+		case "none":
+			*themeRoles[role](&t) = lipgloss.NoColor{}
+		default:
+			*themeRoles[role](&t) = lipgloss.Color(color)
+		}
+	}
+	return t, nil
+}
+
+// setupTheme selects the TUI theme from --theme or the config file, with
+// the config file's color overrides. Call after setupTerminal.
+func setupTheme() error {
+	name := appConfig().TUI.Theme
+	if themeName != "" {
+		name = themeName
+	}
+	if name == "" && !terminalColor {
+		name = "no-color"
+	}
+	t, err := buildTheme(name, appConfig().TUI.Colors)
+	if err != nil {
+		return err
+	}
+	theme = t
+	if theme.noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	emergencyStyle = theme.emergencyStyle()
+	return nil
+}
+
+// emergencyStyle returns the emergency-stop highlight
+func (t tuiTheme) emergencyStyle() lipgloss.Style {
+	return t.highlight(lipgloss.NewStyle().Bold(true).
+		Foreground(t.alertFg).
+		Background(t.bad))
+}
+
+// highlight stands in reverse video for a style's background color when
+// the theme has no colors
+func (t tuiTheme) highlight(style lipgloss.Style) lipgloss.Style {
+	if t.noColor {
+		return style.Reverse(true)
+	}
+	return style
+}
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+var (
+	autoThrottle        bool
+	throttleMaxInterval time.Duration
+)
+
+// Throttling timing: how long an overflow must last before the interval is
+// raised, and how long the consumer must keep up before each step back
+const (
+	throttleSustain = 3 * time.Second
+	throttleRestore = 30 * time.Second
+)
+
+// throttledDevice is the telemetry interval state of one device
+type throttledDevice struct {
+	base      time.Duration // Interval before throttling (0 = not known yet)
+	current   time.Duration // Interval set by the throttle (0 = not throttled)
+	lastState time.Time     // Last STATE_DATA, for measuring the interval
+}
+
+// telemetryThrottle closes the loop on backpressure: when a lossy consumer
+// keeps dropping events, it raises the telemetry interval of every device
+// with TELEMETRY_CONFIG, doubling it each time the overflow persists (up to
+// maxInterval); once the consumer has kept up for a while, it halves the
+// interval again until the original is restored. Each change is published
+// as a TelemetryThrottled event.
+//
+// A device's original interval comes from --telemetry-interval, the last
+// TELEMETRY_CONFIG seen on the bus, or the measured STATE_DATA interval.
+type telemetryThrottle struct {
+	bus         *events.Bus
+	conn        io.Writer
+	watched     *events.Subscription // The consumer whose drops are watched
+	maxInterval time.Duration
+
+	devices     map[uint64]*throttledDevice
+	lastDropped uint64
+	dropped     uint64    // Events dropped in the current overflow
+	overflowAt  time.Time // Start of the current overflow (zero = none)
+	calmSince   time.Time // When the consumer last dropped an event
+}
+
+func newTelemetryThrottle(bus *events.Bus, conn io.Writer, watched *events.Subscription) *telemetryThrottle {
+	return &telemetryThrottle{
+		bus:         bus,
+		conn:        conn,
+		watched:     watched,
+		maxInterval: throttleMaxInterval,
+		devices:     make(map[uint64]*throttledDevice),
+		calmSince:   time.Now(),
+	}
+}
+
+// run tracks devices and checks the consumer every second until done is
+// closed, then restores every throttled device
+func (t *telemetryThrottle) run(done <-chan struct{}) {
+	// Lossless, so no device is missed; tracking is cheap enough not to
+	// hold up the publisher
+	sub := t.bus.SubscribeLossless(64)
+	defer sub.Close()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			t.restoreAll(time.Now())
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if e, ok := e.(events.PacketReceived); ok {
+				t.observe(e.At, e.Packet)
+			}
+		case now := <-ticker.C:
+			t.check(now)
+		}
+	}
+}
+
+// observe learns devices and their telemetry intervals
+func (t *telemetryThrottle) observe(at time.Time, p *fusain.Packet) {
+	switch p.Type() {
+	case fusain.MsgTelemetryConfig:
+		// Another controller configured the device; adopt its interval
+		// unless it is ours
+		enabled, _ := fusain.GetMapBool(p.PayloadMap(), 0)
+		interval, _ := fusain.GetMapUint(p.PayloadMap(), 1)
+		dev := t.device(p.Address())
+		if enabled && interval > 0 && time.Duration(interval)*time.Millisecond != dev.current {
+			dev.base = time.Duration(interval) * time.Millisecond
+			dev.current = 0
+		}
+	case fusain.MsgStateData:
+		dev := t.device(p.Address())
+		if telemetryInterval > 0 {
+			dev.base = telemetryInterval
+		} else if dev.current == 0 && !dev.lastState.IsZero() {
+			// Smooth the measured interval; throttled intervals aren't
+			// the device's own
+			gap := at.Sub(dev.lastState)
+			if dev.base == 0 {
+				dev.base = gap
+			} else {
+				dev.base = (dev.base*7 + gap) / 8
+			}
+		}
+		dev.lastState = at
+	}
+}
+
+func (t *telemetryThrottle) device(address uint64) *throttledDevice {
+	dev, ok := t.devices[address]
+	if !ok {
+		dev = &throttledDevice{}
+		t.devices[address] = dev
+	}
+	return dev
+}
+
+// check raises the intervals after a sustained overflow and lowers them
+// after a calm period
+func (t *telemetryThrottle) check(now time.Time) {
+	dropped := t.watched.Dropped()
+	delta := dropped - t.lastDropped
+	t.lastDropped = dropped
+
+	if delta > 0 {
+		t.dropped += delta
+		t.calmSince = now
+		if t.overflowAt.IsZero() {
+			t.overflowAt = now
+		}
+		if now.Sub(t.overflowAt) >= throttleSustain {
+			t.raise(now)
+			t.overflowAt = now // Give the new interval time to help
+			t.dropped = 0
+		}
+		return
+	}
+
+	t.overflowAt = time.Time{}
+	t.dropped = 0
+	if now.Sub(t.calmSince) >= throttleRestore {
+		t.lower(now)
+		t.calmSince = now
+	}
+}
+
+// raise doubles the interval of every device with a known interval
+func (t *telemetryThrottle) raise(now time.Time) {
+	for address, dev := range t.devices {
+		if dev.base <= 0 {
+			continue
+		}
+		previous := max(dev.current, dev.base)
+		next := min(previous*2, t.maxInterval)
+		if next <= previous {
+			continue
+		}
+		if t.configure(address, next) == nil {
+			dev.current = next
+			t.bus.Publish(events.TelemetryThrottled{At: now, Address: address, Previous: previous, Interval: next, Dropped: t.dropped})
+		}
+	}
+}
+
+// lower halves the interval of every throttled device, down to its
+// original interval
+func (t *telemetryThrottle) lower(now time.Time) {
+	for address, dev := range t.devices {
+		if dev.current == 0 {
+			continue
+		}
+		previous := dev.current
+		next := previous / 2
+		restored := next <= dev.base
+		if restored {
+			next = dev.base
+		}
+		if t.configure(address, next) == nil {
+			dev.current = next
+			if restored {
+				dev.current = 0
+			}
+			t.bus.Publish(events.TelemetryThrottled{At: now, Address: address, Previous: previous, Interval: next, Restored: restored})
+		}
+	}
+}
+
+// restoreAll sends every throttled device its original interval, so
+// devices aren't left throttled after heliostat exits
+func (t *telemetryThrottle) restoreAll(now time.Time) {
+	for address, dev := range t.devices {
+		if dev.current != 0 && t.configure(address, dev.base) == nil {
+			t.bus.Publish(events.TelemetryThrottled{At: now, Address: address, Previous: dev.current, Interval: dev.base, Restored: true})
+			dev.current = 0
+		}
+	}
+}
+
+// configure sends TELEMETRY_CONFIG with the given interval
+func (t *telemetryThrottle) configure(address uint64, interval time.Duration) error {
+	p := fusain.NewTelemetryConfig(address, true, uint32(interval/time.Millisecond))
+	if err := checkCommandPolicy(p); err != nil {
+		return err
+	}
+	_, err := t.conn.Write(fusain.MustEncodePacket(p))
+	return err
+}
+
+// throttleLogMessage describes a TelemetryThrottled event for event logs
+func throttleLogMessage(e events.TelemetryThrottled) string {
+	switch {
+	case e.Restored:
+		return fmt.Sprintf("Telemetry of %s restored to %v", formatAddress(e.Address), e.Interval)
+	case e.Interval > e.Previous:
+		return fmt.Sprintf("Throttled telemetry of %s: %v -> %v (%d events dropped)", formatAddress(e.Address), e.Previous, e.Interval, e.Dropped)
+	}
+	return fmt.Sprintf("Telemetry of %s lowered: %v -> %v", formatAddress(e.Address), e.Previous, e.Interval)
+}
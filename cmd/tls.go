@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	tlsCertPath string
+	tlsKeyPath  string
+
+	// serverTLS is set up from --tls-cert/--tls-key before any command
+	// runs; nil serves plaintext
+	serverTLS *tls.Config
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&tlsCertPath, "tls-cert", "", "TLS certificate (PEM) for serve, simulate --listen and --metrics")
+	rootCmd.PersistentFlags().StringVar(&tlsKeyPath, "tls-key", "", "TLS private key (PEM) for --tls-cert")
+}
+
+// setupServerTLS loads --tls-cert and --tls-key
+func setupServerTLS() error {
+	if tlsCertPath == "" && tlsKeyPath == "" {
+		return nil
+	}
+	if tlsCertPath == "" || tlsKeyPath == "" {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	store := &certStore{certPath: tlsCertPath, keyPath: tlsKeyPath}
+	if _, err := store.get(); err != nil {
+		return err
+	}
+	serverTLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return store.get()
+		},
+	}
+	return nil
+}
+
+// certStore reloads the certificate when either file changes, so renewed
+// certificates (certbot, ...) are served without a restart
+type certStore struct {
+	certPath, keyPath string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time // Newest modification time of the loaded files
+}
+
+func (s *certStore) get() (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	modified, err := newestModTime(s.certPath, s.keyPath)
+	if err != nil {
+		if s.cert != nil {
+			return s.cert, nil
+		}
+		return nil, fmt.Errorf("cannot read TLS certificate: %v", err)
+	}
+	if s.cert != nil && !modified.After(s.modified) {
+		return s.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certPath, s.keyPath)
+	if err != nil {
+		// Keep serving the old certificate while a renewal is half written
+		if s.cert != nil {
+			return s.cert, nil
+		}
+		return nil, fmt.Errorf("cannot load TLS certificate: %v", err)
+	}
+	s.cert = &cert
+	s.modified = modified
+	return s.cert, nil
+}
+
+func newestModTime(paths ...string) (time.Time, error) {
+	var newest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// listenServer opens a TCP listener on addr for an HTTP server, wrapped in
+// TLS when --tls-cert is set
+func listenServer(addr string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if serverTLS == nil {
+		return listener, nil
+	}
+	return tls.NewListener(listener, serverTLS), nil
+}
+
+// serverTransport names the listener security for startup messages
+func serverTransport() string {
+	if serverTLS == nil {
+		return "plaintext"
+	}
+	return "TLS"
+}
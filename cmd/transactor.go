@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// transactorTimeout is how long a sent command waits for an error reply
+// before it is considered accepted
+const transactorTimeout = 2 * time.Second
+
+// pendingCommand is a command sent to a device that may still be rejected
+type pendingCommand struct {
+	msgType uint8
+	sentAt  time.Time
+}
+
+// transactor tracks recently sent commands per device address so that
+// ERROR_INVALID_CMD and ERROR_STATE_REJECT replies can be correlated back to
+// the command that caused them. Fusain error replies carry no request ID, so
+// the oldest unexpired command to the replying address is assumed to match.
+type transactor struct {
+	pending map[uint64][]pendingCommand
+	timeout time.Duration
+}
+
+// newTransactor creates a transactor with the default reply timeout
+func newTransactor() *transactor {
+	return &transactor{
+		pending: make(map[uint64][]pendingCommand),
+		timeout: transactorTimeout,
+	}
+}
+
+// track records a command that was just transmitted. Expired commands are
+// left for settle, so every command is reported as accepted or rejected.
+func (t *transactor) track(p *fusain.Packet) {
+	switch p.Type() {
+	case fusain.MsgPingRequest, fusain.MsgDiscoveryRequest:
+		// Answered by responses, not error replies
+		return
+	}
+	t.pending[p.Address()] = append(t.pending[p.Address()], pendingCommand{
+		msgType: p.Type(),
+		sentAt:  time.Now(),
+	})
+}
+
+// settle removes commands whose reply timeout passed without an error
+// reply, returning them by address. Such commands were accepted.
+func (t *transactor) settle() map[uint64][]pendingCommand {
+	settled := make(map[uint64][]pendingCommand)
+	for address := range t.pending {
+		if expired := t.expire(address); len(expired) > 0 {
+			settled[address] = expired
+		}
+	}
+	return settled
+}
+
+// correlate matches an error reply to the command that caused it.
+// Returns false if no pending command to the replying address is found.
+func (t *transactor) correlate(reply *fusain.Packet) (pendingCommand, bool) {
+	address := reply.Address()
+	t.expire(address)
+
+	queue := t.pending[address]
+	if len(queue) == 0 {
+		return pendingCommand{}, false
+	}
+
+	cmd := queue[0]
+	if len(queue) == 1 {
+		delete(t.pending, address)
+	} else {
+		t.pending[address] = queue[1:]
+	}
+	return cmd, true
+}
+
+// reset forgets all pending commands (e.g., after reconnecting)
+func (t *transactor) reset() {
+	t.pending = make(map[uint64][]pendingCommand)
+}
+
+// expire drops pending commands to address older than the reply timeout
+// and returns them
+func (t *transactor) expire(address uint64) []pendingCommand {
+	queue := t.pending[address]
+	i := 0
+	for i < len(queue) && time.Since(queue[i].sentAt) > t.timeout {
+		i++
+	}
+	if i == len(queue) {
+		delete(t.pending, address)
+	} else if i > 0 {
+		t.pending[address] = queue[i:]
+	}
+	return queue[:i]
+}
+
+// describeErrorReply returns a human-readable reason for a device error reply
+func describeErrorReply(reply *fusain.Packet) string {
+	payloadMap := reply.PayloadMap()
+
+	switch reply.Type() {
+	case fusain.MsgErrorStateReject:
+		// CBOR keys: 0=state that rejected the command
+		state, _ := fusain.GetMapUint(payloadMap, 0)
+		return fmt.Sprintf("device in %s state", fusain.FormatState(uint32(state)))
+
+	case fusain.MsgErrorInvalidCmd:
+		// CBOR keys: 0=error code
+		code, _ := fusain.GetMapInt(payloadMap, 0)
+		switch code {
+		case 1:
+			return "invalid parameter value"
+		case 2:
+			return "invalid device index"
+		}
+		return fmt.Sprintf("invalid command (code %d)", code)
+	}
+
+	return fusain.FormatMessageType(reply.Type())
+}
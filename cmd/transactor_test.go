@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// errorReply is an ERROR_INVALID_CMD from address
+func errorReply(address uint64) *fusain.Packet {
+	return fusain.NewPacketWithPayload(address, fusain.MsgErrorInvalidCmd, map[int]interface{}{0: int64(1)})
+}
+
+// age backdates the pending commands to address by d
+func (t *transactor) age(address uint64, d time.Duration) {
+	for i := range t.pending[address] {
+		t.pending[address][i].sentAt = t.pending[address][i].sentAt.Add(-d)
+	}
+}
+
+func TestTransactorCorrelate(t *testing.T) {
+	tr := newTransactor()
+	tr.track(fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil))
+	tr.track(fusain.NewMotorCommand(1, 0, 2000))
+	tr.track(fusain.NewGlowCommand(1, 0, 1000))
+	tr.track(fusain.NewPumpCommand(2, 0, 500))
+	tr.track(fusain.NewPingRequest(1)) // Not tracked
+
+	// Replies match the oldest pending command to the replying device
+	for _, want := range []uint8{fusain.MsgStateCommand, fusain.MsgMotorCommand, fusain.MsgGlowCommand} {
+		cmd, ok := tr.correlate(errorReply(1))
+		if !ok || cmd.msgType != want {
+			t.Fatalf("got %s (ok=%v), want %s", fusain.FormatMessageType(cmd.msgType), ok, fusain.FormatMessageType(want))
+		}
+	}
+	if _, ok := tr.correlate(errorReply(1)); ok {
+		t.Error("reply matched with nothing pending")
+	}
+	if _, ok := tr.correlate(errorReply(3)); ok {
+		t.Error("reply from a device with no commands matched")
+	}
+	if cmd, ok := tr.correlate(errorReply(2)); !ok || cmd.msgType != fusain.MsgPumpCommand {
+		t.Errorf("device 2: got %s (ok=%v), want PUMP_COMMAND", fusain.FormatMessageType(cmd.msgType), ok)
+	}
+	if len(tr.pending) != 0 {
+		t.Errorf("%d addresses still pending", len(tr.pending))
+	}
+}
+
+func TestTransactorExpiry(t *testing.T) {
+	tr := newTransactor()
+	tr.track(fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil))
+	tr.age(1, transactorTimeout+time.Second)
+	tr.track(fusain.NewMotorCommand(1, 0, 2000))
+
+	// The expired command is skipped; the reply belongs to the newer one
+	if cmd, ok := tr.correlate(errorReply(1)); !ok || cmd.msgType != fusain.MsgMotorCommand {
+		t.Errorf("got %s (ok=%v), want MOTOR_COMMAND", fusain.FormatMessageType(cmd.msgType), ok)
+	}
+
+	tr.track(fusain.NewGlowCommand(1, 0, 1000))
+	tr.age(1, transactorTimeout+time.Second)
+	if _, ok := tr.correlate(errorReply(1)); ok {
+		t.Error("reply matched an expired command")
+	}
+}
+
+func TestTransactorSettle(t *testing.T) {
+	tr := newTransactor()
+	tr.track(fusain.NewStateCommand(1, uint8(fusain.ModeFan), nil))
+	tr.track(fusain.NewMotorCommand(1, 0, 2000))
+	tr.track(fusain.NewPumpCommand(2, 0, 500))
+	tr.age(1, transactorTimeout+time.Second)
+	tr.track(fusain.NewGlowCommand(1, 0, 1000))
+
+	settled := tr.settle()
+	if len(settled) != 1 || len(settled[1]) != 2 {
+		t.Fatalf("settled %v, want the two old commands to device 1", settled)
+	}
+	if settled[1][0].msgType != fusain.MsgStateCommand || settled[1][1].msgType != fusain.MsgMotorCommand {
+		t.Errorf("settled %v out of order", settled[1])
+	}
+	if len(tr.pending[1]) != 1 || len(tr.pending[2]) != 1 {
+		t.Errorf("pending %v, want one command each to devices 1 and 2", tr.pending)
+	}
+
+	tr.reset()
+	if len(tr.settle()) != 0 || len(tr.pending) != 0 {
+		t.Error("commands pending after reset")
+	}
+}
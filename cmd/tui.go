@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Thermoquad/heliostat/pkg/events"
 	"github.com/Thermoquad/heliostat/pkg/fusain"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -18,6 +19,33 @@ type errorLogEntry struct {
 	timestamp time.Time
 	message   string
 	isError   bool // true for errors, false for warnings
+	emergency bool // Emergency stop (highlighted)
+	repeats   int  // Identical entries folded into this one
+	lastSeen  time.Time
+}
+
+// emergencyStyle highlights emergency-stop entries in event logs, in the
+// colors of the theme chosen by setupTheme
+var emergencyStyle = theme.emergencyStyle()
+
+// emergencyLogMessage describes an urgent event for an event log, or
+// returns "" for other events
+func emergencyLogMessage(e events.Event) string {
+	if !events.IsUrgent(e) {
+		return ""
+	}
+	switch e := e.(type) {
+	case events.DeviceStateChanged:
+		return fmt.Sprintf("EMERGENCY STOP: %s entered E_STOP", formatAddress(e.Address))
+	case events.CommandSent:
+		return fmt.Sprintf("EMERGENCY STOP sent to %s", formatAddress(e.Packet.Address()))
+	case events.PacketReceived:
+		// STATE_DATA reporting E_STOP is covered by DeviceStateChanged
+		if e.Packet.Type() == fusain.MsgStateCommand {
+			return fmt.Sprintf("EMERGENCY STOP command on the bus for %s", formatAddress(e.Packet.Address()))
+		}
+	}
+	return ""
 }
 
 // Telemetry data
@@ -33,6 +61,15 @@ type telemetryData struct {
 	temperatures []float64
 	uptime       uint64 // milliseconds
 	hasUptime    bool
+
+	// Identity fields from the last PING_RESPONSE that had any (firmware
+	// version, build, ...; see fusain.DeviceInfo)
+	info []fusain.PayloadField
+
+	// Latest device timestamp (milliseconds) and the device it came from
+	deviceAddress uint64
+	deviceTime    uint64
+	hasDeviceTime bool
 }
 
 // TUI model
@@ -49,22 +86,21 @@ type model struct {
 	height        int
 	quitting      bool
 	lastTelemetry *telemetryData
+	link          *fusain.LinkMonitor
+
+	// showDeviceClock adds the estimated wall-clock time to the device
+	// timestamp ('t' toggles)
+	showDeviceClock bool
+
+	// inspector replaces the stats and event log when open ('i' toggles)
+	inspector packetInspector
+
+	// scrollback pauses, scrolls and searches the event log
+	scrollback logScrollback
 }
 
 // Messages
 type tickMsg time.Time
-type serialDataMsg struct {
-	packet           *fusain.Packet
-	decodeErr        error
-	validationErrors []fusain.ValidationError
-}
-type syncMsg struct {
-	invalidBytes int
-}
-type batchDataMsg struct {
-	messages []serialDataMsg
-	syncMsg  *syncMsg
-}
 
 // formatUptime formats uptime in milliseconds to human-friendly string
 func formatUptime(ms uint64) string {
@@ -146,13 +182,16 @@ func initialModel(connInfo string, statsInterval int, showAll bool) model {
 		connInfo:      connInfo,
 		statsInterval: statsInterval,
 		showAll:       showAll,
-		stats:         fusain.NewStatistics(),
+		stats:         newStatistics(),
 		errorLog:      make([]errorLogEntry, 0),
-		maxLogEntries: 100,
+		maxLogEntries: 1000,
+		link:          fusain.NewLinkMonitor(),
 		synchronized:  false,
 		invalidBytes:  0,
 		width:         80,
 		height:        24,
+
+		showDeviceClock: deviceTime,
 	}
 }
 
@@ -169,11 +208,25 @@ func tickCmd() tea.Cmd {
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if m.inspector.open && m.inspector.handleKey(msg.String()) {
+			return m, nil
+		}
+		if !m.inspector.open && m.scrollback.handleKey(msg, len(m.scrollback.filter(m.errorLog)), m.logHeight()) {
+			return m, nil
+		}
+		if msg.String() == "ctrl+c" {
 			m.quitting = true
 			return m, tea.Quit
 		}
+		switch keyAction(msg.String()) {
+		case actionQuit:
+			m.quitting = true
+			return m, tea.Quit
+		case actionDeviceClock:
+			m.showDeviceClock = !m.showDeviceClock
+		case actionInspect:
+			m.inspector.show()
+		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -184,74 +237,137 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.stats.CalculateRates()
 		return m, tickCmd()
 
-	case syncMsg:
-		m.synchronized = true
-		m.invalidBytes = msg.invalidBytes
-		if msg.invalidBytes > 0 {
-			m.addLogEntry(fmt.Sprintf("Synchronized after skipping %d invalid bytes", msg.invalidBytes), false)
-		} else {
-			m.addLogEntry("Synchronized", false)
-		}
-
-	case serialDataMsg:
-		m.processSerialData(msg)
-
-	case batchDataMsg:
-		// Handle sync message first
-		if msg.syncMsg != nil {
-			m.synchronized = true
-			m.invalidBytes = msg.syncMsg.invalidBytes
-			if msg.syncMsg.invalidBytes > 0 {
-				m.addLogEntry(fmt.Sprintf("Synchronized after skipping %d invalid bytes", msg.syncMsg.invalidBytes), false)
-			} else {
-				m.addLogEntry("Synchronized", false)
-			}
-		}
-		// Process all batched messages
-		for _, data := range msg.messages {
-			m.processSerialData(data)
+	case eventBatchMsg:
+		for _, e := range msg.events {
+			m.processEvent(e)
 		}
 	}
 
 	return m, nil
 }
 
-func (m *model) processSerialData(msg serialDataMsg) {
-	if msg.decodeErr != nil {
+func (m *model) processEvent(e events.Event) {
+	if msg := emergencyLogMessage(e); msg != "" {
+		m.addEmergencyLogEntry(msg)
+	}
+
+	switch e := e.(type) {
+	case events.Synchronized:
+		m.synchronized = true
+		m.invalidBytes = e.Skipped
+		if e.Skipped > 0 {
+			m.addLogEntry(fmt.Sprintf("Synchronized after skipping %d invalid bytes", e.Skipped), false)
+		} else {
+			m.addLogEntry("Synchronized", false)
+		}
+
+	case events.DecodeError:
 		if m.synchronized {
-			m.stats.Update(nil, msg.decodeErr, nil)
-			m.addLogEntry(fmt.Sprintf("DECODE ERROR: %v", msg.decodeErr), true)
+			m.stats.Update(nil, e.Err, nil)
+			m.link.RecordDecodeError(e.At, e.Err)
+			m.addLogEntry(fmt.Sprintf("DECODE ERROR: %v", e.Err), true)
 		}
-	} else if msg.packet != nil {
-		m.stats.Update(msg.packet, nil, msg.validationErrors)
+
+	case events.PacketReceived:
+		m.stats.Update(e.Packet, nil, e.Anomalies)
+		m.link.RecordPacket(e.At)
+		m.inspector.add(e.At, e.Packet, e.Anomalies)
 
 		// Parse telemetry data
-		m.parseTelemetry(msg.packet)
+		m.parseTelemetry(e.Packet)
+		m.recordDeviceTime(e.Packet)
 
-		if len(msg.validationErrors) > 0 {
+		if len(e.Anomalies) > 0 {
 			// Validation errors
-			msgType := fusain.FormatMessageType(msg.packet.Type())
-			for _, err := range msg.validationErrors {
-				m.addLogEntry(fmt.Sprintf("%s: %s", msgType, err.Message), true)
+			msgType := fusain.FormatMessageType(e.Packet.Type())
+			for _, err := range e.Anomalies {
+				if err.Check != "" {
+					m.addLogEntry(fmt.Sprintf("%s: [%s] %s", msgType, err.Check, err.Message), true)
+				} else {
+					m.addLogEntry(fmt.Sprintf("%s: %s", msgType, err.Message), true)
+				}
 			}
-		} else if msg.packet.Type() == fusain.MsgPingResponse {
+		} else if e.Packet.Type() == fusain.MsgPingResponse {
 			// Ping responses update telemetry silently (no log entry)
 			// The uptime will appear in the "Latest Telemetry" box
 		} else if m.showAll {
 			// Valid packet (only if --show-all)
-			msgType := fusain.FormatMessageType(msg.packet.Type())
+			msgType := fusain.FormatMessageType(e.Packet.Type())
 			m.addLogEntry(fmt.Sprintf("%s (valid)", msgType), false)
 		}
+
+	case events.DeviceStale:
+		m.stats.RecordStale([]fusain.ValidationError{e.Anomaly})
+		m.addLogEntry(e.Anomaly.Message, true)
+
+	case events.ConnectionLost:
+		m.addLogEntry(connectionLostMessage(e), true)
+
+	case events.ConnectionRestored:
+		m.connInfo = e.Info
+		m.addLogEntry(connectionRestoredMessage(e), false)
+
+	case events.TelemetryThrottled:
+		m.addLogEntry(throttleLogMessage(e), false)
+
+	case events.AlertRaised, events.AlertCleared:
+		m.appendLogEntry(alertLogEntry(e))
+
+	case events.DeviceStateChanged:
+		if msg := errorHintLogMessage(e); msg != "" {
+			m.addLogEntry(msg, true)
+		}
 	}
 }
 
+// renderLinkQuality renders the link-quality indicator shown in the TUI
+// headers, colored by level, with the main cause when the link is degraded
+func renderLinkQuality(q fusain.LinkQuality) string {
+	colors := map[fusain.LinkLevel]lipgloss.TerminalColor{
+		fusain.LinkUnknown: theme.muted,
+		fusain.LinkGood:    theme.good,
+		fusain.LinkFair:    theme.warning,
+		fusain.LinkPoor:    theme.bad,
+		fusain.LinkDown:    theme.bad,
+	}
+	style := lipgloss.NewStyle().Foreground(colors[q.Level])
+	if q.Level == fusain.LinkDown {
+		style = style.Bold(true)
+	}
+
+	text := fmt.Sprintf("Link %s %s", glyphs.link, q.Level)
+	if q.Level != fusain.LinkUnknown && q.Level != fusain.LinkDown {
+		text += fmt.Sprintf(" %d", q.Score)
+	}
+	if q.Reason != "" {
+		text += " (" + q.Reason + ")"
+	}
+	return style.Render(text)
+}
+
 func (m *model) addLogEntry(message string, isError bool) {
-	entry := errorLogEntry{
+	m.appendLogEntry(errorLogEntry{
 		timestamp: time.Now(),
 		message:   message,
 		isError:   isError,
+	})
+}
+
+func (m *model) addEmergencyLogEntry(message string) {
+	m.appendLogEntry(errorLogEntry{
+		timestamp: time.Now(),
+		message:   message,
+		isError:   true,
+		emergency: true,
+	})
+}
+
+func (m *model) appendLogEntry(entry errorLogEntry) {
+	if collapseRepeat(m.errorLog, entry) {
+		return
 	}
 	m.errorLog = append(m.errorLog, entry)
+	m.scrollback.added(entry)
 
 	// Keep only last N entries
 	if len(m.errorLog) > m.maxLogEntries {
@@ -356,6 +472,22 @@ func (m *model) parseTelemetry(packet *fusain.Packet) {
 	}
 }
 
+// recordDeviceTime keeps the latest device timestamp for the telemetry box
+func (m *model) recordDeviceTime(packet *fusain.Packet) {
+	deviceMs, ok := fusain.DeviceTime(packet)
+	if !ok || m.lastTelemetry == nil {
+		return
+	}
+	m.lastTelemetry.deviceAddress = packet.Address()
+	m.lastTelemetry.deviceTime = deviceMs
+	m.lastTelemetry.hasDeviceTime = true
+}
+
+// logHeight returns how many event log entries fit below the stats
+func (m model) logHeight() int {
+	return max(m.height-16, 5) // Reserve space for header and stats
+}
+
 func (m model) View() string {
 	if m.quitting {
 		return "Shutting down...\n"
@@ -366,117 +498,81 @@ func (m model) View() string {
 	// Styles
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("12")).
-		Background(lipgloss.Color("235")).
+		Foreground(theme.accent).
+		Background(theme.titleBg).
 		Padding(0, 1)
 
 	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241"))
+		Foreground(theme.muted)
 
 	statsLabelStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("12")).
+		Foreground(theme.accent).
 		Bold(true)
 
 	statsValueStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("10"))
+		Foreground(theme.good)
 
 	errorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("9")).
+		Foreground(theme.bad).
 		Bold(true)
 
 	warningStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("11"))
+		Foreground(theme.warning)
 
 	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		Border(glyphs.border).
+		BorderForeground(theme.border).
 		Padding(0, 1)
 
 	// Header
 	s.WriteString(titleStyle.Render("HELIOSTAT - ERROR DETECTION"))
 	s.WriteString("\n")
-	s.WriteString(headerStyle.Render(fmt.Sprintf("%s | Mode: %s | Press 'q' to quit",
+	s.WriteString(headerStyle.Render(fmt.Sprintf("%s | Mode: %s | '%s' quit, '%s' device clock, '%s' inspect | ",
 		m.connInfo, func() string {
 			if m.showAll {
 				return "All packets"
 			}
 			return "Errors only"
-		}())))
+		}(), keyHelp(actionQuit), keyHelp(actionDeviceClock), keyHelp(actionInspect))))
+	s.WriteString(renderLinkQuality(m.link.Quality(time.Now())))
+	if filter := deviceFilter.summary(); filter != "" {
+		s.WriteString(headerStyle.Render(" | " + filter))
+	}
 	s.WriteString("\n\n")
 
 	// Sync status
 	if !m.synchronized {
-		s.WriteString(warningStyle.Render("⏳ Waiting for synchronization..."))
+		s.WriteString(warningStyle.Render(glyphs.waiting + " Waiting for synchronization..."))
 		s.WriteString("\n\n")
 	} else {
-		s.WriteString(statsValueStyle.Render("✓ Synchronized"))
+		s.WriteString(statsValueStyle.Render(glyphs.ok + " Synchronized"))
 		if m.invalidBytes > 0 {
 			s.WriteString(headerStyle.Render(fmt.Sprintf(" (skipped %d invalid bytes)", m.invalidBytes)))
 		}
 		s.WriteString("\n\n")
 	}
 
+	if m.inspector.open {
+		s.WriteString(m.inspector.view(m.width, m.height-5, inspectorStyles{
+			label:  statsLabelStyle,
+			header: headerStyle,
+			error:  errorStyle,
+			box:    boxStyle,
+		}))
+		return s.String()
+	}
+
 	// Statistics
 	m.stats.CalculateRates()
-	var validPercent, errorPercent float64
-	if m.stats.TotalPackets > 0 {
-		validPercent = float64(m.stats.ValidPackets) * 100.0 / float64(m.stats.TotalPackets)
-		totalErrors := m.stats.CRCErrors + m.stats.DecodeErrors + m.stats.MalformedPackets + m.stats.AnomalousValues
-		errorPercent = float64(totalErrors) * 100.0 / float64(m.stats.TotalPackets)
-	}
-
-	statsContent := strings.Builder{}
-	statsContent.WriteString(fmt.Sprintf("%s %s   %s %s   %s %s\n",
-		statsLabelStyle.Render("Total:"), statsValueStyle.Render(fmt.Sprintf("%d", m.stats.TotalPackets)),
-		statsLabelStyle.Render("Valid:"), statsValueStyle.Render(fmt.Sprintf("%d (%.1f%%)", m.stats.ValidPackets, validPercent)),
-		statsLabelStyle.Render("Errors:"), errorStyle.Render(fmt.Sprintf("%d (%.1f%%)", m.stats.CRCErrors+m.stats.DecodeErrors+m.stats.MalformedPackets+m.stats.AnomalousValues, errorPercent)),
-	))
-
-	if m.stats.CRCErrors > 0 || m.stats.DecodeErrors > 0 {
-		statsContent.WriteString(fmt.Sprintf("%s %s   %s %s\n",
-			statsLabelStyle.Render("CRC Errors:"), errorStyle.Render(fmt.Sprintf("%d", m.stats.CRCErrors)),
-			statsLabelStyle.Render("Decode Errors:"), errorStyle.Render(fmt.Sprintf("%d", m.stats.DecodeErrors)),
-		))
-	}
-
-	if m.stats.MalformedPackets > 0 {
-		statsContent.WriteString(fmt.Sprintf("%s %s",
-			statsLabelStyle.Render("Malformed:"), errorStyle.Render(fmt.Sprintf("%d", m.stats.MalformedPackets)),
-		))
-		if m.stats.InvalidCounts > 0 || m.stats.LengthMismatches > 0 {
-			statsContent.WriteString(fmt.Sprintf(" (%s: %d, %s: %d)",
-				headerStyle.Render("invalid counts"), m.stats.InvalidCounts,
-				headerStyle.Render("length mismatches"), m.stats.LengthMismatches,
-			))
-		}
-		statsContent.WriteString("\n")
-	}
-
-	if m.stats.AnomalousValues > 0 {
-		statsContent.WriteString(fmt.Sprintf("%s %s",
-			statsLabelStyle.Render("Anomalous:"), warningStyle.Render(fmt.Sprintf("%d", m.stats.AnomalousValues)),
-		))
-		if m.stats.HighRPM > 0 || m.stats.InvalidTemp > 0 || m.stats.InvalidPWM > 0 {
-			statsContent.WriteString(fmt.Sprintf(" (%s: %d, %s: %d, %s: %d)",
-				headerStyle.Render("high RPM"), m.stats.HighRPM,
-				headerStyle.Render("invalid temp"), m.stats.InvalidTemp,
-				headerStyle.Render("invalid PWM"), m.stats.InvalidPWM,
-			))
-		}
-		statsContent.WriteString("\n")
-	}
-
-	statsContent.WriteString(fmt.Sprintf("%s %s   %s %s",
-		statsLabelStyle.Render("Packet Rate:"), statsValueStyle.Render(fmt.Sprintf("%.1f pkts/s", m.stats.PacketRate)),
-		statsLabelStyle.Render("Error Rate:"), func() string {
-			if m.stats.ErrorRate > 0 {
-				return errorStyle.Render(fmt.Sprintf("%.1f err/s", m.stats.ErrorRate))
-			}
-			return statsValueStyle.Render(fmt.Sprintf("%.1f err/s", m.stats.ErrorRate))
-		}(),
-	))
+	statsContent := renderStats(m.stats, appConfig().TUI.statsRows(), statsStyles{
+		label:   statsLabelStyle,
+		value:   statsValueStyle,
+		header:  headerStyle,
+		error:   errorStyle,
+		warning: warningStyle,
+	})
 
-	s.WriteString(boxStyle.Render(statsContent.String()))
+	s.WriteString(boxStyle.Render(statsContent))
 	s.WriteString("\n\n")
 
 	// Telemetry section - always show fixed height layout
@@ -506,6 +602,20 @@ func (m model) View() string {
 		statsLabelStyle.Render("Uptime:"), statsValueStyle.Render(uptimeStr),
 	))
 
+	// Device time - raw, with the estimated wall-clock time when toggled on
+	deviceTimeStr := "---"
+	if m.lastTelemetry != nil && m.lastTelemetry.hasDeviceTime {
+		deviceTimeStr = fmt.Sprintf("%d ms", m.lastTelemetry.deviceTime)
+		if m.showDeviceClock {
+			if at, ok := deviceClock.WallClock(m.lastTelemetry.deviceAddress, m.lastTelemetry.deviceTime); ok {
+				deviceTimeStr += " (" + at.Format("15:04:05.000") + ")"
+			}
+		}
+	}
+	telemetryContent.WriteString(fmt.Sprintf("%s %s\n",
+		statsLabelStyle.Render("Device Time:"), statsValueStyle.Render(fmt.Sprintf("%-29s", deviceTimeStr)),
+	))
+
 	// Motor 0 - always show
 	motorRPM := int64(0)
 	motorTarget := int64(0)
@@ -528,7 +638,7 @@ func (m model) View() string {
 	}
 	telemetryContent.WriteString(fmt.Sprintf("%s %s\n",
 		statsLabelStyle.Render("Temp 0: "),
-		statsValueStyle.Render(fmt.Sprintf("%7.1f°C", temp)),
+		statsValueStyle.Render(fmt.Sprintf("%9s", formatTemperature(temp))),
 	))
 
 	s.WriteString(boxStyle.Render(telemetryContent.String()))
@@ -536,35 +646,43 @@ func (m model) View() string {
 
 	// Error log
 	s.WriteString(statsLabelStyle.Render("Recent Events:"))
+	if status := m.scrollback.status(); status != "" {
+		s.WriteString(" ")
+		s.WriteString(warningStyle.Render("[" + status + "]"))
+		s.WriteString(headerStyle.Render("  End latest"))
+	} else {
+		s.WriteString(headerStyle.Render("  space pause, PgUp/PgDn scroll, '/' search"))
+	}
 	s.WriteString("\n")
 
-	// Calculate how many log entries we can show
-	logHeight := m.height - 15 // Reserve space for header and stats
-	if logHeight < 5 {
-		logHeight = 5
-	}
+	entries := m.scrollback.filter(m.errorLog)
+	startIdx, endIdx := m.scrollback.window(len(entries), m.logHeight())
 
 	logContent := strings.Builder{}
-	startIdx := len(m.errorLog) - logHeight
-	if startIdx < 0 {
-		startIdx = 0
-	}
-
-	if len(m.errorLog) == 0 {
-		logContent.WriteString(headerStyle.Render("  (no events yet)"))
+	if len(entries) == 0 {
+		if m.scrollback.search != "" {
+			logContent.WriteString(headerStyle.Render("  (no matching events)"))
+		} else {
+			logContent.WriteString(headerStyle.Render("  (no events yet)"))
+		}
 	} else {
-		for i := startIdx; i < len(m.errorLog); i++ {
-			entry := m.errorLog[i]
+		for i := startIdx; i < endIdx; i++ {
+			entry := entries[i]
 			timestamp := entry.timestamp.Format("01/02/06 15:04:05.000")
-			if entry.isError {
+			if entry.emergency {
+				logContent.WriteString(fmt.Sprintf("%s %s\n",
+					headerStyle.Render(timestamp),
+					emergencyStyle.Render(glyphs.emergency+" "+entry.message)+headerStyle.Render(entry.repeatSuffix()),
+				))
+			} else if entry.isError {
 				logContent.WriteString(fmt.Sprintf("%s %s\n",
 					headerStyle.Render(timestamp),
-					errorStyle.Render("✗ "+entry.message),
+					errorStyle.Render(glyphs.err+" "+entry.message)+headerStyle.Render(entry.repeatSuffix()),
 				))
 			} else {
 				logContent.WriteString(fmt.Sprintf("%s %s\n",
 					headerStyle.Render(timestamp),
-					warningStyle.Render("ℹ "+entry.message),
+					warningStyle.Render(glyphs.info+" "+entry.message)+headerStyle.Render(entry.repeatSuffix()),
 				))
 			}
 		}
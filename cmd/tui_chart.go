@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultChartWindow is how much telemetry history the charts show
+const defaultChartWindow = time.Minute
+
+// chartHeight is the number of rows of each chart's plot
+const chartHeight = 5
+
+// chartLabelWidth is the width of the scale labels left of a plot
+const chartLabelWidth = 8
+
+// controlChartWindow holds the control command's --chart-window flag value
+var controlChartWindow time.Duration
+
+// chartSample is one telemetry value with its target, if the device sent one
+type chartSample struct {
+	at        time.Time
+	value     float64
+	target    float64
+	hasTarget bool
+}
+
+// chartSeries is the recent history of one telemetry value
+type chartSeries struct {
+	samples []chartSample
+}
+
+// add appends a sample and drops those older than the window, keeping the
+// newest of them so the plot reaches its left edge
+func (s *chartSeries) add(sample chartSample, window time.Duration) {
+	s.samples = append(s.samples, sample)
+	cutoff := sample.at.Add(-window)
+	drop := 0
+	for drop < len(s.samples)-1 && !s.samples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	s.samples = s.samples[drop:]
+}
+
+// last returns the newest sample
+func (s *chartSeries) last() (chartSample, bool) {
+	if len(s.samples) == 0 {
+		return chartSample{}, false
+	}
+	return s.samples[len(s.samples)-1], true
+}
+
+// deviceCharts is the charted telemetry of one device: motor 0 RPM and
+// thermometer 0 temperature, each against its target
+type deviceCharts struct {
+	rpm  chartSeries
+	temp chartSeries
+}
+
+// chartStyles are the styles the charts are rendered with
+type chartStyles struct {
+	label, value, target, axis lipgloss.Style
+}
+
+// renderChart plots a series over the window ending at now in width x
+// chartHeight cells, the value against its target, with the scale on the
+// left. Each column shows the last sample of its time slice; columns before
+// the first sample stay empty.
+func renderChart(samples []chartSample, now time.Time, window time.Duration, width int, format func(float64) string, st chartStyles) string {
+	columns := max(width-chartLabelWidth-1, 1)
+	if len(samples) == 0 {
+		return st.axis.Render("(no data)") + strings.Repeat("\n", chartHeight-1)
+	}
+
+	// Sample for each column, carried forward across slices without one
+	start := now.Add(-window)
+	slice := window / time.Duration(columns)
+	plotted := make([]*chartSample, columns)
+	next := 0
+	var current *chartSample
+	for c := 0; c < columns; c++ {
+		end := start.Add(slice * time.Duration(c+1))
+		for next < len(samples) && !samples[next].at.After(end) {
+			current = &samples[next]
+			next++
+		}
+		plotted[c] = current
+	}
+
+	// Scale to the visible values and targets
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, sample := range plotted {
+		if sample == nil {
+			continue
+		}
+		lo, hi = min(lo, sample.value), max(hi, sample.value)
+		if sample.hasTarget {
+			lo, hi = min(lo, sample.target), max(hi, sample.target)
+		}
+	}
+	if math.IsInf(lo, 0) {
+		// Every sample is older than the window's first slice
+		lo, hi = samples[len(samples)-1].value, samples[len(samples)-1].value
+	}
+	if hi-lo < 1 {
+		lo, hi = lo-1, hi+1
+	}
+	row := func(v float64) int {
+		return chartHeight - 1 - int(math.Round((v-lo)/(hi-lo)*float64(chartHeight-1)))
+	}
+
+	grid := make([][]string, chartHeight)
+	for r := range grid {
+		grid[r] = make([]string, columns)
+		for c := range grid[r] {
+			grid[r][c] = " "
+		}
+	}
+	for c, sample := range plotted {
+		if sample == nil {
+			continue
+		}
+		if sample.hasTarget {
+			grid[row(sample.target)][c] = st.target.Render(glyphs.chartTarget)
+		}
+		grid[row(sample.value)][c] = st.value.Render(glyphs.chartValue)
+	}
+
+	var s strings.Builder
+	for r := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = format(hi)
+		case chartHeight - 1:
+			label = format(lo)
+		}
+		s.WriteString(st.axis.Render(fmt.Sprintf("%*s", chartLabelWidth, label)))
+		s.WriteString(st.axis.Render("|"))
+		s.WriteString(strings.Join(grid[r], ""))
+		if r < chartHeight-1 {
+			s.WriteString("\n")
+		}
+	}
+	return s.String()
+}
+
+// chartTitle describes the newest sample above a chart
+func chartTitle(name string, series *chartSeries, format func(float64) string, st chartStyles) string {
+	title := st.label.Render(name)
+	sample, ok := series.last()
+	if !ok {
+		return title
+	}
+	title += " " + st.value.Render(format(sample.value))
+	if sample.hasTarget {
+		title += st.axis.Render(" / " + format(sample.target) + " target")
+	}
+	return title
+}
+
+// renderDeviceCharts renders the RPM and temperature charts side by side in
+// width cells
+func renderDeviceCharts(charts *deviceCharts, now time.Time, window time.Duration, width int, st chartStyles) string {
+	chartWidth := max((width-3)/2, chartLabelWidth+10)
+	formatRPM := func(v float64) string {
+		return fmt.Sprintf("%.0f", v)
+	}
+	formatTemp := func(v float64) string {
+		return fmt.Sprintf("%.0f%s", v, temperatureUnit())
+	}
+	// Temperatures are charted in display units
+	temps := make([]chartSample, len(charts.temp.samples))
+	for i, sample := range charts.temp.samples {
+		sample.value = fusain.ConvertTemperature(sample.value, displayUnits)
+		sample.target = fusain.ConvertTemperature(sample.target, displayUnits)
+		temps[i] = sample
+	}
+	tempSeries := &chartSeries{samples: temps}
+
+	box := lipgloss.NewStyle().Width(chartWidth)
+	rpm := chartTitle("RPM", &charts.rpm, formatRPM, st) + "\n" +
+		renderChart(charts.rpm.samples, now, window, chartWidth, formatRPM, st)
+	temp := chartTitle("Temperature", tempSeries, formatTemp, st) + "\n" +
+		renderChart(temps, now, window, chartWidth, formatTemp, st)
+	return lipgloss.JoinHorizontal(lipgloss.Top, box.Render(rpm), "   ", box.Render(temp))
+}
+
+// chartWindow returns the charts' time window: --chart-window, the config
+// file's chart_window, or the default
+func (c TUIConfig) chartWindow() time.Duration {
+	if controlChartWindow > 0 {
+		return controlChartWindow
+	}
+	if window, err := time.ParseDuration(c.ChartWindow); err == nil && window > 0 {
+		return window
+	}
+	return defaultChartWindow
+}
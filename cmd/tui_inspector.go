@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// inspectorCapacity is how many recent packets the packet inspector keeps
+const inspectorCapacity = 500
+
+// inspectorListWidth is the width of the packet list, inside its border
+const inspectorListWidth = 38
+
+// inspectedPacket is a received packet kept for the packet inspector
+type inspectedPacket struct {
+	at        time.Time
+	packet    *fusain.Packet
+	anomalies []fusain.ValidationError
+}
+
+// packetInspector is the TUI packet inspector ('i'): a list of recent
+// packets where the arrow keys select one, next to a detail pane showing
+// its wire bytes, CRC, CBOR and decoded fields
+type packetInspector struct {
+	open     bool
+	packets  []inspectedPacket
+	selected int  // Index into packets
+	follow   bool // Selection tracks the newest packet
+}
+
+// add keeps a received packet, dropping the oldest beyond capacity. The
+// selection stays on its packet unless it follows the newest.
+func (pi *packetInspector) add(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) {
+	pi.packets = append(pi.packets, inspectedPacket{at, p, anomalies})
+	if len(pi.packets) > inspectorCapacity {
+		pi.packets = pi.packets[1:]
+		pi.selected = max(pi.selected-1, 0)
+	}
+	if pi.follow {
+		pi.selected = len(pi.packets) - 1
+	}
+}
+
+// show opens the inspector on the newest packet
+func (pi *packetInspector) show() {
+	pi.open = true
+	pi.follow = true
+	pi.selected = max(len(pi.packets)-1, 0)
+}
+
+// handleKey applies an inspector key, reporting whether it was used
+func (pi *packetInspector) handleKey(key string) bool {
+	last := len(pi.packets) - 1
+	switch key {
+	case "up", "k":
+		pi.selected--
+	case "down", "j":
+		pi.selected++
+	case "pgup":
+		pi.selected -= 10
+	case "pgdown":
+		pi.selected += 10
+	case "home", "g":
+		pi.selected = 0
+	case "end", "G":
+		pi.selected = last
+	case "esc":
+		pi.open = false
+		return true
+	default:
+		if keyAction(key) == actionInspect {
+			pi.open = false
+			return true
+		}
+		return false
+	}
+	pi.selected = max(min(pi.selected, last), 0)
+	pi.follow = pi.selected == last
+	return true
+}
+
+// inspectorStyles are the View styles used by the inspector
+type inspectorStyles struct {
+	label, header, error lipgloss.Style
+	box                  lipgloss.Style
+}
+
+// view renders the list and detail panes in width x height cells
+func (pi *packetInspector) view(width, height int, st inspectorStyles) string {
+	rows := max(height-3, 3) // Box borders and the key hint
+	detailWidth := max(width-inspectorListWidth-8, 20)
+
+	var list strings.Builder
+	if len(pi.packets) == 0 {
+		list.WriteString(st.header.Render("(no packets yet)"))
+		list.WriteString("\n")
+	}
+	start := max(min(pi.selected-rows/2, len(pi.packets)-rows), 0)
+	end := min(start+rows, len(pi.packets))
+	selectedStyle := lipgloss.NewStyle().Reverse(true)
+	for i := start; i < end; i++ {
+		entry := pi.packets[i]
+		mark := " "
+		if len(entry.anomalies) > 0 {
+			mark = glyphs.err
+		}
+		name := fusain.FormatMessageType(entry.packet.Type())
+		if len(name) > 18 {
+			name = name[:18]
+		}
+		row := fmt.Sprintf("%s %-18s %04X %s", entry.at.Format("15:04:05.000"), name,
+			entry.packet.Address()&0xFFFF, mark)
+		switch {
+		case i == pi.selected:
+			row = selectedStyle.Render(row)
+		case len(entry.anomalies) > 0:
+			row = st.error.Render(row)
+		}
+		list.WriteString(row)
+		list.WriteString("\n")
+	}
+	for i := end - start; i < rows; i++ {
+		list.WriteString("\n")
+	}
+	position := "live"
+	if !pi.follow {
+		position = fmt.Sprintf("%d/%d", pi.selected+1, len(pi.packets))
+	}
+	list.WriteString(st.header.Render(fmt.Sprintf("%-10s up/down End Esc", position)))
+
+	detail := "Select a packet"
+	if pi.selected < len(pi.packets) {
+		detail = pi.packets[pi.selected].detail(detailWidth, st)
+	}
+	fit := lipgloss.NewStyle().Width(detailWidth).MaxHeight(rows + 1)
+
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		st.box.Width(inspectorListWidth+2).Render(list.String()),
+		st.box.Render(fit.Render(detail)))
+}
+
+// detail renders the detail pane for a packet
+func (e inspectedPacket) detail(width int, st inspectorStyles) string {
+	p := e.packet
+	var s strings.Builder
+	line := func(label, value string) {
+		s.WriteString(st.label.Render(label))
+		s.WriteString(" ")
+		s.WriteString(value)
+		s.WriteString("\n")
+	}
+
+	line("Type:", fmt.Sprintf("%s (0x%02X)", fusain.FormatMessageType(p.Type()), p.Type()))
+	line("Address:", formatAddress(p.Address()))
+	line("Received:", e.at.Format("2006-01-02 15:04:05.000"))
+	line("CRC:", fmt.Sprintf("0x%04X   Length: %d bytes CBOR", p.CRC(), p.Length()))
+
+	// Wire bytes as received (or re-framed from the payload), and the frame
+	// contents the CRC covers
+	frame := p.WireFrame()
+	perRow := max((width-2)/3/8*8, 8)
+	s.WriteString(st.label.Render(fmt.Sprintf("Wire (%d bytes):", len(frame))))
+	s.WriteString("\n")
+	s.WriteString(hexRows(frame, perRow))
+	if data, err := fusain.UnstuffFrame(frame); err == nil {
+		s.WriteString(st.label.Render(fmt.Sprintf("Unstuffed (%d bytes):", len(data))))
+		s.WriteString("\n")
+		s.WriteString(hexRows(data, perRow))
+	}
+
+	s.WriteString(st.label.Render("CBOR:"))
+	s.WriteString("\n  ")
+	s.WriteString(fusain.FormatCBORDiagnostic(p.PayloadRaw()))
+	s.WriteString("\n")
+
+	s.WriteString(st.label.Render("Fields:"))
+	s.WriteString("\n")
+	fields := fusain.PayloadFields(p)
+	if len(fields) == 0 {
+		s.WriteString(st.header.Render("  (none)"))
+		s.WriteString("\n")
+	}
+	for _, f := range fields {
+		s.WriteString(fmt.Sprintf("  %s = %s\n", f.Name, csvValue(f.Value)))
+	}
+
+	if len(e.anomalies) > 0 {
+		s.WriteString(st.label.Render("Anomalies:"))
+		s.WriteString("\n")
+		for _, a := range e.anomalies {
+			msg := a.Message
+			if a.Check != "" {
+				msg = "[" + a.Check + "] " + msg
+			}
+			s.WriteString(st.error.Render("  " + glyphs.err + " " + msg))
+			s.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+// hexRows formats data as indented rows of space-separated hex bytes
+func hexRows(data []byte, perRow int) string {
+	if len(data) == 0 {
+		return "  (none)\n"
+	}
+	var s strings.Builder
+	for i := 0; i < len(data); i += perRow {
+		fmt.Fprintf(&s, "  % X\n", data[i:min(i+perRow, len(data))])
+	}
+	return s.String()
+}
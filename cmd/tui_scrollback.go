@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logScrollback is the event log's pause, scrollback and search state.
+// offset counts the matching entries hidden below the view; while paused or
+// scrolled back, new entries grow it so the view holds still.
+type logScrollback struct {
+	paused    bool
+	offset    int
+	search    string // Only entries containing this are shown
+	searching bool   // Typing the search ('/')
+}
+
+// frozen reports whether new entries are kept below the view
+func (l *logScrollback) frozen() bool {
+	return l.paused || l.offset > 0
+}
+
+// matches reports whether an entry passes the search
+func (l *logScrollback) matches(entry errorLogEntry) bool {
+	return l.search == "" || strings.Contains(strings.ToLower(entry.message), strings.ToLower(l.search))
+}
+
+// added keeps the view still when a new entry arrives while frozen
+func (l *logScrollback) added(entry errorLogEntry) {
+	if l.frozen() && l.matches(entry) {
+		l.offset++
+	}
+}
+
+// filter returns the entries that pass the search
+func (l *logScrollback) filter(entries []errorLogEntry) []errorLogEntry {
+	if l.search == "" {
+		return entries
+	}
+	var matched []errorLogEntry
+	for _, entry := range entries {
+		if l.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// latest jumps back to the newest entries and resumes
+func (l *logScrollback) latest() {
+	l.paused = false
+	l.offset = 0
+}
+
+// handleKey applies a scrollback key, reporting whether it was used. total
+// is the number of matching entries and page the visible log height.
+func (l *logScrollback) handleKey(msg tea.KeyMsg, total, page int) bool {
+	if l.searching {
+		switch msg.Type {
+		case tea.KeyEnter:
+			l.searching = false
+		case tea.KeyEsc:
+			l.searching = false
+			l.search = ""
+			l.offset = 0
+		case tea.KeyBackspace:
+			if r := []rune(l.search); len(r) > 0 {
+				l.search = string(r[:len(r)-1])
+				l.offset = 0
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			l.search += string(msg.Runes)
+			l.offset = 0
+		case tea.KeyCtrlC:
+			return false
+		}
+		return true
+	}
+
+	switch msg.String() {
+	case " ":
+		if l.frozen() {
+			l.latest()
+		} else {
+			l.paused = true
+		}
+	case "pgup":
+		l.offset = min(l.offset+page, max(total-page, 0))
+	case "pgdown":
+		l.offset = max(l.offset-page, 0)
+	case "end", "G":
+		l.latest()
+	case "/":
+		l.searching = true
+	case "esc":
+		if l.search == "" {
+			return false
+		}
+		l.search = ""
+		l.offset = 0
+	default:
+		return false
+	}
+	return true
+}
+
+// window returns the range of entries (out of total) shown in page lines
+func (l *logScrollback) window(total, page int) (start, end int) {
+	end = max(total-l.offset, 0)
+	return max(end-page, 0), end
+}
+
+// status describes the scrollback state for the log title, or "" when
+// following the newest entries
+func (l *logScrollback) status() string {
+	var parts []string
+	switch {
+	case l.paused:
+		parts = append(parts, "PAUSED")
+	case l.offset > 0:
+		parts = append(parts, "SCROLLBACK")
+	}
+	if l.offset > 0 {
+		parts = append(parts, fmt.Sprintf("%d newer", l.offset))
+	}
+	if l.searching {
+		parts = append(parts, "/"+l.search+"_")
+	} else if l.search != "" {
+		parts = append(parts, fmt.Sprintf("matching %q", l.search))
+	}
+	return strings.Join(parts, ", ")
+}
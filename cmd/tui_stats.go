@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Stats box rows, selectable with "tui": {"stats": [...]} in the config
+const (
+	statsRowTotals    = "totals"    // Total, valid and error counts
+	statsRowErrors    = "errors"    // CRC and decode errors
+	statsRowMalformed = "malformed" // Malformed packets by kind
+	statsRowAnomalous = "anomalous" // Anomalous values by kind
+	statsRowSession   = "session"   // Session anomalies
+	statsRowCustom    = "custom"    // Registered validator counts
+	statsRowGaps      = "gaps"      // Packet gaps and slow telemetry
+	statsRowRates     = "rates"     // Packet and error rates
+	statsRowTypes     = "types"     // Packets per message type
+	statsRowDevices   = "devices"   // Packets per device
+)
+
+// defaultStatsRows is the stats box layout when the config sets none
+var defaultStatsRows = []string{
+	statsRowTotals, statsRowErrors, statsRowMalformed, statsRowAnomalous,
+	statsRowSession, statsRowCustom, statsRowGaps, statsRowRates,
+}
+
+// statsRowNames lists every row, for validation and error messages
+var statsRowNames = []string{
+	statsRowTotals, statsRowErrors, statsRowMalformed, statsRowAnomalous,
+	statsRowSession, statsRowCustom, statsRowGaps, statsRowRates,
+	statsRowTypes, statsRowDevices,
+}
+
+// TUIConfig customizes the TUIs
+type TUIConfig struct {
+	// Stats lists the rows of the error_detection stats box, in order. Rows
+	// other than totals and rates only appear once they have something to
+	// show.
+	Stats []string `json:"stats,omitempty"`
+
+	// ChartWindow is the time span of the control TUI's telemetry charts
+	// (default 1m)
+	ChartWindow string `json:"chart_window,omitempty"`
+
+	// Theme is the TUI color theme (dark, light, high-contrast or
+	// no-color), overridden by --theme
+	Theme string `json:"theme,omitempty"`
+
+	// Colors overrides the theme's colors by role (accent, muted, bad,
+	// ...): an ANSI index, #RRGGBB or "none"
+	Colors map[string]string `json:"colors,omitempty"`
+
+	// Keys rebinds TUI actions (quit, estop, charts, ...) to other keys
+	Keys map[string][]string `json:"keys,omitempty"`
+}
+
+// validate checks the row names, chart window, theme and key bindings
+func (c TUIConfig) validate() error {
+	for _, name := range c.Stats {
+		if !isStatsRow(name) {
+			return fmt.Errorf("unknown stats row %q (valid: %s)", name, strings.Join(statsRowNames, ", "))
+		}
+	}
+	if c.ChartWindow != "" {
+		if window, err := time.ParseDuration(c.ChartWindow); err != nil || window <= 0 {
+			return fmt.Errorf("invalid chart_window %q: must be a positive duration", c.ChartWindow)
+		}
+	}
+	if err := validateTheme(c.Theme, c.Colors); err != nil {
+		return err
+	}
+	if err := validateKeys(c.Keys); err != nil {
+		return fmt.Errorf("keys: %v", err)
+	}
+	return nil
+}
+
+// statsRows returns the configured layout, or the default
+func (c TUIConfig) statsRows() []string {
+	if len(c.Stats) == 0 {
+		return defaultStatsRows
+	}
+	rows := make([]string, len(c.Stats))
+	for i, name := range c.Stats {
+		rows[i] = strings.ToLower(name)
+	}
+	return rows
+}
+
+func isStatsRow(name string) bool {
+	for _, row := range statsRowNames {
+		if strings.EqualFold(name, row) {
+			return true
+		}
+	}
+	return false
+}
+
+// statsStyles are the styles the stats box is rendered with
+type statsStyles struct {
+	label   lipgloss.Style
+	value   lipgloss.Style
+	header  lipgloss.Style
+	error   lipgloss.Style
+	warning lipgloss.Style
+}
+
+// renderStats renders the stats box rows in order, one line each. Rows
+// with nothing to show are left out.
+func renderStats(stats *fusain.Statistics, rows []string, st statsStyles) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if line := renderStatsRow(stats, row, st); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderStatsRow(stats *fusain.Statistics, row string, st statsStyles) string {
+	totalErrors := stats.CRCErrors + stats.DecodeErrors + stats.MalformedPackets + stats.AnomalousValues
+
+	switch row {
+	case statsRowTotals:
+		var validPercent, errorPercent float64
+		if stats.TotalPackets > 0 {
+			validPercent = float64(stats.ValidPackets) * 100.0 / float64(stats.TotalPackets)
+			errorPercent = float64(totalErrors) * 100.0 / float64(stats.TotalPackets)
+		}
+		return fmt.Sprintf("%s %s   %s %s   %s %s",
+			st.label.Render("Total:"), st.value.Render(fmt.Sprintf("%d", stats.TotalPackets)),
+			st.label.Render("Valid:"), st.value.Render(fmt.Sprintf("%d (%.1f%%)", stats.ValidPackets, validPercent)),
+			st.label.Render("Errors:"), st.error.Render(fmt.Sprintf("%d (%.1f%%)", totalErrors, errorPercent)),
+		)
+
+	case statsRowErrors:
+		if stats.CRCErrors == 0 && stats.DecodeErrors == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s %s   %s %s",
+			st.label.Render("CRC Errors:"), st.error.Render(fmt.Sprintf("%d", stats.CRCErrors)),
+			st.label.Render("Decode Errors:"), st.error.Render(fmt.Sprintf("%d", stats.DecodeErrors)),
+		)
+
+	case statsRowMalformed:
+		if stats.MalformedPackets == 0 {
+			return ""
+		}
+		line := fmt.Sprintf("%s %s",
+			st.label.Render("Malformed:"), st.error.Render(fmt.Sprintf("%d", stats.MalformedPackets)),
+		)
+		if stats.InvalidCounts > 0 || stats.LengthMismatches > 0 || stats.MissingFields > 0 {
+			line += fmt.Sprintf(" (%s: %d, %s: %d, %s: %d)",
+				st.header.Render("invalid counts"), stats.InvalidCounts,
+				st.header.Render("length mismatches"), stats.LengthMismatches,
+				st.header.Render("missing fields"), stats.MissingFields,
+			)
+		}
+		return line
+
+	case statsRowAnomalous:
+		if stats.AnomalousValues == 0 {
+			return ""
+		}
+		line := fmt.Sprintf("%s %s",
+			st.label.Render("Anomalous:"), st.warning.Render(fmt.Sprintf("%d", stats.AnomalousValues)),
+		)
+		if stats.HighRPM > 0 || stats.InvalidTemp > 0 || stats.InvalidPWM > 0 {
+			line += fmt.Sprintf(" (%s: %d, %s: %d, %s: %d)",
+				st.header.Render("high RPM"), stats.HighRPM,
+				st.header.Render("invalid temp"), stats.InvalidTemp,
+				st.header.Render("invalid PWM"), stats.InvalidPWM,
+			)
+		}
+		var worst []string
+		if stats.HighRPMValues.Count > 0 {
+			worst = append(worst, stats.HighRPMValues.Format("%.0f")+" RPM")
+		}
+		if stats.TempValues.Count > 0 {
+			temps := stats.TempValues
+			temps.Min = fusain.ConvertTemperature(temps.Min, displayUnits)
+			temps.Max = fusain.ConvertTemperature(temps.Max, displayUnits)
+			worst = append(worst, temps.Format("%.1f")+temperatureUnit())
+		}
+		if len(worst) > 0 {
+			line += fmt.Sprintf(" %s %s", st.header.Render("seen:"), strings.Join(worst, ", "))
+		}
+		return line
+
+	case statsRowSession:
+		if stats.InvalidTransitions == 0 && stats.TimestampErrors == 0 && stats.DuplicatePackets == 0 && stats.StaleDevices == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s %s: %d, %s: %d, %s: %d, %s: %d",
+			st.label.Render("Session:"),
+			st.header.Render("bad transitions"), stats.InvalidTransitions,
+			st.header.Render("timestamps"), stats.TimestampErrors,
+			st.header.Render("duplicates"), stats.DuplicatePackets,
+			st.header.Render("stale"), stats.StaleDevices,
+		)
+
+	case statsRowCustom:
+		checks := stats.Checks()
+		if len(checks) == 0 {
+			return ""
+		}
+		counts := make([]string, len(checks))
+		for i, name := range checks {
+			counts[i] = fmt.Sprintf("%s: %d", st.header.Render(name), stats.CheckCount(name))
+		}
+		return fmt.Sprintf("%s %s", st.label.Render("Custom:"), strings.Join(counts, ", "))
+
+	case statsRowGaps:
+		if stats.Gaps.Count == 0 {
+			return ""
+		}
+		line := fmt.Sprintf("%s %s",
+			st.label.Render("Packet Gap:"), st.value.Render(stats.Gaps.String()),
+		)
+		if stats.SlowTelemetry > 0 {
+			line += fmt.Sprintf("   %s %s",
+				st.label.Render("Slow Telemetry:"), st.warning.Render(fmt.Sprintf("%d", stats.SlowTelemetry)),
+			)
+		}
+		return line
+
+	case statsRowRates:
+		errorRate := st.value.Render(fmt.Sprintf("%.1f err/s", stats.ErrorRate))
+		if stats.ErrorRate > 0 {
+			errorRate = st.error.Render(fmt.Sprintf("%.1f err/s", stats.ErrorRate))
+		}
+		return fmt.Sprintf("%s %s   %s %s",
+			st.label.Render("Packet Rate:"), st.value.Render(fmt.Sprintf("%.1f pkts/s", stats.PacketRate)),
+			st.label.Render("Error Rate:"), errorRate,
+		)
+
+	case statsRowTypes:
+		types := stats.MessageTypes()
+		if len(types) == 0 {
+			return ""
+		}
+		counts := make([]string, len(types))
+		for i, t := range types {
+			counts[i] = formatCountStats(fusain.FormatMessageType(t), stats.TypeStats(t), st)
+		}
+		return fmt.Sprintf("%s %s", st.label.Render("Types:"), strings.Join(counts, ", "))
+
+	case statsRowDevices:
+		devices := stats.Devices()
+		if len(devices) == 0 {
+			return ""
+		}
+		counts := make([]string, len(devices))
+		for i, d := range devices {
+			counts[i] = formatCountStats(deviceLabel(d), stats.DeviceStats(d), st)
+		}
+		return fmt.Sprintf("%s %s", st.label.Render("Devices:"), strings.Join(counts, ", "))
+	}
+	return ""
+}
+
+// formatCountStats formats a per-type or per-device breakdown entry
+func formatCountStats(name string, c fusain.CountStats, st statsStyles) string {
+	entry := fmt.Sprintf("%s: %d", st.header.Render(name), c.Packets)
+	if c.Errors > 0 {
+		entry += " " + st.error.Render(fmt.Sprintf("(%d err)", c.Errors))
+	}
+	return entry
+}
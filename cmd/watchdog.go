@@ -0,0 +1,379 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/events"
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchdogDevice   string
+	watchdogSilence  int
+	watchdogCooldown int
+	watchdogEstop    bool
+	watchdogWebhook  string
+	watchdogExec     string
+)
+
+var watchdogCmd = &cobra.Command{
+	Use:   "watchdog",
+	Short: "Supervise a device and run recovery actions on error or silence",
+	Long: `Run as an external safety watchdog for a single appliance.
+
+The watchdog keeps the device subscribed (re-subscribing after reconnects) and
+monitors its telemetry. A recovery action is triggered when:
+  - STATE_DATA reports the ERROR state or sets the error flag
+  - No telemetry is received from the device for --silence seconds
+
+Recovery actions (any combination, run in this order):
+  --estop            Broadcast STATE_COMMAND EMERGENCY to all devices
+  --webhook URL      POST a JSON event to URL (10s timeout)
+  --exec PATH        Run PATH with HELIOSTAT_DEVICE, HELIOSTAT_REASON and
+                     HELIOSTAT_DETAIL set in its environment (killed after
+                     60s)
+
+The webhook and script run in the background, so a slow one never delays
+monitoring. An E-stop triggered while the connection is down is sent as
+soon as it is back.
+
+After a trigger, further triggers are suppressed for --cooldown seconds.
+Log lines go to stdout so the watchdog can run under systemd or similar.
+
+Examples:
+  heliostat watchdog --url ws://slate.local/ws --device 0x0123456789ABCDEF --estop
+  heliostat watchdog --port /dev/ttyUSB0 --device 0123456789ABCDEF \
+      --silence 5 --exec /usr/local/bin/heater-recover.sh
+
+Exit codes:
+  2 - Connection error on startup
+  4 - Aborted by user (Ctrl+C)
+  5 - Stopped by SIGTERM/SIGHUP (e.g. systemctl stop)`,
+	RunE: runWatchdog,
+}
+
+func init() {
+	rootCmd.AddCommand(watchdogCmd)
+	watchdogCmd.Flags().StringVar(&watchdogDevice, "device", "", "Device address to supervise (hex or alias, required)")
+	watchdogCmd.Flags().IntVar(&watchdogSilence, "silence", 10, "Seconds without telemetry before the device is considered silent")
+	watchdogCmd.Flags().IntVar(&watchdogCooldown, "cooldown", 60, "Seconds to suppress further triggers after a recovery action")
+	watchdogCmd.Flags().BoolVar(&watchdogEstop, "estop", false, "Broadcast an emergency stop on trigger")
+	watchdogCmd.Flags().StringVar(&watchdogWebhook, "webhook", "", "URL to POST a JSON event to on trigger")
+	watchdogCmd.Flags().StringVar(&watchdogExec, "exec", "", "Script to run on trigger")
+	watchdogCmd.MarkFlagRequired("device")
+}
+
+const (
+	// watchdogWebhookTimeout bounds a --webhook request
+	watchdogWebhookTimeout = 10 * time.Second
+
+	// watchdogExecTimeout bounds an --exec script; it is killed after
+	watchdogExecTimeout = 60 * time.Second
+)
+
+// watchdogActions tracks running webhook and script actions, so shutdown
+// can wait for them
+var watchdogActions sync.WaitGroup
+
+// watchdogEvent is the JSON body POSTed to --webhook
+type watchdogEvent struct {
+	Device string    `json:"device"`
+	Reason string    `json:"reason"`
+	Detail string    `json:"detail"`
+	Time   time.Time `json:"time"`
+}
+
+// watchdog tracks device health and fires recovery actions
+type watchdog struct {
+	address     uint64
+	silence     time.Duration
+	cooldown    time.Duration
+	conn        Connection // nil while reconnecting
+	lastSeen    time.Time
+	silent      bool
+	inError     bool
+	lastTrigger time.Time
+
+	// pendingEstop is set when an E-stop could not be sent because the
+	// connection was down; it is sent once reconnected
+	pendingEstop bool
+}
+
+func watchdogLog(format string, args ...interface{}) {
+	fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+func runWatchdog(cmd *cobra.Command, args []string) error {
+	address, err := parseAddress(watchdogDevice)
+	if err != nil {
+		return err
+	}
+	if !deviceFilter.allows(address) {
+		return fmt.Errorf("device %s is not permitted by the address filter", formatAddress(address))
+	}
+	if !watchdogEstop && watchdogWebhook == "" && watchdogExec == "" {
+		return fmt.Errorf("no recovery action configured (use --estop, --webhook or --exec)")
+	}
+
+	conn, connInfo, err := OpenConnection()
+	if err != nil {
+		return err
+	}
+
+	wd := &watchdog{
+		address:  address,
+		silence:  time.Duration(watchdogSilence) * time.Second,
+		cooldown: time.Duration(watchdogCooldown) * time.Second,
+		conn:     conn,
+		lastSeen: time.Now(),
+	}
+
+	watchdogLog("Watching device %s via %s (silence=%v, cooldown=%v)",
+		formatAddress(address), connInfo, wd.silence, wd.cooldown)
+
+	onShutdown(func() {
+		// Let running actions finish, without holding up exit for long
+		done := make(chan struct{})
+		go func() {
+			watchdogActions.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(shutdownTimeout):
+		}
+	})
+
+	for {
+		wd.sendPendingEstop()
+		wd.subscribe()
+		err := wd.monitor()
+		wd.conn.Close()
+		wd.conn = nil
+		watchdogLog("Connection lost: %v", err)
+		wd.conn = wd.reconnect()
+	}
+}
+
+// subscribe asks the router to forward this device's telemetry
+func (wd *watchdog) subscribe() {
+	packet := fusain.NewDataSubscription(fusain.AddressStateless, wd.address)
+	if _, err := wd.conn.Write(fusain.MustEncodePacket(packet)); err != nil {
+		watchdogLog("Subscribe failed: %v", err)
+	}
+}
+
+// monitor watches device events until the connection fails. Any read
+// error counts as a lost connection so the watchdog reconnects.
+func (wd *watchdog) monitor() error {
+	sub := eventBus.SubscribeLossless(100)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go newPacketSource(eventBus, false).run(wd.conn, done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-sub.Events():
+			switch e := e.(type) {
+			case events.PacketReceived:
+				wd.handlePacket(e.Packet)
+			case events.DeviceStateChanged:
+				wd.handleStateChange(e)
+			case events.ReadError:
+				eventBus.Publish(events.ConnectionLost{At: time.Now(), Err: e.Err})
+				return e.Err
+			case events.ConnectionLost:
+				return e.Err
+			}
+		case <-ticker.C:
+			wd.checkSilence()
+		}
+	}
+}
+
+func (wd *watchdog) handlePacket(packet *fusain.Packet) {
+	if packet.Address() != wd.address {
+		return
+	}
+
+	wd.lastSeen = time.Now()
+	if wd.silent {
+		wd.silent = false
+		watchdogLog("Device %s telemetry resumed", formatAddress(wd.address))
+	}
+}
+
+func (wd *watchdog) handleStateChange(e events.DeviceStateChanged) {
+	if e.Address != wd.address {
+		return
+	}
+
+	failed := e.Error || e.State == fusain.SysStateError
+	if failed && !wd.inError {
+		wd.inError = true
+		wd.trigger("error", fmt.Sprintf("state=%s code=%s",
+			fusain.FormatState(uint32(e.State)), fusain.FormatErrorCode(int32(e.Code))))
+		if hint := errorHint(e.Code); hint != "" {
+			watchdogLog("Hint: %s", hint)
+		}
+	} else if !failed && wd.inError {
+		wd.inError = false
+		watchdogLog("Device %s left error state (%s)", formatAddress(wd.address), fusain.FormatState(uint32(e.State)))
+	}
+}
+
+func (wd *watchdog) checkSilence() {
+	if wd.silent || time.Since(wd.lastSeen) < wd.silence {
+		return
+	}
+	wd.silent = true
+	wd.trigger("silent", fmt.Sprintf("no telemetry for %v", time.Since(wd.lastSeen).Round(time.Second)))
+}
+
+// trigger runs the configured recovery actions unless still cooling down
+func (wd *watchdog) trigger(reason, detail string) {
+	watchdogLog("TRIGGER device %s %s: %s", formatAddress(wd.address), reason, detail)
+
+	if !wd.lastTrigger.IsZero() && time.Since(wd.lastTrigger) < wd.cooldown {
+		watchdogLog("Recovery suppressed (cooldown, %v remaining)",
+			(wd.cooldown - time.Since(wd.lastTrigger)).Round(time.Second))
+		return
+	}
+	wd.lastTrigger = time.Now()
+	service.count("triggers", 1)
+
+	if watchdogEstop {
+		wd.pendingEstop = true
+		wd.sendPendingEstop()
+	}
+
+	if watchdogWebhook == "" && watchdogExec == "" {
+		return
+	}
+	// The webhook and script may take a while; run them in order in the
+	// background so monitoring carries on
+	address := wd.address
+	watchdogActions.Add(1)
+	go func() {
+		defer watchdogActions.Done()
+		if watchdogWebhook != "" {
+			if err := postWatchdogWebhook(address, reason, detail); err != nil {
+				watchdogLog("Webhook failed: %v", err)
+			} else {
+				watchdogLog("Webhook delivered")
+			}
+		}
+		if watchdogExec != "" {
+			if err := runWatchdogExec(address, reason, detail); err != nil {
+				watchdogLog("Recovery script failed: %v", err)
+			} else {
+				watchdogLog("Recovery script completed")
+			}
+		}
+	}()
+}
+
+// sendPendingEstop broadcasts a triggered E-stop, or leaves it pending
+// until the connection is back
+func (wd *watchdog) sendPendingEstop() {
+	if !wd.pendingEstop {
+		return
+	}
+	if wd.conn == nil {
+		watchdogLog("E-stop broadcast queued until reconnected")
+		return
+	}
+	packet := fusain.NewStateCommand(fusain.AddressBroadcast, uint8(fusain.ModeEmergency), nil)
+	if err := writePacket(wd.conn, packet); err != nil {
+		watchdogLog("E-stop broadcast failed, will retry once reconnected: %v", err)
+		return
+	}
+	wd.pendingEstop = false
+	watchdogLog("E-stop broadcast sent")
+}
+
+// runWatchdogExec runs the --exec script, killing it after
+// watchdogExecTimeout
+func runWatchdogExec(address uint64, reason, detail string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), watchdogExecTimeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, watchdogExec)
+	c.Env = append(os.Environ(),
+		fmt.Sprintf("HELIOSTAT_DEVICE=%016X", address),
+		"HELIOSTAT_REASON="+reason,
+		"HELIOSTAT_DETAIL="+detail,
+	)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	err := c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("killed after %v", watchdogExecTimeout)
+	}
+	return err
+}
+
+func postWatchdogWebhook(address uint64, reason, detail string) error {
+	body, err := json.Marshal(watchdogEvent{
+		Device: fmt.Sprintf("%016X", address),
+		Reason: reason,
+		Detail: detail,
+		Time:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: watchdogWebhookTimeout}
+	resp, err := client.Post(watchdogWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// reconnect retries with exponential backoff, still watching for silence.
+// A device we cannot reach counts as silent.
+func (wd *watchdog) reconnect() Connection {
+	backoff := 1 * time.Second
+	maxBackoff := 30 * time.Second
+
+	for {
+		time.Sleep(backoff)
+		wd.checkSilence()
+
+		conn, connInfo, err := OpenConnection()
+		if err == nil {
+			watchdogLog("Reconnected via %s", connInfo)
+			eventBus.Publish(events.ConnectionRestored{At: time.Now(), Info: connInfo})
+			return conn
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -4,8 +4,9 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/Thermoquad/heliostat/pkg/fusain"
@@ -33,8 +34,9 @@ This is useful for verifying:
 
 Exit codes:
   0 - Discovery successful (at least one device found)
-  1 - Discovery failed (no devices or timeout)
-  2 - Connection error`,
+  1 - Discovery completed but no devices were found
+  2 - Connection error
+  3 - Timeout (no end-of-discovery marker received)`,
 	RunE: runWsDiscovery,
 }
 
@@ -47,8 +49,7 @@ func runWsDiscovery(cmd *cobra.Command, args []string) error {
 	// Open connection (serial or WebSocket)
 	conn, connInfo, err := OpenConnection()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
-		os.Exit(2)
+		return err
 	}
 	defer conn.Close()
 
@@ -56,71 +57,35 @@ func runWsDiscovery(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Connection: %s\n", connInfo)
 	fmt.Printf("Timeout: %d seconds\n\n", wsDiscoveryTimeout)
 
-	decoder := fusain.NewDecoder()
+	client := fusain.NewClient(conn)
 
-	// Create DISCOVERY_REQUEST packet for stateless address (router)
-	discoveryPacket := fusain.NewDiscoveryRequest(fusain.AddressStateless)
-	wireBytes := fusain.MustEncodePacket(discoveryPacket)
-
-	// Send discovery request
+	// Send DISCOVERY_REQUEST to the stateless address (router) and collect
+	// DEVICE_ANNOUNCE responses until the end-of-discovery marker
 	fmt.Printf("Sending DISCOVERY_REQUEST...\n")
-	_, err = conn.Write(wireBytes)
-	if err != nil {
-		fmt.Printf("SEND FAILED: %v\n", err)
-		os.Exit(2)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(wsDiscoveryTimeout)*time.Second)
+	devices, err := client.Discover(ctx)
+	cancel()
+
+	for _, device := range devices {
+		fmt.Printf("\nDevice found:\n")
+		fmt.Printf("  Address: 0x%016X\n", device.Address)
+		fmt.Printf("  Motors: %d\n", device.MotorCount)
+		fmt.Printf("  Thermometers: %d\n", device.ThermometerCount)
+		fmt.Printf("  Pumps: %d\n", device.PumpCount)
+		fmt.Printf("  Glow plugs: %d\n", device.GlowCount)
 	}
 
-	// Collect DEVICE_ANNOUNCE responses
-	devices := make([]deviceInfo, 0)
-	done := make(chan bool, 1)
-	errChan := make(chan error, 1)
-
-	go func() {
-		buf := make([]byte, 128)
-		for {
-			n, err := conn.Read(buf)
-			if err != nil {
-				errChan <- err
-				return
-			}
-
-			for j := 0; j < n; j++ {
-				packet, decodeErr := decoder.DecodeByte(buf[j])
-				if decodeErr != nil {
-					continue
-				}
-				if packet != nil {
-					msgType := packet.Type()
-					if msgType == fusain.MsgDeviceAnnounce {
-						device := parseDeviceAnnounce(packet)
-						if device.isEndMarker() {
-							fmt.Printf("\nEnd of discovery marker received\n")
-							done <- true
-							return
-						}
-						devices = append(devices, device)
-						fmt.Printf("\nDevice found:\n")
-						fmt.Printf("  Address: 0x%016X\n", device.address)
-						fmt.Printf("  Motors: %d\n", device.motorCount)
-						fmt.Printf("  Thermometers: %d\n", device.thermometerCount)
-						fmt.Printf("  Pumps: %d\n", device.pumpCount)
-						fmt.Printf("  Glow plugs: %d\n", device.glowCount)
-					}
-					// Ignore non-discovery packets (telemetry, etc.)
-				}
-			}
-		}
-	}()
-
-	// Wait for discovery to complete or timeout
-	select {
-	case <-done:
-		// Discovery complete
-	case err := <-errChan:
-		fmt.Printf("READ FAILED: %v\n", err)
-		os.Exit(2)
-	case <-time.After(time.Duration(wsDiscoveryTimeout) * time.Second):
+	timedOut := false
+	switch {
+	case err == nil:
+		fmt.Printf("\nEnd of discovery marker received\n")
+	case errors.Is(err, context.DeadlineExceeded):
+		timedOut = true
 		fmt.Printf("\nTIMEOUT: No end-of-discovery marker received in %ds\n", wsDiscoveryTimeout)
+	case errors.Is(err, fusain.ErrClientClosed):
+		return exitErrorf(ExitConnection, "READ FAILED: %v", client.Err())
+	default:
+		return exitErrorf(ExitConnection, "SEND FAILED: %v", err)
 	}
 
 	// Summary
@@ -129,9 +94,15 @@ func runWsDiscovery(cmd *cobra.Command, args []string) error {
 
 	if len(devices) == 0 {
 		fmt.Printf("No devices discovered. Slate may not have tracked any connected devices yet.\n")
-		os.Exit(1)
+		if timedOut {
+			return exitSilently(ExitTimeout)
+		}
+		return exitSilently(ExitFailure)
 	}
 
+	if timedOut {
+		return exitSilently(ExitTimeout)
+	}
 	return nil
 }
 
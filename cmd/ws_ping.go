@@ -4,8 +4,9 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/Thermoquad/heliostat/pkg/fusain"
@@ -34,8 +35,9 @@ This is useful for verifying:
 
 Exit codes:
   0 - All pings successful
-  1 - One or more pings failed/timed out
-  2 - Connection error`,
+  1 - One or more pings failed
+  2 - Connection error
+  3 - One or more pings timed out (and none failed otherwise)`,
 	RunE: runWsPing,
 }
 
@@ -49,8 +51,7 @@ func runWsPing(cmd *cobra.Command, args []string) error {
 	// Open connection (serial or WebSocket)
 	conn, connInfo, err := OpenConnection()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
-		os.Exit(2)
+		return err
 	}
 	defer conn.Close()
 
@@ -59,73 +60,35 @@ func runWsPing(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Timeout: %d seconds per ping\n", wsPingTimeout)
 	fmt.Printf("Count: %d pings\n\n", wsPingCount)
 
-	decoder := fusain.NewDecoder()
+	client := fusain.NewClient(conn)
 	successCount := 0
 	failCount := 0
+	timeoutCount := 0
 
 	for i := 1; i <= wsPingCount; i++ {
 		fmt.Printf("Ping %d/%d: ", i, wsPingCount)
 
-		// Create PING_REQUEST packet for stateless address (router)
-		pingPacket := fusain.NewPingRequest(fusain.AddressStateless)
-		wireBytes := fusain.MustEncodePacket(pingPacket)
+		// PING_REQUEST to the stateless address is answered by the router
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(wsPingTimeout)*time.Second)
+		resp, rtt, err := client.Ping(ctx, fusain.AddressStateless)
+		cancel()
 
-		// Send ping
-		startTime := time.Now()
-		_, err := conn.Write(wireBytes)
-		if err != nil {
-			fmt.Printf("SEND FAILED: %v\n", err)
-			failCount++
-			continue
-		}
-
-		// Wait for PING_RESPONSE
-		responseChan := make(chan *fusain.Packet, 1)
-		errChan := make(chan error, 1)
-
-		go func() {
-			buf := make([]byte, 128)
-			for {
-				n, err := conn.Read(buf)
-				if err != nil {
-					errChan <- err
-					return
-				}
-
-				for j := 0; j < n; j++ {
-					packet, decodeErr := decoder.DecodeByte(buf[j])
-					if decodeErr != nil {
-						// Ignore decode errors
-						continue
-					}
-					if packet != nil {
-						// Check if it's a PING_RESPONSE
-						if packet.Type() == fusain.MsgPingResponse {
-							responseChan <- packet
-							return
-						}
-						// Ignore non-ping packets (telemetry, etc.)
-					}
-				}
-			}
-		}()
-
-		// Wait for response or timeout
-		select {
-		case packet := <-responseChan:
-			rtt := time.Since(startTime)
-			payloadMap := packet.PayloadMap()
-			uptime, _ := fusain.GetMapUint(payloadMap, 0)
-			uptimeStr := formatUptime(uptime)
-			fmt.Printf("PONG from router, uptime=%s, rtt=%v\n", uptimeStr, rtt.Round(time.Millisecond))
+		switch {
+		case err == nil:
+			fmt.Printf("PONG from router, uptime=%s, rtt=%v\n", formatUptime(resp.Uptime), rtt.Round(time.Millisecond))
 			successCount++
 
-		case err := <-errChan:
-			fmt.Printf("READ FAILED: %v\n", err)
+		case errors.Is(err, context.DeadlineExceeded):
+			fmt.Printf("TIMEOUT (no response in %ds)\n", wsPingTimeout)
+			failCount++
+			timeoutCount++
+
+		case errors.Is(err, fusain.ErrClientClosed):
+			fmt.Printf("READ FAILED: %v\n", client.Err())
 			failCount++
 
-		case <-time.After(time.Duration(wsPingTimeout) * time.Second):
-			fmt.Printf("TIMEOUT (no response in %ds)\n", wsPingTimeout)
+		default:
+			fmt.Printf("SEND FAILED: %v\n", err)
 			failCount++
 		}
 
@@ -141,7 +104,10 @@ func runWsPing(cmd *cobra.Command, args []string) error {
 		wsPingCount, successCount, float64(failCount)/float64(wsPingCount)*100)
 
 	if failCount > 0 {
-		os.Exit(1)
+		if failCount == timeoutCount {
+			return exitSilently(ExitTimeout)
+		}
+		return exitSilently(ExitFailure)
 	}
 	return nil
 }
@@ -5,7 +5,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -21,8 +20,7 @@ or errors encountered. Useful for debugging connection stability issues.
 
 Exit codes:
   0 - Test completed normally
-  1 - Test failed
-  2 - Connection error`,
+  2 - Connection error (could not connect, or connection dropped during the test)`,
 	RunE: runWsTest,
 }
 
@@ -37,8 +35,7 @@ func runWsTest(cmd *cobra.Command, args []string) error {
 	// Open connection (serial or WebSocket)
 	conn, connInfo, err := OpenConnection()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
-		os.Exit(2)
+		return err
 	}
 	defer conn.Close()
 
@@ -89,7 +86,7 @@ func runWsTest(cmd *cobra.Command, args []string) error {
 			fmt.Printf("Packets received: %d\n", packetsReceived)
 			fmt.Printf("Bytes received: %d\n", bytesReceived)
 			fmt.Printf("Result: FAILED (connection error)\n")
-			os.Exit(1)
+			return exitSilently(ExitConnection)
 
 		case <-time.After(1 * time.Second):
 			// Just a heartbeat to show the test is running
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bus delivers published events to every subscriber in publish order.
+//
+// A lossy subscriber (Subscribe) drops events when its buffer is full so a
+// slow TUI never stalls the connection; a lossless subscriber
+// (SubscribeLossless) applies backpressure to the publisher instead, which
+// suits loggers and exporters that must see every event. Urgent events are
+// never dropped: a full lossy subscriber loses its oldest event instead.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	done   chan struct{}
+	once   sync.Once
+
+	// Totals over every subscriber, including closed ones
+	dropped atomic.Uint64
+	blocked atomic.Int64 // Nanoseconds Publish waited on lossless subscribers
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*Subscription]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Subscription receives events from a Bus
+type Subscription struct {
+	bus      *Bus
+	ch       chan Event
+	lossless bool
+	dropped  atomic.Uint64
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Subscribe adds a subscriber that drops events when buffer is full
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	return b.subscribe(buffer, false)
+}
+
+// SubscribeLossless adds a subscriber that blocks Publish when buffer is full
+func (b *Bus) SubscribeLossless(buffer int) *Subscription {
+	return b.subscribe(buffer, true)
+}
+
+func (b *Bus) subscribe(buffer int, lossless bool) *Subscription {
+	s := &Subscription{
+		bus:      b,
+		ch:       make(chan Event, buffer),
+		lossless: lossless,
+		done:     make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(s.ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Publish delivers e to every subscriber. It returns once every lossless
+// subscriber has accepted the event (or unsubscribed).
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	for s := range b.subs {
+		select {
+		case s.ch <- e:
+			continue
+		default:
+		}
+		if s.lossless {
+			start := time.Now()
+			select {
+			case s.ch <- e:
+			case <-s.done:
+			case <-b.done:
+			}
+			b.blocked.Add(int64(time.Since(start)))
+			continue
+		}
+		if !IsUrgent(e) {
+			s.dropped.Add(1)
+			b.dropped.Add(1)
+			continue
+		}
+
+		s.pushUrgent(e, b.done, &b.dropped)
+	}
+}
+
+// pushUrgent delivers e to a full lossy subscriber by dropping its oldest
+// events, so it never waits on the reader. An unbuffered subscriber has
+// nothing to drop and is waited for.
+func (s *Subscription) pushUrgent(e Event, busDone <-chan struct{}, busDropped *atomic.Uint64) {
+	if cap(s.ch) == 0 {
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		case <-busDone:
+		}
+		return
+	}
+	for {
+		// Another publisher may refill the freed slot, so retry until
+		// the send wins
+		select {
+		case s.ch <- e:
+			return
+		default:
+		}
+		select {
+		case <-s.ch:
+			s.dropped.Add(1)
+			busDropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Stats is a snapshot of a bus's subscriber queues
+type Stats struct {
+	Subscribers int
+	Depth       int           // Events buffered across subscribers
+	Capacity    int           // Buffer size across subscribers
+	MaxFill     float64       // Fullest subscriber's depth over its buffer size (0-1)
+	Dropped     uint64        // Events dropped by lossy subscribers, ever
+	Blocked     time.Duration // Time Publish waited on lossless subscribers, ever
+}
+
+// Stats reports how full the subscriber queues are. A MaxFill near 1 or a
+// growing Blocked time (Publish waiting on lossless subscribers) means a
+// subscriber is falling behind.
+func (b *Bus) Stats() Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	st := Stats{
+		Subscribers: len(b.subs),
+		Dropped:     b.dropped.Load(),
+		Blocked:     time.Duration(b.blocked.Load()),
+	}
+	for s := range b.subs {
+		depth, size := len(s.ch), cap(s.ch)
+		st.Depth += depth
+		st.Capacity += size
+		if size > 0 {
+			st.MaxFill = max(st.MaxFill, float64(depth)/float64(size))
+		}
+	}
+	return st
+}
+
+// Close closes every subscriber's channel. Later publishes are ignored.
+func (b *Bus) Close() {
+	b.once.Do(func() {
+		close(b.done)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.closed = true
+		for s := range b.subs {
+			close(s.ch)
+			delete(b.subs, s)
+		}
+	})
+}
+
+// Events returns the event channel. It is closed by Close on the
+// subscription or the bus.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the event channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.bus.mu.Lock()
+		defer s.bus.mu.Unlock()
+		if _, ok := s.bus.subs[s]; ok {
+			delete(s.bus.subs, s)
+			close(s.ch)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func TestBus_DeliversInOrder(t *testing.T) {
+	bus := NewBus()
+	a := bus.Subscribe(10)
+	b := bus.SubscribeLossless(10)
+
+	bus.Publish(Synchronized{Skipped: 3})
+	bus.Publish(DecodeError{Err: errors.New("crc")})
+
+	for _, sub := range []*Subscription{a, b} {
+		if e, ok := (<-sub.Events()).(Synchronized); !ok || e.Skipped != 3 {
+			t.Fatalf("first event = %#v, want Synchronized{Skipped: 3}", e)
+		}
+		if _, ok := (<-sub.Events()).(DecodeError); !ok {
+			t.Fatal("second event is not DecodeError")
+		}
+	}
+}
+
+func TestBus_LossyDropsWhenFull(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+
+	bus.Publish(ReadError{})
+	bus.Publish(ReadError{})
+	bus.Publish(ReadError{})
+
+	if got := sub.Dropped(); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+}
+
+func TestBus_LosslessBlocks(t *testing.T) {
+	bus := NewBus()
+	sub := bus.SubscribeLossless(0)
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(ReadError{})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("Publish returned before the lossless subscriber received")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	<-sub.Events()
+	<-published
+}
+
+func TestBus_CloseUnblocksPublisher(t *testing.T) {
+	bus := NewBus()
+	sub := bus.SubscribeLossless(0)
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(ReadError{})
+		close(published)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	<-published
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("channel still open after Close")
+	}
+	bus.Publish(ReadError{}) // Must not panic or block
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+	bus.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("subscriber channel open after bus Close")
+	}
+	sub.Close() // Must not double-close
+
+	late := bus.Subscribe(1)
+	if _, ok := <-late.Events(); ok {
+		t.Error("subscription on closed bus is open")
+	}
+}
+
+func estop() *fusain.Packet {
+	return fusain.NewStateCommand(fusain.AddressBroadcast, uint8(fusain.ModeEmergency), nil)
+}
+
+func TestIsUrgent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{"estop command sent", CommandSent{Packet: estop()}, true},
+		{"estop command received", PacketReceived{Packet: estop()}, true},
+		{"E_STOP state", DeviceStateChanged{State: fusain.SysStateEstop}, true},
+		{"other state", DeviceStateChanged{State: fusain.SysStateIdle}, false},
+		{"ping", PacketReceived{Packet: fusain.NewPingRequest(1)}, false},
+		{"read error", ReadError{}, false},
+	}
+	for _, tt := range tests {
+		if got := IsUrgent(tt.event); got != tt.want {
+			t.Errorf("%s: IsUrgent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBus_UrgentEvictsOldest(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(2)
+
+	bus.Publish(ReadError{})
+	bus.Publish(ReadError{})
+
+	// Nobody is reading, yet the urgent event must not block or be dropped
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(CommandSent{Packet: estop()})
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(50 * time.Millisecond):
+		t.Fatal("urgent Publish blocked on a full lossy subscriber")
+	}
+
+	<-sub.Events()
+	if _, ok := (<-sub.Events()).(CommandSent); !ok {
+		t.Error("urgent event was not delivered")
+	}
+	if got := sub.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1 (the oldest event)", got)
+	}
+}
+
+func TestBus_UrgentLatencyUnderFlood(t *testing.T) {
+	const bound = 20 * time.Millisecond
+
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// Saturate the subscriber with routine events
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				bus.Publish(ReadError{})
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		sent := time.Now()
+		bus.Publish(DeviceStateChanged{At: sent, State: fusain.SysStateEstop})
+		if elapsed := time.Since(sent); elapsed > bound {
+			t.Fatalf("urgent Publish took %v, want at most %v", elapsed, bound)
+		}
+
+		// Routine events never evict, so the urgent event holds the slot
+		select {
+		case e := <-sub.Events():
+			if _, ok := e.(DeviceStateChanged); !ok {
+				t.Fatalf("urgent event lost under flood, got %T", e)
+			}
+		case <-time.After(bound):
+			t.Fatalf("urgent event not delivered within %v", bound)
+		}
+	}
+}
+
+func TestBus_Stats(t *testing.T) {
+	bus := NewBus()
+	lossy := bus.Subscribe(4)
+	lossless := bus.SubscribeLossless(2)
+
+	for i := 0; i < 2; i++ {
+		bus.Publish(ReadError{})
+	}
+	st := bus.Stats()
+	if st.Subscribers != 2 || st.Depth != 4 || st.Capacity != 6 || st.MaxFill != 1 {
+		t.Errorf("Stats() = %+v, want 2 subscribers, depth 4 of 6, full", st)
+	}
+
+	// The lossless subscriber is full, so this publish waits for it
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-lossless.Events()
+	}()
+	bus.Publish(ReadError{})
+	<-lossless.Events()
+	<-lossless.Events()
+
+	// The lossy subscriber fills up and drops the last event
+	bus.Publish(ReadError{})
+	bus.Publish(ReadError{})
+
+	st = bus.Stats()
+	if st.Dropped != 1 {
+		t.Errorf("Dropped = %d, want 1", st.Dropped)
+	}
+	if st.Blocked < 10*time.Millisecond {
+		t.Errorf("Blocked = %v, want the time Publish waited", st.Blocked)
+	}
+
+	// Totals outlive the subscriber
+	lossy.Close()
+	if st := bus.Stats(); st.Subscribers != 1 || st.Dropped != 1 {
+		t.Errorf("after Close, Stats() = %+v", st)
+	}
+}
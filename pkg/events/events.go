@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+// Package events defines the typed events heliostat frontends exchange and
+// the Bus that carries them. A single producer decodes the connection and
+// publishes events; TUIs, loggers, alert hooks and exporters subscribe to
+// the same bus instead of each decoding the byte stream themselves.
+package events
+
+import (
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// Event is implemented by every event type
+type Event interface {
+	// Time returns when the event occurred
+	Time() time.Time
+}
+
+// Urgent is implemented by events that may carry emergency-stop traffic.
+// The bus never drops an urgent event, and frontends deliver it without
+// waiting for their next batch.
+type Urgent interface {
+	Urgent() bool
+}
+
+// IsUrgent reports whether e is an urgent event
+func IsUrgent(e Event) bool {
+	u, ok := e.(Urgent)
+	return ok && u.Urgent()
+}
+
+// PacketReceived is published for every decoded packet that passed the
+// address filter. Anomalies holds validation results (nil when valid or
+// when the producer does not validate).
+type PacketReceived struct {
+	At        time.Time
+	Packet    *fusain.Packet
+	Anomalies []fusain.ValidationError
+}
+
+// DecodeError is published when the decoder rejects a frame (CRC error,
+// overflow, malformed CBOR, ...)
+type DecodeError struct {
+	At  time.Time
+	Err error
+}
+
+// Synchronized is published once per connection, before the first
+// PacketReceived. Skipped counts decode errors seen before it, which are
+// usually the tail of a frame that was in flight when reading started.
+type Synchronized struct {
+	At      time.Time
+	Skipped int
+}
+
+// ValidationAnomaly is published after PacketReceived for packets with
+// validation errors
+type ValidationAnomaly struct {
+	At        time.Time
+	Packet    *fusain.Packet
+	Anomalies []fusain.ValidationError
+}
+
+// DeviceStale is published when a device has sent no telemetry for longer
+// than the stale timeout. It is published once per silence; the next packet
+// from the device ends it.
+type DeviceStale struct {
+	At       time.Time
+	Address  uint64
+	LastSeen time.Time
+	Anomaly  fusain.ValidationError
+}
+
+// DeviceStateChanged is published when a device's STATE_DATA reports a
+// different state or error flag than before. Known is false for the first
+// STATE_DATA seen from a device, in which case Previous is meaningless.
+type DeviceStateChanged struct {
+	At       time.Time
+	Address  uint64
+	Known    bool
+	Previous fusain.SysState
+	State    fusain.SysState
+	Error    bool
+	Code     fusain.ErrorCode
+}
+
+// ReadError is published for a transient read failure; the producer keeps
+// reading
+type ReadError struct {
+	At  time.Time
+	Err error
+}
+
+// ConnectionLost is published when the connection is gone. With
+// Reconnecting set the connection is being reopened and ConnectionRestored
+// follows once it is back; otherwise the producer has stopped reading.
+type ConnectionLost struct {
+	At           time.Time
+	Err          error
+	Reconnecting bool
+}
+
+// ConnectionRestored is published after a successful reconnect
+type ConnectionRestored struct {
+	At   time.Time
+	Info string // Connection description, e.g. "Serial: /dev/ttyUSB0 @ 115200 baud"
+}
+
+// CommandSent is published after a command packet was written
+type CommandSent struct {
+	At     time.Time
+	Packet *fusain.Packet
+}
+
+// CommandAcked is published once the outcome of a command is known. Fusain
+// devices only reply to commands they reject: Rejection holds the
+// ERROR_INVALID_CMD or ERROR_STATE_REJECT reply, or is nil if none arrived
+// within the reply window.
+type CommandAcked struct {
+	At        time.Time
+	Address   uint64
+	MsgType   uint8 // Type of the command
+	Rejection *fusain.Packet
+}
+
+// TelemetryThrottled is published when a consumer that keeps dropping
+// events has a device's telemetry interval changed with TELEMETRY_CONFIG:
+// raised while the overflow lasts, then lowered step by step once it has
+// cleared. Restored is set when the original interval is back.
+type TelemetryThrottled struct {
+	At       time.Time
+	Address  uint64
+	Previous time.Duration
+	Interval time.Duration
+	Dropped  uint64 // Events dropped during the overflow (0 when lowering)
+	Restored bool
+}
+
+// AlertRaised is published when an alert rule's condition has held for the
+// rule's duration. It is published once; AlertCleared ends it.
+type AlertRaised struct {
+	At        time.Time
+	Rule      string
+	Severity  string // "info", "warning" or "critical"
+	Condition string // e.g. "temp0 > 220"
+	Address   uint64
+	Value     float64   // The metric's value when raised
+	Since     time.Time // When the condition started to hold
+}
+
+// AlertCleared is published when a raised alert's condition no longer holds
+type AlertCleared struct {
+	At        time.Time
+	Rule      string
+	Severity  string
+	Condition string
+	Address   uint64
+	Value     float64   // The metric's value when cleared
+	Since     time.Time // When the condition started to hold
+}
+
+// Accepted reports whether the command was not rejected
+func (e CommandAcked) Accepted() bool { return e.Rejection == nil }
+
+// Urgent reports whether the packet is emergency-stop traffic
+func (e PacketReceived) Urgent() bool { return e.Packet.IsEmergency() }
+
+// Urgent reports whether the device entered the E_STOP state
+func (e DeviceStateChanged) Urgent() bool { return e.State == fusain.SysStateEstop }
+
+// Urgent reports whether the command is an emergency stop
+func (e CommandSent) Urgent() bool { return e.Packet.IsEmergency() }
+
+func (e PacketReceived) Time() time.Time     { return e.At }
+func (e DecodeError) Time() time.Time        { return e.At }
+func (e Synchronized) Time() time.Time       { return e.At }
+func (e ValidationAnomaly) Time() time.Time  { return e.At }
+func (e DeviceStale) Time() time.Time        { return e.At }
+func (e DeviceStateChanged) Time() time.Time { return e.At }
+func (e ReadError) Time() time.Time          { return e.At }
+func (e ConnectionLost) Time() time.Time     { return e.At }
+func (e ConnectionRestored) Time() time.Time { return e.At }
+func (e CommandSent) Time() time.Time        { return e.At }
+func (e CommandAcked) Time() time.Time       { return e.At }
+func (e TelemetryThrottled) Time() time.Time { return e.At }
+func (e AlertRaised) Time() time.Time        { return e.At }
+func (e AlertCleared) Time() time.Time       { return e.At }
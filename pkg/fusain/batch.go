@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Batch records hold several timestamped wire frames, so a 1 kHz stream is
+// written and read a record at a time instead of a frame at a time:
+//
+//	record = length:u32le count:u16le base:i64le entry*count
+//	entry  = delta:uvarint size:uvarint frame:size bytes
+//
+// length counts the bytes after the length field. base is the first
+// frame's receive time in Unix nanoseconds and each delta is the time in
+// nanoseconds since the previous frame (the first delta is 0). Frames are
+// stored exactly as received on the wire, START and END bytes included.
+
+// DefaultBatchFrames is the number of frames per record used by
+// NewBatchWriter when maxFrames is 0
+const DefaultBatchFrames = 256
+
+// MaxBatchFrames is the most frames a record can hold
+const MaxBatchFrames = 0xFFFF
+
+// batchHeaderSize is the size of count and base
+const batchHeaderSize = 2 + 8
+
+// maxBatchRecordSize bounds records accepted by BatchReader
+const maxBatchRecordSize = batchHeaderSize + MaxBatchFrames*(2*binary.MaxVarintLen64+MaxPacketSize*2)
+
+// BatchFrame is one timestamped wire frame from a batch record
+type BatchFrame struct {
+	Timestamp time.Time
+	Frame     []byte
+}
+
+// Packet decodes the frame. The packet's timestamp is the recorded receive
+// time rather than the time of decoding.
+func (f BatchFrame) Packet() (*Packet, error) {
+	p, err := DecodePacket(f.Frame)
+	if err != nil {
+		return nil, err
+	}
+	p.timestamp = f.Timestamp
+	return p, nil
+}
+
+// BatchWriter collects frames into batch records. A record is written when
+// it holds maxFrames frames or on Flush; callers that need bounded latency
+// flush on a timer.
+//
+// A BatchWriter is not safe for concurrent use.
+type BatchWriter struct {
+	w         io.Writer
+	maxFrames int
+
+	buf   []byte    // Entries of the open record
+	count int       // Frames in the open record
+	base  time.Time // First frame time of the open record
+	last  time.Time // Previous frame time
+
+	records uint64
+	frames  uint64
+}
+
+// NewBatchWriter creates a writer that emits a record every maxFrames
+// frames (DefaultBatchFrames when 0, at most MaxBatchFrames)
+func NewBatchWriter(w io.Writer, maxFrames int) *BatchWriter {
+	if maxFrames <= 0 {
+		maxFrames = DefaultBatchFrames
+	}
+	if maxFrames > MaxBatchFrames {
+		maxFrames = MaxBatchFrames
+	}
+	return &BatchWriter{w: w, maxFrames: maxFrames}
+}
+
+// WriteFrame adds a wire frame received at the given time
+func (b *BatchWriter) WriteFrame(at time.Time, frame []byte) error {
+	if b.count == 0 {
+		b.base = at
+		b.last = at
+	}
+
+	// Frames must not go backwards in time; clamp so deltas stay unsigned
+	delta := at.Sub(b.last)
+	if delta < 0 {
+		delta = 0
+	} else {
+		b.last = at
+	}
+
+	b.buf = binary.AppendUvarint(b.buf, uint64(delta))
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(frame)))
+	b.buf = append(b.buf, frame...)
+	b.count++
+
+	if b.count >= b.maxFrames {
+		return b.Flush()
+	}
+	return nil
+}
+
+// WritePacket frames p from its CBOR payload and adds it with the packet's
+// timestamp. Received packets are stored byte-for-byte.
+func (b *BatchWriter) WritePacket(p *Packet) error {
+	raw := p.PayloadRaw()
+	if raw == nil {
+		return fmt.Errorf("fusain: packet has no CBOR payload")
+	}
+	if len(raw) > MaxPayloadSize {
+		return fmt.Errorf("CBOR payload too large: %d bytes (max %d)", len(raw), MaxPayloadSize)
+	}
+	return b.WriteFrame(p.Timestamp(), encodeFrame(p.Address(), raw))
+}
+
+// Flush writes the open record, if any
+func (b *BatchWriter) Flush() error {
+	if b.count == 0 {
+		return nil
+	}
+
+	record := make([]byte, 4+batchHeaderSize, 4+batchHeaderSize+len(b.buf))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(batchHeaderSize+len(b.buf)))
+	binary.LittleEndian.PutUint16(record[4:6], uint16(b.count))
+	binary.LittleEndian.PutUint64(record[6:14], uint64(b.base.UnixNano()))
+	record = append(record, b.buf...)
+
+	frames := b.count
+	b.buf = b.buf[:0]
+	b.count = 0
+
+	if _, err := b.w.Write(record); err != nil {
+		return err
+	}
+	b.records++
+	b.frames += uint64(frames)
+	return nil
+}
+
+// Records returns the number of records written
+func (b *BatchWriter) Records() uint64 {
+	return b.records
+}
+
+// Frames returns the number of frames written (excluding the open record)
+func (b *BatchWriter) Frames() uint64 {
+	return b.frames
+}
+
+// BatchReader reads batch records
+type BatchReader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+// NewBatchReader creates a reader for records written by BatchWriter
+func NewBatchReader(r io.Reader) *BatchReader {
+	return &BatchReader{r: bufio.NewReader(r)}
+}
+
+// Offset returns the number of bytes of the complete records read so far.
+// A reader following a file that is still being written can seek back to
+// it after a truncated record and read the record again once it is whole.
+func (b *BatchReader) Offset() int64 {
+	return b.offset
+}
+
+// ReadBatch returns the frames of the next record. It returns io.EOF after
+// the last record and io.ErrUnexpectedEOF for a truncated one.
+func (b *BatchReader) ReadBatch() ([]BatchFrame, error) {
+	var lengthBytes [4]byte
+	if _, err := io.ReadFull(b.r, lengthBytes[:]); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(lengthBytes[:])
+	if length < batchHeaderSize || length > maxBatchRecordSize {
+		return nil, fmt.Errorf("fusain: invalid batch record length %d", length)
+	}
+
+	record := make([]byte, length)
+	if _, err := io.ReadFull(b.r, record); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	count := int(binary.LittleEndian.Uint16(record[0:2]))
+	at := time.Unix(0, int64(binary.LittleEndian.Uint64(record[2:10])))
+	entries := record[batchHeaderSize:]
+
+	frames := make([]BatchFrame, 0, count)
+	for i := 0; i < count; i++ {
+		delta, n := binary.Uvarint(entries)
+		if n <= 0 {
+			return nil, fmt.Errorf("fusain: batch entry %d: invalid time delta", i)
+		}
+		entries = entries[n:]
+
+		size, n := binary.Uvarint(entries)
+		if n <= 0 || size > uint64(len(entries)-n) {
+			return nil, fmt.Errorf("fusain: batch entry %d: invalid frame size", i)
+		}
+		entries = entries[n:]
+
+		at = at.Add(time.Duration(delta))
+		frames = append(frames, BatchFrame{Timestamp: at, Frame: entries[:size:size]})
+		entries = entries[size:]
+	}
+	if len(entries) != 0 {
+		return nil, fmt.Errorf("fusain: batch record has %d trailing bytes", len(entries))
+	}
+
+	b.offset += int64(len(lengthBytes)) + int64(length)
+	return frames, nil
+}
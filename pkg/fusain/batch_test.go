@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBatch_RoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBatchWriter(&out, 2)
+
+	base := time.Unix(1700000000, 123456789)
+	packets := []*Packet{
+		roundTrip(t, TempData{Reading: 20.5}.Encode(0x01)),
+		roundTrip(t, NewPingRequest(0x02)),
+		roundTrip(t, StateData{State: 5}.Encode(0x03)),
+	}
+	for i, p := range packets {
+		p.timestamp = base.Add(time.Duration(i) * time.Millisecond)
+		if err := w.WritePacket(p); err != nil {
+			t.Fatalf("WritePacket failed: %v", err)
+		}
+	}
+	if w.Records() != 1 || w.Frames() != 2 {
+		t.Errorf("before Flush: %d records, %d frames; want 1, 2", w.Records(), w.Frames())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	r := NewBatchReader(&out)
+	var got []*Packet
+	for {
+		frames, err := r.ReadBatch()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadBatch failed: %v", err)
+		}
+		for _, f := range frames {
+			p, err := f.Packet()
+			if err != nil {
+				t.Fatalf("Packet failed: %v", err)
+			}
+			got = append(got, p)
+		}
+	}
+
+	if len(got) != len(packets) {
+		t.Fatalf("read %d packets, want %d", len(got), len(packets))
+	}
+	for i, p := range got {
+		if p.Address() != packets[i].Address() || !bytes.Equal(p.PayloadRaw(), packets[i].PayloadRaw()) {
+			t.Errorf("packet %d = %016X %x, want %016X %x", i, p.Address(), p.PayloadRaw(), packets[i].Address(), packets[i].PayloadRaw())
+		}
+		if !p.Timestamp().Equal(packets[i].Timestamp()) {
+			t.Errorf("packet %d timestamp = %v, want %v", i, p.Timestamp(), packets[i].Timestamp())
+		}
+	}
+}
+
+func TestBatch_Truncated(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBatchWriter(&out, 0)
+	w.WriteFrame(time.Now(), MustEncodePacket(NewPingRequest(0x01)))
+	w.Flush()
+
+	data := out.Bytes()
+	_, err := NewBatchReader(bytes.NewReader(data[:len(data)-1])).ReadBatch()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadBatch on truncated record = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestBatch_CorruptEntry(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBatchWriter(&out, 0)
+	w.WriteFrame(time.Now(), MustEncodePacket(NewPingRequest(0x01)))
+	w.Flush()
+
+	data := out.Bytes()
+	data[4] = 2 // Claim a second frame that isn't there
+	if _, err := NewBatchReader(bytes.NewReader(data)).ReadBatch(); err == nil {
+		t.Error("expected error for frame count mismatch")
+	}
+}
+
+func TestBatch_OffsetResumesTruncated(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBatchWriter(&out, 0)
+	w.WriteFrame(time.Now(), MustEncodePacket(NewPingRequest(0x01)))
+	w.Flush()
+	first := int64(out.Len())
+	w.WriteFrame(time.Now(), MustEncodePacket(NewPingRequest(0x02)))
+	w.Flush()
+	data := out.Bytes()
+
+	// The second record is only half written
+	r := NewBatchReader(bytes.NewReader(data[:len(data)-3]))
+	if _, err := r.ReadBatch(); err != nil {
+		t.Fatalf("ReadBatch failed: %v", err)
+	}
+	if _, err := r.ReadBatch(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadBatch on truncated record = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if r.Offset() != first {
+		t.Fatalf("Offset() = %d, want %d (end of the first record)", r.Offset(), first)
+	}
+
+	// Once written, the record reads whole from the offset
+	frames, err := NewBatchReader(bytes.NewReader(data[r.Offset():])).ReadBatch()
+	if err != nil || len(frames) != 1 {
+		t.Fatalf("ReadBatch from offset = %d frames, %v", len(frames), err)
+	}
+	if p, err := frames[0].Packet(); err != nil || p.Address() != 0x02 {
+		t.Errorf("resumed packet = %v, %v; want address 0x02", p, err)
+	}
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+// Allocation budgets for the hot path. The package tests fail when decoding
+// or encoding the test vectors allocates more than this, so regressions are
+// caught in CI rather than on a 1 kHz telemetry stream. The throughput
+// benchmark helper is fusaintest.BenchmarkThroughput.
+const (
+	// DecodeFrameAllocBudget is the allocations per decoded frame: the Packet
+	// and its CBOR payload, both made at the length byte. Every other byte
+	// passed to DecodeByte allocates nothing.
+	DecodeFrameAllocBudget = 2
+
+	// EncodePacketAllocBudget is the allocations per EncodePacket call for a
+	// payload of up to 8 keys. Usually 7 or fewer; the CBOR encoder's
+	// key-sorting scratch comes from a sync.Pool that may hold a slice too
+	// small for the map, costing 2 more.
+	EncodePacketAllocBudget = 9
+)
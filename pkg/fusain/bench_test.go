@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import "testing"
+
+func BenchmarkEncodePacket(b *testing.B) {
+	payload := MotorData{Motor: 0, Timestamp: 120000, RPM: 3150, Target: 3200}.Encode(0x01).PayloadMap()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodePacket(0x0123456789ABCDEF, MsgMotorData, payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCalculateCRC(b *testing.B) {
+	data := make([]byte, 1+AddressSize+MaxPayloadSize)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		CalculateCRC(data)
+	}
+}
+
+// minAllocsPerRun is testing.AllocsPerRun, but takes the lowest of several
+// measurements: allocation counts are process-wide, so goroutines left over
+// from other tests can only add to them
+func minAllocsPerRun(runs int, f func()) float64 {
+	lowest := testing.AllocsPerRun(runs, f)
+	for i := 0; i < 4; i++ {
+		lowest = min(lowest, testing.AllocsPerRun(runs, f))
+	}
+	return lowest
+}
+
+func TestAllocBudget_DecodeByte(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful under the race detector")
+	}
+	d := NewDecoder()
+	for _, v := range Vectors() {
+		frame := MustEncodePacket(v.Packet)
+		allocs := minAllocsPerRun(100, func() {
+			for _, c := range frame {
+				d.DecodeByte(c)
+			}
+		})
+		if allocs > DecodeFrameAllocBudget {
+			t.Errorf("%s: %.0f allocs per frame, budget %d", v.Name, allocs, DecodeFrameAllocBudget)
+		}
+	}
+}
+
+func TestAllocBudget_EncodePacket(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful under the race detector")
+	}
+	for _, v := range Vectors() {
+		address, msgType, payload := v.Packet.Address(), v.Packet.Type(), v.Packet.PayloadMap()
+		allocs := minAllocsPerRun(100, func() {
+			EncodePacket(address, msgType, payload)
+		})
+		if allocs > EncodePacketAllocBudget {
+			t.Errorf("%s: %.0f allocs per EncodePacket, budget %d", v.Name, allocs, EncodePacketAllocBudget)
+		}
+	}
+}
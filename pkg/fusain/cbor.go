@@ -4,21 +4,124 @@
 package fusain
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/fxamacker/cbor/v2"
 )
 
+// CBOR decoding limits, derived from MaxPayloadSize. A Fusain message is
+// [msg_type, {key: scalar}], so anything deeper or larger than a valid
+// frame could carry is rejected before allocation.
+const (
+	MaxCBORNestingDepth  = 4                  // array > map > value, plus one level of slack (library minimum)
+	MaxCBORArrayElements = MaxPayloadSize     // every element takes at least one byte
+	MaxCBORMapPairs      = MaxPayloadSize / 2 // every pair takes at least two bytes
+)
+
+// cborDecMode is the hardened decoder used for all incoming payloads
+var cborDecMode = mustDecMode(cbor.DecOptions{
+	MaxNestedLevels:  MaxCBORNestingDepth,
+	MaxArrayElements: MaxCBORArrayElements,
+	MaxMapPairs:      MaxCBORMapPairs,
+})
+
+func mustDecMode(opts cbor.DecOptions) cbor.DecMode {
+	dm, err := opts.DecMode()
+	if err != nil {
+		panic(fmt.Sprintf("fusain: invalid CBOR decode options: %v", err))
+	}
+	return dm
+}
+
+// cborDiagMode renders CBOR diagnostic notation. Its nesting limit is looser
+// than cborDecMode's so payloads the decoder rejects can still be shown;
+// input is bounded by the frame size anyway.
+var cborDiagMode = mustDiagMode(cbor.DiagOptions{
+	MaxNestedLevels: 16,
+	CBORSequence:    true,
+})
+
+// cborSortedEncMode encodes maps with sorted keys for stable diagnostic output
+var cborSortedEncMode = mustEncMode(cbor.EncOptions{Sort: cbor.SortCanonical})
+
+func mustDiagMode(opts cbor.DiagOptions) cbor.DiagMode {
+	dm, err := opts.DiagMode()
+	if err != nil {
+		panic(fmt.Sprintf("fusain: invalid CBOR diagnostic options: %v", err))
+	}
+	return dm
+}
+
+func mustEncMode(opts cbor.EncOptions) cbor.EncMode {
+	em, err := opts.EncMode()
+	if err != nil {
+		panic(fmt.Sprintf("fusain: invalid CBOR encode options: %v", err))
+	}
+	return em
+}
+
+// FormatCBORDiagnostic renders CBOR data in diagnostic notation (RFC 8949
+// section 8), e.g. [80, {0: 1.5, 1: h'0102'}]. Invalid CBOR is returned as
+// a hex byte string followed by the error.
+func FormatCBORDiagnostic(data []byte) string {
+	diag, err := cborDiagMode.Diagnose(data)
+	if err != nil {
+		return fmt.Sprintf("h'%x' (invalid CBOR: %v)", data, err)
+	}
+	return diag
+}
+
+// CBORLimitError reports a payload rejected for exceeding a decoding limit
+type CBORLimitError struct {
+	Limit string // "payload size", "nesting depth", "array elements" or "map pairs"
+	Max   int
+	Err   error // Underlying error from the CBOR library (nil for payload size)
+}
+
+// Error implements the error interface
+func (e *CBORLimitError) Error() string {
+	return fmt.Sprintf("CBOR %s limit exceeded (max %d)", e.Limit, e.Max)
+}
+
+// Unwrap returns the underlying CBOR library error
+func (e *CBORLimitError) Unwrap() error {
+	return e.Err
+}
+
+// limitError converts CBOR library limit violations into a CBORLimitError,
+// returning nil for other errors
+func limitError(err error) *CBORLimitError {
+	var nested *cbor.MaxNestedLevelError
+	var array *cbor.MaxArrayElementsError
+	var pairs *cbor.MaxMapPairsError
+	switch {
+	case errors.As(err, &nested):
+		return &CBORLimitError{Limit: "nesting depth", Max: MaxCBORNestingDepth, Err: err}
+	case errors.As(err, &array):
+		return &CBORLimitError{Limit: "array elements", Max: MaxCBORArrayElements, Err: err}
+	case errors.As(err, &pairs):
+		return &CBORLimitError{Limit: "map pairs", Max: MaxCBORMapPairs, Err: err}
+	}
+	return nil
+}
+
 // ParseCBORMessage parses a Fusain CBOR message: [msg_type, payload_map]
 // Returns the message type and decoded payload map (nil for empty payloads)
 func ParseCBORMessage(data []byte) (msgType uint8, payload map[int]interface{}, err error) {
 	if len(data) == 0 {
 		return 0, nil, fmt.Errorf("empty CBOR payload")
 	}
+	if len(data) > MaxPayloadSize {
+		return 0, nil, &CBORLimitError{Limit: "payload size", Max: MaxPayloadSize}
+	}
 
 	// Decode as array with 2 elements: [msg_type, payload]
 	var msg []interface{}
-	if err := cbor.Unmarshal(data, &msg); err != nil {
+	if err := cborDecMode.Unmarshal(data, &msg); err != nil {
+		if limitErr := limitError(err); limitErr != nil {
+			return 0, nil, limitErr
+		}
 		return 0, nil, fmt.Errorf("failed to decode CBOR: %w", err)
 	}
 
@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultAckWindow is how long SetMode waits for an error reply before
+// treating a command as accepted. Fusain devices only reply to commands
+// they reject.
+const DefaultAckWindow = 500 * time.Millisecond
+
+// ErrClientClosed is returned for requests made after the connection failed
+var ErrClientClosed = errors.New("fusain: client connection closed")
+
+// CommandError is returned when a device rejects a command with
+// ERROR_INVALID_CMD or ERROR_STATE_REJECT
+type CommandError struct {
+	Reply *Packet
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	name := FormatMessageType(e.Reply.Type())
+	if reject, err := DecodeErrorStateReject(e.Reply); err == nil {
+		return fmt.Sprintf("%s: device in %s state", name, FormatState(uint32(reject.State)))
+	}
+	if invalid, err := DecodeErrorInvalidCmd(e.Reply); err == nil {
+		switch invalid.Code {
+		case 1:
+			return name + ": invalid parameter value"
+		case 2:
+			return name + ": invalid device index"
+		}
+		return fmt.Sprintf("%s: code %d", name, invalid.Code)
+	}
+	return name
+}
+
+// DiscoveredDevice is a device reported during discovery
+type DiscoveredDevice struct {
+	Address uint64
+	DeviceAnnounce
+}
+
+// waiter receives packets accepted by match until it is removed
+type waiter struct {
+	match   func(*Packet) bool
+	packets chan *Packet
+}
+
+// Client wraps a connection (serial port, WebSocket, ...) and correlates
+// responses with requests. A background goroutine decodes incoming packets;
+// each one is delivered to the oldest pending request that matches it, or
+// otherwise to the Packets channel.
+//
+// The client does not own the connection: close it to stop the client.
+type Client struct {
+	rw  io.ReadWriter
+	enc *Encoder
+
+	// AckWindow is how long SetMode waits for a rejection (default DefaultAckWindow)
+	AckWindow time.Duration
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	waiters []*waiter
+	err     error
+
+	packets chan *Packet
+	done    chan struct{}
+}
+
+// NewClient starts a client on rw
+func NewClient(rw io.ReadWriter) *Client {
+	c := &Client{
+		rw:        rw,
+		enc:       NewEncoder(rw),
+		AckWindow: DefaultAckWindow,
+		packets:   make(chan *Packet, 64),
+		done:      make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Packets returns packets not claimed by a pending request (telemetry etc.).
+// Packets are dropped if the channel is not drained.
+func (c *Client) Packets() <-chan *Packet {
+	return c.packets
+}
+
+// Done is closed when the connection fails
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the read error that stopped the client, if any
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) readLoop() {
+	decoder := NewDecoder()
+	buf := make([]byte, 256)
+	for {
+		n, err := c.rw.Read(buf)
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+		packets, _ := decoder.Decode(buf[:n])
+		for _, packet := range packets {
+			c.dispatch(packet)
+		}
+	}
+}
+
+// dispatch delivers a packet to the oldest matching waiter
+func (c *Client) dispatch(p *Packet) {
+	c.mu.Lock()
+	for _, w := range c.waiters {
+		if w.match(p) {
+			c.mu.Unlock()
+			select {
+			case w.packets <- p:
+			default:
+			}
+			return
+		}
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.packets <- p:
+	default:
+	}
+}
+
+func (c *Client) addWaiter(match func(*Packet) bool) *waiter {
+	w := &waiter{match: match, packets: make(chan *Packet, 64)}
+	c.mu.Lock()
+	c.waiters = append(c.waiters, w)
+	c.mu.Unlock()
+	return w
+}
+
+func (c *Client) removeWaiter(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.waiters {
+		if existing == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Send encodes and writes a packet without waiting for a response
+func (c *Client) Send(p *Packet) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.enc.WritePacket(p)
+}
+
+// Request sends p and waits for the first response accepted by match
+func (c *Client) Request(ctx context.Context, p *Packet, match func(*Packet) bool) (*Packet, error) {
+	w := c.addWaiter(match)
+	defer c.removeWaiter(w)
+
+	if err := c.Send(p); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-w.packets:
+		return reply, nil
+	case <-c.done:
+		return nil, ErrClientClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Ping sends PING_REQUEST to address and returns the response and round-trip time
+func (c *Client) Ping(ctx context.Context, address uint64) (PingResponse, time.Duration, error) {
+	start := time.Now()
+	reply, err := c.Request(ctx, NewPingRequest(address), func(p *Packet) bool {
+		return p.Type() == MsgPingResponse && p.Address() == address
+	})
+	if err != nil {
+		return PingResponse{}, 0, err
+	}
+	rtt := time.Since(start)
+	resp, err := DecodePingResponse(reply)
+	return resp, rtt, err
+}
+
+// RequestTelemetry sends SEND_TELEMETRY to address and returns the data
+// message for the requested type and index. It returns a *CommandError if
+// the device rejects the request (e.g. for an index it doesn't have).
+func (c *Client) RequestTelemetry(ctx context.Context, address uint64, telemetryType TelemetryType, index uint8) (*Packet, error) {
+	dataType := TelemetryDataType(telemetryType)
+	if dataType == 0 {
+		return nil, fmt.Errorf("unknown telemetry type %d", telemetryType)
+	}
+	reply, err := c.Request(ctx, NewSendTelemetry(address, telemetryType, index), func(p *Packet) bool {
+		switch {
+		case p.Address() != address:
+			return false
+		case p.Type() == MsgErrorInvalidCmd:
+			return true
+		case p.Type() != dataType:
+			return false
+		case dataType == MsgStateData:
+			return true
+		}
+		got, ok := GetMapUint(p.PayloadMap(), 0)
+		return ok && got == uint64(index)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Type() == MsgErrorInvalidCmd {
+		return nil, &CommandError{Reply: reply}
+	}
+	return reply, nil
+}
+
+// Discover sends DISCOVERY_REQUEST to the stateless address and collects
+// DEVICE_ANNOUNCE replies until the end-of-discovery marker. On timeout
+// the devices found so far are returned with ctx.Err().
+func (c *Client) Discover(ctx context.Context) ([]DiscoveredDevice, error) {
+	w := c.addWaiter(func(p *Packet) bool {
+		return p.Type() == MsgDeviceAnnounce
+	})
+	defer c.removeWaiter(w)
+
+	if err := c.Send(NewDiscoveryRequest(AddressStateless)); err != nil {
+		return nil, err
+	}
+
+	var devices []DiscoveredDevice
+	for {
+		select {
+		case p := <-w.packets:
+			announce, err := DecodeDeviceAnnounce(p)
+			if err != nil {
+				continue
+			}
+			if announce.IsEndMarker() {
+				return devices, nil
+			}
+			devices = append(devices, DiscoveredDevice{Address: p.Address(), DeviceAnnounce: announce})
+		case <-c.done:
+			return devices, ErrClientClosed
+		case <-ctx.Done():
+			return devices, ctx.Err()
+		}
+	}
+}
+
+// SetMode sends STATE_COMMAND to address. It returns a *CommandError if the
+// device rejects the command within AckWindow (or before ctx expires),
+// otherwise nil.
+func (c *Client) SetMode(ctx context.Context, address uint64, mode Mode, argument *int64) error {
+	ackCtx, cancel := context.WithTimeout(ctx, c.AckWindow)
+	defer cancel()
+
+	reply, err := c.Request(ackCtx, NewStateCommand(address, uint8(mode), argument), func(p *Packet) bool {
+		return p.Address() == address &&
+			(p.Type() == MsgErrorInvalidCmd || p.Type() == MsgErrorStateReject)
+	})
+	switch {
+	case err == nil:
+		return &CommandError{Reply: reply}
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		return nil // No rejection within the ack window
+	default:
+		return err
+	}
+}
+
+// Subscribe asks the router to forward telemetry from address
+func (c *Client) Subscribe(address uint64) error {
+	return c.Send(NewDataSubscription(AddressStateless, address))
+}
+
+// Unsubscribe asks the router to stop forwarding telemetry from address
+func (c *Client) Unsubscribe(address uint64) error {
+	return c.Send(NewDataUnsubscribe(AddressStateless, address))
+}
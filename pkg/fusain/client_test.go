@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+const testDevice = 0x123456789ABCDEF0
+
+// fakeRouter answers requests on conn until it is closed
+func fakeRouter(t *testing.T, conn net.Conn) {
+	t.Helper()
+	go func() {
+		decoder := NewDecoder()
+		buf := make([]byte, 256)
+		reply := func(p *Packet) { conn.Write(MustEncodePacket(p)) }
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			for i := 0; i < n; i++ {
+				p, err := decoder.DecodeByte(buf[i])
+				if err != nil || p == nil {
+					continue
+				}
+				switch p.Type() {
+				case MsgPingRequest:
+					reply(PingResponse{Uptime: 1000}.Encode(p.Address()))
+				case MsgDiscoveryRequest:
+					reply(StateData{State: SysStateIdle}.Encode(testDevice)) // unsolicited telemetry
+					reply(DeviceAnnounce{MotorCount: 1, ThermometerCount: 1}.Encode(testDevice))
+					reply(DeviceAnnounce{}.Encode(AddressStateless))
+				case MsgSendTelemetry:
+					index, _ := GetMapUint(p.PayloadMap(), 1)
+					if index > 1 {
+						reply(ErrorInvalidCmd{Code: 2}.Encode(p.Address()))
+						continue
+					}
+					reply(MotorData{Motor: uint8(index + 1), RPM: 900}.Encode(p.Address())) // other motor first
+					reply(MotorData{Motor: uint8(index), RPM: 1200}.Encode(p.Address()))
+				case MsgStateCommand:
+					cmd, _ := DecodeStateCommand(p)
+					if cmd.Mode == ModeHeat {
+						reply(ErrorStateReject{State: SysStateError}.Encode(p.Address()))
+					}
+				}
+			}
+		}
+	}()
+}
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	clientConn, routerConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		routerConn.Close()
+	})
+	fakeRouter(t, routerConn)
+	c := NewClient(clientConn)
+	c.AckWindow = 50 * time.Millisecond
+	return c
+}
+
+func TestClient_Ping(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, rtt, err := c.Ping(ctx, AddressStateless)
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if resp.Uptime != 1000 {
+		t.Errorf("Expected uptime=1000, got %d", resp.Uptime)
+	}
+	if rtt <= 0 {
+		t.Errorf("Expected positive rtt, got %v", rtt)
+	}
+}
+
+func TestClient_RequestTelemetry(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	reply, err := c.RequestTelemetry(ctx, testDevice, TelemetryTypeMotor, 1)
+	if err != nil {
+		t.Fatalf("RequestTelemetry failed: %v", err)
+	}
+	motor, err := DecodeMotorData(reply)
+	if err != nil {
+		t.Fatalf("DecodeMotorData failed: %v", err)
+	}
+	if motor.Motor != 1 || motor.RPM != 1200 {
+		t.Errorf("Expected motor 1 at 1200 RPM, got %+v", motor)
+	}
+
+	var cmdErr *CommandError
+	if _, err := c.RequestTelemetry(ctx, testDevice, TelemetryTypeMotor, 5); !errors.As(err, &cmdErr) {
+		t.Errorf("Expected a CommandError for a missing motor, got %v", err)
+	}
+	if _, err := c.RequestTelemetry(ctx, testDevice, TelemetryType(9), 0); err == nil {
+		t.Error("Expected an error for an unknown telemetry type")
+	}
+}
+
+func TestClient_Discover(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	devices, err := c.Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Address != testDevice || devices[0].MotorCount != 1 {
+		t.Errorf("Unexpected devices: %+v", devices)
+	}
+
+	select {
+	case p := <-c.Packets():
+		if p.Type() != MsgStateData {
+			t.Errorf("Expected unclaimed STATE_DATA, got %s", FormatMessageType(p.Type()))
+		}
+	case <-ctx.Done():
+		t.Error("Unclaimed telemetry was not delivered to Packets()")
+	}
+}
+
+func TestClient_SetMode(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	if err := c.SetMode(ctx, testDevice, ModeIdle, nil); err != nil {
+		t.Errorf("Expected IDLE to be accepted, got %v", err)
+	}
+
+	err := c.SetMode(ctx, testDevice, ModeHeat, ptr(int64(500)))
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected CommandError, got %v", err)
+	}
+	if cmdErr.Error() != "ERROR_STATE_REJECT: device in ERROR state" {
+		t.Errorf("Unexpected error text: %q", cmdErr.Error())
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The fake router never answers for an unknown message type
+	_, err := c.Request(ctx, NewTelemetryConfig(testDevice, true, 100), func(p *Packet) bool { return false })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestClient_Closed(t *testing.T) {
+	clientConn, routerConn := net.Pipe()
+	c := NewClient(clientConn)
+	routerConn.Close()
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Client did not notice closed connection")
+	}
+	if _, _, err := c.Ping(context.Background(), testDevice); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	clientConn.Close()
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"sync"
+	"time"
+)
+
+// clockRebootSlack is how far a device timestamp may go backwards before
+// the device is assumed to have restarted. Streams are timestamped
+// independently, so small reorderings between them are normal.
+const clockRebootSlack = 1000 // ms
+
+// DeviceTime returns the device-relative timestamp (milliseconds since the
+// device started) carried by telemetry data and PING_RESPONSE packets
+func DeviceTime(p *Packet) (uint64, bool) {
+	key := 1
+	switch p.Type() {
+	case MsgStateData:
+		key = 3
+	case MsgMotorData, MsgPumpData, MsgGlowData, MsgTempData:
+	case MsgPingResponse:
+		key = 0
+	default:
+		return 0, false
+	}
+	return GetMapUint(p.PayloadMap(), key)
+}
+
+// deviceClock is the estimate for one device
+type deviceClock struct {
+	start time.Time // Wall-clock time of device time zero
+	last  uint64    // Latest device time seen (ms)
+}
+
+// ClockEstimator maps device timestamps to estimated wall-clock times, so
+// device events can be matched against external logs.
+//
+// For each device, the offset between receive time and device time is
+// tracked and the smallest one kept: transmission and buffering delays
+// only ever make a packet arrive later than its timestamp says, so the
+// minimum converges on the true offset. A timestamp that goes back more
+// than a second means the device restarted and the estimate starts over.
+//
+// A ClockEstimator is safe for concurrent use.
+type ClockEstimator struct {
+	mu      sync.Mutex
+	devices map[uint64]*deviceClock
+}
+
+// NewClockEstimator creates an empty clock estimator
+func NewClockEstimator() *ClockEstimator {
+	return &ClockEstimator{devices: make(map[uint64]*deviceClock)}
+}
+
+// Observe updates the estimate for p's device from its device time and
+// receive time (Packet.Timestamp). Packets without a device time are
+// ignored.
+func (c *ClockEstimator) Observe(p *Packet) {
+	deviceMs, ok := DeviceTime(p)
+	if !ok || p.IsBroadcast() || p.IsStateless() {
+		return
+	}
+	start := p.Timestamp().Add(-time.Duration(deviceMs) * time.Millisecond)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, known := c.devices[p.Address()]
+	if !known || deviceMs+clockRebootSlack < d.last {
+		c.devices[p.Address()] = &deviceClock{start: start, last: deviceMs}
+		return
+	}
+	if start.Before(d.start) {
+		d.start = start
+	}
+	if deviceMs > d.last {
+		d.last = deviceMs
+	}
+}
+
+// WallClock returns the estimated wall-clock time of device time deviceMs
+// on the device at address, or false if nothing was observed from it
+func (c *ClockEstimator) WallClock(address uint64, deviceMs uint64) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.devices[address]
+	if !ok {
+		return time.Time{}, false
+	}
+	return d.start.Add(time.Duration(deviceMs) * time.Millisecond), true
+}
+
+// Reset forgets every device
+func (c *ClockEstimator) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.devices = make(map[uint64]*deviceClock)
+}
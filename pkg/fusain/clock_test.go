@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// receivedAt returns p as if decoded at t
+func receivedAt(p *Packet, t time.Time) *Packet {
+	p.timestamp = t
+	return p
+}
+
+func TestDeviceTime(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Packet
+		want uint64
+		ok   bool
+	}{
+		{"state", StateData{State: SysStateIdle, Timestamp: 1500}.Encode(1), 1500, true},
+		{"motor", MotorData{Timestamp: 2500}.Encode(1), 2500, true},
+		{"temp", TempData{Timestamp: 3500}.Encode(1), 3500, true},
+		{"ping", PingResponse{Uptime: 4500}.Encode(1), 4500, true},
+		{"command", NewPingRequest(1), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DeviceTime(tt.p)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("DeviceTime = %d, %v; want %d, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClockEstimator_MinimumOffset(t *testing.T) {
+	c := NewClockEstimator()
+	boot := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	if _, ok := c.WallClock(1, 0); ok {
+		t.Fatal("WallClock known before any packet")
+	}
+
+	// Delays of 30ms, 5ms and 20ms: the 5ms packet sets the estimate
+	for _, sample := range []struct{ deviceMs, delayMs int }{{1000, 30}, {2000, 5}, {3000, 20}} {
+		at := boot.Add(time.Duration(sample.deviceMs+sample.delayMs) * time.Millisecond)
+		c.Observe(receivedAt(MotorData{Timestamp: uint64(sample.deviceMs)}.Encode(1), at))
+	}
+
+	got, ok := c.WallClock(1, 60000)
+	want := boot.Add(60005 * time.Millisecond)
+	if !ok || !got.Equal(want) {
+		t.Errorf("WallClock = %v, %v; want %v", got, ok, want)
+	}
+	if _, ok := c.WallClock(2, 0); ok {
+		t.Error("WallClock known for an unseen device")
+	}
+}
+
+func TestClockEstimator_Restart(t *testing.T) {
+	c := NewClockEstimator()
+	boot := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	c.Observe(receivedAt(StateData{Timestamp: 50000}.Encode(1), boot.Add(50*time.Second)))
+	// Small reordering between streams is not a restart
+	c.Observe(receivedAt(TempData{Timestamp: 49800}.Encode(1), boot.Add(50*time.Second)))
+	if got, _ := c.WallClock(1, 0); !got.Equal(boot) {
+		t.Errorf("start = %v after reordering, want %v", got, boot)
+	}
+
+	// The device restarts 60s after it first booted
+	reboot := boot.Add(60 * time.Second)
+	c.Observe(receivedAt(StateData{Timestamp: 100}.Encode(1), reboot.Add(100*time.Millisecond)))
+	if got, _ := c.WallClock(1, 0); !got.Equal(reboot) {
+		t.Errorf("start = %v after restart, want %v", got, reboot)
+	}
+
+	c.Reset()
+	if _, ok := c.WallClock(1, 0); ok {
+		t.Error("WallClock known after Reset")
+	}
+}
+
+func TestFormatPacket_DeviceClock(t *testing.T) {
+	c := NewClockEstimator()
+	boot := time.Date(2025, 1, 2, 15, 0, 0, 0, time.Local)
+	p := receivedAt(TempData{Timestamp: 5123, Reading: 20}.Encode(1), boot.Add(5200*time.Millisecond))
+	c.Observe(p)
+
+	header := strings.SplitN(FormatPacketWithOptions(p, FormatOptions{Clock: c}), "\n", 2)[0]
+	if !strings.HasSuffix(header, " device=15:00:05.200") {
+		t.Errorf("header %q does not end with the device wall-clock time", header)
+	}
+	if header := strings.SplitN(FormatPacket(p), "\n", 2)[0]; strings.Contains(header, "device=") {
+		t.Errorf("header %q has a device time without a clock", header)
+	}
+}
@@ -40,6 +40,35 @@ func NewTelemetryConfig(address uint64, enabled bool, intervalMs uint32) *Packet
 	return NewPacketWithPayload(address, MsgTelemetryConfig, payload)
 }
 
+// NewSendTelemetry creates a SEND_TELEMETRY packet (0x25).
+// Requests one telemetry message from an appliance in polling mode; index
+// selects the motor, thermometer, pump or glow plug (ignored for state).
+func NewSendTelemetry(address uint64, telemetryType TelemetryType, index uint8) *Packet {
+	payload := map[int]interface{}{
+		0: uint64(telemetryType),
+		1: uint64(index),
+	}
+	return NewPacketWithPayload(address, MsgSendTelemetry, payload)
+}
+
+// TelemetryDataType returns the data message type an appliance sends in
+// reply to SEND_TELEMETRY of the given type, or 0 for unknown types
+func TelemetryDataType(telemetryType TelemetryType) uint8 {
+	switch telemetryType {
+	case TelemetryTypeState:
+		return MsgStateData
+	case TelemetryTypeMotor:
+		return MsgMotorData
+	case TelemetryTypeTemp:
+		return MsgTempData
+	case TelemetryTypePump:
+		return MsgPumpData
+	case TelemetryTypeGlow:
+		return MsgGlowData
+	}
+	return 0
+}
+
 // NewMotorCommand creates a MOTOR_COMMAND packet (0x21).
 // Sets the target RPM for the specified motor.
 // Use rpm=0 to stop the motor.
@@ -73,6 +102,18 @@ func NewGlowCommand(address uint64, glow uint8, durationMs int32) *Packet {
 	return NewPacketWithPayload(address, MsgGlowCommand, payload)
 }
 
+// NewTargetTempCommand creates a TEMP_COMMAND packet (0x24) of type
+// SET_TARGET_TEMP.
+// Sets the target temperature (°C) the thermometer's RPM control holds.
+func NewTargetTempCommand(address uint64, thermometer uint8, targetC float64) *Packet {
+	payload := map[int]interface{}{
+		0: int64(thermometer),
+		1: uint64(TempCmdSetTargetTemp),
+		3: targetC,
+	}
+	return NewPacketWithPayload(address, MsgTempCommand, payload)
+}
+
 // NewDiscoveryRequest creates a DISCOVERY_REQUEST packet (0x1F).
 // Routers respond with DEVICE_ANNOUNCE for each known device, followed by
 // an end-of-discovery marker (DEVICE_ANNOUNCE with all zeros).
@@ -90,3 +131,13 @@ func NewDataSubscription(routerAddress uint64, applianceAddress uint64) *Packet
 	}
 	return NewPacketWithPayload(routerAddress, MsgDataSubscription, payload)
 }
+
+// NewDataUnsubscribe creates a DATA_UNSUBSCRIBE packet (0x15).
+// Tells a router to stop forwarding telemetry from the specified appliance.
+// The packet address should be the router's address (or broadcast/stateless).
+func NewDataUnsubscribe(routerAddress uint64, applianceAddress uint64) *Packet {
+	payload := map[int]interface{}{
+		0: applianceAddress,
+	}
+	return NewPacketWithPayload(routerAddress, MsgDataUnsubscribe, payload)
+}
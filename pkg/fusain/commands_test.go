@@ -225,6 +225,39 @@ func TestNewTelemetryConfig_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestNewSendTelemetry(t *testing.T) {
+	p := NewSendTelemetry(0x1234, TelemetryTypeTemp, 2)
+
+	if p.Type() != MsgSendTelemetry {
+		t.Errorf("Type() = 0x%02X, want 0x%02X", p.Type(), MsgSendTelemetry)
+	}
+	if errs := ValidatePacket(p); len(errs) > 0 {
+		t.Errorf("SEND_TELEMETRY failed validation: %v", errs)
+	}
+	if v, _ := GetMapUint(p.PayloadMap(), 0); v != uint64(TelemetryTypeTemp) {
+		t.Errorf("telemetry type = %d, want %d", v, TelemetryTypeTemp)
+	}
+	if v, _ := GetMapUint(p.PayloadMap(), 1); v != 2 {
+		t.Errorf("index = %d, want 2", v)
+	}
+}
+
+func TestTelemetryDataType(t *testing.T) {
+	tests := map[TelemetryType]uint8{
+		TelemetryTypeState: MsgStateData,
+		TelemetryTypeMotor: MsgMotorData,
+		TelemetryTypeTemp:  MsgTempData,
+		TelemetryTypePump:  MsgPumpData,
+		TelemetryTypeGlow:  MsgGlowData,
+		TelemetryType(9):   0,
+	}
+	for telemetryType, want := range tests {
+		if got := TelemetryDataType(telemetryType); got != want {
+			t.Errorf("TelemetryDataType(%d) = 0x%02X, want 0x%02X", telemetryType, got, want)
+		}
+	}
+}
+
 func TestNewMotorCommand(t *testing.T) {
 	p := NewMotorCommand(0x1234567890ABCDEF, 0, 2500)
 
@@ -417,7 +450,78 @@ func TestNewGlowCommand_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestNewTargetTempCommand(t *testing.T) {
+	p := NewTargetTempCommand(0x1234567890ABCDEF, 1, 180.5)
+
+	encoded, err := EncodePacket(p.Address(), p.Type(), p.PayloadMap())
+	if err != nil {
+		t.Fatalf("EncodePacket failed: %v", err)
+	}
+
+	decoded, err := DecodePacket(encoded)
+	if err != nil {
+		t.Fatalf("DecodePacket failed: %v", err)
+	}
+
+	if decoded.Type() != MsgTempCommand {
+		t.Errorf("decoded Type() = 0x%02X, want 0x%02X", decoded.Type(), MsgTempCommand)
+	}
+
+	payload := decoded.PayloadMap()
+	therm, _ := GetMapInt(payload, 0)
+	cmdType, _ := GetMapUint(payload, 1)
+	target, ok := GetMapFloat(payload, 3)
+	if !ok {
+		t.Fatal("decoded payload missing target-temp (key 3)")
+	}
+	if therm != 1 || TempCmdType(cmdType) != TempCmdSetTargetTemp || target != 180.5 {
+		t.Errorf("decoded thermometer=%d type=%d target=%v, want thermometer=1 type=%d target=180.5",
+			therm, cmdType, target, TempCmdSetTargetTemp)
+	}
+}
+
 // ptr is a helper to create pointer to value
+func TestNewDataSubscription(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(router, appliance uint64) *Packet
+		msgType uint8
+	}{
+		{"subscribe", NewDataSubscription, MsgDataSubscription},
+		{"unsubscribe", NewDataUnsubscribe, MsgDataUnsubscribe},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.build(AddressStateless, 0x1234567890ABCDEF)
+
+			if p.Address() != AddressStateless {
+				t.Errorf("Address() = 0x%X, want 0x%X", p.Address(), uint64(AddressStateless))
+			}
+			if p.Type() != tt.msgType {
+				t.Errorf("Type() = 0x%02X, want 0x%02X", p.Type(), tt.msgType)
+			}
+
+			encoded, err := EncodePacket(p.Address(), p.Type(), p.PayloadMap())
+			if err != nil {
+				t.Fatalf("EncodePacket failed: %v", err)
+			}
+			decoded, err := DecodePacket(encoded)
+			if err != nil {
+				t.Fatalf("DecodePacket failed: %v", err)
+			}
+
+			appliance, ok := GetMapUint(decoded.PayloadMap(), 0)
+			if !ok {
+				t.Fatal("decoded payload missing appliance address (key 0)")
+			}
+			if appliance != 0x1234567890ABCDEF {
+				t.Errorf("decoded appliance = 0x%X, want 0x1234567890ABCDEF", appliance)
+			}
+		})
+	}
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
@@ -4,6 +4,7 @@
 package fusain
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 )
@@ -17,32 +18,48 @@ func DecodePacket(data []byte) (*Packet, error) {
 		return nil, fmt.Errorf("empty packet data")
 	}
 
-	decoder := NewDecoder()
-	var packet *Packet
-	var lastErr error
-
-	for _, b := range data {
-		p, err := decoder.DecodeByte(b)
-		if err != nil {
-			lastErr = err
-		}
-		if p != nil {
-			packet = p
-		}
-	}
-
-	if packet == nil {
-		if lastErr != nil {
-			return nil, lastErr
+	packets, errs := NewDecoder().Decode(data)
+	if len(packets) == 0 {
+		if len(errs) > 0 {
+			return nil, errs[len(errs)-1]
 		}
 		return nil, fmt.Errorf("incomplete packet data")
 	}
 
-	return packet, nil
+	return packets[len(packets)-1], nil
+}
+
+// CRCError is returned by the decoder for a complete frame whose CRC does
+// not match. The address and payload are as received and may themselves be
+// corrupt, but usually identify the sender.
+type CRCError struct {
+	Address  uint64
+	Payload  []byte // CBOR payload as received
+	Expected uint16 // CRC calculated over the received bytes
+	Got      uint16 // CRC carried in the frame
+}
+
+// Error implements the error interface
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("CRC mismatch: expected 0x%04X, got 0x%04X", e.Expected, e.Got)
+}
+
+// MessageType returns the message type from the received payload, if its
+// header is intact
+func (e *CRCError) MessageType() (uint8, bool) {
+	if len(e.Payload) < 3 || e.Payload[0] != 0x82 || e.Payload[1] != 0x18 {
+		return 0, false
+	}
+	return e.Payload[2], true
 }
 
 // Decoder implements the Fusain protocol packet decoder state machine
 type Decoder struct {
+	// RetainRaw attaches a copy of each completed frame's wire bytes to the
+	// packet (see Packet.RawBytes), for captures and inspectors that keep
+	// frames. Off by default: it costs an allocation per packet.
+	RetainRaw bool
+
 	state        int
 	buffer       []byte
 	bufferIndex  int
@@ -71,11 +88,87 @@ func (d *Decoder) Reset() {
 	d.rawBuffer = d.rawBuffer[:0]
 }
 
-// GetRawBytes returns the accumulated raw bytes since the last packet
+// GetRawBytes returns the raw bytes of the frame in progress, from its
+// START byte. The slice is the decoder's internal buffer: it is only valid
+// until the next call to DecodeByte, and it is empty once a frame has
+// completed. Use CopyRawBytes to keep the bytes, or RetainRaw to get them
+// with each packet.
 func (d *Decoder) GetRawBytes() []byte {
 	return d.rawBuffer
 }
 
+// CopyRawBytes returns a copy of the raw bytes of the frame in progress
+// that remains valid after further decoding
+func (d *Decoder) CopyRawBytes() []byte {
+	return bytes.Clone(d.rawBuffer)
+}
+
+// StateName returns the name of the decoder's current state (IDLE, LENGTH,
+// ADDRESS, PAYLOAD, CRC1 or CRC2), for tracing
+func (d *Decoder) StateName() string {
+	switch d.state {
+	case stateIdle:
+		return "IDLE"
+	case stateLength:
+		return "LENGTH"
+	case stateAddress:
+		return "ADDRESS"
+	case statePayload:
+		return "PAYLOAD"
+	case stateCRC1:
+		return "CRC1"
+	case stateCRC2:
+		return "CRC2"
+	default:
+		return fmt.Sprintf("STATE_%d", d.state)
+	}
+}
+
+// Decode processes every byte of buf and returns the packets completed
+// and the errors encountered, each in stream order. buf may hold several
+// packets and partial frames; a frame left incomplete at the end of buf
+// is kept and completed by the next call.
+func (d *Decoder) Decode(buf []byte) ([]*Packet, []error) {
+	var packets []*Packet
+	var errs []error
+	for _, b := range buf {
+		packet, err := d.DecodeByte(b)
+		if err != nil {
+			errs = append(errs, err)
+		} else if packet != nil {
+			packets = append(packets, packet)
+		}
+	}
+	return packets, errs
+}
+
+// VerifyDecode feeds data through a new decoder and checks the invariants
+// the fuzz tests rely on: decoding, validating and formatting never panic,
+// and every decoded packet re-frames to an identical packet. It returns the
+// first violation, or nil. Decode errors are expected and not violations.
+func VerifyDecode(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	packets, _ := NewDecoder().Decode(data)
+	for i, p := range packets {
+		ValidatePacket(p)
+		FormatPacket(p)
+
+		again, decodeErr := DecodePacket(encodeFrame(p.Address(), p.PayloadRaw()))
+		if decodeErr != nil {
+			return fmt.Errorf("packet %d does not decode after re-framing: %v", i, decodeErr)
+		}
+		if again.Address() != p.Address() || again.CRC() != p.CRC() || !bytes.Equal(again.PayloadRaw(), p.PayloadRaw()) {
+			return fmt.Errorf("packet %d changed when re-framed", i)
+		}
+	}
+	return nil
+}
+
 // DecodeByte processes a single byte through the decoder state machine
 // Returns a completed packet, or nil if the packet is incomplete
 // Returns an error if decoding fails
@@ -110,18 +203,27 @@ func (d *Decoder) DecodeByte(b byte) (*Packet, error) {
 			calculatedCRC := CalculateCRC(d.buffer[:d.bufferIndex])
 
 			if packet.crc != calculatedCRC {
-				err := fmt.Errorf("CRC mismatch: expected 0x%04X, got 0x%04X", calculatedCRC, packet.crc)
+				err := &CRCError{
+					Address:  packet.address,
+					Payload:  packet.cborPayload,
+					Expected: calculatedCRC,
+					Got:      packet.crc,
+				}
 				d.Reset()
 				return nil, err
 			}
 
 			packet.timestamp = time.Now()
+			if d.RetainRaw {
+				packet.raw = bytes.Clone(d.rawBuffer)
+			}
 
 			d.Reset()
 			return packet, nil
 		}
+		state := d.StateName()
 		d.Reset()
-		return nil, fmt.Errorf("unexpected END byte in state %d", d.state)
+		return nil, fmt.Errorf("unexpected END byte in state %s", state)
 	}
 
 	// State machine
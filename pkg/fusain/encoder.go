@@ -3,6 +3,7 @@ package fusain
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 
 	"github.com/fxamacker/cbor/v2"
 )
@@ -20,6 +21,11 @@ func EncodePacket(address uint64, msgType uint8, payloadMap map[int]interface{})
 		return nil, fmt.Errorf("CBOR payload too large: %d bytes (max %d)", len(cborPayload), MaxPayloadSize)
 	}
 
+	return encodeFrame(address, cborPayload), nil
+}
+
+// encodeFrame frames an already-encoded CBOR payload (at most MaxPayloadSize bytes)
+func encodeFrame(address uint64, cborPayload []byte) []byte {
 	// Build the data section: length + address + CBOR payload
 	// This is what gets CRC'd and byte-stuffed
 	dataLen := 1 + AddressSize + len(cborPayload) // length byte + 8 address bytes + payload
@@ -44,7 +50,7 @@ func EncodePacket(address uint64, msgType uint8, payloadMap map[int]interface{})
 	packet = append(packet, stuffed...)
 	packet = append(packet, EndByte)
 
-	return packet, nil
+	return packet
 }
 
 // MustEncodePacket encodes an existing Packet struct back to wire format.
@@ -62,6 +68,104 @@ func MustEncodePacket(p *Packet) []byte {
 	return data
 }
 
+// Encoder writes wire-formatted packets to an io.Writer and counts what it
+// sends. An unbuffered encoder writes each packet immediately; a buffered
+// encoder collects packets until Flush or until the next packet would not
+// fit, so a burst of commands goes out in one write.
+//
+// An Encoder is not safe for concurrent use.
+type Encoder struct {
+	w    io.Writer
+	size int    // Buffer size in bytes (0 = unbuffered)
+	buf  []byte // Encoded packets not yet written
+
+	bufPackets  uint64 // Packets in buf
+	packetsSent uint64
+	bytesSent   uint64
+}
+
+// NewEncoder creates an encoder that writes each packet immediately
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// NewBufferedEncoder creates an encoder that buffers up to size bytes.
+// Packets larger than the buffer are written directly.
+func NewBufferedEncoder(w io.Writer, size int) *Encoder {
+	return &Encoder{w: w, size: size, buf: make([]byte, 0, size)}
+}
+
+// WritePacket encodes p and writes or buffers it. Packets that carry CBOR
+// bytes (decoded, or unmarshaled from JSON with a raw payload) are framed
+// from those bytes unchanged, so they are sent exactly as given.
+func (e *Encoder) WritePacket(p *Packet) error {
+	var data []byte
+	if raw := p.Payload(); raw != nil {
+		if len(raw) > MaxPayloadSize {
+			return fmt.Errorf("CBOR payload too large: %d bytes (max %d)", len(raw), MaxPayloadSize)
+		}
+		data = encodeFrame(p.Address(), raw)
+	} else {
+		var err error
+		data, err = EncodePacket(p.Address(), p.Type(), p.PayloadMap())
+		if err != nil {
+			return err
+		}
+	}
+
+	if e.size <= 0 {
+		return e.write(data, 1)
+	}
+
+	if len(e.buf)+len(data) > e.size {
+		if err := e.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(data) > e.size {
+		return e.write(data, 1)
+	}
+	e.buf = append(e.buf, data...)
+	e.bufPackets++
+	return nil
+}
+
+// Flush writes any buffered packets
+func (e *Encoder) Flush() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	err := e.write(e.buf, e.bufPackets)
+	e.buf = e.buf[:0]
+	e.bufPackets = 0
+	return err
+}
+
+// Buffered returns the number of bytes waiting for Flush
+func (e *Encoder) Buffered() int {
+	return len(e.buf)
+}
+
+// PacketsSent returns the number of packets written to the underlying writer
+func (e *Encoder) PacketsSent() uint64 {
+	return e.packetsSent
+}
+
+// BytesSent returns the number of bytes written to the underlying writer
+func (e *Encoder) BytesSent() uint64 {
+	return e.bytesSent
+}
+
+func (e *Encoder) write(data []byte, packets uint64) error {
+	n, err := e.w.Write(data)
+	e.bytesSent += uint64(n)
+	if err != nil {
+		return err
+	}
+	e.packetsSent += packets
+	return nil
+}
+
 // encodeCBORPayload creates the CBOR-encoded payload for a message.
 // Uses explicit 2-byte encoding for message type (0x18 prefix) to ensure
 // consistent wire format across implementations.
@@ -69,7 +173,8 @@ func encodeCBORPayload(msgType uint8, payloadMap map[int]interface{}) ([]byte, e
 	// Manually build CBOR to ensure consistent encoding:
 	// - Array of 2 elements: 0x82
 	// - Message type with 1-byte uint encoding: 0x18 <msgType>
-	// - Payload map (or null): marshaled by cbor library
+	// - Payload map (or null): marshaled by cbor library with sorted keys,
+	//   so the same payload always produces the same bytes and CRC
 
 	var result []byte
 
@@ -86,7 +191,7 @@ func encodeCBORPayload(msgType uint8, payloadMap map[int]interface{}) ([]byte, e
 	if payloadMap == nil || len(payloadMap) == 0 {
 		payloadBytes, err = cbor.Marshal(nil)
 	} else {
-		payloadBytes, err = cbor.Marshal(payloadMap)
+		payloadBytes, err = cborSortedEncMode.Marshal(payloadMap)
 	}
 	if err != nil {
 		return nil, err
@@ -96,6 +201,16 @@ func encodeCBORPayload(msgType uint8, payloadMap map[int]interface{}) ([]byte, e
 	return result, nil
 }
 
+// UnstuffFrame strips the START and END bytes from a wire frame and removes
+// its byte stuffing, returning the length byte, address, CBOR payload and
+// CRC as covered by the checksum
+func UnstuffFrame(frame []byte) ([]byte, error) {
+	if len(frame) < 2 || frame[0] != StartByte || frame[len(frame)-1] != EndByte {
+		return nil, fmt.Errorf("not a framed packet")
+	}
+	return unstuffBytes(frame[1 : len(frame)-1])
+}
+
 // stuffBytes applies byte stuffing to escape special bytes.
 // Special bytes (START, END, ESC) are replaced with ESC + (byte XOR EscXor).
 func stuffBytes(data []byte) []byte {
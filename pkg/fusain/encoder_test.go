@@ -360,6 +360,66 @@ func TestMustEncodePacket(t *testing.T) {
 	}
 }
 
+func TestEncoder_Unbuffered(t *testing.T) {
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+
+	p := NewPingRequest(0x01)
+	if err := enc.WritePacket(p); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), MustEncodePacket(p)) {
+		t.Errorf("wrote %x, want %x", out.Bytes(), MustEncodePacket(p))
+	}
+	if enc.PacketsSent() != 1 || enc.BytesSent() != uint64(out.Len()) {
+		t.Errorf("stats = %d packets, %d bytes", enc.PacketsSent(), enc.BytesSent())
+	}
+}
+
+func TestEncoder_Buffered(t *testing.T) {
+	var out bytes.Buffer
+	frame := MustEncodePacket(NewPingRequest(0x01))
+	enc := NewBufferedEncoder(&out, 2*len(frame)+1)
+
+	for i := 0; i < 2; i++ {
+		if err := enc.WritePacket(NewPingRequest(0x01)); err != nil {
+			t.Fatalf("WritePacket failed: %v", err)
+		}
+	}
+	if out.Len() != 0 || enc.Buffered() != 2*len(frame) || enc.PacketsSent() != 0 {
+		t.Fatalf("wrote %d bytes before the buffer filled", out.Len())
+	}
+
+	// The third packet does not fit, so the first two are flushed
+	enc.WritePacket(NewPingRequest(0x01))
+	if out.Len() != 2*len(frame) || enc.PacketsSent() != 2 {
+		t.Errorf("after overflow: %d bytes written, %d packets sent", out.Len(), enc.PacketsSent())
+	}
+
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if enc.Buffered() != 0 || enc.PacketsSent() != 3 || enc.BytesSent() != uint64(3*len(frame)) {
+		t.Errorf("after Flush: buffered %d, %d packets, %d bytes", enc.Buffered(), enc.PacketsSent(), enc.BytesSent())
+	}
+}
+
+func TestEncoder_EncodeError(t *testing.T) {
+	largePayload := make(map[int]interface{})
+	for i := 0; i < 200; i++ {
+		largePayload[i] = uint64(i)
+	}
+
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+	if err := enc.WritePacket(NewPacketWithPayload(0, MsgStateData, largePayload)); err == nil {
+		t.Error("expected error for oversized payload")
+	}
+	if out.Len() != 0 || enc.PacketsSent() != 0 {
+		t.Error("oversized packet was written")
+	}
+}
+
 func TestMustEncodePacket_Panic(t *testing.T) {
 	// Verify that MustEncodePacket panics on oversized payload as documented
 	defer func() {
@@ -507,3 +567,21 @@ func TestEncodePacket_CBOREncodingError(t *testing.T) {
 		t.Error("expected error for unencodable CBOR payload (channel), got nil")
 	}
 }
+
+func TestEncoder_WritesCarriedPayloadUnchanged(t *testing.T) {
+	// STATE_DATA with its map keys out of canonical order
+	raw := []byte{0x82, 0x18, MsgStateData, 0xA2, 0x02, 0x01, 0x00, 0xF4}
+	p := NewPacket(uint8(len(raw)), 0x01, raw, 0)
+
+	var out bytes.Buffer
+	if err := NewEncoder(&out).WritePacket(p); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	sent, err := DecodePacket(out.Bytes())
+	if err != nil {
+		t.Fatalf("DecodePacket failed: %v", err)
+	}
+	if !bytes.Equal(sent.PayloadRaw(), raw) {
+		t.Errorf("payload sent as % X, want % X", sent.PayloadRaw(), raw)
+	}
+}
@@ -5,20 +5,62 @@ package fusain
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strings"
-	"unsafe"
 )
 
+// FormatOptions controls how packets and payloads are rendered
+type FormatOptions struct {
+	Units UnitSystem // Unit system for temperatures (default metric)
+
+	// CBORDiagnostic renders payloads without a dedicated formatter (unknown
+	// message types, undecodable payloads) in CBOR diagnostic notation
+	CBORDiagnostic bool
+
+	// Clock, when set, adds the estimated wall-clock time of the packet's
+	// device timestamp to the header line (e.g. "device=15:04:05.123")
+	Clock *ClockEstimator
+
+	// DeviceName, when set, names the packet's device on the header line
+	// (e.g. name="garage heater"); "" leaves it out
+	DeviceName func(address uint64) string
+}
+
 // FormatPacket formats a packet into a human-readable string
 func FormatPacket(p *Packet) string {
+	return FormatPacketWithOptions(p, FormatOptions{})
+}
+
+// FormatPacketWithOptions formats a packet into a human-readable string
+// using the given display options
+func FormatPacketWithOptions(p *Packet, opts FormatOptions) string {
 	timestamp := p.timestamp.Format("15:04:05.000")
 	msgType := FormatMessageType(p.Type())
 
-	result := fmt.Sprintf("[%s] %s (0x%02X) addr=%016X len=%d\n", timestamp, msgType, p.Type(), p.address, p.length)
+	result := fmt.Sprintf("[%s] %s (0x%02X) addr=%016X len=%d", timestamp, msgType, p.Type(), p.address, p.length)
+	if opts.DeviceName != nil {
+		if name := opts.DeviceName(p.address); name != "" {
+			result += fmt.Sprintf(" name=%q", name)
+		}
+	}
+	if opts.Clock != nil {
+		if deviceMs, ok := DeviceTime(p); ok {
+			if at, ok := opts.Clock.WallClock(p.address, deviceMs); ok {
+				result += " device=" + at.Format("15:04:05.000")
+			}
+		}
+	}
+	result += "\n"
+
+	// Show the raw CBOR for payloads we cannot interpret
+	if opts.CBORDiagnostic && (p.ParseError() != nil || FormatMessageType(p.Type()) == "UNKNOWN") {
+		return result + "  CBOR: " + FormatCBORDiagnostic(p.PayloadRaw()) + "\n"
+	}
 
 	payloadMap := p.PayloadMap()
 	if payloadMap != nil || p.Type() == MsgPingRequest || p.Type() == MsgDiscoveryRequest {
-		result += FormatPayloadMap(p.Type(), payloadMap)
+		result += FormatPayloadMapWithOptions(p.Type(), payloadMap, opts)
 	}
 
 	return result
@@ -90,16 +132,38 @@ func FormatMessageType(msgType uint8) string {
 	}
 }
 
+// ParseMessageType returns the message type for a name as printed by
+// FormatMessageType (case-insensitive, e.g. "state_data")
+func ParseMessageType(name string) (uint8, bool) {
+	name = strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	for t := 0; t <= 0xFF; t++ {
+		if formatted := FormatMessageType(uint8(t)); formatted != "UNKNOWN" && formatted == name {
+			return uint8(t), true
+		}
+	}
+	return 0, false
+}
+
 // FormatPayloadMap formats the CBOR payload map based on message type
 func FormatPayloadMap(msgType uint8, m map[int]interface{}) string {
+	return FormatPayloadMapWithOptions(msgType, m, FormatOptions{})
+}
+
+// FormatPayloadMapWithOptions formats the CBOR payload map based on message
+// type using the given display options
+func FormatPayloadMapWithOptions(msgType uint8, m map[int]interface{}, opts FormatOptions) string {
 	switch msgType {
 	case MsgPingRequest, MsgDiscoveryRequest:
 		return "  (no payload)\n"
 
 	case MsgPingResponse:
-		// 0 => uptime-ms
+		// 0 => uptime-ms, identity fields (see DeviceInfo) after it
 		uptime, _ := GetMapUint(m, 0)
-		return fmt.Sprintf("  Uptime: %s\n", formatDuration(uptime))
+		result := fmt.Sprintf("  Uptime: %s", formatDuration(uptime))
+		for _, f := range deviceInfo(m) {
+			result += fmt.Sprintf(", %s: %s", f.Name, f.FormatValue())
+		}
+		return result + "\n"
 
 	case MsgStateCommand:
 		// 0 => mode, 1 => argument (optional)
@@ -117,13 +181,13 @@ func FormatPayloadMap(msgType uint8, m map[int]interface{}) string {
 		code, _ := GetMapInt(m, 1)
 		state, _ := GetMapUint(m, 2)
 		timestamp, _ := GetMapUint(m, 3)
-		stateName := formatState(uint32(state))
+		stateName := FormatState(uint32(state))
 		errorStr := "No"
 		if errorFlag {
 			errorStr = "Yes"
 		}
 		return fmt.Sprintf("  State: %s (%d), Error: %s, Code: %s (%d), Time: %d ms\n",
-			stateName, state, errorStr, formatErrorCode(int32(code)), code, timestamp)
+			stateName, state, errorStr, FormatErrorCode(int32(code)), code, timestamp)
 
 	case MsgMotorCommand:
 		// 0 => motor, 1 => rpm
@@ -155,7 +219,7 @@ func FormatPayloadMap(msgType uint8, m map[int]interface{}) string {
 			result += fmt.Sprintf(", Motor: %d", motorIdx)
 		}
 		if hasTarget {
-			result += fmt.Sprintf(", Target: %.1f°C", target)
+			result += fmt.Sprintf(", Target: %s", FormatTemperature(target, opts.Units))
 		}
 		return result + "\n"
 
@@ -251,9 +315,9 @@ func FormatPayloadMap(msgType uint8, m map[int]interface{}) string {
 		watchedMotor, hasMotor := GetMapInt(m, 4)
 		targetTemp, hasTarget := GetMapFloat(m, 5)
 
-		result := fmt.Sprintf("  Thermometer %d: %.1f°C", therm, temp)
+		result := fmt.Sprintf("  Thermometer %d: %s", therm, FormatTemperature(temp, opts.Units))
 		if hasTarget {
-			result += fmt.Sprintf(" (target=%.1f°C)", targetTemp)
+			result += fmt.Sprintf(" (target=%s)", FormatTemperature(targetTemp, opts.Units))
 		}
 		if hasRpmCtrl {
 			rpmStr := "Off"
@@ -292,7 +356,7 @@ func FormatPayloadMap(msgType uint8, m map[int]interface{}) string {
 	case MsgErrorStateReject:
 		// 0 => error-code (state that rejected)
 		state, _ := GetMapUint(m, 0)
-		stateName := formatState(uint32(state))
+		stateName := FormatState(uint32(state))
 		return fmt.Sprintf("  Rejected by state: %s (%d)\n", stateName, state)
 
 	case MsgDataSubscription, MsgDataUnsubscribe:
@@ -359,15 +423,25 @@ func FormatPayloadMap(msgType uint8, m map[int]interface{}) string {
 	if m == nil {
 		return "  (nil payload)\n"
 	}
+	if opts.CBORDiagnostic {
+		if data, err := cborSortedEncMode.Marshal(m); err == nil {
+			return "  CBOR: " + FormatCBORDiagnostic(data) + "\n"
+		}
+	}
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
 	result := "  Payload: {"
-	for k, v := range m {
-		result += fmt.Sprintf("%d: %v, ", k, v)
+	for _, k := range keys {
+		result += fmt.Sprintf("%d: %v, ", k, m[k])
 	}
 	return result + "}\n"
 }
 
-// formatState returns a human-readable state name
-func formatState(state uint32) string {
+// FormatState returns the human-readable name for a system state
+func FormatState(state uint32) string {
 	names := []string{"INITIALIZING", "IDLE", "BLOWING", "PREHEAT", "PREHEAT_STAGE_2", "HEATING", "COOLING", "ERROR", "E_STOP"}
 	if int(state) < len(names) {
 		return names[state]
@@ -391,8 +465,8 @@ func formatMode(mode uint32) string {
 	}
 }
 
-// formatErrorCode returns a human-readable error code name
-func formatErrorCode(code int32) string {
+// FormatErrorCode returns the human-readable name for an error code
+func FormatErrorCode(code int32) string {
 	names := []string{"NONE", "OVERHEAT", "SENSOR_FAULT", "IGNITION_FAIL", "FLAME_OUT", "MOTOR_STALL", "PUMP_FAULT", "COMMANDED_ESTOP"}
 	if int(code) < len(names) && code >= 0 {
 		return names[code]
@@ -538,6 +612,8 @@ func formatDuration(ms uint64) string {
 }
 
 // Float64frombits converts a uint64 to float64
+//
+// Deprecated: Use math.Float64frombits.
 func Float64frombits(b uint64) float64 {
-	return *(*float64)(unsafe.Pointer(&b))
+	return math.Float64frombits(b)
 }
@@ -4,9 +4,12 @@
 package fusain
 
 import (
+	"bytes"
+	"errors"
 	"strings"
+	"sync"
 	"testing"
-	"unsafe"
+	"time"
 
 	"github.com/fxamacker/cbor/v2"
 )
@@ -132,6 +135,48 @@ func TestParseCBORMessage_StateData(t *testing.T) {
 	}
 }
 
+func TestParseCBORMessage_Limits(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		limit string
+	}{
+		{"oversized payload", make([]byte, MaxPayloadSize+1), "payload size"},
+		// [0x30, {0: [[[[1]]]]}]
+		{"nesting depth", []byte{0x82, 0x18, 0x30, 0xA1, 0x00, 0x81, 0x81, 0x81, 0x81, 0x01}, "nesting depth"},
+		// [0x30, {map header declaring 1000 pairs}]
+		{"map pairs", []byte{0x82, 0x18, 0x30, 0xB9, 0x03, 0xE8}, "map pairs"},
+		// [array header declaring 1000 elements]
+		{"array elements", []byte{0x99, 0x03, 0xE8}, "array elements"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseCBORMessage(tt.data)
+			var limitErr *CBORLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Expected CBORLimitError, got %v", err)
+			}
+			if limitErr.Limit != tt.limit {
+				t.Errorf("Expected limit %q, got %q", tt.limit, limitErr.Limit)
+			}
+		})
+	}
+}
+
+func TestValidatePacket_CBORLimitDetails(t *testing.T) {
+	data := []byte{0x82, 0x18, 0x30, 0xB9, 0x03, 0xE8}
+	p := NewPacket(uint8(len(data)), 0x123456789ABCDEF0, data, 0)
+
+	errs := ValidatePacket(p)
+	if len(errs) != 1 || errs[0].Type != AnomalyDecodeError {
+		t.Fatalf("Expected one AnomalyDecodeError, got %v", errs)
+	}
+	if errs[0].Details["limit"] != "map pairs" {
+		t.Errorf("Expected limit detail \"map pairs\", got %v", errs[0].Details["limit"])
+	}
+}
+
 func TestGetMapHelpers(t *testing.T) {
 	m := map[int]interface{}{
 		0: uint64(42),
@@ -234,6 +279,28 @@ func TestPacket_IsStateless(t *testing.T) {
 	}
 }
 
+func TestPacket_IsEmergency(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet *Packet
+		want   bool
+	}{
+		{"emergency command", NewStateCommand(AddressBroadcast, uint8(ModeEmergency), nil), true},
+		{"idle command", NewStateCommand(AddressBroadcast, uint8(ModeIdle), nil), false},
+		{"E_STOP state", StateData{State: SysStateEstop}.Encode(1), true},
+		{"heating state", StateData{State: SysStateHeating}.Encode(1), false},
+		{"ping", NewPingRequest(1), false},
+	}
+	for _, tt := range tests {
+		// Decoded packets must classify the same as constructed ones
+		for _, p := range []*Packet{tt.packet, roundTrip(t, tt.packet)} {
+			if got := p.IsEmergency(); got != tt.want {
+				t.Errorf("%s: IsEmergency() = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
 func TestPacket_Timestamp(t *testing.T) {
 	cborPayload := buildCBOREmptyPayload(MsgPingRequest)
 	p := NewPacket(uint8(len(cborPayload)), 0x123456789ABCDEF0, cborPayload, 0)
@@ -261,6 +328,46 @@ func TestPacket_PayloadMap(t *testing.T) {
 	}
 }
 
+func TestPacket_PayloadRaw(t *testing.T) {
+	cborPayload := buildCBORPayload(MsgPingResponse, map[int]interface{}{0: uint64(1000)})
+	p := NewPacket(uint8(len(cborPayload)), 0x123456789ABCDEF0, cborPayload, 0)
+
+	raw := p.PayloadRaw()
+	if len(raw) == 0 || &raw[0] != &cborPayload[0] {
+		t.Error("PayloadRaw should return the received bytes without copying")
+	}
+
+	built := NewPingRequest(0x123456789ABCDEF0)
+	if built.Payload() != nil {
+		t.Error("Payload should be nil for packets built from a payload map")
+	}
+	msgType, _, err := ParseCBORMessage(built.PayloadRaw())
+	if err != nil {
+		t.Fatalf("PayloadRaw of built packet should decode: %v", err)
+	}
+	if msgType != MsgPingRequest {
+		t.Errorf("Expected type 0x%02X, got 0x%02X", MsgPingRequest, msgType)
+	}
+}
+
+func TestPacket_ConcurrentPayloadMap(t *testing.T) {
+	cborPayload := buildCBORPayload(MsgPingResponse, map[int]interface{}{0: uint64(1000)})
+	p := NewPacket(uint8(len(cborPayload)), 0x123456789ABCDEF0, cborPayload, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uptime, ok := GetMapUint(p.PayloadMap(), 0)
+			if !ok || uptime != 1000 || p.Type() != MsgPingResponse {
+				t.Errorf("Expected uptime=1000, got %d", uptime)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // ============================================================
 // Decoder Tests
 // ============================================================
@@ -297,6 +404,74 @@ func TestDecoder_GetRawBytes(t *testing.T) {
 	}
 }
 
+func TestDecoder_CopyRawBytes(t *testing.T) {
+	d := NewDecoder()
+	d.DecodeByte(StartByte)
+	d.DecodeByte(0x04)
+
+	copied := d.CopyRawBytes()
+	d.DecodeByte(StartByte) // Resync reuses the internal buffer
+	d.DecodeByte(0x09)
+
+	if !bytes.Equal(copied, []byte{StartByte, 0x04}) {
+		t.Errorf("CopyRawBytes = % X after further decoding, want % X", copied, []byte{StartByte, 0x04})
+	}
+}
+
+func TestDecoder_RetainRaw(t *testing.T) {
+	// Address bytes that need stuffing
+	frame := MustEncodePacket(NewPingRequest(0x7E7D))
+
+	d := NewDecoder()
+	packets, _ := d.Decode(frame)
+	if len(packets) != 1 || packets[0].RawBytes() != nil {
+		t.Fatalf("without RetainRaw: RawBytes should be nil")
+	}
+
+	d.RetainRaw = true
+	first, _ := d.Decode(frame)
+	second, _ := d.Decode(MustEncodePacket(NewPingRequest(0x01)))
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("decoded %d and %d packets, want 1 and 1", len(first), len(second))
+	}
+	if !bytes.Equal(first[0].RawBytes(), frame) {
+		t.Errorf("RawBytes = % X, want % X", first[0].RawBytes(), frame)
+	}
+}
+
+func TestPacket_WireFrame(t *testing.T) {
+	// Address bytes that need stuffing
+	frame := MustEncodePacket(NewPingRequest(0x7E7D))
+
+	d := NewDecoder()
+	packets, _ := d.Decode(frame)
+	if len(packets) != 1 {
+		t.Fatalf("decoded %d packets, want 1", len(packets))
+	}
+	p := packets[0]
+	if !bytes.Equal(p.WireFrame(), frame) {
+		t.Errorf("WireFrame = % X, want % X", p.WireFrame(), frame)
+	}
+
+	data, err := UnstuffFrame(p.WireFrame())
+	if err != nil {
+		t.Fatalf("UnstuffFrame: %v", err)
+	}
+	if want := 1 + AddressSize + int(p.Length()) + 2; len(data) != want {
+		t.Fatalf("unstuffed %d bytes, want %d", len(data), want)
+	}
+	if data[0] != p.Length() || !bytes.Equal(data[1+AddressSize:len(data)-2], p.Payload()) {
+		t.Errorf("unstuffed frame % X doesn't hold the length and payload", data)
+	}
+	if crc := uint16(data[len(data)-2])<<8 | uint16(data[len(data)-1]); crc != p.CRC() {
+		t.Errorf("unstuffed CRC = 0x%04X, want 0x%04X", crc, p.CRC())
+	}
+
+	if _, err := UnstuffFrame(frame[1:]); err == nil {
+		t.Error("UnstuffFrame should reject a frame without START")
+	}
+}
+
 func TestDecoder_SimplePacket(t *testing.T) {
 	d := NewDecoder()
 
@@ -547,6 +722,39 @@ func TestDecoder_StartByteResetsState(t *testing.T) {
 	}
 }
 
+func TestDecoder_Decode(t *testing.T) {
+	first := MustEncodePacket(NewPingRequest(0x01))
+	second := MustEncodePacket(TempData{Reading: 20.0}.Encode(0x02))
+
+	corrupt := append([]byte(nil), first...)
+	corrupt[len(corrupt)-2] ^= 0x01 // Flip a CRC bit
+
+	// Leading noise, a corrupt frame, a good frame and half of another
+	stream := []byte{0x00, 0x13}
+	stream = append(stream, corrupt...)
+	stream = append(stream, first...)
+	stream = append(stream, second[:len(second)/2]...)
+
+	d := NewDecoder()
+	packets, errs := d.Decode(stream)
+	if len(packets) != 1 || packets[0].Address() != 0x01 {
+		t.Fatalf("Decode returned %d packets, want the ping from 0x01", len(packets))
+	}
+	if len(errs) != 1 {
+		t.Errorf("Decode returned errors %v, want one CRC mismatch", errs)
+	}
+
+	// The partial frame completes on the next call
+	packets, errs = d.Decode(second[len(second)/2:])
+	if len(packets) != 1 || packets[0].Type() != MsgTempData || len(errs) != 0 {
+		t.Fatalf("Decode of remainder = %d packets, errors %v", len(packets), errs)
+	}
+
+	if packets, errs := d.Decode(nil); packets != nil || errs != nil {
+		t.Errorf("Decode(nil) = %v, %v", packets, errs)
+	}
+}
+
 // ============================================================
 // Validation Tests
 // ============================================================
@@ -714,6 +922,59 @@ func TestValidatePacket_GlowCommand_InvalidDuration(t *testing.T) {
 	}
 }
 
+func TestValidatePacket_Commands(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   *Packet
+		wantType AnomalyType
+		wantErr  bool
+	}{
+		{"idle", NewStateCommand(0x1, uint8(ModeIdle), nil), 0, false},
+		{"fan valid rpm", NewStateCommand(0x1, uint8(ModeFan), ptr(int64(2500))), 0, false},
+		{"fan rpm too high", NewStateCommand(0x1, uint8(ModeFan), ptr(int64(7000))), AnomalyHighRPM, true},
+		{"fan negative rpm", NewStateCommand(0x1, uint8(ModeFan), ptr(int64(-100))), AnomalyInvalidValue, true},
+		{"heat negative rate", NewStateCommand(0x1, uint8(ModeHeat), ptr(int64(-1))), AnomalyInvalidValue, true},
+		{"unknown mode", NewStateCommand(0x1, 0x42, nil), AnomalyInvalidValue, true},
+		{"motor valid rpm", NewMotorCommand(0x1, 0, 3000), 0, false},
+		{"motor rpm too high", NewMotorCommand(0x1, 0, 6001), AnomalyHighRPM, true},
+		{"motor negative rpm", NewMotorCommand(0x1, 0, -5), AnomalyInvalidValue, true},
+		{"motor stop", NewMotorCommand(0x1, 0, 0), 0, false},
+		{"pump valid rate", NewPumpCommand(0x1, 0, 250), 0, false},
+		{"pump negative rate", NewPumpCommand(0x1, 0, -1), AnomalyInvalidValue, true},
+		{"target temp valid", NewTargetTempCommand(0x1, 0, 180), 0, false},
+		{"target temp too high", NewTargetTempCommand(0x1, 0, 1200), AnomalyInvalidTemp, true},
+		{"target temp missing", NewPacketWithPayload(0x1, MsgTempCommand, map[int]interface{}{0: uint64(0), 1: uint64(TempCmdSetTargetTemp)}), AnomalyInvalidValue, true},
+		{"motor config valid", NewPacketWithPayload(0x1, MsgMotorConfig, map[int]interface{}{0: uint64(0), 2: 0.5, 5: int64(5000), 6: int64(800)}), 0, false},
+		{"motor config negative gain", NewPacketWithPayload(0x1, MsgMotorConfig, map[int]interface{}{0: uint64(0), 3: -0.1}), AnomalyInvalidValue, true},
+		{"motor config max rpm too high", NewPacketWithPayload(0x1, MsgMotorConfig, map[int]interface{}{0: uint64(0), 5: int64(9000)}), AnomalyHighRPM, true},
+		{"motor config min above max", NewPacketWithPayload(0x1, MsgMotorConfig, map[int]interface{}{0: uint64(0), 5: int64(3000), 6: int64(4000)}), AnomalyInvalidValue, true},
+		{"pump config zero pulse", NewPacketWithPayload(0x1, MsgPumpConfig, map[int]interface{}{0: uint64(0), 1: uint64(0)}), AnomalyInvalidValue, true},
+		{"temp config negative gain", NewPacketWithPayload(0x1, MsgTempConfig, map[int]interface{}{0: uint64(0), 1: -1.5}), AnomalyInvalidValue, true},
+		{"glow config too long", NewPacketWithPayload(0x1, MsgGlowConfig, map[int]interface{}{0: uint64(0), 1: uint64(400000)}), AnomalyInvalidValue, true},
+		{"timeout enabled zero", NewPacketWithPayload(0x1, MsgTimeoutConfig, map[int]interface{}{0: true, 1: uint64(0)}), AnomalyInvalidValue, true},
+		{"timeout disabled zero", NewPacketWithPayload(0x1, MsgTimeoutConfig, map[int]interface{}{0: false, 1: uint64(0)}), 0, false},
+		{"watch motor", NewPacketWithPayload(0x1, MsgTempCommand, map[int]interface{}{0: uint64(0), 1: uint64(TempCmdWatchMotor), 2: int64(0)}), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := ValidatePacket(tt.packet)
+			if !tt.wantErr {
+				if len(errors) != 0 {
+					t.Errorf("Expected no validation errors, got %d: %v", len(errors), errors)
+				}
+				return
+			}
+			if len(errors) != 1 {
+				t.Fatalf("Expected 1 validation error, got %d", len(errors))
+			}
+			if errors[0].Type != tt.wantType {
+				t.Errorf("Expected anomaly type %d, got %d", tt.wantType, errors[0].Type)
+			}
+		})
+	}
+}
+
 func TestValidatePacket_DeviceAnnounce_Valid(t *testing.T) {
 	payload := map[int]interface{}{
 		0: uint64(2), // motor_count
@@ -895,6 +1156,24 @@ func TestFormatPayloadMap_DeviceAnnounce(t *testing.T) {
 	}
 }
 
+func TestFormatState(t *testing.T) {
+	if got := FormatState(uint32(SysStateHeating)); got != "HEATING" {
+		t.Errorf("FormatState(HEATING) = %q, want %q", got, "HEATING")
+	}
+	if got := FormatState(255); got != "UNKNOWN" {
+		t.Errorf("FormatState(255) = %q, want %q", got, "UNKNOWN")
+	}
+}
+
+func TestFormatErrorCode(t *testing.T) {
+	if got := FormatErrorCode(int32(ErrorFlameOut)); got != "FLAME_OUT" {
+		t.Errorf("FormatErrorCode(FLAME_OUT) = %q, want %q", got, "FLAME_OUT")
+	}
+	if got := FormatErrorCode(-1); got != "UNKNOWN" {
+		t.Errorf("FormatErrorCode(-1) = %q, want %q", got, "UNKNOWN")
+	}
+}
+
 func TestFormatPacket(t *testing.T) {
 	payload := map[int]interface{}{
 		0: false, 1: uint64(0), 2: uint64(1), 3: uint64(1000),
@@ -911,6 +1190,53 @@ func TestFormatPacket(t *testing.T) {
 	}
 }
 
+func TestFormatCBORDiagnostic(t *testing.T) {
+	data, _ := cborSortedEncMode.Marshal([]interface{}{uint64(0x50), map[int]interface{}{0: 1.5, 1: []byte{1, 2}, 2: true}})
+	want := "[80, {0: 1.5, 1: h'0102', 2: true}]"
+	if got := FormatCBORDiagnostic(data); got != want {
+		t.Errorf("FormatCBORDiagnostic = %q, want %q", got, want)
+	}
+
+	if got := FormatCBORDiagnostic([]byte{0x82, 0x18}); !strings.Contains(got, "invalid CBOR") {
+		t.Errorf("FormatCBORDiagnostic(truncated) = %q, want invalid CBOR note", got)
+	}
+}
+
+func TestFormatPacket_CBORDiagnostic(t *testing.T) {
+	// Unknown message type with a nested array the decoder would not expect
+	data, _ := cbor.Marshal([]interface{}{uint64(0x50), map[int]interface{}{0: []interface{}{1, 2}}})
+	p := NewPacket(uint8(len(data)), 1, data, 0)
+
+	result := FormatPacketWithOptions(p, FormatOptions{CBORDiagnostic: true})
+	if !strings.Contains(result, "CBOR: [80, {0: [1, 2]}]") {
+		t.Errorf("result = %q, want CBOR diagnostic notation", result)
+	}
+
+	// Map fallback when only the decoded payload is available
+	result = FormatPayloadMapWithOptions(0x50, map[int]interface{}{1: uint64(100), 0: true}, FormatOptions{CBORDiagnostic: true})
+	if result != "  CBOR: {0: true, 1: 100}\n" {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestFormatPacket_DeviceName(t *testing.T) {
+	names := func(address uint64) string {
+		if address == 1 {
+			return "garage heater"
+		}
+		return ""
+	}
+
+	header := strings.SplitN(FormatPacketWithOptions(PingResponse{Uptime: 1000}.Encode(1), FormatOptions{DeviceName: names}), "\n", 2)[0]
+	if !strings.HasSuffix(header, ` name="garage heater"`) {
+		t.Errorf("header %q does not end with the device name", header)
+	}
+	header = strings.SplitN(FormatPacketWithOptions(PingResponse{Uptime: 1000}.Encode(2), FormatOptions{DeviceName: names}), "\n", 2)[0]
+	if strings.Contains(header, "name=") {
+		t.Errorf("header %q names an unnamed device", header)
+	}
+}
+
 // ============================================================
 // Statistics Tests
 // ============================================================
@@ -992,6 +1318,153 @@ func TestStatistics_Update_ValidationErrors(t *testing.T) {
 	}
 }
 
+func TestStatistics_DetailRanges(t *testing.T) {
+	s := NewStatistics()
+	limits := DefaultValidationLimits()
+
+	for _, rpm := range []int32{7200, 6100} {
+		p := roundTrip(t, MotorData{RPM: rpm, Target: 3000}.Encode(0x01))
+		s.Update(p, nil, ValidatePacketWithOptions(p, ValidateOptions{Limits: &limits}))
+	}
+	for _, reading := range []float64{-60.5, 1200.0} {
+		p := roundTrip(t, TempData{Reading: reading}.Encode(0x01))
+		s.Update(p, nil, ValidatePacketWithOptions(p, ValidateOptions{Limits: &limits}))
+	}
+	s.Update(nil, nil, []ValidationError{{
+		Type:    AnomalyTimestamp,
+		Details: map[string]interface{}{"timestamp": uint64(1000), "previous": uint64(6000)},
+	}})
+
+	if r := s.HighRPMValues; r.Count != 2 || r.Min != 6100 || r.Max != 7200 {
+		t.Errorf("HighRPMValues = %+v, want 6100-7200 from 2 errors", r)
+	}
+	if r := s.TempValues; r.Count != 2 || r.Min != -60.5 || r.Max != 1200 {
+		t.Errorf("TempValues = %+v, want -60.5-1200", r)
+	}
+	if r := s.TimestampRewind; r.Count != 1 || r.Max != 5000 {
+		t.Errorf("TimestampRewind = %+v, want 5000", r)
+	}
+
+	result := s.String()
+	for _, want := range []string{"(6100 to 7200 RPM)", "(-60.5 to 1200.0°C)", "(worst 5000 ms back)"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("String() missing %q:\n%s", want, result)
+		}
+	}
+
+	s.Reset()
+	if s.HighRPMValues.Count != 0 || s.TempValues.Count != 0 {
+		t.Error("Reset should clear value ranges")
+	}
+}
+
+func TestStatistics_Breakdown(t *testing.T) {
+	s := NewStatistics()
+	temp := roundTrip(t, TempData{Reading: 20.0}.Encode(0x01))
+	ping := roundTrip(t, NewPingRequest(0x02))
+
+	s.Update(temp, nil, nil)
+	s.Update(temp, nil, []ValidationError{{Type: AnomalyInvalidTemp}})
+	s.Update(ping, nil, nil)
+
+	// A corrupt TEMP_DATA frame from device 0x02
+	frame := MustEncodePacket(TempData{Reading: 21.0}.Encode(0x02))
+	frame[len(frame)-2] ^= 0x01
+	_, errs := NewDecoder().Decode(frame)
+	if len(errs) != 1 {
+		t.Fatalf("expected one CRC error, got %v", errs)
+	}
+	s.Update(nil, errs[0], nil)
+	if s.CRCErrors != 1 {
+		t.Fatalf("CRCErrors = %d, want 1", s.CRCErrors)
+	}
+
+	if got := s.MessageTypes(); len(got) != 2 || got[0] != MsgPingRequest || got[1] != MsgTempData {
+		t.Errorf("MessageTypes() = %v", got)
+	}
+	if got := s.TypeStats(MsgTempData); got.Packets != 3 || got.Errors != 2 {
+		t.Errorf("TEMP_DATA stats = %+v, want 3 packets, 2 errors", got)
+	}
+	if got := s.DeviceStats(0x01); got.Packets != 2 || got.Errors != 1 || got.Bytes != 2*uint64(int(temp.Length())+frameOverhead) {
+		t.Errorf("device 0x01 stats = %+v", got)
+	}
+	if got := s.DeviceStats(0x02); got.Packets != 2 || got.Errors != 1 {
+		t.Errorf("device 0x02 stats = %+v, want 2 packets, 1 error", got)
+	}
+
+	result := s.String()
+	for _, want := range []string{"By Message Type:", "TEMP_DATA", "By Device:", "0000000000000002"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("String() missing %q:\n%s", want, result)
+		}
+	}
+
+	s.Reset()
+	if len(s.Devices()) != 0 || len(s.MessageTypes()) != 0 {
+		t.Error("breakdowns not cleared by Reset")
+	}
+}
+
+func TestIntervalStats(t *testing.T) {
+	var i IntervalStats
+	for ms := 1; ms <= 100; ms++ {
+		i.add(time.Duration(ms) * time.Millisecond)
+	}
+
+	if i.Count != 100 || i.Min != time.Millisecond || i.Max != 100*time.Millisecond {
+		t.Errorf("Count/Min/Max = %d/%v/%v", i.Count, i.Min, i.Max)
+	}
+	if got := i.Avg(); got != 50500*time.Microsecond {
+		t.Errorf("Avg() = %v, want 50.5ms", got)
+	}
+	if got := i.P95(); got != 95*time.Millisecond {
+		t.Errorf("P95() = %v, want 95ms", got)
+	}
+	// Standard deviation of 1..100 is ~28.87
+	if got := i.Jitter(); got < 28*time.Millisecond || got > 29*time.Millisecond {
+		t.Errorf("Jitter() = %v, want ~28.9ms", got)
+	}
+}
+
+func TestStatistics_TelemetryIntervals(t *testing.T) {
+	s := NewStatistics()
+	start := time.Now()
+	at := func(p *Packet, ms int) *Packet {
+		p.timestamp = start.Add(time.Duration(ms) * time.Millisecond)
+		return p
+	}
+
+	// Learn a 100ms interval for device 0x01
+	s.Update(at(roundTrip(t, NewTelemetryConfig(0x01, true, 100)), 0), nil, nil)
+
+	// Two motors interleaved; motor 1's second report is late
+	s.Update(at(roundTrip(t, MotorData{Motor: 0}.Encode(0x01)), 10), nil, nil)
+	s.Update(at(roundTrip(t, MotorData{Motor: 1}.Encode(0x01)), 20), nil, nil)
+	s.Update(at(roundTrip(t, MotorData{Motor: 0}.Encode(0x01)), 110), nil, nil)
+	s.Update(at(roundTrip(t, MotorData{Motor: 1}.Encode(0x01)), 320), nil, nil)
+
+	motor := s.TelemetryIntervals(MsgMotorData)
+	if motor.Count != 2 || motor.Min != 100*time.Millisecond || motor.Max != 300*time.Millisecond {
+		t.Errorf("MOTOR_DATA intervals = %d/%v/%v, want 2/100ms/300ms", motor.Count, motor.Min, motor.Max)
+	}
+	if s.SlowTelemetry != 1 {
+		t.Errorf("SlowTelemetry = %d, want 1", s.SlowTelemetry)
+	}
+	if s.Gaps.Count != 4 || s.Gaps.Max != 210*time.Millisecond {
+		t.Errorf("Gaps = %d/%v, want 4/210ms", s.Gaps.Count, s.Gaps.Max)
+	}
+	if got := s.TelemetryTypes(); len(got) != 1 || got[0] != MsgMotorData {
+		t.Errorf("TelemetryTypes() = %v", got)
+	}
+
+	result := s.String()
+	for _, want := range []string{"Packet Gap:", "Telemetry Intervals:", "Slow Telemetry:"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("String() missing %q", want)
+		}
+	}
+}
+
 func TestStatistics_Reset(t *testing.T) {
 	s := NewStatistics()
 	s.TotalPackets = 100
@@ -1064,11 +1537,6 @@ func (e *testError) Error() string {
 	return e.msg
 }
 
-// Float64tobits converts float64 to uint64 bits (inverse of Float64frombits)
-func Float64tobits(f float64) uint64 {
-	return *(*uint64)(unsafe.Pointer(&f))
-}
-
 // ============================================================
 // Decoder Buffer Overflow Tests
 // ============================================================
@@ -1155,7 +1623,34 @@ func TestDecoder_UnexpectedEndByte(t *testing.T) {
 	if err == nil {
 		t.Error("Expected unexpected END byte error")
 	}
-	if !strings.Contains(err.Error(), "unexpected END byte") {
-		t.Errorf("Expected 'unexpected END byte', got '%s'", err.Error())
+	if !strings.Contains(err.Error(), "unexpected END byte in state ADDRESS") {
+		t.Errorf("Expected 'unexpected END byte in state ADDRESS', got '%s'", err.Error())
+	}
+}
+
+func TestVerifyDecode(t *testing.T) {
+	var stream []byte
+	for _, v := range Vectors() {
+		stream = append(stream, MustEncodePacket(v.Packet)...)
+	}
+	stream = append(stream, StartByte, 0xFF, EndByte) // Decode errors are not violations
+
+	if err := VerifyDecode(stream); err != nil {
+		t.Errorf("VerifyDecode = %v", err)
+	}
+}
+
+func TestDecoder_StateName(t *testing.T) {
+	d := NewDecoder()
+	want := []string{"LENGTH", "ADDRESS"}
+	for i, b := range []byte{StartByte, 0x04} {
+		d.DecodeByte(b)
+		if got := d.StateName(); got != want[i] {
+			t.Errorf("after byte %d: state = %s, want %s", i, got, want[i])
+		}
+	}
+	d.Reset()
+	if got := d.StateName(); got != "IDLE" {
+		t.Errorf("after Reset: state = %s, want IDLE", got)
 	}
 }
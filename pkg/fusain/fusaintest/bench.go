@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+// Package fusaintest provides test and benchmark helpers for code built on
+// the fusain package. They live apart from fusain so that importing the
+// protocol library does not link in the testing package.
+package fusaintest
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// BenchmarkThroughput measures how fast decoder decodes a stream holding
+// one frame of every test vector (see fusain.Vectors), byte by byte as a
+// serial reader would. It reports bytes/s, allocs/op and frames/op; call it
+// from a benchmark function to compare decoders or track regressions:
+//
+//	func BenchmarkDecoder(b *testing.B) {
+//		fusaintest.BenchmarkThroughput(b, fusain.NewDecoder())
+//	}
+//
+// A nil decoder uses fusain.NewDecoder.
+func BenchmarkThroughput(b *testing.B, decoder *fusain.Decoder) {
+	if decoder == nil {
+		decoder = fusain.NewDecoder()
+	}
+
+	var stream []byte
+	for _, v := range fusain.Vectors() {
+		stream = append(stream, fusain.MustEncodePacket(v.Packet)...)
+	}
+
+	b.SetBytes(int64(len(stream)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	frames := 0
+	for i := 0; i < b.N; i++ {
+		for _, c := range stream {
+			if p, _ := decoder.DecodeByte(c); p != nil {
+				frames++
+			}
+		}
+	}
+	b.ReportMetric(float64(frames)/float64(b.N), "frames/op")
+}
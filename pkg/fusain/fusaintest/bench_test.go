@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusaintest
+
+import (
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+func BenchmarkDecoder(b *testing.B) {
+	BenchmarkThroughput(b, fusain.NewDecoder())
+}
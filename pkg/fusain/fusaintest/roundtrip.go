@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusaintest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// typedCodecs re-encode a packet through its typed payload struct
+// (fusain's messages.go), at the struct's field widths
+var typedCodecs = map[uint8]func(*fusain.Packet) (*fusain.Packet, error){
+	fusain.MsgStateData:        viaTyped(fusain.DecodeStateData),
+	fusain.MsgMotorData:        viaTyped(fusain.DecodeMotorData),
+	fusain.MsgPumpData:         viaTyped(fusain.DecodePumpData),
+	fusain.MsgGlowData:         viaTyped(fusain.DecodeGlowData),
+	fusain.MsgTempData:         viaTyped(fusain.DecodeTempData),
+	fusain.MsgDeviceAnnounce:   viaTyped(fusain.DecodeDeviceAnnounce),
+	fusain.MsgPingResponse:     viaTyped(fusain.DecodePingResponse),
+	fusain.MsgErrorInvalidCmd:  viaTyped(fusain.DecodeErrorInvalidCmd),
+	fusain.MsgErrorStateReject: viaTyped(fusain.DecodeErrorStateReject),
+	fusain.MsgStateCommand:     viaTyped(fusain.DecodeStateCommand),
+	fusain.MsgMotorCommand:     viaTyped(fusain.DecodeMotorCommand),
+	fusain.MsgPumpCommand:      viaTyped(fusain.DecodePumpCommand),
+	fusain.MsgGlowCommand:      viaTyped(fusain.DecodeGlowCommand),
+}
+
+func viaTyped[T interface{ Encode(uint64) *fusain.Packet }](decode func(*fusain.Packet) (T, error)) func(*fusain.Packet) (*fusain.Packet, error) {
+	return func(p *fusain.Packet) (*fusain.Packet, error) {
+		v, err := decode(p)
+		if err != nil {
+			return nil, err
+		}
+		return v.Encode(p.Address()), nil
+	}
+}
+
+// RandomPayload returns a payload for schema holding a random value of
+// every required field and of some optional ones. Integers fit the field's
+// width and Max, with their magnitude spread so every CBOR integer size is
+// used; floats include integral, tiny, huge and non-finite values.
+func RandomPayload(rng *rand.Rand, schema fusain.MessageSchema) map[int]interface{} {
+	payload := make(map[int]interface{}, len(schema.Fields))
+	for _, f := range schema.Fields {
+		if !f.Required && rng.IntN(2) == 0 {
+			continue
+		}
+		payload[f.Key] = randomValue(rng, f)
+	}
+	return payload
+}
+
+func randomValue(rng *rand.Rand, f fusain.FieldSchema) interface{} {
+	bits := f.Bits
+	if bits == 0 {
+		bits = 64
+	}
+	switch f.Kind {
+	case fusain.KindUint:
+		if f.Max > 0 {
+			return rng.Uint64N(f.Max + 1)
+		}
+		return randomBits(rng, bits)
+	case fusain.KindInt:
+		v := int64(randomBits(rng, bits-1))
+		if rng.IntN(2) == 0 {
+			v = -v - 1
+		}
+		return v
+	case fusain.KindFloat:
+		switch rng.IntN(8) {
+		case 0:
+			return float64(rng.Int32())
+		case 1:
+			return rng.NormFloat64() * 1e-300
+		case 2:
+			return rng.NormFloat64() * 1e300
+		case 3:
+			return []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1)}[rng.IntN(4)]
+		}
+		return rng.NormFloat64() * 1000
+	case fusain.KindBool:
+		return rng.IntN(2) == 0
+	case fusain.KindBytes:
+		b := make([]byte, rng.IntN(16))
+		for i := range b {
+			b[i] = byte(rng.Uint32())
+		}
+		return b
+	}
+	return nil
+}
+
+// randomBits returns a random value of up to n bits, its bit length chosen
+// uniformly
+func randomBits(rng *rand.Rand, n int) uint64 {
+	length := rng.IntN(n + 1)
+	if length == 0 {
+		return 0
+	}
+	return rng.Uint64() >> (64 - length)
+}
+
+// CheckRoundTrip asserts, for rounds random payloads of every message type
+// in the schema registry (see RandomPayload) at random addresses, that the
+// encoded frame comes back byte for byte when it is:
+//
+//   - decoded and encoded again
+//   - decoded into its typed payload struct and encoded from that
+//   - marshaled to JSON and unmarshaled from the payload fields
+//
+// and that the decoded packet has the address it was sent to and passes
+// fusain.CheckSchema. A failure means the encoder, decoder, typed structs,
+// JSON form or schema disagree about a field's width, sign or byte order.
+// Call it from a test to check a fork or a schema change:
+//
+//	func TestRoundTrip(t *testing.T) {
+//		fusaintest.CheckRoundTrip(t, rand.New(rand.NewPCG(1, 2)), 1000)
+//	}
+//
+// Each message type reports its first failure only.
+func CheckRoundTrip(t testing.TB, rng *rand.Rand, rounds int) {
+	t.Helper()
+	for msgType := 0; msgType <= 0xFF; msgType++ {
+		schema, ok := fusain.LookupSchema(uint8(msgType))
+		if !ok {
+			continue
+		}
+		for i := 0; i < rounds; i++ {
+			address := rng.Uint64()
+			payload := RandomPayload(rng, schema)
+			if err := checkRoundTrip(address, uint8(msgType), payload); err != nil {
+				t.Errorf("%s at %016X, payload %v: %v", fusain.FormatMessageType(uint8(msgType)), address, payload, err)
+				break
+			}
+		}
+	}
+}
+
+// checkRoundTrip checks one payload; see CheckRoundTrip
+func checkRoundTrip(address uint64, msgType uint8, payload map[int]interface{}) error {
+	frame, err := fusain.EncodePacket(address, msgType, payload)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	p, err := fusain.DecodePacket(frame)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if p.Address() != address {
+		return fmt.Errorf("decoded address %016X", p.Address())
+	}
+	if errs := fusain.CheckSchema(p); len(errs) > 0 {
+		return fmt.Errorf("schema: %s", errs[0].Message)
+	}
+
+	again, err := fusain.EncodePacket(p.Address(), p.Type(), p.PayloadMap())
+	if err != nil {
+		return fmt.Errorf("re-encode: %w", err)
+	}
+	if !bytes.Equal(again, frame) {
+		return fmt.Errorf("re-encoded as % X, want % X", again, frame)
+	}
+
+	if codec, ok := typedCodecs[msgType]; ok {
+		typed, err := codec(p)
+		if err != nil {
+			return fmt.Errorf("typed decode: %w", err)
+		}
+		if again := fusain.MustEncodePacket(typed); !bytes.Equal(again, frame) {
+			return fmt.Errorf("typed struct encoded as % X, want % X", again, frame)
+		}
+	}
+
+	fromJSON, err := jsonRoundTrip(p)
+	if err != nil {
+		return fmt.Errorf("JSON: %w", err)
+	}
+	if again := fusain.MustEncodePacket(fromJSON); !bytes.Equal(again, frame) {
+		return fmt.Errorf("JSON form encoded as % X, want % X", again, frame)
+	}
+	return nil
+}
+
+// jsonRoundTrip marshals p to JSON and unmarshals it without the raw CBOR,
+// so the payload is rebuilt from the JSON fields
+func jsonRoundTrip(p *fusain.Packet) (*fusain.Packet, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "raw")
+	if data, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+	var out fusain.Packet
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusaintest
+
+import (
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// testAddress is the appliance address the round-trip cases are sent to
+const testAddress = 0x0123456789ABCDEF
+
+// fuzzRounds returns the number of rounds from FUZZ_ROUNDS, default 1000,
+// as the fusain package tests do
+func fuzzRounds() int {
+	if rounds, err := strconv.Atoi(os.Getenv("FUZZ_ROUNDS")); err == nil && rounds > 0 {
+		return rounds
+	}
+	return 1000
+}
+
+// fuzzSeed returns the seed from FUZZ_SEED, or one from the current time
+func fuzzSeed() int64 {
+	if seed, err := strconv.ParseInt(os.Getenv("FUZZ_SEED"), 10, 64); err == nil {
+		return seed
+	}
+	return time.Now().UnixNano()
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	seed := fuzzSeed()
+	t.Logf("Seed: %d (reproduce with FUZZ_SEED=%d)", seed, seed)
+	CheckRoundTrip(t, rand.New(rand.NewPCG(uint64(seed), 0)), fuzzRounds())
+}
+
+func TestCheckRoundTrip_CatchesWidthDrift(t *testing.T) {
+	// A typed struct narrower than the schema's documented width
+	saved := typedCodecs[fusain.MsgMotorCommand]
+	typedCodecs[fusain.MsgMotorCommand] = func(p *fusain.Packet) (*fusain.Packet, error) {
+		c, err := fusain.DecodeMotorCommand(p)
+		if err != nil {
+			return nil, err
+		}
+		return fusain.NewMotorCommand(p.Address(), c.Motor, int32(int16(c.RPM))), nil
+	}
+	defer func() { typedCodecs[fusain.MsgMotorCommand] = saved }()
+
+	err := checkRoundTrip(testAddress, fusain.MsgMotorCommand, map[int]interface{}{0: uint64(0), 1: int64(70000)})
+	if err == nil || !strings.Contains(err.Error(), "typed struct") {
+		t.Errorf("checkRoundTrip = %v, want a typed struct mismatch", err)
+	}
+	if err := checkRoundTrip(testAddress, fusain.MsgMotorCommand, map[int]interface{}{0: uint64(0), 1: int64(3200)}); err != nil {
+		t.Errorf("checkRoundTrip in range = %v", err)
+	}
+}
+
+func TestCheckRoundTrip_CatchesUndocumentedWidth(t *testing.T) {
+	// Wider than the schema allows
+	err := checkRoundTrip(testAddress, fusain.MsgPumpCommand, map[int]interface{}{0: uint64(0), 1: int64(1) << 40})
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("checkRoundTrip = %v, want a schema range error", err)
+	}
+}
+
+func BenchmarkCheckRoundTrip(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < b.N; i++ {
+		CheckRoundTrip(b, rng, 1)
+	}
+}
@@ -78,6 +78,23 @@ func buildRandomCBORPayload(rng *rand.Rand, msgType uint8) []byte {
 // Decoder Fuzz Tests
 // ============================================================
 
+// FuzzDecode is the native fuzz target for the decoder (go test -fuzz=FuzzDecode).
+// Failing inputs are saved under testdata/fuzz/FuzzDecode; triage them with
+// heliostat fuzz_triage.
+func FuzzDecode(f *testing.F) {
+	for _, v := range Vectors() {
+		f.Add(MustEncodePacket(v.Packet))
+	}
+	f.Add([]byte{StartByte, EndByte})
+	f.Add([]byte{StartByte, EscByte, StartByte ^ EscXor, EndByte})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := VerifyDecode(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
 // TestFuzzDecoder_RandomBytes feeds random bytes to the decoder
 // and verifies it doesn't crash or panic
 func TestFuzzDecoder_RandomBytes(t *testing.T) {
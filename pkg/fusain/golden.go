@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vector is a named sample packet from the test-vector corpus
+type Vector struct {
+	Name   string // Unique, file-safe name, e.g. "state_data_heating"
+	Packet *Packet
+}
+
+// vectorTime is the receive time of every vector, fixed so rendered output
+// is stable
+var vectorTime = time.Date(2025, 1, 2, 15, 4, 5, 123000000, time.UTC)
+
+// Vector addresses
+const (
+	vectorAppliance  = 0x0123456789ABCDEF
+	vectorController = 0x00000000000000C0
+)
+
+// Vectors returns the test-vector corpus: at least one packet for every
+// known message type, plus an unknown type. Each packet has been through
+// the encoder and decoder, so it looks exactly like a received packet.
+func Vectors() []Vector {
+	type sample struct {
+		name    string
+		address uint64
+		msgType uint8
+		payload map[int]interface{}
+	}
+	samples := []sample{
+		// Configuration commands
+		{"motor_config", vectorAppliance, MsgMotorConfig, map[int]interface{}{
+			0: uint64(0), 1: uint64(20000), 2: 0.5, 3: 0.1, 4: 0.01, 5: int64(6000), 6: int64(800), 7: uint64(10),
+		}},
+		{"pump_config", vectorAppliance, MsgPumpConfig, map[int]interface{}{0: uint64(0), 1: uint64(50), 2: uint64(100)}},
+		{"temp_config", vectorAppliance, MsgTempConfig, map[int]interface{}{0: uint64(0), 1: 1.5, 2: 0.2, 3: 0.05}},
+		{"glow_config", vectorAppliance, MsgGlowConfig, map[int]interface{}{0: uint64(0), 1: uint64(300000)}},
+		{"data_subscription", AddressStateless, MsgDataSubscription, map[int]interface{}{0: uint64(vectorAppliance)}},
+		{"data_unsubscribe", AddressStateless, MsgDataUnsubscribe, map[int]interface{}{0: uint64(vectorAppliance)}},
+		{"telemetry_config", vectorAppliance, MsgTelemetryConfig, map[int]interface{}{0: true, 1: uint64(100)}},
+		{"timeout_config", vectorAppliance, MsgTimeoutConfig, map[int]interface{}{0: true, 1: uint64(30000)}},
+		{"discovery_request", AddressBroadcast, MsgDiscoveryRequest, nil},
+
+		// Control commands
+		{"state_command_heat", vectorAppliance, MsgStateCommand, map[int]interface{}{0: uint64(ModeHeat), 1: int64(2500)}},
+		{"state_command_emergency", AddressBroadcast, MsgStateCommand, map[int]interface{}{0: uint64(ModeEmergency)}},
+		{"motor_command", vectorAppliance, MsgMotorCommand, map[int]interface{}{0: uint64(0), 1: int64(3200)}},
+		{"pump_command", vectorAppliance, MsgPumpCommand, map[int]interface{}{0: uint64(0), 1: int64(250)}},
+		{"glow_command", vectorAppliance, MsgGlowCommand, map[int]interface{}{0: uint64(0), 1: int64(60000)}},
+		{"temp_command", vectorAppliance, MsgTempCommand, map[int]interface{}{
+			0: uint64(0), 1: uint64(TempCmdSetTargetTemp), 3: 180.0,
+		}},
+		{"send_telemetry", vectorAppliance, MsgSendTelemetry, map[int]interface{}{0: uint64(TelemetryTypeMotor), 1: uint64(0)}},
+		{"ping_request", vectorAppliance, MsgPingRequest, nil},
+
+		// Telemetry data
+		{"state_data_heating", vectorAppliance, MsgStateData, map[int]interface{}{
+			0: false, 1: int64(0), 2: uint64(SysStateHeating), 3: uint64(120000),
+		}},
+		{"state_data_error", vectorAppliance, MsgStateData, map[int]interface{}{
+			0: true, 1: int64(1), 2: uint64(SysStateError), 3: uint64(125000),
+		}},
+		{"motor_data", vectorAppliance, MsgMotorData, map[int]interface{}{
+			0: uint64(0), 1: uint64(120000), 2: int64(3150), 3: int64(3200),
+			4: int64(6000), 5: int64(800), 6: uint64(450), 7: uint64(1000),
+		}},
+		{"pump_data", vectorAppliance, MsgPumpData, map[int]interface{}{
+			0: uint64(0), 1: uint64(120000), 2: uint64(PumpEventCycleStart), 3: int64(250),
+		}},
+		{"glow_data", vectorAppliance, MsgGlowData, map[int]interface{}{0: uint64(0), 1: uint64(120000), 2: true}},
+		{"temp_data", vectorAppliance, MsgTempData, map[int]interface{}{
+			0: uint64(0), 1: uint64(120000), 2: 182.5, 3: true, 4: int64(0), 5: 180.0,
+		}},
+		{"device_announce", vectorAppliance, MsgDeviceAnnounce, map[int]interface{}{
+			0: uint64(2), 1: uint64(1), 2: uint64(1), 3: uint64(1),
+		}},
+		{"ping_response", vectorAppliance, MsgPingResponse, map[int]interface{}{0: uint64(90061001)}},
+
+		// Errors
+		{"error_invalid_cmd", vectorController, MsgErrorInvalidCmd, map[int]interface{}{0: int64(1)}},
+		{"error_state_reject", vectorController, MsgErrorStateReject, map[int]interface{}{0: uint64(SysStateHeating)}},
+
+		// Unknown message type
+		{"unknown_type", vectorAppliance, 0x50, map[int]interface{}{0: uint64(1), 1: "x"}},
+	}
+
+	vectors := make([]Vector, len(samples))
+	for i, s := range samples {
+		p, err := DecodePacket(MustEncodePacket(NewPacketWithPayload(s.address, s.msgType, s.payload)))
+		if err != nil {
+			panic(fmt.Sprintf("fusain: vector %s: %v", s.name, err))
+		}
+		p.timestamp = vectorTime
+		vectors[i] = Vector{Name: s.name, Packet: p}
+	}
+	return vectors
+}
+
+// RenderVectors formats every vector with opts, each under a
+// "=== name ===" header, in corpus order
+func RenderVectors(opts FormatOptions) string {
+	var b strings.Builder
+	for _, v := range Vectors() {
+		fmt.Fprintf(&b, "=== %s ===\n", v.Name)
+		b.WriteString(FormatPacketWithOptions(v.Packet, opts))
+	}
+	return b.String()
+}
+
+// CheckGolden compares RenderVectors(opts) against the golden file at path
+// and reports the vectors whose output differs. With update set, the file
+// is rewritten instead. Formatter output is read by log parsers, so any
+// change should be made deliberately by updating the golden files.
+func CheckGolden(path string, opts FormatOptions, update bool) error {
+	got := RenderVectors(opts)
+	if update {
+		return os.WriteFile(path, []byte(got), 0o644)
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(want, []byte(got)) {
+		return nil
+	}
+
+	gotSections := splitGolden(got)
+	wantSections := splitGolden(string(want))
+	var changed []string
+	for _, v := range Vectors() {
+		if gotSections[v.Name] != wantSections[v.Name] {
+			changed = append(changed, v.Name)
+		}
+		delete(wantSections, v.Name)
+	}
+	for name := range wantSections {
+		changed = append(changed, name+" (removed)")
+	}
+	return fmt.Errorf("formatter output differs from %s for: %s", path, strings.Join(changed, ", "))
+}
+
+// splitGolden splits rendered output into sections by vector name
+func splitGolden(s string) map[string]string {
+	sections := make(map[string]string)
+	var name string
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if strings.HasPrefix(line, "=== ") && strings.HasSuffix(line, " ===\n") {
+			name = strings.TrimSuffix(strings.TrimPrefix(line, "=== "), " ===\n")
+			sections[name] = ""
+			continue
+		}
+		sections[name] += line
+	}
+	return sections
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite formatter golden files")
+
+func TestFormatter_Golden(t *testing.T) {
+	tests := []struct {
+		file string
+		opts FormatOptions
+	}{
+		{"metric.golden", FormatOptions{}},
+		{"imperial.golden", FormatOptions{Units: UnitsImperial}},
+		{"cbor_diag.golden", FormatOptions{CBORDiagnostic: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", "golden", tt.file)
+			if err := CheckGolden(path, tt.opts, *updateGolden); err != nil {
+				t.Errorf("%v (run go test -run Golden -update to accept)", err)
+			}
+		})
+	}
+}
+
+func TestVectors_CoverMessageTypes(t *testing.T) {
+	covered := make(map[uint8]bool)
+	names := make(map[string]bool)
+	for _, v := range Vectors() {
+		if names[v.Name] {
+			t.Errorf("duplicate vector name %s", v.Name)
+		}
+		names[v.Name] = true
+		if err := v.Packet.ParseError(); err != nil {
+			t.Errorf("vector %s: %v", v.Name, err)
+		}
+		covered[v.Packet.Type()] = true
+	}
+
+	for i := 0; i <= 0xFF; i++ {
+		msgType := uint8(i)
+		if FormatMessageType(msgType) != "UNKNOWN" && !covered[msgType] {
+			t.Errorf("no vector for %s (0x%02X)", FormatMessageType(msgType), msgType)
+		}
+	}
+}
+
+func TestCheckGolden_ReportsChangedVectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metric.golden")
+	if err := CheckGolden(path, FormatOptions{}, true); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := CheckGolden(path, FormatOptions{}, false); err != nil {
+		t.Errorf("unchanged output reported: %v", err)
+	}
+
+	// Temperatures differ between unit systems, other vectors do not
+	err := CheckGolden(path, FormatOptions{Units: UnitsImperial}, false)
+	if err == nil {
+		t.Fatal("expected mismatch")
+	}
+	if !strings.Contains(err.Error(), "temp_data") || strings.Contains(err.Error(), "ping_request") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,410 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// packetJSON is the JSON form of a Packet:
+//
+//	{
+//	  "address": "0123456789ABCDEF",
+//	  "type": "STATE_DATA",
+//	  "type_id": 48,
+//	  "payload": {"0": false, "1": 0, "2": 5, "3": 120000},
+//	  "timestamp": "2025-01-02T15:04:05.123Z",
+//	  "raw": "821830a400f401000205031a0001d4c0"
+//	}
+//
+// Payload keys are the CBOR map keys as strings. Byte strings are base64 and
+// non-finite floats are the strings "NaN", "+Inf" and "-Inf". Raw is the
+// CBOR payload in hex; when present it takes precedence over type and
+// payload on unmarshal, so received packets round-trip exactly.
+type packetJSON struct {
+	Address   string                     `json:"address"`
+	Type      string                     `json:"type"`
+	TypeID    *uint8                     `json:"type_id,omitempty"`
+	Payload   map[string]json.RawMessage `json:"payload,omitempty"`
+	Timestamp time.Time                  `json:"timestamp"`
+	Raw       string                     `json:"raw,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (p *Packet) MarshalJSON() ([]byte, error) {
+	msgType := p.Type()
+	out := struct {
+		Address   string                 `json:"address"`
+		Type      string                 `json:"type"`
+		TypeID    uint8                  `json:"type_id"`
+		Payload   map[string]interface{} `json:"payload,omitempty"`
+		Timestamp time.Time              `json:"timestamp"`
+		Raw       string                 `json:"raw,omitempty"`
+	}{
+		Address:   fmt.Sprintf("%016X", p.Address()),
+		Type:      FormatMessageType(msgType),
+		TypeID:    msgType,
+		Timestamp: p.Timestamp(),
+		Raw:       hex.EncodeToString(p.PayloadRaw()),
+	}
+
+	if m := p.PayloadMap(); m != nil {
+		out.Payload = make(map[string]interface{}, len(m))
+		for key, v := range m {
+			out.Payload[strconv.Itoa(key)] = jsonValue(v)
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// jsonValue converts a decoded CBOR value to a JSON-encodable value
+func jsonValue(v interface{}) interface{} {
+	var f float64
+	switch x := v.(type) {
+	case float64:
+		f = x
+	case float32:
+		f = float64(x)
+	default:
+		return v
+	}
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return f
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The result is a sendable
+// packet: its CBOR payload and CRC are rebuilt from the JSON fields.
+func (p *Packet) UnmarshalJSON(data []byte) error {
+	var in packetJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	address, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(in.Address, "0x"), "0X"), 16, 64)
+	if err != nil {
+		return fmt.Errorf("fusain: invalid packet address %q", in.Address)
+	}
+
+	var raw []byte
+	if in.Raw != "" {
+		raw, err = hex.DecodeString(in.Raw)
+		if err != nil {
+			return fmt.Errorf("fusain: invalid raw payload: %w", err)
+		}
+		if _, _, err := ParseCBORMessage(raw); err != nil {
+			return fmt.Errorf("fusain: invalid raw payload: %w", err)
+		}
+	} else {
+		raw, err = in.encodePayload()
+		if err != nil {
+			return err
+		}
+	}
+
+	timestamp := in.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	*p = Packet{
+		length:      uint8(len(raw)),
+		address:     address,
+		cborPayload: raw,
+		crc:         packetCRC(address, raw),
+		timestamp:   timestamp,
+	}
+	return nil
+}
+
+// encodePayload builds the CBOR payload from the type and payload fields,
+// using the schema registry to restore CBOR types JSON cannot express
+func (in *packetJSON) encodePayload() ([]byte, error) {
+	var msgType uint8
+	switch {
+	case in.TypeID != nil:
+		msgType = *in.TypeID
+	default:
+		t, ok := ParseMessageType(in.Type)
+		if !ok {
+			return nil, fmt.Errorf("fusain: unknown message type %q", in.Type)
+		}
+		msgType = t
+	}
+
+	var payload map[int]interface{}
+	if in.Payload != nil {
+		schema, _ := LookupSchema(msgType)
+		payload = make(map[int]interface{}, len(in.Payload))
+		for k, v := range in.Payload {
+			key, err := strconv.Atoi(k)
+			if err != nil {
+				return nil, fmt.Errorf("fusain: invalid payload key %q", k)
+			}
+			field, known := schema.Field(key)
+			value, err := cborValue(v, field.Kind, known)
+			if err != nil {
+				return nil, fmt.Errorf("fusain: payload key %d: %w", key, err)
+			}
+			payload[key] = value
+		}
+	}
+
+	raw, err := encodeCBORPayload(msgType, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > MaxPayloadSize {
+		return nil, fmt.Errorf("fusain: CBOR payload too large: %d bytes (max %d)", len(raw), MaxPayloadSize)
+	}
+	return raw, nil
+}
+
+// cborValue converts a JSON payload value to the CBOR value for a field.
+// Without a schema, integers become int64/uint64 and other numbers float64.
+func cborValue(data json.RawMessage, kind FieldKind, known bool) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+
+	case json.Number:
+		if known {
+			switch kind {
+			case KindFloat:
+				return x.Float64()
+			case KindUint:
+				return strconv.ParseUint(x.String(), 10, 64)
+			case KindInt:
+				return strconv.ParseInt(x.String(), 10, 64)
+			}
+			return nil, fmt.Errorf("number given for %s field", kind)
+		}
+		if u, err := strconv.ParseUint(x.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		if i, err := strconv.ParseInt(x.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		return x.Float64()
+
+	case string:
+		switch x {
+		case "NaN":
+			return math.NaN(), nil
+		case "+Inf", "Inf":
+			return math.Inf(1), nil
+		case "-Inf":
+			return math.Inf(-1), nil
+		}
+		return base64.StdEncoding.DecodeString(x)
+	}
+
+	return nil, fmt.Errorf("unsupported JSON value %s", data)
+}
+
+// packetCRC computes the CRC of a packet's length, address and CBOR payload
+func packetCRC(address uint64, cborPayload []byte) uint16 {
+	data := make([]byte, 1+AddressSize+len(cborPayload))
+	data[0] = uint8(len(cborPayload))
+	binary.LittleEndian.PutUint64(data[1:1+AddressSize], address)
+	copy(data[1+AddressSize:], cborPayload)
+	return CalculateCRC(data)
+}
+
+// MarshalJSON implements json.Marshaler:
+//
+//	{"type": "high_rpm", "message": "...", "details": {"rpm": 7200, "max": 6000}}
+//
+// Check is included for anomalies from registered validators. Non-finite
+// float details are encoded as strings, as in packet payloads.
+func (v ValidationError) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Type    string                 `json:"type"`
+		Message string                 `json:"message"`
+		Details map[string]interface{} `json:"details,omitempty"`
+		Check   string                 `json:"check,omitempty"`
+	}{
+		Type:    v.Type.String(),
+		Message: v.Message,
+		Check:   v.Check,
+	}
+
+	if len(v.Details) > 0 {
+		out.Details = make(map[string]interface{}, len(v.Details))
+		for key, value := range v.Details {
+			out.Details[key] = jsonValue(value)
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// statisticsJSON is the JSON form of Statistics. Counters that are zero
+// are still included so consumers see a fixed schema; value ranges,
+// intervals and breakdowns only appear once something was recorded.
+type statisticsJSON struct {
+	StartTime time.Time `json:"start_time"`
+	Elapsed   float64   `json:"elapsed"` // Seconds since StartTime
+
+	TotalPackets       uint64 `json:"total_packets"`
+	ValidPackets       uint64 `json:"valid_packets"`
+	CRCErrors          uint64 `json:"crc_errors"`
+	DecodeErrors       uint64 `json:"decode_errors"`
+	MalformedPackets   uint64 `json:"malformed_packets"`
+	InvalidCounts      uint64 `json:"invalid_counts"`
+	LengthMismatches   uint64 `json:"length_mismatches"`
+	MissingFields      uint64 `json:"missing_fields"`
+	AnomalousValues    uint64 `json:"anomalous_values"`
+	HighRPM            uint64 `json:"high_rpm"`
+	InvalidTemp        uint64 `json:"invalid_temp"`
+	InvalidPWM         uint64 `json:"invalid_pwm"`
+	InvalidTransitions uint64 `json:"invalid_transitions"`
+	TimestampErrors    uint64 `json:"timestamp_errors"`
+	DuplicatePackets   uint64 `json:"duplicate_packets"`
+	StaleDevices       uint64 `json:"stale_devices"`
+	CustomAnomalies    uint64 `json:"custom_anomalies"`
+	SlowTelemetry      uint64 `json:"slow_telemetry"`
+
+	PacketRate float64 `json:"packet_rate"`
+	ErrorRate  float64 `json:"error_rate"`
+
+	HighRPMValues   *rangeJSON `json:"high_rpm_values,omitempty"`
+	TempValues      *rangeJSON `json:"temp_values,omitempty"`
+	PWMExcess       *rangeJSON `json:"pwm_excess,omitempty"`
+	ComponentCounts *rangeJSON `json:"component_counts,omitempty"`
+	TimestampRewind *rangeJSON `json:"timestamp_rewind,omitempty"`
+
+	Gaps               *intervalJSON            `json:"gaps,omitempty"`
+	TelemetryIntervals map[string]*intervalJSON `json:"telemetry_intervals,omitempty"`
+	ByType             map[string]CountStats    `json:"by_type,omitempty"`
+	ByDevice           map[string]CountStats    `json:"by_device,omitempty"`
+	ByCheck            map[string]uint64        `json:"by_check,omitempty"`
+}
+
+type rangeJSON struct {
+	Count uint64  `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+func newRangeJSON(r ValueRange) *rangeJSON {
+	if r.Count == 0 {
+		return nil
+	}
+	return &rangeJSON{Count: r.Count, Min: r.Min, Max: r.Max}
+}
+
+// intervalJSON is IntervalStats in milliseconds
+type intervalJSON struct {
+	Count  uint64  `json:"count"`
+	Min    float64 `json:"min_ms"`
+	Avg    float64 `json:"avg_ms"`
+	P95    float64 `json:"p95_ms"`
+	Max    float64 `json:"max_ms"`
+	Jitter float64 `json:"jitter_ms"`
+}
+
+func newIntervalJSON(i *IntervalStats) *intervalJSON {
+	if i.Count == 0 {
+		return nil
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return &intervalJSON{
+		Count:  i.Count,
+		Min:    ms(i.Min),
+		Avg:    ms(i.Avg()),
+		P95:    ms(i.P95()),
+		Max:    ms(i.Max),
+		Jitter: ms(i.Jitter()),
+	}
+}
+
+// MarshalJSON implements json.Marshaler with a snapshot of the counters,
+// rates (recalculated), value ranges, intervals and per-type, per-device
+// and per-check breakdowns. Types are keyed by name and devices by address
+// in hex.
+func (s *Statistics) MarshalJSON() ([]byte, error) {
+	s.CalculateRates()
+
+	out := statisticsJSON{
+		StartTime:          s.StartTime,
+		Elapsed:            time.Since(s.StartTime).Seconds(),
+		TotalPackets:       s.TotalPackets,
+		ValidPackets:       s.ValidPackets,
+		CRCErrors:          s.CRCErrors,
+		DecodeErrors:       s.DecodeErrors,
+		MalformedPackets:   s.MalformedPackets,
+		InvalidCounts:      s.InvalidCounts,
+		LengthMismatches:   s.LengthMismatches,
+		MissingFields:      s.MissingFields,
+		AnomalousValues:    s.AnomalousValues,
+		HighRPM:            s.HighRPM,
+		InvalidTemp:        s.InvalidTemp,
+		InvalidPWM:         s.InvalidPWM,
+		InvalidTransitions: s.InvalidTransitions,
+		TimestampErrors:    s.TimestampErrors,
+		DuplicatePackets:   s.DuplicatePackets,
+		StaleDevices:       s.StaleDevices,
+		CustomAnomalies:    s.CustomAnomalies,
+		SlowTelemetry:      s.SlowTelemetry,
+		PacketRate:         s.PacketRate,
+		ErrorRate:          s.ErrorRate,
+		HighRPMValues:      newRangeJSON(s.HighRPMValues),
+		TempValues:         newRangeJSON(s.TempValues),
+		PWMExcess:          newRangeJSON(s.PWMExcess),
+		ComponentCounts:    newRangeJSON(s.ComponentCounts),
+		TimestampRewind:    newRangeJSON(s.TimestampRewind),
+		Gaps:               newIntervalJSON(&s.Gaps),
+	}
+
+	if len(s.typeIntervals) > 0 {
+		out.TelemetryIntervals = make(map[string]*intervalJSON, len(s.typeIntervals))
+		for t, i := range s.typeIntervals {
+			out.TelemetryIntervals[FormatMessageType(t)] = newIntervalJSON(i)
+		}
+	}
+	if len(s.byType) > 0 {
+		out.ByType = make(map[string]CountStats, len(s.byType))
+		for t, c := range s.byType {
+			out.ByType[FormatMessageType(t)] = *c
+		}
+	}
+	if len(s.byDevice) > 0 {
+		out.ByDevice = make(map[string]CountStats, len(s.byDevice))
+		for d, c := range s.byDevice {
+			out.ByDevice[fmt.Sprintf("%016X", d)] = *c
+		}
+	}
+	if len(s.byCheck) > 0 {
+		out.ByCheck = make(map[string]uint64, len(s.byCheck))
+		for name, n := range s.byCheck {
+			out.ByCheck[name] = n
+		}
+	}
+
+	return json.Marshal(out)
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPacketJSON_RoundTrip(t *testing.T) {
+	received := roundTrip(t, TempData{Thermometer: 0, Timestamp: 100, Reading: 20.0}.Encode(0x0123456789ABCDEF))
+
+	data, err := json.Marshal(received)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{`"address":"0123456789ABCDEF"`, `"type":"TEMP_DATA"`, `"type_id":52`, `"raw":"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON %s missing %s", data, want)
+		}
+	}
+
+	var reloaded Packet
+	if err := json.Unmarshal(data, &reloaded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !bytes.Equal(reloaded.PayloadRaw(), received.PayloadRaw()) {
+		t.Errorf("raw payload changed: %x != %x", reloaded.PayloadRaw(), received.PayloadRaw())
+	}
+	if reloaded.CRC() != received.CRC() {
+		t.Errorf("CRC = 0x%04X, want 0x%04X", reloaded.CRC(), received.CRC())
+	}
+	if !reloaded.Timestamp().Equal(received.Timestamp()) {
+		t.Errorf("timestamp = %v, want %v", reloaded.Timestamp(), received.Timestamp())
+	}
+
+	// Reloaded packets are sendable
+	resent := roundTrip(t, &reloaded)
+	if resent.Address() != received.Address() || resent.Type() != MsgTempData {
+		t.Errorf("resent packet = %016X %s", resent.Address(), FormatMessageType(resent.Type()))
+	}
+}
+
+func TestPacketJSON_UnmarshalWithoutRaw(t *testing.T) {
+	// 20 is a float per the TEMP_DATA schema even though JSON has no decimal point
+	input := `{"address":"0x0123456789ABCDEF","type":"temp_data","payload":{"0":0,"1":100,"2":20}}`
+
+	var p Packet
+	if err := json.Unmarshal([]byte(input), &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	temp, err := DecodeTempData(&p)
+	if err != nil {
+		t.Fatalf("DecodeTempData failed: %v", err)
+	}
+	if temp.Reading != 20.0 {
+		t.Errorf("Reading = %v, want 20", temp.Reading)
+	}
+	if errs := CheckPayloadTypes(&p); len(errs) != 0 {
+		t.Errorf("CheckPayloadTypes = %v", errs)
+	}
+	if p.Timestamp().IsZero() {
+		t.Error("missing timestamp not defaulted")
+	}
+}
+
+func TestPacketJSON_NonFiniteFloat(t *testing.T) {
+	p := TempData{Reading: math.NaN()}.Encode(1)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"2":"NaN"`) {
+		t.Errorf("JSON %s does not encode NaN as a string", data)
+	}
+}
+
+func TestPacketJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"bad address", `{"address":"xyz","type":"PING_REQUEST"}`},
+		{"unknown type", `{"address":"01","type":"NOT_A_TYPE"}`},
+		{"bad raw", `{"address":"01","raw":"zz"}`},
+		{"bad key", `{"address":"01","type":"STATE_DATA","payload":{"x":1}}`},
+		{"wrong kind", `{"address":"01","type":"STATE_DATA","payload":{"0":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Packet
+			if err := json.Unmarshal([]byte(tt.input), &p); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestValidationErrorJSON(t *testing.T) {
+	v := ValidationError{
+		Type:    AnomalyInvalidTemp,
+		Message: "temperature out of range",
+		Details: map[string]interface{}{"value": math.Inf(1), "max": 1000.0},
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{`"type":"invalid_temp"`, `"message":"temperature out of range"`, `"value":"+Inf"`, `"max":1000`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON %s missing %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), `"check"`) {
+		t.Errorf("JSON %s has a check for a built-in anomaly", data)
+	}
+}
+
+func TestStatisticsJSON(t *testing.T) {
+	s := NewStatistics()
+	limits := DefaultValidationLimits()
+	p := roundTrip(t, MotorData{RPM: 7200, Target: 3000}.Encode(0x01))
+	s.Update(p, nil, ValidatePacketWithOptions(p, ValidateOptions{Limits: &limits}))
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var out struct {
+		TotalPackets  uint64                `json:"total_packets"`
+		HighRPM       uint64                `json:"high_rpm"`
+		CRCErrors     *uint64               `json:"crc_errors"`
+		HighRPMValues *rangeJSON            `json:"high_rpm_values"`
+		TempValues    *rangeJSON            `json:"temp_values"`
+		ByType        map[string]CountStats `json:"by_type"`
+		ByDevice      map[string]CountStats `json:"by_device"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if out.TotalPackets != 1 || out.HighRPM != 1 {
+		t.Errorf("total_packets=%d high_rpm=%d, want 1 and 1", out.TotalPackets, out.HighRPM)
+	}
+	if out.CRCErrors == nil {
+		t.Error("zero counter crc_errors omitted")
+	}
+	if out.HighRPMValues == nil || out.HighRPMValues.Max != 7200 {
+		t.Errorf("high_rpm_values = %+v, want max 7200", out.HighRPMValues)
+	}
+	if out.TempValues != nil {
+		t.Errorf("temp_values = %+v, want omitted", out.TempValues)
+	}
+	if c := out.ByType["MOTOR_DATA"]; c.Packets != 1 || c.Errors != 1 {
+		t.Errorf("by_type MOTOR_DATA = %+v, want 1 packet with 1 error", c)
+	}
+	if c := out.ByDevice["0000000000000001"]; c.Packets != 1 {
+		t.Errorf("by_device = %+v, want 1 packet from 0000000000000001", out.ByDevice)
+	}
+}
+
+func TestParseMessageType(t *testing.T) {
+	for _, name := range []string{"STATE_DATA", "state_data", "state-data"} {
+		if got, ok := ParseMessageType(name); !ok || got != MsgStateData {
+			t.Errorf("ParseMessageType(%q) = 0x%02X, %v", name, got, ok)
+		}
+	}
+	if _, ok := ParseMessageType("UNKNOWN"); ok {
+		t.Error("ParseMessageType(UNKNOWN) succeeded")
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ValidationLimits holds the thresholds ValidatePacket checks values
+// against, so appliance models with different motors, thermometers or glow
+// plugs can be validated without changing the package.
+//
+// A limits profile is JSON; omitted fields keep their defaults:
+//
+//	{
+//	  "max_rpm": 8000,
+//	  "min_temp": -40,
+//	  "max_temp": 850,
+//	  "max_glow_duration_ms": 120000,
+//	  "max_components": 4
+//	}
+type ValidationLimits struct {
+	MaxRPM            int64   `json:"max_rpm"`              // Motor RPM and target RPM
+	MinTemp           float64 `json:"min_temp"`             // Temperature readings and targets (°C)
+	MaxTemp           float64 `json:"max_temp"`             // Temperature readings and targets (°C)
+	MaxGlowDurationMs int64   `json:"max_glow_duration_ms"` // GLOW_COMMAND duration
+	MaxComponents     uint64  `json:"max_components"`       // Per-kind counts in DEVICE_ANNOUNCE
+}
+
+// DefaultValidationLimits returns the limits used when none are given
+func DefaultValidationLimits() ValidationLimits {
+	return ValidationLimits{
+		MaxRPM:            6000,
+		MinTemp:           -50.0,
+		MaxTemp:           1000.0,
+		MaxGlowDurationMs: 300000,
+		MaxComponents:     10,
+	}
+}
+
+// Validate checks that the limits are usable
+func (l ValidationLimits) Validate() error {
+	if l.MaxRPM <= 0 {
+		return fmt.Errorf("max_rpm must be positive, got %d", l.MaxRPM)
+	}
+	if l.MinTemp >= l.MaxTemp {
+		return fmt.Errorf("min_temp (%.1f) must be below max_temp (%.1f)", l.MinTemp, l.MaxTemp)
+	}
+	if l.MaxGlowDurationMs <= 0 {
+		return fmt.Errorf("max_glow_duration_ms must be positive, got %d", l.MaxGlowDurationMs)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Fields missing from the JSON
+// keep their default values and unknown fields are rejected, so a typo in a
+// profile does not silently fall back to the defaults.
+func (l *ValidationLimits) UnmarshalJSON(data []byte) error {
+	type plain ValidationLimits
+	limits := plain(DefaultValidationLimits())
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&limits); err != nil {
+		return err
+	}
+
+	if err := ValidationLimits(limits).Validate(); err != nil {
+		return err
+	}
+	*l = ValidationLimits(limits)
+	return nil
+}
+
+// LoadValidationLimits reads a JSON limits profile
+func LoadValidationLimits(path string) (ValidationLimits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ValidationLimits{}, err
+	}
+
+	var limits ValidationLimits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return ValidationLimits{}, fmt.Errorf("invalid limits profile %s: %v", path, err)
+	}
+	return limits, nil
+}
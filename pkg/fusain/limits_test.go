@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatePacketWithOptions_Limits(t *testing.T) {
+	motor := MotorData{Motor: 0, Timestamp: 1000, RPM: 7000, Target: 7000}.Encode(0x01)
+	temp := TempData{Thermometer: 0, Timestamp: 1000, Reading: 900}.Encode(0x01)
+
+	if errs := ValidatePacket(motor); len(errs) != 1 || errs[0].Type != AnomalyHighRPM {
+		t.Errorf("default limits: expected AnomalyHighRPM, got %v", errs)
+	}
+	if errs := ValidatePacket(temp); len(errs) != 0 {
+		t.Errorf("default limits: expected no errors, got %v", errs)
+	}
+
+	limits := DefaultValidationLimits()
+	limits.MaxRPM = 8000
+	limits.MaxTemp = 850
+	opts := ValidateOptions{Limits: &limits}
+
+	if errs := ValidatePacketWithOptions(motor, opts); len(errs) != 0 {
+		t.Errorf("max_rpm 8000: expected no errors, got %v", errs)
+	}
+	errs := ValidatePacketWithOptions(temp, opts)
+	if len(errs) != 1 || errs[0].Type != AnomalyInvalidTemp {
+		t.Fatalf("max_temp 850: expected AnomalyInvalidTemp, got %v", errs)
+	}
+	if errs[0].Details["max"] != 850.0 {
+		t.Errorf("max detail = %v, want 850", errs[0].Details["max"])
+	}
+}
+
+func TestValidationLimits_UnmarshalJSON(t *testing.T) {
+	var limits ValidationLimits
+	if err := json.Unmarshal([]byte(`{"max_rpm": 8000, "max_components": 4}`), &limits); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := DefaultValidationLimits()
+	want.MaxRPM = 8000
+	want.MaxComponents = 4
+	if limits != want {
+		t.Errorf("limits = %+v, want %+v", limits, want)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unknown field", `{"max_rpms": 8000}`},
+		{"wrong type", `{"max_rpm": "fast"}`},
+		{"zero rpm", `{"max_rpm": 0}`},
+		{"inverted temps", `{"min_temp": 100, "max_temp": 50}`},
+		{"zero glow", `{"max_glow_duration_ms": 0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l ValidationLimits
+			if err := json.Unmarshal([]byte(tt.input), &l); err == nil {
+				t.Errorf("expected error, got %+v", l)
+			}
+		})
+	}
+}
+
+func TestLoadValidationLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"min_temp": -40, "max_glow_duration_ms": 120000}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	limits, err := LoadValidationLimits(path)
+	if err != nil {
+		t.Fatalf("LoadValidationLimits failed: %v", err)
+	}
+	if limits.MinTemp != -40 || limits.MaxGlowDurationMs != 120000 || limits.MaxRPM != 6000 {
+		t.Errorf("limits = %+v", limits)
+	}
+
+	if _, err := LoadValidationLimits(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults for LinkMonitor
+const (
+	DefaultLinkWindow     = 30 * time.Second // Errors older than this no longer count
+	DefaultLinkStaleAfter = 5 * time.Second  // Silence after which the link is down
+)
+
+// LinkLevel is the coarse rating of a LinkQuality score
+type LinkLevel int
+
+// Link levels, from no data yet to no data any more
+const (
+	LinkUnknown LinkLevel = iota // Nothing received yet
+	LinkGood                     // Score 80-100
+	LinkFair                     // Score 50-79
+	LinkPoor                     // Score below 50
+	LinkDown                     // Nothing received for StaleAfter
+)
+
+// String returns the level name (e.g. "GOOD")
+func (l LinkLevel) String() string {
+	switch l {
+	case LinkGood:
+		return "GOOD"
+	case LinkFair:
+		return "FAIR"
+	case LinkPoor:
+		return "POOR"
+	case LinkDown:
+		return "DOWN"
+	}
+	return "UNKNOWN"
+}
+
+// LinkQuality is a composite rating of the physical link
+type LinkQuality struct {
+	Score   int       // 0 (unusable, or nothing received) to 100 (clean)
+	Level   LinkLevel // Rating of Score
+	CRCRate float64   // CRC errors per received frame in the window (0-1)
+	Resyncs int       // Framing errors (lost synchronization) in the window
+	RTT     time.Duration
+	HasRTT  bool
+	Silence time.Duration // Time since the last packet
+	Reason  string        // Largest contributor to the score, empty when clean
+}
+
+// linkBucket counts one second of link activity
+type linkBucket struct {
+	second  int64
+	frames  int
+	crc     int
+	resyncs int
+}
+
+// LinkMonitor rates link quality from CRC errors, resynchronizations, ping
+// round-trip times and how long the link has been silent. Feed it every
+// decoded packet, decode error and measured RTT; Quality combines the
+// counts from the last Window into a 0-100 score:
+//
+//   - CRC errors cost 5 points per percent of frames, up to 50
+//   - Resyncs (framing errors such as an unexpected END byte or an invalid
+//     length) cost 5 points each, up to 25
+//   - A ping RTT above 200ms costs 10 points, above 500ms 20, above 1s 30
+//   - No packet for StaleAfter means the link is down (score 0)
+//
+// A LinkMonitor is safe for concurrent use.
+type LinkMonitor struct {
+	Window     time.Duration
+	StaleAfter time.Duration
+
+	mu         sync.Mutex
+	buckets    []linkBucket
+	lastPacket time.Time
+	rtt        time.Duration
+	rttAt      time.Time
+}
+
+// NewLinkMonitor creates a monitor with the default window and stale time
+func NewLinkMonitor() *LinkMonitor {
+	return &LinkMonitor{Window: DefaultLinkWindow, StaleAfter: DefaultLinkStaleAfter}
+}
+
+// bucket returns the bucket for at, dropping buckets outside the window.
+// Callers hold l.mu.
+func (l *LinkMonitor) bucket(at time.Time) *linkBucket {
+	second := at.Unix()
+	l.prune(at)
+	if n := len(l.buckets); n > 0 && l.buckets[n-1].second >= second {
+		return &l.buckets[n-1]
+	}
+	l.buckets = append(l.buckets, linkBucket{second: second})
+	return &l.buckets[len(l.buckets)-1]
+}
+
+// prune drops buckets older than the window. Callers hold l.mu.
+func (l *LinkMonitor) prune(now time.Time) {
+	oldest := now.Add(-l.Window).Unix()
+	i := 0
+	for i < len(l.buckets) && l.buckets[i].second < oldest {
+		i++
+	}
+	l.buckets = l.buckets[i:]
+}
+
+// RecordPacket counts a successfully decoded packet
+func (l *LinkMonitor) RecordPacket(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucket(at).frames++
+	if at.After(l.lastPacket) {
+		l.lastPacket = at
+	}
+}
+
+// RecordDecodeError counts a decoder error: a *CRCError is a corrupted
+// frame, anything else a loss of framing synchronization
+func (l *LinkMonitor) RecordDecodeError(at time.Time, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(at)
+	var crcErr *CRCError
+	if errors.As(err, &crcErr) {
+		b.frames++
+		b.crc++
+	} else {
+		b.resyncs++
+	}
+}
+
+// RecordRTT records a measured request/response round-trip time. An RTT
+// counts until the window passes without a new one.
+func (l *LinkMonitor) RecordRTT(at time.Time, rtt time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rtt = rtt
+	l.rttAt = at
+}
+
+// Quality rates the link as of now
+func (l *LinkMonitor) Quality(now time.Time) LinkQuality {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	q := LinkQuality{Score: 100}
+	var frames, crc int
+	for _, b := range l.buckets {
+		frames += b.frames
+		crc += b.crc
+		q.Resyncs += b.resyncs
+	}
+	if frames > 0 {
+		q.CRCRate = float64(crc) / float64(frames)
+	}
+	if !l.rttAt.IsZero() && now.Sub(l.rttAt) <= l.Window {
+		q.RTT, q.HasRTT = l.rtt, true
+	}
+
+	if l.lastPacket.IsZero() {
+		q.Score, q.Level = 0, LinkUnknown
+		if q.Resyncs > 0 || crc > 0 {
+			q.Score, q.Level, q.Reason = 0, LinkDown, "no valid packets"
+		}
+		return q
+	}
+	q.Silence = now.Sub(l.lastPacket)
+	if q.Silence >= l.StaleAfter {
+		q.Score, q.Level = 0, LinkDown
+		q.Reason = fmt.Sprintf("no data for %s", q.Silence.Truncate(time.Second))
+		return q
+	}
+
+	// Each penalty lowers the score; the largest one is the reason
+	worst := 0
+	penalize := func(points int, reason string) {
+		q.Score -= points
+		if points > worst {
+			worst, q.Reason = points, reason
+		}
+	}
+	penalize(min(50, int(q.CRCRate*500+0.5)), fmt.Sprintf("CRC errors %.1f%%", q.CRCRate*100))
+	penalize(min(25, q.Resyncs*5), fmt.Sprintf("%d resyncs", q.Resyncs))
+	if q.HasRTT {
+		rttPts := 0
+		switch {
+		case q.RTT > time.Second:
+			rttPts = 30
+		case q.RTT > 500*time.Millisecond:
+			rttPts = 20
+		case q.RTT > 200*time.Millisecond:
+			rttPts = 10
+		}
+		penalize(rttPts, fmt.Sprintf("RTT %dms", q.RTT.Milliseconds()))
+	}
+
+	q.Score = max(q.Score, 0)
+	switch {
+	case q.Score >= 80:
+		q.Level = LinkGood
+	case q.Score >= 50:
+		q.Level = LinkFair
+	default:
+		q.Level = LinkPoor
+	}
+	return q
+}
+
+// Reset forgets all recorded activity
+func (l *LinkMonitor) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets = nil
+	l.lastPacket = time.Time{}
+	l.rtt, l.rttAt = 0, time.Time{}
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLinkMonitor_Clean(t *testing.T) {
+	l := NewLinkMonitor()
+	start := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	if q := l.Quality(start); q.Level != LinkUnknown {
+		t.Errorf("level before any packet = %s, want UNKNOWN", q.Level)
+	}
+
+	for i := 0; i < 100; i++ {
+		l.RecordPacket(start.Add(time.Duration(i) * 10 * time.Millisecond))
+	}
+	q := l.Quality(start.Add(time.Second))
+	if q.Score != 100 || q.Level != LinkGood || q.Reason != "" {
+		t.Errorf("clean link = %d %s %q, want 100 GOOD", q.Score, q.Level, q.Reason)
+	}
+}
+
+func TestLinkMonitor_Penalties(t *testing.T) {
+	start := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	crc := &CRCError{Expected: 1, Got: 2}
+	framing := errors.New("unexpected END byte in state PAYLOAD")
+
+	tests := []struct {
+		name   string
+		record func(l *LinkMonitor)
+		score  int
+		level  LinkLevel
+		reason string
+	}{
+		{
+			"crc errors",
+			func(l *LinkMonitor) {
+				for i := 0; i < 95; i++ {
+					l.RecordPacket(start)
+				}
+				for i := 0; i < 5; i++ {
+					l.RecordDecodeError(start, crc)
+				}
+			},
+			75, LinkFair, "CRC errors 5.0%",
+		},
+		{
+			"resyncs",
+			func(l *LinkMonitor) {
+				l.RecordPacket(start)
+				for i := 0; i < 10; i++ {
+					l.RecordDecodeError(start, framing)
+				}
+			},
+			75, LinkFair, "10 resyncs",
+		},
+		{
+			"slow rtt",
+			func(l *LinkMonitor) {
+				l.RecordPacket(start)
+				l.RecordRTT(start, 1500*time.Millisecond)
+				l.RecordDecodeError(start, framing)
+			},
+			65, LinkFair, "RTT 1500ms",
+		},
+		{
+			"combined",
+			func(l *LinkMonitor) {
+				for i := 0; i < 80; i++ {
+					l.RecordPacket(start)
+				}
+				for i := 0; i < 20; i++ {
+					l.RecordDecodeError(start, crc)
+				}
+				l.RecordRTT(start, 300*time.Millisecond)
+			},
+			40, LinkPoor, "CRC errors 20.0%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLinkMonitor()
+			tt.record(l)
+			q := l.Quality(start.Add(time.Second))
+			if q.Score != tt.score || q.Level != tt.level || q.Reason != tt.reason {
+				t.Errorf("quality = %d %s %q, want %d %s %q", q.Score, q.Level, q.Reason, tt.score, tt.level, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLinkMonitor_WindowAndStale(t *testing.T) {
+	l := NewLinkMonitor()
+	start := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		l.RecordDecodeError(start, errors.New("invalid length"))
+	}
+	l.RecordRTT(start, 2*time.Second)
+	if q := l.Quality(start); q.Level != LinkDown {
+		t.Errorf("level with only errors = %s, want DOWN", q.Level)
+	}
+
+	// Errors and RTT age out of the window while packets keep arriving
+	later := start.Add(l.Window + 2*time.Second)
+	l.RecordPacket(later)
+	if q := l.Quality(later); q.Score != 100 || q.HasRTT {
+		t.Errorf("quality after window = %d (rtt %v), want 100 without RTT", q.Score, q.HasRTT)
+	}
+
+	q := l.Quality(later.Add(l.StaleAfter))
+	if q.Level != LinkDown || q.Score != 0 || q.Reason != "no data for 5s" {
+		t.Errorf("silent link = %d %s %q, want 0 DOWN \"no data for 5s\"", q.Score, q.Level, q.Reason)
+	}
+
+	l.Reset()
+	if q := l.Quality(later); q.Level != LinkUnknown {
+		t.Errorf("level after Reset = %s, want UNKNOWN", q.Level)
+	}
+}
@@ -0,0 +1,487 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import "fmt"
+
+// Typed payload structs give compile-time checked access to message fields.
+// Decode* functions check the message type and required keys (per the
+// schema registry) and convert the CBOR map; Encode builds a Packet for the
+// given address. Optional fields are pointers and are nil when absent.
+
+// decodePayload checks that p has the expected type and all required keys
+func decodePayload(p *Packet, msgType uint8) (map[int]interface{}, error) {
+	if err := p.ParseError(); err != nil {
+		return nil, err
+	}
+	if p.Type() != msgType {
+		return nil, fmt.Errorf("expected %s, got %s", FormatMessageType(msgType), FormatMessageType(p.Type()))
+	}
+	m := p.PayloadMap()
+	if schema, ok := LookupSchema(msgType); ok {
+		for _, field := range schema.Fields {
+			if _, present := m[field.Key]; field.Required && !present {
+				return nil, fmt.Errorf("%s missing required key %d (%s)", FormatMessageType(msgType), field.Key, field.Name)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Optional field helpers
+
+func optInt32(m map[int]interface{}, key int) *int32 {
+	if v, ok := GetMapInt(m, key); ok {
+		i := int32(v)
+		return &i
+	}
+	return nil
+}
+
+func optUint32(m map[int]interface{}, key int) *uint32 {
+	if v, ok := GetMapUint(m, key); ok {
+		u := uint32(v)
+		return &u
+	}
+	return nil
+}
+
+func optFloat(m map[int]interface{}, key int) *float64 {
+	if v, ok := GetMapFloat(m, key); ok {
+		return &v
+	}
+	return nil
+}
+
+func optBool(m map[int]interface{}, key int) *bool {
+	if v, ok := GetMapBool(m, key); ok {
+		return &v
+	}
+	return nil
+}
+
+// ============================================================
+// Telemetry Data
+// ============================================================
+
+// StateData is the STATE_DATA payload (0x30)
+type StateData struct {
+	Error     bool
+	Code      ErrorCode
+	State     SysState
+	Timestamp uint64 // Device uptime in milliseconds
+}
+
+// DecodeStateData decodes a STATE_DATA packet
+func DecodeStateData(p *Packet) (StateData, error) {
+	m, err := decodePayload(p, MsgStateData)
+	if err != nil {
+		return StateData{}, err
+	}
+	errorFlag, _ := GetMapBool(m, 0)
+	code, _ := GetMapInt(m, 1)
+	state, _ := GetMapUint(m, 2)
+	timestamp, _ := GetMapUint(m, 3)
+	return StateData{Error: errorFlag, Code: ErrorCode(code), State: SysState(state), Timestamp: timestamp}, nil
+}
+
+// Encode builds a STATE_DATA packet from address
+func (d StateData) Encode(address uint64) *Packet {
+	return NewPacketWithPayload(address, MsgStateData, map[int]interface{}{
+		0: d.Error,
+		1: int64(d.Code),
+		2: uint64(d.State),
+		3: d.Timestamp,
+	})
+}
+
+// MotorData is the MOTOR_DATA payload (0x31)
+type MotorData struct {
+	Motor     uint8
+	Timestamp uint64
+	RPM       int32
+	Target    int32
+	MaxRPM    *int32
+	MinRPM    *int32
+	PWM       *uint32 // Microseconds
+	PWMMax    *uint32 // Microseconds
+}
+
+// DecodeMotorData decodes a MOTOR_DATA packet
+func DecodeMotorData(p *Packet) (MotorData, error) {
+	m, err := decodePayload(p, MsgMotorData)
+	if err != nil {
+		return MotorData{}, err
+	}
+	motor, _ := GetMapUint(m, 0)
+	timestamp, _ := GetMapUint(m, 1)
+	rpm, _ := GetMapInt(m, 2)
+	target, _ := GetMapInt(m, 3)
+	return MotorData{
+		Motor:     uint8(motor),
+		Timestamp: timestamp,
+		RPM:       int32(rpm),
+		Target:    int32(target),
+		MaxRPM:    optInt32(m, 4),
+		MinRPM:    optInt32(m, 5),
+		PWM:       optUint32(m, 6),
+		PWMMax:    optUint32(m, 7),
+	}, nil
+}
+
+// Encode builds a MOTOR_DATA packet from address
+func (d MotorData) Encode(address uint64) *Packet {
+	payload := map[int]interface{}{
+		0: uint64(d.Motor),
+		1: d.Timestamp,
+		2: int64(d.RPM),
+		3: int64(d.Target),
+	}
+	if d.MaxRPM != nil {
+		payload[4] = int64(*d.MaxRPM)
+	}
+	if d.MinRPM != nil {
+		payload[5] = int64(*d.MinRPM)
+	}
+	if d.PWM != nil {
+		payload[6] = uint64(*d.PWM)
+	}
+	if d.PWMMax != nil {
+		payload[7] = uint64(*d.PWMMax)
+	}
+	return NewPacketWithPayload(address, MsgMotorData, payload)
+}
+
+// PumpData is the PUMP_DATA payload (0x32)
+type PumpData struct {
+	Pump      uint8
+	Timestamp uint64
+	Event     PumpEvent
+	Rate      *int32 // Milliseconds
+}
+
+// DecodePumpData decodes a PUMP_DATA packet
+func DecodePumpData(p *Packet) (PumpData, error) {
+	m, err := decodePayload(p, MsgPumpData)
+	if err != nil {
+		return PumpData{}, err
+	}
+	pump, _ := GetMapUint(m, 0)
+	timestamp, _ := GetMapUint(m, 1)
+	event, _ := GetMapUint(m, 2)
+	return PumpData{Pump: uint8(pump), Timestamp: timestamp, Event: PumpEvent(event), Rate: optInt32(m, 3)}, nil
+}
+
+// Encode builds a PUMP_DATA packet from address
+func (d PumpData) Encode(address uint64) *Packet {
+	payload := map[int]interface{}{
+		0: uint64(d.Pump),
+		1: d.Timestamp,
+		2: uint64(d.Event),
+	}
+	if d.Rate != nil {
+		payload[3] = int64(*d.Rate)
+	}
+	return NewPacketWithPayload(address, MsgPumpData, payload)
+}
+
+// GlowData is the GLOW_DATA payload (0x33)
+type GlowData struct {
+	Glow      uint8
+	Timestamp uint64
+	Lit       bool
+}
+
+// DecodeGlowData decodes a GLOW_DATA packet
+func DecodeGlowData(p *Packet) (GlowData, error) {
+	m, err := decodePayload(p, MsgGlowData)
+	if err != nil {
+		return GlowData{}, err
+	}
+	glow, _ := GetMapUint(m, 0)
+	timestamp, _ := GetMapUint(m, 1)
+	lit, _ := GetMapBool(m, 2)
+	return GlowData{Glow: uint8(glow), Timestamp: timestamp, Lit: lit}, nil
+}
+
+// Encode builds a GLOW_DATA packet from address
+func (d GlowData) Encode(address uint64) *Packet {
+	return NewPacketWithPayload(address, MsgGlowData, map[int]interface{}{
+		0: uint64(d.Glow),
+		1: d.Timestamp,
+		2: d.Lit,
+	})
+}
+
+// TempData is the TEMP_DATA payload (0x34)
+type TempData struct {
+	Thermometer       uint8
+	Timestamp         uint64
+	Reading           float64 // Celsius
+	RPMControl        *bool
+	WatchedMotor      *int32
+	TargetTemperature *float64 // Celsius
+}
+
+// DecodeTempData decodes a TEMP_DATA packet
+func DecodeTempData(p *Packet) (TempData, error) {
+	m, err := decodePayload(p, MsgTempData)
+	if err != nil {
+		return TempData{}, err
+	}
+	therm, _ := GetMapUint(m, 0)
+	timestamp, _ := GetMapUint(m, 1)
+	reading, _ := GetMapFloat(m, 2)
+	return TempData{
+		Thermometer:       uint8(therm),
+		Timestamp:         timestamp,
+		Reading:           reading,
+		RPMControl:        optBool(m, 3),
+		WatchedMotor:      optInt32(m, 4),
+		TargetTemperature: optFloat(m, 5),
+	}, nil
+}
+
+// Encode builds a TEMP_DATA packet from address
+func (d TempData) Encode(address uint64) *Packet {
+	payload := map[int]interface{}{
+		0: uint64(d.Thermometer),
+		1: d.Timestamp,
+		2: d.Reading,
+	}
+	if d.RPMControl != nil {
+		payload[3] = *d.RPMControl
+	}
+	if d.WatchedMotor != nil {
+		payload[4] = int64(*d.WatchedMotor)
+	}
+	if d.TargetTemperature != nil {
+		payload[5] = *d.TargetTemperature
+	}
+	return NewPacketWithPayload(address, MsgTempData, payload)
+}
+
+// DeviceAnnounce is the DEVICE_ANNOUNCE payload (0x35)
+type DeviceAnnounce struct {
+	MotorCount       uint8
+	ThermometerCount uint8
+	PumpCount        uint8
+	GlowCount        uint8
+}
+
+// DecodeDeviceAnnounce decodes a DEVICE_ANNOUNCE packet
+func DecodeDeviceAnnounce(p *Packet) (DeviceAnnounce, error) {
+	m, err := decodePayload(p, MsgDeviceAnnounce)
+	if err != nil {
+		return DeviceAnnounce{}, err
+	}
+	motors, _ := GetMapUint(m, 0)
+	therms, _ := GetMapUint(m, 1)
+	pumps, _ := GetMapUint(m, 2)
+	glows, _ := GetMapUint(m, 3)
+	return DeviceAnnounce{
+		MotorCount:       uint8(motors),
+		ThermometerCount: uint8(therms),
+		PumpCount:        uint8(pumps),
+		GlowCount:        uint8(glows),
+	}, nil
+}
+
+// IsEndMarker reports whether this is the end-of-discovery marker (all
+// counts zero; sent from the stateless address)
+func (d DeviceAnnounce) IsEndMarker() bool {
+	return d == DeviceAnnounce{}
+}
+
+// Encode builds a DEVICE_ANNOUNCE packet from address
+func (d DeviceAnnounce) Encode(address uint64) *Packet {
+	return NewPacketWithPayload(address, MsgDeviceAnnounce, map[int]interface{}{
+		0: uint64(d.MotorCount),
+		1: uint64(d.ThermometerCount),
+		2: uint64(d.PumpCount),
+		3: uint64(d.GlowCount),
+	})
+}
+
+// PingResponse is the PING_RESPONSE payload (0x3F)
+type PingResponse struct {
+	Uptime uint64 // Milliseconds
+}
+
+// DecodePingResponse decodes a PING_RESPONSE packet
+func DecodePingResponse(p *Packet) (PingResponse, error) {
+	m, err := decodePayload(p, MsgPingResponse)
+	if err != nil {
+		return PingResponse{}, err
+	}
+	uptime, _ := GetMapUint(m, 0)
+	return PingResponse{Uptime: uptime}, nil
+}
+
+// Encode builds a PING_RESPONSE packet from address
+func (d PingResponse) Encode(address uint64) *Packet {
+	return NewPacketWithPayload(address, MsgPingResponse, map[int]interface{}{0: d.Uptime})
+}
+
+// DeviceInfo returns the identity fields a device reports in PING_RESPONSE
+// besides its uptime, such as firmware version and build, in key order.
+// They are named from the schema registry (by key number until registered
+// there), so new fields appear without code changes. It returns nil for
+// other message types.
+func DeviceInfo(p *Packet) []PayloadField {
+	if p.Type() != MsgPingResponse {
+		return nil
+	}
+	return deviceInfo(p.PayloadMap())
+}
+
+// deviceInfo returns the identity fields of a PING_RESPONSE payload
+func deviceInfo(m map[int]interface{}) []PayloadField {
+	var info []PayloadField
+	for _, f := range payloadFields(MsgPingResponse, m) {
+		if f.Key != 0 {
+			info = append(info, f)
+		}
+	}
+	return info
+}
+
+// ============================================================
+// Errors
+// ============================================================
+
+// ErrorInvalidCmd is the ERROR_INVALID_CMD payload (0xE0)
+type ErrorInvalidCmd struct {
+	Code int32 // 1 = invalid parameter value, 2 = invalid device index
+}
+
+// DecodeErrorInvalidCmd decodes an ERROR_INVALID_CMD packet
+func DecodeErrorInvalidCmd(p *Packet) (ErrorInvalidCmd, error) {
+	m, err := decodePayload(p, MsgErrorInvalidCmd)
+	if err != nil {
+		return ErrorInvalidCmd{}, err
+	}
+	code, _ := GetMapInt(m, 0)
+	return ErrorInvalidCmd{Code: int32(code)}, nil
+}
+
+// Encode builds an ERROR_INVALID_CMD packet from address
+func (d ErrorInvalidCmd) Encode(address uint64) *Packet {
+	return NewPacketWithPayload(address, MsgErrorInvalidCmd, map[int]interface{}{0: int64(d.Code)})
+}
+
+// ErrorStateReject is the ERROR_STATE_REJECT payload (0xE1)
+type ErrorStateReject struct {
+	State SysState // State that rejected the command
+}
+
+// DecodeErrorStateReject decodes an ERROR_STATE_REJECT packet
+func DecodeErrorStateReject(p *Packet) (ErrorStateReject, error) {
+	m, err := decodePayload(p, MsgErrorStateReject)
+	if err != nil {
+		return ErrorStateReject{}, err
+	}
+	state, _ := GetMapUint(m, 0)
+	return ErrorStateReject{State: SysState(state)}, nil
+}
+
+// Encode builds an ERROR_STATE_REJECT packet from address
+func (d ErrorStateReject) Encode(address uint64) *Packet {
+	return NewPacketWithPayload(address, MsgErrorStateReject, map[int]interface{}{0: uint64(d.State)})
+}
+
+// ============================================================
+// Control Commands
+// ============================================================
+
+// StateCommand is the STATE_COMMAND payload (0x20)
+type StateCommand struct {
+	Mode     Mode
+	Argument *int64 // FAN: target RPM, HEAT: pump rate (ms)
+}
+
+// DecodeStateCommand decodes a STATE_COMMAND packet
+func DecodeStateCommand(p *Packet) (StateCommand, error) {
+	m, err := decodePayload(p, MsgStateCommand)
+	if err != nil {
+		return StateCommand{}, err
+	}
+	mode, _ := GetMapUint(m, 0)
+	cmd := StateCommand{Mode: Mode(mode)}
+	if arg, ok := GetMapInt(m, 1); ok {
+		cmd.Argument = &arg
+	}
+	return cmd, nil
+}
+
+// Encode builds a STATE_COMMAND packet for address
+func (c StateCommand) Encode(address uint64) *Packet {
+	return NewStateCommand(address, uint8(c.Mode), c.Argument)
+}
+
+// MotorCommand is the MOTOR_COMMAND payload (0x21)
+type MotorCommand struct {
+	Motor uint8
+	RPM   int32
+}
+
+// DecodeMotorCommand decodes a MOTOR_COMMAND packet
+func DecodeMotorCommand(p *Packet) (MotorCommand, error) {
+	m, err := decodePayload(p, MsgMotorCommand)
+	if err != nil {
+		return MotorCommand{}, err
+	}
+	motor, _ := GetMapUint(m, 0)
+	rpm, _ := GetMapInt(m, 1)
+	return MotorCommand{Motor: uint8(motor), RPM: int32(rpm)}, nil
+}
+
+// Encode builds a MOTOR_COMMAND packet for address
+func (c MotorCommand) Encode(address uint64) *Packet {
+	return NewMotorCommand(address, c.Motor, c.RPM)
+}
+
+// PumpCommand is the PUMP_COMMAND payload (0x22)
+type PumpCommand struct {
+	Pump   uint8
+	RateMs int32
+}
+
+// DecodePumpCommand decodes a PUMP_COMMAND packet
+func DecodePumpCommand(p *Packet) (PumpCommand, error) {
+	m, err := decodePayload(p, MsgPumpCommand)
+	if err != nil {
+		return PumpCommand{}, err
+	}
+	pump, _ := GetMapUint(m, 0)
+	rate, _ := GetMapInt(m, 1)
+	return PumpCommand{Pump: uint8(pump), RateMs: int32(rate)}, nil
+}
+
+// Encode builds a PUMP_COMMAND packet for address
+func (c PumpCommand) Encode(address uint64) *Packet {
+	return NewPumpCommand(address, c.Pump, c.RateMs)
+}
+
+// GlowCommand is the GLOW_COMMAND payload (0x23)
+type GlowCommand struct {
+	Glow       uint8
+	DurationMs int32
+}
+
+// DecodeGlowCommand decodes a GLOW_COMMAND packet
+func DecodeGlowCommand(p *Packet) (GlowCommand, error) {
+	m, err := decodePayload(p, MsgGlowCommand)
+	if err != nil {
+		return GlowCommand{}, err
+	}
+	glow, _ := GetMapUint(m, 0)
+	duration, _ := GetMapInt(m, 1)
+	return GlowCommand{Glow: uint8(glow), DurationMs: int32(duration)}, nil
+}
+
+// Encode builds a GLOW_COMMAND packet for address
+func (c GlowCommand) Encode(address uint64) *Packet {
+	return NewGlowCommand(address, c.Glow, c.DurationMs)
+}
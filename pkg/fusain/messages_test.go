@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTrip encodes p to wire bytes and decodes it again
+func roundTrip(t *testing.T, p *Packet) *Packet {
+	t.Helper()
+	decoded, err := DecodePacket(MustEncodePacket(p))
+	if err != nil {
+		t.Fatalf("DecodePacket failed: %v", err)
+	}
+	return decoded
+}
+
+func TestTypedMessages_RoundTrip(t *testing.T) {
+	const addr = 0x123456789ABCDEF0
+
+	tests := []struct {
+		name   string
+		value  interface{ Encode(uint64) *Packet }
+		decode func(*Packet) (interface{}, error)
+	}{
+		{"StateData", StateData{Error: true, Code: ErrorFlameOut, State: SysStateError, Timestamp: 12345},
+			func(p *Packet) (interface{}, error) { return DecodeStateData(p) }},
+		{"MotorData", MotorData{Motor: 1, Timestamp: 100, RPM: 2500, Target: 3000, MaxRPM: ptr(int32(6000)), PWM: ptr(uint32(500))},
+			func(p *Packet) (interface{}, error) { return DecodeMotorData(p) }},
+		{"PumpData", PumpData{Pump: 0, Timestamp: 100, Event: PumpEventPulseEnd, Rate: ptr(int32(250))},
+			func(p *Packet) (interface{}, error) { return DecodePumpData(p) }},
+		{"GlowData", GlowData{Glow: 0, Timestamp: 100, Lit: true},
+			func(p *Packet) (interface{}, error) { return DecodeGlowData(p) }},
+		{"TempData", TempData{Thermometer: 0, Timestamp: 100, Reading: 215.5, RPMControl: ptr(true), TargetTemperature: ptr(220.0)},
+			func(p *Packet) (interface{}, error) { return DecodeTempData(p) }},
+		{"DeviceAnnounce", DeviceAnnounce{MotorCount: 1, ThermometerCount: 2, PumpCount: 1, GlowCount: 1},
+			func(p *Packet) (interface{}, error) { return DecodeDeviceAnnounce(p) }},
+		{"PingResponse", PingResponse{Uptime: 60000},
+			func(p *Packet) (interface{}, error) { return DecodePingResponse(p) }},
+		{"ErrorInvalidCmd", ErrorInvalidCmd{Code: 2},
+			func(p *Packet) (interface{}, error) { return DecodeErrorInvalidCmd(p) }},
+		{"ErrorStateReject", ErrorStateReject{State: SysStateHeating},
+			func(p *Packet) (interface{}, error) { return DecodeErrorStateReject(p) }},
+		{"StateCommand", StateCommand{Mode: ModeFan, Argument: ptr(int64(3000))},
+			func(p *Packet) (interface{}, error) { return DecodeStateCommand(p) }},
+		{"MotorCommand", MotorCommand{Motor: 0, RPM: 2000},
+			func(p *Packet) (interface{}, error) { return DecodeMotorCommand(p) }},
+		{"PumpCommand", PumpCommand{Pump: 0, RateMs: 500},
+			func(p *Packet) (interface{}, error) { return DecodePumpCommand(p) }},
+		{"GlowCommand", GlowCommand{Glow: 0, DurationMs: 30000},
+			func(p *Packet) (interface{}, error) { return DecodeGlowCommand(p) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := roundTrip(t, tt.value.Encode(addr))
+			if p.Address() != addr {
+				t.Errorf("Address() = 0x%X, want 0x%X", p.Address(), uint64(addr))
+			}
+			got, err := tt.decode(p)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestTypedMessages_WrongType(t *testing.T) {
+	p := PingResponse{Uptime: 1}.Encode(0x1)
+	_, err := DecodeStateData(p)
+	if err == nil || !strings.Contains(err.Error(), "expected STATE_DATA") {
+		t.Errorf("Expected type mismatch error, got %v", err)
+	}
+}
+
+func TestTypedMessages_MissingRequiredKey(t *testing.T) {
+	cborPayload := buildCBORPayload(MsgTempData, map[int]interface{}{0: uint64(0), 1: uint64(100)})
+	p := NewPacket(uint8(len(cborPayload)), 0x1, cborPayload, 0)
+
+	_, err := DecodeTempData(p)
+	if err == nil || !strings.Contains(err.Error(), "key 2 (reading)") {
+		t.Errorf("Expected missing reading error, got %v", err)
+	}
+}
+
+func TestDeviceAnnounce_IsEndMarker(t *testing.T) {
+	if !(DeviceAnnounce{}).IsEndMarker() {
+		t.Error("All-zero announce should be the end marker")
+	}
+	if (DeviceAnnounce{MotorCount: 1}).IsEndMarker() {
+		t.Error("Announce with a motor should not be the end marker")
+	}
+}
+
+func TestDeviceInfo(t *testing.T) {
+	p := NewPacketWithPayload(1, MsgPingResponse, map[int]interface{}{
+		0: uint64(60000), 2: "a1b2c3", 1: "2.4.0",
+	})
+	info := DeviceInfo(roundTrip(t, p))
+	if len(info) != 2 || info[0].Key != 1 || info[0].FormatValue() != "2.4.0" || info[1].Name != "2" || info[1].FormatValue() != "a1b2c3" {
+		t.Errorf("Expected the fields after uptime in key order, got %+v", info)
+	}
+	if !strings.Contains(FormatPacket(p), "Uptime: 1 minute, 1: 2.4.0, 2: a1b2c3") {
+		t.Errorf("Expected identity fields in the formatted packet:\n%s", FormatPacket(p))
+	}
+
+	if info := DeviceInfo(PingResponse{Uptime: 1}.Encode(1)); info != nil {
+		t.Errorf("Expected no identity fields from a plain ping response, got %+v", info)
+	}
+	if info := DeviceInfo(GlowData{Lit: true}.Encode(1)); info != nil {
+		t.Errorf("Expected nil for other message types, got %+v", info)
+	}
+}
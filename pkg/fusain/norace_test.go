@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+//go:build !race
+
+package fusain
+
+// raceEnabled reports whether the race detector is on; its instrumentation
+// changes allocation counts
+const raceEnabled = false
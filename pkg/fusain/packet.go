@@ -3,7 +3,10 @@
 
 package fusain
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // Packet represents a decoded Fusain protocol packet
 type Packet struct {
@@ -12,12 +15,17 @@ type Packet struct {
 	cborPayload []byte // Raw CBOR bytes: [msg_type, payload_map]
 	crc         uint16
 	timestamp   time.Time
+	raw         []byte // Wire frame as received (Decoder.RetainRaw)
 
-	// Cached parsed values (lazy parsing)
+	// Cached parsed values (lazy parsing, safe for concurrent readers)
+	parseOnce  sync.Once
 	msgType    uint8
 	payloadMap map[int]interface{}
-	parsed     bool
 	parseErr   error
+
+	// Lazily encoded CBOR for packets built from a payload map
+	encodeOnce sync.Once
+	encoded    []byte
 }
 
 // NewPacket creates a new packet with the given fields
@@ -34,25 +42,47 @@ func NewPacket(length uint8, address uint64, cborPayload []byte, crc uint16) *Pa
 // NewPacketWithPayload creates a new packet from message type and payload map.
 // The CBOR encoding and CRC are computed automatically.
 func NewPacketWithPayload(address uint64, msgType uint8, payload map[int]interface{}) *Packet {
-	return &Packet{
+	p := &Packet{
 		address:    address,
 		msgType:    msgType,
 		payloadMap: payload,
-		parsed:     true,
 		timestamp:  time.Now(),
 	}
+	p.parseOnce.Do(func() {})
+	return p
 }
 
-// ensureParsed parses the CBOR payload if not already done
-func (p *Packet) ensureParsed() {
-	if p.parsed {
-		return
+// RawBytes returns the frame exactly as received, from START to END and
+// including byte stuffing. It is nil unless the packet was decoded with
+// Decoder.RetainRaw set. The slice is owned by the packet; do not modify it.
+func (p *Packet) RawBytes() []byte {
+	return p.raw
+}
+
+// WireFrame returns the packet's frame from START to END, including byte
+// stuffing: the bytes as received when the decoder retained them, otherwise
+// the frame rebuilt from the address and CBOR payload. Returns nil for
+// packets without a payload.
+func (p *Packet) WireFrame() []byte {
+	if p.raw != nil {
+		return p.raw
 	}
-	p.parsed = true
-	if len(p.cborPayload) == 0 {
-		return
+	payload := p.PayloadRaw()
+	if payload == nil || len(payload) > MaxPayloadSize {
+		return nil
 	}
-	p.msgType, p.payloadMap, p.parseErr = ParseCBORMessage(p.cborPayload)
+	return encodeFrame(p.address, payload)
+}
+
+// ensureParsed parses the CBOR payload on first use.
+// Safe to call from multiple goroutines.
+func (p *Packet) ensureParsed() {
+	p.parseOnce.Do(func() {
+		if len(p.cborPayload) == 0 {
+			return
+		}
+		p.msgType, p.payloadMap, p.parseErr = ParseCBORMessage(p.cborPayload)
+	})
 }
 
 // Length returns the packet's CBOR payload length
@@ -71,12 +101,33 @@ func (p *Packet) Type() uint8 {
 	return p.msgType
 }
 
-// Payload returns the raw CBOR payload bytes
+// Payload returns the raw CBOR payload bytes as received (nil for packets
+// built with NewPacketWithPayload; use PayloadRaw for those)
 func (p *Packet) Payload() []byte {
 	return p.cborPayload
 }
 
-// PayloadMap returns the decoded CBOR payload map (nil for empty payloads)
+// PayloadRaw returns the CBOR payload bytes without copying or decoding.
+// The slice aliases the packet's buffer and must not be modified. Packets
+// built with NewPacketWithPayload are encoded on the first call.
+func (p *Packet) PayloadRaw() []byte {
+	if p.cborPayload != nil {
+		return p.cborPayload
+	}
+	p.encodeOnce.Do(func() {
+		if p.payloadMap == nil && p.msgType == 0 {
+			return
+		}
+		if raw, err := encodeCBORPayload(p.msgType, p.payloadMap); err == nil {
+			p.encoded = raw
+		}
+	})
+	return p.encoded
+}
+
+// PayloadMap returns the decoded CBOR payload map (nil for empty payloads).
+// The map is decoded once and shared by all callers, so it must be treated
+// as read-only; it is then safe for concurrent readers.
 func (p *Packet) PayloadMap() map[int]interface{} {
 	p.ensureParsed()
 	return p.payloadMap
@@ -107,3 +158,19 @@ func (p *Packet) IsBroadcast() bool {
 func (p *Packet) IsStateless() bool {
 	return p.address == AddressStateless
 }
+
+// IsEmergency returns true for emergency-stop traffic: a STATE_COMMAND with
+// mode EMERGENCY, or a STATE_DATA reporting the E_STOP state. Pipelines
+// that batch, conflate or rate-limit packets must pass these through
+// immediately.
+func (p *Packet) IsEmergency() bool {
+	switch p.Type() {
+	case MsgStateCommand:
+		mode, ok := GetMapUint(p.PayloadMap(), 0)
+		return ok && Mode(mode) == ModeEmergency
+	case MsgStateData:
+		state, ok := GetMapUint(p.PayloadMap(), 2)
+		return ok && SysState(state) == SysStateEstop
+	}
+	return false
+}
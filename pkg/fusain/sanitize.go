@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"fmt"
+	"maps"
+)
+
+// Sanitizer rewrites captured frames so they can be attached to public bug
+// reports. Device addresses are replaced with small sequential ones
+// (0000000000000001, 0000000000000002, ...) in order of first appearance,
+// in the frame header and in payload fields marked as addresses in the
+// schema. Fields marked sensitive are removed. Broadcast and stateless
+// addresses are kept. Frames are re-encoded with a new CRC.
+//
+// A Sanitizer keeps its address mapping across frames, so the devices of
+// one capture stay distinguishable. It is not safe for concurrent use.
+type Sanitizer struct {
+	AnonymizeAddresses bool
+	StripSensitive     bool
+
+	addresses map[uint64]uint64
+}
+
+// NewSanitizer creates a sanitizer that anonymizes addresses and strips
+// sensitive fields
+func NewSanitizer() *Sanitizer {
+	return &Sanitizer{
+		AnonymizeAddresses: true,
+		StripSensitive:     true,
+		addresses:          make(map[uint64]uint64),
+	}
+}
+
+// Address returns the replacement for a device address, assigning the
+// next one the first time an address is seen
+func (s *Sanitizer) Address(address uint64) uint64 {
+	if !s.AnonymizeAddresses || address == AddressBroadcast || address == AddressStateless {
+		return address
+	}
+	if s.addresses == nil {
+		s.addresses = make(map[uint64]uint64)
+	}
+	if mapped, ok := s.addresses[address]; ok {
+		return mapped
+	}
+	mapped := uint64(len(s.addresses) + 1)
+	s.addresses[address] = mapped
+	return mapped
+}
+
+// Addresses returns the mapping from original to replacement addresses
+func (s *Sanitizer) Addresses() map[uint64]uint64 {
+	return maps.Clone(s.addresses)
+}
+
+// Packet returns a sanitized copy of p. Packets whose payload needs no
+// changes keep their CBOR bytes unchanged.
+func (s *Sanitizer) Packet(p *Packet) (*Packet, error) {
+	frame, err := s.encode(p)
+	if err != nil {
+		return nil, err
+	}
+	out, err := DecodePacket(frame)
+	if err != nil {
+		return nil, err
+	}
+	out.timestamp = p.timestamp
+	return out, nil
+}
+
+// Frame decodes a wire frame and returns it sanitized. Frames that fail to
+// decode (including CRC errors) are returned as errors rather than passed
+// through, since their contents cannot be rewritten reliably.
+func (s *Sanitizer) Frame(frame []byte) ([]byte, error) {
+	p, err := DecodePacket(frame)
+	if err != nil {
+		return nil, err
+	}
+	return s.encode(p)
+}
+
+// encode re-encodes p with its address and payload sanitized
+func (s *Sanitizer) encode(p *Packet) ([]byte, error) {
+	address := s.Address(p.Address())
+
+	raw := p.PayloadRaw()
+	if raw == nil {
+		return nil, fmt.Errorf("fusain: packet has no CBOR payload")
+	}
+
+	payload, changed := s.payload(p)
+	if !changed {
+		return encodeFrame(address, raw), nil
+	}
+	return EncodePacket(address, p.Type(), payload)
+}
+
+// payload returns the sanitized payload map and whether it differs from
+// the packet's
+func (s *Sanitizer) payload(p *Packet) (map[int]interface{}, bool) {
+	schema, ok := LookupSchema(p.Type())
+	m := p.PayloadMap()
+	if !ok || m == nil {
+		return m, false
+	}
+
+	var out map[int]interface{}
+	for _, field := range schema.Fields {
+		if _, present := m[field.Key]; !present {
+			continue
+		}
+
+		switch {
+		case field.Sensitive && s.StripSensitive:
+			if out == nil {
+				out = maps.Clone(m)
+			}
+			delete(out, field.Key)
+
+		case field.Address && s.AnonymizeAddresses:
+			original, ok := GetMapUint(m, field.Key)
+			if !ok {
+				continue
+			}
+			if mapped := s.Address(original); mapped != original {
+				if out == nil {
+					out = maps.Clone(m)
+				}
+				out[field.Key] = mapped
+			}
+		}
+	}
+
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
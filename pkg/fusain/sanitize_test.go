@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSanitizer_Addresses(t *testing.T) {
+	s := NewSanitizer()
+
+	state, err := s.Packet(roundTrip(t, StateData{State: SysStateIdle}.Encode(0xAABBCCDD00112233)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Address() != 1 {
+		t.Errorf("first device mapped to %016X, want 1", state.Address())
+	}
+	if !bytes.Equal(state.PayloadRaw(), roundTrip(t, StateData{State: SysStateIdle}.Encode(0x01)).PayloadRaw()) {
+		t.Error("payload without sensitive fields changed")
+	}
+
+	// Payload addresses use the same mapping as frame addresses
+	sub, err := s.Packet(roundTrip(t, NewDataSubscription(AddressStateless, 0xAABBCCDD00112233)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Address() != AddressStateless {
+		t.Errorf("stateless address rewritten to %016X", sub.Address())
+	}
+	if appliance, _ := GetMapUint(sub.PayloadMap(), 0); appliance != 1 {
+		t.Errorf("appliance-address = %016X, want 1", appliance)
+	}
+
+	if got := s.Address(0x0102030405060708); got != 2 {
+		t.Errorf("second device mapped to %d, want 2", got)
+	}
+	if got := s.Address(AddressBroadcast); got != AddressBroadcast {
+		t.Errorf("broadcast mapped to %016X", got)
+	}
+	if m := s.Addresses(); len(m) != 2 || m[0xAABBCCDD00112233] != 1 {
+		t.Errorf("Addresses = %v", m)
+	}
+}
+
+func TestSanitizer_StripSensitive(t *testing.T) {
+	config := NewPacketWithPayload(0x01, MsgMotorConfig, map[int]interface{}{
+		0: uint64(0), 1: uint64(1000), 2: 1.5, 3: 0.25, 4: 0.0,
+	})
+	frame, err := NewSanitizer().Frame(MustEncodePacket(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := DecodePacket(frame)
+	if err != nil {
+		t.Fatalf("sanitized frame does not decode: %v", err)
+	}
+	for _, key := range []int{2, 3, 4} {
+		if _, ok := p.PayloadMap()[key]; ok {
+			t.Errorf("sensitive key %d kept", key)
+		}
+	}
+	if period, _ := GetMapUint(p.PayloadMap(), 1); period != 1000 {
+		t.Errorf("pwm-period = %d, want 1000", period)
+	}
+	if errs := CheckSchema(p); len(errs) != 0 {
+		t.Errorf("sanitized packet fails schema: %v", errs)
+	}
+
+	keep := NewSanitizer()
+	keep.StripSensitive = false
+	keep.AnonymizeAddresses = false
+	frame, _ = keep.Frame(MustEncodePacket(config))
+	if !bytes.Equal(frame, MustEncodePacket(config)) {
+		t.Error("frame changed with sanitizing disabled")
+	}
+}
+
+func TestSanitizer_CorruptFrame(t *testing.T) {
+	frame := MustEncodePacket(NewPingRequest(0x01))
+	frame[len(frame)-2] ^= 0x01 // CRC low byte
+
+	if _, err := NewSanitizer().Frame(frame); err == nil {
+		t.Error("corrupt frame should be rejected")
+	}
+}
+
+func TestSchema_SensitiveFieldsOptional(t *testing.T) {
+	for msgType, schema := range schemaRegistry {
+		for _, field := range schema.Fields {
+			if field.Sensitive && field.Required {
+				t.Errorf("%s key %d (%s) is sensitive and required", FormatMessageType(msgType), field.Key, field.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,419 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// FieldKind is the expected CBOR type of a payload field
+type FieldKind int
+
+// Field kinds
+const (
+	KindUint  FieldKind = iota // CBOR major type 0
+	KindInt                    // CBOR major type 0 or 1 (non-negative values encode as uint)
+	KindFloat                  // CBOR float (major type 7)
+	KindBool                   // CBOR true/false
+	KindBytes                  // CBOR byte string
+)
+
+// String returns the CBOR type name for the kind
+func (k FieldKind) String() string {
+	switch k {
+	case KindUint:
+		return "uint"
+	case KindInt:
+		return "int"
+	case KindFloat:
+		return "float"
+	case KindBool:
+		return "bool"
+	case KindBytes:
+		return "bytes"
+	default:
+		return "unknown"
+	}
+}
+
+// Matches reports whether a decoded CBOR value has this kind. A
+// non-negative int64 (as set by the command builders) encodes as a CBOR
+// uint, so it also matches KindUint.
+func (k FieldKind) Matches(v interface{}) bool {
+	switch x := v.(type) {
+	case uint64:
+		return k == KindUint || k == KindInt
+	case int64:
+		return k == KindInt || (k == KindUint && x >= 0)
+	case float64, float32:
+		return k == KindFloat
+	case bool:
+		return k == KindBool
+	case []byte:
+		return k == KindBytes
+	}
+	return false
+}
+
+// FieldSchema describes a single payload map key
+type FieldSchema struct {
+	Key      int
+	Name     string
+	Kind     FieldKind
+	Required bool
+	Max      uint64 // Largest valid value of a uint field (0 = any)
+
+	// Bits is the width of an int or uint field on the device: 8, 16, 32
+	// or 64. Every integer field has a width or a Max; the typed payload
+	// structs hold each field at this width, and values outside it are out
+	// of range.
+	Bits int
+
+	// Sensitive fields are removed by Sanitizer before captures are
+	// shared. They are always optional, so sanitized packets still pass
+	// schema checks.
+	Sensitive bool
+
+	// Address fields hold a device address, rewritten with the frame
+	// address when a Sanitizer anonymizes addresses
+	Address bool
+}
+
+// MessageSchema describes the payload map of a message type
+type MessageSchema struct {
+	Type   uint8
+	Fields []FieldSchema
+}
+
+// Field returns the schema for a payload key
+func (s MessageSchema) Field(key int) (FieldSchema, bool) {
+	for _, f := range s.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return FieldSchema{}, false
+}
+
+// req and opt build required and optional field schemas
+func req(key int, name string, kind FieldKind) FieldSchema {
+	return FieldSchema{Key: key, Name: name, Kind: kind, Required: true}
+}
+
+func opt(key int, name string, kind FieldKind) FieldSchema {
+	return FieldSchema{Key: key, Name: name, Kind: kind}
+}
+
+// atMost sets the largest valid value of a uint field
+func (f FieldSchema) atMost(max uint64) FieldSchema {
+	f.Max = max
+	return f
+}
+
+// bits sets the device width of an integer field
+func (f FieldSchema) bits(n int) FieldSchema {
+	f.Bits = n
+	return f
+}
+
+// sensitive marks a field that Sanitizer strips (calibration data)
+func (f FieldSchema) sensitive() FieldSchema {
+	f.Sensitive = true
+	return f
+}
+
+// address marks a field holding a device address
+func (f FieldSchema) address() FieldSchema {
+	f.Address = true
+	return f
+}
+
+// maxIndex is the largest component index or count (uint8 on the wire)
+const maxIndex = 0xFF
+
+// schemaRegistry maps message types to payload schemas.
+// Messages without a payload (PING_REQUEST, DISCOVERY_REQUEST) have no entry.
+var schemaRegistry = map[uint8]MessageSchema{
+	// Configuration commands
+	MsgMotorConfig: {MsgMotorConfig, []FieldSchema{
+		req(0, "motor", KindUint).atMost(maxIndex), opt(1, "pwm-period", KindUint).bits(32),
+		opt(2, "pid-kp", KindFloat).sensitive(), opt(3, "pid-ki", KindFloat).sensitive(), opt(4, "pid-kd", KindFloat).sensitive(),
+		opt(5, "max-rpm", KindInt).bits(32), opt(6, "min-rpm", KindInt).bits(32), opt(7, "min-pwm-duty", KindUint).bits(32),
+	}},
+	MsgPumpConfig: {MsgPumpConfig, []FieldSchema{
+		req(0, "pump", KindUint).atMost(maxIndex), opt(1, "pulse-ms", KindUint).bits(32), opt(2, "recovery-ms", KindUint).bits(32),
+	}},
+	MsgTempConfig: {MsgTempConfig, []FieldSchema{
+		req(0, "thermometer", KindUint).atMost(maxIndex),
+		opt(1, "pid-kp", KindFloat).sensitive(), opt(2, "pid-ki", KindFloat).sensitive(), opt(3, "pid-kd", KindFloat).sensitive(),
+	}},
+	MsgGlowConfig: {MsgGlowConfig, []FieldSchema{
+		req(0, "glow", KindUint).atMost(maxIndex), opt(1, "max-duration", KindUint).bits(32),
+	}},
+	MsgDataSubscription: {MsgDataSubscription, []FieldSchema{
+		req(0, "appliance-address", KindUint).bits(64).address(),
+	}},
+	MsgDataUnsubscribe: {MsgDataUnsubscribe, []FieldSchema{
+		req(0, "appliance-address", KindUint).bits(64).address(),
+	}},
+	MsgTelemetryConfig: {MsgTelemetryConfig, []FieldSchema{
+		req(0, "enabled", KindBool), req(1, "interval-ms", KindUint).bits(32),
+	}},
+	MsgTimeoutConfig: {MsgTimeoutConfig, []FieldSchema{
+		req(0, "enabled", KindBool), req(1, "timeout-ms", KindUint).bits(32),
+	}},
+
+	// Control commands
+	MsgStateCommand: {MsgStateCommand, []FieldSchema{
+		req(0, "mode", KindUint).bits(8), opt(1, "argument", KindInt).bits(64),
+	}},
+	MsgMotorCommand: {MsgMotorCommand, []FieldSchema{
+		req(0, "motor", KindUint).atMost(maxIndex), req(1, "rpm", KindInt).bits(32),
+	}},
+	MsgPumpCommand: {MsgPumpCommand, []FieldSchema{
+		req(0, "pump", KindUint).atMost(maxIndex), req(1, "rate-ms", KindInt).bits(32),
+	}},
+	MsgGlowCommand: {MsgGlowCommand, []FieldSchema{
+		req(0, "glow", KindUint).atMost(maxIndex), req(1, "duration", KindInt).bits(32),
+	}},
+	MsgTempCommand: {MsgTempCommand, []FieldSchema{
+		req(0, "thermometer", KindUint).atMost(maxIndex),
+		req(1, "type", KindUint).atMost(uint64(TempCmdSetTargetTemp)),
+		opt(2, "motor-index", KindInt).bits(32), opt(3, "target-temp", KindFloat),
+	}},
+	MsgSendTelemetry: {MsgSendTelemetry, []FieldSchema{
+		req(0, "telemetry-type", KindUint).atMost(uint64(TelemetryTypeGlow)), opt(1, "index", KindUint).atMost(maxIndex),
+	}},
+
+	// Telemetry data
+	MsgStateData: {MsgStateData, []FieldSchema{
+		req(0, "error", KindBool), req(1, "code", KindInt).bits(32), req(2, "state", KindUint).bits(32), req(3, "timestamp", KindUint).bits(64),
+	}},
+	MsgMotorData: {MsgMotorData, []FieldSchema{
+		req(0, "motor", KindUint).atMost(maxIndex), req(1, "timestamp", KindUint).bits(64), req(2, "rpm", KindInt).bits(32), req(3, "target", KindInt).bits(32),
+		opt(4, "max-rpm", KindInt).bits(32), opt(5, "min-rpm", KindInt).bits(32), opt(6, "pwm", KindUint).bits(32), opt(7, "pwm-max", KindUint).bits(32),
+	}},
+	MsgPumpData: {MsgPumpData, []FieldSchema{
+		req(0, "pump", KindUint).atMost(maxIndex), req(1, "timestamp", KindUint).bits(64),
+		req(2, "type", KindUint).atMost(uint64(PumpEventCycleEnd)), opt(3, "rate", KindInt).bits(32),
+	}},
+	MsgGlowData: {MsgGlowData, []FieldSchema{
+		req(0, "glow", KindUint).atMost(maxIndex), req(1, "timestamp", KindUint).bits(64), req(2, "lit", KindBool),
+	}},
+	MsgTempData: {MsgTempData, []FieldSchema{
+		req(0, "thermometer", KindUint).atMost(maxIndex), req(1, "timestamp", KindUint).bits(64), req(2, "reading", KindFloat),
+		opt(3, "temperature-rpm-control", KindBool), opt(4, "watched-motor", KindInt).bits(32), opt(5, "target-temperature", KindFloat),
+	}},
+	MsgDeviceAnnounce: {MsgDeviceAnnounce, []FieldSchema{
+		req(0, "motor-count", KindUint).atMost(maxIndex), req(1, "thermometer-count", KindUint).atMost(maxIndex),
+		req(2, "pump-count", KindUint).atMost(maxIndex), req(3, "glow-count", KindUint).atMost(maxIndex),
+	}},
+	MsgPingResponse: {MsgPingResponse, []FieldSchema{
+		req(0, "uptime-ms", KindUint).bits(64),
+	}},
+
+	// Errors
+	MsgErrorInvalidCmd: {MsgErrorInvalidCmd, []FieldSchema{
+		req(0, "error-code", KindInt).bits(32),
+	}},
+	MsgErrorStateReject: {MsgErrorStateReject, []FieldSchema{
+		req(0, "state", KindUint).atMost(uint64(SysStateEstop)),
+	}},
+}
+
+// LookupSchema returns the payload schema for a message type
+func LookupSchema(msgType uint8) (MessageSchema, bool) {
+	s, ok := schemaRegistry[msgType]
+	return s, ok
+}
+
+// PayloadField is a payload map entry named from the schema registry
+type PayloadField struct {
+	Key   int
+	Name  string // Schema field name, or the key number if the schema has none
+	Value interface{}
+}
+
+// FormatValue formats the value for display: text as is, byte strings in
+// hex and other values as fmt prints them
+func (f PayloadField) FormatValue() string {
+	switch v := f.Value.(type) {
+	case string:
+		return v
+	case []byte:
+		return hex.EncodeToString(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(f.Value)
+}
+
+// PayloadFields returns the payload of p in key order, each field named
+// from the schema registry. Keys the registry doesn't know are included,
+// named by number, so fields sent by newer firmware still show.
+func PayloadFields(p *Packet) []PayloadField {
+	return payloadFields(p.Type(), p.PayloadMap())
+}
+
+func payloadFields(msgType uint8, m map[int]interface{}) []PayloadField {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	schema, _ := LookupSchema(msgType)
+	fields := make([]PayloadField, 0, len(keys))
+	for _, k := range keys {
+		name := strconv.Itoa(k)
+		if f, ok := schema.Field(k); ok {
+			name = f.Name
+		}
+		fields = append(fields, PayloadField{Key: k, Name: name, Value: m[k]})
+	}
+	return fields
+}
+
+// CheckSchema checks a CBOR payload against the schema registry: the
+// payload must be present, required keys must be present, known keys must
+// have the schema's CBOR type (see CheckPayloadTypes) and integer fields
+// must fit their width and Max. Unknown keys and message types without a schema
+// are ignored.
+func CheckSchema(p *Packet) []ValidationError {
+	errors := []ValidationError{}
+
+	schema, ok := LookupSchema(p.Type())
+	if !ok {
+		return errors
+	}
+	msgName := FormatMessageType(p.Type())
+
+	m := p.PayloadMap()
+	if m == nil {
+		return append(errors, ValidationError{
+			Type:    AnomalyLengthMismatch,
+			Message: fmt.Sprintf("%s missing payload", msgName),
+			Details: map[string]interface{}{},
+		})
+	}
+
+	for _, field := range schema.Fields {
+		v, present := m[field.Key]
+		if !present {
+			if field.Required {
+				errors = append(errors, ValidationError{
+					Type:    AnomalyMissingField,
+					Message: fmt.Sprintf("%s missing required key %d (%s)", msgName, field.Key, field.Name),
+					Details: map[string]interface{}{"key": field.Key, "field": field.Name},
+				})
+			}
+			continue
+		}
+
+		if !field.fits(v) {
+			errors = append(errors, ValidationError{
+				Type:    AnomalyInvalidValue,
+				Message: fmt.Sprintf("%s key %d (%s) out of range (%v, %d-bit)", msgName, field.Key, field.Name, v, field.Bits),
+				Details: map[string]interface{}{"key": field.Key, "field": field.Name, "value": v, "bits": field.Bits},
+			})
+			continue
+		}
+		if field.Max == 0 || !KindUint.Matches(v) {
+			continue
+		}
+		if u, _ := GetMapUint(m, field.Key); u > field.Max {
+			errors = append(errors, ValidationError{
+				Type:    AnomalyInvalidValue,
+				Message: fmt.Sprintf("%s key %d (%s) out of range (%d, max %d)", msgName, field.Key, field.Name, u, field.Max),
+				Details: map[string]interface{}{"key": field.Key, "field": field.Name, "value": u, "max": field.Max},
+			})
+		}
+	}
+
+	return append(errors, CheckPayloadTypes(p)...)
+}
+
+// fits reports whether an integer value fits the field's width. Values of
+// the wrong type are left to CheckPayloadTypes.
+func (f FieldSchema) fits(v interface{}) bool {
+	if f.Bits == 0 || f.Bits >= 64 {
+		return true
+	}
+	var limit uint64 // Largest value
+	switch f.Kind {
+	case KindUint:
+		limit = 1<<f.Bits - 1
+	case KindInt:
+		limit = 1<<(f.Bits-1) - 1
+	default:
+		return true
+	}
+	switch x := v.(type) {
+	case uint64:
+		return x <= limit
+	case int64:
+		if x < 0 {
+			return f.Kind == KindUint || x >= -int64(limit)-1
+		}
+		return uint64(x) <= limit
+	}
+	return true
+}
+
+// CheckPayloadTypes verifies that each known payload key has the CBOR type
+// given by the schema registry. Mismatches are reported as AnomalyInvalidValue.
+// Unknown keys and message types without a schema are ignored.
+func CheckPayloadTypes(p *Packet) []ValidationError {
+	errors := []ValidationError{}
+
+	schema, ok := LookupSchema(p.Type())
+	if !ok {
+		return errors
+	}
+
+	for _, field := range schema.Fields {
+		v, present := p.PayloadMap()[field.Key]
+		if !present || field.Kind.Matches(v) {
+			continue
+		}
+		errors = append(errors, ValidationError{
+			Type: AnomalyInvalidValue,
+			Message: fmt.Sprintf("%s key %d (%s) has CBOR type %s, expected %s",
+				FormatMessageType(p.Type()), field.Key, field.Name, cborKindOf(v), field.Kind),
+			Details: map[string]interface{}{
+				"key":      field.Key,
+				"field":    field.Name,
+				"expected": field.Kind.String(),
+				"actual":   cborKindOf(v),
+			},
+		})
+	}
+
+	return errors
+}
+
+// cborKindOf names the CBOR type of a decoded value
+func cborKindOf(v interface{}) string {
+	switch v.(type) {
+	case uint64:
+		return "uint"
+	case int64:
+		return "int"
+	case float64, float32:
+		return "float"
+	case bool:
+		return "bool"
+	case []byte:
+		return "bytes"
+	case string:
+		return "text"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
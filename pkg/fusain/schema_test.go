@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldKind_Matches(t *testing.T) {
+	tests := []struct {
+		kind  FieldKind
+		value interface{}
+		want  bool
+	}{
+		{KindUint, uint64(1), true},
+		{KindUint, int64(-1), false},
+		{KindInt, uint64(1), true},
+		{KindInt, int64(-1), true},
+		{KindInt, float64(1), false},
+		{KindFloat, float64(1.5), true},
+		{KindFloat, uint64(1), false},
+		{KindBool, true, true},
+		{KindBool, uint64(1), false},
+		{KindBytes, []byte{1}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.kind.Matches(tt.value); got != tt.want {
+			t.Errorf("%s.Matches(%T) = %v, want %v", tt.kind, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLookupSchema(t *testing.T) {
+	schema, ok := LookupSchema(MsgTempData)
+	if !ok {
+		t.Fatal("Expected schema for TEMP_DATA")
+	}
+	field, ok := schema.Field(2)
+	if !ok || field.Kind != KindFloat || !field.Required {
+		t.Errorf("Expected required float reading at key 2, got %+v", field)
+	}
+
+	if _, ok := LookupSchema(MsgPingRequest); ok {
+		t.Error("PING_REQUEST has no payload and should have no schema")
+	}
+}
+
+func TestCheckPayloadTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType uint8
+		payload map[int]interface{}
+		want    int
+	}{
+		{"valid temp data", MsgTempData, map[int]interface{}{0: uint64(0), 1: uint64(1000), 2: float64(21.5)}, 0},
+		{"temp reading encoded as int", MsgTempData, map[int]interface{}{0: uint64(0), 1: uint64(1000), 2: uint64(21)}, 1},
+		{"negative motor index", MsgMotorCommand, map[int]interface{}{0: int64(-1), 1: uint64(1000)}, 1},
+		{"error flag encoded as int", MsgStateData, map[int]interface{}{0: uint64(0), 1: uint64(0), 2: uint64(1), 3: uint64(0)}, 1},
+		{"unknown keys ignored", MsgPingResponse, map[int]interface{}{0: uint64(1), 9: "extra"}, 0},
+		{"no schema", MsgPingRequest, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cborPayload := buildCBORPayload(tt.msgType, tt.payload)
+			p := NewPacket(uint8(len(cborPayload)), 0x123456789ABCDEF0, cborPayload, 0)
+
+			errs := CheckPayloadTypes(p)
+			if len(errs) != tt.want {
+				t.Fatalf("Expected %d errors, got %d: %v", tt.want, len(errs), errs)
+			}
+			for _, e := range errs {
+				if e.Type != AnomalyInvalidValue {
+					t.Errorf("Expected AnomalyInvalidValue, got %v", e.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePacketWithOptions_SkipSchema(t *testing.T) {
+	cborPayload := buildCBORPayload(MsgTempData, map[int]interface{}{0: uint64(0), 1: uint64(1000), 2: uint64(21)})
+	p := NewPacket(uint8(len(cborPayload)), 0x123456789ABCDEF0, cborPayload, 0)
+
+	if errs := ValidatePacket(p); len(errs) != 1 {
+		t.Errorf("Expected 1 type error by default, got %v", errs)
+	}
+	if errs := ValidatePacketWithOptions(p, ValidateOptions{SkipSchema: true}); len(errs) != 0 {
+		t.Errorf("Schema checks should be skipped, got %v", errs)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType uint8
+		payload map[int]interface{}
+		want    []AnomalyType
+	}{
+		{"valid", MsgGlowData, map[int]interface{}{0: uint64(0), 1: uint64(100), 2: true}, nil},
+		{"no schema", MsgPingRequest, nil, nil},
+		{"missing payload", MsgGlowData, nil, []AnomalyType{AnomalyLengthMismatch}},
+		{"missing required key", MsgGlowData, map[int]interface{}{0: uint64(0), 1: uint64(100)}, []AnomalyType{AnomalyMissingField}},
+		{"optional key absent", MsgStateCommand, map[int]interface{}{0: uint64(ModeIdle)}, nil},
+		{"wrong type", MsgGlowData, map[int]interface{}{0: uint64(0), 1: uint64(100), 2: uint64(1)}, []AnomalyType{AnomalyInvalidValue}},
+		{"index out of range", MsgGlowData, map[int]interface{}{0: uint64(256), 1: uint64(100), 2: true}, []AnomalyType{AnomalyInvalidValue}},
+		{"enum out of range", MsgSendTelemetry, map[int]interface{}{0: uint64(TelemetryTypeGlow) + 1}, []AnomalyType{AnomalyInvalidValue}},
+		{"unknown key ignored", MsgGlowData, map[int]interface{}{0: uint64(0), 1: uint64(100), 2: true, 9: "x"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cborPayload := buildCBORPayload(tt.msgType, tt.payload)
+			p := NewPacket(uint8(len(cborPayload)), 0x01, cborPayload, 0)
+
+			errs := CheckSchema(p)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("CheckSchema = %v, want %d errors", errs, len(tt.want))
+			}
+			for i, err := range errs {
+				if err.Type != tt.want[i] {
+					t.Errorf("error %d type = %d, want %d (%s)", i, err.Type, tt.want[i], err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePacket_MissingPayloadReportedOnce(t *testing.T) {
+	cborPayload := buildCBOREmptyPayload(MsgMotorData)
+	p := NewPacket(uint8(len(cborPayload)), 0x01, cborPayload, 0)
+
+	errs := ValidatePacket(p)
+	if len(errs) != 1 || errs[0].Type != AnomalyLengthMismatch {
+		t.Errorf("ValidatePacket = %v, want one AnomalyLengthMismatch", errs)
+	}
+}
+
+func TestPayloadFields(t *testing.T) {
+	p := NewPacketWithPayload(1, MsgMotorData, map[int]interface{}{
+		2: int64(2500), 0: uint64(1), 1: uint64(100), 3: int64(3000), 9: []byte{0xAB, 0x01},
+	})
+	fields := PayloadFields(roundTrip(t, p))
+
+	var names, values []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+		values = append(values, f.FormatValue())
+	}
+	if got := strings.Join(names, ","); got != "motor,timestamp,rpm,target,9" {
+		t.Errorf("Expected fields in key order named from the schema, got %s", got)
+	}
+	if got := strings.Join(values, ","); got != "1,100,2500,3000,ab01" {
+		t.Errorf("Unexpected values %s", got)
+	}
+}
+
+func TestSchema_IntegerWidths(t *testing.T) {
+	for msgType, schema := range schemaRegistry {
+		for _, f := range schema.Fields {
+			if f.Kind != KindUint && f.Kind != KindInt {
+				continue
+			}
+			switch {
+			case f.Bits == 0 && f.Max == 0:
+				t.Errorf("%s key %d (%s) has no documented width", FormatMessageType(msgType), f.Key, f.Name)
+			case f.Bits != 0 && f.Bits != 8 && f.Bits != 16 && f.Bits != 32 && f.Bits != 64:
+				t.Errorf("%s key %d (%s) has width %d", FormatMessageType(msgType), f.Key, f.Name, f.Bits)
+			}
+		}
+	}
+}
+
+func TestFieldSchema_Fits(t *testing.T) {
+	u32 := FieldSchema{Kind: KindUint, Bits: 32}
+	i32 := FieldSchema{Kind: KindInt, Bits: 32}
+	tests := []struct {
+		field FieldSchema
+		value interface{}
+		want  bool
+	}{
+		{u32, uint64(0xFFFFFFFF), true},
+		{u32, uint64(0x100000000), false},
+		{u32, int64(5), true},
+		{i32, int64(-2147483648), true},
+		{i32, int64(-2147483649), false},
+		{i32, uint64(2147483647), true},
+		{i32, uint64(2147483648), false},
+		{i32, 1.5, true}, // Wrong type, left to CheckPayloadTypes
+		{FieldSchema{Kind: KindUint, Bits: 64}, uint64(1) << 63, true},
+		{FieldSchema{Kind: KindUint}, uint64(1) << 63, true},
+	}
+	for _, tt := range tests {
+		if got := tt.field.fits(tt.value); got != tt.want {
+			t.Errorf("%+v fits(%v) = %v, want %v", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// DefaultStaleTimeout is how long a device may be silent before
+// SessionValidator reports it as stale
+const DefaultStaleTimeout = 5 * time.Second
+
+// stateTransitions lists the states each state may move to directly, in
+// addition to ERROR, E_STOP and INITIALIZING (device reset), which are
+// reachable from any state
+var stateTransitions = map[SysState][]SysState{
+	SysStateInitializing:  {SysStateIdle},
+	SysStateIdle:          {SysStateBlowing, SysStatePreheat},
+	SysStateBlowing:       {SysStateIdle, SysStatePreheat, SysStateCooling},
+	SysStatePreheat:       {SysStatePreheatStage2, SysStateCooling},
+	SysStatePreheatStage2: {SysStateHeating, SysStateCooling},
+	SysStateHeating:       {SysStateCooling},
+	SysStateCooling:       {SysStateIdle, SysStateBlowing, SysStatePreheat},
+	SysStateError:         {SysStateIdle, SysStateCooling},
+	SysStateEstop:         {SysStateIdle},
+}
+
+// ValidTransition reports whether a device may move from one state to
+// another between two consecutive STATE_DATA packets. Staying in the same
+// state is always valid.
+func ValidTransition(from, to SysState) bool {
+	if from == to {
+		return true
+	}
+	switch to {
+	case SysStateError, SysStateEstop, SysStateInitializing:
+		return true
+	}
+	for _, next := range stateTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceSession is what SessionValidator remembers about one device
+type deviceSession struct {
+	lastSeen time.Time
+	stale    bool
+
+	state    SysState
+	hasState bool
+
+	streams map[telemetryStream]streamSession
+}
+
+// streamSession is the last packet of one telemetry stream
+type streamSession struct {
+	uptime  uint64 // Device timestamp in milliseconds
+	payload []byte // CBOR payload
+}
+
+// SessionValidator checks packets against what was seen before from the
+// same device, catching firmware bugs that single-packet validation
+// cannot:
+//   - state transitions the state machine does not allow (AnomalyInvalidTransition)
+//   - device timestamps going backwards within a telemetry stream (AnomalyTimestamp)
+//   - telemetry identical to the previous packet of the stream (AnomalyDuplicate)
+//   - devices that went silent (AnomalyStaleDevice, reported by Stale)
+//
+// A SessionValidator is not safe for concurrent use.
+type SessionValidator struct {
+	// StaleTimeout is how long a device may be silent before Stale reports
+	// it (0 disables stale detection)
+	StaleTimeout time.Duration
+
+	devices map[uint64]*deviceSession
+}
+
+// NewSessionValidator creates a session validator with DefaultStaleTimeout
+func NewSessionValidator() *SessionValidator {
+	return &SessionValidator{
+		StaleTimeout: DefaultStaleTimeout,
+		devices:      make(map[uint64]*deviceSession),
+	}
+}
+
+// Validate records the packet and returns the cross-packet anomalies it
+// causes. Only telemetry data (0x30-0x3F) is tracked: commands carry the
+// address of the device they are sent to, so they say nothing about
+// whether it is alive.
+func (v *SessionValidator) Validate(p *Packet) []ValidationError {
+	msgType := p.Type()
+	if msgType < MsgStateData || msgType > MsgPingResponse || p.IsStateless() || p.ParseError() != nil {
+		return nil
+	}
+
+	d, ok := v.devices[p.Address()]
+	if !ok {
+		d = &deviceSession{streams: make(map[telemetryStream]streamSession)}
+		v.devices[p.Address()] = d
+	}
+	d.lastSeen = p.Timestamp()
+	d.stale = false
+
+	m := p.PayloadMap()
+	stream := telemetryStream{address: p.Address(), msgType: msgType}
+	uptimeKey := 1
+	switch msgType {
+	case MsgStateData:
+		uptimeKey = 3
+	case MsgMotorData, MsgPumpData, MsgGlowData, MsgTempData:
+		stream.index, _ = GetMapUint(m, 0)
+	default:
+		return nil
+	}
+
+	errors := []ValidationError{}
+
+	if msgType == MsgStateData {
+		if state, ok := GetMapUint(m, 2); ok && state <= uint64(SysStateEstop) {
+			next := SysState(state)
+			if d.hasState && next == SysStateInitializing && d.state != next {
+				// The device was reset, so its uptime starts over
+				d.streams = make(map[telemetryStream]streamSession)
+			}
+			if d.hasState && !ValidTransition(d.state, next) {
+				errors = append(errors, ValidationError{
+					Type: AnomalyInvalidTransition,
+					Message: fmt.Sprintf("Invalid state transition %s -> %s",
+						FormatState(uint32(d.state)), FormatState(uint32(next))),
+					Details: map[string]interface{}{"from": d.state, "to": next},
+				})
+			}
+			d.state = next
+			d.hasState = true
+		}
+	}
+
+	uptime, hasUptime := GetMapUint(m, uptimeKey)
+	previous, seen := d.streams[stream]
+	if seen && bytes.Equal(previous.payload, p.PayloadRaw()) {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyDuplicate,
+			Message: fmt.Sprintf("Duplicate %s (timestamp %d ms)", FormatMessageType(msgType), uptime),
+			Details: map[string]interface{}{"timestamp": uptime},
+		})
+	} else if seen && hasUptime && uptime < previous.uptime {
+		errors = append(errors, ValidationError{
+			Type: AnomalyTimestamp,
+			Message: fmt.Sprintf("%s timestamp went backwards (%d ms after %d ms)",
+				FormatMessageType(msgType), uptime, previous.uptime),
+			Details: map[string]interface{}{"timestamp": uptime, "previous": previous.uptime},
+		})
+	}
+	if hasUptime {
+		d.streams[stream] = streamSession{uptime: uptime, payload: p.PayloadRaw()}
+	}
+
+	return errors
+}
+
+// Stale returns an AnomalyStaleDevice error for every device that has been
+// silent for longer than StaleTimeout at now. A device is reported once,
+// then again only after it has been heard from and gone silent again.
+func (v *SessionValidator) Stale(now time.Time) []ValidationError {
+	if v.StaleTimeout <= 0 {
+		return nil
+	}
+
+	var errors []ValidationError
+	for _, address := range slices.Sorted(maps.Keys(v.devices)) {
+		d := v.devices[address]
+		silent := now.Sub(d.lastSeen)
+		if d.stale || silent <= v.StaleTimeout {
+			continue
+		}
+		d.stale = true
+		errors = append(errors, ValidationError{
+			Type:    AnomalyStaleDevice,
+			Message: fmt.Sprintf("Device %016X silent for %s", address, silent.Round(time.Millisecond)),
+			Details: map[string]interface{}{"address": address, "last_seen": d.lastSeen, "timeout": v.StaleTimeout},
+		})
+	}
+	return errors
+}
+
+// Forget drops everything known about a device, e.g. after it was
+// deliberately reset
+func (v *SessionValidator) Forget(address uint64) {
+	delete(v.devices, address)
+}
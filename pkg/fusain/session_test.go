@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"testing"
+	"time"
+)
+
+func stateAt(t *testing.T, address uint64, state SysState, uptime uint64) *Packet {
+	t.Helper()
+	return roundTrip(t, StateData{State: state, Timestamp: uptime}.Encode(address))
+}
+
+func anomalyTypes(errs []ValidationError) []AnomalyType {
+	types := make([]AnomalyType, len(errs))
+	for i, err := range errs {
+		types[i] = err.Type
+	}
+	return types
+}
+
+func TestValidTransition(t *testing.T) {
+	tests := []struct {
+		from, to SysState
+		want     bool
+	}{
+		{SysStateIdle, SysStateIdle, true},
+		{SysStateIdle, SysStatePreheat, true},
+		{SysStatePreheat, SysStatePreheatStage2, true},
+		{SysStatePreheatStage2, SysStateHeating, true},
+		{SysStateHeating, SysStateCooling, true},
+		{SysStateCooling, SysStateIdle, true},
+		{SysStateHeating, SysStateEstop, true},
+		{SysStateBlowing, SysStateError, true},
+		{SysStateHeating, SysStateInitializing, true},
+		{SysStateIdle, SysStateHeating, false},
+		{SysStatePreheat, SysStateHeating, false},
+		{SysStateHeating, SysStateIdle, false},
+		{SysStateInitializing, SysStateCooling, false},
+	}
+	for _, tt := range tests {
+		if got := ValidTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("ValidTransition(%s, %s) = %v, want %v",
+				FormatState(uint32(tt.from)), FormatState(uint32(tt.to)), got, tt.want)
+		}
+	}
+}
+
+func TestSessionValidator_InvalidTransition(t *testing.T) {
+	v := NewSessionValidator()
+	if errs := v.Validate(stateAt(t, 0x01, SysStateIdle, 1000)); len(errs) != 0 {
+		t.Fatalf("first STATE_DATA: %v", errs)
+	}
+
+	errs := v.Validate(stateAt(t, 0x01, SysStateHeating, 1100))
+	if len(errs) != 1 || errs[0].Type != AnomalyInvalidTransition {
+		t.Fatalf("IDLE -> HEATING: got %v, want one AnomalyInvalidTransition", anomalyTypes(errs))
+	}
+	if errs[0].Details["from"] != SysStateIdle || errs[0].Details["to"] != SysStateHeating {
+		t.Errorf("Details = %v", errs[0].Details)
+	}
+
+	// Devices are tracked separately
+	if errs := v.Validate(stateAt(t, 0x02, SysStateHeating, 1100)); len(errs) != 0 {
+		t.Errorf("first STATE_DATA from another device: %v", errs)
+	}
+}
+
+func TestSessionValidator_Timestamps(t *testing.T) {
+	v := NewSessionValidator()
+	v.Validate(stateAt(t, 0x01, SysStateHeating, 5000))
+
+	errs := v.Validate(stateAt(t, 0x01, SysStateHeating, 4000))
+	if len(errs) != 1 || errs[0].Type != AnomalyTimestamp {
+		t.Fatalf("backwards timestamp: got %v, want one AnomalyTimestamp", anomalyTypes(errs))
+	}
+
+	// Each telemetry stream has its own timestamps
+	v.Validate(roundTrip(t, TempData{Thermometer: 0, Timestamp: 9000, Reading: 20}.Encode(0x01)))
+	if errs := v.Validate(roundTrip(t, TempData{Thermometer: 1, Timestamp: 8000, Reading: 20}.Encode(0x01))); len(errs) != 0 {
+		t.Errorf("other thermometer: %v", errs)
+	}
+
+	// A reset restarts the uptime
+	if errs := v.Validate(stateAt(t, 0x01, SysStateInitializing, 10)); len(errs) != 0 {
+		t.Errorf("reset: %v", errs)
+	}
+	if errs := v.Validate(roundTrip(t, TempData{Thermometer: 0, Timestamp: 20, Reading: 20}.Encode(0x01))); len(errs) != 0 {
+		t.Errorf("telemetry after reset: %v", errs)
+	}
+}
+
+func TestSessionValidator_Duplicate(t *testing.T) {
+	v := NewSessionValidator()
+	motor := MotorData{Motor: 0, Timestamp: 1000, RPM: 3000, Target: 3000}
+	v.Validate(roundTrip(t, motor.Encode(0x01)))
+
+	errs := v.Validate(roundTrip(t, motor.Encode(0x01)))
+	if len(errs) != 1 || errs[0].Type != AnomalyDuplicate {
+		t.Fatalf("repeated MOTOR_DATA: got %v, want one AnomalyDuplicate", anomalyTypes(errs))
+	}
+
+	motor.Timestamp = 1100
+	if errs := v.Validate(roundTrip(t, motor.Encode(0x01))); len(errs) != 0 {
+		t.Errorf("next MOTOR_DATA: %v", errs)
+	}
+}
+
+func TestSessionValidator_IgnoresCommands(t *testing.T) {
+	v := NewSessionValidator()
+	v.Validate(stateAt(t, 0x01, SysStateIdle, 1000))
+
+	cmd := roundTrip(t, NewPingRequest(0x01))
+	if errs := v.Validate(cmd); errs != nil {
+		t.Errorf("PING_REQUEST: %v", errs)
+	}
+	if v.devices[0x01].lastSeen.Equal(cmd.Timestamp()) {
+		t.Error("command refreshed the device's last-seen time")
+	}
+}
+
+func TestSessionValidator_Stale(t *testing.T) {
+	v := NewSessionValidator()
+	v.StaleTimeout = time.Second
+
+	p := stateAt(t, 0x01, SysStateIdle, 1000)
+	start := p.Timestamp()
+	v.Validate(p)
+
+	if errs := v.Stale(start.Add(500 * time.Millisecond)); len(errs) != 0 {
+		t.Errorf("before timeout: %v", errs)
+	}
+	errs := v.Stale(start.Add(2 * time.Second))
+	if len(errs) != 1 || errs[0].Type != AnomalyStaleDevice || errs[0].Details["address"] != uint64(0x01) {
+		t.Fatalf("after timeout: got %v, want one AnomalyStaleDevice for 0x01", errs)
+	}
+	if errs := v.Stale(start.Add(3 * time.Second)); len(errs) != 0 {
+		t.Errorf("stale device reported twice: %v", errs)
+	}
+
+	// Heard from again, then silent again
+	p = stateAt(t, 0x01, SysStateIdle, 5000)
+	v.Validate(p)
+	if errs := v.Stale(p.Timestamp().Add(2 * time.Second)); len(errs) != 1 {
+		t.Errorf("second silence: got %d errors, want 1", len(errs))
+	}
+
+	v.StaleTimeout = 0
+	v.Validate(stateAt(t, 0x02, SysStateIdle, 1000))
+	if errs := v.Stale(start.Add(time.Hour)); errs != nil {
+		t.Errorf("stale detection disabled: %v", errs)
+	}
+}
+
+func TestStatistics_SessionAnomalies(t *testing.T) {
+	stats := NewStatistics()
+	v := NewSessionValidator()
+	v.StaleTimeout = time.Second
+
+	p := stateAt(t, 0x01, SysStateIdle, 1000)
+	stats.Update(p, nil, v.Validate(p))
+	p = stateAt(t, 0x01, SysStateHeating, 900)
+	stats.Update(p, nil, v.Validate(p))
+	stats.RecordStale(v.Stale(p.Timestamp().Add(time.Minute)))
+
+	if stats.InvalidTransitions != 1 || stats.TimestampErrors != 1 || stats.AnomalousValues != 2 {
+		t.Errorf("transitions=%d timestamps=%d anomalous=%d, want 1, 1, 2",
+			stats.InvalidTransitions, stats.TimestampErrors, stats.AnomalousValues)
+	}
+	if stats.StaleDevices != 1 || stats.TotalPackets != 2 {
+		t.Errorf("stale=%d total=%d, want 1, 2", stats.StaleDevices, stats.TotalPackets)
+	}
+}
@@ -4,11 +4,140 @@
 package fusain
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strings"
 	"time"
 )
 
+// intervalWindow is the number of recent intervals kept for percentiles
+const intervalWindow = 1024
+
+// slowTelemetryFactor is how much longer than expected a telemetry interval
+// may be before it counts as slow
+const slowTelemetryFactor = 1.5
+
+// IntervalStats summarizes the time between consecutive packets
+type IntervalStats struct {
+	Count uint64
+	Min   time.Duration
+	Max   time.Duration
+
+	sum    time.Duration
+	sumSq  float64         // Sum of squared intervals in seconds
+	recent []time.Duration // Ring of the last intervalWindow intervals
+	next   int
+}
+
+func (i *IntervalStats) add(d time.Duration) {
+	if i.Count == 0 || d < i.Min {
+		i.Min = d
+	}
+	if d > i.Max {
+		i.Max = d
+	}
+	i.Count++
+	i.sum += d
+	i.sumSq += d.Seconds() * d.Seconds()
+
+	if len(i.recent) < intervalWindow {
+		i.recent = append(i.recent, d)
+		return
+	}
+	i.recent[i.next] = d
+	i.next = (i.next + 1) % intervalWindow
+}
+
+// Avg returns the mean interval
+func (i *IntervalStats) Avg() time.Duration {
+	if i.Count == 0 {
+		return 0
+	}
+	return i.sum / time.Duration(i.Count)
+}
+
+// Jitter returns the standard deviation of the intervals
+func (i *IntervalStats) Jitter() time.Duration {
+	if i.Count == 0 {
+		return 0
+	}
+	mean := i.Avg().Seconds()
+	variance := i.sumSq/float64(i.Count) - mean*mean
+	if variance <= 0 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(variance) * float64(time.Second))
+}
+
+// P95 returns the 95th percentile of the most recent intervals
+func (i *IntervalStats) P95() time.Duration {
+	if len(i.recent) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(i.recent)
+	slices.Sort(sorted)
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+// String returns a one-line summary
+func (i *IntervalStats) String() string {
+	return fmt.Sprintf("min %s avg %s p95 %s max %s jitter %s",
+		formatInterval(i.Min), formatInterval(i.Avg()), formatInterval(i.P95()),
+		formatInterval(i.Max), formatInterval(i.Jitter()))
+}
+
+func formatInterval(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// telemetryStream identifies one periodic telemetry source: a message type
+// from a device, per motor/pump/glow plug/thermometer index
+type telemetryStream struct {
+	address uint64
+	msgType uint8
+	index   uint64
+}
+
+// ValueRange tracks the range of a value reported in validation error
+// Details
+type ValueRange struct {
+	Count uint64
+	Min   float64
+	Max   float64
+}
+
+func (r *ValueRange) add(v float64) {
+	if r.Count == 0 || v < r.Min {
+		r.Min = v
+	}
+	if r.Count == 0 || v > r.Max {
+		r.Max = v
+	}
+	r.Count++
+}
+
+// Format formats the range with a fmt verb for each bound: one value when
+// all observations were equal, "min to max" otherwise
+func (r ValueRange) Format(verb string) string {
+	if r.Min == r.Max {
+		return fmt.Sprintf(verb, r.Max)
+	}
+	return fmt.Sprintf(verb+" to "+verb, r.Min, r.Max)
+}
+
+// frameOverhead is the unstuffed size of a frame without its CBOR payload:
+// START, length, address, CRC and END
+const frameOverhead = 1 + 1 + AddressSize + 2 + 1
+
+// CountStats holds the counters for one message type or device
+type CountStats struct {
+	Packets uint64 `json:"packets"` // Packets received, including those that failed CRC
+	Bytes   uint64 `json:"bytes"`   // Frame bytes before byte stuffing
+	Errors  uint64 `json:"errors"`  // CRC errors and packets with validation anomalies
+}
+
 // Statistics tracks packet statistics and error rates
 type Statistics struct {
 	StartTime      time.Time
@@ -22,14 +151,54 @@ type Statistics struct {
 	MalformedPackets uint64
 	InvalidCounts    uint64
 	LengthMismatches uint64
+	MissingFields    uint64
 	AnomalousValues  uint64
 	HighRPM          uint64
 	InvalidTemp      uint64
 	InvalidPWM       uint64
 
+	// Cross-packet anomalies from SessionValidator. Invalid transitions,
+	// timestamp errors and duplicates also count as anomalous values.
+	InvalidTransitions uint64
+	TimestampErrors    uint64
+	DuplicatePackets   uint64
+	StaleDevices       uint64 // Times a device went silent
+
+	// CustomAnomalies counts AnomalyCustom errors from registered
+	// validators; see CheckCounts for every registered check's errors
+	CustomAnomalies uint64
+
+	// Offending values from validation error Details, so the summary shows
+	// how bad things got and not only how often
+	HighRPMValues   ValueRange // Out-of-range RPM and target RPM
+	TempValues      ValueRange // Out-of-range temperature readings and targets (°C)
+	PWMExcess       ValueRange // PWM above pwm_max (µs)
+	ComponentCounts ValueRange // Component counts above the limit in DEVICE_ANNOUNCE
+	TimestampRewind ValueRange // How far device timestamps went backwards (ms)
+
 	// Rates (calculated)
 	PacketRate float64 // packets/sec
 	ErrorRate  float64 // errors/sec
+
+	// Timing. Gaps covers every decoded packet; telemetry intervals are
+	// measured per device and index and summarized per message type.
+	Gaps          IntervalStats
+	SlowTelemetry uint64 // Telemetry intervals over 1.5x the expected interval
+
+	// TelemetryInterval is the expected periodic telemetry interval. When
+	// zero, it is learned per device from TELEMETRY_CONFIG packets.
+	TelemetryInterval time.Duration
+
+	lastPacket    time.Time
+	typeIntervals map[uint8]*IntervalStats
+	lastTelemetry map[telemetryStream]time.Time
+	configured    map[uint64]time.Duration // Learned intervals by device
+
+	// Breakdowns. CRC errors are attributed by the address and type in the
+	// corrupt frame; other decode errors cannot be attributed.
+	byType   map[uint8]*CountStats
+	byDevice map[uint64]*CountStats
+	byCheck  map[string]uint64 // Errors by registered validator name
 }
 
 // NewStatistics creates a new statistics tracker
@@ -38,6 +207,12 @@ func NewStatistics() *Statistics {
 	return &Statistics{
 		StartTime:      now,
 		LastUpdateTime: now,
+		byType:         make(map[uint8]*CountStats),
+		byDevice:       make(map[uint64]*CountStats),
+		byCheck:        make(map[string]uint64),
+		typeIntervals:  make(map[uint8]*IntervalStats),
+		lastTelemetry:  make(map[telemetryStream]time.Time),
+		configured:     make(map[uint64]time.Duration),
 	}
 }
 
@@ -51,6 +226,12 @@ func (s *Statistics) Update(packet *Packet, decodeErr error, validationErrors []
 		// Check if it's a CRC error
 		if strings.HasPrefix(errMsg, "CRC mismatch") {
 			s.CRCErrors++
+			var crcErr *CRCError
+			if errors.As(decodeErr, &crcErr) {
+				size := uint64(len(crcErr.Payload) + frameOverhead)
+				msgType, typeKnown := crcErr.MessageType()
+				s.count(msgType, typeKnown, crcErr.Address, size, true)
+			}
 		} else {
 			// Other decode errors (framing, overflow, etc.)
 			s.DecodeErrors++
@@ -58,9 +239,22 @@ func (s *Statistics) Update(packet *Packet, decodeErr error, validationErrors []
 		return // Don't process packet further if decode failed
 	}
 
+	if packet != nil {
+		size := uint64(packet.Length()) + frameOverhead
+		s.count(packet.Type(), true, packet.Address(), size, len(validationErrors) > 0)
+		s.recordTiming(packet)
+	}
+
 	// Handle validation errors
 	if len(validationErrors) > 0 {
 		for _, err := range validationErrors {
+			if err.Check != "" {
+				if s.byCheck == nil {
+					s.byCheck = make(map[string]uint64)
+				}
+				s.byCheck[err.Check]++
+			}
+			s.recordDetails(err)
 			switch err.Type {
 			case AnomalyInvalidCount:
 				s.InvalidCounts++
@@ -68,6 +262,9 @@ func (s *Statistics) Update(packet *Packet, decodeErr error, validationErrors []
 			case AnomalyLengthMismatch:
 				s.LengthMismatches++
 				s.MalformedPackets++
+			case AnomalyMissingField:
+				s.MissingFields++
+				s.MalformedPackets++
 			case AnomalyHighRPM:
 				s.HighRPM++
 				s.AnomalousValues++
@@ -79,6 +276,18 @@ func (s *Statistics) Update(packet *Packet, decodeErr error, validationErrors []
 				s.AnomalousValues++
 			case AnomalyInvalidValue:
 				s.AnomalousValues++
+			case AnomalyInvalidTransition:
+				s.InvalidTransitions++
+				s.AnomalousValues++
+			case AnomalyTimestamp:
+				s.TimestampErrors++
+				s.AnomalousValues++
+			case AnomalyDuplicate:
+				s.DuplicatePackets++
+				s.AnomalousValues++
+			case AnomalyCustom:
+				s.CustomAnomalies++
+				s.AnomalousValues++
 			}
 		}
 	} else {
@@ -90,6 +299,237 @@ func (s *Statistics) Update(packet *Packet, decodeErr error, validationErrors []
 	s.LastUpdateTime = time.Now()
 }
 
+// recordDetails adds the offending values in an error's Details to the
+// value ranges
+func (s *Statistics) recordDetails(err ValidationError) {
+	switch err.Type {
+	case AnomalyHighRPM:
+		limit, hasLimit := detailNumber(err.Details, "max")
+		for _, key := range []string{"rpm", "target_rpm"} {
+			if v, ok := detailNumber(err.Details, key); ok && (v < 0 || !hasLimit || v > limit) {
+				s.HighRPMValues.add(v)
+			}
+		}
+	case AnomalyInvalidTemp:
+		if v, ok := detailNumber(err.Details, "value"); ok {
+			s.TempValues.add(v)
+		}
+	case AnomalyInvalidPWM:
+		pwm, ok1 := detailNumber(err.Details, "pwm")
+		pwmMax, ok2 := detailNumber(err.Details, "pwm_max")
+		if ok1 && ok2 {
+			s.PWMExcess.add(pwm - pwmMax)
+		}
+	case AnomalyInvalidCount:
+		for _, key := range []string{"motor_count", "temp_count", "pump_count", "glow_count"} {
+			if v, ok := detailNumber(err.Details, key); ok {
+				s.ComponentCounts.add(v)
+			}
+		}
+	case AnomalyTimestamp:
+		timestamp, ok1 := detailNumber(err.Details, "timestamp")
+		previous, ok2 := detailNumber(err.Details, "previous")
+		if ok1 && ok2 {
+			s.TimestampRewind.add(previous - timestamp)
+		}
+	}
+}
+
+// detailNumber returns a numeric Details value as a float64
+func detailNumber(details map[string]interface{}, key string) (float64, bool) {
+	switch v := details[key].(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// RecordStale counts devices reported stale by SessionValidator.Stale.
+// Stale devices are not packets, so they do not affect the packet counters.
+func (s *Statistics) RecordStale(staleErrors []ValidationError) {
+	for _, err := range staleErrors {
+		if err.Type == AnomalyStaleDevice {
+			s.StaleDevices++
+		}
+	}
+}
+
+// count adds a packet to the per-type and per-device breakdowns
+func (s *Statistics) count(msgType uint8, typeKnown bool, address uint64, size uint64, failed bool) {
+	if s.byType == nil {
+		s.byType = make(map[uint8]*CountStats)
+		s.byDevice = make(map[uint64]*CountStats)
+	}
+
+	var targets []*CountStats
+	if typeKnown {
+		t, ok := s.byType[msgType]
+		if !ok {
+			t = &CountStats{}
+			s.byType[msgType] = t
+		}
+		targets = append(targets, t)
+	}
+	d, ok := s.byDevice[address]
+	if !ok {
+		d = &CountStats{}
+		s.byDevice[address] = d
+	}
+	targets = append(targets, d)
+
+	for _, c := range targets {
+		c.Packets++
+		c.Bytes += size
+		if failed {
+			c.Errors++
+		}
+	}
+}
+
+// recordTiming updates the gap and telemetry interval statistics
+func (s *Statistics) recordTiming(packet *Packet) {
+	if s.typeIntervals == nil {
+		s.typeIntervals = make(map[uint8]*IntervalStats)
+		s.lastTelemetry = make(map[telemetryStream]time.Time)
+		s.configured = make(map[uint64]time.Duration)
+	}
+
+	at := packet.Timestamp()
+	if !s.lastPacket.IsZero() && !at.Before(s.lastPacket) {
+		s.Gaps.add(at.Sub(s.lastPacket))
+	}
+	s.lastPacket = at
+
+	msgType := packet.Type()
+	if msgType == MsgTelemetryConfig {
+		m := packet.PayloadMap()
+		enabled, _ := GetMapBool(m, 0)
+		intervalMs, _ := GetMapUint(m, 1)
+		if enabled && intervalMs > 0 {
+			s.configured[packet.Address()] = time.Duration(intervalMs) * time.Millisecond
+		} else {
+			delete(s.configured, packet.Address()) // Disabled or polling mode
+		}
+		return
+	}
+
+	stream := telemetryStream{address: packet.Address(), msgType: msgType}
+	switch msgType {
+	case MsgStateData:
+	case MsgMotorData, MsgPumpData, MsgGlowData, MsgTempData:
+		stream.index, _ = GetMapUint(packet.PayloadMap(), 0)
+	default:
+		return
+	}
+
+	last, seen := s.lastTelemetry[stream]
+	s.lastTelemetry[stream] = at
+	if !seen || at.Before(last) {
+		return
+	}
+
+	interval := at.Sub(last)
+	stats, ok := s.typeIntervals[msgType]
+	if !ok {
+		stats = &IntervalStats{}
+		s.typeIntervals[msgType] = stats
+	}
+	stats.add(interval)
+
+	expected := s.TelemetryInterval
+	if expected == 0 {
+		expected = s.configured[stream.address]
+	}
+	if expected > 0 && float64(interval) > float64(expected)*slowTelemetryFactor {
+		s.SlowTelemetry++
+	}
+}
+
+// TelemetryTypes returns the telemetry message types with interval
+// measurements, in ascending order
+func (s *Statistics) TelemetryTypes() []uint8 {
+	types := make([]uint8, 0, len(s.typeIntervals))
+	for t := range s.typeIntervals {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// TelemetryIntervals returns the interval statistics for a telemetry type
+func (s *Statistics) TelemetryIntervals(msgType uint8) IntervalStats {
+	if i, ok := s.typeIntervals[msgType]; ok {
+		return *i
+	}
+	return IntervalStats{}
+}
+
+// MessageTypes returns the message types seen, in ascending order
+func (s *Statistics) MessageTypes() []uint8 {
+	types := make([]uint8, 0, len(s.byType))
+	for t := range s.byType {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Devices returns the device addresses seen, in ascending order
+func (s *Statistics) Devices() []uint64 {
+	devices := make([]uint64, 0, len(s.byDevice))
+	for d := range s.byDevice {
+		devices = append(devices, d)
+	}
+	slices.Sort(devices)
+	return devices
+}
+
+// Checks returns the names of the registered validators that reported
+// errors, in ascending order
+func (s *Statistics) Checks() []string {
+	names := make([]string, 0, len(s.byCheck))
+	for name := range s.byCheck {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// CheckCount returns the number of errors a registered validator reported
+func (s *Statistics) CheckCount(name string) uint64 {
+	return s.byCheck[name]
+}
+
+// TypeStats returns the counters for a message type
+func (s *Statistics) TypeStats(msgType uint8) CountStats {
+	if c, ok := s.byType[msgType]; ok {
+		return *c
+	}
+	return CountStats{}
+}
+
+// DeviceStats returns the counters for a device address
+func (s *Statistics) DeviceStats(address uint64) CountStats {
+	if c, ok := s.byDevice[address]; ok {
+		return *c
+	}
+	return CountStats{}
+}
+
 // CalculateRates calculates packet and error rates
 func (s *Statistics) CalculateRates() {
 	elapsed := time.Since(s.StartTime).Seconds()
@@ -129,32 +569,106 @@ func (s *Statistics) String() string {
 	if s.MalformedPackets > 0 {
 		result += fmt.Sprintf("Malformed Pkts:  %8d (%.1f%%)\n", s.MalformedPackets, malformedPercent)
 		if s.InvalidCounts > 0 {
-			result += fmt.Sprintf("  Invalid Counts:   %5d\n", s.InvalidCounts)
+			result += fmt.Sprintf("  Invalid Counts:   %5d%s\n", s.InvalidCounts, worstSuffix(s.ComponentCounts, "largest %.0f"))
 		}
 		if s.LengthMismatches > 0 {
 			result += fmt.Sprintf("  Length Mismatch:  %5d\n", s.LengthMismatches)
 		}
+		if s.MissingFields > 0 {
+			result += fmt.Sprintf("  Missing Fields:   %5d\n", s.MissingFields)
+		}
 	}
 	if s.AnomalousValues > 0 {
 		result += fmt.Sprintf("Anomalous Values:%8d (%.1f%%)\n", s.AnomalousValues, anomalousPercent)
 		if s.HighRPM > 0 {
-			result += fmt.Sprintf("  High RPM (>6000): %5d\n", s.HighRPM)
+			result += fmt.Sprintf("  High RPM:         %5d%s\n", s.HighRPM, rangeSuffix(s.HighRPMValues, "%.0f", " RPM"))
 		}
 		if s.InvalidTemp > 0 {
-			result += fmt.Sprintf("  Invalid Temp:     %5d\n", s.InvalidTemp)
+			result += fmt.Sprintf("  Invalid Temp:     %5d%s\n", s.InvalidTemp, rangeSuffix(s.TempValues, "%.1f", "°C"))
 		}
 		if s.InvalidPWM > 0 {
-			result += fmt.Sprintf("  Invalid PWM:      %5d\n", s.InvalidPWM)
+			result += fmt.Sprintf("  Invalid PWM:      %5d%s\n", s.InvalidPWM, worstSuffix(s.PWMExcess, "worst %.0f µs over max"))
 		}
+		if s.InvalidTransitions > 0 {
+			result += fmt.Sprintf("  Bad Transition:   %5d\n", s.InvalidTransitions)
+		}
+		if s.TimestampErrors > 0 {
+			result += fmt.Sprintf("  Timestamp Errors: %5d%s\n", s.TimestampErrors, worstSuffix(s.TimestampRewind, "worst %.0f ms back"))
+		}
+		if s.DuplicatePackets > 0 {
+			result += fmt.Sprintf("  Duplicates:       %5d\n", s.DuplicatePackets)
+		}
+		if s.CustomAnomalies > 0 {
+			result += fmt.Sprintf("  Custom:           %5d\n", s.CustomAnomalies)
+		}
+	}
+	if s.StaleDevices > 0 {
+		result += fmt.Sprintf("Stale Devices:   %8d\n", s.StaleDevices)
 	}
 
 	result += fmt.Sprintf("Packet Rate:     %8.1f pkts/sec\n", s.PacketRate)
 	result += fmt.Sprintf("Error Rate:      %8.1f errors/sec\n", s.ErrorRate)
+
+	if s.Gaps.Count > 0 {
+		result += fmt.Sprintf("Packet Gap:      %s\n", s.Gaps.String())
+	}
+	if len(s.typeIntervals) > 0 {
+		result += "Telemetry Intervals:\n"
+		for _, t := range s.TelemetryTypes() {
+			result += fmt.Sprintf("  %-22s %s\n", FormatMessageType(t), s.typeIntervals[t].String())
+		}
+	}
+	if s.SlowTelemetry > 0 {
+		result += fmt.Sprintf("Slow Telemetry:  %8d\n", s.SlowTelemetry)
+	}
+
+	if len(s.byCheck) > 0 {
+		result += "Custom Checks:\n"
+		for _, name := range s.Checks() {
+			result += fmt.Sprintf("  %-22s %8d errors\n", name, s.byCheck[name])
+		}
+	}
+
+	if len(s.byType) > 0 {
+		result += "By Message Type:\n"
+		for _, t := range s.MessageTypes() {
+			result += formatCountStats(FormatMessageType(t), s.byType[t])
+		}
+	}
+	if len(s.byDevice) > 0 {
+		result += "By Device:\n"
+		for _, d := range s.Devices() {
+			result += formatCountStats(fmt.Sprintf("%016X", d), s.byDevice[d])
+		}
+	}
+
 	result += "================================\n"
 
 	return result
 }
 
+// rangeSuffix formats a value range for a summary line, or "" when no
+// values were recorded
+func rangeSuffix(r ValueRange, verb, unit string) string {
+	if r.Count == 0 {
+		return ""
+	}
+	return " (" + r.Format(verb) + unit + ")"
+}
+
+// worstSuffix formats the largest value of a range for a summary line, or
+// "" when no values were recorded
+func worstSuffix(r ValueRange, format string) string {
+	if r.Count == 0 {
+		return ""
+	}
+	return " (" + fmt.Sprintf(format, r.Max) + ")"
+}
+
+func formatCountStats(name string, c *CountStats) string {
+	return fmt.Sprintf("  %-22s %8d pkts %10d bytes %6d errors\n", name, c.Packets, c.Bytes, c.Errors)
+}
+
 // Reset resets all statistics counters
 func (s *Statistics) Reset() {
 	now := time.Now()
@@ -167,10 +681,30 @@ func (s *Statistics) Reset() {
 	s.MalformedPackets = 0
 	s.InvalidCounts = 0
 	s.LengthMismatches = 0
+	s.MissingFields = 0
 	s.AnomalousValues = 0
 	s.HighRPM = 0
 	s.InvalidTemp = 0
 	s.InvalidPWM = 0
+	s.InvalidTransitions = 0
+	s.TimestampErrors = 0
+	s.DuplicatePackets = 0
+	s.StaleDevices = 0
+	s.CustomAnomalies = 0
+	s.HighRPMValues = ValueRange{}
+	s.TempValues = ValueRange{}
+	s.PWMExcess = ValueRange{}
+	s.ComponentCounts = ValueRange{}
+	s.TimestampRewind = ValueRange{}
 	s.PacketRate = 0
 	s.ErrorRate = 0
+	s.byType = make(map[uint8]*CountStats)
+	s.byDevice = make(map[uint64]*CountStats)
+	s.byCheck = make(map[string]uint64)
+	s.Gaps = IntervalStats{}
+	s.SlowTelemetry = 0
+	s.lastPacket = time.Time{}
+	s.typeIntervals = make(map[uint8]*IntervalStats)
+	s.lastTelemetry = make(map[telemetryStream]time.Time)
+	s.configured = make(map[uint64]time.Duration)
 }
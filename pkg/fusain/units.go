@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnitSystem selects the units used when displaying physical values.
+// Values on the wire are always metric (temperatures in °C).
+type UnitSystem int
+
+// Unit system values
+const (
+	UnitsMetric UnitSystem = iota
+	UnitsImperial
+)
+
+// String returns the unit system name
+func (u UnitSystem) String() string {
+	switch u {
+	case UnitsMetric:
+		return "metric"
+	case UnitsImperial:
+		return "imperial"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseUnitSystem parses a unit system name ("metric" or "imperial")
+func ParseUnitSystem(name string) (UnitSystem, error) {
+	switch strings.ToLower(name) {
+	case "metric", "si", "":
+		return UnitsMetric, nil
+	case "imperial", "us":
+		return UnitsImperial, nil
+	default:
+		return UnitsMetric, fmt.Errorf("unknown unit system %q (use metric or imperial)", name)
+	}
+}
+
+// ConvertTemperature converts a Celsius value to the given unit system
+func ConvertTemperature(celsius float64, units UnitSystem) float64 {
+	if units == UnitsImperial {
+		return celsius*9.0/5.0 + 32.0
+	}
+	return celsius
+}
+
+// ToCelsius converts a temperature in the given unit system back to Celsius
+func ToCelsius(value float64, units UnitSystem) float64 {
+	if units == UnitsImperial {
+		return (value - 32.0) * 5.0 / 9.0
+	}
+	return value
+}
+
+// TemperatureUnit returns the temperature unit symbol for the given unit system
+func TemperatureUnit(units UnitSystem) string {
+	if units == UnitsImperial {
+		return "°F"
+	}
+	return "°C"
+}
+
+// FormatTemperature formats a Celsius value with one decimal place in the
+// given unit system (e.g., "21.5°C" or "70.7°F")
+func FormatTemperature(celsius float64, units UnitSystem) string {
+	return fmt.Sprintf("%.1f%s", ConvertTemperature(celsius, units), TemperatureUnit(units))
+}
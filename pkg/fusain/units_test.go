@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseUnitSystem(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    UnitSystem
+		wantErr bool
+	}{
+		{"empty defaults to metric", "", UnitsMetric, false},
+		{"metric", "metric", UnitsMetric, false},
+		{"imperial", "imperial", UnitsImperial, false},
+		{"case insensitive", "Imperial", UnitsImperial, false},
+		{"unknown", "kelvin", UnitsMetric, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUnitSystem(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUnitSystem(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseUnitSystem(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTemperature(t *testing.T) {
+	tests := []struct {
+		celsius float64
+		units   UnitSystem
+		want    string
+	}{
+		{21.5, UnitsMetric, "21.5°C"},
+		{0, UnitsImperial, "32.0°F"},
+		{100, UnitsImperial, "212.0°F"},
+		{-40, UnitsImperial, "-40.0°F"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatTemperature(tt.celsius, tt.units); got != tt.want {
+			t.Errorf("FormatTemperature(%v, %v) = %q, want %q", tt.celsius, tt.units, got, tt.want)
+		}
+	}
+}
+
+func TestToCelsius(t *testing.T) {
+	for _, celsius := range []float64{-40, 0, 21.5, 100, 850} {
+		for _, units := range []UnitSystem{UnitsMetric, UnitsImperial} {
+			got := ToCelsius(ConvertTemperature(celsius, units), units)
+			if math.Abs(got-celsius) > 1e-9 {
+				t.Errorf("ToCelsius(ConvertTemperature(%v, %v)) = %v", celsius, units, got)
+			}
+		}
+	}
+}
+
+func TestFormatPayloadMapWithOptions_Imperial(t *testing.T) {
+	payload := map[int]interface{}{
+		0: uint64(0),
+		1: uint64(1000),
+		2: float64(100.0),
+		5: float64(0.0),
+	}
+
+	result := FormatPayloadMapWithOptions(MsgTempData, payload, FormatOptions{Units: UnitsImperial})
+	if !strings.Contains(result, "212.0°F") {
+		t.Errorf("Expected reading in Fahrenheit, got: %s", result)
+	}
+	if !strings.Contains(result, "target=32.0°F") {
+		t.Errorf("Expected target in Fahrenheit, got: %s", result)
+	}
+
+	// Default options remain metric
+	result = FormatPayloadMap(MsgTempData, payload)
+	if !strings.Contains(result, "100.0°C") {
+		t.Errorf("Expected reading in Celsius, got: %s", result)
+	}
+}
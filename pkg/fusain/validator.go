@@ -3,7 +3,10 @@
 
 package fusain
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // AnomalyType represents different types of packet anomalies
 type AnomalyType int
@@ -17,13 +20,51 @@ const (
 	AnomalyInvalidValue
 	AnomalyCRCError
 	AnomalyDecodeError
+	AnomalyMissingField
+
+	// Cross-packet anomalies, reported by SessionValidator
+	AnomalyInvalidTransition
+	AnomalyTimestamp
+	AnomalyDuplicate
+	AnomalyStaleDevice
+
+	// AnomalyCustom is for registered validators (see RegisterValidator)
+	// whose anomalies fit none of the types above
+	AnomalyCustom
 )
 
+// anomalyNames are the AnomalyType names used in JSON output
+var anomalyNames = map[AnomalyType]string{
+	AnomalyInvalidCount:      "invalid_count",
+	AnomalyLengthMismatch:    "length_mismatch",
+	AnomalyHighRPM:           "high_rpm",
+	AnomalyInvalidTemp:       "invalid_temp",
+	AnomalyInvalidPWM:        "invalid_pwm",
+	AnomalyInvalidValue:      "invalid_value",
+	AnomalyCRCError:          "crc_error",
+	AnomalyDecodeError:       "decode_error",
+	AnomalyMissingField:      "missing_field",
+	AnomalyInvalidTransition: "invalid_transition",
+	AnomalyTimestamp:         "timestamp",
+	AnomalyDuplicate:         "duplicate",
+	AnomalyStaleDevice:       "stale_device",
+	AnomalyCustom:            "custom",
+}
+
+// String returns the anomaly type name, e.g. "high_rpm"
+func (t AnomalyType) String() string {
+	if name, ok := anomalyNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("anomaly_%d", int(t))
+}
+
 // ValidationError represents a packet validation failure
 type ValidationError struct {
 	Type    AnomalyType
 	Message string
 	Details map[string]interface{}
+	Check   string // Name of the registered validator that reported it ("" for built-in checks)
 }
 
 // Error implements the error interface
@@ -31,34 +72,101 @@ func (v *ValidationError) Error() string {
 	return v.Message
 }
 
+// ValidateOptions selects optional validation checks
+type ValidateOptions struct {
+	// SkipSchema disables the schema checks (see CheckSchema) that
+	// ValidatePacket runs on every CBOR payload by default
+	SkipSchema bool
+
+	// Limits are the value thresholds to check against (nil uses
+	// DefaultValidationLimits)
+	Limits *ValidationLimits
+
+	// SkipRegistered disables the checks added with RegisterValidator and
+	// RegisterGlobalValidator
+	SkipRegistered bool
+}
+
 // ValidatePacket validates packet structure and detects anomalies
 // Returns a slice of validation errors (empty if packet is valid)
 func ValidatePacket(p *Packet) []ValidationError {
-	errors := []ValidationError{}
+	return ValidatePacketWithOptions(p, ValidateOptions{})
+}
 
+// ValidatePacketWithOptions validates a packet with optional checks enabled
+func ValidatePacketWithOptions(p *Packet, opts ValidateOptions) []ValidationError {
 	// Check for CBOR parse errors first
 	if err := p.ParseError(); err != nil {
+		details := map[string]interface{}{"error": err.Error()}
+		var limitErr *CBORLimitError
+		if errors.As(err, &limitErr) {
+			details["limit"] = limitErr.Limit
+			details["max"] = limitErr.Max
+		}
 		return []ValidationError{{
 			Type:    AnomalyDecodeError,
 			Message: fmt.Sprintf("CBOR parse error: %v", err),
-			Details: map[string]interface{}{"error": err.Error()},
+			Details: details,
 		}}
 	}
 
+	errors := builtinChecks(p, opts)
+	if !opts.SkipRegistered {
+		errors = append(errors, runRegisteredValidators(p)...)
+	}
+	return errors
+}
+
+// builtinChecks runs the schema and per-type checks on a parsed packet
+func builtinChecks(p *Packet, opts ValidateOptions) []ValidationError {
+	errors := []ValidationError{}
 	msgType := p.Type()
 	payloadMap := p.PayloadMap()
 
+	limits := DefaultValidationLimits()
+	if opts.Limits != nil {
+		limits = *opts.Limits
+	}
+
+	if !opts.SkipSchema {
+		errors = append(errors, CheckSchema(p)...)
+
+		// CheckSchema has reported the missing payload; the per-type checks
+		// below would only repeat it
+		if _, hasSchema := LookupSchema(msgType); hasSchema && payloadMap == nil {
+			return errors
+		}
+	}
+
 	switch msgType {
 	case MsgStateData:
 		errors = append(errors, validateStateData(payloadMap)...)
 	case MsgMotorData:
-		errors = append(errors, validateMotorData(payloadMap)...)
+		errors = append(errors, validateMotorData(payloadMap, limits)...)
 	case MsgTempData:
-		errors = append(errors, validateTemperatureData(payloadMap)...)
+		errors = append(errors, validateTemperatureData(payloadMap, limits)...)
+	case MsgStateCommand:
+		errors = append(errors, validateStateCommand(payloadMap, limits)...)
+	case MsgMotorCommand:
+		errors = append(errors, validateMotorCommand(payloadMap, limits)...)
+	case MsgPumpCommand:
+		errors = append(errors, validatePumpCommand(payloadMap)...)
 	case MsgGlowCommand:
-		errors = append(errors, validateGlowCommand(payloadMap)...)
+		errors = append(errors, validateGlowCommand(payloadMap, limits)...)
+	case MsgTempCommand:
+		errors = append(errors, validateTempCommand(payloadMap, limits)...)
+	case MsgMotorConfig:
+		errors = append(errors, validateMotorConfig(payloadMap, limits)...)
+	case MsgPumpConfig:
+		errors = append(errors, validatePumpConfig(payloadMap)...)
+	case MsgTempConfig:
+		errors = append(errors, validatePIDGains(payloadMap, 1)...)
+	case MsgGlowConfig:
+		errors = append(errors, validateGlowConfig(payloadMap, limits)...)
+	case MsgTimeoutConfig:
+		errors = append(errors, validateTimeoutConfig(payloadMap)...)
 	case MsgDeviceAnnounce:
-		errors = append(errors, validateDeviceAnnounce(payloadMap, p.IsStateless())...)
+		errors = append(errors, validateDeviceAnnounce(payloadMap, p.IsStateless(), limits)...)
 	}
 
 	return errors
@@ -102,7 +210,7 @@ func validateStateData(m map[int]interface{}) []ValidationError {
 
 // validateMotorData validates MOTOR_DATA payload
 // CBOR keys: 0=motor, 1=timestamp, 2=rpm, 3=target, 4=max-rpm, 5=min-rpm, 6=pwm, 7=pwm-max
-func validateMotorData(m map[int]interface{}) []ValidationError {
+func validateMotorData(m map[int]interface{}, limits ValidationLimits) []ValidationError {
 	errors := []ValidationError{}
 
 	if m == nil {
@@ -118,11 +226,11 @@ func validateMotorData(m map[int]interface{}) []ValidationError {
 	pwm, hasPWM := GetMapUint(m, 6)
 	pwmMax, hasPWMMax := GetMapUint(m, 7)
 
-	if rpm > 6000 || target > 6000 {
+	if rpm > limits.MaxRPM || target > limits.MaxRPM {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyHighRPM,
-			Message: fmt.Sprintf("High RPM (rpm=%d, target=%d, max 6000)", rpm, target),
-			Details: map[string]interface{}{"rpm": rpm, "target_rpm": target, "max": 6000},
+			Message: fmt.Sprintf("High RPM (rpm=%d, target=%d, max %d)", rpm, target, limits.MaxRPM),
+			Details: map[string]interface{}{"rpm": rpm, "target_rpm": target, "max": limits.MaxRPM},
 		})
 	}
 
@@ -139,7 +247,7 @@ func validateMotorData(m map[int]interface{}) []ValidationError {
 
 // validateTemperatureData validates TEMP_DATA payload
 // CBOR keys: 0=thermometer, 1=timestamp, 2=reading, 3=temperature-rpm-control, 4=watched-motor, 5=target-temperature
-func validateTemperatureData(m map[int]interface{}) []ValidationError {
+func validateTemperatureData(m map[int]interface{}, limits ValidationLimits) []ValidationError {
 	errors := []ValidationError{}
 
 	if m == nil {
@@ -152,21 +260,132 @@ func validateTemperatureData(m map[int]interface{}) []ValidationError {
 
 	// Current temperature (key 2)
 	temp, hasTemp := GetMapFloat(m, 2)
-	if hasTemp && (temp < -50.0 || temp > 1000.0) {
+	if hasTemp && (temp < limits.MinTemp || temp > limits.MaxTemp) {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidTemp,
-			Message: fmt.Sprintf("Temperature out of range (%.1f°C, valid: -50 to 1000°C)", temp),
-			Details: map[string]interface{}{"value": temp, "min": -50.0, "max": 1000.0},
+			Message: fmt.Sprintf("Temperature out of range (%.1f°C, valid: %g to %g°C)", temp, limits.MinTemp, limits.MaxTemp),
+			Details: map[string]interface{}{"value": temp, "min": limits.MinTemp, "max": limits.MaxTemp},
 		})
 	}
 
 	// Target temperature (key 5, optional)
 	targetTemp, hasTarget := GetMapFloat(m, 5)
-	if hasTarget && (targetTemp < -50.0 || targetTemp > 1000.0) {
+	if hasTarget && (targetTemp < limits.MinTemp || targetTemp > limits.MaxTemp) {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidTemp,
-			Message: fmt.Sprintf("Target temperature out of range (%.1f°C, valid: -50 to 1000°C)", targetTemp),
-			Details: map[string]interface{}{"value": targetTemp, "min": -50.0, "max": 1000.0},
+			Message: fmt.Sprintf("Target temperature out of range (%.1f°C, valid: %g to %g°C)", targetTemp, limits.MinTemp, limits.MaxTemp),
+			Details: map[string]interface{}{"value": targetTemp, "min": limits.MinTemp, "max": limits.MaxTemp},
+		})
+	}
+
+	return errors
+}
+
+// validateStateCommand validates STATE_COMMAND payload
+// CBOR keys: 0=mode, 1=argument (optional)
+func validateStateCommand(m map[int]interface{}, limits ValidationLimits) []ValidationError {
+	errors := []ValidationError{}
+
+	if m == nil {
+		return []ValidationError{{
+			Type:    AnomalyLengthMismatch,
+			Message: "STATE_COMMAND missing payload",
+			Details: map[string]interface{}{},
+		}}
+	}
+
+	mode, _ := GetMapUint(m, 0)
+	arg, hasArg := GetMapInt(m, 1)
+
+	switch Mode(mode) {
+	case ModeIdle, ModeEmergency:
+	case ModeFan:
+		// Argument is the target RPM
+		if hasArg && arg < 0 {
+			errors = append(errors, ValidationError{
+				Type:    AnomalyInvalidValue,
+				Message: fmt.Sprintf("Invalid FAN target RPM (%d, valid: 0-%d)", arg, limits.MaxRPM),
+				Details: map[string]interface{}{"target_rpm": arg, "min": 0},
+			})
+		} else if hasArg && arg > limits.MaxRPM {
+			errors = append(errors, ValidationError{
+				Type:    AnomalyHighRPM,
+				Message: fmt.Sprintf("Invalid FAN target RPM (%d, valid: 0-%d)", arg, limits.MaxRPM),
+				Details: map[string]interface{}{"target_rpm": arg, "max": limits.MaxRPM},
+			})
+		}
+	case ModeHeat:
+		// Argument is the pump rate in milliseconds
+		if hasArg && arg < 0 {
+			errors = append(errors, ValidationError{
+				Type:    AnomalyInvalidValue,
+				Message: fmt.Sprintf("Invalid HEAT pump rate (%d ms)", arg),
+				Details: map[string]interface{}{"rate": arg, "min": 0},
+			})
+		}
+	default:
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Invalid mode=%d", mode),
+			Details: map[string]interface{}{"mode": mode},
+		})
+	}
+
+	return errors
+}
+
+// validateMotorCommand validates MOTOR_COMMAND payload
+// CBOR keys: 0=motor, 1=rpm
+func validateMotorCommand(m map[int]interface{}, limits ValidationLimits) []ValidationError {
+	errors := []ValidationError{}
+
+	if m == nil {
+		return []ValidationError{{
+			Type:    AnomalyLengthMismatch,
+			Message: "MOTOR_COMMAND missing payload",
+			Details: map[string]interface{}{},
+		}}
+	}
+
+	// Target RPM (key 1)
+	rpm, ok := GetMapInt(m, 1)
+	if ok && rpm < 0 {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Invalid target RPM (%d, valid: 0-%d)", rpm, limits.MaxRPM),
+			Details: map[string]interface{}{"target_rpm": rpm, "min": 0},
+		})
+	} else if ok && rpm > limits.MaxRPM {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyHighRPM,
+			Message: fmt.Sprintf("Invalid target RPM (%d, valid: 0-%d)", rpm, limits.MaxRPM),
+			Details: map[string]interface{}{"target_rpm": rpm, "max": limits.MaxRPM},
+		})
+	}
+
+	return errors
+}
+
+// validatePumpCommand validates PUMP_COMMAND payload
+// CBOR keys: 0=pump, 1=rate-ms
+func validatePumpCommand(m map[int]interface{}) []ValidationError {
+	errors := []ValidationError{}
+
+	if m == nil {
+		return []ValidationError{{
+			Type:    AnomalyLengthMismatch,
+			Message: "PUMP_COMMAND missing payload",
+			Details: map[string]interface{}{},
+		}}
+	}
+
+	// Pulse rate (key 1)
+	rate, ok := GetMapInt(m, 1)
+	if ok && rate < 0 {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Invalid pump rate (%d ms)", rate),
+			Details: map[string]interface{}{"rate": rate, "min": 0},
 		})
 	}
 
@@ -175,7 +394,7 @@ func validateTemperatureData(m map[int]interface{}) []ValidationError {
 
 // validateGlowCommand validates GLOW_COMMAND payload
 // CBOR keys: 0=glow, 1=duration
-func validateGlowCommand(m map[int]interface{}) []ValidationError {
+func validateGlowCommand(m map[int]interface{}, limits ValidationLimits) []ValidationError {
 	errors := []ValidationError{}
 
 	if m == nil {
@@ -188,11 +407,148 @@ func validateGlowCommand(m map[int]interface{}) []ValidationError {
 
 	// Duration (key 1)
 	duration, ok := GetMapInt(m, 1)
-	if ok && (duration < 0 || duration > 300000) {
+	if ok && (duration < 0 || duration > limits.MaxGlowDurationMs) {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Invalid glow duration (%d ms, valid: 0-%d)", duration, limits.MaxGlowDurationMs),
+			Details: map[string]interface{}{"duration": duration, "min": 0, "max": limits.MaxGlowDurationMs},
+		})
+	}
+
+	return errors
+}
+
+// validateTempCommand validates TEMP_COMMAND payload
+// CBOR keys: 0=thermometer, 1=type, 2=motor-index, 3=target-temp
+func validateTempCommand(m map[int]interface{}, limits ValidationLimits) []ValidationError {
+	errors := []ValidationError{}
+
+	if m == nil {
+		return []ValidationError{{
+			Type:    AnomalyLengthMismatch,
+			Message: "TEMP_COMMAND missing payload",
+			Details: map[string]interface{}{},
+		}}
+	}
+
+	// Target temperature (key 3), required by SET_TARGET_TEMP
+	cmdType, _ := GetMapUint(m, 1)
+	target, hasTarget := GetMapFloat(m, 3)
+	if TempCmdType(cmdType) == TempCmdSetTargetTemp && !hasTarget {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: "SET_TARGET_TEMP missing target temperature",
+			Details: map[string]interface{}{},
+		})
+	}
+	if hasTarget && (target < limits.MinTemp || target > limits.MaxTemp) {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidTemp,
+			Message: fmt.Sprintf("Target temperature out of range (%.1f°C, valid: %g to %g°C)", target, limits.MinTemp, limits.MaxTemp),
+			Details: map[string]interface{}{"value": target, "min": limits.MinTemp, "max": limits.MaxTemp},
+		})
+	}
+
+	return errors
+}
+
+// validatePIDGains checks that the PID gains of a config message, at keys
+// first (kp), first+1 (ki) and first+2 (kd), are not negative
+func validatePIDGains(m map[int]interface{}, first int) []ValidationError {
+	errors := []ValidationError{}
+	for i, name := range []string{"kp", "ki", "kd"} {
+		gain, ok := GetMapFloat(m, first+i)
+		if ok && gain < 0 {
+			errors = append(errors, ValidationError{
+				Type:    AnomalyInvalidValue,
+				Message: fmt.Sprintf("Negative PID %s (%g)", name, gain),
+				Details: map[string]interface{}{name: gain, "min": 0},
+			})
+		}
+	}
+	return errors
+}
+
+// validateMotorConfig validates MOTOR_CONFIG payload
+// CBOR keys: 0=motor, 1=pwm-period, 2-4=PID gains, 5=max-rpm, 6=min-rpm, 7=min-pwm-duty
+func validateMotorConfig(m map[int]interface{}, limits ValidationLimits) []ValidationError {
+	errors := validatePIDGains(m, 2)
+
+	maxRPM, hasMax := GetMapInt(m, 5)
+	if hasMax && (maxRPM <= 0 || maxRPM > limits.MaxRPM) {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyHighRPM,
+			Message: fmt.Sprintf("Invalid max RPM (%d, valid: 1-%d)", maxRPM, limits.MaxRPM),
+			Details: map[string]interface{}{"max_rpm": maxRPM, "max": limits.MaxRPM},
+		})
+	}
+	minRPM, hasMin := GetMapInt(m, 6)
+	if hasMin && minRPM < 0 {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Negative min RPM (%d)", minRPM),
+			Details: map[string]interface{}{"min_rpm": minRPM},
+		})
+	}
+	if hasMin && hasMax && minRPM > maxRPM {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Min RPM above max RPM (%d > %d)", minRPM, maxRPM),
+			Details: map[string]interface{}{"min_rpm": minRPM, "max_rpm": maxRPM},
+		})
+	}
+
+	return errors
+}
+
+// validatePumpConfig validates PUMP_CONFIG payload
+// CBOR keys: 0=pump, 1=pulse-ms, 2=recovery-ms
+func validatePumpConfig(m map[int]interface{}) []ValidationError {
+	errors := []ValidationError{}
+
+	// A pump pulse of 0 ms never delivers fuel
+	pulse, ok := GetMapUint(m, 1)
+	if ok && pulse == 0 {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: "Invalid pump pulse (0 ms)",
+			Details: map[string]interface{}{"pulse_ms": pulse, "min": 1},
+		})
+	}
+
+	return errors
+}
+
+// validateGlowConfig validates GLOW_CONFIG payload
+// CBOR keys: 0=glow, 1=max-duration
+func validateGlowConfig(m map[int]interface{}, limits ValidationLimits) []ValidationError {
+	errors := []ValidationError{}
+
+	duration, ok := GetMapUint(m, 1)
+	if ok && duration > uint64(limits.MaxGlowDurationMs) {
+		errors = append(errors, ValidationError{
+			Type:    AnomalyInvalidValue,
+			Message: fmt.Sprintf("Invalid glow max duration (%d ms, valid: 0-%d)", duration, limits.MaxGlowDurationMs),
+			Details: map[string]interface{}{"duration": duration, "min": 0, "max": limits.MaxGlowDurationMs},
+		})
+	}
+
+	return errors
+}
+
+// validateTimeoutConfig validates TIMEOUT_CONFIG payload
+// CBOR keys: 0=enabled, 1=timeout-ms
+func validateTimeoutConfig(m map[int]interface{}) []ValidationError {
+	errors := []ValidationError{}
+
+	// An enabled timeout of 0 ms would stop the heater immediately
+	enabled, _ := GetMapBool(m, 0)
+	timeout, ok := GetMapUint(m, 1)
+	if enabled && ok && timeout == 0 {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidValue,
-			Message: fmt.Sprintf("Invalid glow duration (%d ms, valid: 0-300000)", duration),
-			Details: map[string]interface{}{"duration": duration, "min": 0, "max": 300000},
+			Message: "Invalid command timeout (0 ms while enabled)",
+			Details: map[string]interface{}{"timeout_ms": timeout, "min": 1},
 		})
 	}
 
@@ -201,7 +557,7 @@ func validateGlowCommand(m map[int]interface{}) []ValidationError {
 
 // validateDeviceAnnounce validates DEVICE_ANNOUNCE payload
 // CBOR keys: 0=motor-count, 1=thermometer-count, 2=pump-count, 3=glow-count
-func validateDeviceAnnounce(m map[int]interface{}, isStateless bool) []ValidationError {
+func validateDeviceAnnounce(m map[int]interface{}, isStateless bool, limits ValidationLimits) []ValidationError {
 	errors := []ValidationError{}
 
 	// End-of-discovery marker uses stateless address with all zeros
@@ -222,35 +578,35 @@ func validateDeviceAnnounce(m map[int]interface{}, isStateless bool) []Validatio
 	pumpCount, _ := GetMapUint(m, 2)
 	glowCount, _ := GetMapUint(m, 3)
 
-	if motorCount > 10 {
+	if motorCount > limits.MaxComponents {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidCount,
-			Message: fmt.Sprintf("Invalid motor_count=%d (max 10)", motorCount),
-			Details: map[string]interface{}{"motor_count": motorCount, "max": 10},
+			Message: fmt.Sprintf("Invalid motor_count=%d (max %d)", motorCount, limits.MaxComponents),
+			Details: map[string]interface{}{"motor_count": motorCount, "max": limits.MaxComponents},
 		})
 	}
 
-	if tempCount > 10 {
+	if tempCount > limits.MaxComponents {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidCount,
-			Message: fmt.Sprintf("Invalid temp_count=%d (max 10)", tempCount),
-			Details: map[string]interface{}{"temp_count": tempCount, "max": 10},
+			Message: fmt.Sprintf("Invalid temp_count=%d (max %d)", tempCount, limits.MaxComponents),
+			Details: map[string]interface{}{"temp_count": tempCount, "max": limits.MaxComponents},
 		})
 	}
 
-	if pumpCount > 10 {
+	if pumpCount > limits.MaxComponents {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidCount,
-			Message: fmt.Sprintf("Invalid pump_count=%d (max 10)", pumpCount),
-			Details: map[string]interface{}{"pump_count": pumpCount, "max": 10},
+			Message: fmt.Sprintf("Invalid pump_count=%d (max %d)", pumpCount, limits.MaxComponents),
+			Details: map[string]interface{}{"pump_count": pumpCount, "max": limits.MaxComponents},
 		})
 	}
 
-	if glowCount > 10 {
+	if glowCount > limits.MaxComponents {
 		errors = append(errors, ValidationError{
 			Type:    AnomalyInvalidCount,
-			Message: fmt.Sprintf("Invalid glow_count=%d (max 10)", glowCount),
-			Details: map[string]interface{}{"glow_count": glowCount, "max": 10},
+			Message: fmt.Sprintf("Invalid glow_count=%d (max %d)", glowCount, limits.MaxComponents),
+			Details: map[string]interface{}{"glow_count": glowCount, "max": limits.MaxComponents},
 		})
 	}
 
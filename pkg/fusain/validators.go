@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Validator is an anomaly check that runs alongside the built-in ones.
+// Register it with RegisterValidator or RegisterGlobalValidator.
+type Validator interface {
+	// Validate returns the anomalies found in p (nil or empty when valid).
+	// The payload has parsed; p.PayloadMap() may still be nil for message
+	// types without a payload.
+	Validate(p *Packet) []ValidationError
+}
+
+// ValidatorFunc adapts a function to the Validator interface
+type ValidatorFunc func(p *Packet) []ValidationError
+
+// Validate calls f(p)
+func (f ValidatorFunc) Validate(p *Packet) []ValidationError {
+	return f(p)
+}
+
+// registeredValidator is a Validator in the registry
+type registeredValidator struct {
+	name    string
+	msgType uint8
+	global  bool
+	v       Validator
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []registeredValidator
+)
+
+// RegisterValidator registers a check that runs on every packet of the
+// given message type. Name identifies the check in ValidationError.Check
+// and the statistics, and must be unique.
+func RegisterValidator(name string, msgType uint8, v Validator) error {
+	return register(registeredValidator{name: name, msgType: msgType, v: v})
+}
+
+// RegisterGlobalValidator registers a check that runs on every packet
+func RegisterGlobalValidator(name string, v Validator) error {
+	return register(registeredValidator{name: name, global: true, v: v})
+}
+
+func register(r registeredValidator) error {
+	if r.name == "" {
+		return fmt.Errorf("fusain: validator name is empty")
+	}
+	if r.v == nil {
+		return fmt.Errorf("fusain: validator %q is nil", r.name)
+	}
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	for _, existing := range validators {
+		if existing.name == r.name {
+			return fmt.Errorf("fusain: validator %q already registered", r.name)
+		}
+	}
+	validators = append(validators, r)
+	return nil
+}
+
+// UnregisterValidator removes a registered check. It reports whether the
+// check was registered.
+func UnregisterValidator(name string) bool {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	for i, r := range validators {
+		if r.name == name {
+			validators = slices.Delete(validators, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// RegisteredValidators returns the names of the registered checks, in
+// registration order
+func RegisteredValidators() []string {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	names := make([]string, len(validators))
+	for i, r := range validators {
+		names[i] = r.name
+	}
+	return names
+}
+
+// runRegisteredValidators runs the registered checks that apply to p, in
+// registration order. Each error's Check is set to the check's name; a
+// check that panics is reported as an AnomalyCustom error instead of
+// crashing the caller.
+func runRegisteredValidators(p *Packet) []ValidationError {
+	validatorsMu.RLock()
+	applicable := make([]registeredValidator, 0, len(validators))
+	for _, r := range validators {
+		if r.global || r.msgType == p.Type() {
+			applicable = append(applicable, r)
+		}
+	}
+	validatorsMu.RUnlock()
+
+	var errors []ValidationError
+	for _, r := range applicable {
+		for _, err := range runValidator(r, p) {
+			err.Check = r.name
+			if err.Details == nil {
+				err.Details = map[string]interface{}{}
+			}
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// runValidator runs one check, converting a panic into an error
+func runValidator(r registeredValidator, p *Packet) (errors []ValidationError) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			errors = []ValidationError{{
+				Type:    AnomalyCustom,
+				Message: fmt.Sprintf("Validator %s panicked: %v", r.name, recovered),
+				Details: map[string]interface{}{"panic": fmt.Sprint(recovered)},
+			}}
+		}
+	}()
+	return r.v.Validate(p)
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package fusain
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// registerForTest registers a validator and removes it when the test ends
+func registerForTest(t *testing.T, name string, msgType uint8, global bool, v Validator) {
+	t.Helper()
+	var err error
+	if global {
+		err = RegisterGlobalValidator(name, v)
+	} else {
+		err = RegisterValidator(name, msgType, v)
+	}
+	if err != nil {
+		t.Fatalf("register %s: %v", name, err)
+	}
+	t.Cleanup(func() { UnregisterValidator(name) })
+}
+
+func TestRegisterValidator_PerType(t *testing.T) {
+	registerForTest(t, "reading_cap", MsgTempData, false, ValidatorFunc(func(p *Packet) []ValidationError {
+		if reading, _ := GetMapFloat(p.PayloadMap(), 2); reading > 300 {
+			return []ValidationError{{Type: AnomalyCustom, Message: "Reading above 300°C"}}
+		}
+		return nil
+	}))
+
+	errs := ValidatePacket(roundTrip(t, TempData{Reading: 350}.Encode(0x01)))
+	if len(errs) != 1 || errs[0].Type != AnomalyCustom || errs[0].Check != "reading_cap" {
+		t.Fatalf("got %+v, want one AnomalyCustom from reading_cap", errs)
+	}
+	if errs[0].Details == nil {
+		t.Error("Details should default to an empty map")
+	}
+
+	if errs := ValidatePacket(roundTrip(t, StateData{State: SysStateIdle}.Encode(0x01))); len(errs) != 0 {
+		t.Errorf("check ran on another message type: %v", errs)
+	}
+	if errs := ValidatePacketWithOptions(roundTrip(t, TempData{Reading: 350}.Encode(0x01)), ValidateOptions{SkipRegistered: true}); len(errs) != 0 {
+		t.Errorf("SkipRegistered: %v", errs)
+	}
+}
+
+func TestRegisterGlobalValidator(t *testing.T) {
+	var seen []uint8
+	registerForTest(t, "counter", 0, true, ValidatorFunc(func(p *Packet) []ValidationError {
+		seen = append(seen, p.Type())
+		return nil
+	}))
+
+	ValidatePacket(roundTrip(t, NewPingRequest(0x01)))
+	ValidatePacket(roundTrip(t, StateData{State: SysStateIdle}.Encode(0x01)))
+	if !slices.Equal(seen, []uint8{MsgPingRequest, MsgStateData}) {
+		t.Errorf("global check saw %v", seen)
+	}
+}
+
+func TestRegisterValidator_Errors(t *testing.T) {
+	noop := ValidatorFunc(func(p *Packet) []ValidationError { return nil })
+	registerForTest(t, "dup", MsgTempData, false, noop)
+
+	if err := RegisterValidator("dup", MsgMotorData, noop); err == nil {
+		t.Error("duplicate name should fail")
+	}
+	if err := RegisterGlobalValidator("", noop); err == nil {
+		t.Error("empty name should fail")
+	}
+	if err := RegisterGlobalValidator("nil", nil); err == nil {
+		t.Error("nil validator should fail")
+	}
+	if !slices.Equal(RegisteredValidators(), []string{"dup"}) {
+		t.Errorf("RegisteredValidators = %v", RegisteredValidators())
+	}
+	if UnregisterValidator("missing") {
+		t.Error("UnregisterValidator of unknown name should report false")
+	}
+}
+
+func TestRegisteredValidator_Panic(t *testing.T) {
+	registerForTest(t, "broken", 0, true, ValidatorFunc(func(p *Packet) []ValidationError {
+		panic("boom")
+	}))
+
+	errs := ValidatePacket(roundTrip(t, NewPingRequest(0x01)))
+	if len(errs) != 1 || errs[0].Type != AnomalyCustom || !strings.Contains(errs[0].Message, "boom") {
+		t.Fatalf("got %+v, want one AnomalyCustom reporting the panic", errs)
+	}
+}
+
+func TestStatistics_CustomChecks(t *testing.T) {
+	registerForTest(t, "always", MsgPingRequest, false, ValidatorFunc(func(p *Packet) []ValidationError {
+		return []ValidationError{
+			{Type: AnomalyCustom, Message: "custom"},
+			{Type: AnomalyInvalidValue, Message: "built-in type"},
+		}
+	}))
+
+	stats := NewStatistics()
+	p := roundTrip(t, NewPingRequest(0x01))
+	stats.Update(p, nil, ValidatePacket(p))
+
+	if stats.CustomAnomalies != 1 || stats.AnomalousValues != 2 {
+		t.Errorf("custom=%d anomalous=%d, want 1, 2", stats.CustomAnomalies, stats.AnomalousValues)
+	}
+	if !slices.Equal(stats.Checks(), []string{"always"}) || stats.CheckCount("always") != 2 {
+		t.Errorf("Checks = %v, CheckCount = %d", stats.Checks(), stats.CheckCount("always"))
+	}
+	if !strings.Contains(stats.String(), "Custom Checks:") {
+		t.Error("String() should list custom checks")
+	}
+}
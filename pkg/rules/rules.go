@@ -0,0 +1,398 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+// Package rules evaluates user-defined alert rules over device telemetry.
+// A rule is a condition on one telemetry value, such as "temp0 > 220" or
+// "rpm0 deviates from target by > 20%", that must hold for a duration
+// before its alert is raised. The alert clears when the condition stops
+// holding.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+	"gopkg.in/yaml.v3"
+)
+
+// Alert severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Rule is one alert rule of a rules file
+type Rule struct {
+	// Name identifies the rule in alerts
+	Name string `yaml:"name"`
+
+	// When is the condition: METRIC OP NUMBER, or METRIC deviates from
+	// target by [>|>=] NUMBER[%]. It may end with "for DURATION" instead
+	// of setting For.
+	When string `yaml:"when"`
+
+	// For is how long the condition must hold before the alert is raised
+	// (0 = at once)
+	For time.Duration `yaml:"for"`
+
+	// Device limits the rule to one device, by address or name. The
+	// caller resolves it into Address.
+	Device string `yaml:"device"`
+
+	// Severity is info, warning (the default) or critical
+	Severity string `yaml:"severity"`
+
+	// Exit asks the program to stop when the alert is raised
+	Exit bool `yaml:"exit"`
+
+	// Address is the device the rule applies to (0 = every device)
+	Address uint64 `yaml:"-"`
+
+	cond condition
+}
+
+// Condition returns the rule's condition without its duration
+func (r *Rule) Condition() string {
+	return r.cond.text
+}
+
+// condition is a parsed When
+type condition struct {
+	text        string
+	measurement string // Telemetry measurement, e.g. "motor"
+	component   string // Component index tag value ("" for state)
+	field       string // Field compared, e.g. "rpm"
+	op          string
+	threshold   float64
+	deviation   bool // Compare |field - target_field| instead of field
+	percent     bool // Deviation as a percentage of the target
+}
+
+// componentTags maps measurements to the tag holding the component index
+var componentTags = map[string]string{
+	"state":       "",
+	"motor":       "motor",
+	"pump":        "pump",
+	"glow":        "glow",
+	"temperature": "thermometer",
+}
+
+// Shorthand metric names
+var shorthands = map[string]struct{ measurement, field string }{
+	"temp": {"temperature", "temperature"},
+	"rpm":  {"motor", "rpm"},
+}
+
+var (
+	forPattern       = regexp.MustCompile(`^(.*\S)\s+for\s+(\S+)$`)
+	comparePattern   = regexp.MustCompile(`^(\S+?)\s*(>=|<=|==|!=|>|<)\s*(\S+)$`)
+	deviatesPattern  = regexp.MustCompile(`^(\S+)\s+deviates\s+from\s+target\s+by\s*(>=|>)?\s*([^\s%]+)\s*(%?)$`)
+	shorthandPattern = regexp.MustCompile(`^([a-z]+)([0-9]+)$`)
+	metricPattern    = regexp.MustCompile(`^([a-z]+?)([0-9]*)\.([a-z_]+)$`)
+)
+
+// Load reads and parses a rules file
+func Load(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Parse parses a rules file: a YAML document with a "rules" list
+func Parse(data []byte) ([]Rule, error) {
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// Compile parses one condition given outside a rules file, such as a
+// command-line trigger. The rule has the default severity.
+func Compile(name, when string) (Rule, error) {
+	r := Rule{Name: name, When: when}
+	if err := r.compile(); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+// compile parses When and checks the other fields
+func (r *Rule) compile() error {
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity %q (valid: info, warning, critical)", r.Severity)
+	}
+	if r.For < 0 {
+		return fmt.Errorf("negative duration %v", r.For)
+	}
+
+	when := strings.TrimSpace(r.When)
+	if when == "" {
+		return fmt.Errorf("no condition (when)")
+	}
+	if m := forPattern.FindStringSubmatch(when); m != nil {
+		d, err := time.ParseDuration(m[2])
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid duration %q", m[2])
+		}
+		if r.For != 0 {
+			return fmt.Errorf("duration given both in when and for")
+		}
+		when, r.For = m[1], d
+	}
+
+	c := condition{text: when}
+	var metric, number string
+	if m := deviatesPattern.FindStringSubmatch(when); m != nil {
+		metric, number = m[1], m[3]
+		c.deviation, c.percent = true, m[4] == "%"
+		c.op = m[2]
+		if c.op == "" {
+			c.op = ">"
+		}
+	} else if m := comparePattern.FindStringSubmatch(when); m != nil {
+		metric, c.op, number = m[1], m[2], m[3]
+	} else {
+		return fmt.Errorf("invalid condition %q: expected METRIC OP NUMBER or METRIC deviates from target by NUMBER[%%]", when)
+	}
+
+	threshold, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", number)
+	}
+	if c.deviation && threshold < 0 {
+		return fmt.Errorf("negative deviation %v", threshold)
+	}
+	c.threshold = threshold
+
+	if err := c.parseMetric(metric); err != nil {
+		return err
+	}
+	r.cond = c
+	return nil
+}
+
+// parseMetric parses a shorthand such as temp0 or rpm1, or a field of a
+// telemetry measurement such as motor0.pwm_us or state.error_code
+func (c *condition) parseMetric(metric string) error {
+	if m := shorthandPattern.FindStringSubmatch(metric); m != nil {
+		s, ok := shorthands[m[1]]
+		if !ok {
+			return fmt.Errorf("unknown metric %q (use temp<N>, rpm<N> or MEASUREMENT<N>.FIELD)", metric)
+		}
+		c.measurement, c.component, c.field = s.measurement, m[2], s.field
+		return nil
+	}
+
+	m := metricPattern.FindStringSubmatch(metric)
+	if m == nil {
+		return fmt.Errorf("unknown metric %q (use temp<N>, rpm<N> or MEASUREMENT<N>.FIELD)", metric)
+	}
+	tag, ok := componentTags[m[1]]
+	if !ok {
+		return fmt.Errorf("unknown measurement %q (valid: state, motor, pump, glow, temperature)", m[1])
+	}
+	if tag == "" && m[2] != "" {
+		return fmt.Errorf("%s has no components; use %s.%s", m[1], m[1], m[3])
+	}
+	if tag != "" && m[2] == "" {
+		return fmt.Errorf("%s needs a component index, e.g. %s0.%s", m[1], m[1], m[3])
+	}
+	c.measurement, c.component, c.field = m[1], m[2], m[3]
+	return nil
+}
+
+// matches reports whether a sample is the condition's metric
+func (c *condition) matches(t sinks.Telemetry) bool {
+	if t.Measurement != c.measurement {
+		return false
+	}
+	if tag := componentTags[c.measurement]; tag != "" && t.Tags[tag] != c.component {
+		return false
+	}
+	return true
+}
+
+// eval returns the value the condition compares (the field, or its
+// deviation from the target) and whether it holds. ok is false when the
+// sample lacks the field or target.
+func (c *condition) eval(t sinks.Telemetry) (value float64, holds, ok bool) {
+	value, ok = number(t.Fields[c.field])
+	if !ok {
+		return 0, false, false
+	}
+	compared := value
+	if c.deviation {
+		target, ok := number(t.Fields["target_"+c.field])
+		if !ok {
+			return 0, false, false
+		}
+		compared = math.Abs(value - target)
+		if c.percent {
+			// Any deviation from a target of zero is unbounded; don't
+			// alert on a stopped motor coasting down
+			if target == 0 {
+				return value, false, true
+			}
+			compared = 100 * compared / math.Abs(target)
+		}
+	}
+
+	switch c.op {
+	case ">":
+		holds = compared > c.threshold
+	case ">=":
+		holds = compared >= c.threshold
+	case "<":
+		holds = compared < c.threshold
+	case "<=":
+		holds = compared <= c.threshold
+	case "==":
+		holds = compared == c.threshold
+	case "!=":
+		holds = compared != c.threshold
+	}
+	return value, holds, true
+}
+
+// number converts a telemetry field value to a float; booleans are 1 or 0
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Alert is a rule's alert being raised or cleared for one device
+type Alert struct {
+	Rule    *Rule
+	Address uint64
+	Active  bool      // Raised; false when cleared
+	At      time.Time // Time of the sample that raised or cleared it
+	Since   time.Time // When the condition started to hold
+	Value   float64   // The metric's value in that sample
+}
+
+// ruleState tracks one rule for one device
+type ruleState struct {
+	since  time.Time // Zero while the condition doesn't hold
+	raised bool
+}
+
+type stateKey struct {
+	rule    int
+	address uint64
+}
+
+// Engine evaluates rules against telemetry samples. Durations are measured
+// with sample times, so replayed recordings alert as they did live. It is
+// safe for concurrent use.
+type Engine struct {
+	mu     sync.Mutex
+	rules  []Rule
+	states map[stateKey]*ruleState
+}
+
+// NewEngine creates an engine for rules
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{rules: rules, states: make(map[stateKey]*ruleState)}
+}
+
+// Rules returns the engine's rules
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Evaluate feeds a sample to every rule on its metric and returns the
+// alerts it raised or cleared
+func (e *Engine) Evaluate(t sinks.Telemetry) []Alert {
+	address, err := strconv.ParseUint(t.Tags["address"], 16, 64)
+	if err != nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []Alert
+	for i := range e.rules {
+		r := &e.rules[i]
+		if (r.Address != 0 && r.Address != address) || !r.cond.matches(t) {
+			continue
+		}
+		value, holds, ok := r.cond.eval(t)
+		if !ok {
+			continue
+		}
+
+		key := stateKey{rule: i, address: address}
+		s := e.states[key]
+		if s == nil {
+			s = &ruleState{}
+			e.states[key] = s
+		}
+		if !holds {
+			if s.raised {
+				alerts = append(alerts, Alert{Rule: r, Address: address, At: t.Time, Since: s.since, Value: value})
+			}
+			*s = ruleState{}
+			continue
+		}
+		if s.since.IsZero() {
+			s.since = t.Time
+		}
+		if !s.raised && t.Time.Sub(s.since) >= r.For {
+			s.raised = true
+			alerts = append(alerts, Alert{Rule: r, Address: address, Active: true, At: t.Time, Since: s.since, Value: value})
+		}
+	}
+	return alerts
+}
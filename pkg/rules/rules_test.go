@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/sinks"
+)
+
+var start = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// motorSample is a MOTOR_DATA sample from device 1, motor 0, at start+offset
+func motorSample(offset time.Duration, rpm, target int64) sinks.Telemetry {
+	return sinks.Telemetry{
+		Time:        start.Add(offset),
+		Measurement: "motor",
+		Tags:        map[string]string{"address": "0000000000000001", "motor": "0"},
+		Fields:      map[string]interface{}{"rpm": rpm, "target_rpm": target},
+	}
+}
+
+// tempSample is a TEMP_DATA sample from address, thermometer 0
+func tempSample(address string, offset time.Duration, reading float64) sinks.Telemetry {
+	return sinks.Telemetry{
+		Time:        start.Add(offset),
+		Measurement: "temperature",
+		Tags:        map[string]string{"address": address, "thermometer": "0"},
+		Fields:      map[string]interface{}{"temperature": reading},
+	}
+}
+
+func mustParse(t *testing.T, yaml string) []Rule {
+	t.Helper()
+	rules, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return rules
+}
+
+func TestParse(t *testing.T) {
+	rules := mustParse(t, `
+rules:
+  - name: exhaust
+    when: temp0 > 220 for 10s
+    severity: critical
+    exit: true
+  - name: fan
+    when: rpm1 deviates from target by > 20%
+    for: 5s
+  - name: pwm
+    when: motor0.pwm_us >= 900
+`)
+	if len(rules) != 3 {
+		t.Fatalf("got %d rules, want 3", len(rules))
+	}
+	if r := rules[0]; r.For != 10*time.Second || r.Condition() != "temp0 > 220" || r.Severity != SeverityCritical || !r.Exit {
+		t.Errorf("rule 0 = %+v", r)
+	}
+	if c := rules[0].cond; c.measurement != "temperature" || c.component != "0" || c.field != "temperature" || c.threshold != 220 {
+		t.Errorf("rule 0 condition = %+v", c)
+	}
+	if r := rules[1]; r.For != 5*time.Second || r.Severity != SeverityWarning || !r.cond.deviation || !r.cond.percent || r.cond.component != "1" {
+		t.Errorf("rule 1 = %+v", r)
+	}
+	if c := rules[2].cond; c.measurement != "motor" || c.field != "pwm_us" || c.op != ">=" {
+		t.Errorf("rule 2 condition = %+v", c)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		yaml, want string
+	}{
+		{"rules:\n  - when: temp0 > 1\n", "no name"},
+		{"rules:\n  - name: a\n    when: temp0 > 1\n  - name: a\n    when: temp0 > 2\n", "defined twice"},
+		{"rules:\n  - name: a\n", "no condition"},
+		{"rules:\n  - name: a\n    when: temp0 is hot\n", "invalid condition"},
+		{"rules:\n  - name: a\n    when: volts0 > 1\n", "unknown metric"},
+		{"rules:\n  - name: a\n    when: heater0.x > 1\n", "unknown measurement"},
+		{"rules:\n  - name: a\n    when: motor.rpm > 1\n", "needs a component index"},
+		{"rules:\n  - name: a\n    when: state0.error > 0\n", "has no components"},
+		{"rules:\n  - name: a\n    when: temp0 > hot\n", "invalid number"},
+		{"rules:\n  - name: a\n    when: temp0 > 1 for soon\n", "invalid duration"},
+		{"rules:\n  - name: a\n    when: temp0 > 1 for 2s\n    for: 3s\n", "both"},
+		{"rules:\n  - name: a\n    when: temp0 > 1\n    severity: dire\n", "invalid severity"},
+		{"rules:\n  - name: a\n    when: temp0 > 1\n    exits: true\n", "not found"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.yaml, err, tt.want)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	r, err := Compile("trigger", "temp0 > 220 for 2s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Condition() != "temp0 > 220" || r.For != 2*time.Second || r.Severity != SeverityWarning {
+		t.Errorf("Compile = %q for %v (%s)", r.Condition(), r.For, r.Severity)
+	}
+	if _, err := Compile("trigger", "temp0 is hot"); err == nil {
+		t.Error("Compile accepted an invalid condition")
+	}
+}
+
+func TestEngine_Duration(t *testing.T) {
+	e := NewEngine(mustParse(t, "rules:\n  - name: hot\n    when: temp0 > 220 for 10s\n"))
+	addr := "0000000000000001"
+
+	steps := []struct {
+		offset  time.Duration
+		reading float64
+		want    string // "", "raise" or "clear"
+	}{
+		{0, 230, ""},
+		{5 * time.Second, 210, ""}, // Dips below: the duration starts over
+		{6 * time.Second, 230, ""},
+		{15 * time.Second, 231, ""},
+		{16 * time.Second, 232, "raise"},
+		{17 * time.Second, 240, ""}, // Raised once
+		{18 * time.Second, 200, "clear"},
+		{19 * time.Second, 200, ""},
+	}
+	for _, step := range steps {
+		alerts := e.Evaluate(tempSample(addr, step.offset, step.reading))
+		got := ""
+		if len(alerts) == 1 {
+			got = "clear"
+			if alerts[0].Active {
+				got = "raise"
+			}
+		} else if len(alerts) > 1 {
+			t.Fatalf("at %v: %d alerts", step.offset, len(alerts))
+		}
+		if got != step.want {
+			t.Errorf("at %v: got %q, want %q", step.offset, got, step.want)
+		}
+		if got == "raise" {
+			a := alerts[0]
+			if a.Address != 1 || a.Value != 232 || !a.Since.Equal(start.Add(6*time.Second)) || a.Rule.Name != "hot" {
+				t.Errorf("alert = %+v", a)
+			}
+		}
+	}
+}
+
+func TestEngine_PerDevice(t *testing.T) {
+	rules := mustParse(t, "rules:\n  - name: hot\n    when: temp0 > 220\n  - name: only2\n    when: temp0 > 100\n")
+	rules[1].Address = 2
+	e := NewEngine(rules)
+
+	// Each device has its own state; only2 ignores device 1
+	if alerts := e.Evaluate(tempSample("0000000000000001", 0, 230)); len(alerts) != 1 || alerts[0].Rule.Name != "hot" {
+		t.Errorf("device 1 alerts = %+v", alerts)
+	}
+	if alerts := e.Evaluate(tempSample("0000000000000002", 0, 230)); len(alerts) != 2 {
+		t.Errorf("device 2 alerts = %+v, want hot and only2", alerts)
+	}
+	if alerts := e.Evaluate(tempSample("0000000000000001", time.Second, 230)); len(alerts) != 0 {
+		t.Errorf("device 1 raised again: %+v", alerts)
+	}
+
+	// Other measurements and thermometers don't affect the rules
+	other := tempSample("0000000000000001", 2*time.Second, 0)
+	other.Tags["thermometer"] = "1"
+	if alerts := e.Evaluate(other); len(alerts) != 0 {
+		t.Errorf("thermometer 1 alerts = %+v", alerts)
+	}
+	if alerts := e.Evaluate(motorSample(2*time.Second, 0, 0)); len(alerts) != 0 {
+		t.Errorf("motor alerts = %+v", alerts)
+	}
+}
+
+func TestEngine_Deviation(t *testing.T) {
+	e := NewEngine(mustParse(t, `
+rules:
+  - name: percent
+    when: rpm0 deviates from target by >20%
+  - name: absolute
+    when: rpm0 deviates from target by 500
+`))
+	names := func(alerts []Alert) string {
+		var s []string
+		for _, a := range alerts {
+			s = append(s, a.Rule.Name)
+		}
+		return strings.Join(s, ",")
+	}
+
+	if got := names(e.Evaluate(motorSample(0, 2300, 2000))); got != "" {
+		t.Errorf("15%%, 300 rpm off: alerts %q", got)
+	}
+	if got := names(e.Evaluate(motorSample(time.Second, 1500, 2000))); got != "percent" {
+		t.Errorf("25%%, 500 rpm off: alerts %q, want percent", got)
+	}
+	if got := names(e.Evaluate(motorSample(2*time.Second, 1400, 2000))); got != "absolute" {
+		t.Errorf("30%%, 600 rpm off: alerts %q, want absolute", got)
+	}
+
+	// A target of zero can't be deviated from by a percentage
+	if got := names(e.Evaluate(motorSample(3*time.Second, 800, 0))); got != "percent" {
+		t.Errorf("target 0: alerts %q, want percent cleared", got)
+	}
+
+	// Samples without the target are skipped
+	s := motorSample(4*time.Second, 0, 0)
+	delete(s.Fields, "target_rpm")
+	if got := names(e.Evaluate(s)); got != "" {
+		t.Errorf("no target: alerts %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package sinks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// PacketSink receives every decoded packet with its validation errors
+type PacketSink interface {
+	// WritePacket writes one packet received at at. anomalies is nil when
+	// the packet was not validated. Sinks may buffer; buffered packets are
+	// written out by Close.
+	WritePacket(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) error
+
+	// Close flushes buffered packets and releases the sink
+	Close() error
+}
+
+// DecodeErrorSink is implemented by packet sinks that also record frames
+// that failed to decode
+type DecodeErrorSink interface {
+	WriteDecodeError(at time.Time, err error) error
+}
+
+// Fanout delivers each packet to every registered sink from a single decode
+// loop. Telemetry sinks receive the packet converted by TelemetryFromPacket
+// (converted once, however many sinks there are); packets that are not
+// telemetry skip them.
+//
+// A failing sink doesn't stop delivery to the others: the write goes to
+// every sink and the errors are joined. A Fanout is itself a PacketSink,
+// DecodeErrorSink and AlertSink, so fan-outs nest. It is safe for
+// concurrent use.
+type Fanout struct {
+	mu        sync.Mutex
+	packets   []PacketSink
+	telemetry []TelemetrySink
+	closed    bool
+}
+
+// NewFanout creates a fan-out with no sinks
+func NewFanout() *Fanout {
+	return &Fanout{}
+}
+
+// AddPacketSink registers a sink for every packet
+func (f *Fanout) AddPacketSink(s PacketSink) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.packets = append(f.packets, s)
+}
+
+// AddTelemetrySink registers a sink for telemetry samples
+func (f *Fanout) AddTelemetrySink(s TelemetrySink) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.telemetry = append(f.telemetry, s)
+}
+
+// Len returns the number of registered sinks
+func (f *Fanout) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.packets) + len(f.telemetry)
+}
+
+// WritePacket delivers p to every packet sink and, for telemetry packets,
+// its sample to every telemetry sink. Writes after Close are dropped.
+func (f *Fanout) WritePacket(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+
+	var errs []error
+	for _, s := range f.packets {
+		if err := s.WritePacket(at, p, anomalies); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(f.telemetry) > 0 {
+		if sample, ok := TelemetryFromPacket(p); ok {
+			for _, s := range f.telemetry {
+				if err := s.WriteTelemetry(sample); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteDecodeError delivers a decode error to the packet sinks that
+// implement DecodeErrorSink
+func (f *Fanout) WriteDecodeError(at time.Time, decodeErr error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+
+	var errs []error
+	for _, s := range f.packets {
+		if es, ok := s.(DecodeErrorSink); ok {
+			if err := es.WriteDecodeError(at, decodeErr); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteAlert delivers an alert to the packet sinks that implement AlertSink
+// and, as a TelemetryFromAlert sample, to every telemetry sink
+func (f *Fanout) WriteAlert(a Alert) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+
+	var errs []error
+	for _, s := range f.packets {
+		if as, ok := s.(AlertSink); ok {
+			if err := as.WriteAlert(a); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(f.telemetry) > 0 {
+		sample := TelemetryFromAlert(a)
+		for _, s := range f.telemetry {
+			if err := s.WriteTelemetry(sample); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink, in the order they were added. Later calls do
+// nothing.
+func (f *Fanout) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	var errs []error
+	for _, s := range f.packets {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, s := range f.telemetry {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// recordingSink counts what it receives and fails with err
+type recordingSink struct {
+	packets, telemetry, decodeErrors, closes int
+	err                                      error
+}
+
+func (s *recordingSink) WritePacket(_ time.Time, _ *fusain.Packet, _ []fusain.ValidationError) error {
+	s.packets++
+	return s.err
+}
+
+func (s *recordingSink) WriteTelemetry(Telemetry) error {
+	s.telemetry++
+	return s.err
+}
+
+func (s *recordingSink) WriteDecodeError(time.Time, error) error {
+	s.decodeErrors++
+	return s.err
+}
+
+func (s *recordingSink) Close() error {
+	s.closes++
+	return s.err
+}
+
+// capturingSink keeps the telemetry it receives
+type capturingSink struct {
+	samples []Telemetry
+}
+
+func (s *capturingSink) WriteTelemetry(t Telemetry) error {
+	s.samples = append(s.samples, t)
+	return nil
+}
+
+func (s *capturingSink) Close() error { return nil }
+
+func TestFanout(t *testing.T) {
+	failing := &recordingSink{err: errors.New("disk full")}
+	packets := &recordingSink{}
+	telemetry := &recordingSink{}
+
+	f := NewFanout()
+	f.AddPacketSink(failing)
+	f.AddPacketSink(packets)
+	f.AddTelemetrySink(telemetry)
+	if f.Len() != 3 {
+		t.Errorf("Len = %d, want 3", f.Len())
+	}
+
+	// The failing sink doesn't keep the packet from the others
+	err := f.WritePacket(sampleTime, fusain.MotorData{RPM: 2000}.Encode(1), nil)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("WritePacket error = %v, want the failing sink's error", err)
+	}
+	if err := f.WritePacket(sampleTime, fusain.NewPingRequest(1), nil); !errors.Is(err, failing.err) {
+		t.Errorf("WritePacket error = %v", err)
+	}
+	f.WriteDecodeError(sampleTime, errors.New("CRC mismatch"))
+
+	if packets.packets != 2 || packets.decodeErrors != 1 {
+		t.Errorf("packet sink got %d packets, %d decode errors, want 2 and 1", packets.packets, packets.decodeErrors)
+	}
+	if telemetry.telemetry != 1 || telemetry.packets != 0 {
+		t.Errorf("telemetry sink got %d samples, want 1 (PING_REQUEST is not telemetry)", telemetry.telemetry)
+	}
+
+	if err := f.Close(); !errors.Is(err, failing.err) {
+		t.Errorf("Close error = %v", err)
+	}
+	f.Close()
+	f.WritePacket(sampleTime, fusain.MotorData{}.Encode(1), nil)
+	if packets.closes != 1 || telemetry.closes != 1 || packets.packets != 2 {
+		t.Errorf("after Close: %d closes, %d packets, want 1 close and no new packets", packets.closes, packets.packets)
+	}
+}
+
+func TestJSONLSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewJSONLWriter(&buf)
+	anomaly := fusain.ValidationError{Type: fusain.AnomalyInvalidValue, Message: "RPM out of range"}
+
+	f := NewFanout()
+	f.AddPacketSink(s)
+	f.WritePacket(sampleTime, fusain.MotorData{RPM: 9000}.Encode(1), []fusain.ValidationError{anomaly})
+	f.WriteDecodeError(sampleTime, errors.New("CRC mismatch"))
+	f.WriteAlert(Alert{Time: sampleTime, Rule: "overspeed", Severity: "warning", Condition: "rpm0 > 8000", Address: "0000000000000001", Active: true, Value: 9000})
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	var records []map[string]interface{}
+	for _, line := range lines {
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	packet, _ := records[0]["packet"].(map[string]interface{})
+	if records[0]["kind"] != "packet" || packet["type"] != "MOTOR_DATA" || records[0]["anomalies"] == nil {
+		t.Errorf("packet record = %s", lines[0])
+	}
+	if records[1]["kind"] != "decode_error" || records[1]["error"] != "CRC mismatch" {
+		t.Errorf("decode error record = %s", lines[1])
+	}
+	alert, _ := records[2]["alert"].(map[string]interface{})
+	if records[2]["kind"] != "alert" || alert["rule"] != "overspeed" || alert["active"] != true || alert["value"] != 9000.0 {
+		t.Errorf("alert record = %s", lines[2])
+	}
+}
+
+func TestJSONLSink_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "packets.jsonl")
+	s, err := NewJSONLFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.WriteDecodeError(sampleTime, errors.New("before"))
+	rotated := filepath.Join(dir, "packets.jsonl.1")
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	s.WriteDecodeError(sampleTime, errors.New("after"))
+
+	for file, want := range map[string]string{rotated: "before", path: "after"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], want) {
+			t.Errorf("%s = %q, want one %q record", filepath.Base(file), data, want)
+		}
+	}
+}
+
+func TestFanout_WriteAlert(t *testing.T) {
+	packets := &recordingSink{}
+	telemetry := &capturingSink{}
+
+	f := NewFanout()
+	f.AddPacketSink(packets) // Not an AlertSink
+	f.AddTelemetrySink(telemetry)
+	if err := f.WriteAlert(Alert{Time: sampleTime, Rule: "hot", Severity: "critical", Address: "0000000000000001", Value: 230}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(telemetry.samples) != 1 {
+		t.Fatalf("telemetry sink got %d samples, want 1", len(telemetry.samples))
+	}
+	sample := telemetry.samples[0]
+	if sample.Measurement != "alert" || sample.Tags["rule"] != "hot" || sample.Tags["severity"] != "critical" ||
+		sample.Fields["active"] != false || sample.Fields["value"] != 230.0 || !sample.Time.Equal(sampleTime) {
+		t.Errorf("alert sample = %+v", sample)
+	}
+}
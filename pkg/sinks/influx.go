@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package sinks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for InfluxConfig fields left zero
+const (
+	defaultInfluxBatchSize     = 500
+	defaultInfluxFlushInterval = time.Second
+)
+
+// InfluxConfig configures an InfluxDB v2 HTTP write sink
+type InfluxConfig struct {
+	URL    string // Server URL (e.g. http://localhost:8086)
+	Org    string
+	Bucket string
+	Token  string // API token (sent as "Authorization: Token ...")
+
+	// Samples are written in batches of up to BatchSize lines, or when
+	// FlushInterval has passed since the last write to the server
+	BatchSize     int
+	FlushInterval time.Duration
+
+	Client *http.Client // nil uses http.DefaultClient
+}
+
+// InfluxSink writes telemetry as InfluxDB line protocol, either to an
+// io.Writer (one line per sample, unbuffered) or to the InfluxDB v2 HTTP
+// write API in batches.
+//
+// An InfluxSink is safe for concurrent use.
+type InfluxSink struct {
+	mu sync.Mutex
+	w  io.Writer
+
+	cfg       InfluxConfig
+	writeURL  string
+	batch     bytes.Buffer
+	lines     int
+	lastFlush time.Time
+}
+
+// NewInfluxWriter creates a sink that writes line protocol to w
+func NewInfluxWriter(w io.Writer) *InfluxSink {
+	return &InfluxSink{w: w}
+}
+
+// NewInfluxHTTP creates a sink that writes to the InfluxDB v2 HTTP API
+func NewInfluxHTTP(cfg InfluxConfig) (*InfluxSink, error) {
+	if cfg.URL == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("influx: URL and bucket are required")
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.URL, "/") + "/api/v2/write")
+	if err != nil {
+		return nil, fmt.Errorf("influx: invalid URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("org", cfg.Org)
+	q.Set("bucket", cfg.Bucket)
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultInfluxBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultInfluxFlushInterval
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &InfluxSink{cfg: cfg, writeURL: u.String(), lastFlush: time.Now()}, nil
+}
+
+// WriteTelemetry implements TelemetrySink. In HTTP mode the batch is sent
+// once it is full or FlushInterval has passed; a failed batch is dropped
+// and its error returned.
+func (s *InfluxSink) WriteTelemetry(t Telemetry) error {
+	line := FormatLineProtocol(t)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w != nil {
+		_, err := io.WriteString(s.w, line+"\n")
+		return err
+	}
+
+	s.batch.WriteString(line)
+	s.batch.WriteByte('\n')
+	s.lines++
+	if s.lines >= s.cfg.BatchSize || time.Since(s.lastFlush) >= s.cfg.FlushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+// Flush sends buffered samples to the server
+func (s *InfluxSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *InfluxSink) flush() error {
+	s.lastFlush = time.Now()
+	if s.w != nil || s.lines == 0 {
+		return nil
+	}
+	body := bytes.NewReader(s.batch.Bytes())
+	lines := s.lines
+	defer func() {
+		s.batch.Reset()
+		s.lines = 0
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, s.writeURL, body)
+	if err != nil {
+		return fmt.Errorf("influx: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.cfg.Token)
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx: writing %d samples: %w", lines, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influx: writing %d samples: %s: %s", lines, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Close implements TelemetrySink by flushing buffered samples
+func (s *InfluxSink) Close() error {
+	return s.Flush()
+}
+
+// FormatLineProtocol formats a sample as one line of InfluxDB line
+// protocol. Tags and fields are sorted by key; integers get the "i" suffix
+// and the timestamp is in nanoseconds.
+func FormatLineProtocol(t Telemetry) string {
+	var b strings.Builder
+	b.WriteString(lineEscaper.Replace(t.Measurement))
+
+	for _, k := range sortedKeys(t.Tags) {
+		if t.Tags[k] == "" {
+			continue // Empty tag values are not allowed
+		}
+		b.WriteByte(',')
+		b.WriteString(tagEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(tagEscaper.Replace(t.Tags[k]))
+	}
+
+	sep := byte(' ')
+	for _, k := range sortedKeys(t.Fields) {
+		b.WriteByte(sep)
+		sep = ','
+		b.WriteString(tagEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(formatFieldValue(t.Fields[k]))
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(t.Time.UnixNano(), 10))
+	return b.String()
+}
+
+var (
+	lineEscaper  = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper   = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	fieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// formatFieldValue formats a field value in line protocol syntax
+func formatFieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10) + "i"
+	case int:
+		return strconv.Itoa(v) + "i"
+	case uint64:
+		return strconv.FormatUint(v, 10) + "i"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return `"` + fieldEscaper.Replace(v) + `"`
+	default:
+		return `"` + fieldEscaper.Replace(fmt.Sprint(v)) + `"`
+	}
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package sinks
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+var sampleTime = time.Unix(1700000000, 5)
+
+func TestTelemetryFromPacket(t *testing.T) {
+	target := 180.5
+	tests := []struct {
+		name string
+		p    *fusain.Packet
+		want string
+	}{
+		{
+			"state",
+			fusain.StateData{State: fusain.SysStateHeating, Timestamp: 1500}.Encode(1),
+			`state,address=0000000000000001 device_time_ms=1500i,error=false,error_code=0i,state="HEATING",state_code=5i 1700000000000000005`,
+		},
+		{
+			"motor",
+			fusain.MotorData{Motor: 1, RPM: 2400, Target: 2500, Timestamp: 10}.Encode(0xAB),
+			`motor,address=00000000000000AB,motor=1 device_time_ms=10i,rpm=2400i,target_rpm=2500i 1700000000000000005`,
+		},
+		{
+			"temperature",
+			fusain.TempData{Thermometer: 0, Reading: 21.25, TargetTemperature: &target}.Encode(1),
+			`temperature,address=0000000000000001,thermometer=0 device_time_ms=0i,target_temperature=180.5,temperature=21.25 1700000000000000005`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, ok := TelemetryFromPacket(tt.p)
+			if !ok {
+				t.Fatal("TelemetryFromPacket returned false")
+			}
+			sample.Time = sampleTime
+			if got := FormatLineProtocol(sample); got != tt.want {
+				t.Errorf("line = %s\nwant   %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := TelemetryFromPacket(fusain.NewPingRequest(1)); ok {
+		t.Error("PING_REQUEST converted to telemetry")
+	}
+}
+
+func TestFormatLineProtocol_Escaping(t *testing.T) {
+	got := FormatLineProtocol(Telemetry{
+		Time:        sampleTime,
+		Measurement: "my measurement",
+		Tags:        map[string]string{"a,b": "x=y z", "empty": ""},
+		Fields:      map[string]interface{}{"msg": `say "hi" \o/`},
+	})
+	want := `my\ measurement,a\,b=x\=y\ z msg="say \"hi\" \\o/" 1700000000000000005`
+	if got != want {
+		t.Errorf("line = %s\nwant   %s", got, want)
+	}
+}
+
+func TestInfluxWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewInfluxWriter(&buf)
+	sample, _ := TelemetryFromPacket(fusain.GlowData{Glow: 0, Lit: true}.Encode(1))
+	sample.Time = sampleTime
+
+	if err := sink.WriteTelemetry(sample); err != nil {
+		t.Fatal(err)
+	}
+	want := "glow,address=0000000000000001,glow=0 device_time_ms=0i,lit=true 1700000000000000005\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestInfluxHTTP_Batches(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewInfluxHTTP(InfluxConfig{
+		URL: server.URL, Org: "home", Bucket: "heaters", Token: "secret",
+		BatchSize: 2, FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sample, _ := TelemetryFromPacket(fusain.MotorData{RPM: 100}.Encode(1))
+	for i := 0; i < 3; i++ {
+		if err := sink.WriteTelemetry(sample); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requests) != 1 {
+		t.Fatalf("%d requests after 3 samples with batch size 2, want 1", len(requests))
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("%d requests after Close, want 2", len(requests))
+	}
+
+	r := requests[0]
+	if r.URL.Path != "/api/v2/write" || r.URL.Query().Get("bucket") != "heaters" ||
+		r.URL.Query().Get("org") != "home" || r.URL.Query().Get("precision") != "ns" {
+		t.Errorf("request URL = %s", r.URL)
+	}
+	if got := r.Header.Get("Authorization"); got != "Token secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if n := strings.Count(bodies[0], "\n"); n != 2 {
+		t.Errorf("first batch has %d lines, want 2", n)
+	}
+	if n := strings.Count(bodies[1], "\n"); n != 1 {
+		t.Errorf("second batch has %d lines, want 1", n)
+	}
+}
+
+func TestInfluxHTTP_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"bucket not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	sink, err := NewInfluxHTTP(InfluxConfig{URL: server.URL, Bucket: "missing", BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample, _ := TelemetryFromPacket(fusain.MotorData{}.Encode(1))
+	err = sink.WriteTelemetry(sample)
+	if err == nil || !strings.Contains(err.Error(), "bucket not found") {
+		t.Errorf("err = %v, want the server's message", err)
+	}
+
+	if _, err := NewInfluxHTTP(InfluxConfig{URL: server.URL}); err == nil {
+		t.Error("NewInfluxHTTP accepted a config without a bucket")
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package sinks
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// jsonlRecord is one line of a JSONLSink, in the same shape as the
+// "packet", "decode_error" and "alert" records of heliostat's --output json
+type jsonlRecord struct {
+	Kind      string                   `json:"kind"`
+	Time      time.Time                `json:"time"`
+	Packet    *fusain.Packet           `json:"packet,omitempty"`
+	Anomalies []fusain.ValidationError `json:"anomalies,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+	Alert     *Alert                   `json:"alert,omitempty"`
+}
+
+// JSONLSink writes packets, decode errors and alerts as JSON Lines, one object per
+// line. Each line is written with a single Write, so a file being followed
+// never shows half a record.
+type JSONLSink struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	path   string // File opened by NewJSONLFile, for Reopen
+}
+
+// NewJSONLWriter creates a sink writing to w. Close doesn't close w.
+func NewJSONLWriter(w io.Writer) *JSONLSink {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONLSink{enc: enc}
+}
+
+// NewJSONLFile creates a sink appending to the file at path, creating it if
+// needed. Close closes the file.
+func NewJSONLFile(path string) (*JSONLSink, error) {
+	f, err := openJSONLFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := NewJSONLWriter(f)
+	s.closer = f
+	s.path = path
+	return s, nil
+}
+
+func openJSONLFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+}
+
+// Reopen closes the file opened by NewJSONLFile and opens its path again,
+// so a log rotated by renaming continues in a new file. On error the sink
+// keeps writing to the old file.
+func (s *JSONLSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" || s.closer == nil {
+		return nil
+	}
+	f, err := openJSONLFile(s.path)
+	if err != nil {
+		return err
+	}
+	s.closer.Close()
+	s.enc = json.NewEncoder(f)
+	s.enc.SetEscapeHTML(false)
+	s.closer = f
+	return nil
+}
+
+func (s *JSONLSink) write(r jsonlRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(r)
+}
+
+// WritePacket writes a "packet" record
+func (s *JSONLSink) WritePacket(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) error {
+	return s.write(jsonlRecord{Kind: "packet", Time: at, Packet: p, Anomalies: anomalies})
+}
+
+// WriteDecodeError writes a "decode_error" record
+func (s *JSONLSink) WriteDecodeError(at time.Time, err error) error {
+	return s.write(jsonlRecord{Kind: "decode_error", Time: at, Error: err.Error()})
+}
+
+// WriteAlert writes an "alert" record
+func (s *JSONLSink) WriteAlert(a Alert) error {
+	return s.write(jsonlRecord{Kind: "alert", Time: a.Time, Alert: &a})
+}
+
+// Close closes the file opened by NewJSONLFile
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closer == nil {
+		return nil
+	}
+	err := s.closer.Close()
+	s.closer = nil
+	return err
+}
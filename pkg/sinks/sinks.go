@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+// Package sinks defines the outputs heliostat writes decoded data to. A
+// PacketSink receives every decoded packet and a TelemetrySink one
+// Telemetry point per telemetry packet; a Fanout drives any number of both
+// from the same decode loop, so a time-series database, a file and a
+// message broker can be fed at once.
+package sinks
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// Telemetry is one telemetry sample from a device: a measurement ("state",
+// "motor", "pump", "glow" or "temperature", or "alert" for alerts), tags
+// identifying the device and component, and the decoded values as fields.
+//
+// Tags always include "address" (16 hex digits) and, for component
+// telemetry, the component index ("motor", "pump", "glow" or
+// "thermometer"). Field values are int64, float64, bool or string.
+type Telemetry struct {
+	Time        time.Time
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+}
+
+// TelemetrySink receives decoded telemetry
+type TelemetrySink interface {
+	// WriteTelemetry writes one sample. Sinks may buffer; buffered samples
+	// are written out by Close.
+	WriteTelemetry(t Telemetry) error
+
+	// Close flushes buffered samples and releases the sink
+	Close() error
+}
+
+// Alert is an alert rule being raised or cleared on a device
+type Alert struct {
+	Time      time.Time `json:"-"`
+	Rule      string    `json:"rule"`
+	Severity  string    `json:"severity"`
+	Condition string    `json:"condition"`
+	Address   string    `json:"address"` // 16 hex digits
+	Active    bool      `json:"active"`  // Raised; false when cleared
+	Value     float64   `json:"value"`
+	Since     time.Time `json:"since"` // When the condition started to hold
+}
+
+// AlertSink is implemented by packet sinks that also record alerts
+type AlertSink interface {
+	WriteAlert(a Alert) error
+}
+
+// TelemetryFromAlert converts an alert into an "alert" sample, tagged with
+// the device, rule and severity, so telemetry sinks can store alerts next
+// to the data that raised them
+func TelemetryFromAlert(a Alert) Telemetry {
+	return Telemetry{
+		Time:        a.Time,
+		Measurement: "alert",
+		Tags:        map[string]string{"address": a.Address, "rule": a.Rule, "severity": a.Severity},
+		Fields: map[string]interface{}{
+			"active":    a.Active,
+			"value":     a.Value,
+			"condition": a.Condition,
+		},
+	}
+}
+
+// TelemetryFromPacket converts a telemetry data packet (STATE_DATA,
+// MOTOR_DATA, PUMP_DATA, GLOW_DATA or TEMP_DATA) into a sample stamped with
+// the packet's receive time. It returns false for other packets and for
+// packets missing required fields.
+func TelemetryFromPacket(p *fusain.Packet) (Telemetry, bool) {
+	t := Telemetry{
+		Time:   p.Timestamp(),
+		Tags:   map[string]string{"address": fmt.Sprintf("%016X", p.Address())},
+		Fields: make(map[string]interface{}),
+	}
+
+	switch p.Type() {
+	case fusain.MsgStateData:
+		d, err := fusain.DecodeStateData(p)
+		if err != nil {
+			return Telemetry{}, false
+		}
+		t.Measurement = "state"
+		t.Fields["state"] = fusain.FormatState(uint32(d.State))
+		t.Fields["state_code"] = int64(d.State)
+		t.Fields["error"] = d.Error
+		t.Fields["error_code"] = int64(d.Code)
+		t.Fields["device_time_ms"] = int64(d.Timestamp)
+
+	case fusain.MsgMotorData:
+		d, err := fusain.DecodeMotorData(p)
+		if err != nil {
+			return Telemetry{}, false
+		}
+		t.Measurement = "motor"
+		t.Tags["motor"] = strconv.Itoa(int(d.Motor))
+		t.Fields["rpm"] = int64(d.RPM)
+		t.Fields["target_rpm"] = int64(d.Target)
+		t.Fields["device_time_ms"] = int64(d.Timestamp)
+		if d.MaxRPM != nil {
+			t.Fields["max_rpm"] = int64(*d.MaxRPM)
+		}
+		if d.MinRPM != nil {
+			t.Fields["min_rpm"] = int64(*d.MinRPM)
+		}
+		if d.PWM != nil {
+			t.Fields["pwm_us"] = int64(*d.PWM)
+		}
+		if d.PWMMax != nil {
+			t.Fields["pwm_max_us"] = int64(*d.PWMMax)
+		}
+
+	case fusain.MsgPumpData:
+		d, err := fusain.DecodePumpData(p)
+		if err != nil {
+			return Telemetry{}, false
+		}
+		t.Measurement = "pump"
+		t.Tags["pump"] = strconv.Itoa(int(d.Pump))
+		t.Fields["event"] = int64(d.Event)
+		t.Fields["device_time_ms"] = int64(d.Timestamp)
+		if d.Rate != nil {
+			t.Fields["rate_ms"] = int64(*d.Rate)
+		}
+
+	case fusain.MsgGlowData:
+		d, err := fusain.DecodeGlowData(p)
+		if err != nil {
+			return Telemetry{}, false
+		}
+		t.Measurement = "glow"
+		t.Tags["glow"] = strconv.Itoa(int(d.Glow))
+		t.Fields["lit"] = d.Lit
+		t.Fields["device_time_ms"] = int64(d.Timestamp)
+
+	case fusain.MsgTempData:
+		d, err := fusain.DecodeTempData(p)
+		if err != nil {
+			return Telemetry{}, false
+		}
+		t.Measurement = "temperature"
+		t.Tags["thermometer"] = strconv.Itoa(int(d.Thermometer))
+		t.Fields["temperature"] = d.Reading
+		t.Fields["device_time_ms"] = int64(d.Timestamp)
+		if d.RPMControl != nil {
+			t.Fields["rpm_control"] = *d.RPMControl
+		}
+		if d.WatchedMotor != nil {
+			t.Fields["watched_motor"] = int64(*d.WatchedMotor)
+		}
+		if d.TargetTemperature != nil {
+			t.Fields["target_temperature"] = *d.TargetTemperature
+		}
+
+	default:
+		return Telemetry{}, false
+	}
+	return t, true
+}
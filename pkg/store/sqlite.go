@@ -0,0 +1,443 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// schemaVersion is stored in PRAGMA user_version
+const schemaVersion = 1
+
+// schema creates the tables. Times are Unix nanoseconds and addresses the
+// 64-bit address stored as a signed integer (same bits), so the stateless
+// address round-trips.
+const schema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id         INTEGER PRIMARY KEY,
+	started_at INTEGER NOT NULL,
+	connection TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS packets (
+	id         INTEGER PRIMARY KEY,
+	session_id INTEGER NOT NULL REFERENCES sessions(id),
+	time       INTEGER NOT NULL,
+	address    INTEGER NOT NULL,
+	type       INTEGER NOT NULL,
+	type_name  TEXT NOT NULL,
+	frame      BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS packets_time ON packets(time);
+CREATE INDEX IF NOT EXISTS packets_address_time ON packets(address, time);
+CREATE INDEX IF NOT EXISTS packets_type_time ON packets(type, time);
+CREATE TABLE IF NOT EXISTS validation_errors (
+	id         INTEGER PRIMARY KEY,
+	session_id INTEGER NOT NULL REFERENCES sessions(id),
+	packet_id  INTEGER REFERENCES packets(id),
+	time       INTEGER NOT NULL,
+	address    INTEGER NOT NULL,
+	type       INTEGER NOT NULL,
+	anomaly    TEXT NOT NULL,
+	check_name TEXT NOT NULL,
+	message    TEXT NOT NULL,
+	details    TEXT
+);
+CREATE INDEX IF NOT EXISTS validation_errors_time ON validation_errors(time);
+CREATE TABLE IF NOT EXISTS decode_errors (
+	id         INTEGER PRIMARY KEY,
+	session_id INTEGER NOT NULL REFERENCES sessions(id),
+	time       INTEGER NOT NULL,
+	message    TEXT NOT NULL,
+	frame      BLOB
+);
+CREATE INDEX IF NOT EXISTS decode_errors_time ON decode_errors(time);
+CREATE TABLE IF NOT EXISTS stats_snapshots (
+	id         INTEGER PRIMARY KEY,
+	session_id INTEGER NOT NULL REFERENCES sessions(id),
+	time       INTEGER NOT NULL,
+	stats      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS stats_snapshots_time ON stats_snapshots(time);
+`
+
+// SQLite stores sessions in an SQLite database file.
+//
+// Writes are collected in a transaction that is committed by Flush (and
+// Close), so a recorder writing hundreds of packets a second does one
+// commit per flush interval. A SQLite store is safe for concurrent use.
+type SQLite struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	tx      *sql.Tx
+	session int64
+}
+
+var _ Store = (*SQLite)(nil)
+
+func init() {
+	Register("sqlite", func(dsn string) (Store, error) {
+		return OpenSQLite(dsn)
+	})
+}
+
+// OpenSQLite opens or creates the database at path and its schema
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	// One connection: SQLite serializes writers anyway, and the pragmas
+	// below are per connection
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if version > schemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("open %s: schema version %d is newer than supported (%d)", path, version, schemaVersion)
+	}
+
+	for _, stmt := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = NORMAL",
+		"PRAGMA busy_timeout = 5000",
+		schema,
+		fmt.Sprintf("PRAGMA user_version = %d", schemaVersion),
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("open %s: %w", path, err)
+		}
+	}
+	return &SQLite{db: db}, nil
+}
+
+// StartSession starts a new session; later writes belong to it
+func (s *SQLite) StartSession(at time.Time, connection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.commit(); err != nil {
+		return err
+	}
+	res, err := s.db.Exec("INSERT INTO sessions (started_at, connection) VALUES (?, ?)", at.UnixNano(), connection)
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	s.session, err = res.LastInsertId()
+	return err
+}
+
+// begin returns the open write transaction, starting one if needed.
+// Callers hold s.mu.
+func (s *SQLite) begin() (*sql.Tx, error) {
+	if s.session == 0 {
+		return nil, fmt.Errorf("no session started")
+	}
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		s.tx = tx
+	}
+	return s.tx, nil
+}
+
+// commit commits the open write transaction. Callers hold s.mu.
+func (s *SQLite) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	err := s.tx.Commit()
+	s.tx = nil
+	return err
+}
+
+// WritePacket stores a decoded packet received at at, with its validation
+// errors
+func (s *SQLite) WritePacket(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) error {
+	frame := p.RawBytes()
+	if frame == nil {
+		frame = fusain.MustEncodePacket(p)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec("INSERT INTO packets (session_id, time, address, type, type_name, frame) VALUES (?, ?, ?, ?, ?, ?)",
+		s.session, at.UnixNano(), int64(p.Address()), p.Type(), fusain.FormatMessageType(p.Type()), frame)
+	if err != nil {
+		return fmt.Errorf("write packet: %w", err)
+	}
+	packetID, _ := res.LastInsertId()
+
+	for _, a := range anomalies {
+		var details []byte
+		if len(a.Details) > 0 {
+			details, _ = json.Marshal(a.Details)
+		}
+		_, err := tx.Exec("INSERT INTO validation_errors (session_id, packet_id, time, address, type, anomaly, check_name, message, details) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			s.session, packetID, at.UnixNano(), int64(p.Address()), p.Type(), a.Type.String(), a.Check, a.Message, nullString(details))
+		if err != nil {
+			return fmt.Errorf("write validation error: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteDecodeError stores a frame the decoder rejected (frame may be nil)
+func (s *SQLite) WriteDecodeError(at time.Time, decodeErr error, frame []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO decode_errors (session_id, time, message, frame) VALUES (?, ?, ?, ?)",
+		s.session, at.UnixNano(), decodeErr.Error(), frame); err != nil {
+		return fmt.Errorf("write decode error: %w", err)
+	}
+	return nil
+}
+
+// WriteStats stores a statistics snapshot as JSON
+func (s *SQLite) WriteStats(at time.Time, stats *fusain.Statistics) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO stats_snapshots (session_id, time, stats) VALUES (?, ?, ?)",
+		s.session, at.UnixNano(), string(data)); err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+	return nil
+}
+
+// Flush commits buffered writes
+func (s *SQLite) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit()
+}
+
+// Close commits buffered writes and closes the database
+func (s *SQLite) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.commit()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// nullString returns data as a string, or NULL when empty
+func nullString(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// ============================================================
+// Queries
+// ============================================================
+
+// where builds the WHERE clause and arguments for q
+func (q Query) where(withType bool) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if !q.From.IsZero() {
+		conds = append(conds, "time >= ?")
+		args = append(args, q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		conds = append(conds, "time < ?")
+		args = append(args, q.To.UnixNano())
+	}
+	if q.Session != 0 {
+		conds = append(conds, "session_id = ?")
+		args = append(args, q.Session)
+	}
+	if q.AfterID != 0 {
+		conds = append(conds, "id > ?")
+		args = append(args, q.AfterID)
+	}
+	if len(q.Addresses) > 0 {
+		conds = append(conds, "address IN ("+placeholders(len(q.Addresses))+")")
+		for _, a := range q.Addresses {
+			args = append(args, int64(a))
+		}
+	}
+	if withType && len(q.Types) > 0 {
+		conds = append(conds, "type IN ("+placeholders(len(q.Types))+")")
+		for _, t := range q.Types {
+			args = append(args, t)
+		}
+	}
+
+	clause := ""
+	if len(conds) > 0 {
+		clause = " WHERE " + strings.Join(conds, " AND ")
+	}
+	clause += " ORDER BY time, id"
+	if q.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	return clause, args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// Sessions lists the recorded sessions with their packet counts
+func (s *SQLite) Sessions() ([]Session, error) {
+	rows, err := s.db.Query(`SELECT s.id, s.started_at, s.connection,
+		(SELECT COUNT(*) FROM packets p WHERE p.session_id = s.id)
+		FROM sessions s ORDER BY s.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var sess Session
+		var started int64
+		if err := rows.Scan(&sess.ID, &started, &sess.Connection, &sess.Packets); err != nil {
+			return nil, err
+		}
+		sess.StartedAt = time.Unix(0, started)
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+// Packets returns the packets matching q in receive order
+func (s *SQLite) Packets(q Query) ([]PacketRecord, error) {
+	clause, args := q.where(true)
+	rows, err := s.db.Query("SELECT id, session_id, time, frame FROM packets"+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []PacketRecord
+	for rows.Next() {
+		var r PacketRecord
+		var at int64
+		var frame []byte
+		if err := rows.Scan(&r.ID, &r.Session, &at, &frame); err != nil {
+			return nil, err
+		}
+		r.Packet, err = fusain.BatchFrame{Timestamp: time.Unix(0, at), Frame: frame}.Packet()
+		if err != nil {
+			return nil, fmt.Errorf("packet %d: %w", r.ID, err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Anomalies returns the validation errors matching q
+func (s *SQLite) Anomalies(q Query) ([]AnomalyRecord, error) {
+	clause, args := q.where(true)
+	rows, err := s.db.Query("SELECT id, session_id, packet_id, time, address, type, anomaly, check_name, message, details FROM validation_errors"+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []AnomalyRecord
+	for rows.Next() {
+		var r AnomalyRecord
+		var at, address int64
+		var packetID sql.NullInt64
+		var details sql.NullString
+		if err := rows.Scan(&r.ID, &r.Session, &packetID, &at, &address, &r.MsgType, &r.Anomaly, &r.Check, &r.Message, &details); err != nil {
+			return nil, err
+		}
+		r.PacketID = packetID.Int64
+		r.Time = time.Unix(0, at)
+		r.Address = uint64(address)
+		if details.Valid {
+			json.Unmarshal([]byte(details.String), &r.Details)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// DecodeErrors returns the decode errors matching q (address and type
+// filters don't apply)
+func (s *SQLite) DecodeErrors(q Query) ([]DecodeErrorRecord, error) {
+	q.Addresses = nil
+	clause, args := q.where(false)
+	rows, err := s.db.Query("SELECT id, session_id, time, message, frame FROM decode_errors"+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []DecodeErrorRecord
+	for rows.Next() {
+		var r DecodeErrorRecord
+		var at int64
+		if err := rows.Scan(&r.ID, &r.Session, &at, &r.Message, &r.Frame); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(0, at)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Stats returns the statistics snapshots matching q (address and type
+// filters don't apply)
+func (s *SQLite) Stats(q Query) ([]StatsRecord, error) {
+	q.Addresses = nil
+	clause, args := q.where(false)
+	rows, err := s.db.Query("SELECT id, session_id, time, stats FROM stats_snapshots"+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []StatsRecord
+	for rows.Next() {
+		var r StatsRecord
+		var at int64
+		var stats string
+		if err := rows.Scan(&r.ID, &r.Session, &at, &stats); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(0, at)
+		r.Stats = json.RawMessage(stats)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// openTestStore opens a store in a temporary directory with one session
+func openTestStore(t *testing.T) (*SQLite, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.db")
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.StartSession(time.Unix(1700000000, 0), "Serial: /dev/ttyUSB0"); err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func TestSQLite_PacketsRoundTrip(t *testing.T) {
+	s, _ := openTestStore(t)
+	start := time.Unix(1700000000, 0)
+
+	anomaly := fusain.ValidationError{
+		Type:    fusain.AnomalyInvalidValue,
+		Message: "RPM out of range",
+		Details: map[string]interface{}{"rpm": 9000.0},
+	}
+	writes := []struct {
+		p         *fusain.Packet
+		anomalies []fusain.ValidationError
+	}{
+		{fusain.StateData{State: fusain.SysStateHeating}.Encode(1), nil},
+		{fusain.MotorData{RPM: 9000}.Encode(1), []fusain.ValidationError{anomaly}},
+		{fusain.MotorData{RPM: 2000}.Encode(fusain.AddressStateless), nil},
+		{fusain.TempData{Reading: 21}.Encode(2), nil},
+	}
+	for i, w := range writes {
+		if err := s.WritePacket(start.Add(time.Duration(i)*time.Second), w.p, w.anomalies); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := s.Packets(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("%d packets, want 4", len(all))
+	}
+	if got := all[2].Packet.Address(); got != fusain.AddressStateless {
+		t.Errorf("stateless address read back as %016X", got)
+	}
+	if got := all[1].Packet.Timestamp(); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("timestamp = %v, want %v", got, start.Add(time.Second))
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want int
+	}{
+		{"by type", Query{Types: []uint8{fusain.MsgMotorData}}, 2},
+		{"by device", Query{Addresses: []uint64{1}}, 2},
+		{"by time", Query{From: start.Add(time.Second), To: start.Add(3 * time.Second)}, 2},
+		{"combined", Query{Addresses: []uint64{1}, Types: []uint8{fusain.MsgMotorData}}, 1},
+		{"limit", Query{Limit: 3}, 3},
+		{"after id", Query{AfterID: all[1].ID}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := s.Packets(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != tt.want {
+				t.Errorf("%d packets, want %d", len(records), tt.want)
+			}
+		})
+	}
+
+	anomalies, err := s.Anomalies(Query{Addresses: []uint64{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("%d anomalies, want 1", len(anomalies))
+	}
+	a := anomalies[0]
+	if a.PacketID != all[1].ID || a.Anomaly != "invalid_value" || a.Message != anomaly.Message || a.Details["rpm"] != 9000.0 {
+		t.Errorf("anomaly = %+v", a)
+	}
+}
+
+func TestSQLite_ErrorsStatsAndSessions(t *testing.T) {
+	s, path := openTestStore(t)
+	at := time.Unix(1700000100, 0)
+
+	if err := s.WriteDecodeError(at, errors.New("CRC mismatch"), []byte{0x7E, 0x01, 0x7F}); err != nil {
+		t.Fatal(err)
+	}
+	stats := fusain.NewStatistics()
+	stats.Update(fusain.MotorData{}.Encode(1), nil, nil)
+	if err := s.WriteStats(at, stats); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.StartSession(at, "WebSocket: ws://slate/ws"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WritePacket(at, fusain.MotorData{}.Encode(1), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything was committed by Close
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	decodeErrs, err := s.DecodeErrors(Query{})
+	if err != nil || len(decodeErrs) != 1 || decodeErrs[0].Message != "CRC mismatch" || len(decodeErrs[0].Frame) != 3 {
+		t.Errorf("decode errors = %+v, %v", decodeErrs, err)
+	}
+
+	snapshots, err := s.Stats(Query{})
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("stats = %+v, %v", snapshots, err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(snapshots[0].Stats, &decoded); err != nil || decoded["total_packets"] != 1.0 {
+		t.Errorf("stats JSON = %s (%v)", snapshots[0].Stats, err)
+	}
+
+	sessions, err := s.Sessions()
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("sessions = %+v, %v", sessions, err)
+	}
+	if sessions[0].Packets != 0 || sessions[1].Packets != 1 || sessions[1].Connection != "WebSocket: ws://slate/ws" {
+		t.Errorf("sessions = %+v", sessions)
+	}
+	if records, _ := s.Packets(Query{Session: sessions[0].ID}); len(records) != 0 {
+		t.Errorf("%d packets in the first session, want 0", len(records))
+	}
+}
+
+func TestSQLite_FollowWhileRecording(t *testing.T) {
+	s, path := openTestStore(t)
+	start := time.Unix(1700000000, 0)
+
+	// A second instance reads the database the recorder is writing
+	reader, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var last int64
+	for i := 0; i < 3; i++ {
+		if err := s.WritePacket(start.Add(time.Duration(i)*time.Second), fusain.MotorData{RPM: int32(1000 * (i + 1))}.Encode(1), nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := reader.Packets(Query{AfterID: last})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 {
+			t.Fatalf("poll %d: %d new packets, want 1", i, len(records))
+		}
+		last = records[0].ID
+	}
+}
+
+func TestSQLite_NoSession(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "empty.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.WritePacket(time.Now(), fusain.MotorData{}.Encode(1), nil); err == nil {
+		t.Error("WritePacket succeeded without a session")
+	}
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+// Package store persists recorded sessions - decoded packets, validation
+// errors, decode errors and statistics snapshots - in a database that can
+// be queried after the fact, so postmortems don't start with replaying
+// capture files.
+//
+// Backends implement Store and register an opener under a name; SQLite is
+// built in. Open picks the backend from the location: a file path (or
+// sqlite:PATH) opens SQLite, and NAME://... opens the backend registered
+// as NAME, so a Postgres or TimescaleDB backend can be added by a package
+// that calls Register from its init function.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thermoquad/heliostat/pkg/fusain"
+)
+
+// Writer records sessions. Writes may be buffered until Flush.
+type Writer interface {
+	// StartSession starts a new session; later writes belong to it
+	StartSession(at time.Time, connection string) error
+
+	// WritePacket stores a decoded packet received at at, with its
+	// validation errors
+	WritePacket(at time.Time, p *fusain.Packet, anomalies []fusain.ValidationError) error
+
+	// WriteDecodeError stores a frame the decoder rejected (frame may be
+	// nil)
+	WriteDecodeError(at time.Time, decodeErr error, frame []byte) error
+
+	// WriteStats stores a statistics snapshot
+	WriteStats(at time.Time, stats *fusain.Statistics) error
+
+	// Flush makes buffered writes durable
+	Flush() error
+
+	// Close flushes buffered writes and releases the store
+	Close() error
+}
+
+// Reader queries recorded sessions. Records are returned in time order.
+type Reader interface {
+	// Sessions lists the recorded sessions with their packet counts
+	Sessions() ([]Session, error)
+
+	// Packets returns the packets matching q
+	Packets(q Query) ([]PacketRecord, error)
+
+	// Anomalies returns the validation errors matching q
+	Anomalies(q Query) ([]AnomalyRecord, error)
+
+	// DecodeErrors returns the decode errors matching q (address and
+	// type filters don't apply)
+	DecodeErrors(q Query) ([]DecodeErrorRecord, error)
+
+	// Stats returns the statistics snapshots matching q (address and type
+	// filters don't apply)
+	Stats(q Query) ([]StatsRecord, error)
+
+	Close() error
+}
+
+// Store is a persistence backend for recorded sessions. Implementations
+// must be safe for concurrent use.
+type Store interface {
+	Writer
+	Reader
+}
+
+// Opener opens a backend's store from a data source name: the path for
+// sqlite:PATH, the whole location for NAME://... URLs
+type Opener func(dsn string) (Store, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Opener)
+)
+
+// Register makes a backend available to Open under name (e.g. "postgres").
+// Registering a name twice replaces the earlier opener.
+func Register(name string, open Opener) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[strings.ToLower(name)] = open
+}
+
+// Backends returns the registered backend names, sorted
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseLocation splits a store location into the backend name and the data
+// source name passed to its opener. Plain paths are SQLite.
+func ParseLocation(location string) (backend, dsn string) {
+	if name, _, ok := strings.Cut(location, "://"); ok && name != "" {
+		return strings.ToLower(name), location
+	}
+	if path, ok := strings.CutPrefix(location, "sqlite:"); ok {
+		return "sqlite", path
+	}
+	return "sqlite", location
+}
+
+// Open opens the store at location with its backend
+func Open(location string) (Store, error) {
+	backend, dsn := ParseLocation(location)
+	backendsMu.RLock()
+	open, ok := backends[backend]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no store backend %q (available: %s)", backend, strings.Join(Backends(), ", "))
+	}
+	return open(dsn)
+}
+
+// Query selects records. Zero fields don't filter.
+type Query struct {
+	From, To  time.Time // Inclusive start, exclusive end
+	Session   int64
+	Addresses []uint64
+	Types     []uint8 // Message types
+	Limit     int
+
+	// AfterID selects records with a larger ID only. IDs increase as
+	// records are written, so a reader follows a recording in progress by
+	// passing the last ID it has seen.
+	AfterID int64
+}
+
+// Session is one recording session
+type Session struct {
+	ID         int64
+	StartedAt  time.Time
+	Connection string
+	Packets    int64
+}
+
+// PacketRecord is a stored packet. Packet.Timestamp() is the receive time.
+type PacketRecord struct {
+	ID      int64
+	Session int64
+	Packet  *fusain.Packet
+}
+
+// AnomalyRecord is a stored validation error
+type AnomalyRecord struct {
+	ID       int64
+	Session  int64
+	PacketID int64
+	Time     time.Time
+	Address  uint64
+	MsgType  uint8
+	Anomaly  string // fusain.AnomalyType name (e.g. "invalid_value")
+	Check    string
+	Message  string
+	Details  map[string]interface{}
+}
+
+// DecodeErrorRecord is a stored decode error
+type DecodeErrorRecord struct {
+	ID      int64
+	Session int64
+	Time    time.Time
+	Message string
+	Frame   []byte
+}
+
+// StatsRecord is a stored statistics snapshot (Statistics JSON)
+type StatsRecord struct {
+	ID      int64
+	Session int64
+	Time    time.Time
+	Stats   json.RawMessage
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: GPL-2.0-or-later
+// Copyright (c) 2025 Kaz Walker, Thermoquad
+
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		location, backend, dsn string
+	}{
+		{"soak.db", "sqlite", "soak.db"},
+		{"/var/lib/heliostat/soak.db", "sqlite", "/var/lib/heliostat/soak.db"},
+		{`C:\data\soak.db`, "sqlite", `C:\data\soak.db`},
+		{"sqlite:soak.db", "sqlite", "soak.db"},
+		{"postgres://user@db/heliostat", "postgres", "postgres://user@db/heliostat"},
+		{"Timescale://db/heliostat", "timescale", "Timescale://db/heliostat"},
+	}
+	for _, tt := range tests {
+		backend, dsn := ParseLocation(tt.location)
+		if backend != tt.backend || dsn != tt.dsn {
+			t.Errorf("ParseLocation(%q) = %q, %q, want %q, %q", tt.location, backend, dsn, tt.backend, tt.dsn)
+		}
+	}
+}
+
+func TestOpen_SQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.db")
+	s, err := Open("sqlite:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*SQLite); !ok {
+		t.Errorf("Open returned %T, want *SQLite", s)
+	}
+	if err := s.StartSession(time.Unix(1700000000, 0), "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopened by plain path
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	sessions, err := s.Sessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Connection != "test" {
+		t.Errorf("sessions = %+v, want one \"test\" session", sessions)
+	}
+}
+
+func TestOpen_RegisteredBackend(t *testing.T) {
+	errOpened := errors.New("opened")
+	var got string
+	Register("fake", func(dsn string) (Store, error) {
+		got = dsn
+		return nil, errOpened
+	})
+	defer func() {
+		backendsMu.Lock()
+		delete(backends, "fake")
+		backendsMu.Unlock()
+	}()
+
+	if _, err := Open("fake://host/db"); !errors.Is(err, errOpened) {
+		t.Fatalf("Open error = %v, want the backend's error", err)
+	}
+	if got != "fake://host/db" {
+		t.Errorf("backend got DSN %q, want the whole URL", got)
+	}
+}
+
+func TestOpen_UnknownBackend(t *testing.T) {
+	_, err := Open("postgres://db/heliostat")
+	if err == nil {
+		t.Fatal("Open succeeded for an unregistered backend")
+	}
+	if !strings.Contains(err.Error(), `"postgres"`) || !strings.Contains(err.Error(), "sqlite") {
+		t.Errorf("error %q should name the backend and the available ones", err)
+	}
+}